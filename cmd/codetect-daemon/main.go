@@ -4,6 +4,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
@@ -29,9 +30,9 @@ func main() {
 	case "start":
 		cmdStart(os.Args[2:])
 	case "stop":
-		cmdStop()
+		cmdStop(os.Args[2:])
 	case "status":
-		cmdStatus()
+		cmdStatus(os.Args[2:])
 	case "help", "--help", "-h":
 		printUsage()
 	default:
@@ -44,7 +45,7 @@ func main() {
 func printUsage() {
 	fmt.Println("codetect-daemon - Background indexing daemon")
 	fmt.Println()
-	fmt.Println("Usage: codetect-daemon <command>")
+	fmt.Println("Usage: codetect-daemon <command> [options]")
 	fmt.Println()
 	fmt.Println("Commands:")
 	fmt.Println("  start     Start the daemon")
@@ -52,33 +53,56 @@ func printUsage() {
 	fmt.Println("  status    Show daemon status")
 	fmt.Println("  help      Show this help")
 	fmt.Println()
+	fmt.Println("Start Options:")
+	fmt.Println("  --foreground         Run in foreground (don't daemonize)")
+	fmt.Println("  --socket <path>      IPC socket path [default: /tmp/codetect-<uid>.sock]")
+	fmt.Println("  --pid-file <path>    PID file path [default: ~/.config/codetect/daemon.pid]")
+	fmt.Println("  --log-file <path>    Log file path [default: ~/.config/codetect/daemon.log]")
+	fmt.Println("  --registry <path>    Project registry to watch [default: ~/.config/codetect/registry.json]")
+	fmt.Println()
+	fmt.Println("Stop/Status Options:")
+	fmt.Println("  --socket <path>      Socket of the daemon instance to target")
+	fmt.Println()
 	fmt.Println("Environment Variables:")
-	fmt.Println("  CODETECT_LOG_LEVEL   Log level (debug, info, warn, error) [default: info]")
-	fmt.Println("  CODETECT_LOG_FORMAT  Output format (text, json) [default: text]")
+	fmt.Println("  CODETECT_LOG_LEVEL        Log level (debug, info, warn, error) [default: info]")
+	fmt.Println("  CODETECT_LOG_FORMAT       Output format (text, json) [default: text]")
+	fmt.Println("  CODETECT_DAEMON_SOCKET    Default for --socket")
+	fmt.Println("  CODETECT_DAEMON_PID_FILE  Default for --pid-file")
+	fmt.Println("  CODETECT_DAEMON_LOG_FILE  Default for --log-file")
+	fmt.Println("  CODETECT_REGISTRY         Default for --registry")
+	fmt.Println()
+	fmt.Println("Run several daemons side by side, for example one per registry, by giving")
+	fmt.Println("each its own socket, PID file, log file and registry, and pass the same")
+	fmt.Println("--socket to stop/status to reach an instance.")
 }
 
 func cmdStart(args []string) {
 	fs := flag.NewFlagSet("start", flag.ExitOnError)
 	foreground := fs.Bool("foreground", false, "Run in foreground (don't daemonize)")
+	cfg := daemon.LoadConfigFromEnv()
+	fs.StringVar(&cfg.SocketPath, "socket", cfg.SocketPath, "IPC socket path")
+	fs.StringVar(&cfg.PIDPath, "pid-file", cfg.PIDPath, "PID file path")
+	fs.StringVar(&cfg.LogPath, "log-file", cfg.LogPath, "Log file path")
+	registryPath := fs.String("registry", registry.DefaultRegistryPath(), "Project registry to watch")
 	fs.Parse(args)
 
-	// Check if already running
-	client := daemon.NewIPCClient(daemon.DefaultSocketPath())
-	if client.IsRunning() {
-		logger.Error("daemon is already running")
+	// Check if already running, clearing files left by a crashed daemon
+	if err := daemon.RemoveStale(cfg, logger); err != nil {
+		if errors.Is(err, daemon.ErrAlreadyRunning) {
+			logger.Error("daemon is already running", "socket", cfg.SocketPath)
+		} else {
+			logger.Error("failed to clean up stale daemon files", "error", err)
+		}
 		os.Exit(1)
 	}
 
 	// Load registry
-	reg, err := registry.NewRegistry()
+	reg, err := registry.NewRegistryAt(*registryPath)
 	if err != nil {
-		logger.Error("failed to load registry", "error", err)
+		logger.Error("failed to load registry", "path", *registryPath, "error", err)
 		os.Exit(1)
 	}
 
-	// Create daemon config
-	cfg := daemon.DefaultConfig()
-
 	// Create and run daemon
 	d, err := daemon.New(reg, cfg)
 	if err != nil {
@@ -104,10 +128,14 @@ func cmdStart(args []string) {
 	}
 }
 
-func cmdStop() {
-	client := daemon.NewIPCClient(daemon.DefaultSocketPath())
+func cmdStop(args []string) {
+	fs := flag.NewFlagSet("stop", flag.ExitOnError)
+	socket := socketFlag(fs)
+	fs.Parse(args)
+
+	client := daemon.NewIPCClient(*socket)
 	if !client.IsRunning() {
-		logger.Error("daemon is not running")
+		logger.Error("daemon is not running", "socket", *socket)
 		os.Exit(1)
 	}
 
@@ -119,12 +147,20 @@ func cmdStop() {
 	logger.Info("daemon stopped")
 }
 
-func cmdStatus() {
-	client := daemon.NewIPCClient(daemon.DefaultSocketPath())
+func cmdStatus(args []string) {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	socket := socketFlag(fs)
+	fs.Parse(args)
+
+	client := daemon.NewIPCClient(*socket)
 
 	status, err := client.Status()
+	if errors.Is(err, daemon.ErrVersionMismatch) {
+		logger.Error("daemon is running but cannot be queried", "error", err)
+		os.Exit(1)
+	}
 	if err != nil {
-		logger.Info("daemon is not running")
+		logger.Info("daemon is not running", "socket", *socket)
 		os.Exit(1)
 	}
 
@@ -132,3 +168,9 @@ func cmdStatus() {
 	data, _ := json.MarshalIndent(status, "", "  ")
 	fmt.Println(string(data))
 }
+
+// socketFlag registers --socket on a client command, defaulting to the
+// socket the daemon itself would listen on.
+func socketFlag(fs *flag.FlagSet) *string {
+	return fs.String("socket", daemon.LoadConfigFromEnv().SocketPath, "Socket of the daemon instance to target")
+}
@@ -8,11 +8,16 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"codetect/evals"
+	searchconfig "codetect/internal/config"
+	"codetect/internal/fusion"
 	"codetect/internal/logging"
+	"codetect/internal/tools"
 )
 
 var logger *slog.Logger
@@ -32,10 +37,18 @@ func main() {
 		runEval(os.Args[2:])
 	case "report":
 		showReport(os.Args[2:])
+	case "trend":
+		showTrend(os.Args[2:])
 	case "list":
 		listCases(os.Args[2:])
 	case "logs":
 		showLogs(os.Args[2:])
+	case "prune":
+		pruneFiles(os.Args[2:])
+	case "retrieval":
+		runRetrieval(os.Args[2:])
+	case "tune-weights":
+		tuneWeights(os.Args[2:])
 	case "version":
 		fmt.Printf("codetect-eval v%s\n", version)
 	case "help", "-h", "--help":
@@ -118,7 +131,7 @@ func runEval(args []string) {
 		fmt.Fprintln(os.Stderr, "")
 
 		// Check if we're evaluating a different repo
-		repoEvalDir := filepath.Join(absRepoPath, ".codetect", "evals", "cases")
+		repoEvalDir := filepath.Join(searchconfig.DataDir(absRepoPath), "evals", "cases")
 		if absRepoPath != "." {
 			fmt.Fprintf(os.Stderr, "For repo-specific eval cases, create them in:\n")
 			fmt.Fprintf(os.Stderr, "  %s\n", repoEvalDir)
@@ -213,6 +226,68 @@ func showReport(args []string) {
 	reporter.PrintReportToStdout(report)
 }
 
+func showTrend(args []string) {
+	fs := flag.NewFlagSet("trend", flag.ExitOnError)
+	repoPath := fs.String("repo", ".", "Path to repository")
+	resultsDir := fs.String("dir", "", "Results directory to read (default: the repo's .codetect/evals/results)")
+	csvOutput := fs.Bool("csv", false, "Output the trend as CSV")
+	fs.Parse(args)
+
+	var paths []string
+	if *resultsDir != "" {
+		files, err := filepath.Glob(filepath.Join(*resultsDir, "*-results.json"))
+		if err != nil {
+			logger.Error("error finding results", "error", err)
+			os.Exit(1)
+		}
+		paths = files
+	} else {
+		config := evals.DefaultConfig()
+		absRepoPath, err := filepath.Abs(*repoPath)
+		if err != nil {
+			logger.Error("invalid repo path", "error", err)
+			os.Exit(1)
+		}
+		config.RepoPath = absRepoPath
+
+		results, err := evals.NewRunner(config).ListResults()
+		if err != nil {
+			logger.Error("error listing results", "error", err)
+			os.Exit(1)
+		}
+		for _, res := range results {
+			if res.Kind == "results" {
+				paths = append(paths, res.Path)
+			}
+		}
+	}
+
+	reporter := evals.NewReporter()
+	var reports []*evals.EvalReport
+	for _, path := range paths {
+		report, err := reporter.LoadReport(path)
+		if err != nil {
+			logger.Warn("skipping unreadable results file", "path", path, "error", err)
+			continue
+		}
+		reports = append(reports, report)
+	}
+
+	if len(reports) == 0 {
+		logger.Error("no results files found, run 'codetect-eval run' first")
+		os.Exit(1)
+	}
+
+	if *csvOutput {
+		if err := reporter.WriteTrendCSV(reports, os.Stdout); err != nil {
+			logger.Error("error writing CSV", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	reporter.PrintTrend(reports, os.Stdout)
+}
+
 func listCases(args []string) {
 	fs := flag.NewFlagSet("list", flag.ExitOnError)
 	repoPath := fs.String("repo", ".", "Path to repository to evaluate")
@@ -260,8 +335,15 @@ func showLogs(args []string) {
 	mode := fs.String("mode", "", "Filter by mode (with_mcp, without_mcp)")
 	latest := fs.Bool("latest", false, "Show only the latest log")
 	listOnly := fs.Bool("list", false, "List logs without showing content")
+	format := fs.String("format", "raw", "Log output format: raw (stream-json as logged) or pretty (readable transcript)")
+	fs.StringVar(format, "output-format", "raw", "Long form of --format")
 	fs.Parse(args)
 
+	if *format != "raw" && *format != "pretty" {
+		logger.Error("unknown log format, use raw or pretty", "format", *format)
+		os.Exit(1)
+	}
+
 	config := evals.DefaultConfig()
 
 	absRepoPath, err := filepath.Abs(*repoPath)
@@ -330,7 +412,11 @@ func showLogs(args []string) {
 			continue
 		}
 
-		os.Stdout.Write(content)
+		if *format == "pretty" {
+			evals.WriteTranscript(os.Stdout, content)
+		} else {
+			os.Stdout.Write(content)
+		}
 
 		if len(filtered) > 1 && i < len(filtered)-1 {
 			fmt.Println()
@@ -338,6 +424,245 @@ func showLogs(args []string) {
 	}
 }
 
+func pruneFiles(args []string) {
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	repoPath := fs.String("repo", ".", "Path to repository")
+	keep := fs.Int("keep", 0, "Keep the N newest logs per test case and mode, and result files per kind")
+	olderThan := fs.String("older-than", "", "Only delete files older than this age, e.g. 30d or 12h")
+	dryRun := fs.Bool("dry-run", false, "List the files that would be deleted without deleting them")
+	fs.Parse(args)
+
+	var maxAge time.Duration
+	if *olderThan != "" {
+		age, err := parseAge(*olderThan)
+		if err != nil {
+			logger.Error("invalid --older-than", "value", *olderThan, "error", err)
+			os.Exit(1)
+		}
+		maxAge = age
+	}
+
+	config := evals.DefaultConfig()
+
+	absRepoPath, err := filepath.Abs(*repoPath)
+	if err != nil {
+		logger.Error("invalid repo path", "error", err)
+		os.Exit(1)
+	}
+	config.RepoPath = absRepoPath
+
+	runner := evals.NewRunner(config)
+	files, err := runner.PlanPrune(evals.PruneOptions{Keep: *keep, OlderThan: maxAge}, time.Now())
+	if err != nil {
+		logger.Error("error planning prune", "error", err)
+		os.Exit(1)
+	}
+
+	if len(files) == 0 {
+		fmt.Println("Nothing to prune")
+		return
+	}
+
+	var total int64
+	for _, f := range files {
+		fmt.Printf("  %s  %8s  %s\n", f.Timestamp.Format("2006-01-02 15:04:05"), formatBytes(f.Size), filepath.Base(f.Path))
+		total += f.Size
+	}
+
+	if *dryRun {
+		fmt.Printf("\nWould delete %d files (%s)\n", len(files), formatBytes(total))
+		return
+	}
+
+	if err := runner.Prune(files); err != nil {
+		logger.Error("error deleting files", "error", err)
+		os.Exit(1)
+	}
+	fmt.Printf("\nDeleted %d files (%s)\n", len(files), formatBytes(total))
+}
+
+// parseAge parses a duration that may also be given in days, like "30d".
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid number of days %q", days)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func runRetrieval(args []string) {
+	fs := flag.NewFlagSet("retrieval", flag.ExitOnError)
+	repoPath := fs.String("repo", ".", "Path to indexed repository")
+	casesDir := fs.String("cases", "evals/cases", "Directory containing test case JSONL files")
+	categories := fs.String("category", "", "Filter by category (comma-separated: search,navigate,understand)")
+	modes := fs.String("mode", "keyword,semantic,hybrid", "Retrieval modes to score (comma-separated)")
+	k := fs.Int("k", 10, "Score the top k results of each mode")
+	fs.Parse(args)
+
+	config := evals.DefaultConfig()
+	if *categories != "" {
+		config.Categories = strings.Split(*categories, ",")
+	}
+
+	absRepoPath, err := filepath.Abs(*repoPath)
+	if err != nil {
+		logger.Error("invalid repo path", "error", err)
+		os.Exit(1)
+	}
+	config.RepoPath = absRepoPath
+
+	absCasesDir, err := filepath.Abs(*casesDir)
+	if err != nil {
+		logger.Error("invalid cases dir", "error", err)
+		os.Exit(1)
+	}
+
+	runner := evals.NewRunner(config)
+	cases, err := runner.LoadTestCases(absCasesDir)
+	if err != nil {
+		logger.Error("error loading test cases", "error", err)
+		os.Exit(1)
+	}
+	if len(cases) == 0 {
+		logger.Error("no test cases found", "cases", absCasesDir)
+		os.Exit(1)
+	}
+
+	retriever, err := tools.OpenV2Retriever(absRepoPath)
+	if err != nil {
+		logger.Error("error opening v2 index", "error", err)
+		os.Exit(1)
+	}
+	defer retriever.Close()
+
+	var selected []evals.ExecutionMode
+	for _, m := range strings.Split(*modes, ",") {
+		mode := evals.ExecutionMode(strings.TrimSpace(m))
+		if !slices.Contains(evals.RetrievalModes, mode) {
+			logger.Error("unknown retrieval mode", "mode", mode)
+			os.Exit(1)
+		}
+		if mode == evals.ModeSemantic && !retriever.SemanticAvailable() {
+			logger.Warn("semantic search unavailable, skipping semantic mode")
+			continue
+		}
+		selected = append(selected, mode)
+	}
+
+	fmt.Fprintf(os.Stderr, "Scoring %d test cases against %s (no agent)...\n", len(cases), absRepoPath)
+
+	retrieve := func(ctx context.Context, query string, limit int) [][]fusion.Result {
+		return retriever.Retrieve(ctx, query, limit).Lists()
+	}
+	weights := searchconfig.LoadRetrieverConfigFromEnv().Weights
+	report := evals.NewRetrievalRunner(retrieve, weights, *k).Run(context.Background(), cases, selected)
+	report.RepoPath = absRepoPath
+
+	if err := runner.SaveRetrievalResults(report); err != nil {
+		logger.Warn("could not save results", "error", err)
+	}
+
+	evals.NewReporter().PrintRetrievalReport(report, os.Stdout)
+}
+
+func tuneWeights(args []string) {
+	fs := flag.NewFlagSet("tune-weights", flag.ExitOnError)
+	repoPath := fs.String("repo", ".", "Path to indexed repository")
+	casesDir := fs.String("cases", "evals/cases", "Directory containing test case JSONL files")
+	categories := fs.String("category", "", "Filter by category (comma-separated: search,navigate,understand)")
+	k := fs.Int("k", 10, "Recall cutoff: score the top k fused results")
+	step := fs.Float64("step", 0.1, "Weight grid spacing")
+	top := fs.Int("top", 5, "Number of top weightings to print")
+	fs.Parse(args)
+
+	config := evals.DefaultConfig()
+	if *categories != "" {
+		config.Categories = strings.Split(*categories, ",")
+	}
+
+	absRepoPath, err := filepath.Abs(*repoPath)
+	if err != nil {
+		logger.Error("invalid repo path", "error", err)
+		os.Exit(1)
+	}
+	config.RepoPath = absRepoPath
+
+	absCasesDir, err := filepath.Abs(*casesDir)
+	if err != nil {
+		logger.Error("invalid cases dir", "error", err)
+		os.Exit(1)
+	}
+
+	runner := evals.NewRunner(config)
+	cases, err := runner.LoadTestCases(absCasesDir)
+	if err != nil {
+		logger.Error("error loading test cases", "error", err)
+		os.Exit(1)
+	}
+
+	retriever, err := tools.OpenV2Retriever(absRepoPath)
+	if err != nil {
+		logger.Error("error opening v2 index", "error", err)
+		os.Exit(1)
+	}
+	defer retriever.Close()
+
+	if !retriever.SemanticAvailable() {
+		logger.Warn("semantic search unavailable, tuning keyword results only")
+	}
+
+	// Fetch each channel once; the tuner re-fuses them per weighting
+	ctx := context.Background()
+	var retrieval []evals.RetrievalCase
+	for _, tc := range cases {
+		if len(tc.GroundTruth.Files) == 0 && len(tc.GroundTruth.Symbols) == 0 {
+			continue
+		}
+		channels := retriever.Retrieve(ctx, tc.Prompt, *k)
+		retrieval = append(retrieval, evals.RetrievalCase{TestCase: tc, Lists: channels.Lists()})
+	}
+
+	if len(retrieval) == 0 {
+		logger.Error("no test cases with ground truth files or symbols", "cases", absCasesDir)
+		os.Exit(1)
+	}
+
+	baseline := searchconfig.LoadRetrieverConfigFromEnv().Weights
+	result := evals.NewWeightTuner(*k, *step).Tune(retrieval, baseline)
+
+	fmt.Printf("Tuned %d cases over %d weightings of %s (recall@%d)\n\n",
+		result.Cases, len(result.Evaluated), strings.Join(result.Sources, ", "), result.K)
+	fmt.Printf("  Current: %s  recall=%.3f\n", formatWeights(result.Baseline.Weights, result.Sources), result.Baseline.MeanRecall)
+	for i, ws := range result.Evaluated {
+		if i >= *top {
+			break
+		}
+		fmt.Printf("  #%-6d %s  recall=%.3f\n", i+1, formatWeights(ws.Weights, result.Sources), ws.MeanRecall)
+	}
+
+	if result.Best.MeanRecall <= result.Baseline.MeanRecall {
+		fmt.Println("\nCurrent weights are already optimal on this grid.")
+		return
+	}
+
+	fmt.Println("\nBest weights:")
+	for _, source := range result.Sources {
+		fmt.Printf("  export CODETECT_WEIGHT_%s=%g\n", strings.ToUpper(source), result.Best.Weights[source])
+	}
+}
+
+// formatWeights renders weights for the given sources in order.
+func formatWeights(weights map[string]float64, sources []string) string {
+	parts := make([]string, len(sources))
+	for i, s := range sources {
+		parts[i] = fmt.Sprintf("%s=%.2f", s, weights[s])
+	}
+	return strings.Join(parts, " ")
+}
+
 func formatBytes(b int64) string {
 	const unit = 1024
 	if b < unit {
@@ -358,12 +683,16 @@ Usage:
   codetect-eval <command> [options]
 
 Commands:
-  run      Run evaluation test cases
-  report   Display a saved report
-  list     List available test cases
-  logs     View raw Claude output logs from eval runs
-  version  Print version
-  help     Show this help
+  run           Run evaluation test cases
+  report        Display a saved report
+  trend         Show summary metrics of every saved run over time
+  list          List available test cases
+  logs          View Claude output logs from eval runs
+  prune         Delete old eval logs and result files
+  retrieval     Score search results against ground truth without an agent
+  tune-weights  Find RRF fusion weights that maximize retrieval recall
+  version       Print version
+  help          Show this help
 
 Run Options:
   --repo <path>      Repository to evaluate (default: .)
@@ -378,12 +707,48 @@ Run Options:
 Report Options:
   --results <path>   Path to results JSON file
 
+Trend Options:
+  --repo <path>      Repository whose saved results to read (default: .)
+  --dir <dir>        Read *-results.json from this directory instead
+  --csv              Output CSV instead of a table
+
 Logs Options:
   --repo <path>      Repository to view logs for (default: .)
   --case <id>        Filter by test case ID
   --mode <mode>      Filter by mode (with_mcp, without_mcp)
   --latest           Show only the most recent log
   --list             List logs without showing content
+  --format <fmt>     raw (default) prints stream-json as logged, pretty a
+                     readable transcript of tool calls, answer and totals
+
+Prune Options:
+  --repo <path>      Repository to prune (default: .)
+  --keep <n>         Keep the n newest logs per test case and mode, and
+                     result files per kind
+  --older-than <age> Only delete files older than this, e.g. 30d or 12h
+  --dry-run          List what would be deleted without deleting it
+
+  With both --keep and --older-than, a file must be outside the newest n
+  and older than the age to be deleted.
+
+Retrieval Options:
+  --repo <path>      Repository with a v2 index (default: .)
+  --cases <dir>      Test cases directory (default: evals/cases)
+  --category <cat>   Filter by category (search,navigate,understand)
+  --mode <modes>     Modes to score: keyword,semantic,hybrid (default: all)
+  --k <n>            Score the top n results of each mode (default: 10)
+
+Tune-weights Options:
+  --repo <path>      Repository with a v2 index (default: .)
+  --cases <dir>      Test cases directory (default: evals/cases)
+  --category <cat>   Filter by category (search,navigate,understand)
+  --k <n>            Score recall over the top n fused results (default: 10)
+  --step <f>         Weight grid spacing (default: 0.1)
+  --top <n>          Number of top weightings to print (default: 5)
+
+  Runs the hybrid_search_v2 retriever directly (no Claude invocation) and
+  grid-searches keyword/semantic weights against ground truth files and
+  symbols. Current weights come from CODETECT_WEIGHT_* variables.
 
 Examples:
   # Run all tests on current directory
@@ -395,6 +760,9 @@ Examples:
   # View the most recent report
   codetect-eval report
 
+  # See whether accuracy and cost improved run over run
+  codetect-eval trend --repo /path/to/project
+
   # List available test cases
   codetect-eval list
 
@@ -405,5 +773,17 @@ Examples:
   codetect-eval logs --repo /path/to/project --latest
 
   # View logs for a specific test case
-  codetect-eval logs --repo /path/to/project --case search-001`)
+  codetect-eval logs --repo /path/to/project --case search-001
+
+  # Read the latest log as a transcript
+  codetect-eval logs --repo /path/to/project --latest --format pretty
+
+  # See what pruning to the last 10 runs would delete
+  codetect-eval prune --repo /path/to/project --keep 10 --dry-run
+
+  # Score retrieval quality without running Claude
+  codetect-eval retrieval --repo /path/to/project --k 5
+
+  # Tune fusion weights against a repo's eval cases
+  codetect-eval tune-weights --repo /path/to/project --cases /path/to/project/.codetect/evals/cases --k 5`)
 }
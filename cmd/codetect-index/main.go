@@ -3,21 +3,35 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
+	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	ignore "github.com/sabhiram/go-gitignore"
 
+	"codetect/evals"
 	"codetect/internal/config"
 	"codetect/internal/db"
 	"codetect/internal/embedding"
+	"codetect/internal/fusion"
 	"codetect/internal/indexer"
 	"codetect/internal/logging"
+	"codetect/internal/search/keyword"
 	"codetect/internal/search/symbols"
+	"codetect/internal/snapshot"
+	"codetect/internal/watch"
 )
 
 var logger *slog.Logger
@@ -42,6 +56,39 @@ func main() {
 	case "stats":
 		runStats(os.Args[2:])
 
+	case "watch":
+		runWatch(os.Args[2:])
+
+	case "recent":
+		runRecent(os.Args[2:])
+
+	case "diff":
+		runDiff(os.Args[2:])
+
+	case "list-symbols":
+		runListSymbols(os.Args[2:])
+
+	case "search":
+		runSearch(os.Args[2:])
+
+	case "migrate-v2":
+		runMigrateV2(os.Args[2:])
+
+	case "export":
+		runExport(os.Args[2:])
+
+	case "import":
+		runImport(os.Args[2:])
+
+	case "repack":
+		runRepack(os.Args[2:])
+
+	case "rekey":
+		runRekey(os.Args[2:])
+
+	case "bench-models":
+		runBenchModels(os.Args[2:])
+
 	case "version":
 		fmt.Printf("codetect-index v%s\n", version)
 
@@ -60,10 +107,17 @@ func runIndex(args []string) {
 	force := fs.Bool("force", false, "Force full reindex")
 	fs.BoolVar(force, "f", false, "Short for --force")
 	useV2 := fs.Bool("v2", false, "Use v2 indexer (AST chunking, Merkle tree)")
+	reembedModel := fs.Bool("reembed-model", false, "With --v2, re-embed chunks cached under another model, keeping chunking and change tracking")
 	verbose := fs.Bool("verbose", false, "Enable verbose output")
 	fs.BoolVar(verbose, "v", false, "Short for --verbose")
 	jsonOutput := fs.Bool("json", false, "Output results as JSON")
+	repoID := fs.String("repo-id", "", "Stable repo identifier used as the index key (overrides CODETECT_REPO_ID)")
+	maxDepth := fs.Int("max-depth", -1, "Skip directories more than N levels below the repo root, 0 = unlimited (overrides CODETECT_INDEX_MAX_DEPTH)")
+	oneFileSystem := fs.Bool("one-file-system", false, "Don't descend into directories on other filesystems (overrides CODETECT_INDEX_ONE_FILE_SYSTEM)")
 	fs.Parse(args)
+	setRepoID(*repoID)
+	setMaxDepth(*maxDepth)
+	setOneFileSystem(*oneFileSystem)
 
 	path := "."
 	if fs.NArg() > 0 {
@@ -78,9 +132,13 @@ func runIndex(args []string) {
 	}
 
 	if *useV2 {
-		runIndexV2(absPath, *force, *verbose, *jsonOutput)
+		runIndexV2(absPath, *force, *reembedModel, *verbose, *jsonOutput)
 		return
 	}
+	if *reembedModel {
+		logger.Error("--reembed-model needs the v2 indexer (--v2)")
+		os.Exit(1)
+	}
 
 	// V1 path: ctags-based symbol indexing
 
@@ -96,7 +154,7 @@ func runIndex(args []string) {
 
 	// For SQLite, ensure .codetect directory exists and set path relative to target
 	if dbConfig.Type == db.DatabaseSQLite {
-		indexDir := filepath.Join(absPath, ".codetect")
+		indexDir := config.DataDir(absPath)
 		if err := os.MkdirAll(indexDir, 0755); err != nil {
 			logger.Error("creating index directory failed", "error", err)
 			os.Exit(1)
@@ -150,33 +208,8 @@ func runIndex(args []string) {
 
 // runIndexV2 uses the new v2 indexer with Merkle tree change detection,
 // AST-based chunking, and content-addressed embedding cache.
-func runIndexV2(absPath string, force, verbose, jsonOutput bool) {
-	// Load configuration from environment
-	dbConfig := config.LoadDatabaseConfigFromEnv()
-	embConfig := embedding.LoadConfigFromEnv()
-
-	// Build indexer config
-	cfg := &indexer.Config{
-		DBType:            string(dbConfig.Type),
-		Dimensions:        dbConfig.VectorDimensions,
-		EmbeddingProvider: string(embConfig.Provider),
-		EmbeddingModel:    embConfig.Model,
-		OllamaURL:         embConfig.OllamaURL,
-		LiteLLMURL:        embConfig.LiteLLMURL,
-		LiteLLMKey:        embConfig.LiteLLMKey,
-		BatchSize:         32,
-		MaxWorkers:        4,
-	}
-
-	// Set database path/DSN
-	if dbConfig.Type == db.DatabasePostgres {
-		cfg.DSN = dbConfig.DSN
-	} else {
-		cfg.DBPath = filepath.Join(absPath, ".codetect", "index.db")
-	}
-
-	// Load gitignore patterns
-	cfg.IgnorePatterns = indexer.LoadGitignore(absPath)
+func runIndexV2(absPath string, force, reembedModel, verbose, jsonOutput bool) {
+	cfg := indexer.LoadConfigFromEnv(absPath)
 
 	if verbose {
 		logger.Info("v2 indexer starting",
@@ -194,13 +227,29 @@ func runIndexV2(absPath string, force, verbose, jsonOutput bool) {
 	}
 	defer idx.Close()
 
-	// Run indexing
-	ctx := context.Background()
+	// Run indexing; Ctrl-C cancels it
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// Progress output uses fmt.Fprintf for \r carriage return support
+	showedProgress := false
 	result, err := idx.Index(ctx, indexer.IndexOptions{
-		Force:   force,
-		Verbose: verbose,
+		Force:        force,
+		Verbose:      verbose,
+		ReembedModel: reembedModel,
+		Progress: func(done, total int) {
+			fmt.Fprintf(os.Stderr, "\rindexing file %d/%d...", done, total)
+			showedProgress = true
+		},
 	})
+	if showedProgress {
+		fmt.Fprintln(os.Stderr) // newline after progress
+	}
 	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			logger.Info("v2 indexing interrupted, re-run to resume", "detail", err)
+			idx.Close()
+			os.Exit(130)
+		}
 		logger.Error("v2 indexing failed", "error", err)
 		os.Exit(1)
 	}
@@ -236,6 +285,10 @@ func runIndexV2(absPath string, force, verbose, jsonOutput bool) {
 			"chunks_embedded", result.ChunksEmbedded,
 			"duration", result.Duration.Round(time.Millisecond))
 	}
+	if result.FilesFailed > 0 {
+		logger.Warn("some files failed to index and will be retried on the next run",
+			"files_failed", result.FilesFailed)
+	}
 }
 
 func runEmbed(args []string) {
@@ -246,11 +299,29 @@ func runEmbed(args []string) {
 	model := fs.String("model", "", "Embedding model (provider-specific default if empty)")
 	parallel := fs.Int("parallel", 10, "Number of parallel embedding workers")
 	fs.IntVar(parallel, "j", 10, "Short for --parallel (like make -j)")
+	root := fs.String("root", "", "Repository root; positional arguments are then files or directories to embed")
+	checkpoint := fs.Int("checkpoint", embedding.DefaultCheckpointInterval, "Save embeddings every N chunks so an interrupted run can resume (0 = only at the end)")
+	repoID := fs.String("repo-id", "", "Stable repo identifier used as the index key (overrides CODETECT_REPO_ID)")
+	maxDepth := fs.Int("max-depth", -1, "Skip directories more than N levels below the repo root, 0 = unlimited (overrides CODETECT_INDEX_MAX_DEPTH)")
+	oneFileSystem := fs.Bool("one-file-system", false, "Don't descend into directories on other filesystems (overrides CODETECT_INDEX_ONE_FILE_SYSTEM)")
+	selfTest := fs.Bool("selftest", false, "Check the provider returns sane embeddings of the configured size, then exit")
 	fs.Parse(args)
+	setRepoID(*repoID)
+	setMaxDepth(*maxDepth)
+	setOneFileSystem(*oneFileSystem)
 
+	// A single directory argument is the repo root (embed everything).
+	// Otherwise the arguments are a subset of the repo at --root (default: cwd).
 	path := "."
-	if fs.NArg() > 0 {
+	var targets []string
+	switch {
+	case *root != "":
+		path = *root
+		targets = fs.Args()
+	case fs.NArg() == 1 && isDir(fs.Arg(0)):
 		path = fs.Arg(0)
+	default:
+		targets = fs.Args()
 	}
 
 	absPath, err := filepath.Abs(path)
@@ -278,6 +349,12 @@ func runEmbed(args []string) {
 		cfg.Model = *model
 	}
 
+	// The self-test reports every outcome as a PASS or FAIL line
+	if *selfTest {
+		runEmbedSelfTest(cfg, config.LoadDatabaseConfigFromEnv().VectorDimensions)
+		return
+	}
+
 	// Check if embedding is disabled
 	if cfg.Provider == embedding.ProviderOff {
 		logger.Info("embedding disabled", "provider", "off")
@@ -309,7 +386,7 @@ func runEmbed(args []string) {
 
 	// For SQLite, verify index exists and set path relative to target
 	if dbConfig.Type == db.DatabaseSQLite {
-		indexDir := filepath.Join(absPath, ".codetect")
+		indexDir := config.DataDir(absPath)
 		dbPath := filepath.Join(indexDir, "symbols.db")
 		if _, err := os.Stat(dbPath); os.IsNotExist(err) {
 			logger.Error("no symbol index found, run 'codetect-index index' first")
@@ -343,9 +420,26 @@ func runEmbed(args []string) {
 		os.Exit(1)
 	}
 	searcher := embedding.NewSemanticSearcher(store, embedder)
+	searcher.SetLogger(logger)
+	searcher.SetCheckpointInterval(*checkpoint)
+
+	// Repo config tracks the current model, tagged with any truncation size
+	modelName := cfg.Model
+	if modelName == "" || cfg.TruncateDim > 0 {
+		modelName = embedding.ModelName(embedder.ProviderID())
+	}
+
+	// Truncated and full-size vectors of one model must not share an index
+	if repoCfg, err := store.GetRepoConfig(config.RepoID(absPath)); err == nil && repoCfg != nil &&
+		embedding.MixesTruncation(repoCfg.Model, modelName) && !*force {
+		logger.Error("repo is embedded with a different truncation of this model, re-run with --force to replace it",
+			"existing_model", repoCfg.Model,
+			"model", modelName)
+		os.Exit(1)
+	}
 
 	// Check for dimension mismatch (model change)
-	oldDim, hasMismatch, err := store.CheckDimensionMismatch(absPath, dbConfig.VectorDimensions)
+	oldDim, hasMismatch, err := store.CheckDimensionMismatch(config.RepoID(absPath), dbConfig.VectorDimensions)
 	if err != nil {
 		logger.Warn("checking dimension mismatch", "error", err)
 	}
@@ -356,15 +450,15 @@ func runEmbed(args []string) {
 			"model", cfg.Model)
 
 		// Migrate: delete old embeddings and update config
-		if err := store.MigrateRepoDimensions(absPath, oldDim, dbConfig.VectorDimensions, cfg.Model); err != nil {
+		if err := store.MigrateRepoDimensions(config.RepoID(absPath), oldDim, dbConfig.VectorDimensions, modelName); err != nil {
 			logger.Error("migrating embeddings failed", "error", err)
 			os.Exit(1)
 		}
 		logger.Info("migrated to new dimension group, re-embedding required")
 	}
 
-	// Clear embeddings if force flag set
-	if *force {
+	// Clear embeddings if force flag set (a subset embed always replaces its files)
+	if *force && len(targets) == 0 {
 		logger.Info("clearing existing embeddings")
 		if err := searcher.Store().DeleteAll(); err != nil {
 			logger.Error("clearing embeddings failed", "error", err)
@@ -377,57 +471,44 @@ func runEmbed(args []string) {
 
 	// First pass: collect file info for preview
 	logger.Info("scanning files to embed")
-	var filesToEmbed []string
-	var totalSize int64
+	filesToEmbed, totalSize, err := collectEmbedFiles(absPath, targets, gi)
+	if err != nil {
+		logger.Error("scanning files failed", "error", err)
+		os.Exit(1)
+	}
 
-	err = filepath.Walk(absPath, func(filePath string, info os.FileInfo, err error) error {
+	// Subset embed: drop stale embeddings for the requested files first,
+	// including files that no longer exist
+	var stale []string
+	if len(targets) > 0 {
+		stale, err = stalePaths(absPath, targets, filesToEmbed)
 		if err != nil {
-			return nil
+			logger.Error("resolving paths failed", "error", err)
+			os.Exit(1)
 		}
-
-		relPath, _ := filepath.Rel(absPath, filePath)
-
-		if info.IsDir() {
-			name := info.Name()
-			// Always skip these directories
-			if name == ".git" || name == "node_modules" || name == "vendor" || name == ".codetect" {
-				return filepath.SkipDir
-			}
-			// Check gitignore for directories
-			if gi != nil && gi.MatchesPath(relPath+"/") {
-				return filepath.SkipDir
+		for _, relPath := range stale {
+			if err := searcher.Store().DeleteByPath(relPath); err != nil {
+				logger.Error("clearing embeddings failed", "path", relPath, "error", err)
+				os.Exit(1)
 			}
-			return nil
-		}
-
-		// Check gitignore for files
-		if gi != nil && gi.MatchesPath(relPath) {
-			return nil
-		}
-
-		// Only count code files
-		if isCodeFile(filePath) {
-			filesToEmbed = append(filesToEmbed, filePath)
-			totalSize += info.Size()
 		}
-
-		return nil
-	})
-
-	if err != nil {
-		logger.Error("scanning directory failed", "error", err)
-		os.Exit(1)
+		logger.Info("embedding subset", "targets", len(targets), "files", len(filesToEmbed))
 	}
 
+	indexConfig := config.LoadIndexConfigFromEnv()
+
 	// Display preview
 	if len(filesToEmbed) == 0 {
+		if indexConfig.KeywordIndex && len(stale) > 0 {
+			updateKeywordIndex(idx, absPath, stale, nil)
+		}
 		logger.Info("no code files to embed")
 		return
 	}
 
 	fmt.Fprintf(os.Stderr, "\n📊 Embedding Preview:\n")
 	fmt.Fprintf(os.Stderr, "   Files to embed: %d\n", len(filesToEmbed))
-	fmt.Fprintf(os.Stderr, "   Total size: %s\n", formatBytes(totalSize))
+	fmt.Fprintf(os.Stderr, "   Total size: %s\n", evals.FormatBytes(totalSize))
 	fmt.Fprintf(os.Stderr, "   Provider: %s\n", cfg.Provider)
 	if cfg.Model != "" {
 		fmt.Fprintf(os.Stderr, "   Model: %s\n", cfg.Model)
@@ -438,6 +519,9 @@ func runEmbed(args []string) {
 	logger.Info("collecting code chunks")
 	var allChunks []embedding.Chunk
 	chunkerConfig := embedding.DefaultChunkerConfig()
+	if n := indexConfig.MaxChunkChars; n > 0 {
+		chunkerConfig.MaxChunkChars = n
+	}
 
 	// Walk indexed files and create chunks
 	for _, filePath := range filesToEmbed {
@@ -446,72 +530,483 @@ func runEmbed(args []string) {
 		// Get symbols for this file (for smart chunking)
 		syms, _ := idx.ListDefsInFile(relPath)
 
+		// Chunk paths are absolute; the store saves them repo-relative
 		chunks, err := embedding.ChunkFile(filePath, syms, chunkerConfig)
 		if err != nil {
 			continue // Skip files we can't chunk
 		}
 
-		// Fix paths to be relative
-		for i := range chunks {
-			chunks[i].Path = relPath
-		}
-
 		allChunks = append(allChunks, chunks...)
 	}
 
 	logger.Info("found chunks to embed", "chunks", len(allChunks))
 
+	// Fill the keyword index while chunk contents are in hand; a full
+	// embed (nil stale) replaces the whole repo's contents
+	if indexConfig.KeywordIndex {
+		updateKeywordIndex(idx, absPath, stale, allChunks)
+	}
+
 	if len(allChunks) == 0 {
 		logger.Info("no chunks to embed")
 		return
 	}
 
-	// Embed chunks with progress
+	// Embed chunks with progress. Ctrl-C cancels the run; chunks in flight
+	// finish and everything embedded so far is saved.
 	start := time.Now()
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Progress output uses fmt.Fprintf for \r carriage return support
 	progressFn := func(current, total int) {
 		fmt.Fprintf(os.Stderr, "\rembedding chunk %d/%d...", current, total)
 	}
 
-	if err := searcher.IndexChunksParallel(ctx, allChunks, *parallel, progressFn); err != nil {
+	embedResult, err := searcher.IndexChunksParallel(ctx, allChunks, *parallel, progressFn)
+	if err != nil {
 		fmt.Fprintln(os.Stderr) // newline after progress
+		if errors.Is(err, context.Canceled) {
+			count, fileCount, _ := searcher.Store().Stats()
+			logger.Info("embedding interrupted, re-run to resume",
+				"detail", err,
+				"embedded", embedResult.Embedded,
+				"chunks_indexed", count,
+				"files", fileCount,
+				"duration", time.Since(start).Round(time.Millisecond))
+			idx.Close()
+			os.Exit(130)
+		}
 		logger.Error("embedding failed", "error", err)
 		os.Exit(1)
 	}
 
-	// Print stats
-	count, fileCount, err := searcher.Store().Stats()
+	// Print this run's counts, then the store's totals
 	fmt.Fprintln(os.Stderr) // newline after progress
-	if err != nil {
+	logger.Info("embedding complete",
+		"embedded", embedResult.Embedded,
+		"already_embedded", embedResult.CacheHits,
+		"failed", embedResult.Errors,
+		"duration", embedResult.Duration.Round(time.Millisecond))
+	if count, fileCount, err := searcher.Store().Stats(); err != nil {
 		logger.Warn("could not get stats", "error", err)
 	} else {
-		elapsed := time.Since(start)
-		logger.Info("embedding complete",
-			"chunks", count,
-			"files", fileCount,
-			"duration", elapsed.Round(time.Millisecond))
+		logger.Info("index totals", "chunks", count, "files", fileCount)
 	}
 
 	// Update repo config to track current model and dimensions
-	if err := store.SetRepoConfig(absPath, cfg.Model, dbConfig.VectorDimensions); err != nil {
+	if err := store.SetRepoConfig(config.RepoID(absPath), modelName, dbConfig.VectorDimensions); err != nil {
 		logger.Warn("could not update repo config", "error", err)
 	}
+	if err := store.RecordEmbedMetadata(embedder.ProviderID(), dbConfig.VectorDimensions, version); err != nil {
+		logger.Warn("could not record index metadata", "error", err)
+	}
+}
+
+// runEmbedSelfTest checks the configured provider returns sane vectors of
+// wantDims dimensions, printing PASS or FAIL and exiting 1 on failure. A
+// provider that is off or can't be reached fails with the reason.
+func runEmbedSelfTest(cfg embedding.ProviderConfig, wantDims int) {
+	if cfg.Provider == embedding.ProviderOff {
+		fmt.Println("FAIL off: embedding is disabled (set CODETECT_EMBEDDING_PROVIDER)")
+		os.Exit(1)
+	}
+	embedder, err := embedding.NewEmbedder(cfg)
+	if err != nil {
+		fmt.Printf("FAIL %s: %v\n", cfg.Provider, err)
+		os.Exit(1)
+	}
+	if !embedder.Available() {
+		fmt.Printf("FAIL %s: provider not available\n", embedder.ProviderID())
+		os.Exit(1)
+	}
+
+	result, err := embedding.SelfTest(context.Background(), embedder, wantDims)
+	if err != nil {
+		fmt.Printf("FAIL %s: %v\n", embedder.ProviderID(), err)
+		os.Exit(1)
+	}
+	if !result.Passed() {
+		fmt.Printf("FAIL %s (%d dimensions measured)\n", result.ProviderID, result.Dimensions)
+		for _, problem := range result.Problems {
+			fmt.Printf("  %s\n", problem)
+		}
+		os.Exit(1)
+	}
+	fmt.Printf("PASS %s: %d dimensions, related texts %.3f > unrelated %.3f\n",
+		result.ProviderID, result.Dimensions, result.Similar, result.Dissimilar)
+}
+
+func runBenchModels(args []string) {
+	fs := flag.NewFlagSet("bench-models", flag.ExitOnError)
+	models := fs.String("models", "", "Embedding models to compare (comma-separated, required)")
+	provider := fs.String("provider", "", "Embedding provider (ollama, litellm)")
+	casesDir := fs.String("cases", "evals/cases", "Directory containing test case JSONL files")
+	categories := fs.String("category", "", "Filter by category (comma-separated: search,navigate,understand)")
+	k := fs.Int("k", 10, "Score the top k results of each model")
+	parallel := fs.Int("parallel", 10, "Number of parallel embedding workers")
+	fs.IntVar(parallel, "j", 10, "Short for --parallel (like make -j)")
+	fs.Parse(args)
+
+	var modelNames []string
+	for _, m := range strings.Split(*models, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			modelNames = append(modelNames, m)
+		}
+	}
+	if len(modelNames) == 0 {
+		logger.Error("--models is required")
+		os.Exit(1)
+	}
+
+	path := "."
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		logger.Error("invalid path", "error", err)
+		os.Exit(1)
+	}
+
+	cfg := embedding.LoadConfigFromEnv()
+	switch *provider {
+	case "":
+	case "ollama":
+		cfg.Provider = embedding.ProviderOllama
+	case "litellm":
+		cfg.Provider = embedding.ProviderLiteLLM
+	default:
+		logger.Error("unknown provider", "provider", *provider)
+		os.Exit(1)
+	}
+	if cfg.Provider == embedding.ProviderOff {
+		logger.Error("embedding is disabled, set --provider or CODETECT_EMBEDDING_PROVIDER")
+		os.Exit(1)
+	}
+
+	// Load the labeled queries the same way codetect-eval does
+	evalConfig := evals.DefaultConfig()
+	evalConfig.RepoPath = absPath
+	if *categories != "" {
+		evalConfig.Categories = strings.Split(*categories, ",")
+	}
+	absCasesDir, err := filepath.Abs(*casesDir)
+	if err != nil {
+		logger.Error("invalid cases dir", "error", err)
+		os.Exit(1)
+	}
+	runner := evals.NewRunner(evalConfig)
+	cases, err := runner.LoadTestCases(absCasesDir)
+	if err != nil {
+		logger.Error("error loading test cases", "error", err)
+		os.Exit(1)
+	}
+	if len(cases) == 0 {
+		logger.Error("no test cases found", "cases", absCasesDir)
+		os.Exit(1)
+	}
+
+	// Chunk the repo once; every model embeds the same chunks
+	logger.Info("collecting code chunks")
+	chunks, err := collectBenchChunks(absPath)
+	if err != nil {
+		logger.Error("scanning files failed", "error", err)
+		os.Exit(1)
+	}
+	if len(chunks) == 0 {
+		logger.Info("no chunks to embed")
+		return
+	}
+
+	// Each model gets its own throwaway database, removed on exit
+	tmpDir, err := os.MkdirTemp("", "codetect-bench-")
+	if err != nil {
+		logger.Error("creating temp dir failed", "error", err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report := &evals.ModelBenchReport{
+		Timestamp: time.Now(),
+		RepoPath:  absPath,
+		K:         *k,
+		Cases:     len(cases),
+		Chunks:    len(chunks),
+	}
+	for i, model := range modelNames {
+		logger.Info("benchmarking model", "model", model, "chunks", len(chunks), "cases", len(cases))
+		modelCfg := cfg
+		modelCfg.Model = model
+		dbPath := filepath.Join(tmpDir, fmt.Sprintf("model-%d.db", i))
+
+		result, err := benchModel(ctx, modelCfg, dbPath, absPath, chunks, cases, *k, *parallel)
+		if ctx.Err() != nil {
+			logger.Info("benchmark interrupted")
+			os.RemoveAll(tmpDir)
+			os.Exit(130)
+		}
+		if err != nil {
+			logger.Warn("benchmarking model failed", "model", model, "error", err)
+			result.Error = err.Error()
+		}
+		result.Model = model
+		report.Models = append(report.Models, result)
+		os.Remove(dbPath)
+	}
+
+	if err := runner.SaveModelBenchResults(report); err != nil {
+		logger.Warn("could not save results", "error", err)
+	}
+	evals.NewReporter().PrintModelBenchReport(report, os.Stdout)
+}
+
+// collectBenchChunks chunks every code file in the repo, using the symbol
+// index for smart chunking when one exists.
+func collectBenchChunks(absPath string) ([]embedding.Chunk, error) {
+	files, _, err := collectEmbedFiles(absPath, nil, loadGitignore(absPath))
+	if err != nil {
+		return nil, err
+	}
+
+	var idx *symbols.Index
+	dbPath := filepath.Join(config.DataDir(absPath), "symbols.db")
+	if _, err := os.Stat(dbPath); err == nil {
+		if idx, err = symbols.NewIndexWithConfig(db.DefaultConfig(dbPath), absPath); err != nil {
+			logger.Warn("opening symbol index failed, chunking without symbols", "error", err)
+			idx = nil
+		} else {
+			defer idx.Close()
+		}
+	}
+
+	var allChunks []embedding.Chunk
+	chunkerConfig := embedding.DefaultChunkerConfig()
+	if n := config.LoadIndexConfigFromEnv().MaxChunkChars; n > 0 {
+		chunkerConfig.MaxChunkChars = n
+	}
+	for _, filePath := range files {
+		relPath, _ := filepath.Rel(absPath, filePath)
+
+		var syms []symbols.Symbol
+		if idx != nil {
+			syms, _ = idx.ListDefsInFile(relPath)
+		}
+
+		chunks, err := embedding.ChunkFile(filePath, syms, chunkerConfig)
+		if err != nil {
+			continue // Skip files we can't chunk
+		}
+		allChunks = append(allChunks, chunks...)
+	}
+	return allChunks, nil
+}
+
+// benchModel embeds chunks with one model into a fresh SQLite database at
+// dbPath and scores semantic retrieval against cases.
+func benchModel(ctx context.Context, cfg embedding.ProviderConfig, dbPath, repoRoot string, chunks []embedding.Chunk, cases []evals.TestCase, k, parallel int) (evals.ModelBenchResult, error) {
+	var result evals.ModelBenchResult
+
+	embedder, err := embedding.NewEmbedder(cfg)
+	if err != nil {
+		return result, fmt.Errorf("creating embedder: %w", err)
+	}
+	if !embedder.Available() {
+		return result, fmt.Errorf("provider %s not available", cfg.Provider)
+	}
+	result.Dimensions = embedder.Dimensions()
+
+	// No WAL, so the database file alone measures the index size
+	dbCfg := db.DefaultConfig(dbPath)
+	dbCfg.EnableWAL = false
+	database, err := db.Open(dbCfg)
+	if err != nil {
+		return result, fmt.Errorf("opening database: %w", err)
+	}
+	defer database.Close()
+
+	store, err := embedding.NewEmbeddingStoreWithOptions(database, dbCfg.Dialect(), result.Dimensions, repoRoot)
+	if err != nil {
+		return result, fmt.Errorf("creating embedding store: %w", err)
+	}
+	searcher := embedding.NewSemanticSearcher(store, embedder)
+	searcher.SetLogger(logger)
+	searcher.SetCheckpointInterval(0)
+
+	progressFn := func(current, total int) {
+		fmt.Fprintf(os.Stderr, "\rembedding chunk %d/%d...", current, total)
+	}
+	start := time.Now()
+	_, err = searcher.IndexChunksParallel(ctx, chunks, parallel, progressFn)
+	result.EmbedTime = time.Since(start)
+	fmt.Fprintln(os.Stderr) // newline after progress
+	if err != nil {
+		return result, fmt.Errorf("embedding: %w", err)
+	}
+
+	if info, err := os.Stat(dbPath); err == nil {
+		result.IndexBytes = info.Size()
+	}
+
+	retrieve := func(ctx context.Context, query string, limit int) [][]fusion.Result {
+		resp, err := searcher.SearchWithContext(ctx, query, limit)
+		if err != nil || !resp.Available {
+			return nil
+		}
+		list := make([]fusion.Result, 0, len(resp.Results))
+		for _, r := range resp.Results {
+			list = append(list, fusion.Result{
+				ID:      fmt.Sprintf("%s:%d", r.Path, r.StartLine),
+				Path:    r.Path,
+				Line:    r.StartLine,
+				EndLine: r.EndLine,
+				Score:   float64(r.Score),
+				Source:  string(evals.ModeSemantic),
+			})
+		}
+		return [][]fusion.Result{list}
+	}
+	result.Stats = evals.ScoreSemantic(ctx, retrieve, cases, k)
+	return result, nil
+}
+
+// isDir reports whether path is an existing directory.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// collectEmbedFiles returns the code files to embed under root and their
+// total size. If targets is non-empty, only those files and directories are
+// walked. Ignored directories and gitignored paths are skipped.
+func collectEmbedFiles(root string, targets []string, gi *ignore.GitIgnore) ([]string, int64, error) {
+	walkRoots := []string{root}
+	if len(targets) > 0 {
+		walkRoots = nil
+		for _, t := range targets {
+			abs, err := filepath.Abs(t)
+			if err != nil {
+				return nil, 0, err
+			}
+			if rel, err := filepath.Rel(root, abs); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return nil, 0, fmt.Errorf("%s is outside the repository %s", t, root)
+			}
+			if _, err := os.Stat(abs); os.IsNotExist(err) {
+				continue // Deleted file: only its stale embeddings are removed
+			}
+			walkRoots = append(walkRoots, abs)
+		}
+	}
+
+	var files []string
+	var totalSize int64
+	seen := make(map[string]bool)
+	ignoredDirs := config.IgnoredDirs()
+	indexCfg := config.LoadIndexConfigFromEnv()
+	guard := config.NewWalkGuard(root, indexCfg.OneFileSystem)
+
+	for _, walkRoot := range walkRoots {
+		err := filepath.Walk(walkRoot, func(filePath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+
+			relPath, _ := filepath.Rel(root, filePath)
+
+			if info.IsDir() {
+				name := info.Name()
+				// Always skip ignored directories
+				if filePath != root && ignoredDirs[name] {
+					return filepath.SkipDir
+				}
+				// Check gitignore for directories
+				if gi != nil && relPath != "." && gi.MatchesPath(relPath+"/") {
+					return filepath.SkipDir
+				}
+				if indexCfg.BeyondMaxDepth(relPath) {
+					return filepath.SkipDir
+				}
+				if guard.SkipDir(info) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			// Check gitignore for files
+			if gi != nil && gi.MatchesPath(relPath) {
+				return nil
+			}
+
+			// Only count code files
+			if isCodeFile(filePath) && !seen[filePath] {
+				seen[filePath] = true
+				files = append(files, filePath)
+				totalSize += info.Size()
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return files, totalSize, nil
+}
+
+// updateKeywordIndex replaces the keyword index contents of paths, or of
+// every file when paths is nil, with chunks. Failures only warn, since
+// keyword search falls back to ripgrep.
+func updateKeywordIndex(idx *symbols.Index, root string, paths []string, chunks []embedding.Chunk) {
+	if idx.Dialect().Name() != "sqlite" {
+		logger.Warn("keyword index needs SQLite, skipping")
+		return
+	}
+	fts, err := keyword.NewFTSIndex(idx.DBAdapter(), root)
+	if err != nil {
+		logger.Warn("opening keyword index failed", "error", err)
+		return
+	}
+	keywordChunks := make([]keyword.Chunk, len(chunks))
+	for i, c := range chunks {
+		keywordChunks[i] = keyword.Chunk{Path: c.Path, StartLine: c.StartLine, Content: c.Content}
+	}
+	if err := fts.Replace(paths, keywordChunks); err != nil {
+		logger.Warn("updating keyword index failed", "error", err)
+		return
+	}
+	logger.Info("keyword index updated", "chunks", len(keywordChunks))
 }
 
-// formatBytes converts bytes to human-readable format
-func formatBytes(b int64) string {
-	const unit = 1024
-	if b < unit {
-		return fmt.Sprintf("%d B", b)
+// stalePaths returns the repo-relative paths whose embeddings should be
+// cleared before a subset embed: every file about to be embedded, plus
+// targets that no longer exist on disk.
+func stalePaths(root string, targets, files []string) ([]string, error) {
+	var paths []string
+	for _, f := range files {
+		rel, err := filepath.Rel(root, f)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, rel)
 	}
-	div, exp := int64(unit), 0
-	for n := b / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
+	for _, t := range targets {
+		abs, err := filepath.Abs(t)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(abs); os.IsNotExist(err) {
+			rel, err := filepath.Rel(root, abs)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, rel)
+		}
 	}
-	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+	return paths, nil
 }
 
 // isCodeFile returns true for files that should be embedded
@@ -601,7 +1096,11 @@ func runStats(args []string) {
 	fs := flag.NewFlagSet("stats", flag.ExitOnError)
 	useV2 := fs.Bool("v2", false, "Show v2 index stats")
 	jsonOutput := fs.Bool("json", false, "Output stats as JSON")
+	model := fs.String("model", "", "Verify the index was embedded with this model")
+	dimensions := fs.Int("dimensions", 0, "Verify the index was embedded with these vector dimensions")
+	repoID := fs.String("repo-id", "", "Stable repo identifier used as the index key (overrides CODETECT_REPO_ID)")
 	fs.Parse(args)
+	setRepoID(*repoID)
 
 	path := "."
 	if fs.NArg() > 0 {
@@ -626,7 +1125,7 @@ func runStats(args []string) {
 
 	// For SQLite, verify index exists and set path relative to target
 	if dbConfig.Type == db.DatabaseSQLite {
-		dbPath := filepath.Join(absPath, ".codetect", "symbols.db")
+		dbPath := filepath.Join(config.DataDir(absPath), "symbols.db")
 		if _, err := os.Stat(dbPath); os.IsNotExist(err) {
 			logger.Error("no index found, run 'index' first")
 			os.Exit(1)
@@ -668,29 +1167,56 @@ func runStats(args []string) {
 			fmt.Printf("Embeddings: %d chunks from %d files\n", embCount, embFileCount)
 		}
 	}
-}
-
-// runStatsV2 shows statistics from the v2 indexer.
-func runStatsV2(absPath string, jsonOutput bool) {
-	// Load configuration from environment
-	dbConfig := config.LoadDatabaseConfigFromEnv()
-	embConfig := embedding.LoadConfigFromEnv()
 
-	// Build indexer config
-	cfg := &indexer.Config{
-		DBType:            string(dbConfig.Type),
-		Dimensions:        dbConfig.VectorDimensions,
-		EmbeddingProvider: "off", // Don't need embedder for stats
-		EmbeddingModel:    embConfig.Model,
+	verify := *model != "" || *dimensions > 0
+	if store == nil {
+		if verify {
+			logger.Error("cannot verify embedding config", "error", err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	// Set database path/DSN
-	if dbConfig.Type == db.DatabasePostgres {
-		cfg.DSN = dbConfig.DSN
-	} else {
-		cfg.DBPath = filepath.Join(absPath, ".codetect", "index.db")
+	repoCfg, err := store.VerifyRepoConfig(config.RepoID(absPath), *model, *dimensions)
+	if err != nil && !errors.Is(err, embedding.ErrEmbeddingConfigMismatch) {
+		logger.Error("reading embedding config failed", "error", err)
+		os.Exit(1)
 	}
-
+	if repoCfg != nil {
+		fmt.Printf("Embedding model: %s (%d dimensions)\n", repoCfg.Model, repoCfg.Dimensions)
+	}
+	if meta, metaErr := store.EmbedMetadata(); metaErr == nil && meta != nil && meta.ToolVersion != "" {
+		fmt.Printf("Embedded by: codetect-index v%s at %s\n", meta.ToolVersion, meta.EmbeddedAt.Format(time.DateTime))
+	}
+	if !verify {
+		return
+	}
+	if err != nil {
+		logger.Error("embedding config mismatch: searches against this index will return meaningless results",
+			"index_model", repoCfg.Model, "index_dimensions", repoCfg.Dimensions,
+			"configured_model", *model, "configured_dimensions", *dimensions)
+		os.Exit(1)
+	}
+	if repoCfg == nil {
+		logger.Error("no embedding config recorded for this repo, run 'embed' first")
+		os.Exit(1)
+	}
+	fmt.Println("Embedding config matches")
+}
+
+// runStatsV2 shows statistics from the v2 indexer.
+func runStatsV2(absPath string, jsonOutput bool) {
+	cfg := indexer.LoadConfigFromEnv(absPath)
+	cfg.EmbeddingProvider = "off" // Don't need embedder for stats
+
+	// Opening the indexer would create an empty index
+	if cfg.DBType == string(db.DatabaseSQLite) {
+		if _, err := os.Stat(cfg.DBPath); os.IsNotExist(err) {
+			logger.Error("no v2 index found, run 'index --v2' first")
+			os.Exit(1)
+		}
+	}
+
 	// Create indexer
 	idx, err := indexer.New(absPath, cfg)
 	if err != nil {
@@ -724,6 +1250,11 @@ func runStatsV2(absPath string, jsonOutput bool) {
 	fmt.Printf("Unique Hashes:     %d\n", stats.UniqueHashes)
 	fmt.Printf("Files:             %d\n", stats.FileCount)
 	fmt.Printf("Cached Embeddings: %d\n", stats.CachedEmbeddings)
+	fmt.Printf("Not Embedded:      %d\n", stats.MissingEmbeddings)
+	if lookups := stats.CacheHits + stats.CacheMisses; lookups > 0 {
+		fmt.Printf("Cache Hit Ratio:   %.1f%% (%d hits, %d misses, all runs)\n",
+			stats.CacheHitRatio*100, stats.CacheHits, stats.CacheMisses)
+	}
 
 	if stats.IndexedVectors > 0 {
 		indexType := "brute-force"
@@ -733,19 +1264,836 @@ func runStatsV2(absPath string, jsonOutput bool) {
 		fmt.Printf("Indexed Vectors:   %d (%s)\n", stats.IndexedVectors, indexType)
 	}
 
-	if len(stats.ByNodeType) > 0 {
-		fmt.Printf("\nBy Node Type:\n")
-		for nodeType, count := range stats.ByNodeType {
-			fmt.Printf("  %-20s %d\n", nodeType+":", count)
+	printCounts("By Node Type", stats.ByNodeType, stats.TotalChunks)
+	printCounts("By Language", stats.ByLanguage, stats.TotalChunks)
+
+	// Flag an index that won't search the way its counts suggest
+	if stats.MissingEmbeddings > 0 {
+		fmt.Printf("\nWarning: %d unique chunks have no embedding, run 'index --v2' with an embedding provider\n", stats.MissingEmbeddings)
+	}
+	// The vector index holds one vector per hash, and the cache may be
+	// shared with other repos, so compare against this repo's embedded hashes
+	if embedded := stats.UniqueHashes - stats.MissingEmbeddings; stats.IndexedVectors > 0 && stats.IndexedVectors != embedded {
+		fmt.Printf("\nWarning: vector index holds %d vectors but %d unique chunks have embeddings\n", stats.IndexedVectors, embedded)
+	}
+}
+
+// printCounts prints a titled breakdown, largest first, with each count's
+// share of total.
+func printCounts(title string, counts map[string]int, total int) {
+	if len(counts) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	fmt.Printf("\n%s:\n", title)
+	for _, k := range keys {
+		pct := 0.0
+		if total > 0 {
+			pct = float64(counts[k]) / float64(total) * 100
+		}
+		fmt.Printf("  %-24s %6d  %5.1f%%\n", k+":", counts[k], pct)
+	}
+}
+
+// runRecent lists files indexed within the --since window, most recent first.
+func runRecent(args []string) {
+	fs := flag.NewFlagSet("recent", flag.ExitOnError)
+	since := fs.Duration("since", time.Hour, "Show files indexed within this duration")
+	jsonOutput := fs.Bool("json", false, "Output results as JSON")
+	repoID := fs.String("repo-id", "", "Stable repo identifier used as the index key (overrides CODETECT_REPO_ID)")
+	fs.Parse(args)
+	setRepoID(*repoID)
+
+	path := "."
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		logger.Error("invalid path", "error", err)
+		os.Exit(1)
+	}
+
+	dbConfig := config.LoadDatabaseConfigFromEnv()
+	if dbConfig.Type == db.DatabaseSQLite {
+		dbPath := filepath.Join(config.DataDir(absPath), "symbols.db")
+		if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+			logger.Error("no index found, run 'index' first")
+			os.Exit(1)
+		}
+		dbConfig.Path = dbPath
+	}
+
+	idx, err := symbols.NewIndexWithConfig(dbConfig.ToDBConfig(), absPath)
+	if err != nil {
+		logger.Error("opening index failed", "error", err)
+		os.Exit(1)
+	}
+	defer idx.Close()
+
+	records, err := idx.RecentlyIndexed(time.Now().Add(-*since))
+	if err != nil {
+		logger.Error("querying recent files failed", "error", err)
+		os.Exit(1)
+	}
+
+	if *jsonOutput {
+		if records == nil {
+			records = []symbols.FileRecord{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(records); err != nil {
+			logger.Error("encoding JSON failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(records) == 0 {
+		fmt.Printf("No files indexed in the last %s\n", *since)
+		return
+	}
+
+	for _, r := range records {
+		fmt.Printf("%s  %s\n", r.IndexedAt.Format(time.DateTime), r.Path)
+	}
+	fmt.Printf("\n%d files indexed in the last %s\n", len(records), *since)
+}
+
+// runListSymbols lists the repo's symbols of one kind, ordered by file.
+func runListSymbols(args []string) {
+	fs := flag.NewFlagSet("list-symbols", flag.ExitOnError)
+	kind := fs.String("kind", "", "Symbol kind to list, e.g. interface or struct (required)")
+	group := fs.Bool("group", false, "Group symbols under their files")
+	limit := fs.Int("limit", 100, "Maximum number of symbols")
+	offset := fs.Int("offset", 0, "Skip this many symbols, to page through them")
+	jsonOutput := fs.Bool("json", false, "Output results as JSON")
+	repoID := fs.String("repo-id", "", "Stable repo identifier used as the index key (overrides CODETECT_REPO_ID)")
+	fs.Parse(args)
+	setRepoID(*repoID)
+
+	if *kind == "" {
+		logger.Error("--kind is required")
+		os.Exit(1)
+	}
+
+	path := "."
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		logger.Error("invalid path", "error", err)
+		os.Exit(1)
+	}
+
+	dbConfig := config.LoadDatabaseConfigFromEnv()
+	if dbConfig.Type == db.DatabaseSQLite {
+		dbPath := filepath.Join(config.DataDir(absPath), "symbols.db")
+		if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+			logger.Error("no index found, run 'index' first")
+			os.Exit(1)
+		}
+		dbConfig.Path = dbPath
+	}
+
+	idx, err := symbols.NewIndexWithConfig(dbConfig.ToDBConfig(), absPath)
+	if err != nil {
+		logger.Error("opening index failed", "error", err)
+		os.Exit(1)
+	}
+	defer idx.Close()
+
+	syms, hasMore, err := idx.ListByKind(*kind, *offset, *limit)
+	if err != nil {
+		logger.Error("listing symbols failed", "error", err)
+		os.Exit(1)
+	}
+
+	if *jsonOutput {
+		result := symbols.ListSymbolsResult{Kind: symbols.CanonicalKind(*kind), Symbols: syms, HasMore: hasMore}
+		if *group {
+			result.Symbols = nil
+			result.Files = symbols.GroupByFile(syms)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			logger.Error("encoding JSON failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(syms) == 0 {
+		fmt.Printf("No %s symbols indexed\n", symbols.CanonicalKind(*kind))
+		return
+	}
+
+	if *group {
+		for _, f := range symbols.GroupByFile(syms) {
+			fmt.Println(f.Path)
+			for _, s := range f.Symbols {
+				fmt.Printf("  %-6d %s\n", s.Line, s.Name)
+			}
+		}
+	} else {
+		for _, s := range syms {
+			fmt.Printf("%s:%d  %s\n", s.Path, s.Line, s.Name)
+		}
+	}
+	if hasMore {
+		fmt.Printf("\nMore symbols follow, use --offset %d for the next page\n", *offset+len(syms))
+	}
+}
+
+// runDiff previews what an incremental v2 index would do by diffing the
+// current Merkle tree against the stored one, without indexing anything.
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Output changes as JSON")
+	maxDepth := fs.Int("max-depth", -1, "Skip directories more than N levels below the repo root, 0 = unlimited (overrides CODETECT_INDEX_MAX_DEPTH)")
+	oneFileSystem := fs.Bool("one-file-system", false, "Don't descend into directories on other filesystems (overrides CODETECT_INDEX_ONE_FILE_SYSTEM)")
+	fs.Parse(args)
+	setMaxDepth(*maxDepth)
+	setOneFileSystem(*oneFileSystem)
+
+	path := "."
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		logger.Error("invalid path", "error", err)
+		os.Exit(1)
+	}
+
+	changes, indexed, err := indexer.PendingChanges(absPath, indexer.LoadConfigFromEnv(absPath))
+	if err != nil {
+		logger.Error("diffing merkle tree failed", "error", err)
+		os.Exit(1)
+	}
+
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(map[string]interface{}{
+			"indexed":  indexed,
+			"added":    changes.Added,
+			"modified": changes.Modified,
+			"deleted":  changes.Deleted,
+		}); err != nil {
+			logger.Error("encoding JSON failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if !indexed {
+		fmt.Printf("No stored Merkle tree, the next index will be a full index of %d files\n", len(changes.Added))
+		return
+	}
+	if changes.IsEmpty() {
+		fmt.Println("No changes since the last index")
+		return
+	}
+
+	for _, f := range changes.Added {
+		fmt.Printf("A  %s\n", f)
+	}
+	for _, f := range changes.Modified {
+		fmt.Printf("M  %s\n", f)
+	}
+	for _, f := range changes.Deleted {
+		fmt.Printf("D  %s\n", f)
+	}
+	fmt.Printf("\n%d added, %d modified, %d deleted\n", len(changes.Added), len(changes.Modified), len(changes.Deleted))
+}
+
+// runMigrateV2 carries a repo's v1 embeddings over to the v2 cache and
+// location store so v2 search can use them without re-embedding.
+func runMigrateV2(args []string) {
+	fs := flag.NewFlagSet("migrate-v2", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Output results as JSON")
+	repoID := fs.String("repo-id", "", "Stable repo identifier used as the index key (overrides CODETECT_REPO_ID)")
+	fs.Parse(args)
+	setRepoID(*repoID)
+
+	path := "."
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		logger.Error("invalid path", "error", err)
+		os.Exit(1)
+	}
+
+	// Open the v1 embedding store
+	dbConfig := config.LoadDatabaseConfigFromEnv()
+	if dbConfig.Type == db.DatabaseSQLite {
+		dbPath := filepath.Join(config.DataDir(absPath), "symbols.db")
+		if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+			logger.Error("no v1 index found, nothing to migrate")
+			os.Exit(1)
 		}
+		dbConfig.Path = dbPath
+	}
+
+	v1, err := symbols.NewIndexWithConfig(dbConfig.ToDBConfig(), absPath)
+	if err != nil {
+		logger.Error("opening v1 index failed", "error", err)
+		os.Exit(1)
 	}
+	defer v1.Close()
 
-	if len(stats.ByLanguage) > 0 {
-		fmt.Printf("\nBy Language:\n")
-		for lang, count := range stats.ByLanguage {
-			fmt.Printf("  %-20s %d\n", lang+":", count)
+	store, err := embedding.NewEmbeddingStoreWithOptions(v1.DBAdapter(), v1.Dialect(), dbConfig.VectorDimensions, absPath)
+	if err != nil {
+		logger.Error("opening v1 embedding store failed", "error", err)
+		os.Exit(1)
+	}
+	records, err := store.GetAll()
+	if err != nil && !errors.Is(err, embedding.ErrNoEmbeddingsForDimension) {
+		logger.Error("reading v1 embeddings failed", "error", err)
+		os.Exit(1)
+	}
+	if len(records) == 0 {
+		logger.Error("no v1 embeddings found, run 'embed' first")
+		os.Exit(1)
+	}
+
+	// Queries are embedded with the v2 embedder, so migrate only the
+	// configured model's vectors when the v1 index holds them
+	cfg := indexer.LoadConfigFromEnv(absPath)
+	if model := v1ModelFor(records, cfg.EmbeddingModel); model != "" {
+		records, err = store.GetAllForModel(model)
+		if err != nil {
+			logger.Error("reading v1 embeddings failed", "error", err)
+			os.Exit(1)
 		}
 	}
+	if v1Model := embedding.ModelName(records[0].Model); cfg.EmbeddingModel != "" && v1Model != cfg.EmbeddingModel {
+		logger.Warn("v1 embeddings use a different model than the v2 config, search quality will suffer",
+			"v1_model", v1Model, "v2_model", cfg.EmbeddingModel)
+	}
+	cfg.EmbeddingProvider = "off" // Nothing is embedded
+
+	idx, err := indexer.New(absPath, cfg)
+	if err != nil {
+		logger.Error("opening v2 indexer failed", "error", err)
+		os.Exit(1)
+	}
+	defer idx.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	result, err := idx.MigrateV1(ctx, records)
+	if err != nil {
+		logger.Error("migration failed", "error", err)
+		os.Exit(1)
+	}
+
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			logger.Error("encoding JSON failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	logger.Info("migration complete",
+		"records", result.Records,
+		"migrated", result.Migrated,
+		"vectors", result.Vectors,
+		"skipped", result.Skipped,
+		"duration", result.Duration.Round(time.Millisecond))
+}
+
+// v1ModelFor returns the provider ID under which records hold embeddings
+// from model, or "" if none or more than one provider does.
+func v1ModelFor(records []embedding.EmbeddingRecord, model string) string {
+	var found string
+	for _, rec := range records {
+		if rec.Model == found || embedding.ModelName(rec.Model) != model {
+			continue
+		}
+		if found != "" {
+			return ""
+		}
+		found = rec.Model
+	}
+	return found
+}
+
+// runExport writes a repo's index to a portable gzip-compressed tar archive.
+// gzip rather than zstd keeps the archive format in the standard library
+// (archive/tar and compress/gzip), without a compression dependency.
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	repoID := fs.String("repo-id", "", "Stable repo identifier used as the index key (overrides CODETECT_REPO_ID)")
+	fs.Parse(args)
+	setRepoID(*repoID)
+
+	if fs.NArg() != 2 {
+		logger.Error("usage: codetect-index export [options] <path> <out.tar.gz> (gzip, not zstd, to avoid a compression dependency)")
+		os.Exit(1)
+	}
+
+	absPath, err := filepath.Abs(fs.Arg(0))
+	if err != nil {
+		logger.Error("invalid path", "error", err)
+		os.Exit(1)
+	}
+
+	// Read through the configured backend, so a PostgreSQL index can be exported
+	dbConfig := config.LoadDatabaseConfigFromEnv()
+	if dbConfig.Type == db.DatabaseSQLite {
+		dbPath := filepath.Join(config.DataDir(absPath), "symbols.db")
+		if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+			logger.Error("no index found, run 'index' first")
+			os.Exit(1)
+		}
+		dbConfig.Path = dbPath
+	}
+
+	idx, err := symbols.NewIndexWithConfig(dbConfig.ToDBConfig(), absPath)
+	if err != nil {
+		logger.Error("opening index failed", "error", err)
+		os.Exit(1)
+	}
+	defer idx.Close()
+
+	store, err := embedding.NewEmbeddingStoreWithOptions(idx.DBAdapter(), idx.Dialect(), dbConfig.VectorDimensions, absPath)
+	if err != nil {
+		logger.Error("opening embedding store failed", "error", err)
+		os.Exit(1)
+	}
+
+	out, err := os.Create(fs.Arg(1))
+	if err != nil {
+		logger.Error("creating archive failed", "error", err)
+		os.Exit(1)
+	}
+
+	manifest, err := snapshot.Export(out, idx, store, config.RepoID(absPath))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(fs.Arg(1))
+		logger.Error("export failed", "error", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Exported %d symbols, %d files, %d embeddings to %s\n",
+		manifest.Symbols, manifest.Files, manifest.Embeddings, fs.Arg(1))
+}
+
+// runImport loads an archive written by export into a local SQLite index.
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	repoID := fs.String("repo-id", "", "Stable repo identifier used as the index key (overrides CODETECT_REPO_ID)")
+	fs.Parse(args)
+	setRepoID(*repoID)
+
+	if fs.NArg() != 2 {
+		logger.Error("usage: codetect-index import [options] <in.tar.gz> <path>")
+		os.Exit(1)
+	}
+
+	absPath, err := filepath.Abs(fs.Arg(1))
+	if err != nil {
+		logger.Error("invalid path", "error", err)
+		os.Exit(1)
+	}
+
+	in, err := os.Open(fs.Arg(0))
+	if err != nil {
+		logger.Error("opening archive failed", "error", err)
+		os.Exit(1)
+	}
+	defer in.Close()
+
+	// Always import into the local SQLite index, whatever backend built the archive
+	dataDir := config.DataDir(absPath)
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		logger.Error("creating data directory failed", "error", err)
+		os.Exit(1)
+	}
+	dbConfig := config.LoadDatabaseConfigFromEnv()
+	dbConfig.Type = db.DatabaseSQLite
+	dbConfig.Path = filepath.Join(dataDir, "symbols.db")
+
+	idx, err := symbols.NewIndexWithConfig(dbConfig.ToDBConfig(), absPath)
+	if err != nil {
+		logger.Error("opening index failed", "error", err)
+		os.Exit(1)
+	}
+	defer idx.Close()
+
+	store, err := embedding.NewEmbeddingStoreWithOptions(idx.DBAdapter(), idx.Dialect(), dbConfig.VectorDimensions, absPath)
+	if err != nil {
+		logger.Error("opening embedding store failed", "error", err)
+		os.Exit(1)
+	}
+
+	manifest, err := snapshot.Import(in, idx, store)
+	if err != nil {
+		logger.Error("import failed", "error", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Imported %d symbols, %d files, %d embeddings from %s (built %s)\n",
+		manifest.Symbols, manifest.Files, manifest.Embeddings, manifest.RepoID,
+		manifest.CreatedAt.Local().Format(time.DateTime))
+}
+
+// runRekey moves a repo's index rows from an old repo_root to a new one,
+// rescuing a path-keyed index after the checkout was moved.
+func runRekey(args []string) {
+	fs := flag.NewFlagSet("rekey", flag.ExitOnError)
+	from := fs.String("from", "", "Old repo_root the index was built under (required)")
+	to := fs.String("to", "", "New repo path; must exist (required)")
+	fs.Parse(args)
+
+	if *from == "" || *to == "" {
+		logger.Error("usage: codetect-index rekey --from <old> --to <new>")
+		os.Exit(1)
+	}
+
+	info, err := os.Stat(*to)
+	if err != nil || !info.IsDir() {
+		logger.Error("new repo root is not a directory", "path", *to)
+		os.Exit(1)
+	}
+	newRoot := config.NormalizeRepoRoot(*to)
+	newKey := config.RepoID(newRoot)
+	// The old path is usually gone, so it's cleaned but not resolved
+	oldKey := *from
+	if filepath.IsAbs(oldKey) {
+		oldKey = filepath.Clean(oldKey)
+	}
+
+	dbConfig := config.LoadDatabaseConfigFromEnv()
+	var dbCfgs []db.Config
+	// Out-of-tree data directory to rename once it's known to hold the index
+	var moveFrom, moveTo string
+	if dbConfig.Type == db.DatabaseSQLite {
+		// An in-tree .codetect moves with the repo; an out-of-tree data
+		// directory is named after the old path and needs moving too
+		dataDir := config.DataDir(newRoot)
+		indexDir := dataDir
+		if oldDir := config.DataDir(oldKey); !config.InTreeDataDir() && oldDir != dataDir {
+			if _, err := os.Stat(dataDir); os.IsNotExist(err) {
+				if _, err := os.Stat(oldDir); err == nil {
+					indexDir = oldDir
+					moveFrom, moveTo = oldDir, dataDir
+				}
+			}
+		}
+		// Symbols and v1 embeddings, then the v2 index
+		for _, name := range []string{"symbols.db", "index.db"} {
+			path := filepath.Join(indexDir, name)
+			if _, err := os.Stat(path); err == nil {
+				dbCfgs = append(dbCfgs, db.DefaultConfig(path))
+			}
+		}
+		if len(dbCfgs) == 0 {
+			logger.Error("no index found", "dir", indexDir)
+			os.Exit(1)
+		}
+	} else {
+		dbCfgs = append(dbCfgs, dbConfig.ToDBConfig())
+	}
+
+	ctx := context.Background()
+	databases := make([]db.DB, len(dbCfgs))
+	openAll := func() {
+		for i, cfg := range dbCfgs {
+			database, err := db.Open(cfg)
+			if err != nil {
+				logger.Error("opening database failed", "error", err)
+				os.Exit(1)
+			}
+			databases[i] = database
+		}
+	}
+	closeAll := func() {
+		for _, database := range databases {
+			database.Close()
+		}
+	}
+
+	// Count before touching anything, so a wrong --from leaves the data
+	// directory where it was
+	openAll()
+	var oldRows int64
+	for i, database := range databases {
+		counts, err := db.CountRepoRows(ctx, database, dbCfgs[i].Dialect(), oldKey)
+		if err != nil {
+			logger.Error("counting indexed rows failed", "error", err)
+			os.Exit(1)
+		}
+		for _, n := range counts {
+			oldRows += n
+		}
+	}
+	if oldRows == 0 {
+		logger.Error("nothing is indexed under the old repo root", "from", oldKey)
+		os.Exit(1)
+	}
+
+	if moveFrom != "" {
+		closeAll()
+		if err := os.Rename(moveFrom, moveTo); err != nil {
+			logger.Error("moving data directory failed", "error", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Moved %s to %s\n", moveFrom, moveTo)
+		for i := range dbCfgs {
+			dbCfgs[i].Path = filepath.Join(moveTo, filepath.Base(dbCfgs[i].Path))
+		}
+		openAll()
+	}
+	defer closeAll()
+
+	var total int64
+	for i, database := range databases {
+		updated, err := db.RekeyRepo(ctx, database, dbCfgs[i].Dialect(), oldKey, newKey)
+		if err != nil {
+			logger.Error("rekey failed", "error", err)
+			os.Exit(1)
+		}
+		for _, table := range slices.Sorted(maps.Keys(updated)) {
+			fmt.Printf("  %-24s %d rows\n", table, updated[table])
+			total += updated[table]
+		}
+	}
+	fmt.Printf("Rekeyed %d rows from %s to %s\n", total, oldKey, newKey)
+}
+
+// runRepack rewrites a SQLite index's stored vectors in another format
+// and makes it the format later embeds write.
+func runRepack(args []string) {
+	fs := flag.NewFlagSet("repack", flag.ExitOnError)
+	format := fs.String("format", "binary", "Vector format to store: binary (packed float32) or json")
+	repoID := fs.String("repo-id", "", "Stable repo identifier used as the index key (overrides CODETECT_REPO_ID)")
+	fs.Parse(args)
+	setRepoID(*repoID)
+
+	vectorFormat, err := embedding.ParseVectorFormat(*format)
+	if err != nil {
+		logger.Error("invalid --format", "error", err)
+		os.Exit(1)
+	}
+
+	path := "."
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		logger.Error("invalid path", "error", err)
+		os.Exit(1)
+	}
+
+	dbConfig := config.LoadDatabaseConfigFromEnv()
+	if dbConfig.Type != db.DatabaseSQLite {
+		logger.Error("repack only applies to SQLite indexes, PostgreSQL stores native vectors")
+		os.Exit(1)
+	}
+	dbPath := filepath.Join(config.DataDir(absPath), "symbols.db")
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		logger.Error("no index found, run 'index' first")
+		os.Exit(1)
+	}
+	dbConfig.Path = dbPath
+
+	idx, err := symbols.NewIndexWithConfig(dbConfig.ToDBConfig(), absPath)
+	if err != nil {
+		logger.Error("opening index failed", "error", err)
+		os.Exit(1)
+	}
+	defer idx.Close()
+
+	store, err := embedding.NewEmbeddingStoreWithOptions(idx.DBAdapter(), idx.Dialect(), dbConfig.VectorDimensions, absPath)
+	if err != nil {
+		logger.Error("opening embedding store failed", "error", err)
+		os.Exit(1)
+	}
+
+	repacked, err := store.Repack(vectorFormat)
+	if err != nil {
+		logger.Error("repack failed", "error", err)
+		os.Exit(1)
+	}
+	// Rewritten rows leave free pages behind until the file is vacuumed
+	if _, err := idx.DBAdapter().Exec("VACUUM"); err != nil {
+		logger.Warn("vacuum after repack failed", "error", err)
+	}
+
+	fmt.Printf("Repacked %d embeddings as %s\n", repacked, vectorFormat)
+}
+
+// runWatch watches a single repository in the foreground and re-runs the
+// incremental index (and embed for v1) after changes settle, until Ctrl-C.
+func runWatch(args []string) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	useV2 := fs.Bool("v2", false, "Use v2 indexer (AST chunking, Merkle tree)")
+	noEmbed := fs.Bool("no-embed", false, "Skip embedding after v1 reindex")
+	debounceMs := fs.Int("debounce", 500, "Milliseconds of inactivity before reindexing")
+	verbose := fs.Bool("verbose", false, "Enable verbose output")
+	fs.BoolVar(verbose, "v", false, "Short for --verbose")
+	repoID := fs.String("repo-id", "", "Stable repo identifier used as the index key (overrides CODETECT_REPO_ID)")
+	maxDepth := fs.Int("max-depth", -1, "Skip directories more than N levels below the repo root, 0 = unlimited (overrides CODETECT_INDEX_MAX_DEPTH)")
+	oneFileSystem := fs.Bool("one-file-system", false, "Don't descend into directories on other filesystems (overrides CODETECT_INDEX_ONE_FILE_SYSTEM)")
+	fs.Parse(args)
+	setRepoID(*repoID)
+	setMaxDepth(*maxDepth)
+	setOneFileSystem(*oneFileSystem)
+
+	path := "."
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		logger.Error("invalid path", "error", err)
+		os.Exit(1)
+	}
+
+	// Re-invoke this binary for each pass so a failing run doesn't end the watch
+	self, err := os.Executable()
+	if err != nil {
+		logger.Error("cannot locate codetect-index executable", "error", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	w, err := watch.New(time.Duration(*debounceMs)*time.Millisecond, logger)
+	if err != nil {
+		logger.Error("creating watcher failed", "error", err)
+		os.Exit(1)
+	}
+	defer w.Close()
+
+	count, err := w.AddTree(absPath)
+	if err != nil {
+		logger.Error("watching path failed", "path", absPath, "error", err)
+		os.Exit(1)
+	}
+
+	reindex := func() {
+		start := time.Now()
+		fmt.Fprintf(os.Stderr, "[codetect-index] indexing %s\n", absPath)
+
+		indexArgs := []string{"index"}
+		if *useV2 {
+			indexArgs = append(indexArgs, "--v2")
+		}
+		if *verbose {
+			indexArgs = append(indexArgs, "--verbose")
+		}
+		if err := runSelf(ctx, self, append(indexArgs, absPath)...); err != nil {
+			if ctx.Err() == nil {
+				logger.Error("index failed", "path", absPath, "error", err)
+			}
+			return
+		}
+
+		// v2 embeds as part of indexing; v1 needs a separate pass
+		if !*useV2 && !*noEmbed {
+			if err := runSelf(ctx, self, "embed", absPath); err != nil {
+				if ctx.Err() == nil {
+					logger.Error("embed failed", "path", absPath, "error", err)
+				}
+				return
+			}
+		}
+
+		fmt.Fprintf(os.Stderr, "[codetect-index] up to date (%s), watching for changes...\n", time.Since(start).Round(time.Millisecond))
+	}
+
+	// Serialize passes; changes during a pass coalesce into one follow-up run
+	pending := make(chan struct{}, 1)
+	pending <- struct{}{} // Initial pass so the index is current before watching
+
+	go w.Run(ctx, func(p string) string {
+		if watch.IsSubpath(p, absPath) {
+			return absPath
+		}
+		return ""
+	}, func(string) {
+		select {
+		case pending <- struct{}{}:
+		default:
+		}
+	})
+
+	fmt.Fprintf(os.Stderr, "[codetect-index] watching %s (%d directories), press Ctrl-C to stop\n", absPath, count)
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(os.Stderr, "\n[codetect-index] stopped watching")
+			return
+		case <-pending:
+			reindex()
+		}
+	}
+}
+
+// setRepoID applies a --repo-id flag. It is exported through the environment
+// so every store, and any subcommand we spawn, keys the index the same way.
+func setRepoID(id string) {
+	if id != "" {
+		os.Setenv("CODETECT_REPO_ID", id)
+	}
+}
+
+// setMaxDepth applies a --max-depth flag (negative when not given) through
+// the environment, like setRepoID, so every walk honours it
+func setMaxDepth(depth int) {
+	if depth >= 0 {
+		os.Setenv("CODETECT_INDEX_MAX_DEPTH", strconv.Itoa(depth))
+	}
+}
+
+// setOneFileSystem applies a --one-file-system flag through the
+// environment, like setMaxDepth
+func setOneFileSystem(oneFileSystem bool) {
+	if oneFileSystem {
+		os.Setenv("CODETECT_INDEX_ONE_FILE_SYSTEM", "true")
+	}
+}
+
+// runSelf runs a codetect-index subcommand, streaming its output to the terminal
+func runSelf(ctx context.Context, self string, args ...string) error {
+	cmd := exec.CommandContext(ctx, self, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
 }
 
 func printUsage() {
@@ -754,25 +2102,120 @@ func printUsage() {
 Usage:
   codetect-index index [options] [path]   Index symbols using ctags
   codetect-index embed [options] [path]   Generate embeddings
+  codetect-index embed [options] <file|dir>...
+                                          Re-embed only the given files (repo root: cwd or --root)
   codetect-index stats [options] [path]   Show index statistics
+  codetect-index watch [options] [path]   Watch and reindex on changes (foreground)
+  codetect-index recent [options] [path]  List recently indexed files
+  codetect-index diff [options] [path]    Preview files the next v2 index would process
+  codetect-index list-symbols --kind <kind> [options] [path]
+                                          List every symbol of one kind, by file
+  codetect-index search [options] <query> Browse hybrid search results (run in the repo root)
+  codetect-index migrate-v2 [options] [path]
+                                          Carry v1 embeddings over to the v2 index
+  codetect-index export [options] <path> <out.tar.gz>
+                                          Export the index to a gzip-compressed tar archive
+                                          (gzip, not zstd: no extra compression dependency)
+  codetect-index import [options] <in.tar.gz> <path>
+                                          Load an exported archive into a local SQLite index
+  codetect-index repack [options] [path]  Rewrite stored SQLite vectors in another format
+  codetect-index rekey --from <old> --to <new>
+                                          Move an index to a repo's new path after mv
+  codetect-index bench-models --models a,b [options] [path]
+                                          Compare embedding models on eval test cases
   codetect-index version                  Print version
   codetect-index help                     Show this help
 
 Index Options:
   --force, -f    Force full reindex (default: incremental)
   --v2           Use v2 indexer (AST chunking, Merkle tree change detection)
+  --reembed-model
+                 With --v2, re-embed every chunk cached under another model
+                 (after changing CODETECT_EMBEDDING_MODEL) without a full --force
   --verbose, -v  Enable verbose output
   --json         Output results as JSON
 
 Stats Options:
   --v2           Show v2 index statistics
   --json         Output stats as JSON
+  --model        Fail unless the index was embedded with this model
+  --dimensions   Fail unless the index was embedded with these dimensions
+
+Recent Options:
+  --since        Show files indexed within this duration (default: 1h)
+  --json         Output results as JSON
+
+Diff Options:
+  --json         Output added/modified/deleted files as JSON
+
+List-symbols Options:
+  --kind         Symbol kind to list: function, method, class, struct, interface,
+                 enum, type, module, variable, constant (required)
+  --group        Group symbols under their files
+  --limit        Maximum number of symbols (default: 100)
+  --offset       Skip this many symbols, to page through them
+  --json         Output results as JSON
+
+Search Options:
+  --format       tui (browse with the arrow keys, enter prints path:line, q quits),
+                 quickfix (path:line:col: message lines for vim's :cfile, emacs
+                 compile-mode or VSCode problem matchers), jsonl (one JSON object
+                 per result, for pipelines) or json (default: tui)
+  --limit        Maximum number of results (default: 20)
+  --rerank       Apply cross-encoder reranking
+  --context      Lines of context around each semantic snippet
+
+Migrate-v2 Options:
+  --json         Output results as JSON
+
+Watch Options:
+  --v2           Use v2 indexer (embeds as part of indexing)
+  --no-embed     Skip the embed pass after v1 reindexing
+  --debounce     Milliseconds of inactivity before reindexing (default: 500)
+  --verbose, -v  Enable verbose output
 
 Embed Options:
   --force, -f    Re-embed all chunks (ignore cache)
   --provider     Embedding provider (ollama, litellm, off)
   --model        Embedding model (provider-specific default if empty)
   --parallel, -j Number of parallel workers (default: 10)
+  --root         Repository root when embedding a subset (default: cwd)
+  --checkpoint   Save embeddings every N chunks so an interrupted run can
+                 resume where it stopped (default: 500, 0 = only at the end)
+  --selftest     Embed a few known texts and check the vectors are sane: the
+                 configured size, not zero or NaN, related texts closest.
+                 Prints PASS or FAIL and exits without embedding
+
+Repack Options:
+  --format       Vector format: binary (packed float32 BLOBs, about a third
+                 the size of JSON and faster to load) or json (default: binary).
+                 Later embeds keep writing the chosen format
+
+Rekey Options:
+  --from         repo_root the index was built under: the old path, or the key
+                 shown by 'codetect-index stats' (required)
+  --to           The repo's new path, which must exist (required). Every
+                 repo_root row (symbols, files, imports, embeddings, v2
+                 locations, repo config) moves in one transaction per database
+
+Bench-models Options:
+  --models       Embedding models to compare (comma-separated, required)
+  --provider     Embedding provider (ollama, litellm)
+  --cases        Test case directory (default: evals/cases)
+  --category     Filter test cases by category (comma-separated)
+  --k            Score the top k results of each model (default: 10)
+  --parallel, -j Number of parallel workers (default: 10)
+
+Common Options:
+  --repo-id      Stable repo identifier used as the index key. By default the
+                 key is derived from the git origin remote (e.g.
+                 github.com/org/repo), falling back to the absolute path
+  --max-depth    Skip directories more than N levels below the repo root, a
+                 guard for deeply nested vendored trees (index, embed, watch,
+                 diff; default: unlimited)
+  --one-file-system
+                 Don't cross into other mounts under the repo root, like
+                 rsync's option of the same name (index, embed, watch, diff)
 
 v2 Indexer Features:
   The v2 indexer (--v2) provides significant improvements:
@@ -794,13 +2237,39 @@ Embedding Environment Variables:
   CODETECT_LITELLM_URL          LiteLLM URL [default: http://localhost:4000]
   CODETECT_LITELLM_API_KEY      LiteLLM API key
   CODETECT_EMBEDDING_MODEL      Model override
+  CODETECT_EMBEDDING_TRUNCATE_DIM  Keep the first N dimensions of Matryoshka models
+                                (nomic-embed-text, text-embedding-3-*), also the
+                                stored vector size [default: full size]
+  CODETECT_EMBEDDING_FALLBACK   Providers to try when the primary fails, in
+                                order (e.g. ollama); must serve the same model
+  CODETECT_INDEX_DOC_COMMENTS   Embed doc comments separately (v2) [default: false]
+
+Indexing Environment Variables:
+  CODETECT_IGNORE_DIRS          Extra directory names to skip, comma-separated
+  CODETECT_INDEX_CONTENT_HASH   Detect v1 symbol changes by content hash, not mtime [default: false]
+  CODETECT_CTAGS_OPTIONS        Extra ctags arguments, space-separated, applied after the
+                                defaults (e.g. "--kinds-go=-p --extras=-q")
+  CODETECT_INDEX_MAX_DEPTH      Same as --max-depth, also bounds daemon watches [default: 0, unlimited]
+  CODETECT_INDEX_ONE_FILE_SYSTEM  Same as --one-file-system, also bounds daemon watches [default: false]
+  CODETECT_INDEX_MAX_CHUNK_CHARS  Largest embedded chunk in bytes; bigger chunks are split [default: 6000]
+  CODETECT_INDEX_KEYWORD        Build a SQLite FTS5 keyword index during embed; literal
+                                keyword searches use it before ripgrep [default: false]
+  CODETECT_INDEX_WORKERS        Files chunked and batches embedded at once by the v2
+                                indexer [default: 4]
+  CODETECT_INDEX_BATCH_BYTES    File content read and chunked per v2 indexing batch,
+                                in bytes [default: 33554432, 32 MiB]
+  CODETECT_REPO_ID              Stable repo identifier (same as --repo-id)
+  CODETECT_REPO_IDENTITY        Key repos by "path" (absolute path) or "remote" (git origin) [default: path]
+  CODETECT_DATA_DIR             Keep index data in <dir>/<repo> instead of .codetect/
+                                in the repo (for read-only checkouts)
 
 Logging Environment Variables:
   CODETECT_LOG_LEVEL            Log level (debug, info, warn, error) [default: info]
   CODETECT_LOG_FORMAT           Output format (text, json) [default: text]
 
 Database:
-  Default: SQLite stored in .codetect/ relative to indexed path.
+  Default: SQLite stored in .codetect/ relative to indexed path
+  (or under CODETECT_DATA_DIR).
   PostgreSQL: Set CODETECT_DB_TYPE=postgres and CODETECT_DB_DSN.
 
 Requirements:
@@ -818,7 +2287,54 @@ Examples:
   codetect-index index .
   codetect-index embed .
 
+  # Refresh just the files you touched
+  codetect-index embed internal/api/handler.go internal/api/routes.go
+
   # v2 indexing (AST-based, recommended)
   codetect-index index --v2 .
-  codetect-index stats --v2 .`)
+  codetect-index stats --v2 .
+
+  # Keep the index live while you work (Ctrl-C to stop)
+  codetect-index watch --v2 .
+
+  # Check which files were picked up by the last reindex
+  codetect-index recent --since 10m .
+
+  # Preview what the next incremental v2 index will pick up
+  codetect-index diff .
+
+  # Get an overview of the interfaces in a repo
+  codetect-index list-symbols --kind interface --group .
+
+  # Pick a search result and open it in your editor
+  code -g "$(codetect-index search 'where are retries configured')"
+
+  # Load search results into vim's quickfix list
+  vim -q <(codetect-index search --format quickfix 'retry backoff')
+
+  # Pipe search results into jq, one object per line
+  codetect-index search --format jsonl 'retry backoff' | jq -r .path | sort -u
+
+  # Move an embedded v1 index to v2 without re-embedding
+  codetect-index migrate-v2 .
+
+  # Check a shared index matches your embedding config before searching
+  codetect-index stats --model nomic-embed-text --dimensions 768 .
+
+  # Build once on CI, then load it on a laptop
+  codetect-index export . codetect-index.tar.gz
+  codetect-index import codetect-index.tar.gz ~/src/repo
+
+  # Keep the index after moving a checkout that isn't keyed by a git remote
+  mv ~/src/tool ~/code/tool
+  codetect-index rekey --from ~/src/tool --to ~/code/tool
+
+  # Shrink a SQLite index and speed up brute-force search
+  codetect-index repack --format binary .
+
+  # Pick an embedding model using the repo's eval cases
+  codetect-index bench-models --models nomic-embed-text,all-minilm --cases .codetect/evals/cases .
+
+  # CI: key a shared index by remote URL rather than the checkout path
+  codetect-index index --repo-id github.com/org/repo .`)
 }
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"codetect/internal/fusion"
+	"codetect/internal/tools"
+)
+
+// runSearch runs hybrid search over the index of the current directory and
+// shows the results in a terminal UI, or prints them for scripts.
+func runSearch(args []string) {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	limit := fs.Int("limit", 20, "Maximum number of results")
+	rerank := fs.Bool("rerank", false, "Apply cross-encoder reranking")
+	contextLines := fs.Int("context", 0, "Lines of context around each semantic snippet")
+	format := fs.String("format", "tui", "Output format: tui (browse results, print the chosen path:line), quickfix, jsonl or json")
+	fs.Parse(args)
+
+	query := strings.Join(fs.Args(), " ")
+	if query == "" {
+		logger.Error("search needs a query")
+		os.Exit(1)
+	}
+	switch *format {
+	case "tui", "quickfix", "jsonl", "json":
+	default:
+		logger.Error("unknown search format, use tui, quickfix, jsonl or json", "format", *format)
+		os.Exit(1)
+	}
+
+	repoRoot, err := os.Getwd()
+	if err != nil {
+		logger.Error("getting working directory failed", "error", err)
+		os.Exit(1)
+	}
+
+	result, err := tools.HybridSearch(context.Background(), repoRoot, query, tools.HybridSearchOptions{
+		Limit:        *limit,
+		Rerank:       *rerank,
+		ContextLines: *contextLines,
+	})
+	if err != nil {
+		logger.Error("search failed", "error", err)
+		os.Exit(1)
+	}
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			logger.Error("encoding JSON failed", "error", err)
+			os.Exit(1)
+		}
+	case "quickfix":
+		writeQuickfix(os.Stdout, result.Results)
+	case "jsonl":
+		if err := writeJSONLines(os.Stdout, result.Results); err != nil {
+			logger.Error("writing results failed", "error", err)
+			os.Exit(1)
+		}
+	case "tui":
+		if len(result.Results) == 0 {
+			fmt.Fprintf(os.Stderr, "No results for %q\n", query)
+			os.Exit(1)
+		}
+		chosen, err := browseResults(query, result.Results)
+		if err != nil {
+			logger.Error("search UI failed, try --format json", "error", err)
+			os.Exit(1)
+		}
+		if chosen != nil {
+			fmt.Printf("%s:%d\n", chosen.Path, chosen.Line)
+		}
+	}
+}
+
+// writeQuickfix prints results in the path:line:col: message format of grep
+// -n, which vim's :cfile, emacs compile-mode and VSCode problem matchers
+// parse without a plugin. The message is the score and the snippet's first
+// line.
+func writeQuickfix(w io.Writer, results []fusion.RRFResult) {
+	for _, r := range results {
+		msg := fmt.Sprintf("score=%.4f", r.RRFScore)
+		for text := range strings.SplitSeq(r.Snippet, "\n") {
+			if text = strings.TrimSpace(text); text != "" {
+				msg += " " + text
+				break
+			}
+		}
+		fmt.Fprintf(w, "%s:%d:1: %s\n", r.Path, r.Line, msg)
+	}
+}
+
+// searchLine is one result of --format jsonl.
+type searchLine struct {
+	Path      string   `json:"path"`
+	StartLine int      `json:"start_line"`
+	EndLine   int      `json:"end_line"`
+	Score     float64  `json:"score"`
+	Snippet   string   `json:"snippet,omitempty"`
+	Sources   []string `json:"sources,omitempty"`
+}
+
+// writeJSONLines writes one JSON object per result on its own line. Fusion
+// ranks the whole candidate set before any result is final, so the lines come
+// out together once the search is done, not as results are scored.
+func writeJSONLines(w io.Writer, results []fusion.RRFResult) error {
+	enc := json.NewEncoder(w)
+	for _, r := range results {
+		line := searchLine{
+			Path:      r.Path,
+			StartLine: r.Line,
+			EndLine:   max(r.EndLine, r.Line),
+			Score:     r.RRFScore,
+			Snippet:   r.Snippet,
+			Sources:   r.Sources,
+		}
+		if err := enc.Encode(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resultBrowser is the state of the search results UI. It draws on the
+// terminal directly, so stdout stays free for the chosen result.
+type resultBrowser struct {
+	query    string
+	results  []fusion.RRFResult
+	selected int
+	top      int // First result shown in the list
+	rows     int
+	cols     int
+}
+
+// browseResults lets the user pick a result with the arrow keys and enter.
+// It returns nil if they quit without choosing.
+func browseResults(query string, results []fusion.RRFResult) (*fusion.RRFResult, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("no terminal: %w", err)
+	}
+	defer tty.Close()
+
+	saved, err := stty(tty, "-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty(tty, "raw", "-echo"); err != nil {
+		return nil, err
+	}
+	// Switch to the alternate screen and hide the cursor, undone on return
+	fmt.Fprint(tty, "\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Fprint(tty, "\x1b[?25h\x1b[?1049l")
+		stty(tty, strings.TrimSpace(saved)) //nolint:errcheck
+	}()
+
+	b := &resultBrowser{query: query, results: results, rows: 24, cols: 80}
+	if size, err := stty(tty, "size"); err == nil {
+		if rows, cols, ok := strings.Cut(strings.TrimSpace(size), " "); ok {
+			b.rows, _ = strconv.Atoi(rows)
+			b.cols, _ = strconv.Atoi(cols)
+		}
+	}
+
+	buf := make([]byte, 16)
+	for {
+		b.draw(tty)
+		n, err := tty.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		switch b.handleKey(string(buf[:n])) {
+		case keyChoose:
+			return &b.results[b.selected], nil
+		case keyQuit:
+			return nil, nil
+		}
+	}
+}
+
+// keyAction is what a key press asks the browser to do after it updates
+// the selection.
+type keyAction int
+
+const (
+	keyContinue keyAction = iota
+	keyChoose
+	keyQuit
+)
+
+// handleKey applies one key press, given as the bytes the terminal sent.
+func (b *resultBrowser) handleKey(key string) keyAction {
+	page := max(b.listHeight(), 1)
+	switch key {
+	case "\x1b[A", "\x1bOA", "k", "\x10": // Up, ctrl-p
+		b.selected--
+	case "\x1b[B", "\x1bOB", "j", "\x0e": // Down, ctrl-n
+		b.selected++
+	case "\x1b[5~": // Page up
+		b.selected -= page
+	case "\x1b[6~", " ": // Page down
+		b.selected += page
+	case "g", "\x1b[H":
+		b.selected = 0
+	case "G", "\x1b[F":
+		b.selected = len(b.results) - 1
+	case "\r", "\n":
+		return keyChoose
+	case "q", "\x1b", "\x03": // Esc, ctrl-c
+		return keyQuit
+	}
+	b.selected = min(max(b.selected, 0), len(b.results)-1)
+
+	// Scroll the list to keep the selection visible
+	if b.selected < b.top {
+		b.top = b.selected
+	} else if b.selected >= b.top+page {
+		b.top = b.selected - page + 1
+	}
+	return keyContinue
+}
+
+// listHeight is how many results the list shows; the preview gets the
+// rest of the screen below it.
+func (b *resultBrowser) listHeight() int {
+	return min(len(b.results), max((b.rows-3)/2, 1))
+}
+
+// draw renders the header, result list and the selected result's snippet.
+// Raw mode needs explicit carriage returns.
+func (b *resultBrowser) draw(w io.Writer) {
+	var sb strings.Builder
+	sb.WriteString("\x1b[H\x1b[2J")
+	line := func(s string) {
+		sb.WriteString(clip(s, b.cols))
+		sb.WriteString("\r\n")
+	}
+
+	line(fmt.Sprintf("%q: %d results  (up/down move, enter opens, q quits)", b.query, len(b.results)))
+	height := b.listHeight()
+	for i := b.top; i < b.top+height && i < len(b.results); i++ {
+		r := b.results[i]
+		entry := fmt.Sprintf("  %s:%d  %.4f  %s", r.Path, r.Line, r.RRFScore, strings.Join(r.Sources, "+"))
+		if i == b.selected {
+			sb.WriteString("\x1b[7m")
+			entry = ">" + entry[1:]
+			sb.WriteString(clip(entry, b.cols))
+			sb.WriteString("\x1b[0m\r\n")
+			continue
+		}
+		line(entry)
+	}
+	line(strings.Repeat("-", max(b.cols, 1)))
+
+	sel := b.results[b.selected]
+	snippet := strings.Split(strings.TrimRight(sel.Snippet, "\n"), "\n")
+	for i, text := range snippet {
+		if i >= b.rows-height-3 {
+			break
+		}
+		line(fmt.Sprintf("%5d  %s", sel.Line+i, strings.ReplaceAll(text, "\t", "    ")))
+	}
+	io.WriteString(w, sb.String())
+}
+
+// clip cuts s to n runes, the width of the terminal
+func clip(s string, n int) string {
+	if n <= 0 {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+// stty runs stty against the terminal, returning its output.
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("stty %s: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("stty %s: %w", strings.Join(args, " "), err)
+	}
+	return string(out), nil
+}
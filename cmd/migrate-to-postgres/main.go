@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"text/tabwriter"
 	"time"
 
 	"codetect/internal/config"
@@ -17,16 +18,13 @@ import (
 
 var logger *slog.Logger
 
-const (
-	defaultSQLitePath = ".codetect/symbols.db"
-)
-
 func main() {
 	logger = logging.Default("migrate-to-postgres")
 
 	// Parse flags
-	sqlitePath := flag.String("source", defaultSQLitePath, "SQLite database path")
+	sqlitePath := flag.String("source", filepath.Join(config.DataDir("."), "symbols.db"), "SQLite database path")
 	batchSize := flag.Int("batch", 1000, "Number of embeddings to migrate per batch")
+	workers := flag.Int("workers", 4, "Number of batches to insert into PostgreSQL concurrently")
 	skipExisting := flag.Bool("skip-existing", true, "Skip embeddings that already exist in PostgreSQL")
 	dropTarget := flag.Bool("drop-target", false, "Drop existing PostgreSQL tables before migration")
 	dryRun := flag.Bool("dry-run", false, "Perform validation without migrating data")
@@ -72,6 +70,7 @@ func main() {
 	fmt.Printf("Source:      SQLite (%s)\n", *sqlitePath)
 	fmt.Printf("Target:      %s\n", pgConfig.String())
 	fmt.Printf("Batch size:  %d\n", *batchSize)
+	fmt.Printf("Workers:     %d\n", *workers)
 	fmt.Printf("Skip exists: %v\n", *skipExisting)
 	fmt.Printf("Drop target: %v\n", *dropTarget)
 	fmt.Printf("Dry run:     %v\n", *dryRun)
@@ -125,7 +124,7 @@ func main() {
 
 	// Get SQL dialect for PostgreSQL from config
 	dialect := targetCfg.Dialect()
-	targetStore, err := embedding.NewEmbeddingStoreWithDialect(targetDB, dialect, repoRoot)
+	targetStore, err := embedding.NewEmbeddingStoreWithOptions(targetDB, dialect, pgConfig.VectorDimensions, repoRoot)
 	if err != nil {
 		logger.Error("error creating target embedding store", "error", err)
 		os.Exit(1)
@@ -148,6 +147,12 @@ func main() {
 	fmt.Println()
 
 	if *dryRun {
+		plan, err := embedding.PlanMigration(sourceStore, targetStore)
+		if err != nil {
+			logger.Error("error planning migration", "error", err)
+			os.Exit(1)
+		}
+		printPlan(plan)
 		fmt.Println("Dry run mode - no data will be migrated")
 		os.Exit(0)
 	}
@@ -158,6 +163,7 @@ func main() {
 		SkipExisting: *skipExisting,
 		DropTarget:   *dropTarget,
 		DryRun:       *dryRun,
+		Workers:      *workers,
 	}
 
 	// Progress tracking
@@ -205,7 +211,7 @@ func main() {
 	if *validate {
 		fmt.Println()
 		fmt.Println("Validating migration...")
-		if err := embedding.ValidateMigration(sourceStore, targetStore, *sampleSize); err != nil {
+		if err := embedding.ValidateMigration(sourceStore, targetStore, *sampleSize, *batchSize); err != nil {
 			logger.Error("validation failed", "error", err)
 			os.Exit(1)
 		}
@@ -234,3 +240,30 @@ func main() {
 	fmt.Println("  2. Test semantic search: codetect (in MCP mode)")
 	fmt.Println("  3. Optional: Backup SQLite database and remove it")
 }
+
+// printPlan shows the dry-run breakdown of the source database per repo,
+// model and dimension, and the table each group would be migrated to
+func printPlan(plan []embedding.MigrationPlanEntry) {
+	fmt.Println("Migration plan")
+	fmt.Println("==============")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPO\tMODEL\tDIMS\tEMBEDDINGS\tTARGET TABLE\t")
+	migrated, skipped := 0, 0
+	for _, e := range plan {
+		target := e.TargetTable
+		if e.Migrated {
+			migrated += e.Count
+		} else {
+			target = "skipped: " + e.Skip
+			skipped += e.Count
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t\n", e.RepoRoot, e.Model, e.Dimensions, e.Count, target)
+	}
+	w.Flush()
+	fmt.Println()
+	fmt.Printf("Would migrate: %d embeddings\n", migrated)
+	if skipped > 0 {
+		fmt.Printf("Would skip:    %d embeddings\n", skipped)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,33 @@
+package evals
+
+import (
+	"context"
+	"time"
+)
+
+// ModelBenchReport compares embedding models on the same repo and test cases.
+type ModelBenchReport struct {
+	Timestamp time.Time          `json:"timestamp"`
+	RepoPath  string             `json:"repo_path"`
+	K         int                `json:"k"`
+	Cases     int                `json:"cases"`
+	Chunks    int                `json:"chunks"`
+	Models    []ModelBenchResult `json:"models"`
+}
+
+// ModelBenchResult is one embedding model's cost and retrieval quality.
+type ModelBenchResult struct {
+	Model      string             `json:"model"`
+	Dimensions int                `json:"dimensions"`
+	EmbedTime  time.Duration      `json:"embed_time_ns"`
+	IndexBytes int64              `json:"index_bytes"`
+	Stats      RetrievalModeStats `json:"stats"`
+	Error      string             `json:"error,omitempty"` // Set if the model could not be benchmarked
+}
+
+// ScoreSemantic scores a single semantic channel against cases, returning
+// the aggregate stats for ModeSemantic at cutoff k.
+func ScoreSemantic(ctx context.Context, retrieve RetrieveFunc, cases []TestCase, k int) RetrievalModeStats {
+	report := NewRetrievalRunner(retrieve, nil, k).Run(ctx, cases, []ExecutionMode{ModeSemantic})
+	return report.Summary[0]
+}
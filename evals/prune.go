@@ -0,0 +1,127 @@
+package evals
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ResultFile is a saved report in the repo's evals/results directory.
+type ResultFile struct {
+	Path      string    `json:"path"`
+	Kind      string    `json:"kind"` // results, retrieval or bench-models, see saveJSON
+	Timestamp time.Time `json:"timestamp"`
+	Size      int64     `json:"size"`
+}
+
+// ListResults returns all saved report files for the repo, sorted by
+// timestamp (newest first).
+func (r *Runner) ListResults() ([]ResultFile, error) {
+	resultsDir := filepath.Join(r.dataDir(), "evals", "results")
+
+	files, err := filepath.Glob(filepath.Join(resultsDir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("finding result files: %w", err)
+	}
+
+	var entries []ResultFile
+	for _, file := range files {
+		// Format: 2006-01-02-150405-kind.json
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		n := len(fileTimestampLayout)
+		if len(name) < n+2 {
+			continue
+		}
+		timestamp, err := time.Parse(fileTimestampLayout, name[:n])
+		if err != nil {
+			continue // Skip unparseable filenames
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, ResultFile{Path: file, Kind: name[n+1:], Timestamp: timestamp, Size: info.Size()})
+	}
+
+	slices.SortStableFunc(entries, func(a, b ResultFile) int {
+		return b.Timestamp.Compare(a.Timestamp)
+	})
+	return entries, nil
+}
+
+// PruneOptions selects old log and result files to delete.
+type PruneOptions struct {
+	// Keep is how many of the newest files to keep: logs per test case and
+	// mode, result files per kind. 0 keeps any number.
+	Keep int
+	// OlderThan only deletes files older than this. 0 deletes at any age.
+	OlderThan time.Duration
+}
+
+// PruneFile is a log or result file selected for deletion.
+type PruneFile struct {
+	Path      string
+	Timestamp time.Time
+	Size      int64
+}
+
+// PlanPrune returns the log and result files opts selects for deletion,
+// oldest first, without deleting anything. A file is selected when it is
+// outside the Keep newest of its group and older than OlderThan; at least
+// one of the two must be set.
+func (r *Runner) PlanPrune(opts PruneOptions, now time.Time) ([]PruneFile, error) {
+	if opts.Keep <= 0 && opts.OlderThan <= 0 {
+		return nil, errors.New("prune needs a number of files to keep or a maximum age")
+	}
+
+	logs, err := r.ListLogs()
+	if err != nil {
+		return nil, err
+	}
+	results, err := r.ListResults()
+	if err != nil {
+		return nil, err
+	}
+
+	// Both lists are newest first, so a file's position in its group is its
+	// rank by age
+	seen := make(map[string]int)
+	var files []PruneFile
+	consider := func(group, path string, timestamp time.Time, size int64) {
+		seen[group]++
+		if opts.Keep > 0 && seen[group] <= opts.Keep {
+			return
+		}
+		if opts.OlderThan > 0 && now.Sub(timestamp) <= opts.OlderThan {
+			return
+		}
+		files = append(files, PruneFile{Path: path, Timestamp: timestamp, Size: size})
+	}
+	for _, log := range logs {
+		consider("log:"+log.TestCase+"-"+string(log.Mode), log.Path, log.Timestamp, log.Size)
+	}
+	for _, res := range results {
+		consider("result:"+res.Kind, res.Path, res.Timestamp, res.Size)
+	}
+
+	slices.SortStableFunc(files, func(a, b PruneFile) int {
+		return cmp.Or(a.Timestamp.Compare(b.Timestamp), strings.Compare(a.Path, b.Path))
+	})
+	return files, nil
+}
+
+// Prune deletes files planned by PlanPrune.
+func (r *Runner) Prune(files []PruneFile) error {
+	var errs []error
+	for _, f := range files {
+		if err := os.Remove(f.Path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,93 @@
+package evals
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPlanPrune(t *testing.T) {
+	t.Setenv("CODETECT_DATA_DIR", "")
+	repo := t.TempDir()
+	runner := NewRunner(EvalConfig{RepoPath: repo})
+	evalsDir := filepath.Join(runner.dataDir(), "evals")
+
+	for _, name := range []string{
+		"logs/2026-01-01-100000-search-001-with_mcp.log",
+		"logs/2026-01-02-100000-search-001-with_mcp.log",
+		"logs/2026-01-03-100000-search-001-with_mcp.log",
+		"logs/2026-01-01-100000-search-001-without_mcp.log",
+		"logs/2026-01-03-100000-search-001-without_mcp.log",
+		"results/2026-01-01-100500-results.json",
+		"results/2026-01-03-100500-results.json",
+		"results/2026-01-02-110000-retrieval.json",
+		"results/notes.json",
+	} {
+		path := filepath.Join(evalsDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Date(2026, 1, 3, 12, 0, 0, 0, time.UTC)
+	names := func(opts PruneOptions) []string {
+		t.Helper()
+		files, err := runner.PlanPrune(opts, now)
+		if err != nil {
+			t.Fatalf("PlanPrune(%+v) error = %v", opts, err)
+		}
+		var got []string
+		for _, f := range files {
+			got = append(got, filepath.Base(f.Path))
+		}
+		return got
+	}
+
+	want := []string{
+		"2026-01-01-100000-search-001-with_mcp.log",
+		"2026-01-01-100000-search-001-without_mcp.log",
+		"2026-01-01-100500-results.json",
+		"2026-01-02-100000-search-001-with_mcp.log",
+	}
+	if got := names(PruneOptions{Keep: 1}); !slices.Equal(got, want) {
+		t.Errorf("Keep 1 = %v, want %v", got, want)
+	}
+
+	want = []string{
+		"2026-01-01-100000-search-001-with_mcp.log",
+		"2026-01-01-100000-search-001-without_mcp.log",
+		"2026-01-01-100500-results.json",
+	}
+	if got := names(PruneOptions{OlderThan: 36 * time.Hour}); !slices.Equal(got, want) {
+		t.Errorf("OlderThan 36h = %v, want %v", got, want)
+	}
+
+	// Both rules have to agree
+	want = []string{"2026-01-01-100000-search-001-with_mcp.log"}
+	if got := names(PruneOptions{Keep: 2, OlderThan: 36 * time.Hour}); !slices.Equal(got, want) {
+		t.Errorf("Keep 2, OlderThan 36h = %v, want %v", got, want)
+	}
+
+	if _, err := runner.PlanPrune(PruneOptions{}, now); err == nil {
+		t.Error("PlanPrune() with no rule succeeded, want an error")
+	}
+
+	files, err := runner.PlanPrune(PruneOptions{Keep: 1}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runner.Prune(files); err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if got := names(PruneOptions{Keep: 1}); len(got) != 0 {
+		t.Errorf("after Prune, Keep 1 still selects %v", got)
+	}
+	if _, err := os.Stat(filepath.Join(evalsDir, "results", "notes.json")); err != nil {
+		t.Errorf("Prune() touched a file it doesn't manage: %v", err)
+	}
+}
@@ -1,10 +1,13 @@
 package evals
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -37,6 +40,9 @@ func (r *Reporter) GenerateSummary(report *EvalReport) {
 			withMCP.TotalCostUSD += result.CostUSD
 			withMCP.AvgTurns += float64(result.NumTurns)
 			withMCP.TotalToolCalls += result.ToolCallCount
+			if result.TimedOut {
+				withMCP.TimedOut++
+			}
 		} else {
 			noMcpCount++
 			if result.Success {
@@ -51,6 +57,9 @@ func (r *Reporter) GenerateSummary(report *EvalReport) {
 			withoutMCP.TotalCostUSD += result.CostUSD
 			withoutMCP.AvgTurns += float64(result.NumTurns)
 			withoutMCP.TotalToolCalls += result.ToolCallCount
+			if result.TimedOut {
+				withoutMCP.TimedOut++
+			}
 		}
 	}
 
@@ -191,6 +200,13 @@ func (r *Reporter) PrintReport(report *EvalReport, w io.Writer) {
 		report.Summary.WithMCP.SuccessRate*100,
 		report.Summary.WithoutMCP.SuccessRate*100,
 		(report.Summary.WithMCP.SuccessRate-report.Summary.WithoutMCP.SuccessRate)*100)
+	if report.Summary.WithMCP.TimedOut > 0 || report.Summary.WithoutMCP.TimedOut > 0 {
+		fmt.Fprintf(w, "| %-18s | %15d | %15d | %15s |\n",
+			"Timed Out",
+			report.Summary.WithMCP.TimedOut,
+			report.Summary.WithoutMCP.TimedOut,
+			"")
+	}
 	fmt.Fprintln(w, strings.Repeat("-", 75))
 	fmt.Fprintln(w, "")
 
@@ -223,6 +239,151 @@ func (r *Reporter) PrintReport(report *EvalReport, w io.Writer) {
 	fmt.Fprintln(w, strings.Repeat("-", 90))
 }
 
+// PrintRetrievalReport writes a formatted retrieval-only report to the given writer.
+func (r *Reporter) PrintRetrievalReport(report *RetrievalReport, w io.Writer) {
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "codetect Retrieval Evaluation Report")
+	fmt.Fprintln(w, "====================================")
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "Timestamp: %s\n", report.Timestamp.Format(time.RFC3339))
+	fmt.Fprintf(w, "Repository: %s\n", report.RepoPath)
+	fmt.Fprintf(w, "Cutoff: top %d\n", report.K)
+	fmt.Fprintln(w, "")
+
+	// Summary table
+	fmt.Fprintln(w, "Results Summary:")
+	fmt.Fprintln(w, strings.Repeat("-", 98))
+	fmt.Fprintf(w, "| %-10s | %-6s | %-10s | %-10s | %-10s | %-10s | %-10s | %-10s |\n",
+		"Mode", "Cases", "Precision", "Recall", "F1", "MRR", "nDCG", "Latency")
+	fmt.Fprintln(w, strings.Repeat("-", 98))
+	for _, stats := range report.Summary {
+		fmt.Fprintf(w, "| %-10s | %6d | %9.1f%% | %9.1f%% | %9.1f%% | %10.3f | %10.3f | %10s |\n",
+			stats.Mode,
+			stats.Cases,
+			stats.AvgPrecision*100,
+			stats.AvgRecall*100,
+			stats.AvgF1*100,
+			stats.MRR,
+			stats.AvgNDCG,
+			formatDuration(stats.AvgLatency))
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 98))
+	fmt.Fprintln(w, "")
+
+	// Per-test breakdown
+	fmt.Fprintln(w, "Per-Test Results:")
+	fmt.Fprintln(w, strings.Repeat("-", 93))
+	fmt.Fprintf(w, "| %-12s | %-10s | %-10s | %-9s | %-9s | %-7s | %-6s | %-6s |\n",
+		"ID", "Category", "Mode", "Precision", "Recall", "F1", "RR", "nDCG")
+	fmt.Fprintln(w, strings.Repeat("-", 93))
+	for _, res := range report.Results {
+		fmt.Fprintf(w, "| %-12s | %-10s | %-10s | %8.1f%% | %8.1f%% | %6.1f%% | %6.3f | %6.3f |\n",
+			res.Run.TestCaseID,
+			res.Category,
+			res.Run.Mode,
+			res.Validation.Precision*100,
+			res.Validation.Recall*100,
+			res.Validation.F1Score*100,
+			res.Validation.ReciprocalRank,
+			res.Validation.NDCG)
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 93))
+}
+
+// PrintModelBenchReport writes a formatted embedding model comparison to the given writer.
+func (r *Reporter) PrintModelBenchReport(report *ModelBenchReport, w io.Writer) {
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "codetect Embedding Model Benchmark")
+	fmt.Fprintln(w, "==================================")
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "Timestamp: %s\n", report.Timestamp.Format(time.RFC3339))
+	fmt.Fprintf(w, "Repository: %s\n", report.RepoPath)
+	fmt.Fprintf(w, "Test cases: %d, chunks: %d, cutoff: top %d\n", report.Cases, report.Chunks, report.K)
+	fmt.Fprintln(w, "")
+
+	fmt.Fprintln(w, strings.Repeat("-", 101))
+	fmt.Fprintf(w, "| %-28s | %-5s | %-10s | %-10s | %-8s | %-8s | %-10s |\n",
+		"Model", "Dims", "Recall", "MRR", "nDCG", "Embed", "Index Size")
+	fmt.Fprintln(w, strings.Repeat("-", 101))
+	for _, res := range report.Models {
+		if res.Error != "" {
+			fmt.Fprintf(w, "| %-28s | %-66s |\n", res.Model, "error: "+res.Error)
+			continue
+		}
+		fmt.Fprintf(w, "| %-28s | %5d | %9.1f%% | %10.3f | %8.3f | %8s | %10s |\n",
+			res.Model,
+			res.Dimensions,
+			res.Stats.AvgRecall*100,
+			res.Stats.MRR,
+			res.Stats.AvgNDCG,
+			formatDuration(res.EmbedTime),
+			FormatBytes(res.IndexBytes))
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 101))
+}
+
+// PrintTrend writes one row of summary metrics per report, oldest first,
+// to show how runs compare over time.
+func (r *Reporter) PrintTrend(reports []*EvalReport, w io.Writer) {
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "codetect Evaluation Trend")
+	fmt.Fprintln(w, "=========================")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, strings.Repeat("-", 104))
+	fmt.Fprintf(w, "| %-19s | %-6s | %-5s | %-8s | %-8s | %-8s | %-8s | %-10s | %-8s |\n",
+		"Run", "Model", "Cases", "MCP F1", "No-MCP", "F1 Gain", "Tokens", "MCP Cost", "Cost")
+	fmt.Fprintln(w, strings.Repeat("-", 104))
+	for _, rep := range sortedByTime(reports) {
+		fmt.Fprintf(w, "| %-19s | %-6s | %5d | %7.1f%% | %7.1f%% | %+7.1f%% | %+7.1f%% | $%9.4f | %+7.1f%% |\n",
+			rep.Timestamp.Format("2006-01-02 15:04:05"),
+			rep.Config.Model,
+			rep.Summary.TotalCases,
+			rep.Summary.WithMCP.AvgAccuracy*100,
+			rep.Summary.WithoutMCP.AvgAccuracy*100,
+			rep.Summary.AccuracyImprovement,
+			rep.Summary.TokenReduction,
+			rep.Summary.WithMCP.TotalCostUSD,
+			rep.Summary.CostReduction)
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 104))
+	fmt.Fprintln(w, "Tokens and Cost are reductions with MCP; positive is better.")
+}
+
+// WriteTrendCSV writes the PrintTrend rows as CSV, with more precision and
+// the per-mode averages.
+func (r *Reporter) WriteTrendCSV(reports []*EvalReport, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"timestamp", "model", "cases",
+		"mcp_accuracy", "no_mcp_accuracy", "accuracy_improvement_pct",
+		"mcp_avg_tokens", "no_mcp_avg_tokens", "token_reduction_pct",
+		"mcp_total_cost_usd", "no_mcp_total_cost_usd", "cost_reduction_pct",
+		"latency_reduction_pct",
+	})
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	for _, rep := range sortedByTime(reports) {
+		sum := rep.Summary
+		cw.Write([]string{
+			rep.Timestamp.Format(time.RFC3339), rep.Config.Model, strconv.Itoa(sum.TotalCases),
+			f(sum.WithMCP.AvgAccuracy), f(sum.WithoutMCP.AvgAccuracy), f(sum.AccuracyImprovement),
+			f(sum.WithMCP.AvgTotalTokens), f(sum.WithoutMCP.AvgTotalTokens), f(sum.TokenReduction),
+			f(sum.WithMCP.TotalCostUSD), f(sum.WithoutMCP.TotalCostUSD), f(sum.CostReduction),
+			f(sum.LatencyReduction),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// sortedByTime returns reports ordered oldest first.
+func sortedByTime(reports []*EvalReport) []*EvalReport {
+	sorted := slices.Clone(reports)
+	slices.SortStableFunc(sorted, func(a, b *EvalReport) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+	return sorted
+}
+
 // PrintReportToStdout prints the report to stdout.
 func (r *Reporter) PrintReportToStdout(report *EvalReport) {
 	r.PrintReport(report, os.Stdout)
@@ -259,6 +420,20 @@ func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%.1fs", d.Seconds())
 }
 
+// FormatBytes converts bytes to human-readable format.
+func FormatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
 // calcReduction calculates the percentage reduction from baseline to new value.
 // Positive result means reduction (new is smaller), negative means increase.
 func calcReduction(baseline, new float64) float64 {
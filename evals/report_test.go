@@ -0,0 +1,49 @@
+package evals
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrendOrdersRunsOldestFirst(t *testing.T) {
+	newer := &EvalReport{
+		Timestamp: time.Date(2026, 2, 1, 9, 0, 0, 0, time.UTC),
+		Config:    EvalConfig{Model: "sonnet"},
+		Summary:   ReportSummary{TotalCases: 12, AccuracyImprovement: 20, TokenReduction: 35.5},
+	}
+	older := &EvalReport{
+		Timestamp: time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC),
+		Config:    EvalConfig{Model: "haiku"},
+		Summary:   ReportSummary{TotalCases: 10, AccuracyImprovement: 5, TokenReduction: 10},
+	}
+	reports := []*EvalReport{newer, older}
+	reporter := NewReporter()
+
+	var buf bytes.Buffer
+	if err := reporter.WriteTrendCSV(reports, &buf); err != nil {
+		t.Fatalf("WriteTrendCSV() error = %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("parsing CSV: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d CSV rows, want a header and 2 runs", len(rows))
+	}
+	if rows[1][1] != "haiku" || rows[2][1] != "sonnet" || rows[2][8] != "35.5" {
+		t.Errorf("CSV rows = %v, want haiku then sonnet with token reduction 35.5", rows[1:])
+	}
+	if reports[0] != newer {
+		t.Error("WriteTrendCSV() reordered the caller's slice")
+	}
+
+	buf.Reset()
+	reporter.PrintTrend(reports, &buf)
+	out := buf.String()
+	if i, j := strings.Index(out, "2026-01-01"), strings.Index(out, "2026-02-01"); i < 0 || j < i {
+		t.Errorf("PrintTrend() rows not oldest first:\n%s", out)
+	}
+}
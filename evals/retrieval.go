@@ -0,0 +1,170 @@
+package evals
+
+import (
+	"context"
+	"time"
+
+	"codetect/internal/fusion"
+)
+
+// Retrieval execution modes score a single search channel, or the fused
+// hybrid ranking, directly against ground truth without running an agent.
+const (
+	ModeKeyword  ExecutionMode = "keyword"
+	ModeSemantic ExecutionMode = "semantic"
+	ModeHybrid   ExecutionMode = "hybrid"
+)
+
+// RetrievalModes lists the retrieval modes in report order.
+var RetrievalModes = []ExecutionMode{ModeKeyword, ModeSemantic, ModeHybrid}
+
+// RetrieveFunc returns each retrieval channel's ranked results for a query,
+// with up to limit results per channel. Results carry their channel in Source.
+type RetrieveFunc func(ctx context.Context, query string, limit int) [][]fusion.Result
+
+// RetrievalReport contains the results of a retrieval-only evaluation.
+type RetrievalReport struct {
+	Timestamp time.Time            `json:"timestamp"`
+	RepoPath  string               `json:"repo_path"`
+	K         int                  `json:"k"`
+	Weights   map[string]float64   `json:"weights"`
+	Summary   []RetrievalModeStats `json:"summary"`
+	Results   []RetrievalResult    `json:"results"`
+}
+
+// RetrievalModeStats contains aggregate metrics for one retrieval mode.
+type RetrievalModeStats struct {
+	Mode         ExecutionMode `json:"mode"`
+	Cases        int           `json:"cases"`
+	AvgPrecision float64       `json:"avg_precision"`
+	AvgRecall    float64       `json:"avg_recall"`
+	AvgF1        float64       `json:"avg_f1"`
+	MRR          float64       `json:"mrr"`      // Mean reciprocal rank of the first correct file
+	AvgNDCG      float64       `json:"avg_ndcg"` // Mean nDCG@k
+	AvgLatency   time.Duration `json:"avg_latency_ns"`
+}
+
+// RetrievalResult is one test case scored in one retrieval mode.
+type RetrievalResult struct {
+	Category   string           `json:"category"`
+	Run        RunResult        `json:"run"`
+	Validation ValidationResult `json:"validation"`
+}
+
+// RetrievalRunner evaluates search channels directly against test case
+// ground truth, skipping the agent entirely.
+type RetrievalRunner struct {
+	retrieve  RetrieveFunc
+	weights   map[string]float64
+	k         int
+	validator *Validator
+}
+
+// NewRetrievalRunner creates a runner that scores the top k results of each
+// mode. weights are the fusion weights used for the hybrid mode.
+func NewRetrievalRunner(retrieve RetrieveFunc, weights map[string]float64, k int) *RetrievalRunner {
+	if k <= 0 {
+		k = 10
+	}
+	return &RetrievalRunner{
+		retrieve:  retrieve,
+		weights:   weights,
+		k:         k,
+		validator: NewValidator(),
+	}
+}
+
+// Run retrieves each case's prompt once and scores it in every mode.
+// Latency is the time to retrieve all channels, shared by every mode.
+func (r *RetrievalRunner) Run(ctx context.Context, cases []TestCase, modes []ExecutionMode) *RetrievalReport {
+	report := &RetrievalReport{
+		Timestamp: time.Now(),
+		K:         r.k,
+		Weights:   r.weights,
+	}
+
+	for _, tc := range cases {
+		start := time.Now()
+		lists := r.retrieve(ctx, tc.Prompt, r.k)
+		duration := time.Since(start)
+
+		for _, mode := range modes {
+			run := NewRetrievalRun(tc, mode, fusion.TopN(RankForMode(mode, r.weights, lists), r.k))
+			run.Duration = duration
+			report.Results = append(report.Results, RetrievalResult{
+				Category:   tc.Category,
+				Run:        run,
+				Validation: r.validator.ValidateRetrieval(tc, run),
+			})
+		}
+	}
+
+	report.Summary = summarizeRetrieval(report.Results, modes)
+	return report
+}
+
+// RankForMode returns the ranking a mode produces from the channel lists:
+// a single channel's results in their original order, or the weighted RRF
+// fusion of all channels for ModeHybrid.
+func RankForMode(mode ExecutionMode, weights map[string]float64, lists [][]fusion.Result) []fusion.RRFResult {
+	if mode == ModeHybrid {
+		return fusion.WeightedRRF(weights, lists...)
+	}
+
+	var channel []fusion.Result
+	for _, list := range lists {
+		for _, res := range list {
+			if res.Source == string(mode) {
+				channel = append(channel, res)
+			}
+		}
+	}
+	// RRF over a single list preserves its order
+	return fusion.ReciprocalRankFusion(channel)
+}
+
+// NewRetrievalRun builds a run result from ranked retrieval results, so they
+// can be scored by the Validator like an agent's output.
+func NewRetrievalRun(tc TestCase, mode ExecutionMode, ranked []fusion.RRFResult) RunResult {
+	run := RunResult{
+		TestCaseID: tc.ID,
+		Mode:       mode,
+		Success:    len(ranked) > 0,
+		Output:     RetrievalOutput(ranked),
+	}
+	for _, res := range ranked {
+		run.RankedFiles = append(run.RankedFiles, res.Path)
+	}
+	return run
+}
+
+// summarizeRetrieval averages validation metrics per mode.
+func summarizeRetrieval(results []RetrievalResult, modes []ExecutionMode) []RetrievalModeStats {
+	summary := make([]RetrievalModeStats, 0, len(modes))
+	for _, mode := range modes {
+		stats := RetrievalModeStats{Mode: mode}
+		for _, res := range results {
+			if res.Run.Mode != mode {
+				continue
+			}
+			stats.Cases++
+			stats.AvgPrecision += res.Validation.Precision
+			stats.AvgRecall += res.Validation.Recall
+			stats.AvgF1 += res.Validation.F1Score
+			stats.MRR += res.Validation.ReciprocalRank
+			stats.AvgNDCG += res.Validation.NDCG
+			stats.AvgLatency += res.Run.Duration
+		}
+		if stats.Cases > 0 {
+			n := float64(stats.Cases)
+			stats.AvgPrecision /= n
+			stats.AvgRecall /= n
+			stats.AvgF1 /= n
+			stats.MRR /= n
+			stats.AvgNDCG /= n
+			stats.AvgLatency /= time.Duration(stats.Cases)
+		}
+		summary = append(summary, stats)
+	}
+	return summary
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -14,6 +15,8 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	searchconfig "codetect/internal/config"
 )
 
 // Runner executes evaluation test cases.
@@ -27,15 +30,22 @@ func NewRunner(config EvalConfig) *Runner {
 }
 
 // LoadTestCases loads test cases from JSONL files in the cases directory.
-// It first checks for a repo-specific .codetect/evals/cases directory,
-// and falls back to the provided casesDir if not found.
+// It first checks for a repo-specific evals/cases directory in the data
+// directory, then in the repo's own .codetect/ (which may be checked in
+// when the data directory is elsewhere), and falls back to the provided
+// casesDir if neither is found.
 func (r *Runner) LoadTestCases(casesDir string) ([]TestCase, error) {
 	var cases []TestCase
 
 	// Check for repo-specific eval cases first
-	repoEvalDir := filepath.Join(r.config.RepoPath, ".codetect", "evals", "cases")
-	if info, err := os.Stat(repoEvalDir); err == nil && info.IsDir() {
-		casesDir = repoEvalDir
+	for _, repoEvalDir := range []string{
+		filepath.Join(r.dataDir(), "evals", "cases"),
+		filepath.Join(r.config.RepoPath, searchconfig.DataDirName, "evals", "cases"),
+	} {
+		if info, err := os.Stat(repoEvalDir); err == nil && info.IsDir() {
+			casesDir = repoEvalDir
+			break
+		}
 	}
 
 	// Find all JSONL files (including in subdirectories)
@@ -269,6 +279,9 @@ func (r *Runner) runTestCase(ctx context.Context, tc TestCase, mode ExecutionMod
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
+	// Don't wait forever on pipes held open by claude's own subprocesses
+	// once it has been killed
+	cmd.WaitDelay = 5 * time.Second
 
 	err := cmd.Run()
 	duration := time.Since(start)
@@ -286,14 +299,40 @@ func (r *Runner) runTestCase(ctx context.Context, tc TestCase, mode ExecutionMod
 
 	if err != nil {
 		result.Success = false
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			// Keep what the run got through before it was killed
+			result.TimedOut = true
+			result.Error = fmt.Sprintf("timed out after %s", r.config.Timeout)
+			parseStreamOutput(stdout.Bytes(), result)
+			return result, nil
+		}
 		result.Error = fmt.Sprintf("%v: %s", err, stderr.String())
 		return result, nil
 	}
 
-	// Parse streaming JSON output - each line is a separate JSON event
-	// We look for the final "result" event which contains usage stats
-	lines := bytes.Split(stdout.Bytes(), []byte("\n"))
-	for _, line := range lines {
+	// If we didn't find a result event, try parsing as simple JSON
+	if !parseStreamOutput(stdout.Bytes(), result) {
+		var resp ClaudeResponse
+		if err := json.Unmarshal(stdout.Bytes(), &resp); err == nil {
+			result.Success = true
+			result.Output = resp.Result
+			result.SessionID = resp.SessionID
+		}
+	}
+
+	return result, nil
+}
+
+// parseStreamOutput fills result from Claude's streaming JSON output, where
+// each line is a separate event, and reports whether it held the final
+// "result" event. Without one, as when a run is killed, the token and turn
+// counts are summed from the assistant messages seen so far.
+func parseStreamOutput(stdout []byte, result *RunResult) bool {
+	var partial ClaudeUsage
+	messages := make(map[string]bool) // Message IDs, repeated for each content block
+	finished := false
+
+	for _, line := range bytes.Split(stdout, []byte("\n")) {
 		if len(line) == 0 {
 			continue
 		}
@@ -303,36 +342,56 @@ func (r *Runner) runTestCase(ctx context.Context, tc TestCase, mode ExecutionMod
 			continue // Skip unparseable lines
 		}
 
-		// The final "result" event contains the summary
-		if event.Type == "result" {
+		if event.SessionID != "" {
+			result.SessionID = event.SessionID
+		}
+
+		switch event.Type {
+		case "assistant":
+			if event.Message == nil {
+				continue
+			}
+			for _, block := range event.Message.Content {
+				if block.Type == "tool_use" {
+					result.ToolCallCount++
+				}
+			}
+			if u := event.Message.Usage; u != nil && !messages[event.Message.ID] {
+				messages[event.Message.ID] = true
+				partial.InputTokens += u.InputTokens
+				partial.OutputTokens += u.OutputTokens
+				partial.CacheReadInputTokens += u.CacheReadInputTokens
+				partial.CacheCreationInputTokens += u.CacheCreationInputTokens
+			}
+
+		case "result":
+			// The final "result" event contains the summary
+			finished = true
 			result.Success = event.Subtype == "success"
 			result.Output = event.Result
-			result.SessionID = event.SessionID
 			result.NumTurns = event.NumTurns
 			result.CostUSD = event.TotalCost
-
 			if event.Usage != nil {
-				result.InputTokens = event.Usage.InputTokens
-				result.OutputTokens = event.Usage.OutputTokens
-				result.CacheReadTokens = event.Usage.CacheReadInputTokens
-				result.CacheCreateTokens = event.Usage.CacheCreationInputTokens
-				result.TokensUsed = event.Usage.InputTokens + event.Usage.OutputTokens +
-					event.Usage.CacheReadInputTokens + event.Usage.CacheCreationInputTokens
+				setUsage(result, *event.Usage)
 			}
 		}
 	}
 
-	// If we didn't find a result event, try parsing as simple JSON
-	if result.Output == "" {
-		var resp ClaudeResponse
-		if err := json.Unmarshal(stdout.Bytes(), &resp); err == nil {
-			result.Success = true
-			result.Output = resp.Result
-			result.SessionID = resp.SessionID
-		}
+	if !finished {
+		result.NumTurns = len(messages)
+		setUsage(result, partial)
 	}
+	return finished
+}
 
-	return result, nil
+// setUsage records token usage on result.
+func setUsage(result *RunResult, u ClaudeUsage) {
+	result.InputTokens = u.InputTokens
+	result.OutputTokens = u.OutputTokens
+	result.CacheReadTokens = u.CacheReadInputTokens
+	result.CacheCreateTokens = u.CacheCreationInputTokens
+	result.TokensUsed = u.InputTokens + u.OutputTokens +
+		u.CacheReadInputTokens + u.CacheCreationInputTokens
 }
 
 // buildClaudeArgs constructs the command-line arguments for Claude.
@@ -365,19 +424,40 @@ func (r *Runner) buildClaudeArgs(tc TestCase, mode ExecutionMode) []string {
 }
 
 // SaveResults writes the raw results to a JSON file.
-// It always uses the repo-specific .codetect/evals/results directory.
+// It always uses the repo-specific evals/results directory in the data directory.
 func (r *Runner) SaveResults(report *EvalReport) error {
+	return r.saveJSON("results", report)
+}
+
+// SaveRetrievalResults writes a retrieval-only report alongside the
+// agent eval results.
+func (r *Runner) SaveRetrievalResults(report *RetrievalReport) error {
+	return r.saveJSON("retrieval", report)
+}
+
+// SaveModelBenchResults writes an embedding model comparison alongside the
+// agent eval results.
+func (r *Runner) SaveModelBenchResults(report *ModelBenchReport) error {
+	return r.saveJSON("bench-models", report)
+}
+
+// fileTimestampLayout starts the name of every saved log and result file
+const fileTimestampLayout = "2006-01-02-150405"
+
+// saveJSON writes v to a timestamped <kind>.json file in the repo-specific
+// results directory.
+func (r *Runner) saveJSON(kind string, v any) error {
 	// Always use repo-specific results directory to keep results with cases
-	outputDir := filepath.Join(r.config.RepoPath, ".codetect", "evals", "results")
+	outputDir := filepath.Join(r.dataDir(), "evals", "results")
 
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return fmt.Errorf("creating output dir: %w", err)
 	}
 
-	filename := fmt.Sprintf("%s-results.json", time.Now().Format("2006-01-02-150405"))
+	filename := fmt.Sprintf("%s-%s.json", time.Now().Format(fileTimestampLayout), kind)
 	path := filepath.Join(outputDir, filename)
 
-	data, err := json.MarshalIndent(report, "", "  ")
+	data, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshaling results: %w", err)
 	}
@@ -390,18 +470,23 @@ func (r *Runner) SaveResults(report *EvalReport) error {
 	return nil
 }
 
+// dataDir returns the data directory of the repo under evaluation.
+func (r *Runner) dataDir() string {
+	return searchconfig.DataDir(r.config.RepoPath)
+}
+
 func contains(slice []string, item string) bool {
 	return slices.Contains(slice, item)
 }
 
 // saveLog writes the raw Claude stdout to a log file for later inspection.
 func (r *Runner) saveLog(testCaseID string, mode ExecutionMode, timestamp time.Time, data []byte) error {
-	logsDir := filepath.Join(r.config.RepoPath, ".codetect", "evals", "logs")
+	logsDir := filepath.Join(r.dataDir(), "evals", "logs")
 	if err := os.MkdirAll(logsDir, 0755); err != nil {
 		return fmt.Errorf("creating logs dir: %w", err)
 	}
 
-	filename := fmt.Sprintf("%s-%s-%s.log", timestamp.Format("2006-01-02-150405"), testCaseID, mode)
+	filename := fmt.Sprintf("%s-%s-%s.log", timestamp.Format(fileTimestampLayout), testCaseID, mode)
 	path := filepath.Join(logsDir, filename)
 
 	if err := os.WriteFile(path, data, 0644); err != nil {
@@ -422,7 +507,7 @@ type LogEntry struct {
 
 // ListLogs returns all log files for a given repo, sorted by timestamp (newest first).
 func (r *Runner) ListLogs() ([]LogEntry, error) {
-	logsDir := filepath.Join(r.config.RepoPath, ".codetect", "evals", "logs")
+	logsDir := filepath.Join(r.dataDir(), "evals", "logs")
 
 	files, err := filepath.Glob(filepath.Join(logsDir, "*.log"))
 	if err != nil {
@@ -463,18 +548,19 @@ func parseLogFilename(path string) (LogEntry, error) {
 	filename = strings.TrimSuffix(filename, ".log")
 
 	// Split into timestamp-testcase-mode
-	// Timestamp is fixed format: 2006-01-02-150405 (19 chars)
-	if len(filename) < 22 { // 19 + 1 (dash) + at least 1 char + 1 (dash) + mode
+	// Timestamp is fixed format: 2006-01-02-150405 (17 chars)
+	n := len(fileTimestampLayout)
+	if len(filename) < n+3 { // timestamp + dash + at least 1 char + dash + mode
 		return LogEntry{}, fmt.Errorf("filename too short")
 	}
 
-	timestampStr := filename[:19]
-	timestamp, err := time.Parse("2006-01-02-150405", timestampStr)
+	timestampStr := filename[:n]
+	timestamp, err := time.Parse(fileTimestampLayout, timestampStr)
 	if err != nil {
 		return LogEntry{}, fmt.Errorf("parsing timestamp: %w", err)
 	}
 
-	rest := filename[20:] // Skip timestamp and dash
+	rest := filename[n+1:] // Skip timestamp and dash
 
 	// Find mode suffix
 	var mode ExecutionMode
@@ -503,7 +589,12 @@ func (r *Runner) ReadLog(path string) ([]byte, error) {
 }
 
 // EnsureGitignore ensures the .codetect directory is in the target repo's .gitignore.
+// It does nothing when CODETECT_DATA_DIR keeps the data out of the tree.
 func (r *Runner) EnsureGitignore() error {
+	if !searchconfig.InTreeDataDir() {
+		return nil
+	}
+
 	gitignorePath := filepath.Join(r.config.RepoPath, ".gitignore")
 
 	// Read existing .gitignore if it exists
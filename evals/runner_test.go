@@ -0,0 +1,69 @@
+package evals
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Stream output of a run that made one tool call and was killed before the
+// final result event. The first message is repeated per content block.
+var partialStream = strings.Join([]string{
+	`{"type":"system","subtype":"init","session_id":"s1"}`,
+	`{"type":"assistant","message":{"id":"m1","content":[{"type":"text","text":"Searching"}],"usage":{"input_tokens":100,"output_tokens":10,"cache_read_input_tokens":1000}}}`,
+	`{"type":"assistant","message":{"id":"m1","content":[{"type":"tool_use","id":"t1","name":"Grep","input":{}}],"usage":{"input_tokens":100,"output_tokens":10,"cache_read_input_tokens":1000}}}`,
+	`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"a.go"}]}}`,
+	`{"type":"assistant","message":{"id":"m2","content":[{"type":"text","text":"Reading"}],"usage":{"input_tokens":50,"output_tokens":5}}}`,
+}, "\n")
+
+func TestParseStreamOutput(t *testing.T) {
+	var partial RunResult
+	if parseStreamOutput([]byte(partialStream), &partial) {
+		t.Error("parseStreamOutput() found a result event in a partial stream")
+	}
+	if partial.NumTurns != 2 || partial.ToolCallCount != 1 || partial.InputTokens != 150 ||
+		partial.OutputTokens != 15 || partial.TokensUsed != 1165 || partial.SessionID != "s1" {
+		t.Errorf("partial result = %+v, want 2 turns, 1 tool call, 1165 tokens", partial)
+	}
+
+	final := partialStream + "\n" + `{"type":"result","subtype":"success","result":"done","num_turns":3,"total_cost_usd":0.5,"usage":{"input_tokens":200,"output_tokens":20}}`
+	var full RunResult
+	if !parseStreamOutput([]byte(final), &full) {
+		t.Error("parseStreamOutput() missed the result event")
+	}
+	if !full.Success || full.Output != "done" || full.NumTurns != 3 || full.TokensUsed != 220 || full.CostUSD != 0.5 {
+		t.Errorf("full result = %+v, want the result event's totals", full)
+	}
+}
+
+func TestRunTestCaseTimeoutKeepsPartialOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as claude")
+	}
+	bin := t.TempDir()
+	stream := filepath.Join(bin, "stream.jsonl")
+	if err := os.WriteFile(stream, []byte(partialStream+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\ncat " + stream + "\nexec sleep 10\n"
+	if err := os.WriteFile(filepath.Join(bin, "claude"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("CODETECT_DATA_DIR", "")
+
+	config := DefaultConfig()
+	config.RepoPath = t.TempDir()
+	config.Timeout = 200 * time.Millisecond
+	result, err := NewRunner(config).runTestCase(context.Background(), TestCase{ID: "slow"}, ModeWithoutMCP)
+	if err != nil {
+		t.Fatalf("runTestCase() error = %v", err)
+	}
+	if !result.TimedOut || result.Success || result.NumTurns != 2 || result.TokensUsed != 1165 {
+		t.Errorf("runTestCase() = %+v, want a timed-out result with the partial counts", result)
+	}
+}
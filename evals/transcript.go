@@ -0,0 +1,133 @@
+package evals
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Transcript output limits, to keep large tool inputs and results readable
+const (
+	maxTranscriptInput  = 300
+	maxTranscriptResult = 200
+)
+
+// WriteTranscript renders a Claude stream-json log as a readable
+// transcript: any prompt in the log, each tool call with its arguments and
+// a summary of its result, the final answer, and token and cost totals.
+// Lines that aren't stream events are skipped, so truncated logs still
+// render up to where they stop.
+func WriteTranscript(w io.Writer, log []byte) {
+	finished := false
+	calls := make(map[string]string) // tool_use ID -> tool name
+	for _, line := range bytes.Split(log, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var event ClaudeStreamEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			continue
+		}
+
+		switch event.Type {
+		case "system":
+			if event.Model != "" {
+				fmt.Fprintf(w, "Model: %s\n\n", event.Model)
+			}
+		case "user":
+			if event.Message == nil {
+				continue
+			}
+			for _, block := range event.Message.Content {
+				switch block.Type {
+				case "text":
+					fmt.Fprintf(w, "User:\n%s\n\n", indent(block.Text))
+				case "tool_result":
+					status := "ok"
+					if block.IsError {
+						status = "error"
+					}
+					fmt.Fprintf(w, "  <- %s %s: %s\n\n", calls[block.ToolUseID], status, summarizeToolResult(block.Content))
+				}
+			}
+		case "assistant":
+			if event.Message == nil {
+				continue
+			}
+			for _, block := range event.Message.Content {
+				switch block.Type {
+				case "text":
+					if text := strings.TrimSpace(block.Text); text != "" {
+						fmt.Fprintf(w, "Assistant:\n%s\n\n", indent(text))
+					}
+				case "tool_use":
+					calls[block.ID] = block.Name
+					fmt.Fprintf(w, "  -> %s %s\n", block.Name, truncate(compactJSON(block.Input), maxTranscriptInput))
+				}
+			}
+		case "result":
+			finished = true
+			if event.Result != "" {
+				fmt.Fprintf(w, "Final answer (%s):\n%s\n\n", event.Subtype, indent(event.Result))
+			} else {
+				fmt.Fprintf(w, "Finished: %s\n\n", event.Subtype)
+			}
+			fmt.Fprintf(w, "Turns: %d  Cost: $%.4f\n", event.NumTurns, event.TotalCost)
+			if u := event.Usage; u != nil {
+				fmt.Fprintf(w, "Tokens: %d input, %d output, %d cache read, %d cache create\n",
+					u.InputTokens, u.OutputTokens, u.CacheReadInputTokens, u.CacheCreationInputTokens)
+			}
+		}
+	}
+	if !finished {
+		fmt.Fprintln(w, "No result event: the run was cut off before it finished")
+	}
+}
+
+// summarizeToolResult returns the first line of a tool_result's text and how
+// many more lines follow
+func summarizeToolResult(content json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(content, &text); err != nil {
+		var blocks []ClaudeContentBlock
+		if err := json.Unmarshal(content, &blocks); err != nil {
+			return truncate(compactJSON(content), maxTranscriptResult)
+		}
+		var parts []string
+		for _, b := range blocks {
+			if b.Type == "text" {
+				parts = append(parts, b.Text)
+			}
+		}
+		text = strings.Join(parts, "\n")
+	}
+
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	summary := truncate(lines[0], maxTranscriptResult)
+	if len(lines) > 1 {
+		summary += fmt.Sprintf(" (+%d more lines)", len(lines)-1)
+	}
+	return summary
+}
+
+// compactJSON returns raw with insignificant whitespace removed
+func compactJSON(raw json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "") + "..."
+}
+
+func indent(s string) string {
+	return "  " + strings.ReplaceAll(strings.TrimSpace(s), "\n", "\n  ")
+}
@@ -0,0 +1,44 @@
+package evals
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteTranscript(t *testing.T) {
+	log := strings.Join([]string{
+		`{"type":"system","subtype":"init","model":"claude-sonnet"}`,
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Let me search."},{"type":"tool_use","id":"t1","name":"mcp__codetect__find_symbol","input":{"name": "Server"}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":[{"type":"text","text":"internal/mcp/server.go:25\nmore\nlines"}]}]}}`,
+		`not json`,
+		`{"type":"result","subtype":"success","result":"It is in server.go","num_turns":2,"total_cost_usd":0.0123,"usage":{"input_tokens":10,"output_tokens":5,"cache_read_input_tokens":100,"cache_creation_input_tokens":0}}`,
+	}, "\n")
+
+	var buf bytes.Buffer
+	WriteTranscript(&buf, []byte(log))
+	got := buf.String()
+
+	for _, want := range []string{
+		"Model: claude-sonnet",
+		"Assistant:\n  Let me search.",
+		`-> mcp__codetect__find_symbol {"name":"Server"}`,
+		"<- mcp__codetect__find_symbol ok: internal/mcp/server.go:25 (+2 more lines)",
+		"Final answer (success):\n  It is in server.go",
+		"Turns: 2  Cost: $0.0123",
+		"Tokens: 10 input, 5 output, 100 cache read, 0 cache create",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("transcript missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "cut off") {
+		t.Errorf("finished run reported as cut off:\n%s", got)
+	}
+
+	buf.Reset()
+	WriteTranscript(&buf, []byte(`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Read","input":{}}]}}`))
+	if !strings.Contains(buf.String(), "cut off") {
+		t.Errorf("unfinished run not reported as cut off:\n%s", buf.String())
+	}
+}
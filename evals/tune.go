@@ -0,0 +1,185 @@
+package evals
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"codetect/internal/fusion"
+)
+
+// RetrievalCase is a test case paired with the ranked result lists each
+// retrieval channel returned for its prompt. Lists are fetched once and
+// re-fused for every candidate weighting, so tuning never re-runs search.
+type RetrievalCase struct {
+	TestCase TestCase
+	Lists    [][]fusion.Result
+}
+
+// WeightScore is the mean recall@k achieved by a set of fusion weights.
+type WeightScore struct {
+	Weights    map[string]float64 `json:"weights"`
+	MeanRecall float64            `json:"mean_recall"`
+}
+
+// TuneResult is the outcome of a weight search.
+type TuneResult struct {
+	K         int           `json:"k"`
+	Cases     int           `json:"cases"`
+	Sources   []string      `json:"sources"`
+	Baseline  WeightScore   `json:"baseline"`
+	Best      WeightScore   `json:"best"`
+	Evaluated []WeightScore `json:"evaluated"` // Sorted by recall, best first
+}
+
+// WeightTuner grid-searches RRF weights to maximize mean recall@k against
+// test case ground truth, scoring with the same Validator as full runs.
+type WeightTuner struct {
+	// K is the recall cutoff: only the top K fused results are scored
+	K int
+
+	// Step is the grid spacing; weights are multiples of Step summing to 1
+	Step float64
+
+	validator *Validator
+}
+
+// NewWeightTuner creates a tuner scoring recall@k with grid spacing step.
+func NewWeightTuner(k int, step float64) *WeightTuner {
+	if k <= 0 {
+		k = 10
+	}
+	if step <= 0 || step > 1 {
+		step = 0.1
+	}
+	return &WeightTuner{K: k, Step: step, validator: NewValidator()}
+}
+
+// Tune evaluates every weighting on the grid for the sources present in
+// cases and returns the best, along with the baseline's score for
+// comparison. Ties keep the weighting closest to the baseline.
+func (t *WeightTuner) Tune(cases []RetrievalCase, baseline map[string]float64) TuneResult {
+	sources := caseSources(cases)
+	result := TuneResult{
+		K:        t.K,
+		Cases:    len(cases),
+		Sources:  sources,
+		Baseline: t.Score(cases, baseline),
+	}
+
+	for _, weights := range weightGrid(sources, t.Step) {
+		result.Evaluated = append(result.Evaluated, t.Score(cases, weights))
+	}
+
+	sort.SliceStable(result.Evaluated, func(i, j int) bool {
+		a, b := result.Evaluated[i], result.Evaluated[j]
+		if a.MeanRecall != b.MeanRecall {
+			return a.MeanRecall > b.MeanRecall
+		}
+		return weightDistance(a.Weights, baseline) < weightDistance(b.Weights, baseline)
+	})
+
+	result.Best = result.Baseline
+	if len(result.Evaluated) > 0 && result.Evaluated[0].MeanRecall > result.Baseline.MeanRecall {
+		result.Best = result.Evaluated[0]
+	}
+	return result
+}
+
+// Score fuses each case's lists with weights and returns mean recall@k.
+func (t *WeightTuner) Score(cases []RetrievalCase, weights map[string]float64) WeightScore {
+	score := WeightScore{Weights: weights}
+	if len(cases) == 0 {
+		return score
+	}
+
+	var total float64
+	for _, rc := range cases {
+		fused := fusion.TopN(fusion.WeightedRRF(weights, rc.Lists...), t.K)
+		vr := t.validator.Validate(rc.TestCase, NewRetrievalRun(rc.TestCase, ModeHybrid, fused))
+		total += vr.Recall
+	}
+	score.MeanRecall = total / float64(len(cases))
+	return score
+}
+
+// RetrievalOutput renders ranked results as validator input: one path per
+// result followed by its snippet, so both file and symbol ground truth can
+// be matched.
+func RetrievalOutput(results []fusion.RRFResult) string {
+	var sb strings.Builder
+	for _, r := range results {
+		sb.WriteString(r.Path)
+		sb.WriteString("\n")
+		if r.Snippet != "" {
+			sb.WriteString(r.Snippet)
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// caseSources returns the distinct result sources across all cases, sorted.
+func caseSources(cases []RetrievalCase) []string {
+	seen := make(map[string]bool)
+	for _, rc := range cases {
+		for _, list := range rc.Lists {
+			for _, r := range list {
+				seen[r.Source] = true
+			}
+		}
+	}
+
+	sources := make([]string, 0, len(seen))
+	for s := range seen {
+		sources = append(sources, s)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// weightGrid returns every assignment of weights to sources that are
+// multiples of step and sum to 1. Zero weights are skipped because
+// WeightedRRF treats 0 as "unweighted" (1.0), so the smallest weight is step.
+func weightGrid(sources []string, step float64) []map[string]float64 {
+	units := int(math.Round(1 / step))
+	if len(sources) == 0 || units < len(sources) {
+		return nil
+	}
+
+	var grid []map[string]float64
+	counts := make([]int, len(sources))
+	var fill func(i, remaining int)
+	fill = func(i, remaining int) {
+		if i == len(sources)-1 {
+			counts[i] = remaining
+			weights := make(map[string]float64, len(sources))
+			for j, s := range sources {
+				weights[s] = math.Round(float64(counts[j])/float64(units)*1000) / 1000
+			}
+			grid = append(grid, weights)
+			return
+		}
+		// Leave at least one unit for each later source
+		for n := 1; n <= remaining-(len(sources)-1-i); n++ {
+			counts[i] = n
+			fill(i+1, remaining-n)
+		}
+	}
+	fill(0, units)
+	return grid
+}
+
+// weightDistance is the L1 distance between two weightings.
+func weightDistance(a, b map[string]float64) float64 {
+	var d float64
+	for s, w := range a {
+		d += math.Abs(w - b[s])
+	}
+	for s, w := range b {
+		if _, ok := a[s]; !ok {
+			d += math.Abs(w)
+		}
+	}
+	return d
+}
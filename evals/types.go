@@ -2,6 +2,7 @@
 package evals
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -47,6 +48,8 @@ type RunResult struct {
 	CostUSD       float64       `json:"cost_usd,omitempty"`
 	NumTurns      int           `json:"num_turns,omitempty"`
 	ToolCallCount int           `json:"tool_call_count,omitempty"`
+	RankedFiles   []string      `json:"ranked_files,omitempty"` // Result paths in rank order (retrieval modes only)
+	TimedOut      bool          `json:"timed_out,omitempty"` // Killed at the timeout; counts cover what ran before it
 	Error         string        `json:"error,omitempty"`
 }
 
@@ -57,6 +60,8 @@ type ValidationResult struct {
 	Precision  float64       `json:"precision"` // Correct items / Total returned
 	Recall     float64       `json:"recall"`    // Correct items / Total expected
 	F1Score    float64       `json:"f1_score"`  // Harmonic mean of precision and recall
+	ReciprocalRank float64   `json:"reciprocal_rank,omitempty"` // 1 / rank of the first correct file (ranked runs only)
+	NDCG       float64       `json:"ndcg,omitempty"`            // nDCG@k over ranked files (ranked runs only)
 	FilesFound []string      `json:"files_found"`
 	FilesMissed []string     `json:"files_missed"`
 	SymbolsFound []string    `json:"symbols_found"`
@@ -121,6 +126,7 @@ type ModeStats struct {
 	AvgTurns            float64       `json:"avg_turns"`
 	SuccessRate         float64       `json:"success_rate"`
 	TotalToolCalls      int           `json:"total_tool_calls"`
+	TimedOut            int           `json:"timed_out,omitempty"` // Runs killed at the timeout
 }
 
 // ComparisonResult compares results between modes for a single test case.
@@ -152,6 +158,28 @@ type ClaudeStreamEvent struct {
 	NumTurns  int     `json:"num_turns,omitempty"`
 	TotalCost float64 `json:"total_cost_usd,omitempty"`
 	Usage     *ClaudeUsage `json:"usage,omitempty"`
+	Model     string         `json:"model,omitempty"`   // Set on the system init event
+	Message   *ClaudeMessage `json:"message,omitempty"` // Set on assistant and user events
+}
+
+// ClaudeMessage is the message carried by an assistant or user stream event.
+type ClaudeMessage struct {
+	ID      string               `json:"id,omitempty"`
+	Content []ClaudeContentBlock `json:"content"`
+	Usage   *ClaudeUsage         `json:"usage,omitempty"`
+}
+
+// ClaudeContentBlock is one block of a stream message: text, a tool_use
+// call from the assistant, or the tool_result sent back for it.
+type ClaudeContentBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`          // tool_use
+	Name      string          `json:"name,omitempty"`        // tool_use
+	Input     json.RawMessage `json:"input,omitempty"`       // tool_use
+	ToolUseID string          `json:"tool_use_id,omitempty"` // tool_result
+	Content   json.RawMessage `json:"content,omitempty"`     // tool_result: a string or text blocks
+	IsError   bool            `json:"is_error,omitempty"`    // tool_result
 }
 
 // ClaudeUsage represents token usage from Claude's output.
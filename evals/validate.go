@@ -1,6 +1,7 @@
 package evals
 
 import (
+	"math"
 	"regexp"
 	"strings"
 )
@@ -75,6 +76,88 @@ func (v *Validator) Validate(tc TestCase, result RunResult) ValidationResult {
 		vr.F1Score = 2 * (vr.Precision * vr.Recall) / (vr.Precision + vr.Recall)
 	}
 
+	// Rank-sensitive metrics need the ordered result list
+	if len(result.RankedFiles) > 0 {
+		vr.ReciprocalRank, vr.NDCG = rankMetrics(result.RankedFiles, expectedFiles)
+	}
+
+	return vr
+}
+
+// rankMetrics computes the reciprocal rank and nDCG@k of ranked files
+// against the expected files, with binary relevance. Ranks are positions
+// among distinct files, so several chunks from one file count once, and
+// each expected file is credited at most once. k is the number of
+// distinct ranked files.
+func rankMetrics(ranked, expected []string) (reciprocalRank, ndcg float64) {
+	if len(expected) == 0 {
+		return 0, 0
+	}
+
+	credited := make(map[string]bool)
+	seen := make(map[string]bool)
+	var dcg float64
+	rank := 0
+	for _, f := range ranked {
+		if seen[f] {
+			continue
+		}
+		seen[f] = true
+		rank++
+
+		fLower := strings.ToLower(f)
+		for _, e := range expected {
+			if credited[e] || !containsPath([]string{e}, fLower) {
+				continue
+			}
+			credited[e] = true
+			if reciprocalRank == 0 {
+				reciprocalRank = 1 / float64(rank)
+			}
+			dcg += 1 / math.Log2(float64(rank+1))
+			break
+		}
+	}
+
+	// Ideal ordering puts every expected file first
+	var idcg float64
+	for i := 1; i <= min(len(expected), rank); i++ {
+		idcg += 1 / math.Log2(float64(i+1))
+	}
+	if idcg > 0 {
+		ndcg = dcg / idcg
+	}
+	return reciprocalRank, ndcg
+}
+
+// ValidateRetrieval scores a retrieval run. Recall comes from Validate;
+// because the returned files are known, precision is the fraction of
+// distinct returned files that match a ground truth file rather than
+// Validate's approximation. Cases without ground truth files fall back
+// to Validate's scores.
+func (v *Validator) ValidateRetrieval(tc TestCase, result RunResult) ValidationResult {
+	vr := v.Validate(tc, result)
+	if !result.Success || len(tc.GroundTruth.Files) == 0 || len(result.RankedFiles) == 0 {
+		return vr
+	}
+
+	seen := make(map[string]bool)
+	relevant := 0
+	for _, f := range result.RankedFiles {
+		if seen[f] {
+			continue
+		}
+		seen[f] = true
+		if containsPath(tc.GroundTruth.Files, strings.ToLower(f)) {
+			relevant++
+		}
+	}
+
+	vr.Precision = float64(relevant) / float64(len(seen))
+	vr.F1Score = 0
+	if vr.Precision+vr.Recall > 0 {
+		vr.F1Score = 2 * (vr.Precision * vr.Recall) / (vr.Precision + vr.Recall)
+	}
 	return vr
 }
 
@@ -24,7 +24,8 @@ const MinGapLines = 3
 // It splits code at natural AST boundaries (functions, classes, methods) to
 // produce more semantically coherent chunks for embedding.
 type ASTChunker struct {
-	OverlapLines int // Lines of context to include from adjacent chunks (for future use)
+	OverlapLines       int  // Lines of context to include from adjacent chunks (for future use)
+	ExtractDocComments bool // Populate Chunk.DocComment from leading comments/docstrings
 }
 
 // NewASTChunker creates a new ASTChunker with default settings.
@@ -89,6 +90,9 @@ func (c *ASTChunker) walkTree(node *sitter.Node, content []byte, path string, co
 
 	if splitNodes[nodeType] {
 		chunk := c.nodeToChunk(node, content, path, config)
+		if c.ExtractDocComments {
+			chunk.DocComment = extractDocComment(node, content, config)
+		}
 		if chunk.LineCount() > 0 {
 			*chunks = append(*chunks, chunk)
 
@@ -307,12 +311,13 @@ func sortChunks(chunks []Chunk) {
 
 // ChunkFileWithOptions allows customization of chunking behavior.
 type ChunkOptions struct {
-	MaxChunkSize     int  // Override default max chunk size
-	IncludeGaps      bool // Include gap chunks for uncovered regions
-	FallbackEnabled  bool // Enable fallback for unsupported languages
-	ComputeHashes    bool // Compute content hashes
-	FallbackChunkSize int // Lines per chunk in fallback mode
-	FallbackOverlap   int // Overlap lines in fallback mode
+	MaxChunkSize       int  // Override default max chunk size
+	IncludeGaps        bool // Include gap chunks for uncovered regions
+	FallbackEnabled    bool // Enable fallback for unsupported languages
+	ComputeHashes      bool // Compute content hashes
+	FallbackChunkSize  int  // Lines per chunk in fallback mode
+	FallbackOverlap    int  // Overlap lines in fallback mode
+	ExtractDocComments bool // Populate Chunk.DocComment from leading comments/docstrings
 }
 
 // DefaultChunkOptions returns the default chunking options.
@@ -361,10 +366,17 @@ func (c *ASTChunker) ChunkFileWithOptions(ctx context.Context, path string, cont
 		splitNodeSet[nodeType] = true
 	}
 
+	walker := c
+	if opts.ExtractDocComments && !c.ExtractDocComments {
+		withDocs := *c
+		withDocs.ExtractDocComments = true
+		walker = &withDocs
+	}
+
 	var chunks []Chunk
 	covered := make(map[int]bool)
 
-	c.walkTree(root, content, path, &effectiveConfig, splitNodeSet, &chunks, covered)
+	walker.walkTree(root, content, path, &effectiveConfig, splitNodeSet, &chunks, covered)
 
 	if opts.IncludeGaps {
 		c.fillGaps(content, path, &effectiveConfig, covered, &chunks)
@@ -9,16 +9,17 @@ import (
 // It contains positional information, content, and metadata about the
 // AST node it was extracted from.
 type Chunk struct {
-	Path        string `json:"path"`         // File path
-	StartLine   int    `json:"start_line"`   // 1-indexed start line
-	EndLine     int    `json:"end_line"`     // 1-indexed end line (inclusive)
-	StartByte   int    `json:"start_byte"`   // Byte offset of chunk start
-	EndByte     int    `json:"end_byte"`     // Byte offset of chunk end
-	Content     string `json:"content"`      // The actual code content
-	ContentHash string `json:"content_hash"` // SHA-256 hex hash of content
-	NodeType    string `json:"node_type"`    // AST node type (e.g., "function_declaration")
-	NodeName    string `json:"node_name"`    // Symbol name if applicable (e.g., function name)
-	Language    string `json:"language"`     // Language identifier
+	Path        string `json:"path"`                  // File path
+	StartLine   int    `json:"start_line"`            // 1-indexed start line
+	EndLine     int    `json:"end_line"`              // 1-indexed end line (inclusive)
+	StartByte   int    `json:"start_byte"`            // Byte offset of chunk start
+	EndByte     int    `json:"end_byte"`              // Byte offset of chunk end
+	Content     string `json:"content"`               // The actual code content
+	ContentHash string `json:"content_hash"`          // SHA-256 hex hash of content
+	NodeType    string `json:"node_type"`             // AST node type (e.g., "function_declaration")
+	NodeName    string `json:"node_name"`             // Symbol name if applicable (e.g., function name)
+	Language    string `json:"language"`              // Language identifier
+	DocComment  string `json:"doc_comment,omitempty"` // Leading doc comment or docstring, if extracted
 }
 
 // ComputeHash calculates and sets the content hash using SHA-256.
@@ -1167,6 +1167,136 @@ func b() {}
 	}
 }
 
+// =============================================================================
+// Doc Comment Tests
+// =============================================================================
+
+func TestExtractDocComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		content  string
+		nodeName string
+		want     string
+	}{
+		{
+			name: "go line comments",
+			path: "config.go",
+			content: `package main
+
+var x = 1 // not a doc comment
+// LoadConfig parses the config file
+// and applies defaults.
+func LoadConfig() {}
+`,
+			nodeName: "LoadConfig",
+			want:     "LoadConfig parses the config file\nand applies defaults.",
+		},
+		{
+			name: "go comment separated by blank line",
+			path: "config.go",
+			content: `package main
+
+// Unrelated header comment
+
+func LoadConfig() {}
+`,
+			nodeName: "LoadConfig",
+			want:     "",
+		},
+		{
+			name: "python docstring",
+			path: "config.py",
+			content: `def load_config(path):
+    """Parse the config file.
+
+    Returns a dict.
+    """
+    return {}
+`,
+			nodeName: "load_config",
+			want:     "Parse the config file.\n\nReturns a dict.",
+		},
+		{
+			name: "jsdoc on export",
+			path: "config.js",
+			content: `/**
+ * Parse the config file.
+ * @param {string} path
+ */
+export function loadConfig(path) {
+  return {};
+}
+`,
+			nodeName: "",
+			want:     "Parse the config file.\n@param {string} path",
+		},
+		{
+			name: "rust doc comments",
+			path: "config.rs",
+			content: `/// Parse the config file.
+#[inline]
+fn load_config() {}
+`,
+			nodeName: "load_config",
+			want:     "Parse the config file.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunker := NewASTChunker()
+			chunker.ExtractDocComments = true
+
+			chunks, err := chunker.ChunkFile(context.Background(), tt.path, []byte(tt.content))
+			if err != nil {
+				t.Fatalf("ChunkFile failed: %v", err)
+			}
+
+			matches := filterChunks(chunks, func(c Chunk) bool {
+				return c.NodeType != "gap" && c.NodeName == tt.nodeName
+			})
+			if len(matches) == 0 {
+				t.Fatalf("no chunk named %q in %+v", tt.nodeName, chunks)
+			}
+			if got := matches[0].DocComment; got != tt.want {
+				t.Errorf("DocComment = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocCommentsDisabledByDefault(t *testing.T) {
+	content := `package main
+
+// LoadConfig parses the config file.
+func LoadConfig() {}
+`
+	chunker := NewASTChunker()
+	chunks, err := chunker.ChunkFile(context.Background(), "config.go", []byte(content))
+	if err != nil {
+		t.Fatalf("ChunkFile failed: %v", err)
+	}
+	for _, c := range chunks {
+		if c.DocComment != "" {
+			t.Errorf("expected no doc comment by default, got %q", c.DocComment)
+		}
+	}
+
+	opts := DefaultChunkOptions()
+	opts.ExtractDocComments = true
+	chunks, err = chunker.ChunkFileWithOptions(context.Background(), "config.go", []byte(content), opts)
+	if err != nil {
+		t.Fatalf("ChunkFileWithOptions failed: %v", err)
+	}
+	funcChunks := filterChunks(chunks, func(c Chunk) bool {
+		return c.NodeType == "function_declaration"
+	})
+	if len(funcChunks) != 1 || funcChunks[0].DocComment != "LoadConfig parses the config file." {
+		t.Errorf("expected doc comment with ExtractDocComments option, got %+v", funcChunks)
+	}
+}
+
 // =============================================================================
 // Benchmark Tests
 // =============================================================================
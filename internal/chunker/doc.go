@@ -0,0 +1,160 @@
+package chunker
+
+import (
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// extractDocComment returns the natural-language documentation for a node:
+// the Python docstring, or the comment block directly above the node
+// (Go //, JSDoc /** */, Rust ///, etc.). Returns "" if there is none.
+func extractDocComment(node *sitter.Node, content []byte, config *LanguageConfig) string {
+	if config.Name == "python" {
+		if doc := pythonDocstring(node, content); doc != "" {
+			return doc
+		}
+	}
+
+	comments := leadingComments(node, content)
+	if len(comments) == 0 {
+		// Exported JS/TS declarations carry their JSDoc on the export statement
+		if parent := node.Parent(); parent != nil && parent.Type() == "export_statement" {
+			comments = leadingComments(parent, content)
+		}
+	}
+
+	cleaned := make([]string, 0, len(comments))
+	for _, c := range comments {
+		if text := cleanComment(c); text != "" {
+			cleaned = append(cleaned, text)
+		}
+	}
+	return strings.Join(cleaned, "\n")
+}
+
+// leadingComments collects the contiguous comment siblings immediately above
+// a node, in source order. A blank line or a trailing comment on a code line
+// ends the block.
+func leadingComments(node *sitter.Node, content []byte) []string {
+	var comments []string
+	row := node.StartPoint().Row
+
+	for sib := node.PrevSibling(); sib != nil; sib = sib.PrevSibling() {
+		nodeType := sib.Type()
+
+		// Attributes and decorators may sit between the doc and the item
+		if nodeType == "attribute_item" || nodeType == "decorator" {
+			row = sib.StartPoint().Row
+			continue
+		}
+
+		if !strings.Contains(nodeType, "comment") {
+			break
+		}
+		if sib.EndPoint().Row+1 < row || !startsLine(content, int(sib.StartByte())) {
+			break
+		}
+
+		comments = append(comments, sib.Content(content))
+		row = sib.StartPoint().Row
+	}
+
+	// Collected bottom-up; restore source order
+	for i, j := 0, len(comments)-1; i < j; i, j = i+1, j-1 {
+		comments[i], comments[j] = comments[j], comments[i]
+	}
+	return comments
+}
+
+// pythonDocstring returns the docstring of a Python function or class definition.
+func pythonDocstring(node *sitter.Node, content []byte) string {
+	def := node
+	if node.Type() == "decorated_definition" {
+		if d := node.ChildByFieldName("definition"); d != nil {
+			def = d
+		}
+	}
+
+	body := def.ChildByFieldName("body")
+	if body == nil || body.NamedChildCount() == 0 {
+		return ""
+	}
+
+	first := body.NamedChild(0)
+	if first.Type() != "expression_statement" || first.NamedChildCount() == 0 {
+		return ""
+	}
+
+	str := first.NamedChild(0)
+	if str.Type() != "string" {
+		return ""
+	}
+
+	return cleanDocstring(str.Content(content))
+}
+
+// startsLine reports whether only whitespace precedes offset on its line.
+func startsLine(content []byte, offset int) bool {
+	for i := offset - 1; i >= 0; i-- {
+		switch content[i] {
+		case '\n':
+			return true
+		case ' ', '\t':
+			continue
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// cleanComment strips comment markers and leading asterisks from a comment.
+func cleanComment(raw string) string {
+	raw = strings.TrimSpace(raw)
+
+	block := strings.HasPrefix(raw, "/*")
+	if block {
+		raw = strings.TrimPrefix(raw, "/**")
+		raw = strings.TrimPrefix(raw, "/*")
+		raw = strings.TrimSuffix(raw, "*/")
+	}
+
+	lines := strings.Split(raw, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if block {
+			line = strings.TrimPrefix(line, "*")
+		} else {
+			for _, marker := range []string{"///", "//!", "//", "#"} {
+				if strings.HasPrefix(line, marker) {
+					line = strings.TrimPrefix(line, marker)
+					break
+				}
+			}
+		}
+		lines[i] = strings.TrimSpace(line)
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// cleanDocstring strips string prefixes and quotes from a Python docstring
+// and removes per-line indentation.
+func cleanDocstring(raw string) string {
+	raw = strings.TrimLeft(strings.TrimSpace(raw), "rRbBuUfF")
+
+	for _, quote := range []string{`"""`, `'''`, `"`, `'`} {
+		if strings.HasPrefix(raw, quote) && strings.HasSuffix(raw, quote) && len(raw) >= 2*len(quote) {
+			raw = raw[len(quote) : len(raw)-len(quote)]
+			break
+		}
+	}
+
+	lines := strings.Split(raw, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
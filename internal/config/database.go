@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"codetect/internal/db"
@@ -29,6 +30,8 @@ type DatabaseConfig struct {
 //   - CODETECT_DB_DSN: Connection string for PostgreSQL
 //   - CODETECT_DB_PATH: Database file path for SQLite
 //   - CODETECT_VECTOR_DIMENSIONS: Vector dimensions (default: 768)
+//   - CODETECT_EMBEDDING_TRUNCATE_DIM: Matryoshka truncation size, which
+//     overrides CODETECT_VECTOR_DIMENSIONS since stored vectors are truncated
 //
 // If no environment variables are set, defaults to SQLite with standard path.
 func LoadDatabaseConfigFromEnv() DatabaseConfig {
@@ -72,6 +75,15 @@ func LoadDatabaseConfigFromEnv() DatabaseConfig {
 			cfg.VectorDimensions = d
 		}
 	}
+	if dims := os.Getenv("CODETECT_EMBEDDING_TRUNCATE_DIM"); dims != "" {
+		var d int
+		if _, err := fmt.Sscanf(dims, "%d", &d); err == nil && d > 0 {
+			if os.Getenv("CODETECT_VECTOR_DIMENSIONS") != "" && d != cfg.VectorDimensions {
+				fmt.Fprintf(os.Stderr, "Warning: CODETECT_VECTOR_DIMENSIONS=%d ignored, embeddings are truncated to CODETECT_EMBEDDING_TRUNCATE_DIM=%d\n", cfg.VectorDimensions, d)
+			}
+			cfg.VectorDimensions = d
+		}
+	}
 
 	return cfg
 }
@@ -95,7 +107,7 @@ func (c DatabaseConfig) ToDBConfig() db.Config {
 		path := c.Path
 		if path == "" {
 			// Default path if not specified
-			path = ".codetect/symbols.db"
+			path = filepath.Join(DataDir("."), "symbols.db")
 		}
 		return db.DefaultConfig(path)
 	}
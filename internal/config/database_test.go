@@ -102,6 +102,17 @@ func TestLoadDatabaseConfigFromEnv(t *testing.T) {
 		os.Unsetenv("CODETECT_VECTOR_DIMENSIONS")
 	})
 
+	t.Run("Truncated Embeddings Set Vector Dimensions", func(t *testing.T) {
+		t.Setenv("CODETECT_VECTOR_DIMENSIONS", "768")
+		t.Setenv("CODETECT_EMBEDDING_TRUNCATE_DIM", "256")
+
+		cfg := LoadDatabaseConfigFromEnv()
+
+		if cfg.VectorDimensions != 256 {
+			t.Errorf("Expected truncated dimensions 256, got %d", cfg.VectorDimensions)
+		}
+	})
+
 	t.Run("Invalid Database Type Falls Back to SQLite", func(t *testing.T) {
 		os.Setenv("CODETECT_DB_TYPE", "invalid")
 
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DataDirName is the in-tree directory holding a repo's index data.
+const DataDirName = ".codetect"
+
+// DataDir returns the directory holding the index data for the repository
+// at root: the SQLite databases, Merkle trees, HNSW graph, and eval cases,
+// results and logs. By default this is <root>/.codetect.
+//
+// Setting CODETECT_DATA_DIR moves it out of the tree, for read-only or
+// sandboxed checkouts, to <CODETECT_DATA_DIR>/<repo>, where <repo> is a
+// path-safe form of the checkout's path ending in a short hash of it. One
+// setting relocates every repo, e.g. CODETECT_DATA_DIR=$XDG_CACHE_HOME/codetect.
+// The path is used rather than RepoID, since the databases here belong to
+// one checkout even when several share a remote-derived key.
+func DataDir(root string) string {
+	base := strings.TrimSpace(os.Getenv("CODETECT_DATA_DIR"))
+	if base == "" {
+		return filepath.Join(root, DataDirName)
+	}
+	return filepath.Join(base, dataDirKey(NormalizeRepoRoot(root)))
+}
+
+// InTreeDataDir reports whether DataDir is inside the repository, in which
+// case callers may want it listed in the repo's .gitignore.
+func InTreeDataDir() bool {
+	return strings.TrimSpace(os.Getenv("CODETECT_DATA_DIR")) == ""
+}
+
+// dataDirKey flattens a checkout path into a single directory name, so
+// nested repos don't land inside each other's data directories. Flattening
+// alone would map paths such as "/a/b" and "/a_b" to the same name, so a
+// short hash of the full path is appended.
+func dataDirKey(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	name := strings.Trim(filepath.ToSlash(id), "/")
+	name = strings.NewReplacer("/", "_", ":", "_", "\\", "_").Replace(name)
+	return name + "-" + hex.EncodeToString(sum[:4])
+}
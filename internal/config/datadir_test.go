@@ -0,0 +1,36 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDataDir(t *testing.T) {
+	t.Setenv("CODETECT_DATA_DIR", "")
+	t.Setenv("CODETECT_REPO_ID", "")
+	if got := DataDir("/home/me/repo"); got != filepath.Join("/home/me/repo", ".codetect") {
+		t.Errorf("DataDir() = %q, want the in-tree .codetect", got)
+	}
+	if !InTreeDataDir() {
+		t.Error("InTreeDataDir() = false with CODETECT_DATA_DIR unset")
+	}
+
+	t.Setenv("CODETECT_DATA_DIR", "/cache/codetect")
+	if got := DataDir("/home/me/repo"); got != "/cache/codetect/home_me_repo-74b392f5" {
+		t.Errorf("DataDir() = %q, want the path flattened under the override", got)
+	}
+	if InTreeDataDir() {
+		t.Error("InTreeDataDir() = true with CODETECT_DATA_DIR set")
+	}
+
+	// Checkouts sharing a repo key still get their own directories
+	t.Setenv("CODETECT_REPO_ID", "github.com/org/repo")
+	if DataDir("/home/me/repo") == DataDir("/home/me/repo-worktree") {
+		t.Error("DataDir() puts two checkouts with one repo key in the same directory")
+	}
+
+	// Paths that flatten alike still get their own directories
+	if dataDirKey("/home/me/repo") == dataDirKey("/home/me_repo") {
+		t.Error("dataDirKey() maps me/repo and me_repo to the same directory")
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// DefaultIgnoredDirs lists directory names skipped by indexing and watching.
+var DefaultIgnoredDirs = []string{
+	// Version control
+	".git", ".svn", ".hg",
+
+	// IDE/Editor
+	".idea", ".vscode",
+
+	// Build outputs
+	"dist", "build", "target", "out",
+
+	// Dependencies
+	"node_modules", "vendor", ".bundle", "Pods",
+
+	// Python
+	"__pycache__", ".venv", "venv", "env", ".tox", ".pytest_cache",
+
+	// Ruby/Rails
+	"tmp", "log", "coverage", "sorbet",
+
+	// Generated/Cache
+	".cache", ".codetect", ".next", ".nuxt", ".turbo", ".parcel-cache",
+}
+
+// IgnoredDirs returns the set of directory names to skip when walking a
+// repository: DefaultIgnoredDirs plus any names in CODETECT_IGNORE_DIRS
+// (comma-separated, e.g. ".gradle,cmake-build-debug").
+func IgnoredDirs() map[string]bool {
+	ignored := make(map[string]bool, len(DefaultIgnoredDirs))
+	for _, name := range DefaultIgnoredDirs {
+		ignored[name] = true
+	}
+
+	for _, name := range strings.Split(os.Getenv("CODETECT_IGNORE_DIRS"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			ignored[name] = true
+		}
+	}
+
+	return ignored
+}
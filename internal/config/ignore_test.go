@@ -0,0 +1,47 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestIgnoredDirs(t *testing.T) {
+	t.Setenv("CODETECT_IGNORE_DIRS", "")
+
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"node_modules", true},
+		{"vendor", true},
+		{"dist", true},
+		{".git", true},
+		{".codetect", true},
+		{"src", false},
+		{"internal", false},
+		{"pkg", false},
+	}
+
+	ignored := IgnoredDirs()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ignored[tt.name] != tt.expected {
+				t.Errorf("IgnoredDirs()[%q] = %v, want %v", tt.name, ignored[tt.name], tt.expected)
+			}
+		})
+	}
+}
+
+func TestIgnoredDirsFromEnv(t *testing.T) {
+	t.Setenv("CODETECT_IGNORE_DIRS", ".gradle, cmake-build-debug,,")
+
+	ignored := IgnoredDirs()
+	if !ignored[".gradle"] || !ignored["cmake-build-debug"] {
+		t.Errorf("expected env dirs to be ignored, got %v", ignored)
+	}
+	if !ignored["node_modules"] {
+		t.Error("env dirs should be appended to defaults, not replace them")
+	}
+	if ignored[""] {
+		t.Error("empty entries should be skipped")
+	}
+}
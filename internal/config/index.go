@@ -1,7 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -23,11 +26,64 @@ const (
 type IndexConfig struct {
 	// Backend specifies which indexing tool to use
 	Backend IndexBackend
+
+	// DocComments enables extracting and embedding doc comments separately
+	// from code chunks (v2 indexer only)
+	DocComments bool
+
+	// ContentHash detects changed files by content hash (a Merkle tree,
+	// like the v2 indexer) instead of mtime+size (v1 symbol indexer only)
+	ContentHash bool
+
+	// MaxDepth stops directory walks this many levels below the repo root,
+	// a guard against deeply nested vendored trees the ignore list misses.
+	// 0 means unlimited.
+	MaxDepth int
+
+	// OneFileSystem keeps directory walks on the root's device, so
+	// mounts inside the tree are not indexed or watched
+	OneFileSystem bool
+
+	// MaxChunkChars overrides the embedding chunker's content size limit.
+	// 0 keeps the chunker default.
+	MaxChunkChars int
+
+	// KeywordIndex fills a SQLite FTS5 table with chunk contents during
+	// embed, and has keyword search answer literal queries from it
+	// before running ripgrep
+	KeywordIndex bool
+
+	// Workers caps how many files the v2 indexer chunks, and batches it
+	// embeds, at once. 0 keeps the indexer default.
+	Workers int
+
+	// BatchBytes caps the file content the v2 indexer reads, chunks and
+	// embeds per batch. 0 keeps the indexer default.
+	BatchBytes int64
+
+	// Ctags tunes what universal-ctags extracts for the symbol index
+	Ctags CtagsOptions
+}
+
+// CtagsOptions tunes a ctags run. Args are appended after codetect's own
+// defaults (all kinds, qualified extras), so they can add or take away
+// kinds per language, e.g. "--kinds-go=-p" or "--extras=-q".
+type CtagsOptions struct {
+	Args []string
 }
 
 // LoadIndexConfigFromEnv loads indexing configuration from environment variables.
-// Supports the following variable:
+// Supports the following variables:
 //   - CODETECT_INDEX_BACKEND: Backend to use ("auto", "ast-grep", or "ctags")
+//   - CODETECT_INDEX_DOC_COMMENTS: Embed doc comments separately (default: false)
+//   - CODETECT_INDEX_CONTENT_HASH: Detect symbol index changes by content hash (default: false)
+//   - CODETECT_INDEX_MAX_DEPTH: Deepest directory level walked, 0 for unlimited (default: 0)
+//   - CODETECT_INDEX_ONE_FILE_SYSTEM: Don't cross device boundaries while walking (default: false)
+//   - CODETECT_INDEX_MAX_CHUNK_CHARS: Largest embedded chunk in bytes (default: chunker default)
+//   - CODETECT_INDEX_KEYWORD: Build an FTS5 keyword index during embed, SQLite only (default: false)
+//   - CODETECT_INDEX_WORKERS: Concurrent v2 chunking and embedding workers (default: indexer default)
+//   - CODETECT_INDEX_BATCH_BYTES: File content per v2 indexing batch in bytes (default: indexer default)
+//   - CODETECT_CTAGS_OPTIONS: Extra space-separated ctags arguments (default: none)
 //
 // If no environment variable is set, defaults to "auto" (hybrid approach).
 func LoadIndexConfigFromEnv() IndexConfig {
@@ -49,9 +105,82 @@ func LoadIndexConfigFromEnv() IndexConfig {
 		}
 	}
 
+	if v := os.Getenv("CODETECT_INDEX_DOC_COMMENTS"); v != "" {
+		cfg.DocComments = parseBool(v, false)
+	}
+
+	if v := os.Getenv("CODETECT_INDEX_CONTENT_HASH"); v != "" {
+		cfg.ContentHash = parseBool(v, false)
+	}
+
+	if v := os.Getenv("CODETECT_INDEX_MAX_DEPTH"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.MaxDepth = n
+		} else {
+			fmt.Fprintf(os.Stderr, "Warning: Invalid CODETECT_INDEX_MAX_DEPTH %q, walking all directories\n", v)
+		}
+	}
+
+	if v := os.Getenv("CODETECT_INDEX_ONE_FILE_SYSTEM"); v != "" {
+		cfg.OneFileSystem = parseBool(v, false)
+	}
+
+	if v := os.Getenv("CODETECT_INDEX_MAX_CHUNK_CHARS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.MaxChunkChars = n
+		} else {
+			fmt.Fprintf(os.Stderr, "Warning: Invalid CODETECT_INDEX_MAX_CHUNK_CHARS %q, using default\n", v)
+		}
+	}
+
+	if v := os.Getenv("CODETECT_INDEX_KEYWORD"); v != "" {
+		cfg.KeywordIndex = parseBool(v, false)
+	}
+
+	if v := os.Getenv("CODETECT_INDEX_WORKERS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.Workers = n
+		} else {
+			fmt.Fprintf(os.Stderr, "Warning: Invalid CODETECT_INDEX_WORKERS %q, using default\n", v)
+		}
+	}
+
+	if v := os.Getenv("CODETECT_INDEX_BATCH_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			cfg.BatchBytes = n
+		} else {
+			fmt.Fprintf(os.Stderr, "Warning: Invalid CODETECT_INDEX_BATCH_BYTES %q, using default\n", v)
+		}
+	}
+
+	for _, arg := range strings.Fields(os.Getenv("CODETECT_CTAGS_OPTIONS")) {
+		if !strings.HasPrefix(arg, "-") {
+			// Anything else would be taken as a file to tag
+			fmt.Fprintf(os.Stderr, "Warning: Ignoring CODETECT_CTAGS_OPTIONS argument %q, options must start with -\n", arg)
+			continue
+		}
+		cfg.Ctags.Args = append(cfg.Ctags.Args, arg)
+	}
+
 	return cfg
 }
 
+// BeyondMaxDepth reports whether the directory at relDir, relative to the
+// repo root, is deeper than MaxDepth and should not be walked. The root is
+// depth 0 and its subdirectories depth 1.
+func (c IndexConfig) BeyondMaxDepth(relDir string) bool {
+	return c.MaxDepth > 0 && DirDepth(relDir) > c.MaxDepth
+}
+
+// DirDepth returns how many levels below the repo root relDir is
+func DirDepth(relDir string) int {
+	relDir = strings.Trim(filepath.ToSlash(filepath.Clean(relDir)), "/")
+	if relDir == "." || relDir == "" {
+		return 0
+	}
+	return strings.Count(relDir, "/") + 1
+}
+
 // UseAstGrep returns true if ast-grep should be used for indexing
 func (c IndexConfig) UseAstGrep() bool {
 	return c.Backend == IndexBackendAuto || c.Backend == IndexBackendAstGrep
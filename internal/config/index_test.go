@@ -0,0 +1,50 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestBeyondMaxDepth(t *testing.T) {
+	cfg := IndexConfig{MaxDepth: 2}
+	tests := []struct {
+		dir      string
+		expected bool
+	}{
+		{".", false},
+		{"", false},
+		{"src", false},
+		{"src/pkg", false},
+		{"src/pkg/deep", true},
+		{"vendor/a/b/c", true},
+	}
+	for _, tt := range tests {
+		if got := cfg.BeyondMaxDepth(tt.dir); got != tt.expected {
+			t.Errorf("BeyondMaxDepth(%q) = %v, want %v", tt.dir, got, tt.expected)
+		}
+	}
+
+	// 0 is unlimited
+	if (IndexConfig{}).BeyondMaxDepth("a/b/c/d/e/f") {
+		t.Error("BeyondMaxDepth() = true with MaxDepth 0")
+	}
+}
+
+func TestLoadIndexConfigMaxDepth(t *testing.T) {
+	t.Setenv("CODETECT_INDEX_MAX_DEPTH", "3")
+	if got := LoadIndexConfigFromEnv().MaxDepth; got != 3 {
+		t.Errorf("MaxDepth = %d, want 3", got)
+	}
+
+	t.Setenv("CODETECT_INDEX_MAX_DEPTH", "-1")
+	if got := LoadIndexConfigFromEnv().MaxDepth; got != 0 {
+		t.Errorf("MaxDepth = %d for an invalid value, want unlimited", got)
+	}
+}
+
+func TestLoadIndexConfigCtagsOptions(t *testing.T) {
+	t.Setenv("CODETECT_CTAGS_OPTIONS", "  --kinds-go=+p  stray --extras=-q ")
+	got := LoadIndexConfigFromEnv().Ctags.Args
+	if len(got) != 2 || got[0] != "--kinds-go=+p" || got[1] != "--extras=-q" {
+		t.Errorf("Ctags.Args = %q, want the two options without the stray word", got)
+	}
+}
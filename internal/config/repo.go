@@ -0,0 +1,176 @@
+package config
+
+import (
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// RepoID returns the key used to scope index data (the repo_root column)
+// for the repository at root. When CODETECT_REPO_ID is set it overrides the
+// filesystem path with a stable logical identifier (e.g. a git remote URL),
+// so a shared index stays consistent across checkout paths and machines.
+// Otherwise the key is derived with RepoIdentity.
+func RepoID(root string) string {
+	if id := strings.TrimSpace(os.Getenv("CODETECT_REPO_ID")); id != "" {
+		return id
+	}
+	return RepoIdentity(root)
+}
+
+// repoIdentities caches RepoIdentity results, since stores are created
+// often and each lookup shells out to git.
+var repoIdentities sync.Map
+
+// RepoIdentity returns the identifier for the repository at path. By
+// default it is the absolute path (see NormalizeRepoRoot). With
+// CODETECT_REPO_IDENTITY=remote it is derived from the normalized origin
+// remote instead (e.g. "github.com/org/repo"), so clones of the same repo on
+// different machines share a key in a shared index; it falls back to the
+// path when path is not a git repo or has no origin remote. Remote keying
+// is opt-in because every local checkout of one remote, worktrees included,
+// then shares a key, and in one database they would overwrite each other.
+func RepoIdentity(path string) string {
+	absPath := NormalizeRepoRoot(path)
+
+	if !RemoteRepoIdentity() {
+		return absPath
+	}
+
+	if id, ok := repoIdentities.Load(absPath); ok {
+		return id.(string)
+	}
+
+	id := absPath
+	if info, err := os.Stat(absPath); err == nil && info.IsDir() {
+		out, err := exec.Command("git", "-C", absPath, "remote", "get-url", "origin").Output()
+		if err == nil {
+			if remote := NormalizeRemoteURL(string(out)); remote != "" {
+				id = remote
+				// Indexing a subdirectory: keep it distinct from the whole repo
+				if rel := repoSubdir(absPath); rel != "" {
+					id = remote + "/" + rel
+				}
+			}
+		}
+	}
+
+	repoIdentities.Store(absPath, id)
+	return id
+}
+
+// RemoteRepoIdentity reports whether CODETECT_REPO_IDENTITY=remote asks
+// for repos to be keyed by their git origin remote.
+func RemoteRepoIdentity() bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv("CODETECT_REPO_IDENTITY")), "remote")
+}
+
+// NormalizeRepoRoot returns the canonical spelling of a repository path:
+// absolute, cleaned, and with symlinks resolved when the path exists. Every
+// repo_root key is derived from it, since scoping compares keys as strings
+// and "repo/", "./repo", "x/../repo" or a symlink to repo would otherwise
+// split one repository's data across several keys. "" stays "".
+func NormalizeRepoRoot(path string) string {
+	if path == "" {
+		return ""
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	if resolved, err := filepath.EvalSymlinks(absPath); err == nil {
+		return resolved
+	}
+	return absPath
+}
+
+// repoSubdir returns the slash-separated path of dir relative to the top of
+// its git work tree, or "" when dir is the top level.
+func repoSubdir(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--show-prefix").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.Trim(strings.TrimSpace(string(out)), "/")
+}
+
+// NormalizeRemoteURL converts a git remote URL to a canonical "host/path"
+// form so that HTTPS, SSH and scp-style remotes for the same repo match:
+//
+//	https://github.com/Org/Repo.git  -> github.com/Org/Repo
+//	git@github.com:Org/Repo.git      -> github.com/Org/Repo
+//	ssh://git@github.com:22/Org/Repo -> github.com/Org/Repo
+//
+// Local-path remotes are returned cleaned but otherwise unchanged.
+// Returns "" for an empty remote.
+func NormalizeRemoteURL(remote string) string {
+	remote = strings.TrimSpace(remote)
+	remote = strings.TrimSuffix(remote, "/")
+	remote = strings.TrimSuffix(remote, ".git")
+	if remote == "" {
+		return ""
+	}
+
+	var host, repoPath string
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return remote
+		}
+		if u.Scheme == "file" {
+			return filepath.Clean(u.Path)
+		}
+		host, repoPath = u.Hostname(), u.Path
+	} else if at := strings.Index(remote, "@"); at >= 0 && strings.Contains(remote[at:], ":") {
+		// scp-like syntax: user@host:path
+		rest := remote[at+1:]
+		colon := strings.Index(rest, ":")
+		host, repoPath = rest[:colon], rest[colon+1:]
+	} else if colon := strings.Index(remote, ":"); colon > 0 && !strings.ContainsAny(remote[:colon], `/\`) && colon != 1 {
+		// scp-like syntax without a user: host:path (but not C:\ drive paths)
+		host, repoPath = remote[:colon], remote[colon+1:]
+	} else {
+		return filepath.Clean(remote)
+	}
+
+	repoPath = strings.Trim(repoPath, "/")
+	return strings.ToLower(host) + "/" + repoPath
+}
+
+// RepoRelPath returns path as a clean, slash-separated path relative to the
+// repository at root, the form every index stores and every search returns
+// so agents can open results directly. Relative paths are taken to already
+// be repo-relative. Absolute paths outside root, or any path when root is
+// not a filesystem path (e.g. a remote-derived repo ID), are returned cleaned.
+func RepoRelPath(root, path string) string {
+	if path == "" {
+		return path
+	}
+	if !filepath.IsAbs(path) {
+		return filepath.ToSlash(filepath.Clean(path))
+	}
+	if filepath.IsAbs(root) {
+		if rel, ok := relUnder(root, path); ok {
+			return rel
+		}
+		// root may be normalized while path was spelled through a symlink
+		if resolved, err := filepath.EvalSymlinks(path); err == nil && resolved != path {
+			if rel, ok := relUnder(root, resolved); ok {
+				return rel
+			}
+		}
+	}
+	return filepath.Clean(path)
+}
+
+// relUnder returns path relative to root, slash-separated, if it is inside root
+func relUnder(root, path string) (string, bool) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"fmt"
+	"math"
 	"os"
 	"strconv"
 	"strings"
@@ -29,6 +31,60 @@ type RetrieverConfig struct {
 	// Keys: "keyword", "semantic", "symbol"
 	Weights map[string]float64 `yaml:"weights"`
 
+	// DocCommentWeight is how much doc comment similarity contributes to a
+	// semantic result's score, for chunks indexed with doc comments.
+	// Default: 0.3
+	DocCommentWeight float64 `yaml:"doc_comment_weight"`
+
+	// KeywordPrefilter enables a keyword stage before brute-force semantic
+	// scoring: only chunks containing a query term are scored. Every
+	// vector is still loaded, so it saves scoring time, not I/O.
+	// Default: false
+	KeywordPrefilter bool `yaml:"keyword_prefilter"`
+
+	// PrefilterMinCandidates is the fewest keyword candidates required to
+	// use the prefiltered set; below this every chunk is scored.
+	// Default: 50
+	PrefilterMinCandidates int `yaml:"prefilter_min_candidates"`
+
+	// CandidateMultiplier sets how many vector candidates semantic search
+	// fetches per requested result, leaving headroom for candidates that
+	// resolve to duplicate or out-of-repo locations.
+	// Default: 2
+	CandidateMultiplier int `yaml:"candidate_multiplier"`
+
+	// MaxCandidates caps how many vector candidates semantic search fetches
+	// when it widens the search to fill a short result set.
+	// Default: 500
+	MaxCandidates int `yaml:"max_candidates"`
+
+	// NodeTypeWeights multiplies v2 semantic scores by the chunk's AST node
+	// type, so definitions outrank boilerplate like import blocks. A key
+	// applies to every node type containing it ("function" covers
+	// "function_declaration"), the longest matching key winning. Unmatched
+	// node types keep their score.
+	// Default: none
+	NodeTypeWeights map[string]float64 `yaml:"node_type_weights"`
+
+	// ExcludeTests leaves test files (_test.go, *.spec.*, *.test.*, and
+	// test/, tests/ or __tests__/ directories) out of semantic results
+	// unless a search asks for them.
+	// Default: false
+	ExcludeTests bool `yaml:"exclude_tests"`
+
+	// RecencyWeight boosts semantic results from recently modified files:
+	// scores are multiplied by a factor decaying with file age from 1 to
+	// 1-RecencyWeight. In [0, 1]; 0 disables the boost.
+	// Default: 0
+	RecencyWeight float64 `yaml:"recency_weight"`
+
+	// ScoreNormalization rescales the scores semantic search returns, after
+	// ranking: "minmax" maps each result set onto 0-1, "sigmoid" squashes
+	// similarities so they compare across queries. The similarity is kept
+	// as raw_score. "" or "none" returns raw similarities.
+	// Default: none
+	ScoreNormalization string `yaml:"score_normalization"`
+
 	// Parallel enables parallel retrieval from all signals.
 	// When true, all search signals run concurrently.
 	// When false, signals run sequentially (useful for debugging).
@@ -87,8 +143,12 @@ func DefaultRetrieverConfig() RetrieverConfig {
 			"semantic": 0.5,
 			"symbol":   0.2,
 		},
-		Parallel:  true,
-		TimeoutMs: 5000,
+		DocCommentWeight:       0.3,
+		PrefilterMinCandidates: 50,
+		CandidateMultiplier:    2,
+		MaxCandidates:          500,
+		Parallel:               true,
+		TimeoutMs:              5000,
 	}
 }
 
@@ -106,19 +166,8 @@ func DefaultRerankerConfig() RerankerConfig {
 }
 
 // LoadSearchConfigFromEnv loads search configuration from environment variables.
-// Supports the following variables:
-//
-// Retrieval:
-//   - CODETECT_SEARCH_KEYWORD_LIMIT: Max keyword results (default: 30)
-//   - CODETECT_SEARCH_SEMANTIC_LIMIT: Max semantic results (default: 20)
-//   - CODETECT_SEARCH_SYMBOL_LIMIT: Max symbol results (default: 10)
-//   - CODETECT_SEARCH_PARALLEL: Enable parallel retrieval (default: true)
-//   - CODETECT_SEARCH_TIMEOUT_MS: Retrieval timeout in ms (default: 5000)
-//   - CODETECT_SEARCH_WEIGHT_KEYWORD: Keyword signal weight (default: 0.3)
-//   - CODETECT_SEARCH_WEIGHT_SEMANTIC: Semantic signal weight (default: 0.5)
-//   - CODETECT_SEARCH_WEIGHT_SYMBOL: Symbol signal weight (default: 0.2)
-//
-// Reranking:
+// Retrieval variables are documented on LoadRetrieverConfigFromEnv.
+// Reranking variables:
 //   - CODETECT_RERANK_ENABLED: Enable reranking (default: false)
 //   - CODETECT_RERANK_MODEL: Reranking model (default: bge-reranker-v2-m3)
 //   - CODETECT_RERANK_PROVIDER: Provider (default: ollama)
@@ -127,76 +176,229 @@ func DefaultRerankerConfig() RerankerConfig {
 //   - CODETECT_RERANK_BASE_URL: Service base URL (default: http://localhost:11434)
 func LoadSearchConfigFromEnv() SearchConfig {
 	cfg := DefaultSearchConfig()
+	cfg.Retrieval = LoadRetrieverConfigFromEnv()
+
+	// Reranking config
+	if v := os.Getenv("CODETECT_RERANK_ENABLED"); v != "" {
+		cfg.Reranking.Enabled = parseBool(v, false)
+	}
+	if v := os.Getenv("CODETECT_RERANK_MODEL"); v != "" {
+		cfg.Reranking.Model = v
+	}
+	if v := os.Getenv("CODETECT_RERANK_PROVIDER"); v != "" {
+		cfg.Reranking.Provider = v
+	}
+	if v := os.Getenv("CODETECT_RERANK_TOP_K"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.Reranking.TopK = n
+		}
+	}
+	if v := os.Getenv("CODETECT_RERANK_THRESHOLD"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			cfg.Reranking.Threshold = f
+		}
+	}
+	if v := os.Getenv("CODETECT_RERANK_BASE_URL"); v != "" {
+		cfg.Reranking.BaseURL = v
+	}
+
+	return cfg
+}
+
+// weightEnvVars maps each fusion signal to its weight variables, in order of
+// precedence. The CODETECT_WEIGHT_* forms are shorthand aliases.
+var weightEnvVars = []struct {
+	signal string
+	vars   []string
+}{
+	{"keyword", []string{"CODETECT_SEARCH_WEIGHT_KEYWORD", "CODETECT_WEIGHT_KEYWORD"}},
+	{"semantic", []string{"CODETECT_SEARCH_WEIGHT_SEMANTIC", "CODETECT_WEIGHT_SEMANTIC"}},
+	{"symbol", []string{"CODETECT_SEARCH_WEIGHT_SYMBOL", "CODETECT_WEIGHT_SYMBOL"}},
+}
+
+// LoadRetrieverConfigFromEnv loads retrieval configuration from environment
+// variables, so fusion weights can be tuned per repo without recompiling.
+// Supports the following variables:
+//   - CODETECT_SEARCH_KEYWORD_LIMIT: Max keyword results (default: 30)
+//   - CODETECT_SEARCH_SEMANTIC_LIMIT: Max semantic results (default: 20)
+//   - CODETECT_SEARCH_SYMBOL_LIMIT: Max symbol results (default: 10)
+//   - CODETECT_SEARCH_PARALLEL: Enable parallel retrieval (default: true)
+//   - CODETECT_SEARCH_TIMEOUT_MS: Retrieval timeout in ms (default: 5000)
+//   - CODETECT_SEARCH_WEIGHT_KEYWORD or CODETECT_WEIGHT_KEYWORD: Keyword signal weight (default: 0.3)
+//   - CODETECT_SEARCH_WEIGHT_SEMANTIC or CODETECT_WEIGHT_SEMANTIC: Semantic signal weight (default: 0.5)
+//   - CODETECT_SEARCH_WEIGHT_SYMBOL or CODETECT_WEIGHT_SYMBOL: Symbol signal weight (default: 0.2)
+//   - CODETECT_SEARCH_WEIGHT_DOC: Doc comment similarity weight (default: 0.3)
+//   - CODETECT_SEARCH_PREFILTER: Keyword prefilter before semantic scoring (default: false)
+//   - CODETECT_SEARCH_PREFILTER_MIN: Min prefilter candidates (default: 50)
+//   - CODETECT_SEARCH_CANDIDATE_MULTIPLIER: Vector candidates per result (default: 2)
+//   - CODETECT_SEARCH_MAX_CANDIDATES: Cap on vector candidates (default: 500)
+//   - CODETECT_SEARCH_NODE_WEIGHTS: Node type score multipliers, e.g. "function=1,class=0.9,gap=0.3" (default: none)
+//   - CODETECT_SEARCH_EXCLUDE_TESTS: Leave test files out of semantic results (default: false)
+//   - CODETECT_SEARCH_RECENCY_WEIGHT: Boost for recently modified files, 0-1 (default: 0)
+//   - CODETECT_SEARCH_SCORE_NORMALIZATION: Rescale returned scores: none, minmax, sigmoid (default: none)
+//
+// Weights that don't parse as non-negative floats are ignored with a warning,
+// as are weights that don't sum to roughly 1.
+func LoadRetrieverConfigFromEnv() RetrieverConfig {
+	cfg := DefaultRetrieverConfig()
 
-	// Retrieval config
 	if v := os.Getenv("CODETECT_SEARCH_KEYWORD_LIMIT"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			cfg.Retrieval.KeywordLimit = n
+			cfg.KeywordLimit = n
 		}
 	}
 	if v := os.Getenv("CODETECT_SEARCH_SEMANTIC_LIMIT"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			cfg.Retrieval.SemanticLimit = n
+			cfg.SemanticLimit = n
 		}
 	}
 	if v := os.Getenv("CODETECT_SEARCH_SYMBOL_LIMIT"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			cfg.Retrieval.SymbolLimit = n
+			cfg.SymbolLimit = n
 		}
 	}
 	if v := os.Getenv("CODETECT_SEARCH_PARALLEL"); v != "" {
-		cfg.Retrieval.Parallel = parseBool(v, true)
+		cfg.Parallel = parseBool(v, true)
 	}
 	if v := os.Getenv("CODETECT_SEARCH_TIMEOUT_MS"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			cfg.Retrieval.TimeoutMs = n
+			cfg.TimeoutMs = n
 		}
 	}
 
-	// Retrieval weights
-	if v := os.Getenv("CODETECT_SEARCH_WEIGHT_KEYWORD"); v != "" {
-		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 {
-			cfg.Retrieval.Weights["keyword"] = f
+	if v := os.Getenv("CODETECT_SEARCH_PREFILTER"); v != "" {
+		cfg.KeywordPrefilter = parseBool(v, false)
+	}
+	if v := os.Getenv("CODETECT_SEARCH_PREFILTER_MIN"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.PrefilterMinCandidates = n
 		}
 	}
-	if v := os.Getenv("CODETECT_SEARCH_WEIGHT_SEMANTIC"); v != "" {
-		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 {
-			cfg.Retrieval.Weights["semantic"] = f
+	if v := os.Getenv("CODETECT_SEARCH_CANDIDATE_MULTIPLIER"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.CandidateMultiplier = n
 		}
 	}
-	if v := os.Getenv("CODETECT_SEARCH_WEIGHT_SYMBOL"); v != "" {
-		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 {
-			cfg.Retrieval.Weights["symbol"] = f
+	if v := os.Getenv("CODETECT_SEARCH_MAX_CANDIDATES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.MaxCandidates = n
 		}
 	}
 
-	// Reranking config
-	if v := os.Getenv("CODETECT_RERANK_ENABLED"); v != "" {
-		cfg.Reranking.Enabled = parseBool(v, false)
+	if v := os.Getenv("CODETECT_SEARCH_EXCLUDE_TESTS"); v != "" {
+		cfg.ExcludeTests = parseBool(v, false)
 	}
-	if v := os.Getenv("CODETECT_RERANK_MODEL"); v != "" {
-		cfg.Reranking.Model = v
+
+	if v := os.Getenv("CODETECT_SEARCH_RECENCY_WEIGHT"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 && f <= 1 {
+			cfg.RecencyWeight = f
+		} else {
+			fmt.Fprintf(os.Stderr, "Warning: Invalid CODETECT_SEARCH_RECENCY_WEIGHT %q, recency boost disabled\n", v)
+		}
 	}
-	if v := os.Getenv("CODETECT_RERANK_PROVIDER"); v != "" {
-		cfg.Reranking.Provider = v
+
+	if v := os.Getenv("CODETECT_SEARCH_SCORE_NORMALIZATION"); v != "" {
+		switch method := strings.ToLower(strings.TrimSpace(v)); method {
+		case "none":
+		case "minmax", "sigmoid":
+			cfg.ScoreNormalization = method
+		default:
+			fmt.Fprintf(os.Stderr, "Warning: Invalid CODETECT_SEARCH_SCORE_NORMALIZATION %q, using raw scores\n", v)
+		}
 	}
-	if v := os.Getenv("CODETECT_RERANK_TOP_K"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			cfg.Reranking.TopK = n
+
+	// Retrieval weights
+	for _, w := range weightEnvVars {
+		for _, name := range w.vars {
+			v := os.Getenv(name)
+			if v == "" {
+				continue
+			}
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f < 0 {
+				fmt.Fprintf(os.Stderr, "Warning: Invalid %s %q, using %s weight %g\n", name, v, w.signal, cfg.Weights[w.signal])
+			} else {
+				cfg.Weights[w.signal] = f
+			}
+			break
 		}
 	}
-	if v := os.Getenv("CODETECT_RERANK_THRESHOLD"); v != "" {
-		if f, err := strconv.ParseFloat(v, 64); err == nil {
-			cfg.Reranking.Threshold = f
+	if err := cfg.ValidateWeights(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	if v := os.Getenv("CODETECT_SEARCH_WEIGHT_DOC"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 && f <= 1 {
+			cfg.DocCommentWeight = f
 		}
 	}
-	if v := os.Getenv("CODETECT_RERANK_BASE_URL"); v != "" {
-		cfg.Reranking.BaseURL = v
+
+	if v := os.Getenv("CODETECT_SEARCH_NODE_WEIGHTS"); v != "" {
+		weights, err := ParseNodeTypeWeights(v)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Invalid CODETECT_SEARCH_NODE_WEIGHTS: %v\n", err)
+		} else {
+			cfg.NodeTypeWeights = weights
+		}
 	}
 
 	return cfg
 }
 
+// ParseNodeTypeWeights parses a comma-separated list of node type weights
+// like "function=1,import=0.3". Weights must be non-negative.
+func ParseNodeTypeWeights(s string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		nodeType, value, ok := strings.Cut(entry, "=")
+		nodeType = strings.TrimSpace(nodeType)
+		if !ok || nodeType == "" {
+			return nil, fmt.Errorf("%q is not nodetype=weight", entry)
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || f < 0 {
+			return nil, fmt.Errorf("invalid weight %q for %s", value, nodeType)
+		}
+		weights[nodeType] = f
+	}
+	return weights, nil
+}
+
+// NodeTypeWeight returns the multiplier for nodeType from a NodeTypeWeights
+// map: the weight of the longest key contained in nodeType, or 1 when none is.
+func NodeTypeWeight(weights map[string]float64, nodeType string) float64 {
+	weight, matched := 1.0, ""
+	for key, w := range weights {
+		if len(key) > len(matched) && strings.Contains(nodeType, key) {
+			weight, matched = w, key
+		}
+	}
+	return weight
+}
+
+// weightSumTolerance is how far fusion weights may sum from 1 before
+// ValidateWeights complains.
+const weightSumTolerance = 0.05
+
+// ValidateWeights checks that the keyword, semantic and symbol weights sum
+// to roughly 1. Other sums still work with RRF but make scores harder to
+// compare across configurations.
+func (c RetrieverConfig) ValidateWeights() error {
+	var sum float64
+	for _, w := range weightEnvVars {
+		sum += c.Weights[w.signal]
+	}
+	if math.Abs(sum-1) > weightSumTolerance {
+		return fmt.Errorf("retriever weights keyword=%g semantic=%g symbol=%g sum to %.2f, expected ~1",
+			c.Weights["keyword"], c.Weights["semantic"], c.Weights["symbol"], sum)
+	}
+	return nil
+}
+
 // parseBool parses a string as boolean with a default value.
 func parseBool(s string, defaultVal bool) bool {
 	s = strings.ToLower(strings.TrimSpace(s))
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -134,6 +135,136 @@ func TestLoadSearchConfigFromEnv(t *testing.T) {
 	}
 }
 
+func TestLoadRetrieverConfigFromEnvWeights(t *testing.T) {
+	t.Setenv("CODETECT_SEARCH_WEIGHT_KEYWORD", "")
+	t.Setenv("CODETECT_SEARCH_WEIGHT_SEMANTIC", "0.6")
+	t.Setenv("CODETECT_SEARCH_WEIGHT_SYMBOL", "")
+	t.Setenv("CODETECT_WEIGHT_KEYWORD", "0.4")
+	t.Setenv("CODETECT_WEIGHT_SEMANTIC", "0.5") // Overridden by the long form
+	t.Setenv("CODETECT_WEIGHT_SYMBOL", "lots")
+
+	cfg := LoadRetrieverConfigFromEnv()
+
+	if cfg.Weights["keyword"] != 0.4 {
+		t.Errorf("expected keyword weight=0.4 from alias, got %f", cfg.Weights["keyword"])
+	}
+	if cfg.Weights["semantic"] != 0.6 {
+		t.Errorf("expected semantic weight=0.6, got %f", cfg.Weights["semantic"])
+	}
+	if cfg.Weights["symbol"] != 0.2 {
+		t.Errorf("expected invalid symbol weight to keep default 0.2, got %f", cfg.Weights["symbol"])
+	}
+}
+
+func TestLoadRetrieverConfigFromEnvCandidates(t *testing.T) {
+	t.Setenv("CODETECT_SEARCH_CANDIDATE_MULTIPLIER", "5")
+	t.Setenv("CODETECT_SEARCH_MAX_CANDIDATES", "-1")
+
+	cfg := LoadRetrieverConfigFromEnv()
+
+	if cfg.CandidateMultiplier != 5 {
+		t.Errorf("expected CandidateMultiplier=5, got %d", cfg.CandidateMultiplier)
+	}
+	if cfg.MaxCandidates != 500 {
+		t.Errorf("expected invalid MaxCandidates to keep default 500, got %d", cfg.MaxCandidates)
+	}
+}
+
+func TestLoadRetrieverConfigFromEnvNodeWeights(t *testing.T) {
+	t.Setenv("CODETECT_SEARCH_NODE_WEIGHTS", "function=1, class=0.9,import=0.3")
+	cfg := LoadRetrieverConfigFromEnv()
+	want := map[string]float64{"function": 1, "class": 0.9, "import": 0.3}
+	if len(cfg.NodeTypeWeights) != len(want) {
+		t.Fatalf("NodeTypeWeights = %v, want %v", cfg.NodeTypeWeights, want)
+	}
+	for k, v := range want {
+		if cfg.NodeTypeWeights[k] != v {
+			t.Errorf("NodeTypeWeights[%q] = %g, want %g", k, cfg.NodeTypeWeights[k], v)
+		}
+	}
+
+	t.Setenv("CODETECT_SEARCH_NODE_WEIGHTS", "function=high")
+	if cfg := LoadRetrieverConfigFromEnv(); cfg.NodeTypeWeights != nil {
+		t.Errorf("expected invalid weights to be ignored, got %v", cfg.NodeTypeWeights)
+	}
+}
+
+func TestLoadRetrieverConfigFromEnvExcludeTests(t *testing.T) {
+	if LoadRetrieverConfigFromEnv().ExcludeTests {
+		t.Error("expected ExcludeTests to default to false")
+	}
+	t.Setenv("CODETECT_SEARCH_EXCLUDE_TESTS", "true")
+	if !LoadRetrieverConfigFromEnv().ExcludeTests {
+		t.Error("expected CODETECT_SEARCH_EXCLUDE_TESTS=true to set ExcludeTests")
+	}
+}
+
+func TestLoadRetrieverConfigFromEnvRecencyWeight(t *testing.T) {
+	t.Setenv("CODETECT_SEARCH_RECENCY_WEIGHT", "0.2")
+	if got := LoadRetrieverConfigFromEnv().RecencyWeight; got != 0.2 {
+		t.Errorf("RecencyWeight = %g, want 0.2", got)
+	}
+	t.Setenv("CODETECT_SEARCH_RECENCY_WEIGHT", "1.5")
+	if got := LoadRetrieverConfigFromEnv().RecencyWeight; got != 0 {
+		t.Errorf("RecencyWeight = %g for an out-of-range value, want disabled", got)
+	}
+}
+
+func TestLoadRetrieverConfigFromEnvScoreNormalization(t *testing.T) {
+	t.Setenv("CODETECT_SEARCH_SCORE_NORMALIZATION", "MinMax")
+	if got := LoadRetrieverConfigFromEnv().ScoreNormalization; got != "minmax" {
+		t.Errorf("ScoreNormalization = %q, want minmax", got)
+	}
+	t.Setenv("CODETECT_SEARCH_SCORE_NORMALIZATION", "zscore")
+	if got := LoadRetrieverConfigFromEnv().ScoreNormalization; got != "" {
+		t.Errorf("ScoreNormalization = %q for an unknown method, want raw scores", got)
+	}
+}
+
+func TestParseNodeTypeWeights(t *testing.T) {
+	for _, bad := range []string{"function", "=1", "import=-0.5", "class=x"} {
+		if _, err := ParseNodeTypeWeights(bad); err == nil {
+			t.Errorf("ParseNodeTypeWeights(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestNodeTypeWeight(t *testing.T) {
+	weights := map[string]float64{"function": 1, "arrow_function": 0.8, "import": 0.3, "gap": 0.5}
+	tests := []struct {
+		nodeType string
+		expected float64
+	}{
+		{"function_declaration", 1},
+		{"arrow_function", 0.8},
+		{"import_declaration", 0.3},
+		{"gap", 0.5},
+		{"class_definition", 1},
+		{"", 1},
+	}
+	for _, tt := range tests {
+		if got := NodeTypeWeight(weights, tt.nodeType); got != tt.expected {
+			t.Errorf("NodeTypeWeight(%q) = %g, want %g", tt.nodeType, got, tt.expected)
+		}
+	}
+	if got := NodeTypeWeight(nil, "import_declaration"); got != 1 {
+		t.Errorf("NodeTypeWeight with no weights = %g, want 1", got)
+	}
+}
+
+func TestValidateWeights(t *testing.T) {
+	cfg := DefaultRetrieverConfig()
+	if err := cfg.ValidateWeights(); err != nil {
+		t.Errorf("default weights should validate: %v", err)
+	}
+
+	cfg.Weights["keyword"] = 0.9
+	err := cfg.ValidateWeights()
+	if err == nil || !strings.Contains(err.Error(), "sum to 1.60") {
+		t.Errorf("expected sum warning, got %v", err)
+	}
+}
+
 func TestParseBool(t *testing.T) {
 	tests := []struct {
 		input    string
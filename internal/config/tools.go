@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// ToolConfig holds server-wide defaults for MCP tool arguments. It is read
+// once when the server registers its tools; per-call arguments still win.
+type ToolConfig struct {
+	// DefaultLimit replaces every tool's built-in default result limit
+	// when set. 0 keeps each tool's own default.
+	DefaultLimit int
+
+	// StructuredContent returns tool results as MCP structured content, as
+	// well as text, to clients that support it. Default: false
+	StructuredContent bool
+}
+
+// LoadToolConfigFromEnv loads tool defaults from environment variables.
+//
+// Environment variables:
+//   - CODETECT_DEFAULT_LIMIT: Default result limit for all search tools
+//     (default: per tool, e.g. 10 for search_semantic, 20 for search_keyword)
+//   - CODETECT_MCP_STRUCTURED_CONTENT: Also return tool results as
+//     structured content to clients that negotiate it (default: false)
+func LoadToolConfigFromEnv() ToolConfig {
+	var cfg ToolConfig
+	if v := os.Getenv("CODETECT_DEFAULT_LIMIT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.DefaultLimit = n
+		} else {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring CODETECT_DEFAULT_LIMIT=%q, want a positive integer\n", v)
+		}
+	}
+	cfg.StructuredContent = parseBool(os.Getenv("CODETECT_MCP_STRUCTURED_CONTENT"), false)
+	return cfg
+}
+
+// Limit returns the configured default limit, or toolDefault if none is set.
+func (c ToolConfig) Limit(toolDefault int) int {
+	if c.DefaultLimit > 0 {
+		return c.DefaultLimit
+	}
+	return toolDefault
+}
@@ -0,0 +1,33 @@
+package config
+
+import "testing"
+
+func TestLoadToolConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		env      string
+		expected int
+	}{
+		{"", 10},
+		{"25", 25},
+		{"0", 10},
+		{"lots", 10},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("CODETECT_DEFAULT_LIMIT", tt.env)
+		if got := LoadToolConfigFromEnv().Limit(10); got != tt.expected {
+			t.Errorf("CODETECT_DEFAULT_LIMIT=%q: Limit(10) = %d, want %d", tt.env, got, tt.expected)
+		}
+	}
+}
+
+func TestLoadToolConfigFromEnvStructuredContent(t *testing.T) {
+	t.Setenv("CODETECT_MCP_STRUCTURED_CONTENT", "")
+	if LoadToolConfigFromEnv().StructuredContent {
+		t.Error("StructuredContent = true by default, want false")
+	}
+	t.Setenv("CODETECT_MCP_STRUCTURED_CONTENT", "true")
+	if !LoadToolConfigFromEnv().StructuredContent {
+		t.Error("StructuredContent = false with CODETECT_MCP_STRUCTURED_CONTENT=true")
+	}
+}
@@ -0,0 +1,55 @@
+package config
+
+import "os"
+
+// WalkGuard keeps a directory walk from looping or wandering off the
+// tree. It skips any directory already visited in the walk, which breaks
+// cycles through bind mounts, and with OneFileSystem set any directory on a
+// different device than the root, like rsync's --one-file-system.
+//
+// A WalkGuard holds the state of one walk and is not safe for concurrent use.
+type WalkGuard struct {
+	oneFileSystem bool
+	rootDev       uint64
+	haveRootDev   bool
+	visited       map[fileID]bool
+}
+
+// fileID identifies a directory independently of the path it was reached by
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// NewWalkGuard returns a guard for a walk of root. oneFileSystem is
+// normally IndexConfig.OneFileSystem.
+func NewWalkGuard(root string, oneFileSystem bool) *WalkGuard {
+	g := &WalkGuard{
+		oneFileSystem: oneFileSystem,
+		visited:       make(map[fileID]bool),
+	}
+	if info, err := os.Stat(root); err == nil {
+		if id, ok := fileIDOf(info); ok {
+			g.rootDev = id.dev
+			g.haveRootDev = true
+		}
+	}
+	return g
+}
+
+// SkipDir reports whether the walk should not descend into the directory
+// described by info. Each call marks the directory visited.
+func (g *WalkGuard) SkipDir(info os.FileInfo) bool {
+	id, ok := fileIDOf(info)
+	if !ok {
+		return false
+	}
+	if g.oneFileSystem && g.haveRootDev && id.dev != g.rootDev {
+		return true
+	}
+	if g.visited[id] {
+		return true
+	}
+	g.visited[id] = true
+	return false
+}
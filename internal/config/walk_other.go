@@ -0,0 +1,12 @@
+//go:build !unix
+
+package config
+
+import "os"
+
+// fileIDOf reports no device or inode on platforms without syscall.Stat_t,
+// so the walk guard neither skips visited directories nor stops at device
+// boundaries there
+func fileIDOf(info os.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestWalkGuardSkipsVisited(t *testing.T) {
+	dir := t.TempDir()
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fileIDOf(info); !ok {
+		t.Skip("no device and inode numbers on this platform")
+	}
+
+	guard := NewWalkGuard(dir, false)
+	if guard.SkipDir(info) {
+		t.Fatal("SkipDir() = true on the first visit")
+	}
+	if !guard.SkipDir(info) {
+		t.Error("SkipDir() = false for a directory already visited")
+	}
+}
+
+func TestWalkGuardOneFileSystem(t *testing.T) {
+	dir := t.TempDir()
+	other, err := os.Stat("/proc")
+	if err != nil {
+		t.Skip("no /proc mount to compare against")
+	}
+	if id, _ := fileIDOf(other); NewWalkGuard(dir, true).rootDev == id.dev {
+		t.Skip("/proc is on the same device as the temp dir")
+	}
+
+	if NewWalkGuard(dir, false).SkipDir(other) {
+		t.Error("SkipDir() = true for another device with OneFileSystem off")
+	}
+	if !NewWalkGuard(dir, true).SkipDir(other) {
+		t.Error("SkipDir() = false for another device with OneFileSystem on")
+	}
+}
@@ -4,35 +4,45 @@ package daemon
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"codetect/internal/embedding"
 	"codetect/internal/logging"
 	"codetect/internal/registry"
-
-	"github.com/fsnotify/fsnotify"
-	ignore "github.com/sabhiram/go-gitignore"
+	"codetect/internal/watch"
 )
 
 // Daemon manages background file watching and indexing
 type Daemon struct {
-	registry    *registry.Registry
-	watcher     *fsnotify.Watcher
-	indexQueue  chan string
-	debounceMap map[string]*time.Timer
-	debounceMu  sync.Mutex
-	ctx         context.Context
-	cancel      context.CancelFunc
-	logger      *slog.Logger
-	logFile     *os.File
+	registry   *registry.Registry
+	watcher    *watch.Watcher
+	indexQueue chan string
+	ctx        context.Context
+	cancel     context.CancelFunc
+	logger     *slog.Logger
+	logFile    *logWriter
+
+	// Auto-embed: debounced per project, run one at a time
+	embedQueue    chan string
+	embedDebounce time.Duration
+	embedMu       sync.Mutex
+	embedTimers   map[string]*time.Timer
+
+	// Held while codetect-index runs on a project, so an embed never
+	// overlaps an index of the same repo
+	projectMu    sync.Mutex
+	projectLocks map[string]*sync.Mutex
 }
 
 // DaemonStatus represents the current state of the daemon
@@ -42,6 +52,7 @@ type DaemonStatus struct {
 	StartedAt       time.Time `json:"started_at"`
 	WatchedProjects int       `json:"watched_projects"`
 	TotalWatches    int       `json:"total_watches"`
+	ProtocolVersion int       `json:"protocol_version"`
 }
 
 // Config holds daemon configuration
@@ -55,32 +66,41 @@ type Config struct {
 // DefaultConfig returns the default daemon configuration
 func DefaultConfig() Config {
 	configDir := registry.DefaultConfigDir()
-	uid := os.Getuid()
 	return Config{
 		DebounceMs: 500,
 		LogPath:    filepath.Join(configDir, "daemon.log"),
 		PIDPath:    filepath.Join(configDir, "daemon.pid"),
-		SocketPath: fmt.Sprintf("/tmp/codetect-%d.sock", uid),
+		SocketPath: DefaultSocketPath(),
 	}
 }
 
-// New creates a new daemon instance
-func New(reg *registry.Registry, cfg Config) (*Daemon, error) {
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create watcher: %w", err)
+// LoadConfigFromEnv returns DefaultConfig with the socket, PID file and log
+// file paths overridden by CODETECT_DAEMON_SOCKET, CODETECT_DAEMON_PID_FILE
+// and CODETECT_DAEMON_LOG_FILE. Giving each daemon its own set of paths lets
+// several instances run side by side.
+func LoadConfigFromEnv() Config {
+	cfg := DefaultConfig()
+	if v := os.Getenv("CODETECT_DAEMON_SOCKET"); v != "" {
+		cfg.SocketPath = v
 	}
+	if v := os.Getenv("CODETECT_DAEMON_PID_FILE"); v != "" {
+		cfg.PIDPath = v
+	}
+	if v := os.Getenv("CODETECT_DAEMON_LOG_FILE"); v != "" {
+		cfg.LogPath = v
+	}
+	return cfg
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
-
+// New creates a new daemon instance
+func New(reg *registry.Registry, cfg Config) (*Daemon, error) {
 	// Setup logging - use file if configured, otherwise use logging package defaults
-	var logFile *os.File
+	var logFile *logWriter
 	var logger *slog.Logger
 	if cfg.LogPath != "" {
-		logFile, err = os.OpenFile(cfg.LogPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		var err error
+		logFile, err = openLogWriter(cfg.LogPath)
 		if err != nil {
-			watcher.Close()
-			cancel()
 			return nil, fmt.Errorf("failed to open log file: %w", err)
 		}
 		// Create slog logger with file output
@@ -91,15 +111,34 @@ func New(reg *registry.Registry, cfg Config) (*Daemon, error) {
 		logger = logging.Default("codetect-daemon")
 	}
 
+	debounce := time.Duration(reg.Settings().DebounceMs) * time.Millisecond
+	watcher, err := watch.New(debounce, logger)
+	if err != nil {
+		if logFile != nil {
+			logFile.Close()
+		}
+		return nil, err
+	}
+
+	embedDebounce := time.Duration(reg.Settings().EmbedDebounceMs) * time.Millisecond
+	if embedDebounce <= 0 {
+		embedDebounce = registry.DefaultEmbedDebounceMs * time.Millisecond
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &Daemon{
-		registry:    reg,
-		watcher:     watcher,
-		indexQueue:  make(chan string, 100),
-		debounceMap: make(map[string]*time.Timer),
-		ctx:         ctx,
-		cancel:      cancel,
-		logger:      logger,
-		logFile:     logFile,
+		registry:      reg,
+		watcher:       watcher,
+		indexQueue:    make(chan string, 100),
+		ctx:           ctx,
+		cancel:        cancel,
+		logger:        logger,
+		logFile:       logFile,
+		embedQueue:    make(chan string, 100),
+		embedDebounce: embedDebounce,
+		embedTimers:   make(map[string]*time.Timer),
+		projectLocks:  make(map[string]*sync.Mutex),
 	}, nil
 }
 
@@ -107,6 +146,14 @@ func New(reg *registry.Registry, cfg Config) (*Daemon, error) {
 func (d *Daemon) Run(cfg Config) error {
 	d.logger.Info("daemon starting")
 
+	// Claim the socket before writing the PID file, so a second daemon that
+	// loses the race exits without clobbering the first one's PID file
+	ipcServer, err := NewIPCServer(cfg.SocketPath, d)
+	if err != nil {
+		return fmt.Errorf("failed to start IPC server: %w", err)
+	}
+	defer ipcServer.Close()
+
 	// Write PID file
 	if err := d.writePIDFile(cfg.PIDPath); err != nil {
 		return fmt.Errorf("failed to write PID file: %w", err)
@@ -116,6 +163,12 @@ func (d *Daemon) Run(cfg Config) error {
 	// Setup signal handlers
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(sigChan)
+
+	// SIGHUP reopens the log file so logrotate's rename-then-create works
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	defer signal.Stop(hupChan)
 
 	// Start watching registered projects
 	if err := d.watchAllProjects(); err != nil {
@@ -123,16 +176,11 @@ func (d *Daemon) Run(cfg Config) error {
 	}
 
 	// Start IPC server
-	ipcServer, err := NewIPCServer(cfg.SocketPath, d)
-	if err != nil {
-		return fmt.Errorf("failed to start IPC server: %w", err)
-	}
-	defer ipcServer.Close()
-
 	go ipcServer.Serve(d.ctx)
 
-	// Start index worker
+	// Start index and embed workers
 	go d.indexWorker()
+	go d.embedWorker()
 
 	// Start watcher event handler
 	go d.watcherLoop()
@@ -140,17 +188,29 @@ func (d *Daemon) Run(cfg Config) error {
 	d.logger.Info("daemon started", "pid", os.Getpid())
 
 	// Wait for shutdown signal
-	select {
-	case sig := <-sigChan:
-		d.logger.Info("received signal", "signal", sig)
-	case <-d.ctx.Done():
-		d.logger.Info("context cancelled")
+wait:
+	for {
+		select {
+		case <-hupChan:
+			d.reopenLog()
+		case sig := <-sigChan:
+			d.logger.Info("received signal", "signal", sig)
+			break wait
+		case <-d.ctx.Done():
+			d.logger.Info("context cancelled")
+			break wait
+		}
 	}
 
 	// Cleanup
 	d.logger.Info("daemon shutting down")
 	d.cancel()
 	d.watcher.Close()
+	d.embedMu.Lock()
+	for _, t := range d.embedTimers {
+		t.Stop()
+	}
+	d.embedMu.Unlock()
 	if d.logFile != nil {
 		d.logFile.Close()
 	}
@@ -158,6 +218,20 @@ func (d *Daemon) Run(cfg Config) error {
 	return nil
 }
 
+// reopenLog closes and reopens the log file at its configured path
+func (d *Daemon) reopenLog() {
+	if d.logFile == nil {
+		d.logger.Info("received SIGHUP, no log file to reopen")
+		return
+	}
+	if err := d.logFile.Reopen(); err != nil {
+		// The old handle is kept, so this error still lands somewhere
+		d.logger.Error("failed to reopen log file", "path", d.logFile.Path(), "error", err)
+		return
+	}
+	d.logger.Info("reopened log file", "path", d.logFile.Path())
+}
+
 // Stop signals the daemon to shut down
 func (d *Daemon) Stop() {
 	d.cancel()
@@ -171,6 +245,7 @@ func (d *Daemon) Status() DaemonStatus {
 		StartedAt:       time.Now(), // TODO: track actual start time
 		WatchedProjects: len(d.registry.GetWatchedProjects()),
 		TotalWatches:    len(d.watcher.WatchList()),
+		ProtocolVersion: ProtocolVersion,
 	}
 }
 
@@ -187,122 +262,21 @@ func (d *Daemon) watchAllProjects() error {
 	return nil
 }
 
-// maxWatchesPerProject limits file watchers to prevent file descriptor exhaustion
-const maxWatchesPerProject = 1000
-
 // watchProject adds watches for all directories in a project
 func (d *Daemon) watchProject(projectPath string) error {
-	count := 0
-	var limitReached bool
-
-	// Load gitignore patterns for this project
-	gi := loadGitignore(projectPath)
-
-	err := filepath.WalkDir(projectPath, func(path string, entry os.DirEntry, err error) error {
-		if err != nil {
-			return nil // Skip errors
-		}
-		if entry.IsDir() {
-			// Check hardcoded ignore list first
-			if isIgnoredDir(entry.Name()) {
-				return filepath.SkipDir
-			}
-
-			// Check gitignore patterns
-			if gi != nil {
-				relPath, err := filepath.Rel(projectPath, path)
-				if err == nil && gi.MatchesPath(relPath+"/") {
-					return filepath.SkipDir
-				}
-			}
-
-			if count >= maxWatchesPerProject {
-				if !limitReached {
-					d.logger.Warn("reached max watches limit", "limit", maxWatchesPerProject, "project", projectPath)
-					limitReached = true
-				}
-				return filepath.SkipDir
-			}
-			if err := d.watcher.Add(path); err != nil {
-				return nil // Skip errors
-			}
-			count++
-		}
-		return nil
-	})
-	d.logger.Debug("added watches", "count", count, "project", projectPath)
+	_, err := d.watcher.AddTree(projectPath)
 	return err
 }
 
 // unwatchProject removes watches for a project
 func (d *Daemon) unwatchProject(projectPath string) error {
-	watchList := d.watcher.WatchList()
-	for _, path := range watchList {
-		if isSubpath(path, projectPath) {
-			d.watcher.Remove(path)
-		}
-	}
+	d.watcher.RemoveTree(projectPath)
 	return nil
 }
 
-// watcherLoop handles fsnotify events
+// watcherLoop handles file system events, queueing debounced reindexes
 func (d *Daemon) watcherLoop() {
-	debounceMs := d.registry.Settings().DebounceMs
-	if debounceMs <= 0 {
-		debounceMs = 500
-	}
-	debounceDuration := time.Duration(debounceMs) * time.Millisecond
-
-	for {
-		select {
-		case <-d.ctx.Done():
-			return
-		case event, ok := <-d.watcher.Events:
-			if !ok {
-				return
-			}
-			d.handleEvent(event, debounceDuration)
-		case err, ok := <-d.watcher.Errors:
-			if !ok {
-				return
-			}
-			d.logger.Error("watcher error", "error", err)
-		}
-	}
-}
-
-// handleEvent processes a file system event with debouncing
-func (d *Daemon) handleEvent(event fsnotify.Event, debounceDuration time.Duration) {
-	// Skip non-code files
-	if !isCodeFile(event.Name) && !event.Has(fsnotify.Create) {
-		return
-	}
-
-	// Handle new directories
-	if event.Has(fsnotify.Create) {
-		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
-			if !isIgnoredDir(filepath.Base(event.Name)) {
-				d.watcher.Add(event.Name)
-			}
-		}
-	}
-
-	// Find which project this event belongs to
-	project := d.findProjectForPath(event.Name)
-	if project == "" {
-		return
-	}
-
-	// Debounce: reset timer for this project
-	d.debounceMu.Lock()
-	if timer, ok := d.debounceMap[project]; ok {
-		timer.Stop()
-	}
-	d.debounceMap[project] = time.AfterFunc(debounceDuration, func() {
-		d.debounceMu.Lock()
-		delete(d.debounceMap, project)
-		d.debounceMu.Unlock()
-
+	d.watcher.Run(d.ctx, d.findProjectForPath, func(project string) {
 		select {
 		case d.indexQueue <- project:
 			d.logger.Debug("queued reindex", "project", project)
@@ -310,14 +284,13 @@ func (d *Daemon) handleEvent(event fsnotify.Event, debounceDuration time.Duratio
 			d.logger.Warn("index queue full, skipping", "project", project)
 		}
 	})
-	d.debounceMu.Unlock()
 }
 
 // findProjectForPath returns the project path that contains the given path
 func (d *Daemon) findProjectForPath(path string) string {
 	projects := d.registry.GetWatchedProjects()
 	for _, p := range projects {
-		if isSubpath(path, p.Path) {
+		if watch.IsSubpath(path, p.Path) {
 			return p.Path
 		}
 	}
@@ -338,8 +311,16 @@ func (d *Daemon) indexWorker() {
 
 // runIndex executes the indexer for a project
 func (d *Daemon) runIndex(projectPath string) {
+	unlock := d.lockProject(projectPath)
+	defer unlock()
+
 	d.logger.Info("indexing", "project", projectPath)
 
+	// Pick up CLI edits (e.g. auto_embed) before saving over them below
+	if err := d.registry.Reload(); err != nil {
+		d.logger.Warn("failed to reload registry", "error", err)
+	}
+
 	// Run codetect-index
 	cmd := exec.CommandContext(d.ctx, "codetect-index", "index", projectPath)
 	output, err := cmd.CombinedOutput()
@@ -354,6 +335,143 @@ func (d *Daemon) runIndex(projectPath string) {
 	if err := d.registry.SetLastIndexed(projectPath); err != nil {
 		d.logger.Error("failed to update registry", "error", err)
 	}
+
+	d.scheduleEmbed(projectPath)
+}
+
+// scheduleEmbed queues an incremental embed for a project with auto_embed
+// set. Embedding calls the provider's API, so it waits for a much longer
+// quiet period than symbol indexing: a burst of saves re-embeds once.
+func (d *Daemon) scheduleEmbed(projectPath string) {
+	project, err := d.registry.Get(projectPath)
+	if err != nil || !project.AutoEmbed {
+		return
+	}
+
+	d.embedMu.Lock()
+	defer d.embedMu.Unlock()
+	if t, ok := d.embedTimers[projectPath]; ok {
+		t.Reset(d.embedDebounce)
+		return
+	}
+	d.embedTimers[projectPath] = time.AfterFunc(d.embedDebounce, func() {
+		d.embedMu.Lock()
+		delete(d.embedTimers, projectPath)
+		d.embedMu.Unlock()
+
+		select {
+		case d.embedQueue <- projectPath:
+			d.logger.Debug("queued embed", "project", projectPath)
+		default:
+			d.logger.Warn("embed queue full, skipping", "project", projectPath)
+		}
+	})
+}
+
+// lockProject waits until no index or embed is running on projectPath and
+// returns the function that releases it
+func (d *Daemon) lockProject(projectPath string) func() {
+	d.projectMu.Lock()
+	mu, ok := d.projectLocks[projectPath]
+	if !ok {
+		mu = &sync.Mutex{}
+		d.projectLocks[projectPath] = mu
+	}
+	d.projectMu.Unlock()
+
+	mu.Lock()
+	return mu.Unlock
+}
+
+// embedWorker processes the embed queue
+func (d *Daemon) embedWorker() {
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case projectPath := <-d.embedQueue:
+			d.runEmbed(projectPath)
+		}
+	}
+}
+
+// runEmbed executes an incremental embed for a project. Chunks already in
+// the embedding cache are not sent to the provider again.
+func (d *Daemon) runEmbed(projectPath string) {
+	unlock := d.lockProject(projectPath)
+	defer unlock()
+
+	var settings *registry.ProjectSettings
+	if project, err := d.registry.Get(projectPath); err == nil {
+		settings = project.Settings
+	}
+
+	cfg, env, err := embedConfig(settings)
+	if err != nil {
+		d.logger.Error("invalid project settings", "project", projectPath, "error", err)
+		return
+	}
+	if reason := embedUnavailable(cfg); reason != "" {
+		d.logger.Info("skipping embed", "project", projectPath, "reason", reason)
+		return
+	}
+
+	d.logger.Info("embedding", "project", projectPath, "provider", cfg.Provider, "model", cfg.Model)
+
+	cmd := exec.CommandContext(d.ctx, "codetect-index", "embed", projectPath)
+	cmd.Env = append(os.Environ(), env...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		d.logger.Error("embed failed", "project", projectPath, "error", err, "output", string(output))
+		return
+	}
+
+	d.logger.Info("embed completed", "project", projectPath)
+}
+
+// embedConfig applies a project's overrides to the daemon's embedding
+// configuration. It returns the result and the environment variables that
+// carry the overrides to codetect-index.
+func embedConfig(settings *registry.ProjectSettings) (embedding.ProviderConfig, []string, error) {
+	cfg := embedding.LoadConfigFromEnv()
+	if settings == nil {
+		return cfg, nil, nil
+	}
+
+	var env []string
+	if settings.EmbeddingProvider != "" {
+		provider, ok := embedding.ParseProvider(settings.EmbeddingProvider)
+		if !ok {
+			return cfg, nil, fmt.Errorf("unknown embedding provider %q", settings.EmbeddingProvider)
+		}
+		cfg.Provider = provider
+		env = append(env, "CODETECT_EMBEDDING_PROVIDER="+string(provider))
+	}
+	if settings.EmbeddingModel != "" {
+		cfg.Model = settings.EmbeddingModel
+		env = append(env, "CODETECT_EMBEDDING_MODEL="+settings.EmbeddingModel)
+	}
+	if settings.EmbeddingDimensions > 0 {
+		cfg.Dimensions = settings.EmbeddingDimensions
+		env = append(env, fmt.Sprintf("CODETECT_EMBEDDING_DIMENSIONS=%d", settings.EmbeddingDimensions))
+	}
+	return cfg, env, nil
+}
+
+// embedUnavailable returns why the embedding provider can't be used right
+// now, or "" if it can
+func embedUnavailable(cfg embedding.ProviderConfig) string {
+	if cfg.Provider == embedding.ProviderOff {
+		return "embedding disabled"
+	}
+	embedder, err := embedding.NewEmbedder(cfg)
+	if err != nil {
+		return err.Error()
+	}
+	if !embedder.Available() {
+		return fmt.Sprintf("provider %s not available", cfg.Provider)
+	}
+	return ""
 }
 
 // AddProject adds a project to the watch list
@@ -382,145 +500,124 @@ func (d *Daemon) TriggerReindex(projectPath string) error {
 	}
 }
 
-// writePIDFile writes the daemon PID to a file
-func (d *Daemon) writePIDFile(path string) error {
-	return os.WriteFile(path, []byte(fmt.Sprintf("%d", os.Getpid())), 0644)
+// ErrAlreadyRunning is returned by RemoveStale when a live daemon answers
+// on the configured socket.
+var ErrAlreadyRunning = errors.New("daemon is already running")
+
+// RemoveStale clears the PID file and socket a crashed daemon left behind,
+// so a new daemon can start with cfg. A daemon counts as running only if it
+// answers on the socket; otherwise the PID file is a leftover, whether or
+// not its process still exists, as is a socket with nothing listening.
+func RemoveStale(cfg Config, logger *slog.Logger) error {
+	if NewIPCClient(cfg.SocketPath).IsRunning() {
+		return ErrAlreadyRunning
+	}
+
+	if _, err := os.Lstat(cfg.SocketPath); err == nil {
+		if err := removeStaleSocket(cfg.SocketPath); err != nil {
+			return err
+		}
+		logger.Info("removed stale socket", "path", cfg.SocketPath)
+	}
+
+	pid, err := readPIDFile(cfg.PIDPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		logger.Warn("removing unreadable PID file", "path", cfg.PIDPath, "error", err)
+	} else if processAlive(pid) {
+		// Usually a reused PID: a hung daemon still holds its socket and
+		// fails removeStaleSocket above
+		logger.Warn("PID file names a live process that is not a responding daemon", "pid", pid)
+	}
+	if err := os.Remove(cfg.PIDPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove stale PID file: %w", err)
+	}
+	logger.Info("removed stale PID file", "path", cfg.PIDPath)
+	return nil
 }
 
-// isIgnoredDir returns true if the directory should be skipped
-func isIgnoredDir(name string) bool {
-	ignored := map[string]bool{
-		// Version control
-		".git": true,
-		".svn": true,
-		".hg":  true,
-
-		// IDE/Editor
-		".idea":   true,
-		".vscode": true,
-
-		// Build outputs
-		"dist":   true,
-		"build":  true,
-		"target": true,
-		"out":    true,
-
-		// Dependencies
-		"node_modules": true,
-		"vendor":       true,
-		".bundle":      true,
-		"Pods":         true,
-
-		// Python
-		"__pycache__": true,
-		".venv":       true,
-		"venv":        true,
-		"env":         true,
-		".tox":        true,
-		".pytest_cache": true,
-
-		// Ruby/Rails
-		"tmp":      true,
-		"log":      true,
-		"coverage": true,
-		"sorbet":   true,
-
-		// Generated/Cache
-		".cache":       true,
-		".codetect": true,
-		".next":        true,
-		".nuxt":        true,
-		".turbo":       true,
-		".parcel-cache": true,
-
-		// Assets (often generated)
-		"public/assets": true,
-		"public/packs":  true,
-	}
-	return ignored[name]
-}
-
-// isCodeFile returns true if the file is a code file
-func isCodeFile(path string) bool {
-	ext := filepath.Ext(path)
-	codeExts := map[string]bool{
-		".go":    true,
-		".js":    true,
-		".ts":    true,
-		".tsx":   true,
-		".jsx":   true,
-		".py":    true,
-		".java":  true,
-		".c":     true,
-		".cpp":   true,
-		".h":     true,
-		".hpp":   true,
-		".rs":    true,
-		".rb":    true,
-		".php":   true,
-		".swift": true,
-		".kt":    true,
-		".scala": true,
-		".cs":    true,
-		".md":    true,
-		".json":  true,
-		".yaml":  true,
-		".yml":   true,
-		".toml":  true,
-	}
-	return codeExts[ext]
-}
-
-// isSubpath returns true if child is under parent
-func isSubpath(child, parent string) bool {
-	rel, err := filepath.Rel(parent, child)
+// readPIDFile reads the PID written by writePIDFile
+func readPIDFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return false
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("invalid PID file %s", path)
 	}
-	return len(rel) > 0 && rel[0] != '.'
+	return pid, nil
 }
 
-// loadGitignore loads gitignore patterns from local .gitignore and global ~/.gitignore
-func loadGitignore(rootPath string) *ignore.GitIgnore {
-	var patterns []string
+// processAlive reports whether a process with the given PID exists.
+// EPERM means it exists but belongs to another user.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
 
-	// Load global gitignore (~/.gitignore)
-	homeDir, err := os.UserHomeDir()
-	if err == nil {
-		globalGitignore := filepath.Join(homeDir, ".gitignore")
-		if content, err := os.ReadFile(globalGitignore); err == nil {
-			for _, line := range splitLines(string(content)) {
-				if line != "" && !isComment(line) {
-					patterns = append(patterns, line)
-				}
-			}
-		}
+// writePIDFile writes the daemon PID to a file
+func (d *Daemon) writePIDFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
 	}
+	return os.WriteFile(path, []byte(fmt.Sprintf("%d", os.Getpid())), 0644)
+}
 
-	// Load local .gitignore
-	localGitignore := filepath.Join(rootPath, ".gitignore")
-	if content, err := os.ReadFile(localGitignore); err == nil {
-		for _, line := range splitLines(string(content)) {
-			if line != "" && !isComment(line) {
-				patterns = append(patterns, line)
-			}
-		}
+// logWriter is an append-mode log file that can be reopened in place.
+// The logger keeps writing through the same logWriter, so reopening
+// swaps the handler's output without rebuilding the logger.
+type logWriter struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// openLogWriter opens path for appending, creating it if necessary
+func openLogWriter(path string) (*logWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
 	}
+	return &logWriter{path: path, file: f}, nil
+}
 
-	if len(patterns) == 0 {
-		return nil
+// Write implements io.Writer
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Write(p)
+}
+
+// Reopen opens a fresh handle at the configured path and closes the old one.
+// On failure the existing handle is left in place.
+func (w *logWriter) Reopen() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
 	}
 
-	return ignore.CompileIgnoreLines(patterns...)
+	w.mu.Lock()
+	old := w.file
+	w.file = f
+	w.mu.Unlock()
+
+	return old.Close()
 }
 
-// splitLines splits content into lines
-func splitLines(content string) []string {
-	return strings.Split(content, "\n")
+// Path returns the configured log file path
+func (w *logWriter) Path() string {
+	return w.path
 }
 
-// isComment returns true if line is a gitignore comment
-func isComment(line string) bool {
-	trimmed := strings.TrimSpace(line)
-	return len(trimmed) > 0 && trimmed[0] == '#'
+// Close closes the underlying file
+func (w *logWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
 }
@@ -4,24 +4,43 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"os"
+	"path/filepath"
+	"time"
 )
 
+// ProtocolVersion is the version of the IPC messages below. Bump it on any
+// change an older client or daemon would misread.
+const ProtocolVersion = 1
+
+// ErrVersionMismatch is returned by the client when the daemon speaks a
+// different protocol version, typically after upgrading the binaries
+// without restarting the daemon.
+var ErrVersionMismatch = errors.New("daemon protocol version mismatch")
+
 // Command represents a request from the CLI to the daemon
 type Command struct {
-	Action string `json:"action"` // status, stop, reindex, add, remove
-	Path   string `json:"path,omitempty"`
+	Version int    `json:"version"` // ProtocolVersion of the client
+	Action  string `json:"action"`  // status, stop, reindex, add, remove
+	Path    string `json:"path,omitempty"`
 }
 
 // Response represents a response from the daemon to the CLI
 type Response struct {
-	Status  string `json:"status"` // ok, error
+	Version int    `json:"version"` // ProtocolVersion of the daemon
+	Status  string `json:"status"`  // ok, error
 	Message string `json:"message,omitempty"`
 	Data    any    `json:"data,omitempty"`
 }
 
+// versionMismatchMessage tells the user how to resolve a version mismatch
+func versionMismatchMessage(daemon, client int) string {
+	return fmt.Sprintf("daemon speaks IPC protocol v%d, client speaks v%d: restart the daemon (codetect daemon stop && codetect daemon start)", daemon, client)
+}
+
 // IPCServer handles communication between CLI and daemon
 type IPCServer struct {
 	socketPath string
@@ -31,8 +50,13 @@ type IPCServer struct {
 
 // NewIPCServer creates a new IPC server
 func NewIPCServer(socketPath string, daemon *Daemon) (*IPCServer, error) {
-	// Remove stale socket if it exists
-	os.Remove(socketPath)
+	if err := removeStaleSocket(socketPath); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
 
 	listener, err := net.Listen("unix", socketPath)
 	if err != nil {
@@ -46,6 +70,25 @@ func NewIPCServer(socketPath string, daemon *Daemon) (*IPCServer, error) {
 	}, nil
 }
 
+// removeStaleSocket unlinks a socket file left behind by a daemon that
+// exited without cleaning up. A socket something is still listening on
+// belongs to a live daemon and is left alone.
+func removeStaleSocket(socketPath string) error {
+	if _, err := os.Lstat(socketPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	conn, err := net.DialTimeout("unix", socketPath, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("another daemon is listening on %s", socketPath)
+	}
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+	return nil
+}
+
 // Close shuts down the IPC server
 func (s *IPCServer) Close() error {
 	os.Remove(s.socketPath)
@@ -90,6 +133,12 @@ func (s *IPCServer) handleConnection(ctx context.Context, conn net.Conn) {
 
 // handleCommand processes a command and returns a response
 func (s *IPCServer) handleCommand(ctx context.Context, cmd Command) Response {
+	// Stop is accepted from any version, so a client can always shut down
+	// a daemon it can't otherwise talk to
+	if cmd.Version != ProtocolVersion && cmd.Action != "stop" {
+		return Response{Status: "error", Message: versionMismatchMessage(ProtocolVersion, cmd.Version)}
+	}
+
 	switch cmd.Action {
 	case "status":
 		status := s.daemon.Status()
@@ -133,6 +182,7 @@ func (s *IPCServer) handleCommand(ctx context.Context, cmd Command) Response {
 
 // sendResponse sends a JSON response to the client
 func (s *IPCServer) sendResponse(conn net.Conn, resp Response) {
+	resp.Version = ProtocolVersion
 	data, _ := json.Marshal(resp)
 	conn.Write(append(data, '\n'))
 }
@@ -152,8 +202,10 @@ func DefaultSocketPath() string {
 	return fmt.Sprintf("/tmp/codetect-%d.sock", os.Getuid())
 }
 
-// Send sends a command to the daemon and returns the response
+// Send sends a command to the daemon and returns the response. A daemon on
+// another protocol version yields ErrVersionMismatch, except for stop.
 func (c *IPCClient) Send(cmd Command) (*Response, error) {
+	cmd.Version = ProtocolVersion
 	conn, err := net.Dial("unix", c.socketPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to daemon: %w", err)
@@ -175,13 +227,20 @@ func (c *IPCClient) Send(cmd Command) (*Response, error) {
 	if err := json.Unmarshal(line, &resp); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
+	if resp.Version != ProtocolVersion && cmd.Action != "stop" {
+		return nil, fmt.Errorf("%w: %s", ErrVersionMismatch, versionMismatchMessage(resp.Version, ProtocolVersion))
+	}
 
 	return &resp, nil
 }
 
-// IsRunning checks if the daemon is running
+// IsRunning checks if the daemon is running. A daemon on another protocol
+// version still counts as running.
 func (c *IPCClient) IsRunning() bool {
 	resp, err := c.Send(Command{Action: "status"})
+	if errors.Is(err, ErrVersionMismatch) {
+		return true
+	}
 	return err == nil && resp.Status == "ok"
 }
 
@@ -64,6 +64,15 @@ type Dialect interface {
 	// QuoteIdentifier quotes a table or column name to handle reserved words.
 	// SQLite/Postgres: "name" or [name], ClickHouse: `name`
 	QuoteIdentifier(name string) string
+
+	// ColumnExistsSQL returns a query (and its args) that yields the number of
+	// columns named column on table: 1 if it exists, 0 otherwise.
+	// SQLite: pragma_table_info, Postgres: information_schema.columns,
+	// ClickHouse: system.columns
+	ColumnExistsSQL(table, column string) (string, []any)
+
+	// AddColumnSQL generates an ALTER TABLE ... ADD COLUMN statement.
+	AddColumnSQL(table string, col ColumnDef) string
 }
 
 // ColumnDef defines a column for table creation.
@@ -89,6 +98,7 @@ const (
 	ColTypeBoolean
 	ColTypeAutoIncrement // Auto-incrementing primary key
 	ColTypeVector        // Vector type for pgvector (PostgreSQL) or JSON (SQLite)
+	ColTypeBigInteger    // 64-bit integer, for counters that can pass 2^31
 )
 
 // String returns the string representation of the column type.
@@ -110,6 +120,8 @@ func (ct ColumnType) String() string {
 		return "AUTOINCREMENT"
 	case ColTypeVector:
 		return "VECTOR"
+	case ColTypeBigInteger:
+		return "BIGINT"
 	default:
 		return "UNKNOWN"
 	}
@@ -123,7 +123,7 @@ func (d *ClickHouseDialect) columnDefSQL(col ColumnDef, _ bool) string {
 
 func (d *ClickHouseDialect) mapColumnType(ct ColumnType) string {
 	switch ct {
-	case ColTypeInteger, ColTypeAutoIncrement:
+	case ColTypeInteger, ColTypeAutoIncrement, ColTypeBigInteger:
 		return "Int64"
 	case ColTypeText:
 		return "String"
@@ -160,3 +160,11 @@ func (d *ClickHouseDialect) SupportsReturning() bool {
 func (d *ClickHouseDialect) QuoteIdentifier(name string) string {
 	return "`" + strings.ReplaceAll(name, "`", "\\`") + "`"
 }
+
+func (d *ClickHouseDialect) ColumnExistsSQL(table, column string) (string, []any) {
+	return "SELECT count() FROM system.columns WHERE database = currentDatabase() AND table = ? AND name = ?", []any{table, column}
+}
+
+func (d *ClickHouseDialect) AddColumnSQL(table string, col ColumnDef) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s", table, d.columnDefSQL(col, false))
+}
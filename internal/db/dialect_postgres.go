@@ -173,6 +173,8 @@ func (d *PostgresDialect) mapColumnType(ct ColumnType) string {
 	switch ct {
 	case ColTypeInteger:
 		return "INTEGER"
+	case ColTypeBigInteger:
+		return "BIGINT"
 	case ColTypeText:
 		return "TEXT"
 	case ColTypeBlob:
@@ -211,3 +213,12 @@ func (d *PostgresDialect) SupportsReturning() bool {
 func (d *PostgresDialect) QuoteIdentifier(name string) string {
 	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
 }
+
+func (d *PostgresDialect) ColumnExistsSQL(table, column string) (string, []any) {
+	return `SELECT COUNT(*) FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1 AND column_name = $2`, []any{table, column}
+}
+
+func (d *PostgresDialect) AddColumnSQL(table string, col ColumnDef) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s", table, d.columnDefSQL(col, true))
+}
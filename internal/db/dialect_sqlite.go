@@ -132,8 +132,8 @@ func (d *SQLiteDialect) columnDefSQL(col ColumnDef, useCompositePK bool) string
 
 func (d *SQLiteDialect) mapColumnType(ct ColumnType) string {
 	switch ct {
-	case ColTypeInteger:
-		return "INTEGER"
+	case ColTypeInteger, ColTypeBigInteger:
+		return "INTEGER" // 64-bit either way
 	case ColTypeText:
 		return "TEXT"
 	case ColTypeBlob:
@@ -176,3 +176,13 @@ func (d *SQLiteDialect) QuoteIdentifier(name string) string {
 	// SQLite accepts double quotes or square brackets
 	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
 }
+
+func (d *SQLiteDialect) ColumnExistsSQL(table, column string) (string, []any) {
+	return "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", []any{table, column}
+}
+
+func (d *SQLiteDialect) AddColumnSQL(table string, col ColumnDef) string {
+	// SQLite can't add PRIMARY KEY/UNIQUE columns via ALTER TABLE, and
+	// NOT NULL columns require a default
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table, d.columnDefSQL(col, true))
+}
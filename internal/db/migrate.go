@@ -0,0 +1,180 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// MigrationsTable is the table that records applied schema migrations.
+// Multiple components (symbols, embeddings, ...) can share one database,
+// so each row is keyed by component and version.
+const MigrationsTable = "schema_migrations"
+
+// Executor is the subset of DB and Tx used by migrations.
+// Migrations run inside a transaction, so they receive a Tx in practice.
+type Executor interface {
+	Query(query string, args ...any) (Rows, error)
+	QueryRow(query string, args ...any) Row
+	Exec(query string, args ...any) (Result, error)
+}
+
+// Migration is a single, ordered schema change.
+// Up should be idempotent where practical (CREATE ... IF NOT EXISTS), so that
+// databases created before versioning existed can adopt migration #1 safely.
+type Migration struct {
+	// Version is the 1-based, strictly increasing migration number
+	Version int
+
+	// Description is a short human-readable summary
+	Description string
+
+	// Up applies the change
+	Up func(ctx context.Context, exec Executor) error
+}
+
+// Migrator applies an ordered list of migrations for one component.
+type Migrator struct {
+	db         DB
+	dialect    Dialect
+	component  string
+	migrations []Migration
+}
+
+// NewMigrator creates a migrator for the given component.
+// Migrations must be sorted by Version, starting at 1 with no gaps.
+func NewMigrator(db DB, dialect Dialect, component string, migrations []Migration) *Migrator {
+	return &Migrator{
+		db:         db,
+		dialect:    dialect,
+		component:  component,
+		migrations: migrations,
+	}
+}
+
+// LatestVersion returns the highest version this migrator knows about.
+func (m *Migrator) LatestVersion() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// CurrentVersion returns the highest applied version for the component (0 if none).
+func (m *Migrator) CurrentVersion(ctx context.Context) (int, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return 0, err
+	}
+	return m.currentVersion(ctx, m.db)
+}
+
+// Migrate applies all pending migrations in order, each in its own transaction.
+// Returns the number of migrations applied.
+func (m *Migrator) Migrate(ctx context.Context) (int, error) {
+	if err := m.validate(); err != nil {
+		return 0, err
+	}
+	if err := m.ensureTable(ctx); err != nil {
+		return 0, err
+	}
+
+	current, err := m.currentVersion(ctx, m.db)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, mig := range m.migrations {
+		if mig.Version <= current {
+			continue
+		}
+		if err := m.apply(ctx, mig); err != nil {
+			// Another process may have applied it concurrently
+			if now, verr := m.currentVersion(ctx, m.db); verr == nil && now >= mig.Version {
+				current = now
+				continue
+			}
+			return applied, fmt.Errorf("%s migration %d (%s): %w", m.component, mig.Version, mig.Description, err)
+		}
+		current = mig.Version
+		applied++
+	}
+
+	return applied, nil
+}
+
+// apply runs a single migration and records it in the same transaction.
+func (m *Migrator) apply(ctx context.Context, mig Migration) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if err := mig.Up(ctx, tx); err != nil {
+		return err
+	}
+
+	insertSQL := fmt.Sprintf(
+		"INSERT INTO %s (component, version, description, applied_at) VALUES (%s)",
+		MigrationsTable, m.dialect.Placeholders(4))
+	if _, err := tx.Exec(insertSQL, m.component, mig.Version, mig.Description, time.Now().Unix()); err != nil {
+		return fmt.Errorf("recording migration: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// currentVersion reads the highest applied version for the component.
+func (m *Migrator) currentVersion(ctx context.Context, exec DB) (int, error) {
+	query := fmt.Sprintf("SELECT COALESCE(MAX(version), 0) FROM %s WHERE component = %s",
+		MigrationsTable, m.dialect.Placeholder(1))
+	var version int
+	if err := exec.QueryRowContext(ctx, query, m.component).Scan(&version); err != nil {
+		return 0, fmt.Errorf("reading %s schema version: %w", m.component, err)
+	}
+	return version, nil
+}
+
+// ensureTable creates the migrations table if it doesn't exist.
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	columns := []ColumnDef{
+		{Name: "component", Type: ColTypeText, Nullable: false},
+		{Name: "version", Type: ColTypeInteger, Nullable: false},
+		{Name: "description", Type: ColTypeText, Nullable: true},
+		{Name: "applied_at", Type: ColTypeInteger, Nullable: false},
+	}
+	if _, err := m.db.ExecContext(ctx, m.dialect.CreateTableSQL(MigrationsTable, columns)); err != nil {
+		return fmt.Errorf("creating %s table: %w", MigrationsTable, err)
+	}
+
+	idx := m.dialect.CreateIndexSQL(MigrationsTable, "idx_schema_migrations_unique", []string{"component", "version"}, true)
+	if _, err := m.db.ExecContext(ctx, idx); err != nil {
+		return fmt.Errorf("creating %s index: %w", MigrationsTable, err)
+	}
+	return nil
+}
+
+// validate checks that migrations are numbered 1..n in order.
+func (m *Migrator) validate() error {
+	for i, mig := range m.migrations {
+		if mig.Version != i+1 {
+			return fmt.Errorf("%s migrations out of order: position %d has version %d", m.component, i+1, mig.Version)
+		}
+		if mig.Up == nil {
+			return fmt.Errorf("%s migration %d has no Up function", m.component, mig.Version)
+		}
+	}
+	return nil
+}
+
+// ExecAll executes statements in order, stopping at the first error.
+// Convenience for migrations that are a list of DDL statements.
+func ExecAll(exec Executor, statements ...string) error {
+	for _, stmt := range statements {
+		if _, err := exec.Exec(stmt); err != nil {
+			return fmt.Errorf("executing %q: %w", stmt, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMigrator_Migrate(t *testing.T) {
+	database, err := Open(DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	dialect := &SQLiteDialect{}
+
+	migrations := []Migration{
+		{
+			Version:     1,
+			Description: "create widgets",
+			Up: func(ctx context.Context, exec Executor) error {
+				return ExecAll(exec, "CREATE TABLE IF NOT EXISTS widgets (id INTEGER PRIMARY KEY, name TEXT)")
+			},
+		},
+	}
+
+	m := NewMigrator(database, dialect, "widgets", migrations)
+	applied, err := m.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if applied != 1 {
+		t.Errorf("Migrate() applied = %d, want 1", applied)
+	}
+
+	// Re-running is a no-op
+	applied, err = m.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("second Migrate() error = %v", err)
+	}
+	if applied != 0 {
+		t.Errorf("second Migrate() applied = %d, want 0", applied)
+	}
+
+	// Appending a migration upgrades an existing database
+	migrations = append(migrations, Migration{
+		Version:     2,
+		Description: "add color",
+		Up: func(ctx context.Context, exec Executor) error {
+			return ExecAll(exec, "ALTER TABLE widgets ADD COLUMN color TEXT")
+		},
+	})
+	m = NewMigrator(database, dialect, "widgets", migrations)
+	applied, err = m.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("upgrade Migrate() error = %v", err)
+	}
+	if applied != 1 {
+		t.Errorf("upgrade Migrate() applied = %d, want 1", applied)
+	}
+	if _, err := database.Exec("INSERT INTO widgets (name, color) VALUES ('a', 'red')"); err != nil {
+		t.Errorf("new column not usable: %v", err)
+	}
+
+	version, err := m.CurrentVersion(ctx)
+	if err != nil {
+		t.Fatalf("CurrentVersion() error = %v", err)
+	}
+	if version != 2 || m.LatestVersion() != 2 {
+		t.Errorf("CurrentVersion() = %d, LatestVersion() = %d, want 2", version, m.LatestVersion())
+	}
+
+	// Components are versioned independently
+	other, err := NewMigrator(database, dialect, "other", nil).CurrentVersion(ctx)
+	if err != nil {
+		t.Fatalf("CurrentVersion() for other component error = %v", err)
+	}
+	if other != 0 {
+		t.Errorf("other component version = %d, want 0", other)
+	}
+}
+
+func TestMigrator_FailedMigrationRollsBack(t *testing.T) {
+	database, err := Open(DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	m := NewMigrator(database, &SQLiteDialect{}, "broken", []Migration{
+		{
+			Version:     1,
+			Description: "fails",
+			Up: func(ctx context.Context, exec Executor) error {
+				if err := ExecAll(exec, "CREATE TABLE partial (id INTEGER)"); err != nil {
+					return err
+				}
+				return errors.New("boom")
+			},
+		},
+	})
+
+	if _, err := m.Migrate(ctx); err == nil {
+		t.Fatal("Migrate() expected error")
+	}
+
+	version, err := m.CurrentVersion(ctx)
+	if err != nil {
+		t.Fatalf("CurrentVersion() error = %v", err)
+	}
+	if version != 0 {
+		t.Errorf("CurrentVersion() = %d, want 0 after failed migration", version)
+	}
+
+	var count int
+	if err := database.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'partial'").Scan(&count); err != nil {
+		t.Fatalf("checking table: %v", err)
+	}
+	if count != 0 {
+		t.Error("partial table should have been rolled back")
+	}
+}
+
+func TestMigrator_ValidatesOrder(t *testing.T) {
+	noop := func(ctx context.Context, exec Executor) error { return nil }
+	m := NewMigrator(nil, &SQLiteDialect{}, "bad", []Migration{
+		{Version: 1, Up: noop},
+		{Version: 3, Up: noop},
+	})
+	if _, err := m.Migrate(context.Background()); err == nil {
+		t.Error("Migrate() expected error for gap in versions")
+	}
+}
@@ -2,9 +2,12 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
-	"strings"
+	"sync"
+
+	"github.com/lib/pq"
 )
 
 // HNSWConfig holds HNSW index configuration parameters.
@@ -32,6 +35,9 @@ func DefaultHNSWConfig() HNSWConfig {
 type PostgresHNSW struct {
 	db      DB
 	dialect *PostgresDialect
+
+	iterativeOnce sync.Once
+	iterative     bool // pgvector supports hnsw.iterative_scan
 }
 
 // NewPostgresHNSW creates a new PostgreSQL HNSW helper.
@@ -149,42 +155,79 @@ func (p *PostgresHNSW) Search(ctx context.Context, tableName string, query []flo
 	return results, nil
 }
 
+// FilteredHNSWSearchSQL returns SQL for HNSW nearest neighbor search within
+// a set of repositories, passed as a text array in $2.
+func (p *PostgresHNSW) FilteredHNSWSearchSQL(tableName string, limit int, metric string) string {
+	distOp := metricToOperator(metric)
+	return fmt.Sprintf(`
+		SELECT content_hash, embedding %s $1 as distance
+		FROM %s
+		WHERE embedding IS NOT NULL AND repo_root = ANY($2)
+		ORDER BY embedding %s $1
+		LIMIT %d
+	`, distOp, tableName, distOp, limit)
+}
+
 // SearchWithRepoFilter performs HNSW search filtered to specific repositories.
+// The filter is applied inside the index scan, so the top k is computed over
+// the repos' vectors only. On pgvector 0.8.0+ an iterative scan keeps reading
+// the index until k matches are found; on older versions a short result falls
+// back to an exact scan, since the index only yields ef_search candidates.
 func (p *PostgresHNSW) SearchWithRepoFilter(ctx context.Context, tableName string, query []float32, k int, cfg HNSWConfig, repoRoots []string) ([]HNSWSearchResult, error) {
 	if len(repoRoots) == 0 {
 		return p.Search(ctx, tableName, query, k, cfg)
 	}
 
-	// Set ef_search for this query session
-	if err := p.SetEfSearch(ctx, cfg.EfSearch); err != nil {
-		return nil, err
+	// SET LOCAL needs a transaction so the settings apply to this query's connection
+	tx, err := p.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("starting filtered HNSW search: %w", err)
 	}
+	defer tx.Rollback()
 
-	// Format query vector
-	queryVec := formatVectorForPgvector(query)
+	efSearch := cfg.EfSearch
+	if efSearch < k {
+		efSearch = k
+	}
+	if _, err := tx.Exec(fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", efSearch)); err != nil {
+		return nil, fmt.Errorf("setting ef_search: %w", err)
+	}
 
-	// Build query with repo filter
-	distOp := metricToOperator(cfg.DistanceMetric)
-	placeholders := make([]string, len(repoRoots))
-	args := make([]interface{}, 0, len(repoRoots)+1)
-	args = append(args, queryVec)
-	for i, repo := range repoRoots {
-		placeholders[i] = fmt.Sprintf("$%d", i+2)
-		args = append(args, repo)
+	iterative := p.supportsIterativeScan(ctx)
+	if iterative {
+		if _, err := tx.Exec("SET LOCAL hnsw.iterative_scan = strict_order"); err != nil {
+			return nil, fmt.Errorf("enabling iterative scan: %w", err)
+		}
 	}
 
-	sql := fmt.Sprintf(`
-		SELECT content_hash, embedding %s $1 as distance
-		FROM %s
-		WHERE embedding IS NOT NULL AND repo_root IN (%s)
-		ORDER BY embedding %s $1
-		LIMIT %d
-	`, distOp, tableName, strings.Join(placeholders, ", "), distOp, k)
+	searchSQL := p.FilteredHNSWSearchSQL(tableName, k, cfg.DistanceMetric)
+	args := []any{formatVectorForPgvector(query), pq.Array(repoRoots)}
 
-	rows, err := p.db.QueryContext(ctx, sql, args...)
+	results, err := queryHNSWResults(tx, searchSQL, cfg.DistanceMetric, args...)
 	if err != nil {
 		return nil, fmt.Errorf("executing filtered HNSW search: %w", err)
 	}
+	if len(results) >= k || iterative {
+		return results, nil
+	}
+
+	// Too few candidates survived the filter; rank the repos' vectors exactly
+	if _, err := tx.Exec("SET LOCAL enable_indexscan = off"); err != nil {
+		return nil, fmt.Errorf("disabling index scan: %w", err)
+	}
+	results, err = queryHNSWResults(tx, searchSQL, cfg.DistanceMetric, args...)
+	if err != nil {
+		return nil, fmt.Errorf("executing exact filtered search: %w", err)
+	}
+	return results, nil
+}
+
+// queryHNSWResults runs a search query within a transaction and scans its results.
+func queryHNSWResults(tx Tx, query, metric string, args ...any) ([]HNSWSearchResult, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
 	var results []HNSWSearchResult
@@ -193,13 +236,26 @@ func (p *PostgresHNSW) SearchWithRepoFilter(ctx context.Context, tableName strin
 		if err := rows.Scan(&r.ContentHash, &r.Distance); err != nil {
 			return nil, fmt.Errorf("scanning search result: %w", err)
 		}
-		r.Score = distanceToScore(r.Distance, cfg.DistanceMetric)
+		r.Score = distanceToScore(r.Distance, metric)
 		results = append(results, r)
 	}
 
 	return results, rows.Err()
 }
 
+// supportsIterativeScan reports whether pgvector supports hnsw.iterative_scan
+// (0.8.0+). The version is looked up once per PostgresHNSW.
+func (p *PostgresHNSW) supportsIterativeScan(ctx context.Context) bool {
+	p.iterativeOnce.Do(func() {
+		var version string
+		err := p.db.QueryRowContext(ctx, `
+			SELECT extversion FROM pg_extension WHERE extname = 'vector'
+		`).Scan(&version)
+		p.iterative = err == nil && isVersionAtLeast(version, "0.8.0")
+	})
+	return p.iterative
+}
+
 // RebuildIndex drops and recreates the HNSW index.
 // This is useful after bulk inserts to optimize index structure.
 func (p *PostgresHNSW) RebuildIndex(ctx context.Context, tableName string, cfg HNSWConfig) error {
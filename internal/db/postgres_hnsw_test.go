@@ -188,6 +188,23 @@ func TestHNSWSearchSQL(t *testing.T) {
 	}
 }
 
+func TestFilteredHNSWSearchSQL(t *testing.T) {
+	hnsw := NewPostgresHNSW(nil)
+
+	sql := hnsw.FilteredHNSWSearchSQL("embeddings_768", 10, "cosine")
+
+	// The repo filter must be part of the same query as the vector ordering
+	if !containsString(sql, "repo_root = ANY($2)") {
+		t.Errorf("SQL should filter repo_root with ANY($2): %s", sql)
+	}
+	if !containsString(sql, "ORDER BY embedding <=> $1") {
+		t.Errorf("SQL should order by cosine distance: %s", sql)
+	}
+	if !containsString(sql, "LIMIT 10") {
+		t.Errorf("SQL should contain LIMIT 10: %s", sql)
+	}
+}
+
 func TestDefaultHNSWConfig_DB(t *testing.T) {
 	cfg := DefaultHNSWConfig()
 
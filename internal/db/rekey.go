@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrRepoKeyInUse is returned by RekeyRepo when rows already exist under
+// the new repo_root, which the rename would collide with.
+var ErrRepoKeyInUse = errors.New("repo_root already has indexed data")
+
+// RepoRootTables returns the tables in database that have a repo_root
+// column, sorted by name: every table holding per-repo index data, whichever
+// component created it.
+func RepoRootTables(ctx context.Context, database DB, dialect Dialect) ([]string, error) {
+	var query string
+	switch dialect.Name() {
+	case "postgres":
+		query = `SELECT table_name FROM information_schema.columns
+			WHERE table_schema = current_schema() AND column_name = 'repo_root'`
+	case "sqlite":
+		query = `SELECT m.name FROM sqlite_master m, pragma_table_info(m.name) p
+			WHERE m.type = 'table' AND p.name = 'repo_root'`
+	default:
+		return nil, fmt.Errorf("listing repo tables is not supported for %s", dialect.Name())
+	}
+
+	rows, err := database.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("listing repo tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scanning table name: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	slices.Sort(tables)
+	return slices.Compact(tables), nil
+}
+
+// CountRepoRows returns how many rows each repo table holds for repoRoot.
+// Tables with no rows for it are left out.
+func CountRepoRows(ctx context.Context, database DB, dialect Dialect, repoRoot string) (map[string]int64, error) {
+	tables, err := RepoRootTables(ctx, database, dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64)
+	for _, table := range tables {
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE repo_root = %s", table, dialect.Placeholder(1))
+		var n int64
+		if err := database.QueryRowContext(ctx, query, repoRoot).Scan(&n); err != nil {
+			return nil, fmt.Errorf("counting %s rows: %w", table, err)
+		}
+		if n > 0 {
+			counts[table] = n
+		}
+	}
+	return counts, nil
+}
+
+// RekeyRepo moves every row keyed by repo_root from to to, across all repo
+// tables in one transaction, so a repository's index follows it to a new
+// path. It fails with ErrRepoKeyInUse if any table already has rows under
+// to. Returns the rows updated per table.
+func RekeyRepo(ctx context.Context, database DB, dialect Dialect, from, to string) (map[string]int64, error) {
+	if from == to {
+		return nil, fmt.Errorf("old and new repo_root are both %q", from)
+	}
+
+	existing, err := CountRepoRows(ctx, database, dialect, to)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		return nil, fmt.Errorf("%w: %q in %d tables", ErrRepoKeyInUse, to, len(existing))
+	}
+	tables, err := RepoRootTables(ctx, database, dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	updated := make(map[string]int64)
+	for _, table := range tables {
+		query := fmt.Sprintf("UPDATE %s SET repo_root = %s WHERE repo_root = %s",
+			table, dialect.Placeholder(1), dialect.Placeholder(2))
+		res, err := tx.Exec(query, to, from)
+		if err != nil {
+			return nil, fmt.Errorf("updating %s: %w", table, err)
+		}
+		if n, err := res.RowsAffected(); err == nil && n > 0 {
+			updated[table] = n
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing: %w", err)
+	}
+	return updated, nil
+}
+
+// AdoptRepoKey moves a repository's rows from its path key to its current
+// key when they differ (a remote-derived identity or CODETECT_REPO_ID) and
+// nothing is indexed under the current key yet, so switching how a repo is
+// keyed picks up its existing index instead of orphaning it. It reports
+// whether any rows moved.
+func AdoptRepoKey(ctx context.Context, database DB, dialect Dialect, pathKey, key string) (bool, error) {
+	if pathKey == "" || pathKey == key {
+		return false, nil
+	}
+	old, err := CountRepoRows(ctx, database, dialect, pathKey)
+	if err != nil || len(old) == 0 {
+		return false, err
+	}
+	updated, err := RekeyRepo(ctx, database, dialect, pathKey, key)
+	if errors.Is(err, ErrRepoKeyInUse) {
+		return false, nil // Already indexed under the new key; leave both
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(updated) > 0, nil
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRekeyRepo(t *testing.T) {
+	database, err := Open(DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer database.Close()
+	dialect := GetDialect(DatabaseSQLite)
+	ctx := context.Background()
+
+	if err := ExecAll(database,
+		"CREATE TABLE symbols (repo_root TEXT NOT NULL, name TEXT, UNIQUE (repo_root, name))",
+		"CREATE TABLE repo_embedding_configs (repo_root TEXT PRIMARY KEY, model TEXT)",
+		"CREATE VIRTUAL TABLE keyword_fts USING fts5(repo_root UNINDEXED, content)",
+		"CREATE TABLE schema_version (version INTEGER)",
+		"INSERT INTO symbols VALUES ('/old/repo', 'A'), ('/old/repo', 'B'), ('/other', 'A')",
+		"INSERT INTO repo_embedding_configs VALUES ('/old/repo', 'nomic')",
+		"INSERT INTO keyword_fts VALUES ('/old/repo', 'func A')",
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	tables, err := RepoRootTables(ctx, database, dialect)
+	if err != nil {
+		t.Fatalf("RepoRootTables() error = %v", err)
+	}
+	if want := []string{"keyword_fts", "repo_embedding_configs", "symbols"}; len(tables) != len(want) || tables[0] != want[0] || tables[2] != want[2] {
+		t.Errorf("RepoRootTables() = %v, want %v", tables, want)
+	}
+
+	updated, err := RekeyRepo(ctx, database, dialect, "/old/repo", "/new/repo")
+	if err != nil {
+		t.Fatalf("RekeyRepo() error = %v", err)
+	}
+	if updated["symbols"] != 2 || updated["repo_embedding_configs"] != 1 || updated["keyword_fts"] != 1 {
+		t.Errorf("RekeyRepo() updated %v, want every /old/repo row", updated)
+	}
+
+	if counts, err := CountRepoRows(ctx, database, dialect, "/old/repo"); err != nil || len(counts) != 0 {
+		t.Errorf("rows left under the old root = %v, %v", counts, err)
+	}
+	if counts, err := CountRepoRows(ctx, database, dialect, "/other"); err != nil || counts["symbols"] != 1 {
+		t.Errorf("other repo's rows = %v, %v, want untouched", counts, err)
+	}
+
+	// Moving onto a root that already has data would merge two indexes
+	if err := ExecAll(database, "INSERT INTO symbols VALUES ('/old/repo', 'C')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RekeyRepo(ctx, database, dialect, "/old/repo", "/other"); !errors.Is(err, ErrRepoKeyInUse) {
+		t.Errorf("RekeyRepo() onto an indexed root error = %v, want ErrRepoKeyInUse", err)
+	}
+}
+
+func TestAdoptRepoKey(t *testing.T) {
+	database, err := Open(DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer database.Close()
+	dialect := GetDialect(DatabaseSQLite)
+	ctx := context.Background()
+
+	if err := ExecAll(database,
+		"CREATE TABLE symbols (repo_root TEXT NOT NULL, name TEXT)",
+		"INSERT INTO symbols VALUES ('/home/me/repo', 'A'), ('/home/me/clone', 'A')",
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rows under the path move to the remote-derived key
+	moved, err := AdoptRepoKey(ctx, database, dialect, "/home/me/repo", "github.com/org/repo")
+	if err != nil || !moved {
+		t.Fatalf("AdoptRepoKey() = %v, %v, want the path rows moved", moved, err)
+	}
+	if counts, _ := CountRepoRows(ctx, database, dialect, "github.com/org/repo"); counts["symbols"] != 1 {
+		t.Errorf("rows under the new key = %v, want 1", counts)
+	}
+
+	// A second checkout of the same remote is left alone rather than merged
+	moved, err = AdoptRepoKey(ctx, database, dialect, "/home/me/clone", "github.com/org/repo")
+	if err != nil || moved {
+		t.Errorf("AdoptRepoKey() onto an indexed key = %v, %v, want nothing moved", moved, err)
+	}
+	if counts, _ := CountRepoRows(ctx, database, dialect, "/home/me/clone"); counts["symbols"] != 1 {
+		t.Errorf("second checkout's rows = %v, want untouched", counts)
+	}
+
+	// Nothing to do when the keys match or the path has no rows
+	for _, from := range []string{"github.com/org/repo", "/home/me/none"} {
+		if moved, err := AdoptRepoKey(ctx, database, dialect, from, "github.com/org/repo"); err != nil || moved {
+			t.Errorf("AdoptRepoKey(%q) = %v, %v, want nothing moved", from, moved, err)
+		}
+	}
+}
@@ -44,6 +44,45 @@ func (s *SchemaBuilder) CreateIndex(ctx context.Context, table, indexName string
 	return err
 }
 
+// ColumnExists reports whether table has a column with the given name.
+func (s *SchemaBuilder) ColumnExists(ctx context.Context, table, column string) (bool, error) {
+	return ColumnExists(ctx, s.db, s.dialect, table, column)
+}
+
+// EnsureColumn adds col to table via ALTER TABLE if it doesn't already exist.
+// Returns true if the column was added. Existing rows are preserved; new
+// NOT NULL columns need a Default so they can be backfilled.
+func (s *SchemaBuilder) EnsureColumn(ctx context.Context, table string, col ColumnDef) (bool, error) {
+	return EnsureColumn(ctx, s.db, s.dialect, table, col)
+}
+
+// ColumnExists reports whether table has a column with the given name.
+// Accepts any Executor so it can be used inside migrations.
+func ColumnExists(ctx context.Context, exec Executor, dialect Dialect, table, column string) (bool, error) {
+	query, args := dialect.ColumnExistsSQL(table, column)
+	var count int
+	if err := exec.QueryRow(query, args...).Scan(&count); err != nil {
+		return false, fmt.Errorf("checking column %s.%s: %w", table, column, err)
+	}
+	return count > 0, nil
+}
+
+// EnsureColumn adds col to table via ALTER TABLE if it doesn't already exist.
+// Accepts any Executor so it can be used inside migrations.
+func EnsureColumn(ctx context.Context, exec Executor, dialect Dialect, table string, col ColumnDef) (bool, error) {
+	exists, err := ColumnExists(ctx, exec, dialect, table, col.Name)
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+	if _, err := exec.Exec(dialect.AddColumnSQL(table, col)); err != nil {
+		return false, fmt.Errorf("adding column %s.%s: %w", table, col.Name, err)
+	}
+	return true, nil
+}
+
 // Upsert performs an insert-or-update operation.
 // columns: all columns to insert
 // conflictColumns: columns that define uniqueness (for ON CONFLICT)
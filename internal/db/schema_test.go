@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"testing"
 )
 
@@ -140,3 +141,81 @@ func TestQueryBuilder_SQL(t *testing.T) {
 		})
 	}
 }
+
+func TestSchemaBuilder_EnsureColumn(t *testing.T) {
+	database, err := Open(DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	schema := NewSchemaBuilder(database, &SQLiteDialect{})
+
+	if _, err := database.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatalf("creating table: %v", err)
+	}
+	if _, err := database.Exec("INSERT INTO items (name) VALUES ('existing')"); err != nil {
+		t.Fatalf("inserting row: %v", err)
+	}
+
+	col := ColumnDef{Name: "status", Type: ColTypeText, Nullable: false, Default: "'new'"}
+
+	added, err := schema.EnsureColumn(ctx, "items", col)
+	if err != nil {
+		t.Fatalf("EnsureColumn() error = %v", err)
+	}
+	if !added {
+		t.Error("EnsureColumn() should report the column was added")
+	}
+
+	// Second call is a no-op
+	added, err = schema.EnsureColumn(ctx, "items", col)
+	if err != nil {
+		t.Fatalf("second EnsureColumn() error = %v", err)
+	}
+	if added {
+		t.Error("second EnsureColumn() should not add the column again")
+	}
+
+	// Existing rows are preserved and backfilled with the default
+	var name, status string
+	if err := database.QueryRow("SELECT name, status FROM items").Scan(&name, &status); err != nil {
+		t.Fatalf("querying row: %v", err)
+	}
+	if name != "existing" || status != "new" {
+		t.Errorf("row = (%q, %q), want (\"existing\", \"new\")", name, status)
+	}
+
+	exists, err := schema.ColumnExists(ctx, "items", "missing")
+	if err != nil {
+		t.Fatalf("ColumnExists() error = %v", err)
+	}
+	if exists {
+		t.Error("ColumnExists() = true for missing column")
+	}
+}
+
+func TestDialects_AddColumnSQL(t *testing.T) {
+	col := ColumnDef{Name: "signature", Type: ColTypeText, Nullable: true}
+	tests := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{&SQLiteDialect{}, "ALTER TABLE symbols ADD COLUMN signature TEXT"},
+		{&PostgresDialect{}, "ALTER TABLE symbols ADD COLUMN IF NOT EXISTS signature TEXT"},
+		{&ClickHouseDialect{}, "ALTER TABLE symbols ADD COLUMN IF NOT EXISTS signature String"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dialect.Name(), func(t *testing.T) {
+			if got := tt.dialect.AddColumnSQL("symbols", col); got != tt.want {
+				t.Errorf("AddColumnSQL() = %q, want %q", got, tt.want)
+			}
+			query, args := tt.dialect.ColumnExistsSQL("symbols", "signature")
+			if query == "" || len(args) != 2 {
+				t.Errorf("ColumnExistsSQL() = %q, %v", query, args)
+			}
+		})
+	}
+}
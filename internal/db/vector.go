@@ -1,6 +1,9 @@
 package db
 
-import "context"
+import (
+	"context"
+	"math"
+)
 
 // VectorDB provides an interface for vector similarity search operations.
 // This abstraction allows switching between different vector search backends:
@@ -262,12 +265,7 @@ func sqrt32(x float32) float32 {
 	if x <= 0 {
 		return 0
 	}
-	// Newton-Raphson method
-	z := x / 2
-	for range 10 {
-		z = z - (z*z-x)/(2*z)
-	}
-	return z
+	return float32(math.Sqrt(float64(x)))
 }
 
 // Verify interface compliance at compile time.
@@ -187,6 +187,10 @@ func TestSqrt32(t *testing.T) {
 		{4, 2},
 		{9, 3},
 		{25, 5},
+		// A fixed-iteration Newton's method drifted this far from 1
+		{1e6, 1000},
+		{1e8, 10000},
+		{1e-4, 0.01},
 	}
 
 	for _, tt := range tests {
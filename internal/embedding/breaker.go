@@ -0,0 +1,147 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrProviderOffline is returned when the embedding provider stops
+// responding mid-run and doesn't come back within the breaker's downtime limit.
+var ErrProviderOffline = errors.New("embedding provider went offline")
+
+// CircuitBreakerConfig controls when an embedding run pauses to wait for
+// a failing provider, and when it gives up.
+type CircuitBreakerConfig struct {
+	// Threshold is the number of consecutive embed failures, across all
+	// workers, that trips the breaker. 0 disables the breaker.
+	Threshold int
+
+	// InitialBackoff is the delay before the first availability probe.
+	// It doubles after each failed probe, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// MaxDowntime is how long to keep probing before aborting the run.
+	MaxDowntime time.Duration
+}
+
+// DefaultCircuitBreakerConfig returns the breaker settings used for embed runs.
+func DefaultCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		Threshold:      10,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+		MaxDowntime:    2 * time.Minute,
+	}
+}
+
+// circuitBreaker pauses embedding workers after repeated failures. The
+// worker whose failure trips the breaker probes the provider with backoff
+// while the others block in Allow; once the provider is back they all
+// resume, and if it never comes back they all stop with ErrProviderOffline.
+type circuitBreaker struct {
+	cfg       CircuitBreakerConfig
+	available func() bool
+	logger    *slog.Logger
+
+	mu       sync.Mutex
+	failures int
+	probing  chan struct{} // Non-nil while probing, closed when the probe ends
+	err      error         // Set once the run must stop
+}
+
+func newCircuitBreaker(cfg CircuitBreakerConfig, available func() bool, logger *slog.Logger) *circuitBreaker {
+	return &circuitBreaker{cfg: cfg, available: available, logger: logger}
+}
+
+// Allow blocks while the breaker is open. It returns an error once the
+// provider has been declared offline or ctx is cancelled.
+func (b *circuitBreaker) Allow(ctx context.Context) error {
+	b.mu.Lock()
+	wait, err := b.probing, b.err
+	b.mu.Unlock()
+
+	if err != nil || wait == nil {
+		return err
+	}
+
+	select {
+	case <-wait:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
+
+// Record notes the outcome of an embed call. Once Threshold consecutive
+// failures accumulate, the calling worker probes the provider until it
+// recovers or MaxDowntime passes. Returns a non-nil error if the run must stop.
+func (b *circuitBreaker) Record(ctx context.Context, embedErr error) error {
+	b.mu.Lock()
+	if embedErr == nil {
+		b.failures = 0
+		b.mu.Unlock()
+		return nil
+	}
+
+	b.failures++
+	if b.cfg.Threshold <= 0 || b.failures < b.cfg.Threshold || b.probing != nil || b.err != nil {
+		err := b.err
+		b.mu.Unlock()
+		return err
+	}
+
+	done := make(chan struct{})
+	b.probing = done
+	failures := b.failures
+	b.mu.Unlock()
+
+	b.logger.Warn("embedding provider failing, pausing until it responds", "consecutive_failures", failures)
+	err := b.probe(ctx)
+	if err == nil {
+		b.logger.Info("embedding provider recovered, resuming")
+	}
+
+	b.mu.Lock()
+	b.failures = 0
+	b.err = err
+	b.probing = nil
+	close(done)
+	b.mu.Unlock()
+	return err
+}
+
+// probe polls the provider's availability with exponential backoff.
+func (b *circuitBreaker) probe(ctx context.Context) error {
+	deadline := time.Now().Add(b.cfg.MaxDowntime)
+	backoff := b.cfg.InitialBackoff
+	if backoff <= 0 {
+		backoff = time.Millisecond
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		if b.available() {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return ErrProviderOffline
+		}
+
+		backoff *= 2
+		if b.cfg.MaxBackoff > 0 && backoff > b.cfg.MaxBackoff {
+			backoff = b.cfg.MaxBackoff
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"codetect/internal/db"
+)
+
+// outageEmbedder succeeds for the first upFor calls, then fails until
+// Available has been probed recoverAfter times (never, if negative).
+type outageEmbedder struct {
+	*mockEmbedder
+
+	mu           sync.Mutex
+	upFor        int
+	recoverAfter int
+	calls        int
+	failed       int
+	probes       int
+}
+
+func (e *outageEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.calls++
+	if e.calls > e.upFor && (e.recoverAfter < 0 || e.probes < e.recoverAfter) {
+		e.failed++
+		return nil, fmt.Errorf("connection refused")
+	}
+	return e.mockEmbedder.Embed(ctx, texts)
+}
+
+func (e *outageEmbedder) Available() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.calls <= e.upFor {
+		return true
+	}
+	e.probes++
+	return e.recoverAfter >= 0 && e.probes >= e.recoverAfter
+}
+
+func setupBreakerTest(t *testing.T, embedder Embedder) *SemanticSearcher {
+	t.Helper()
+
+	database, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	store, err := NewEmbeddingStore(database, "/test/repo")
+	if err != nil {
+		t.Fatalf("creating store: %v", err)
+	}
+
+	searcher := NewSemanticSearcher(store, embedder)
+	searcher.SetCircuitBreaker(CircuitBreakerConfig{
+		Threshold:      3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     2 * time.Millisecond,
+		MaxDowntime:    20 * time.Millisecond,
+	})
+	return searcher
+}
+
+func breakerTestChunks(n int) []Chunk {
+	chunks := make([]Chunk, n)
+	for i := range chunks {
+		chunks[i] = Chunk{
+			Path:      fmt.Sprintf("file%d.go", i),
+			StartLine: 1,
+			EndLine:   3,
+			Content:   fmt.Sprintf("func f%d() {}", i),
+		}
+	}
+	return chunks
+}
+
+func TestIndexChunksParallelProviderOffline(t *testing.T) {
+	embedder := &outageEmbedder{mockEmbedder: newMockEmbedder(4), upFor: 5, recoverAfter: -1}
+	searcher := setupBreakerTest(t, embedder)
+
+	_, err := searcher.IndexChunksParallel(context.Background(), breakerTestChunks(200), 4, nil)
+	if !errors.Is(err, ErrProviderOffline) {
+		t.Fatalf("IndexChunksParallel() error = %v, want ErrProviderOffline", err)
+	}
+
+	// Stopped well short of hammering every chunk
+	if embedder.calls > 50 {
+		t.Errorf("made %d embed calls after the provider went down", embedder.calls)
+	}
+
+	// Chunks embedded before the outage were kept
+	count, _, err := searcher.Store().Stats()
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if count != 5 {
+		t.Errorf("saved %d embeddings, want 5", count)
+	}
+}
+
+func TestIndexChunksProviderOffline(t *testing.T) {
+	embedder := &outageEmbedder{mockEmbedder: newMockEmbedder(4), upFor: 2, recoverAfter: -1}
+	searcher := setupBreakerTest(t, embedder)
+
+	_, err := searcher.IndexChunks(context.Background(), breakerTestChunks(50), nil)
+	if !errors.Is(err, ErrProviderOffline) {
+		t.Fatalf("IndexChunks() error = %v, want ErrProviderOffline", err)
+	}
+	if embedder.calls != 5 {
+		t.Errorf("made %d embed calls, want 5 (2 ok + 3 failures)", embedder.calls)
+	}
+	if count, _, _ := searcher.Store().Stats(); count != 2 {
+		t.Errorf("saved %d embeddings, want 2", count)
+	}
+}
+
+func TestIndexChunksParallelProviderRecovers(t *testing.T) {
+	embedder := &outageEmbedder{mockEmbedder: newMockEmbedder(4), upFor: 5, recoverAfter: 2}
+	searcher := setupBreakerTest(t, embedder)
+	var logs bytes.Buffer
+	searcher.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	const n = 100
+	if _, err := searcher.IndexChunksParallel(context.Background(), breakerTestChunks(n), 4, nil); err != nil {
+		t.Fatalf("IndexChunksParallel() error = %v, want recovery", err)
+	}
+
+	// Only chunks attempted during the outage are missing
+	count, _, err := searcher.Store().Stats()
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if count != n-embedder.failed {
+		t.Errorf("saved %d embeddings, want %d (%d failed)", count, n-embedder.failed, embedder.failed)
+	}
+
+	// The pause and the recovery go through the searcher's logger
+	if !strings.Contains(logs.String(), "pausing until it responds") || !strings.Contains(logs.String(), "provider recovered") {
+		t.Errorf("breaker did not log the pause and recovery, got:\n%s", logs.String())
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"strings"
 	"sync"
 	"time"
@@ -46,8 +47,31 @@ type CacheStats struct {
 	NewestEntry     time.Time
 	MostAccessed    int
 	LeastAccessed   int
+
+	// Lookups by the indexing pipeline over the cache's lifetime, summed
+	// over every repo sharing the cache (see LookupCounts for one repo). A
+	// hit is a chunk whose embedding was already cached; a miss had to be
+	// embedded.
+	LifetimeHits   int64 `json:"lifetime_hits"`
+	LifetimeMisses int64 `json:"lifetime_misses"`
 }
 
+// HitRatio returns the lifetime share of lookups that were hits, from 0 to
+// 1, or 0 if nothing has been looked up yet.
+func (s *CacheStats) HitRatio() float64 {
+	total := s.LifetimeHits + s.LifetimeMisses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.LifetimeHits) / float64(total)
+}
+
+// Counter names in the cache's stats table
+const (
+	cacheStatHits   = "lookup_hits"
+	cacheStatMisses = "lookup_misses"
+)
+
 // NewEmbeddingCache creates a new content-addressed embedding cache.
 // dimensions specifies the vector size (e.g., 768 for nomic-embed-text).
 // model identifies the embedding model for cache invalidation.
@@ -94,6 +118,17 @@ func (c *EmbeddingCache) initSchema() error {
 		return fmt.Errorf("creating access index: %w", err)
 	}
 
+	// Lifetime counters, kept beside the cache table they describe. The
+	// cache is shared, so each repo counts its own lookups.
+	statsSQL := c.dialect.CreateTableSQL(c.statsTableName(), []db.ColumnDef{
+		{Name: "repo_root", Type: db.ColTypeText, Nullable: false, PrimaryKey: true},
+		{Name: "name", Type: db.ColTypeText, Nullable: false, PrimaryKey: true},
+		{Name: "value", Type: db.ColTypeBigInteger, Nullable: false},
+	})
+	if _, err := c.database.Exec(statsSQL); err != nil {
+		return fmt.Errorf("creating %s table: %w", c.statsTableName(), err)
+	}
+
 	return nil
 }
 
@@ -148,6 +183,11 @@ func (c *EmbeddingCache) tableName() string {
 	return "embedding_cache"
 }
 
+// statsTableName returns the table holding this cache's lifetime counters.
+func (c *EmbeddingCache) statsTableName() string {
+	return c.tableName() + "_stats"
+}
+
 // Get retrieves an embedding by content hash.
 // Returns nil if not found (cache miss), without error.
 // Updates access statistics on cache hit.
@@ -225,6 +265,16 @@ func (c *EmbeddingCache) Get(contentHash string) (*CacheEntry, error) {
 // Returns a map of hash -> entry for found embeddings.
 // Missing hashes are simply not included in the result (no error).
 func (c *EmbeddingCache) GetBatch(hashes []string) (map[string]*CacheEntry, error) {
+	return c.getBatch(hashes, true)
+}
+
+// PeekBatch is GetBatch without recording an access, for bulk reads such
+// as rebuilding a vector index that shouldn't count toward eviction order.
+func (c *EmbeddingCache) PeekBatch(hashes []string) (map[string]*CacheEntry, error) {
+	return c.getBatch(hashes, false)
+}
+
+func (c *EmbeddingCache) getBatch(hashes []string, recordAccess bool) (map[string]*CacheEntry, error) {
 	if len(hashes) == 0 {
 		return make(map[string]*CacheEntry), nil
 	}
@@ -314,7 +364,7 @@ func (c *EmbeddingCache) GetBatch(hashes []string) (map[string]*CacheEntry, erro
 	}
 
 	// Update access stats asynchronously for found entries
-	if len(foundHashes) > 0 {
+	if recordAccess && len(foundHashes) > 0 {
 		go c.updateAccessStatsBatch(foundHashes)
 	}
 
@@ -323,7 +373,14 @@ func (c *EmbeddingCache) GetBatch(hashes []string) (map[string]*CacheEntry, erro
 
 // Put stores an embedding in the cache.
 // If the hash already exists, increments access_count and updates last_accessed.
+// An invalid vector (empty, all zeros, or containing NaN or Inf) is logged
+// and not stored.
 func (c *EmbeddingCache) Put(contentHash string, embedding []float32) error {
+	if err := validateEmbedding(embedding); err != nil {
+		slog.Warn("skipping invalid embedding", "content_hash", contentHash, "error", err)
+		return nil
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -373,6 +430,7 @@ func (c *EmbeddingCache) Put(contentHash string, embedding []float32) error {
 
 // PutBatch stores multiple embeddings in a transaction.
 // More efficient than individual Put calls for bulk operations.
+// Invalid vectors are logged and skipped, as in Put.
 func (c *EmbeddingCache) PutBatch(entries map[string][]float32) error {
 	if len(entries) == 0 {
 		return nil
@@ -417,6 +475,10 @@ func (c *EmbeddingCache) PutBatch(entries map[string][]float32) error {
 	defer stmt.Close()
 
 	for hash, embedding := range entries {
+		if err := validateEmbedding(embedding); err != nil {
+			slog.Warn("skipping invalid embedding", "content_hash", hash, "error", err)
+			continue
+		}
 		embJSON, err := json.Marshal(embedding)
 		if err != nil {
 			return fmt.Errorf("marshaling embedding for %s: %w", hash, err)
@@ -539,9 +601,86 @@ func (c *EmbeddingCache) Stats() (*CacheStats, error) {
 		stats.LeastAccessed = int(leastAccessed.Int64)
 	}
 
+	stats.LifetimeHits, stats.LifetimeMisses, err = c.lookupCounts("", nil)
+	if err != nil {
+		return nil, err
+	}
+
 	return &stats, nil
 }
 
+// LookupCounts returns the lifetime cache hits and misses recorded for one
+// repo's indexing runs.
+func (c *EmbeddingCache) LookupCounts(repoRoot string) (hits, misses int64, err error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.lookupCounts(" WHERE repo_root = ?", []any{repoRoot})
+}
+
+// lookupCounts sums the hit and miss counters of the rows where selects
+func (c *EmbeddingCache) lookupCounts(where string, args []any) (hits, misses int64, err error) {
+	query := c.schema.SubstitutePlaceholders(fmt.Sprintf(
+		"SELECT name, SUM(value) FROM %s%s GROUP BY name", c.statsTableName(), where))
+	rows, err := c.database.Query(query, args...)
+	if err != nil {
+		return 0, 0, fmt.Errorf("querying lifetime stats: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var value int64
+		if err := rows.Scan(&name, &value); err != nil {
+			return 0, 0, fmt.Errorf("scanning lifetime stats: %w", err)
+		}
+		switch name {
+		case cacheStatHits:
+			hits = value
+		case cacheStatMisses:
+			misses = value
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("iterating lifetime stats: %w", err)
+	}
+	return hits, misses, nil
+}
+
+// RecordLookups adds one indexing run's cache hits and misses to repoRoot's
+// lifetime counters. Each counter is read and written back through the
+// dialect's upsert, since not every backend can add to a row on conflict.
+func (c *EmbeddingCache) RecordLookups(repoRoot string, hits, misses int) error {
+	if hits == 0 && misses == 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	table := c.statsTableName()
+	selectQuery := c.schema.SubstitutePlaceholders(fmt.Sprintf(
+		"SELECT value FROM %s WHERE repo_root = ? AND name = ?", table))
+	upsertQuery := c.dialect.UpsertSQL(table,
+		[]string{"repo_root", "name", "value"}, []string{"repo_root", "name"}, []string{"value"})
+
+	tx, err := c.database.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for name, n := range map[string]int{cacheStatHits: hits, cacheStatMisses: misses} {
+		var value int64
+		if err := tx.QueryRow(selectQuery, repoRoot, name).Scan(&value); err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("reading %s: %w", name, err)
+		}
+		if _, err := tx.Exec(upsertQuery, repoRoot, name, value+int64(n)); err != nil {
+			return fmt.Errorf("recording %s: %w", name, err)
+		}
+	}
+	return tx.Commit()
+}
+
 // Evict removes least-recently-used entries to reduce cache size.
 // keepCount specifies the maximum number of entries to retain.
 func (c *EmbeddingCache) Evict(keepCount int) (int, error) {
@@ -613,6 +752,28 @@ func (c *EmbeddingCache) EvictByModel(model string) (int, error) {
 	return int(evicted), nil
 }
 
+// Models returns the distinct models with entries in this cache's table.
+func (c *EmbeddingCache) Models() ([]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	rows, err := c.database.Query(fmt.Sprintf("SELECT DISTINCT model FROM %s", c.tableName()))
+	if err != nil {
+		return nil, fmt.Errorf("listing models: %w", err)
+	}
+	defer rows.Close()
+
+	var models []string
+	for rows.Next() {
+		var model string
+		if err := rows.Scan(&model); err != nil {
+			return nil, fmt.Errorf("scanning model: %w", err)
+		}
+		models = append(models, model)
+	}
+	return models, rows.Err()
+}
+
 // updateAccessStats updates access_count and last_accessed for a single entry.
 func (c *EmbeddingCache) updateAccessStats(contentHash string) {
 	tableName := c.tableName()
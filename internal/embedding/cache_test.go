@@ -1,6 +1,7 @@
 package embedding
 
 import (
+	"math"
 	"math/rand"
 	"testing"
 	"time"
@@ -113,6 +114,31 @@ func TestCachePutUpdate(t *testing.T) {
 	}
 }
 
+func TestCachePutSkipsInvalidEmbeddings(t *testing.T) {
+	cache := setupTestCache(t)
+
+	if err := cache.Put("nan", []float32{float32(math.NaN()), 0.2}); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := cache.PutBatch(map[string][]float32{
+		"good": {0.1, 0.2},
+		"inf":  {float32(math.Inf(1)), 0.2},
+		"zero": {0, 0},
+	}); err != nil {
+		t.Fatalf("PutBatch failed: %v", err)
+	}
+
+	for hash, want := range map[string]bool{"good": true, "nan": false, "inf": false, "zero": false} {
+		has, err := cache.HasEntry(hash)
+		if err != nil {
+			t.Fatalf("HasEntry(%q) failed: %v", hash, err)
+		}
+		if has != want {
+			t.Errorf("HasEntry(%q) = %v, want %v", hash, has, want)
+		}
+	}
+}
+
 func TestBatchLookup(t *testing.T) {
 	cache := setupTestCache(t)
 
@@ -253,7 +279,7 @@ func TestCacheDeleteBatch(t *testing.T) {
 	// Store 5 entries
 	for i := 0; i < 5; i++ {
 		hash := HashContent(string(rune('a' + i)))
-		if err := cache.Put(hash, []float32{float32(i)}); err != nil {
+		if err := cache.Put(hash, []float32{float32(i + 1)}); err != nil {
 			t.Fatalf("Put failed: %v", err)
 		}
 	}
@@ -281,7 +307,7 @@ func TestCacheEvict(t *testing.T) {
 	// Store 10 entries
 	for i := 0; i < 10; i++ {
 		hash := HashContent(string(rune('a' + i)))
-		if err := cache.Put(hash, []float32{float32(i)}); err != nil {
+		if err := cache.Put(hash, []float32{float32(i + 1)}); err != nil {
 			t.Fatalf("Put failed: %v", err)
 		}
 		// Small delay to ensure different timestamps
@@ -318,12 +344,12 @@ func TestCacheEvictByModel(t *testing.T) {
 
 	// Store entries with model-1
 	for i := 0; i < 3; i++ {
-		cache1.Put(HashContent(string(rune('a'+i))), []float32{float32(i)})
+		cache1.Put(HashContent(string(rune('a'+i))), []float32{float32(i + 1)})
 	}
 
 	// Store entries with model-2
 	for i := 0; i < 2; i++ {
-		cache2.Put(HashContent(string(rune('x'+i))), []float32{float32(i)})
+		cache2.Put(HashContent(string(rune('x'+i))), []float32{float32(i + 1)})
 	}
 
 	// Evict model-1 entries
@@ -348,7 +374,7 @@ func TestCacheStats(t *testing.T) {
 	// Store some entries
 	for i := 0; i < 5; i++ {
 		hash := HashContent(string(rune('a' + i)))
-		cache.Put(hash, []float32{float32(i)})
+		cache.Put(hash, []float32{float32(i + 1)})
 	}
 
 	stats, err := cache.Stats()
@@ -361,6 +387,61 @@ func TestCacheStats(t *testing.T) {
 	}
 }
 
+func TestCacheRecordLookups(t *testing.T) {
+	cache := setupTestCache(t)
+
+	stats, err := cache.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.LifetimeHits != 0 || stats.LifetimeMisses != 0 || stats.HitRatio() != 0 {
+		t.Errorf("new cache lifetime stats = %+v, want zero", stats)
+	}
+
+	if err := cache.RecordLookups("/repo/a", 3, 1); err != nil {
+		t.Fatalf("RecordLookups failed: %v", err)
+	}
+	if err := cache.RecordLookups("/repo/a", 5, 1); err != nil {
+		t.Fatalf("RecordLookups failed: %v", err)
+	}
+	if err := cache.RecordLookups("/repo/b", 0, 2); err != nil {
+		t.Fatalf("RecordLookups failed: %v", err)
+	}
+
+	// Stats covers every repo sharing the cache
+	stats, err = cache.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.LifetimeHits != 8 || stats.LifetimeMisses != 4 {
+		t.Errorf("lifetime hits/misses = %d/%d, want 8/4", stats.LifetimeHits, stats.LifetimeMisses)
+	}
+	if got := stats.HitRatio(); got < 0.666 || got > 0.667 {
+		t.Errorf("HitRatio = %v, want 2/3", got)
+	}
+
+	// LookupCounts keeps each repo's own
+	hits, misses, err := cache.LookupCounts("/repo/a")
+	if err != nil || hits != 8 || misses != 2 {
+		t.Errorf("LookupCounts(/repo/a) = %d, %d, %v, want 8, 2", hits, misses, err)
+	}
+	hits, misses, err = cache.LookupCounts("/repo/b")
+	if err != nil || hits != 0 || misses != 2 {
+		t.Errorf("LookupCounts(/repo/b) = %d, %d, %v, want 0, 2", hits, misses, err)
+	}
+
+	// Counters hold 64-bit totals
+	if err := cache.RecordLookups("/repo/c", math.MaxInt32, math.MaxInt32); err != nil {
+		t.Fatalf("RecordLookups failed: %v", err)
+	}
+	if err := cache.RecordLookups("/repo/c", math.MaxInt32, 0); err != nil {
+		t.Fatalf("RecordLookups failed: %v", err)
+	}
+	if hits, _, err := cache.LookupCounts("/repo/c"); err != nil || hits != 2*math.MaxInt32 {
+		t.Errorf("LookupCounts(/repo/c) hits = %d, %v, want %d", hits, err, int64(2*math.MaxInt32))
+	}
+}
+
 func TestHasEntry(t *testing.T) {
 	cache := setupTestCache(t)
 
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"os"
 	"strings"
+	"unicode/utf8"
 
 	"codetect/internal/search/symbols"
 )
@@ -12,6 +13,10 @@ const (
 	DefaultMaxChunkLines = 30
 	DefaultChunkOverlap  = 15
 	MinChunkLines        = 5
+
+	// DefaultMaxChunkChars keeps chunk content comfortably inside the
+	// context window of common embedding models (~2k tokens)
+	DefaultMaxChunkChars = 6000
 )
 
 // Chunk represents a code chunk for embedding
@@ -21,12 +26,25 @@ type Chunk struct {
 	EndLine   int    `json:"end_line"`
 	Content   string `json:"content"`
 	Kind      string `json:"kind"` // "function", "class", "type", "block", "fixed"
+
+	// DocComment is the natural-language doc comment or docstring for the
+	// chunk, embedded separately so searches can match documented intent.
+	DocComment string `json:"doc_comment,omitempty"`
+
+	// Truncated is set when a single line was longer than MaxChunkChars and
+	// the chunk holds only its prefix
+	Truncated bool `json:"truncated,omitempty"`
 }
 
 // ChunkerConfig configures the chunking behavior
 type ChunkerConfig struct {
 	MaxChunkLines int
 	ChunkOverlap  int
+
+	// MaxChunkChars bounds chunk content in bytes. Larger chunks are split
+	// on line boundaries, keeping their kind, and a line that alone exceeds
+	// it is truncated. 0 means unlimited.
+	MaxChunkChars int
 }
 
 // DefaultChunkerConfig returns the default chunker configuration
@@ -34,6 +52,7 @@ func DefaultChunkerConfig() ChunkerConfig {
 	return ChunkerConfig{
 		MaxChunkLines: DefaultMaxChunkLines,
 		ChunkOverlap:  DefaultChunkOverlap,
+		MaxChunkChars: DefaultMaxChunkChars,
 	}
 }
 
@@ -51,11 +70,15 @@ func ChunkFile(path string, syms []symbols.Symbol, config ChunkerConfig) ([]Chun
 
 	// If we have symbols, use them for chunking
 	if len(syms) > 0 {
-		return chunkBySymbols(path, lines, syms, config)
+		chunks, err := chunkBySymbols(path, lines, syms, config)
+		if err != nil {
+			return nil, err
+		}
+		return limitChunkSize(chunks, config.MaxChunkChars), nil
 	}
 
 	// Fall back to fixed-size chunking
-	return chunkByLines(path, lines, config), nil
+	return limitChunkSize(chunkByLines(path, lines, config), config.MaxChunkChars), nil
 }
 
 // chunkBySymbols creates chunks based on symbol boundaries
@@ -251,6 +274,58 @@ func chunkByLines(path string, lines []string, config ChunkerConfig) []Chunk {
 	return chunks
 }
 
+// limitChunkSize splits chunks whose content is longer than maxChars into
+// consecutive line ranges that fit, copying the rest of the chunk's fields.
+// A line longer than maxChars on its own becomes a truncated chunk.
+func limitChunkSize(chunks []Chunk, maxChars int) []Chunk {
+	if maxChars <= 0 {
+		return chunks
+	}
+
+	var limited []Chunk
+	for _, chunk := range chunks {
+		if len(chunk.Content) <= maxChars {
+			limited = append(limited, chunk)
+			continue
+		}
+
+		lines := strings.Split(chunk.Content, "\n")
+		start := 0
+		for start < len(lines) {
+			// Take as many whole lines as fit, always at least one
+			end := start + 1
+			size := len(lines[start])
+			for end < len(lines) && size+1+len(lines[end]) <= maxChars {
+				size += 1 + len(lines[end])
+				end++
+			}
+
+			sub := chunk
+			sub.StartLine = chunk.StartLine + start
+			sub.EndLine = chunk.StartLine + end - 1
+			sub.Content = strings.Join(lines[start:end], "\n")
+			if len(sub.Content) > maxChars {
+				sub.Content = truncateUTF8(sub.Content, maxChars)
+				sub.Truncated = true
+			}
+			limited = append(limited, sub)
+			start = end
+		}
+	}
+	return limited
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a rune
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
 // createChunk creates a chunk from line range
 func createChunk(path string, lines []string, startLine, endLine int, kind string) Chunk {
 	// Convert to 0-indexed for slicing
@@ -291,7 +366,7 @@ func ChunkFileSimple(path string, config ChunkerConfig) ([]Chunk, error) {
 		return nil, err
 	}
 
-	chunks := chunkByLines(path, lines, config)
+	chunks := limitChunkSize(chunkByLines(path, lines, config), config.MaxChunkChars)
 	// Set the path correctly
 	for i := range chunks {
 		chunks[i].Path = path
@@ -212,6 +212,97 @@ func TestDefaultChunkerConfig(t *testing.T) {
 	if config.ChunkOverlap != DefaultChunkOverlap {
 		t.Errorf("expected ChunkOverlap=%d, got %d", DefaultChunkOverlap, config.ChunkOverlap)
 	}
+	if config.MaxChunkChars != DefaultMaxChunkChars {
+		t.Errorf("expected MaxChunkChars=%d, got %d", DefaultMaxChunkChars, config.MaxChunkChars)
+	}
+}
+
+func TestLimitChunkSize(t *testing.T) {
+	lines := []string{"aaaa", "bbbb", "cccc", "dddd", "eeee"}
+	chunk := Chunk{
+		Path:       "test.go",
+		StartLine:  10,
+		EndLine:    14,
+		Content:    strings.Join(lines, "\n"),
+		Kind:       "function",
+		DocComment: "Does things",
+	}
+
+	t.Run("splits on line boundaries", func(t *testing.T) {
+		chunks := limitChunkSize([]Chunk{chunk}, 10)
+		if len(chunks) != 3 {
+			t.Fatalf("expected 3 chunks, got %d: %+v", len(chunks), chunks)
+		}
+		next := chunk.StartLine
+		for _, c := range chunks {
+			if len(c.Content) > 10 {
+				t.Errorf("chunk %d-%d is %d bytes, want at most 10", c.StartLine, c.EndLine, len(c.Content))
+			}
+			if c.StartLine != next {
+				t.Errorf("chunk starts at line %d, want %d", c.StartLine, next)
+			}
+			if c.Kind != "function" || c.DocComment != "Does things" || c.Truncated {
+				t.Errorf("sub-chunk lost its metadata: %+v", c)
+			}
+			next = c.EndLine + 1
+		}
+		if next != chunk.EndLine+1 {
+			t.Errorf("sub-chunks end at line %d, want %d", next-1, chunk.EndLine)
+		}
+		if chunks[0].Content != "aaaa\nbbbb" {
+			t.Errorf("first sub-chunk = %q", chunks[0].Content)
+		}
+	})
+
+	t.Run("leaves small chunks alone", func(t *testing.T) {
+		if chunks := limitChunkSize([]Chunk{chunk}, len(chunk.Content)); len(chunks) != 1 || chunks[0].Content != chunk.Content {
+			t.Errorf("chunk at the limit was changed: %+v", chunks)
+		}
+		if chunks := limitChunkSize([]Chunk{chunk}, 0); len(chunks) != 1 {
+			t.Errorf("expected no limit with 0, got %d chunks", len(chunks))
+		}
+	})
+
+	t.Run("truncates an overlong line", func(t *testing.T) {
+		long := Chunk{StartLine: 1, EndLine: 1, Content: strings.Repeat("é", 10), Kind: "fixed"}
+		chunks := limitChunkSize([]Chunk{long}, 5)
+		if len(chunks) != 1 {
+			t.Fatalf("expected 1 chunk, got %d", len(chunks))
+		}
+		if !chunks[0].Truncated {
+			t.Error("expected Truncated to be set")
+		}
+		if chunks[0].Content != "éé" {
+			t.Errorf("truncated content = %q, want whole runes within 5 bytes", chunks[0].Content)
+		}
+	})
+}
+
+func TestChunkFileMaxChunkChars(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "bundle.min.js")
+	content := strings.Repeat("x", 20000) + "\n" + strings.Repeat("var a = 1;\n", 10)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultChunkerConfig()
+	config.MaxChunkChars = 1000
+	chunks, err := ChunkFile(path, nil, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) == 0 {
+		t.Fatal("expected chunks")
+	}
+	for _, c := range chunks {
+		if len(c.Content) > config.MaxChunkChars {
+			t.Errorf("chunk %d-%d is %d bytes, want at most %d", c.StartLine, c.EndLine, len(c.Content), config.MaxChunkChars)
+		}
+	}
+	if !chunks[0].Truncated {
+		t.Error("expected the minified line's chunk to be truncated")
+	}
 }
 
 func TestTruncateSnippet(t *testing.T) {
@@ -0,0 +1,76 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// FallbackEmbedder tries a chain of providers in order, so embedding and
+// search keep working when the primary (say, a shared LiteLLM endpoint) is
+// down and a secondary (a local Ollama) is up.
+//
+// Vectors from different models aren't comparable, so every link must
+// serve the same model at the same dimensions; NewFallbackEmbedder refuses
+// a chain that doesn't. Embeddings are recorded under the primary's
+// ProviderID whichever link made them, so a fallback never splits the index.
+type FallbackEmbedder struct {
+	links []Embedder
+}
+
+// NewFallbackEmbedder chains links, primary first. It fails unless every
+// link serves the primary's model ("ollama:nomic-embed-text" and
+// "litellm:nomic-embed-text" match) with the same dimensions.
+func NewFallbackEmbedder(links ...Embedder) (*FallbackEmbedder, error) {
+	if len(links) == 0 {
+		return nil, fmt.Errorf("fallback chain has no providers")
+	}
+	primary := links[0]
+	model := baseModelName(ModelName(primary.ProviderID()))
+	for _, link := range links[1:] {
+		if m := baseModelName(ModelName(link.ProviderID())); m != model {
+			return nil, fmt.Errorf("fallback %s serves a different model than %s; vectors from different models aren't comparable", link.ProviderID(), primary.ProviderID())
+		}
+		if d, p := link.Dimensions(), primary.Dimensions(); d > 0 && p > 0 && d != p {
+			return nil, fmt.Errorf("fallback %s has %d dimensions, %s has %d", link.ProviderID(), d, primary.ProviderID(), p)
+		}
+	}
+	return &FallbackEmbedder{links: links}, nil
+}
+
+// Embed implements Embedder.Embed, trying each link in order until one
+// succeeds
+func (f *FallbackEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var errs []error
+	for _, link := range f.links {
+		vectors, err := link.Embed(ctx, texts)
+		if err == nil {
+			return vectors, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", link.ProviderID(), err))
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Available implements Embedder.Available - true if any link is available
+func (f *FallbackEmbedder) Available() bool {
+	for _, link := range f.links {
+		if link.Available() {
+			return true
+		}
+	}
+	return false
+}
+
+// ProviderID implements Embedder.ProviderID - the primary's ID
+func (f *FallbackEmbedder) ProviderID() string {
+	return f.links[0].ProviderID()
+}
+
+// Dimensions implements Embedder.Dimensions - the primary's dimensions
+func (f *FallbackEmbedder) Dimensions() int {
+	return f.links[0].Dimensions()
+}
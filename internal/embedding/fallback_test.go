@@ -0,0 +1,68 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// chainLink is a mockEmbedder under its own provider ID that can be down
+type chainLink struct {
+	*mockEmbedder
+	id   string
+	down bool
+}
+
+func (l *chainLink) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if l.down {
+		return nil, errors.New("connection refused")
+	}
+	return l.mockEmbedder.Embed(ctx, texts)
+}
+
+func (l *chainLink) Available() bool    { return !l.down }
+func (l *chainLink) ProviderID() string { return l.id }
+
+func TestFallbackEmbedder(t *testing.T) {
+	primary := &chainLink{mockEmbedder: newMockEmbedder(4), id: "litellm:nomic-embed-text", down: true}
+	secondary := &chainLink{mockEmbedder: newMockEmbedder(4), id: "ollama:nomic-embed-text:latest"}
+
+	chain, err := NewFallbackEmbedder(primary, secondary)
+	if err != nil {
+		t.Fatalf("NewFallbackEmbedder() error = %v", err)
+	}
+	if !chain.Available() {
+		t.Error("Available() = false with the secondary up")
+	}
+	if _, err := chain.Embed(context.Background(), []string{"x"}); err != nil {
+		t.Fatalf("Embed() error = %v, want the secondary's vectors", err)
+	}
+	if secondary.embedCount != 1 {
+		t.Errorf("secondary embedded %d texts, want 1", secondary.embedCount)
+	}
+	if chain.ProviderID() != primary.ProviderID() {
+		t.Errorf("ProviderID() = %s, want the primary's %s", chain.ProviderID(), primary.ProviderID())
+	}
+
+	secondary.down = true
+	if chain.Available() {
+		t.Error("Available() = true with every link down")
+	}
+	if _, err := chain.Embed(context.Background(), []string{"x"}); err == nil {
+		t.Error("Embed() succeeded with every link down")
+	}
+}
+
+func TestNewFallbackEmbedderRefusesMismatches(t *testing.T) {
+	primary := &chainLink{mockEmbedder: newMockEmbedder(4), id: "litellm:nomic-embed-text"}
+
+	otherModel := &chainLink{mockEmbedder: newMockEmbedder(4), id: "ollama:bge-m3"}
+	if _, err := NewFallbackEmbedder(primary, otherModel); err == nil {
+		t.Error("NewFallbackEmbedder() accepted a link serving another model")
+	}
+
+	otherSize := &chainLink{mockEmbedder: newMockEmbedder(8), id: "ollama:nomic-embed-text"}
+	if _, err := NewFallbackEmbedder(primary, otherSize); err == nil {
+		t.Error("NewFallbackEmbedder() accepted a link with other dimensions")
+	}
+}
@@ -0,0 +1,637 @@
+package embedding
+
+import (
+	"container/heap"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"codetect/internal/config"
+)
+
+// HNSWFileName is the default name for the persisted HNSW graph.
+const HNSWFileName = "hnsw.bin"
+
+// hnswFileVersion is bumped whenever the on-disk format changes.
+const hnswFileVersion = 1
+
+// ErrDimensionMismatch is returned when a vector's length doesn't match the index.
+var ErrDimensionMismatch = errors.New("embedding dimensions do not match index")
+
+// HNSWIndex implements VectorIndex with an in-process HNSW graph.
+// It gives SQLite, which has no native vector index without sqlite-vec,
+// sub-linear search over cached embeddings. The graph is kept in memory,
+// updated incrementally, and persisted with Save.
+//
+// Deleted vectors are tombstoned so the graph stays connected; they are
+// skipped in results and compacted away once they outnumber live vectors.
+type HNSWIndex struct {
+	cfg        config.HNSWConfig
+	dimensions int
+
+	mu       sync.RWMutex
+	nodes    []hnswNode
+	ids      map[string]uint32 // Content hash -> node id
+	entry    int               // Entry point node id, -1 when empty
+	maxLevel int
+	deleted  int
+	rng      *rand.Rand
+
+	// repoHashes resolves a repo to the content hashes it references,
+	// used to filter searches to a set of repos
+	repoHashes func(repoRoot string) ([]string, error)
+}
+
+// hnswNode is one vector in the graph. Exported fields are persisted.
+type hnswNode struct {
+	Hash      string
+	Vector    []float32  // Normalized when using cosine distance
+	Neighbors [][]uint32 // Neighbor ids per layer, 0 is the densest
+	Deleted   bool
+}
+
+// hnswFile is the persisted form of an HNSWIndex.
+type hnswFile struct {
+	Version        int
+	Dimensions     int
+	M              int
+	EfConstruction int
+	DistanceMetric string
+	Entry          int
+	MaxLevel       int
+	Nodes          []hnswNode
+}
+
+// NewHNSWIndex creates an empty HNSW index for vectors of the given size.
+func NewHNSWIndex(dimensions int, cfg config.HNSWConfig) *HNSWIndex {
+	if cfg.M < 2 {
+		cfg.M = config.DefaultHNSWConfig().M
+	}
+	if cfg.EfConstruction < cfg.M {
+		cfg.EfConstruction = config.DefaultHNSWConfig().EfConstruction
+	}
+	if cfg.EfSearch < 1 {
+		cfg.EfSearch = config.DefaultHNSWConfig().EfSearch
+	}
+	if cfg.DistanceMetric == "" {
+		cfg.DistanceMetric = "cosine"
+	}
+
+	return &HNSWIndex{
+		cfg:        cfg,
+		dimensions: dimensions,
+		ids:        make(map[string]uint32),
+		entry:      -1,
+		rng:        rand.New(rand.NewSource(1)),
+	}
+}
+
+// LoadHNSWIndex reads a graph saved with Save. Query-time settings such as
+// EfSearch come from cfg; build settings come from the file.
+// Returns nil, nil if the file doesn't exist.
+func LoadHNSWIndex(path string, cfg config.HNSWConfig) (*HNSWIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open HNSW file: %w", err)
+	}
+	defer f.Close()
+
+	var data hnswFile
+	if err := gob.NewDecoder(f).Decode(&data); err != nil {
+		return nil, fmt.Errorf("decode HNSW file: %w", err)
+	}
+	if data.Version != hnswFileVersion {
+		return nil, fmt.Errorf("unsupported HNSW file version %d", data.Version)
+	}
+
+	cfg.M = data.M
+	cfg.EfConstruction = data.EfConstruction
+	cfg.DistanceMetric = data.DistanceMetric
+
+	h := NewHNSWIndex(data.Dimensions, cfg)
+	h.nodes = data.Nodes
+	h.entry = data.Entry
+	h.maxLevel = data.MaxLevel
+	for i, n := range h.nodes {
+		h.ids[n.Hash] = uint32(i)
+		if n.Deleted {
+			h.deleted++
+		}
+	}
+	return h, nil
+}
+
+// Save persists the graph to path.
+// The file is written atomically using a temp file + rename.
+func (h *HNSWIndex) Save(path string) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	data := hnswFile{
+		Version:        hnswFileVersion,
+		Dimensions:     h.dimensions,
+		M:              h.cfg.M,
+		EfConstruction: h.cfg.EfConstruction,
+		DistanceMetric: h.cfg.DistanceMetric,
+		Entry:          h.entry,
+		MaxLevel:       h.maxLevel,
+		Nodes:          h.nodes,
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create data dir: %w", err)
+	}
+
+	tempPath := path + ".tmp"
+	f, err := os.Create(tempPath)
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	if err := gob.NewEncoder(f).Encode(&data); err != nil {
+		f.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("encode HNSW graph: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("write temp file: %w", err)
+	}
+
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath) // Clean up on failure
+		return fmt.Errorf("rename temp file: %w", err)
+	}
+	return nil
+}
+
+// SetRepoHashes sets how SearchWithFilter resolves a repo to its content
+// hashes, typically LocationStore.GetHashesForRepo.
+func (h *HNSWIndex) SetRepoHashes(fn func(repoRoot string) ([]string, error)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.repoHashes = fn
+}
+
+// Dimensions returns the vector size the index accepts.
+func (h *HNSWIndex) Dimensions() int {
+	return h.dimensions
+}
+
+// Contains reports whether a live vector is indexed for the hash.
+func (h *HNSWIndex) Contains(contentHash string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	id, ok := h.ids[contentHash]
+	return ok && !h.nodes[id].Deleted
+}
+
+// Hashes returns the content hashes of all live vectors.
+func (h *HNSWIndex) Hashes() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	hashes := make([]string, 0, len(h.nodes)-h.deleted)
+	for _, n := range h.nodes {
+		if !n.Deleted {
+			hashes = append(hashes, n.Hash)
+		}
+	}
+	return hashes
+}
+
+// Insert adds an embedding to the graph. Re-inserting a hash is a no-op,
+// since a content hash always maps to the same embedding.
+func (h *HNSWIndex) Insert(ctx context.Context, contentHash string, embedding []float32) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.insertLocked(contentHash, embedding)
+}
+
+// InsertBatch adds multiple embeddings.
+func (h *HNSWIndex) InsertBatch(ctx context.Context, entries map[string][]float32) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Insert in a stable order so rebuilding from the same cache gives the same graph
+	hashes := make([]string, 0, len(entries))
+	for hash := range entries {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+
+	for _, hash := range hashes {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := h.insertLocked(hash, entries[hash]); err != nil {
+			return fmt.Errorf("inserting %s: %w", hash, err)
+		}
+	}
+	return nil
+}
+
+// Search finds the k nearest neighbors to the query vector.
+func (h *HNSWIndex) Search(ctx context.Context, query []float32, k int) ([]VectorResult, error) {
+	return h.search(query, k, nil)
+}
+
+// SearchWithFilter finds the k nearest neighbors among the vectors
+// referenced by the given repos. The filter is applied during the graph
+// search, so a full k is returned whenever the repos have k vectors.
+// Without a SetRepoHashes resolver the filter is ignored.
+func (h *HNSWIndex) SearchWithFilter(ctx context.Context, query []float32, k int, repoRoots []string) ([]VectorResult, error) {
+	h.mu.RLock()
+	resolve := h.repoHashes
+	h.mu.RUnlock()
+
+	if len(repoRoots) == 0 || resolve == nil {
+		return h.search(query, k, nil)
+	}
+
+	allowed := make(map[string]bool)
+	for _, repo := range repoRoots {
+		hashes, err := resolve(repo)
+		if err != nil {
+			return nil, fmt.Errorf("getting hashes for %s: %w", repo, err)
+		}
+		for _, hash := range hashes {
+			allowed[hash] = true
+		}
+	}
+	return h.search(query, k, allowed)
+}
+
+// search finds the k nearest live vectors, restricted to allowed hashes
+// when allowed is non-nil.
+func (h *HNSWIndex) search(query []float32, k int, allowed map[string]bool) ([]VectorResult, error) {
+	if len(query) != h.dimensions {
+		return nil, fmt.Errorf("query has %d dimensions, index has %d: %w", len(query), h.dimensions, ErrDimensionMismatch)
+	}
+	if k <= 0 {
+		return nil, nil
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.entry < 0 {
+		return nil, nil
+	}
+
+	q := h.prepare(query)
+	ef := h.cfg.EfSearch
+	if ef < k {
+		ef = k
+	}
+
+	var candidates []hnswCandidate
+	if allowed != nil && len(allowed) <= 4*ef {
+		// A small filtered set is cheaper to rank exactly than to search for
+		candidates = h.rankExact(q, allowed)
+	} else {
+		accept := func(id uint32) bool {
+			n := &h.nodes[id]
+			return !n.Deleted && (allowed == nil || allowed[n.Hash])
+		}
+		ep := h.greedyDescend(q, uint32(h.entry), h.maxLevel, 0)
+		candidates = h.searchLayer(q, ep, ef, 0, accept)
+	}
+
+	if len(candidates) > k {
+		candidates = candidates[:k]
+	}
+	results := make([]VectorResult, len(candidates))
+	for i, c := range candidates {
+		results[i] = VectorResult{
+			ContentHash: h.nodes[c.id].Hash,
+			Distance:    c.dist,
+			Score:       h.score(c.dist),
+		}
+	}
+	return results, nil
+}
+
+// rankExact scores every live vector in the allowed set, closest first.
+func (h *HNSWIndex) rankExact(q []float32, allowed map[string]bool) []hnswCandidate {
+	candidates := make([]hnswCandidate, 0, len(allowed))
+	for hash := range allowed {
+		id, ok := h.ids[hash]
+		if !ok || h.nodes[id].Deleted {
+			continue
+		}
+		candidates = append(candidates, hnswCandidate{id: id, dist: h.distance(q, h.nodes[id].Vector)})
+	}
+	sortCandidates(candidates)
+	return candidates
+}
+
+// Delete removes an embedding from the graph.
+func (h *HNSWIndex) Delete(ctx context.Context, contentHash string) error {
+	return h.DeleteBatch(ctx, []string{contentHash})
+}
+
+// DeleteBatch removes multiple embeddings, compacting the graph once
+// tombstones outnumber live vectors.
+func (h *HNSWIndex) DeleteBatch(ctx context.Context, contentHashes []string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, hash := range contentHashes {
+		id, ok := h.ids[hash]
+		if !ok || h.nodes[id].Deleted {
+			continue
+		}
+		h.nodes[id].Deleted = true
+		h.deleted++
+	}
+
+	if h.deleted > 0 && h.deleted*2 > len(h.nodes) {
+		return h.rebuildLocked(ctx)
+	}
+	return nil
+}
+
+// Rebuild recreates the graph from its live vectors, dropping tombstones.
+func (h *HNSWIndex) Rebuild(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.rebuildLocked(ctx)
+}
+
+// IsNative returns true (this is a real HNSW graph, not brute force).
+func (h *HNSWIndex) IsNative() bool {
+	return true
+}
+
+// Count returns the number of live vectors in the index.
+func (h *HNSWIndex) Count(ctx context.Context) (int, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.nodes) - h.deleted, nil
+}
+
+// rebuildLocked re-inserts every live vector into a fresh graph.
+func (h *HNSWIndex) rebuildLocked(ctx context.Context) error {
+	old := h.nodes
+	h.nodes = nil
+	h.ids = make(map[string]uint32, len(old)-h.deleted)
+	h.entry = -1
+	h.maxLevel = 0
+	h.deleted = 0
+
+	for _, n := range old {
+		if n.Deleted {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// Vectors are already prepared, so insert them directly
+		h.insertPrepared(n.Hash, n.Vector)
+	}
+	return nil
+}
+
+// insertLocked validates and prepares a vector, then adds it to the graph.
+func (h *HNSWIndex) insertLocked(contentHash string, embedding []float32) error {
+	if len(embedding) != h.dimensions {
+		return fmt.Errorf("vector has %d dimensions, index has %d: %w", len(embedding), h.dimensions, ErrDimensionMismatch)
+	}
+	if id, ok := h.ids[contentHash]; ok {
+		if h.nodes[id].Deleted {
+			h.nodes[id].Deleted = false
+			h.deleted--
+		}
+		return nil
+	}
+	h.insertPrepared(contentHash, h.prepare(embedding))
+	return nil
+}
+
+// insertPrepared links a new node into every layer up to its random level.
+func (h *HNSWIndex) insertPrepared(contentHash string, vec []float32) {
+	level := h.randomLevel()
+	id := uint32(len(h.nodes))
+	h.nodes = append(h.nodes, hnswNode{
+		Hash:      contentHash,
+		Vector:    vec,
+		Neighbors: make([][]uint32, level+1),
+	})
+	h.ids[contentHash] = id
+
+	if h.entry < 0 {
+		h.entry = int(id)
+		h.maxLevel = level
+		return
+	}
+
+	ep := h.greedyDescend(vec, uint32(h.entry), h.maxLevel, level)
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		candidates := h.searchLayer(vec, ep, h.cfg.EfConstruction, l, nil)
+		neighbors := h.selectNeighbors(candidates, h.maxConnections(l))
+
+		h.nodes[id].Neighbors[l] = neighbors
+		for _, n := range neighbors {
+			h.link(n, id, l)
+		}
+		ep = candidates[0].id
+	}
+
+	if level > h.maxLevel {
+		h.entry = int(id)
+		h.maxLevel = level
+	}
+}
+
+// link adds a directed edge from -> to on a layer, pruning from's
+// neighbor list back to the closest maxConnections if it overflows.
+func (h *HNSWIndex) link(from, to uint32, layer int) {
+	neighbors := append(h.nodes[from].Neighbors[layer], to)
+	limit := h.maxConnections(layer)
+	if len(neighbors) > limit {
+		base := h.nodes[from].Vector
+		candidates := make([]hnswCandidate, len(neighbors))
+		for i, n := range neighbors {
+			candidates[i] = hnswCandidate{id: n, dist: h.distance(base, h.nodes[n].Vector)}
+		}
+		sortCandidates(candidates)
+		neighbors = h.selectNeighbors(candidates, limit)
+	}
+	h.nodes[from].Neighbors[layer] = neighbors
+}
+
+// greedyDescend walks from the entry point down to stopLevel+1, moving to
+// the closest neighbor on each layer, and returns the best node found.
+func (h *HNSWIndex) greedyDescend(q []float32, ep uint32, fromLevel, stopLevel int) uint32 {
+	best := h.distance(q, h.nodes[ep].Vector)
+	for l := fromLevel; l > stopLevel; l-- {
+		for changed := true; changed; {
+			changed = false
+			for _, n := range h.neighborsAt(ep, l) {
+				if d := h.distance(q, h.nodes[n].Vector); d < best {
+					best, ep, changed = d, n, true
+				}
+			}
+		}
+	}
+	return ep
+}
+
+// searchLayer runs a beam search of width ef on one layer, returning
+// candidates sorted closest first. Nodes rejected by accept are still
+// traversed, keeping the graph connected, but never returned; a nil
+// accept returns every node.
+func (h *HNSWIndex) searchLayer(q []float32, ep uint32, ef, layer int, accept func(uint32) bool) []hnswCandidate {
+	visited := map[uint32]bool{ep: true}
+	start := hnswCandidate{id: ep, dist: h.distance(q, h.nodes[ep].Vector)}
+
+	frontier := &candidateHeap{items: []hnswCandidate{start}}
+	found := &candidateHeap{max: true}
+	if accept == nil || accept(ep) {
+		found.items = append(found.items, start)
+	}
+
+	for frontier.Len() > 0 {
+		c := heap.Pop(frontier).(hnswCandidate)
+		if found.Len() >= ef && c.dist > found.items[0].dist {
+			break
+		}
+
+		for _, n := range h.neighborsAt(c.id, layer) {
+			if visited[n] {
+				continue
+			}
+			visited[n] = true
+
+			d := h.distance(q, h.nodes[n].Vector)
+			if found.Len() < ef || d < found.items[0].dist {
+				heap.Push(frontier, hnswCandidate{id: n, dist: d})
+				if accept == nil || accept(n) {
+					heap.Push(found, hnswCandidate{id: n, dist: d})
+					if found.Len() > ef {
+						heap.Pop(found)
+					}
+				}
+			}
+		}
+	}
+
+	results := found.items
+	sortCandidates(results)
+	return results
+}
+
+// selectNeighbors keeps the closest m candidates (which must be sorted).
+func (h *HNSWIndex) selectNeighbors(candidates []hnswCandidate, m int) []uint32 {
+	if len(candidates) > m {
+		candidates = candidates[:m]
+	}
+	ids := make([]uint32, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.id
+	}
+	return ids
+}
+
+// neighborsAt returns a node's neighbors on a layer it may not reach.
+func (h *HNSWIndex) neighborsAt(id uint32, layer int) []uint32 {
+	if layer >= len(h.nodes[id].Neighbors) {
+		return nil
+	}
+	return h.nodes[id].Neighbors[layer]
+}
+
+// maxConnections is M on upper layers and 2*M on layer 0.
+func (h *HNSWIndex) maxConnections(layer int) int {
+	if layer == 0 {
+		return 2 * h.cfg.M
+	}
+	return h.cfg.M
+}
+
+// randomLevel draws a node level from the exponential distribution
+// with normalization factor 1/ln(M).
+func (h *HNSWIndex) randomLevel() int {
+	mult := 1 / math.Log(float64(h.cfg.M))
+	return int(-math.Log(1-h.rng.Float64()) * mult)
+}
+
+// prepare copies a vector into the form stored in the graph.
+func (h *HNSWIndex) prepare(v []float32) []float32 {
+	if h.cfg.DistanceMetric == "cosine" {
+		return Normalize(v)
+	}
+	out := make([]float32, len(v))
+	copy(out, v)
+	return out
+}
+
+// distance returns the dissimilarity between two prepared vectors.
+func (h *HNSWIndex) distance(a, b []float32) float32 {
+	switch h.cfg.DistanceMetric {
+	case "euclidean":
+		return EuclideanDistance(a, b)
+	case "dot_product":
+		return -DotProduct(a, b)
+	default:
+		return 1 - DotProduct(a, b)
+	}
+}
+
+// score converts a distance back to a similarity (higher is better).
+func (h *HNSWIndex) score(dist float32) float32 {
+	switch h.cfg.DistanceMetric {
+	case "euclidean":
+		return 1 / (1 + dist)
+	case "dot_product":
+		return -dist
+	default:
+		return 1 - dist
+	}
+}
+
+// hnswCandidate is a node and its distance from the current query.
+type hnswCandidate struct {
+	id   uint32
+	dist float32
+}
+
+// candidateHeap is a min-heap by distance, or a max-heap when max is set.
+type candidateHeap struct {
+	items []hnswCandidate
+	max   bool
+}
+
+func (c *candidateHeap) Len() int { return len(c.items) }
+func (c *candidateHeap) Less(i, j int) bool {
+	if c.max {
+		return c.items[i].dist > c.items[j].dist
+	}
+	return c.items[i].dist < c.items[j].dist
+}
+func (c *candidateHeap) Swap(i, j int) { c.items[i], c.items[j] = c.items[j], c.items[i] }
+func (c *candidateHeap) Push(x any)    { c.items = append(c.items, x.(hnswCandidate)) }
+func (c *candidateHeap) Pop() any {
+	last := c.items[len(c.items)-1]
+	c.items = c.items[:len(c.items)-1]
+	return last
+}
+
+// sortCandidates orders candidates closest first, breaking ties by id.
+func sortCandidates(candidates []hnswCandidate) {
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].dist != candidates[j].dist {
+			return candidates[i].dist < candidates[j].dist
+		}
+		return candidates[i].id < candidates[j].id
+	})
+}
@@ -0,0 +1,219 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"codetect/internal/config"
+)
+
+func randomVectors(n, dims int, seed int64) map[string][]float32 {
+	rng := rand.New(rand.NewSource(seed))
+	vectors := make(map[string][]float32, n)
+	for i := 0; i < n; i++ {
+		v := make([]float32, dims)
+		for j := range v {
+			v[j] = rng.Float32()*2 - 1
+		}
+		vectors[fmt.Sprintf("hash%d", i)] = v
+	}
+	return vectors
+}
+
+func TestHNSWIndex_RecallMatchesBruteForce(t *testing.T) {
+	ctx := context.Background()
+	vectors := randomVectors(1000, 16, 1)
+
+	hnsw := NewHNSWIndex(16, config.DefaultHNSWConfig())
+	brute := NewBruteForceVectorIndex(nil, 16)
+	if err := hnsw.InsertBatch(ctx, vectors); err != nil {
+		t.Fatalf("InsertBatch failed: %v", err)
+	}
+	brute.InsertBatch(ctx, vectors)
+
+	const k = 10
+	var hits, total int
+	for _, query := range randomVectors(50, 16, 2) {
+		want, _ := brute.Search(ctx, query, k)
+		got, err := hnsw.Search(ctx, query, k)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+
+		found := make(map[string]bool)
+		for _, r := range got {
+			found[r.ContentHash] = true
+		}
+		for _, r := range want {
+			if found[r.ContentHash] {
+				hits++
+			}
+		}
+		total += len(want)
+	}
+
+	if recall := float64(hits) / float64(total); recall < 0.9 {
+		t.Errorf("recall@%d = %.2f, want >= 0.90", k, recall)
+	}
+}
+
+func TestHNSWIndex_SearchOrderAndScore(t *testing.T) {
+	ctx := context.Background()
+	idx := NewHNSWIndex(3, config.DefaultHNSWConfig())
+	idx.InsertBatch(ctx, map[string][]float32{
+		"hash1": {1, 0, 0},
+		"hash2": {0, 1, 0},
+		"hash3": {1, 1, 0},
+	})
+
+	results, err := idx.Search(ctx, []float32{1, 0, 0}, 2)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 || results[0].ContentHash != "hash1" || results[1].ContentHash != "hash3" {
+		t.Fatalf("Search = %+v, want hash1 then hash3", results)
+	}
+	if results[0].Score < 0.999 {
+		t.Errorf("exact match score = %f, want ~1", results[0].Score)
+	}
+}
+
+func TestHNSWIndex_SaveAndLoad(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), HNSWFileName)
+
+	idx := NewHNSWIndex(8, config.DefaultHNSWConfig())
+	idx.InsertBatch(ctx, randomVectors(200, 8, 3))
+	idx.Delete(ctx, "hash7")
+	if err := idx.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := LoadHNSWIndex(path, config.DefaultHNSWConfig())
+	if err != nil {
+		t.Fatalf("LoadHNSWIndex failed: %v", err)
+	}
+	if count, _ := loaded.Count(ctx); count != 199 {
+		t.Errorf("loaded Count = %d, want 199", count)
+	}
+	if loaded.Contains("hash7") {
+		t.Error("deleted hash survived the round trip")
+	}
+
+	query := randomVectors(1, 8, 4)["hash0"]
+	want, _ := idx.Search(ctx, query, 5)
+	got, _ := loaded.Search(ctx, query, 5)
+	if len(got) != len(want) {
+		t.Fatalf("loaded Search returned %d results, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].ContentHash != want[i].ContentHash {
+			t.Errorf("result %d = %s, want %s", i, got[i].ContentHash, want[i].ContentHash)
+		}
+	}
+}
+
+func TestLoadHNSWIndex_Missing(t *testing.T) {
+	idx, err := LoadHNSWIndex(filepath.Join(t.TempDir(), HNSWFileName), config.DefaultHNSWConfig())
+	if idx != nil || err != nil {
+		t.Errorf("LoadHNSWIndex() = %v, %v, want nil, nil", idx, err)
+	}
+}
+
+func TestHNSWIndex_DeleteAndCompact(t *testing.T) {
+	ctx := context.Background()
+	idx := NewHNSWIndex(3, config.DefaultHNSWConfig())
+	idx.InsertBatch(ctx, map[string][]float32{
+		"hash1": {1, 0, 0},
+		"hash2": {0, 1, 0},
+		"hash3": {0, 0, 1},
+	})
+
+	if err := idx.Delete(ctx, "hash1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	results, _ := idx.Search(ctx, []float32{1, 0, 0}, 3)
+	for _, r := range results {
+		if r.ContentHash == "hash1" {
+			t.Error("deleted hash returned from Search")
+		}
+	}
+
+	// A second deletion leaves more tombstones than live vectors
+	idx.Delete(ctx, "hash2")
+	if len(idx.nodes) != 1 {
+		t.Errorf("graph has %d nodes after compaction, want 1", len(idx.nodes))
+	}
+	if count, _ := idx.Count(ctx); count != 1 {
+		t.Errorf("Count = %d, want 1", count)
+	}
+
+	// Re-inserting works after compaction
+	idx.Insert(ctx, "hash1", []float32{1, 0, 0})
+	results, _ = idx.Search(ctx, []float32{1, 0, 0}, 1)
+	if len(results) != 1 || results[0].ContentHash != "hash1" {
+		t.Errorf("Search after re-insert = %+v, want hash1", results)
+	}
+}
+
+func TestHNSWIndex_DimensionMismatch(t *testing.T) {
+	ctx := context.Background()
+	idx := NewHNSWIndex(3, config.DefaultHNSWConfig())
+
+	if err := idx.Insert(ctx, "hash1", []float32{1, 0}); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("Insert() error = %v, want ErrDimensionMismatch", err)
+	}
+	if _, err := idx.Search(ctx, []float32{1, 0}, 1); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("Search() error = %v, want ErrDimensionMismatch", err)
+	}
+}
+
+func TestHNSWIndex_SearchWithFilter(t *testing.T) {
+	ctx := context.Background()
+	vectors := randomVectors(1000, 16, 5)
+
+	// Every third vector belongs to repo-a; repo-b holds just a handful
+	repos := map[string][]string{}
+	for i := 0; i < len(vectors); i++ {
+		hash := fmt.Sprintf("hash%d", i)
+		if i%3 == 0 {
+			repos["repo-a"] = append(repos["repo-a"], hash)
+		} else if i < 20 {
+			repos["repo-b"] = append(repos["repo-b"], hash)
+		}
+	}
+
+	idx := NewHNSWIndex(16, config.DefaultHNSWConfig())
+	idx.InsertBatch(ctx, vectors)
+	idx.SetRepoHashes(func(repoRoot string) ([]string, error) {
+		return repos[repoRoot], nil
+	})
+
+	const k = 20
+	for repo, hashes := range repos {
+		inRepo := make(map[string]bool)
+		for _, hash := range hashes {
+			inRepo[hash] = true
+		}
+		want := min(k, len(hashes))
+
+		for _, query := range randomVectors(10, 16, 6) {
+			results, err := idx.SearchWithFilter(ctx, query, k, []string{repo})
+			if err != nil {
+				t.Fatalf("SearchWithFilter failed: %v", err)
+			}
+			if len(results) != want {
+				t.Errorf("%s: got %d results, want a full %d", repo, len(results), want)
+			}
+			for _, r := range results {
+				if !inRepo[r.ContentHash] {
+					t.Errorf("%s: result %s is from another repo", repo, r.ContentHash)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package embedding
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// flightTimeout bounds a coalesced call, which no single caller's context
+// governs
+const flightTimeout = 2 * time.Minute
+
+// flightGroup coalesces concurrent calls that share a key, singleflight
+// style: the first caller starts fn and later callers wait for its result
+// instead of repeating the work. Nothing is kept once the call returns;
+// sequential calls each run fn.
+type flightGroup[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall[T]
+}
+
+// flightCall is an in-flight or completed call
+type flightCall[T any] struct {
+	done chan struct{}
+	dups int // Callers that joined instead of calling fn
+	val  T
+	err  error
+}
+
+// do runs fn once for all concurrent callers with the same key. fn runs
+// detached from any caller's cancellation, under flightTimeout, so one
+// caller giving up doesn't fail the others; each caller stops waiting when
+// its own ctx is done. shared reports whether the result went to more than
+// one caller, in which case it must be treated as read-only.
+func (g *flightGroup[T]) do(ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (val T, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall[T])
+	}
+	c, ok := g.calls[key]
+	if ok {
+		c.dups++
+	} else {
+		c = &flightCall[T]{done: make(chan struct{})}
+		g.calls[key] = c
+		go g.run(ctx, key, c, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		return val, ctx.Err(), false
+	}
+	g.mu.Lock()
+	shared = c.dups > 0
+	g.mu.Unlock()
+	return c.val, c.err, shared
+}
+
+// run makes the call for c and releases its waiters
+func (g *flightGroup[T]) run(ctx context.Context, key string, c *flightCall[T], fn func(ctx context.Context) (T, error)) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), flightTimeout)
+	defer cancel()
+	c.val, c.err = fn(ctx)
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(c.done)
+}
+
+// Searches are coalesced across searcher instances, since MCP tools open a
+// new searcher per call
+var (
+	semanticFlights flightGroup[*SemanticSearchResult]
+	v2SearchFlights flightGroup[*V2SearchResponse]
+)
@@ -0,0 +1,126 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlightGroupCoalesces(t *testing.T) {
+	var g flightGroup[*SemanticSearchResult]
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fn := func(context.Context) (*SemanticSearchResult, error) {
+		calls.Add(1)
+		<-release
+		return &SemanticSearchResult{Available: true, Results: []SemanticResult{{Path: "a.go"}}}, nil
+	}
+
+	const callers = 5
+	results := make([]*SemanticSearchResult, callers)
+	shared := make([]bool, callers)
+	var wg sync.WaitGroup
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _, shared[i] = g.do(context.Background(), "key", fn)
+		}()
+	}
+
+	// Hold the first call open until every other caller has joined it
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		g.mu.Lock()
+		c := g.calls["key"]
+		joined := c != nil && c.dups == callers-1
+		g.mu.Unlock()
+		if joined {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("callers never joined the in-flight call")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("fn ran %d times, want 1", n)
+	}
+	for i := range callers {
+		if !shared[i] || results[i] == nil || results[i].Results[0].Path != "a.go" {
+			t.Errorf("caller %d got %+v, shared=%v, want the shared result", i, results[i], shared[i])
+		}
+	}
+
+	// Once the call completes, the next one runs fn again
+	release = make(chan struct{})
+	close(release)
+	if _, _, shared := g.do(context.Background(), "key", fn); shared || calls.Load() != 2 {
+		t.Errorf("sequential call: shared=%v, fn ran %d times, want a fresh call", shared, calls.Load())
+	}
+}
+
+func TestFlightGroupOutlivesLeaderCancel(t *testing.T) {
+	var g flightGroup[*SemanticSearchResult]
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (*SemanticSearchResult, error) {
+		select {
+		case <-release:
+			return &SemanticSearchResult{Available: true}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err, _ := g.do(leaderCtx, "key", fn)
+		leaderErr <- err
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		g.mu.Lock()
+		started := g.calls["key"] != nil
+		g.mu.Unlock()
+		if started {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("leader never started the call")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	follower := make(chan *SemanticSearchResult, 1)
+	go func() {
+		result, _, _ := g.do(context.Background(), "key", fn)
+		follower <- result
+	}()
+
+	// The leader giving up returns its own error without failing the call
+	cancel()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled leader got error %v, want context.Canceled", err)
+	}
+	close(release)
+	if result := <-follower; result == nil || !result.Available {
+		t.Errorf("follower got %+v after the leader cancelled, want the search result", result)
+	}
+}
+
+func TestSemanticSearchResultClone(t *testing.T) {
+	orig := &SemanticSearchResult{Available: true, Results: []SemanticResult{{Path: "a.go"}}}
+	c := orig.clone()
+	c.Results[0].Snippet = "filled in"
+	c.Warning = "changed"
+	if orig.Results[0].Snippet != "" || orig.Warning != "" {
+		t.Errorf("clone shares state with the original: %+v", orig)
+	}
+}
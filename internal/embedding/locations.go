@@ -1,6 +1,7 @@
 package embedding
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"sync"
@@ -22,6 +23,8 @@ type ChunkLocation struct {
 	NodeType    string    `json:"node_type"`    // AST node type (function, class, etc.)
 	NodeName    string    `json:"node_name"`    // Symbol name
 	Language    string    `json:"language"`
+	DocComment  string    `json:"doc_comment,omitempty"` // Leading doc comment/docstring
+	DocHash     string    `json:"doc_hash,omitempty"`    // Cache key of the doc comment embedding
 	CreatedAt   time.Time `json:"created_at"`
 }
 
@@ -66,13 +69,24 @@ func (s *LocationStore) initSchema() error {
 		{Name: "language", Type: db.ColTypeText, Nullable: true},
 		{Name: "created_at", Type: db.ColTypeInteger, Nullable: false},
 	}
+	docColumns := []db.ColumnDef{
+		{Name: "doc_comment", Type: db.ColTypeText, Nullable: true},
+		{Name: "doc_hash", Type: db.ColTypeText, Nullable: true},
+	}
 
 	// Create table
-	createSQL := s.dialect.CreateTableSQL("chunk_locations", columns)
+	createSQL := s.dialect.CreateTableSQL("chunk_locations", append(columns, docColumns...))
 	if _, err := s.database.Exec(createSQL); err != nil {
 		return fmt.Errorf("creating chunk_locations table: %w", err)
 	}
 
+	// Tables created before doc comment support lack these columns
+	for _, col := range docColumns {
+		if _, err := s.schema.EnsureColumn(context.Background(), "chunk_locations", col); err != nil {
+			return fmt.Errorf("adding %s column: %w", col.Name, err)
+		}
+	}
+
 	// Create unique constraint for upserts (repo, path, start, end)
 	idxUnique := s.dialect.CreateIndexSQL("chunk_locations", "idx_chunk_locations_unique",
 		[]string{"repo_root", "path", "start_line", "end_line"}, true)
@@ -116,16 +130,17 @@ func (s *LocationStore) SaveLocation(loc ChunkLocation) error {
 
 	// Use upsert for idempotent saves
 	columns := []string{"repo_root", "path", "start_line", "end_line", "content_hash",
-		"node_type", "node_name", "language", "created_at"}
+		"node_type", "node_name", "language", "doc_comment", "doc_hash", "created_at"}
 	conflictColumns := []string{"repo_root", "path", "start_line", "end_line"}
-	updateColumns := []string{"content_hash", "node_type", "node_name", "language"}
+	updateColumns := []string{"content_hash", "node_type", "node_name", "language", "doc_comment", "doc_hash"}
 
 	upsertSQL := s.dialect.UpsertSQL("chunk_locations", columns, conflictColumns, updateColumns)
 	upsertSQL = s.schema.SubstitutePlaceholders(upsertSQL)
 
 	_, err := s.database.Exec(upsertSQL,
 		loc.RepoRoot, loc.Path, loc.StartLine, loc.EndLine, loc.ContentHash,
-		nullString(loc.NodeType), nullString(loc.NodeName), nullString(loc.Language), now,
+		nullString(loc.NodeType), nullString(loc.NodeName), nullString(loc.Language),
+		nullString(loc.DocComment), nullString(loc.DocHash), now,
 	)
 
 	return err
@@ -147,9 +162,9 @@ func (s *LocationStore) SaveLocationsBatch(locs []ChunkLocation) error {
 	defer tx.Rollback() //nolint:errcheck
 
 	columns := []string{"repo_root", "path", "start_line", "end_line", "content_hash",
-		"node_type", "node_name", "language", "created_at"}
+		"node_type", "node_name", "language", "doc_comment", "doc_hash", "created_at"}
 	conflictColumns := []string{"repo_root", "path", "start_line", "end_line"}
-	updateColumns := []string{"content_hash", "node_type", "node_name", "language"}
+	updateColumns := []string{"content_hash", "node_type", "node_name", "language", "doc_comment", "doc_hash"}
 
 	upsertSQL := s.dialect.UpsertSQL("chunk_locations", columns, conflictColumns, updateColumns)
 	upsertSQL = s.schema.SubstitutePlaceholders(upsertSQL)
@@ -164,7 +179,8 @@ func (s *LocationStore) SaveLocationsBatch(locs []ChunkLocation) error {
 	for _, loc := range locs {
 		_, err := stmt.Exec(
 			loc.RepoRoot, loc.Path, loc.StartLine, loc.EndLine, loc.ContentHash,
-			nullString(loc.NodeType), nullString(loc.NodeName), nullString(loc.Language), now,
+			nullString(loc.NodeType), nullString(loc.NodeName), nullString(loc.Language),
+			nullString(loc.DocComment), nullString(loc.DocHash), now,
 		)
 		if err != nil {
 			return fmt.Errorf("inserting location for %s:%d-%d: %w",
@@ -182,7 +198,7 @@ func (s *LocationStore) GetByPath(repoRoot, path string) ([]ChunkLocation, error
 
 	query := s.schema.SubstitutePlaceholders(`
 		SELECT id, repo_root, path, start_line, end_line, content_hash,
-		       node_type, node_name, language, created_at
+		       node_type, node_name, language, doc_comment, doc_hash, created_at
 		FROM chunk_locations
 		WHERE repo_root = ? AND path = ?
 		ORDER BY start_line
@@ -204,7 +220,7 @@ func (s *LocationStore) GetByRepo(repoRoot string) ([]ChunkLocation, error) {
 
 	query := s.schema.SubstitutePlaceholders(`
 		SELECT id, repo_root, path, start_line, end_line, content_hash,
-		       node_type, node_name, language, created_at
+		       node_type, node_name, language, doc_comment, doc_hash, created_at
 		FROM chunk_locations
 		WHERE repo_root = ?
 		ORDER BY path, start_line
@@ -227,7 +243,7 @@ func (s *LocationStore) GetByHash(contentHash string) ([]ChunkLocation, error) {
 
 	query := s.schema.SubstitutePlaceholders(`
 		SELECT id, repo_root, path, start_line, end_line, content_hash,
-		       node_type, node_name, language, created_at
+		       node_type, node_name, language, doc_comment, doc_hash, created_at
 		FROM chunk_locations
 		WHERE content_hash = ?
 		ORDER BY repo_root, path, start_line
@@ -255,6 +271,62 @@ func (s *LocationStore) DeleteByPath(repoRoot, path string) error {
 	return err
 }
 
+// DeleteStaleByPath removes a file's locations whose line range is not
+// among keep, the file's chunks as just indexed. Called after re-indexing
+// a file, so chunks removed from it or moved elsewhere don't linger while a
+// failed re-index leaves the old locations searchable. Returns the number
+// removed.
+func (s *LocationStore) DeleteStaleByPath(repoRoot, path string, keep []ChunkLocation) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	live := make(map[[2]int]bool, len(keep))
+	for _, loc := range keep {
+		live[[2]int{loc.StartLine, loc.EndLine}] = true
+	}
+
+	tx, err := s.database.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	query := s.schema.SubstitutePlaceholders(
+		"SELECT id, start_line, end_line FROM chunk_locations WHERE repo_root = ? AND path = ?",
+	)
+	rows, err := tx.Query(query, repoRoot, path)
+	if err != nil {
+		return 0, fmt.Errorf("querying locations: %w", err)
+	}
+	var stale []int64
+	for rows.Next() {
+		var id int64
+		var start, end int
+		if err := rows.Scan(&id, &start, &end); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scanning location: %w", err)
+		}
+		if !live[[2]int{start, end}] {
+			stale = append(stale, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	del := s.schema.SubstitutePlaceholders("DELETE FROM chunk_locations WHERE id = ?")
+	for _, id := range stale {
+		if _, err := tx.Exec(del, id); err != nil {
+			return 0, fmt.Errorf("deleting location %d: %w", id, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(stale), nil
+}
+
 // DeleteByRepo removes all locations for a repository.
 func (s *LocationStore) DeleteByRepo(repoRoot string) error {
 	s.mu.Lock()
@@ -323,6 +395,56 @@ func (s *LocationStore) GetHashesForPath(repoRoot, path string) ([]string, error
 	return hashes, rows.Err()
 }
 
+// GetDocHashesForRepo returns all unique doc comment hashes used in a repo.
+func (s *LocationStore) GetDocHashesForRepo(repoRoot string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := s.schema.SubstitutePlaceholders(`
+		SELECT DISTINCT doc_hash FROM chunk_locations
+		WHERE repo_root = ? AND doc_hash IS NOT NULL
+	`)
+
+	rows, err := s.database.Query(query, repoRoot)
+	if err != nil {
+		return nil, fmt.Errorf("querying doc hashes: %w", err)
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			continue
+		}
+		hashes = append(hashes, hash)
+	}
+
+	return hashes, rows.Err()
+}
+
+// GetByDocHash retrieves all locations whose doc comment has the given hash.
+func (s *LocationStore) GetByDocHash(docHash string) ([]ChunkLocation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := s.schema.SubstitutePlaceholders(`
+		SELECT id, repo_root, path, start_line, end_line, content_hash,
+		       node_type, node_name, language, doc_comment, doc_hash, created_at
+		FROM chunk_locations
+		WHERE doc_hash = ?
+		ORDER BY repo_root, path, start_line
+	`)
+
+	rows, err := s.database.Query(query, docHash)
+	if err != nil {
+		return nil, fmt.Errorf("querying locations: %w", err)
+	}
+	defer rows.Close()
+
+	return scanLocations(rows)
+}
+
 // CountByRepo returns the number of chunk locations in a repository.
 func (s *LocationStore) CountByRepo(repoRoot string) (int, error) {
 	s.mu.RLock()
@@ -361,8 +483,9 @@ func (s *LocationStore) GetOrphanedHashes(allCacheHashes []string) ([]string, er
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	// Get all referenced hashes from chunk_locations
-	referencedQuery := "SELECT DISTINCT content_hash FROM chunk_locations"
+	// Get all referenced hashes (code and doc comments) from chunk_locations
+	referencedQuery := `SELECT content_hash FROM chunk_locations
+		UNION SELECT doc_hash FROM chunk_locations WHERE doc_hash IS NOT NULL`
 	rows, err := s.database.Query(referencedQuery)
 	if err != nil {
 		return nil, fmt.Errorf("querying referenced hashes: %w", err)
@@ -393,6 +516,46 @@ func (s *LocationStore) GetOrphanedHashes(allCacheHashes []string) ([]string, er
 	return orphaned, nil
 }
 
+// GetSharedHashes returns those of hashes that locations outside repoRoot
+// also reference, as code or doc comment. Their cache entries are still in
+// use elsewhere when this repo stops needing them.
+func (s *LocationStore) GetSharedHashes(repoRoot string, hashes []string) ([]string, error) {
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := s.schema.SubstitutePlaceholders(`SELECT content_hash FROM chunk_locations WHERE repo_root <> ?
+		UNION SELECT doc_hash FROM chunk_locations WHERE repo_root <> ? AND doc_hash IS NOT NULL`)
+	rows, err := s.database.Query(query, repoRoot, repoRoot)
+	if err != nil {
+		return nil, fmt.Errorf("querying referenced hashes: %w", err)
+	}
+	defer rows.Close()
+
+	elsewhere := make(map[string]bool)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			continue
+		}
+		elsewhere[hash] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var shared []string
+	for _, hash := range hashes {
+		if elsewhere[hash] {
+			shared = append(shared, hash)
+		}
+	}
+	return shared, nil
+}
+
 // ListPaths returns all unique file paths in a repository.
 func (s *LocationStore) ListPaths(repoRoot string) ([]string, error) {
 	s.mu.RLock()
@@ -429,7 +592,7 @@ func (s *LocationStore) GetLocationsBySymbol(repoRoot, nodeName string) ([]Chunk
 
 	query := s.schema.SubstitutePlaceholders(`
 		SELECT id, repo_root, path, start_line, end_line, content_hash,
-		       node_type, node_name, language, created_at
+		       node_type, node_name, language, doc_comment, doc_hash, created_at
 		FROM chunk_locations
 		WHERE repo_root = ? AND node_name = ?
 		ORDER BY path, start_line
@@ -451,7 +614,7 @@ func (s *LocationStore) GetLocationsByType(repoRoot, nodeType string) ([]ChunkLo
 
 	query := s.schema.SubstitutePlaceholders(`
 		SELECT id, repo_root, path, start_line, end_line, content_hash,
-		       node_type, node_name, language, created_at
+		       node_type, node_name, language, doc_comment, doc_hash, created_at
 		FROM chunk_locations
 		WHERE repo_root = ? AND node_type = ?
 		ORDER BY path, start_line
@@ -473,11 +636,11 @@ func scanLocations(rows db.Rows) ([]ChunkLocation, error) {
 	for rows.Next() {
 		var loc ChunkLocation
 		var createdAt int64
-		var nodeType, nodeName, language sql.NullString
+		var nodeType, nodeName, language, docComment, docHash sql.NullString
 
 		err := rows.Scan(
 			&loc.ID, &loc.RepoRoot, &loc.Path, &loc.StartLine, &loc.EndLine,
-			&loc.ContentHash, &nodeType, &nodeName, &language, &createdAt,
+			&loc.ContentHash, &nodeType, &nodeName, &language, &docComment, &docHash, &createdAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("scanning location: %w", err)
@@ -486,6 +649,8 @@ func scanLocations(rows db.Rows) ([]ChunkLocation, error) {
 		loc.NodeType = nodeType.String
 		loc.NodeName = nodeName.String
 		loc.Language = language.String
+		loc.DocComment = docComment.String
+		loc.DocHash = docHash.String
 		loc.CreatedAt = time.Unix(createdAt, 0)
 
 		locations = append(locations, loc)
@@ -1,6 +1,7 @@
 package embedding
 
 import (
+	"slices"
 	"testing"
 	"time"
 
@@ -244,6 +245,33 @@ func TestDeleteByPath(t *testing.T) {
 	}
 }
 
+func TestDeleteStaleByPath(t *testing.T) {
+	store := setupTestLocationStore(t)
+
+	store.SaveLocationsBatch([]ChunkLocation{
+		{RepoRoot: "/project", Path: "a.go", StartLine: 1, EndLine: 10, ContentHash: "h1"},
+		{RepoRoot: "/project", Path: "a.go", StartLine: 15, EndLine: 25, ContentHash: "h2"},
+		{RepoRoot: "/project", Path: "b.go", StartLine: 15, EndLine: 25, ContentHash: "h3"},
+	})
+
+	keep := []ChunkLocation{{StartLine: 1, EndLine: 10}}
+	removed, err := store.DeleteStaleByPath("/project", "a.go", keep)
+	if err != nil {
+		t.Fatalf("DeleteStaleByPath failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("expected 1 stale location removed, got %d", removed)
+	}
+
+	aLocs, _ := store.GetByPath("/project", "a.go")
+	if len(aLocs) != 1 || aLocs[0].ContentHash != "h1" {
+		t.Errorf("expected only h1 left in a.go, got %+v", aLocs)
+	}
+	if n, _ := store.CountByPath("/project", "b.go"); n != 1 {
+		t.Errorf("expected b.go untouched, got %d locations", n)
+	}
+}
+
 func TestDeleteByRepo(t *testing.T) {
 	store := setupTestLocationStore(t)
 
@@ -303,6 +331,74 @@ func TestGetHashesForRepo(t *testing.T) {
 	}
 }
 
+func TestDocCommentLocations(t *testing.T) {
+	store := setupTestLocationStore(t)
+
+	locations := []ChunkLocation{
+		{RepoRoot: "/project", Path: "a.go", StartLine: 1, EndLine: 10, ContentHash: "hash1", DocComment: "Does a.", DocHash: "doc1"},
+		{RepoRoot: "/project", Path: "b.go", StartLine: 1, EndLine: 10, ContentHash: "hash2"},
+		{RepoRoot: "/other", Path: "c.go", StartLine: 1, EndLine: 10, ContentHash: "hash3", DocHash: "doc3"},
+	}
+	if err := store.SaveLocationsBatch(locations); err != nil {
+		t.Fatalf("SaveLocationsBatch failed: %v", err)
+	}
+
+	hashes, err := store.GetDocHashesForRepo("/project")
+	if err != nil {
+		t.Fatalf("GetDocHashesForRepo failed: %v", err)
+	}
+	if len(hashes) != 1 || hashes[0] != "doc1" {
+		t.Errorf("GetDocHashesForRepo = %v, want [doc1]", hashes)
+	}
+
+	locs, err := store.GetByDocHash("doc1")
+	if err != nil {
+		t.Fatalf("GetByDocHash failed: %v", err)
+	}
+	if len(locs) != 1 || locs[0].Path != "a.go" || locs[0].DocComment != "Does a." {
+		t.Errorf("GetByDocHash = %+v, want a.go with doc comment", locs)
+	}
+
+	// Doc embeddings are referenced and must not be reported as orphans
+	orphaned, err := store.GetOrphanedHashes([]string{"hash1", "doc1", "doc3", "unused"})
+	if err != nil {
+		t.Fatalf("GetOrphanedHashes failed: %v", err)
+	}
+	if len(orphaned) != 1 || orphaned[0] != "unused" {
+		t.Errorf("GetOrphanedHashes = %v, want [unused]", orphaned)
+	}
+}
+
+func TestLocationStoreAddsDocColumns(t *testing.T) {
+	cfg := db.DefaultConfig(":memory:")
+	database, err := db.Open(cfg)
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	defer database.Close()
+
+	// Table as created before doc comment support
+	_, err = database.Exec(`CREATE TABLE chunk_locations (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		repo_root TEXT NOT NULL, path TEXT NOT NULL,
+		start_line INTEGER NOT NULL, end_line INTEGER NOT NULL,
+		content_hash TEXT NOT NULL, node_type TEXT, node_name TEXT,
+		language TEXT, created_at INTEGER NOT NULL)`)
+	if err != nil {
+		t.Fatalf("creating legacy table: %v", err)
+	}
+
+	store, err := NewLocationStore(database, cfg.Dialect())
+	if err != nil {
+		t.Fatalf("NewLocationStore failed: %v", err)
+	}
+
+	loc := ChunkLocation{RepoRoot: "/project", Path: "a.go", StartLine: 1, EndLine: 2, ContentHash: "h", DocHash: "d"}
+	if err := store.SaveLocation(loc); err != nil {
+		t.Fatalf("SaveLocation on upgraded table failed: %v", err)
+	}
+}
+
 func TestGetHashesForPath(t *testing.T) {
 	store := setupTestLocationStore(t)
 
@@ -491,6 +587,28 @@ func TestGetOrphanedHashes(t *testing.T) {
 	}
 }
 
+func TestGetSharedHashes(t *testing.T) {
+	store := setupTestLocationStore(t)
+
+	locations := []ChunkLocation{
+		{RepoRoot: "/project", Path: "a.go", StartLine: 1, EndLine: 10, ContentHash: "own", DocHash: "doc1"},
+		{RepoRoot: "/project", Path: "b.go", StartLine: 1, EndLine: 10, ContentHash: "both"},
+		{RepoRoot: "/other", Path: "b.go", StartLine: 1, EndLine: 10, ContentHash: "both"},
+		{RepoRoot: "/other", Path: "c.go", StartLine: 1, EndLine: 10, ContentHash: "theirs", DocHash: "doc1"},
+	}
+	if err := store.SaveLocationsBatch(locations); err != nil {
+		t.Fatalf("SaveLocationsBatch failed: %v", err)
+	}
+
+	shared, err := store.GetSharedHashes("/project", []string{"own", "both", "doc1", "unused"})
+	if err != nil {
+		t.Fatalf("GetSharedHashes failed: %v", err)
+	}
+	if !slices.Equal(shared, []string{"both", "doc1"}) {
+		t.Errorf("GetSharedHashes = %v, want [both doc1]", shared)
+	}
+}
+
 func TestLocationEmptyBatchOperations(t *testing.T) {
 	store := setupTestLocationStore(t)
 
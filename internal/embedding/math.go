@@ -1,6 +1,7 @@
 package embedding
 
 import (
+	"fmt"
 	"math"
 )
 
@@ -10,22 +11,60 @@ func CosineSimilarity(a, b []float32) float32 {
 	if len(a) != len(b) || len(a) == 0 {
 		return 0
 	}
+	return CosineSimilarityNorms(a, vectorNorm(a), b, vectorNorm(b))
+}
 
-	var dotProduct float64
-	var normA float64
-	var normB float64
-
-	for i := 0; i < len(a); i++ {
-		dotProduct += float64(a[i]) * float64(b[i])
-		normA += float64(a[i]) * float64(a[i])
-		normB += float64(b[i]) * float64(b[i])
+// CosineSimilarityNorms is CosineSimilarity for vectors whose L2 norms are
+// already known, e.g. candidates normed once at load. Only the dot product
+// is computed per pair.
+func CosineSimilarityNorms(a []float32, normA float64, b []float32, normB float64) float32 {
+	if len(a) != len(b) || len(a) == 0 || normA == 0 || normB == 0 {
+		return 0
 	}
+	return finiteScore(float64(dot32(a, b)) / (normA * normB))
+}
 
-	if normA == 0 || normB == 0 {
+// finiteScore converts a similarity to float32, mapping NaN and Inf (from
+// bad vectors stored before they were validated) to 0 so one bad embedding
+// can't break the ranking of a whole result set
+func finiteScore(score float64) float32 {
+	if math.IsNaN(score) || math.IsInf(score, 0) {
 		return 0
 	}
+	return float32(score)
+}
+
+// dot32 computes the dot product of two equal-length vectors. This is the
+// inner loop of every brute-force search, so it is unrolled into eight
+// independent float32 sums: the multiplies pipeline instead of waiting on
+// one accumulator, and staying in float32 avoids a conversion per element.
+// Each sum covers a few hundred terms at most for real embeddings, well
+// within float32 precision for ranking.
+func dot32(a, b []float32) float32 {
+	b = b[:len(a)] // Lets the compiler drop bounds checks on b
+	var s0, s1, s2, s3, s4, s5, s6, s7 float32
+	i := 0
+	for ; i+8 <= len(a); i += 8 {
+		x := a[i : i+8 : i+8]
+		y := b[i : i+8 : i+8]
+		s0 += x[0] * y[0]
+		s1 += x[1] * y[1]
+		s2 += x[2] * y[2]
+		s3 += x[3] * y[3]
+		s4 += x[4] * y[4]
+		s5 += x[5] * y[5]
+		s6 += x[6] * y[6]
+		s7 += x[7] * y[7]
+	}
+	for ; i < len(a); i++ {
+		s0 += a[i] * b[i]
+	}
+	return ((s0 + s1) + (s2 + s3)) + ((s4 + s5) + (s6 + s7))
+}
 
-	return float32(dotProduct / (math.Sqrt(normA) * math.Sqrt(normB)))
+// vectorNorm returns the L2 norm of v in float64
+func vectorNorm(v []float32) float64 {
+	return math.Sqrt(float64(dot32(v, v)))
 }
 
 // DotProduct computes the dot product of two vectors
@@ -84,9 +123,46 @@ type ScoredItem struct {
 	Score float32
 }
 
+// ScoreFunc scores how similar candidate is to query, higher meaning more
+// similar. Searchers default to cosine similarity; other scorers (weighted
+// cosine, angular distance, ...) can be swapped in for experiments.
+type ScoreFunc func(query, candidate []float32) float32
+
 // TopKByCosineSimilarity finds the top-k most similar vectors to query
 // Returns indices and scores sorted by similarity (highest first)
 func TopKByCosineSimilarity(query []float32, vectors [][]float32, k int) []ScoredItem {
+	return TopKByCosineSimilarityNorms(query, vectors, nil, k)
+}
+
+// TopKByCosineSimilarityNorms is TopKByCosineSimilarity for candidates
+// whose L2 norms were computed once up front, norms[i] being the norm of
+// vectors[i]. The query's norm is computed once per call. A nil norms
+// computes them as it goes.
+func TopKByCosineSimilarityNorms(query []float32, vectors [][]float32, norms []float64, k int) []ScoredItem {
+	if k <= 0 || len(vectors) == 0 {
+		return nil
+	}
+
+	queryNorm := vectorNorm(query)
+	similarities := make([]ScoredItem, len(vectors))
+	for i, v := range vectors {
+		var norm float64
+		if norms != nil {
+			norm = norms[i]
+		} else {
+			norm = vectorNorm(v)
+		}
+		similarities[i] = ScoredItem{
+			Index: i,
+			Score: CosineSimilarityNorms(query, queryNorm, v, norm),
+		}
+	}
+	return topKScored(similarities, k)
+}
+
+// TopKByScore finds the top-k vectors scoring highest against query with
+// score. Returns indices and scores sorted by score (highest first)
+func TopKByScore(query []float32, vectors [][]float32, k int, score ScoreFunc) []ScoredItem {
 	if k <= 0 || len(vectors) == 0 {
 		return nil
 	}
@@ -96,10 +172,15 @@ func TopKByCosineSimilarity(query []float32, vectors [][]float32, k int) []Score
 	for i, v := range vectors {
 		similarities[i] = ScoredItem{
 			Index: i,
-			Score: CosineSimilarity(query, v),
+			Score: finiteScore(float64(score(query, v))),
 		}
 	}
+	return topKScored(similarities, k)
+}
 
+// topKScored moves the k highest scores to the front of similarities, in
+// descending order, and returns them
+func topKScored(similarities []ScoredItem, k int) []ScoredItem {
 	// Simple selection sort for top-k (efficient for small k)
 	// For large k or vectors, consider using a heap
 	for i := 0; i < k && i < len(similarities); i++ {
@@ -117,3 +198,32 @@ func TopKByCosineSimilarity(query []float32, vectors [][]float32, k int) []Score
 	}
 	return similarities[:k]
 }
+
+// vectorProblem describes what is wrong with v, or returns "" if it looks
+// like a real embedding
+func vectorProblem(v []float32) string {
+	if len(v) == 0 {
+		return "is empty"
+	}
+	zero := true
+	for _, x := range v {
+		if math.IsNaN(float64(x)) || math.IsInf(float64(x), 0) {
+			return "contains NaN or Inf"
+		}
+		if x != 0 {
+			zero = false
+		}
+	}
+	if zero {
+		return "is all zeros"
+	}
+	return ""
+}
+
+// validateEmbedding returns an error if v isn't fit to store or score
+func validateEmbedding(v []float32) error {
+	if problem := vectorProblem(v); problem != "" {
+		return fmt.Errorf("invalid embedding: vector %s", problem)
+	}
+	return nil
+}
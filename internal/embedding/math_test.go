@@ -2,6 +2,7 @@ package embedding
 
 import (
 	"math"
+	"math/rand"
 	"testing"
 )
 
@@ -54,6 +55,18 @@ func TestCosineSimilarity(t *testing.T) {
 			b:    []float32{1, 2, 3},
 			want: 0.0,
 		},
+		{
+			name: "NaN component returns 0",
+			a:    []float32{float32(math.NaN()), 0, 0},
+			b:    []float32{1, 2, 3},
+			want: 0.0,
+		},
+		{
+			name: "Inf component returns 0",
+			a:    []float32{float32(math.Inf(1)), 1, 0},
+			b:    []float32{1, 2, 3},
+			want: 0.0,
+		},
 	}
 
 	for _, tt := range tests {
@@ -254,3 +267,120 @@ func TestTopKByCosineSimilarity(t *testing.T) {
 		}
 	})
 }
+
+func TestTopKByScore(t *testing.T) {
+	query := []float32{1, 0}
+	vectors := [][]float32{
+		{10, 0},  // same direction, far away
+		{1, 0.1}, // close by, slightly off direction
+	}
+
+	// Cosine prefers direction
+	if got := TopKByCosineSimilarity(query, vectors, 1); got[0].Index != 0 {
+		t.Errorf("cosine top result = %d, want 0", got[0].Index)
+	}
+
+	// A distance-based scorer prefers proximity
+	negDistance := func(a, b []float32) float32 { return -EuclideanDistance(a, b) }
+	got := TopKByScore(query, vectors, 2, negDistance)
+	if got[0].Index != 1 {
+		t.Errorf("distance top result = %d, want 1", got[0].Index)
+	}
+	if got[0].Score != negDistance(query, vectors[1]) {
+		t.Errorf("score = %v, want the scorer's value %v", got[0].Score, negDistance(query, vectors[1]))
+	}
+}
+
+// cosineSimilarityScalar is the straightforward single-accumulator cosine,
+// the reference for the optimized implementations
+func cosineSimilarityScalar(a, b []float32) float32 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
+
+// benchVectors returns n pseudo-random vectors of dim dimensions
+func benchVectors(n, dim int) [][]float32 {
+	rng := rand.New(rand.NewSource(1))
+	vectors := make([][]float32, n)
+	for i := range vectors {
+		vectors[i] = make([]float32, dim)
+		for j := range vectors[i] {
+			vectors[i][j] = rng.Float32()*2 - 1
+		}
+	}
+	return vectors
+}
+
+func TestOptimizedCosineMatchesScalar(t *testing.T) {
+	// Odd lengths exercise the unrolled loops' tails
+	for _, dim := range []int{1, 3, 7, 768, 1025} {
+		vectors := benchVectors(20, dim)
+		query := vectors[0]
+		norms := make([]float64, len(vectors))
+		for i, v := range vectors {
+			norms[i] = vectorNorm(v)
+		}
+
+		for i, v := range vectors {
+			want := cosineSimilarityScalar(query, v)
+			for name, got := range map[string]float32{
+				"CosineSimilarity":      CosineSimilarity(query, v),
+				"CosineSimilarityNorms": CosineSimilarityNorms(query, norms[0], v, norms[i]),
+				"cosineSimilarity":      cosineSimilarity(query, v),
+			} {
+				if math.Abs(float64(got-want)) > 1e-4 {
+					t.Errorf("dim %d vector %d: %s = %v, want %v", dim, i, name, got, want)
+				}
+			}
+		}
+
+		topK := TopKByCosineSimilarityNorms(query, vectors, norms, 5)
+		plain := TopKByCosineSimilarity(query, vectors, 5)
+		for i := range topK {
+			if topK[i].Index != plain[i].Index {
+				t.Errorf("dim %d: precomputed norms rank %d = %d, want %d", dim, i, topK[i].Index, plain[i].Index)
+			}
+		}
+	}
+}
+
+// BenchmarkCosineTopK10Kx768 compares a brute-force top-10 over 10k
+// 768-dimension vectors with the scalar loop, the unrolled loop, and the
+// unrolled loop with candidate norms precomputed at load.
+func BenchmarkCosineTopK10Kx768(b *testing.B) {
+	vectors := benchVectors(10000, 768)
+	query := benchVectors(1, 768)[0]
+	norms := make([]float64, len(vectors))
+	for i, v := range vectors {
+		norms[i] = vectorNorm(v)
+	}
+
+	b.Run("scalar", func(b *testing.B) {
+		for b.Loop() {
+			TopKByScore(query, vectors, 10, cosineSimilarityScalar)
+		}
+	})
+	b.Run("unrolled", func(b *testing.B) {
+		for b.Loop() {
+			TopKByCosineSimilarity(query, vectors, 10)
+		}
+	})
+	b.Run("precomputed_norms", func(b *testing.B) {
+		for b.Loop() {
+			TopKByCosineSimilarityNorms(query, vectors, norms, 10)
+		}
+	})
+	b.Run("v2_float32", func(b *testing.B) {
+		for b.Loop() {
+			TopKByScore(query, vectors, 10, cosineSimilarity)
+		}
+	})
+}
@@ -0,0 +1,149 @@
+package embedding
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Index metadata keys recorded at embed time.
+const (
+	MetaEmbeddingModel      = "embedding_model"      // Provider ID, same as the embeddings model column
+	MetaEmbeddingDimensions = "embedding_dimensions" // Vector dimensions
+	MetaToolVersion         = "tool_version"         // codetect-index version that embedded
+	MetaEmbeddedAt          = "embedded_at"          // Unix time of the last embed
+	MetaVectorFormat        = "vector_format"        // VectorFormat vectors are written in
+)
+
+// IndexMetadata describes how a SQLite index was embedded.
+type IndexMetadata struct {
+	Model       string    `json:"model"`
+	Dimensions  int       `json:"dimensions"`
+	ToolVersion string    `json:"tool_version,omitempty"`
+	EmbeddedAt  time.Time `json:"embedded_at"`
+}
+
+// initMetadataTable creates the index_metadata key/value table (SQLite only).
+// A SQLite database holds a single repo's index, so metadata is database-wide;
+// PostgreSQL tracks the equivalent per repo in repo_embedding_configs.
+func (s *EmbeddingStore) initMetadataTable() error {
+	const schema = `
+CREATE TABLE IF NOT EXISTS index_metadata (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+)`
+	if _, err := s.db.Exec(schema); err != nil {
+		return fmt.Errorf("creating index_metadata table: %w", err)
+	}
+	return nil
+}
+
+// SetMetadata stores a metadata value. No-op on PostgreSQL.
+func (s *EmbeddingStore) SetMetadata(key, value string) error {
+	if s.dialect.Name() != "sqlite" {
+		return nil
+	}
+
+	upsertSQL := s.dialect.UpsertSQL("index_metadata", []string{"key", "value"}, []string{"key"}, []string{"value"})
+	upsertSQL = s.schema.SubstitutePlaceholders(upsertSQL)
+	if _, err := s.db.Exec(upsertSQL, key, value); err != nil {
+		return fmt.Errorf("setting metadata %s: %w", key, err)
+	}
+	return nil
+}
+
+// GetMetadata returns a metadata value, or "" if it is not set.
+// Always returns "" on PostgreSQL.
+func (s *EmbeddingStore) GetMetadata(key string) (string, error) {
+	if s.dialect.Name() != "sqlite" {
+		return "", nil
+	}
+
+	query := s.schema.SubstitutePlaceholders("SELECT value FROM index_metadata WHERE key = ?")
+	var value string
+	err := s.db.QueryRow(query, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("getting metadata %s: %w", key, err)
+	}
+	return value, nil
+}
+
+// RecordEmbedMetadata records the model, dimensions and tool version used
+// to embed this index. Called at the end of an embed run.
+func (s *EmbeddingStore) RecordEmbedMetadata(model string, dimensions int, toolVersion string) error {
+	values := map[string]string{
+		MetaEmbeddingModel:      model,
+		MetaEmbeddingDimensions: strconv.Itoa(dimensions),
+		MetaToolVersion:         toolVersion,
+		MetaEmbeddedAt:          strconv.FormatInt(time.Now().Unix(), 10),
+	}
+	for key, value := range values {
+		if err := s.SetMetadata(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EmbedMetadata returns how this index was embedded, or nil if it has never
+// been embedded (or the database is PostgreSQL).
+func (s *EmbeddingStore) EmbedMetadata() (*IndexMetadata, error) {
+	model, err := s.GetMetadata(MetaEmbeddingModel)
+	if err != nil || model == "" {
+		return nil, err
+	}
+
+	meta := &IndexMetadata{Model: model}
+	if v, err := s.GetMetadata(MetaEmbeddingDimensions); err != nil {
+		return nil, err
+	} else if v != "" {
+		meta.Dimensions, _ = strconv.Atoi(v)
+	}
+	if meta.ToolVersion, err = s.GetMetadata(MetaToolVersion); err != nil {
+		return nil, err
+	}
+	if v, err := s.GetMetadata(MetaEmbeddedAt); err != nil {
+		return nil, err
+	} else if v != "" {
+		ts, _ := strconv.ParseInt(v, 10, 64)
+		meta.EmbeddedAt = time.Unix(ts, 0)
+	}
+	return meta, nil
+}
+
+// ModelName returns the model part of a "provider:model" provider ID.
+func ModelName(providerID string) string {
+	if _, model, ok := strings.Cut(providerID, ":"); ok {
+		return model
+	}
+	return providerID
+}
+
+// ModelWarning returns a warning if the searcher's embedder differs from the
+// one the index was embedded with, since similarity scores across models are
+// meaningless. Returns "" when they match or nothing was recorded.
+func (s *SemanticSearcher) ModelWarning() string {
+	if s.embedder == nil {
+		return ""
+	}
+
+	meta, err := s.store.EmbedMetadata()
+	if err != nil || meta == nil {
+		return ""
+	}
+
+	if meta.Model != s.embedder.ProviderID() {
+		return fmt.Sprintf("index was embedded with %s but searching with %s; re-run 'codetect-index embed --force' or switch models",
+			meta.Model, s.embedder.ProviderID())
+	}
+	if meta.Dimensions > 0 && s.embedder.Dimensions() > 0 && meta.Dimensions != s.embedder.Dimensions() {
+		return fmt.Sprintf("index has %d-dimension embeddings but %s produces %d",
+			meta.Dimensions, s.embedder.ProviderID(), s.embedder.Dimensions())
+	}
+	return ""
+}
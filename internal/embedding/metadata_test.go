@@ -0,0 +1,88 @@
+package embedding
+
+import (
+	"strings"
+	"testing"
+
+	"codetect/internal/db"
+)
+
+func TestEmbedMetadata(t *testing.T) {
+	database, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	store, err := NewEmbeddingStore(database, "/test/repo")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	meta, err := store.EmbedMetadata()
+	if err != nil || meta != nil {
+		t.Fatalf("EmbedMetadata() before embed = %v, %v; want nil, nil", meta, err)
+	}
+
+	if err := store.RecordEmbedMetadata("ollama:nomic-embed-text", 768, "0.4.0"); err != nil {
+		t.Fatalf("RecordEmbedMetadata() error = %v", err)
+	}
+
+	meta, err = store.EmbedMetadata()
+	if err != nil {
+		t.Fatalf("EmbedMetadata() error = %v", err)
+	}
+	if meta.Model != "ollama:nomic-embed-text" || meta.Dimensions != 768 || meta.ToolVersion != "0.4.0" {
+		t.Errorf("EmbedMetadata() = %+v", meta)
+	}
+	if meta.EmbeddedAt.IsZero() {
+		t.Error("EmbeddedAt should be set")
+	}
+
+	// Re-embedding overwrites
+	if err := store.RecordEmbedMetadata("mock:test", 4, "0.5.0"); err != nil {
+		t.Fatalf("RecordEmbedMetadata() error = %v", err)
+	}
+	if v, _ := store.GetMetadata(MetaToolVersion); v != "0.5.0" {
+		t.Errorf("tool_version = %q, want 0.5.0", v)
+	}
+}
+
+func TestSemanticSearcherModelWarning(t *testing.T) {
+	database, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	store, err := NewEmbeddingStore(database, "/test/repo")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	searcher := NewSemanticSearcher(store, newMockEmbedder(4))
+
+	// Nothing recorded yet
+	if w := searcher.ModelWarning(); w != "" {
+		t.Errorf("ModelWarning() without metadata = %q, want empty", w)
+	}
+
+	store.RecordEmbedMetadata("mock:test", 4, "0.4.0")
+	if w := searcher.ModelWarning(); w != "" {
+		t.Errorf("ModelWarning() with matching model = %q, want empty", w)
+	}
+
+	store.RecordEmbedMetadata("ollama:nomic-embed-text", 768, "0.4.0")
+	w := searcher.ModelWarning()
+	if !strings.Contains(w, "ollama:nomic-embed-text") || !strings.Contains(w, "mock:test") {
+		t.Errorf("ModelWarning() = %q, want both models named", w)
+	}
+}
+
+func TestModelName(t *testing.T) {
+	if got := ModelName("ollama:nomic-embed-text"); got != "nomic-embed-text" {
+		t.Errorf("ModelName() = %q", got)
+	}
+	if got := ModelName("bge-m3"); got != "bge-m3" {
+		t.Errorf("ModelName() without provider = %q", got)
+	}
+}
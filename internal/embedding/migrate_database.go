@@ -3,6 +3,10 @@ package embedding
 import (
 	"context"
 	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
 
 	"codetect/internal/db"
 )
@@ -20,6 +24,11 @@ type MigrationOptions struct {
 
 	// DryRun performs validation without actually migrating data
 	DryRun bool
+
+	// Workers is how many batches are saved to the target concurrently,
+	// each on its own connection from the target's pool. SQLite targets
+	// take one writer at a time and always use a single worker.
+	Workers int
 }
 
 // DefaultMigrationOptions returns sensible defaults for migration.
@@ -29,6 +38,7 @@ func DefaultMigrationOptions() MigrationOptions {
 		SkipExisting: true,
 		DropTarget:   false,
 		DryRun:       false,
+		Workers:      4,
 	}
 }
 
@@ -42,6 +52,7 @@ type MigrationProgress struct {
 }
 
 // MigrationCallback is called periodically during migration to report progress.
+// Calls are serialized even when batches are saved by several workers.
 type MigrationCallback func(progress MigrationProgress)
 
 // MigrateDatabase migrates all embeddings from one database to another.
@@ -63,6 +74,9 @@ func MigrateDatabase(
 	if opts.BatchSize <= 0 {
 		opts.BatchSize = 1000
 	}
+	if opts.Workers <= 0 || !target.useNativeVec {
+		opts.Workers = 1
+	}
 
 	// Initialize target schema if needed
 	if opts.DropTarget && !opts.DryRun {
@@ -88,93 +102,161 @@ func MigrateDatabase(
 		return nil
 	}
 
-	// Get all embeddings from source
-	// For large datasets, this should be paginated, but for now we load all
-	embeddings, err := source.GetAll()
-	if err != nil {
-		return fmt.Errorf("fetching source embeddings: %w", err)
+	save := func(batch []EmbeddingRecord) (int, int, error) {
+		return migrateBatch(target, batch, opts.SkipExisting)
 	}
+	return runMigration(ctx, source, opts, save, progress, callback)
+}
 
-	// Process in batches
-	for i := 0; i < len(embeddings); i += opts.BatchSize {
-		end := i + opts.BatchSize
-		if end > len(embeddings) {
-			end = len(embeddings)
-		}
-
-		batch := embeddings[i:end]
-
-		// Convert to chunks and vectors for batch insertion
-		chunks := make([]Chunk, len(batch))
-		vectors := make([][]float32, len(batch))
-		model := ""
-
-		for j, emb := range batch {
-			chunks[j] = Chunk{
-				Path:      emb.Path,
-				StartLine: emb.StartLine,
-				EndLine:   emb.EndLine,
-				Content:   "", // Not needed for migration
-			}
-			vectors[j] = emb.Embedding
-			if model == "" {
-				model = emb.Model
-			}
+// batchSaver saves one batch of source records to the migration target,
+// returning how many records were saved and skipped.
+type batchSaver func(batch []EmbeddingRecord) (migrated, skipped int, err error)
 
-			// Update progress
-			if emb.Path != progress.CurrentFile {
-				progress.CurrentFile = emb.Path
-			}
+// runMigration pages through source and hands the batches to opts.Workers
+// goroutines calling save, adding their counts to progress.
+func runMigration(
+	ctx context.Context,
+	source *EmbeddingStore,
+	opts MigrationOptions,
+	save batchSaver,
+	progress MigrationProgress,
+	callback MigrationCallback,
+) error {
+	// Batches are independent (rows are keyed by repo, path and lines), so
+	// workers can save them in any order. The first failure cancels the rest.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batches := make(chan []EmbeddingRecord)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
 		}
+		mu.Unlock()
+		cancel()
+	}
 
-		// Check for existing embeddings if SkipExisting is enabled
-		if opts.SkipExisting {
-			// Filter out existing embeddings
-			filteredChunks := make([]Chunk, 0, len(chunks))
-			filteredVectors := make([][]float32, 0, len(vectors))
-
-			for j, chunk := range chunks {
-				exists, err := target.HasEmbedding(chunk, model)
+	for range opts.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				migrated, skipped, err := save(batch)
 				if err != nil {
-					return fmt.Errorf("checking existing embedding: %w", err)
+					mu.Lock()
+					progress.FailedEmbeddings += len(batch) - skipped
+					mu.Unlock()
+					fail(err)
+					return
 				}
 
-				if !exists {
-					filteredChunks = append(filteredChunks, chunk)
-					filteredVectors = append(filteredVectors, vectors[j])
-				} else {
-					progress.SkippedEmbeddings++
+				// Aggregate progress; the callback never runs concurrently
+				mu.Lock()
+				progress.MigratedEmbeddings += migrated
+				progress.SkippedEmbeddings += skipped
+				progress.CurrentFile = batch[len(batch)-1].Path
+				if callback != nil {
+					callback(progress)
 				}
+				mu.Unlock()
 			}
+		}()
+	}
 
-			chunks = filteredChunks
-			vectors = filteredVectors
+	// Page through the source by ID instead of loading it whole, so memory
+	// holds only the batches in flight however large the source is
+	var afterID int64
+dispatch:
+	for ctx.Err() == nil {
+		batch, err := source.GetPage(afterID, opts.BatchSize)
+		if err != nil {
+			fail(fmt.Errorf("fetching source embeddings: %w", err))
+			break
 		}
+		if len(batch) == 0 {
+			break
+		}
+		afterID = batch[len(batch)-1].ID
 
-		// Save batch to target
-		if len(chunks) > 0 {
-			if err := target.SaveBatch(chunks, vectors, model); err != nil {
-				progress.FailedEmbeddings += len(chunks)
-				return fmt.Errorf("saving batch to target: %w", err)
-			}
+		select {
+		case batches <- batch:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(batches)
+	wg.Wait()
 
-			progress.MigratedEmbeddings += len(chunks)
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// migrateBatch saves one batch of source records to target, leaving out
+// those already there when skipExisting is set, and those whose dimensions
+// don't fit the target table (PlanMigration reports those groups as not
+// migrated). It returns how many records were saved and skipped.
+func migrateBatch(target *EmbeddingStore, batch []EmbeddingRecord, skipExisting bool) (migrated, skipped int, err error) {
+	// Convert to chunks and vectors for batch insertion, per model since a
+	// page can span several
+	type modelBatch struct {
+		chunks  []Chunk
+		vectors [][]float32
+	}
+	var models []string
+	byModel := make(map[string]*modelBatch)
+
+	for _, emb := range batch {
+		if len(emb.Embedding) != target.vectorDim {
+			skipped++
+			continue
 		}
 
-		// Report progress
-		if callback != nil {
-			callback(progress)
+		chunk := Chunk{
+			Path:      emb.Path,
+			StartLine: emb.StartLine,
+			EndLine:   emb.EndLine,
+			Content:   "", // Not needed for migration
 		}
 
-		// Check for cancellation
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
+		// Check for existing embeddings if SkipExisting is enabled
+		if skipExisting {
+			exists, err := target.HasEmbedding(chunk, emb.Model)
+			if err != nil {
+				return 0, skipped, fmt.Errorf("checking existing embedding: %w", err)
+			}
+			if exists {
+				skipped++
+				continue
+			}
 		}
+
+		mb := byModel[emb.Model]
+		if mb == nil {
+			mb = &modelBatch{}
+			byModel[emb.Model] = mb
+			models = append(models, emb.Model)
+		}
+		mb.chunks = append(mb.chunks, chunk)
+		mb.vectors = append(mb.vectors, emb.Embedding)
 	}
 
-	return nil
+	// Save batch to target
+	for _, model := range models {
+		mb := byModel[model]
+		if err := target.SaveBatch(mb.chunks, mb.vectors, model); err != nil {
+			return migrated, skipped, fmt.Errorf("saving batch to target: %w", err)
+		}
+		migrated += len(mb.chunks)
+	}
+	return migrated, skipped, nil
 }
 
 // MigrateDatabaseWithVectorIndex migrates embeddings and creates a vector index
@@ -212,89 +294,187 @@ func MigrateDatabaseWithVectorIndex(
 	return nil
 }
 
+// MigrationPlanEntry is one group of source embeddings in a migration plan:
+// where its rows would land and whether this migration moves them.
+type MigrationPlanEntry struct {
+	EmbeddingGroup
+
+	// TargetTable is the dimension-routed table the group belongs in
+	TargetTable string `json:"target_table"`
+
+	// Migrated is false for groups a run with this source and target
+	// leaves behind, with the reason in Skip
+	Migrated bool   `json:"migrated"`
+	Skip     string `json:"skip,omitempty"`
+}
+
+// PlanMigration breaks the source embeddings down per repo, model and
+// dimension, for a dry run. A migration only copies the source store's repo
+// into the target store's dimension table, so groups for other repos
+// sharing the source database, or with other dimensions, are marked as
+// not migrated.
+func PlanMigration(source *EmbeddingStore, target *EmbeddingStore) ([]MigrationPlanEntry, error) {
+	groups, err := source.CountsByGroup()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := make([]MigrationPlanEntry, len(groups))
+	for i, g := range groups {
+		entry := MigrationPlanEntry{
+			EmbeddingGroup: g,
+			TargetTable:    tableNameForDimensions(target.dialect, g.Dimensions),
+			Migrated:       true,
+		}
+		switch {
+		case g.RepoRoot != source.repoID:
+			entry.Migrated = false
+			entry.Skip = fmt.Sprintf("other repo (migrate with CODETECT_REPO_ID=%s)", g.RepoRoot)
+		case g.Dimensions != target.vectorDim:
+			entry.Migrated = false
+			entry.Skip = fmt.Sprintf("target is %d dimensions (set CODETECT_VECTOR_DIMENSIONS=%d)", target.vectorDim, g.Dimensions)
+		}
+		plan[i] = entry
+	}
+	return plan, nil
+}
+
+// vectorEpsilon is the largest per-element difference ValidateMigration
+// accepts. Vectors are float32 on both sides, so a lossless JSON to
+// pgvector round-trip matches exactly; the tolerance only absorbs
+// formatting of the last digit.
+const vectorEpsilon = 1e-6
+
 // ValidateMigration validates that a migration was successful by comparing
-// embedding counts and sampling random embeddings.
-func ValidateMigration(source *EmbeddingStore, target *EmbeddingStore, sampleSize int) error {
+// embedding counts, then checking that a random sample of sampleSize
+// embeddings has the same vector, element by element, on both sides.
+// Records are matched by repo, path, line range and model, and those that
+// don't fit the target's dimensions, which MigrateDatabase skips, are left
+// out of both checks. The first mismatch is reported with its location. The source is read in pages of
+// batchSize, like MigrateDatabase, so the sample never needs the whole table
+// in memory.
+func ValidateMigration(source *EmbeddingStore, target *EmbeddingStore, sampleSize, batchSize int) error {
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
 	// Compare counts
 	sourceCount, err := source.Count()
 	if err != nil {
 		return fmt.Errorf("counting source embeddings: %w", err)
 	}
 
-	targetCount, err := target.Count()
+	unfit, err := countUnfit(source, target)
 	if err != nil {
-		return fmt.Errorf("counting target embeddings: %w", err)
-	}
-
-	if sourceCount != targetCount {
-		return fmt.Errorf("embedding count mismatch: source=%d, target=%d", sourceCount, targetCount)
+		return fmt.Errorf("counting source embeddings by dimension: %w", err)
 	}
 
-	// Sample random embeddings and compare
-	sourceEmbeddings, err := source.GetAll()
+	targetCount, err := target.Count()
 	if err != nil {
-		return fmt.Errorf("fetching source embeddings: %w", err)
+		return fmt.Errorf("counting target embeddings: %w", err)
 	}
 
-	if len(sourceEmbeddings) == 0 {
-		return nil // Empty database, nothing to validate
+	if sourceCount-unfit != targetCount {
+		return fmt.Errorf("embedding count mismatch: source=%d, target=%d", sourceCount-unfit, targetCount)
 	}
 
-	// Sample at most sampleSize embeddings
-	step := len(sourceEmbeddings) / sampleSize
-	if step < 1 {
-		step = 1
+	if sourceCount == unfit {
+		return nil // Empty database, nothing to validate
 	}
 
-	for i := 0; i < len(sourceEmbeddings); i += step {
-		srcEmb := sourceEmbeddings[i]
+	// Sampled positions in ID order, picked up as the pages go by
+	sample := sampleIndexes(sourceCount, sampleSize)
+	slices.Sort(sample)
 
-		// Find matching embedding in target
-		targetEmbeddings, err := target.GetByPath(srcEmb.Path)
+	var afterID int64
+	pos := 0
+	for len(sample) > 0 {
+		page, err := source.GetPage(afterID, batchSize)
 		if err != nil {
-			return fmt.Errorf("fetching target embeddings for %s: %w", srcEmb.Path, err)
+			return fmt.Errorf("fetching source embeddings: %w", err)
+		}
+		if len(page) == 0 {
+			break
 		}
+		afterID = page[len(page)-1].ID
 
-		// Find exact match
-		found := false
-		for _, tgtEmb := range targetEmbeddings {
-			if tgtEmb.StartLine == srcEmb.StartLine &&
-				tgtEmb.EndLine == srcEmb.EndLine &&
-				tgtEmb.Model == srcEmb.Model {
-				found = true
-
-				// Compare embedding dimensions
-				if len(tgtEmb.Embedding) != len(srcEmb.Embedding) {
-					return fmt.Errorf("embedding dimension mismatch for %s:%d-%d",
-						srcEmb.Path, srcEmb.StartLine, srcEmb.EndLine)
+		for len(sample) > 0 && sample[0] < pos+len(page) {
+			if rec := page[sample[0]-pos]; len(rec.Embedding) == target.vectorDim {
+				if err := validateRecord(target, rec); err != nil {
+					return err
 				}
+			}
+			sample = sample[1:]
+		}
+		pos += len(page)
+	}
 
-				// Compare first few values (floating point comparison with tolerance)
-				compareCount := min(10, len(srcEmb.Embedding))
-				for j := range compareCount {
-					diff := srcEmb.Embedding[j] - tgtEmb.Embedding[j]
-					if diff < 0 {
-						diff = -diff
-					}
-					if diff > 0.0001 {
-						return fmt.Errorf("embedding value mismatch for %s:%d-%d at index %d",
-							srcEmb.Path, srcEmb.StartLine, srcEmb.EndLine, j)
-					}
-				}
+	return nil
+}
 
-				break
-			}
+// countUnfit returns how many of the source repo's embeddings have
+// dimensions other than the target's, which MigrateDatabase skips
+func countUnfit(source *EmbeddingStore, target *EmbeddingStore) (int, error) {
+	groups, err := source.CountsByGroup()
+	if err != nil {
+		return 0, err
+	}
+	unfit := 0
+	for _, g := range groups {
+		if g.RepoRoot == source.repoID && g.Dimensions != target.vectorDim {
+			unfit += g.Count
 		}
+	}
+	return unfit, nil
+}
 
-		if !found {
-			return fmt.Errorf("embedding not found in target: %s:%d-%d",
-				srcEmb.Path, srcEmb.StartLine, srcEmb.EndLine)
+// validateRecord checks that target holds srcEmb with the same vector
+func validateRecord(target *EmbeddingStore, srcEmb EmbeddingRecord) error {
+	// Find matching embedding in target
+	targetEmbeddings, err := target.GetByPath(srcEmb.Path)
+	if err != nil {
+		return fmt.Errorf("fetching target embeddings for %s: %w", srcEmb.Path, err)
+	}
+
+	var tgtEmb *EmbeddingRecord
+	for j := range targetEmbeddings {
+		if targetEmbeddings[j].StartLine == srcEmb.StartLine &&
+			targetEmbeddings[j].EndLine == srcEmb.EndLine &&
+			targetEmbeddings[j].Model == srcEmb.Model {
+			tgtEmb = &targetEmbeddings[j]
+			break
 		}
 	}
+	if tgtEmb == nil {
+		return fmt.Errorf("embedding not found in target: %s:%d-%d (%s)",
+			srcEmb.Path, srcEmb.StartLine, srcEmb.EndLine, srcEmb.Model)
+	}
+
+	if len(tgtEmb.Embedding) != len(srcEmb.Embedding) {
+		return fmt.Errorf("embedding dimension mismatch for %s:%d-%d (%s): source=%d, target=%d",
+			srcEmb.Path, srcEmb.StartLine, srcEmb.EndLine, srcEmb.Model,
+			len(srcEmb.Embedding), len(tgtEmb.Embedding))
+	}
 
+	for j, v := range srcEmb.Embedding {
+		if diff := math.Abs(float64(v) - float64(tgtEmb.Embedding[j])); diff > vectorEpsilon {
+			return fmt.Errorf("embedding value mismatch for %s:%d-%d (%s) at index %d: source=%g, target=%g",
+				srcEmb.Path, srcEmb.StartLine, srcEmb.EndLine, srcEmb.Model,
+				j, v, tgtEmb.Embedding[j])
+		}
+	}
 	return nil
 }
 
+// sampleIndexes picks up to size distinct indexes in [0, n) at random,
+// or all of them when size is not positive or covers every record
+func sampleIndexes(n, size int) []int {
+	if size <= 0 || size >= n {
+		size = n
+	}
+	return rand.Perm(n)[:size]
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -2,8 +2,13 @@ package embedding
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"codetect/internal/db"
 )
@@ -33,7 +38,7 @@ func TestMigrateDatabase(t *testing.T) {
 	}
 	defer targetDB.Close()
 
-	targetStore, err := NewEmbeddingStore(targetDB, testRepoRoot)
+	targetStore, err := NewEmbeddingStoreWithOptions(targetDB, db.GetDialect(db.DatabaseSQLite), 3, testRepoRoot)
 	if err != nil {
 		t.Fatalf("Failed to create target store: %v", err)
 	}
@@ -188,7 +193,7 @@ func TestValidateMigration(t *testing.T) {
 	}
 	defer targetDB.Close()
 
-	targetStore, err := NewEmbeddingStore(targetDB, testRepoRoot)
+	targetStore, err := NewEmbeddingStoreWithOptions(targetDB, db.GetDialect(db.DatabaseSQLite), 3, testRepoRoot)
 	if err != nil {
 		t.Fatalf("Failed to create target store: %v", err)
 	}
@@ -215,7 +220,7 @@ func TestValidateMigration(t *testing.T) {
 		}
 
 		// Validate
-		err = ValidateMigration(sourceStore, targetStore, 10)
+		err = ValidateMigration(sourceStore, targetStore, 10, 0)
 		if err != nil {
 			t.Errorf("Validation failed: %v", err)
 		}
@@ -225,12 +230,26 @@ func TestValidateMigration(t *testing.T) {
 		// Delete one embedding from target
 		targetStore.DeleteByPath("file2.go")
 
-		err := ValidateMigration(sourceStore, targetStore, 10)
+		err := ValidateMigration(sourceStore, targetStore, 10, 0)
 		if err == nil {
 			t.Error("Expected validation to fail with count mismatch")
 		}
 	})
 
+	t.Run("Validation Vector Mismatch", func(t *testing.T) {
+		// Restore the count, but with a vector that differs in one element
+		err := targetStore.SaveBatch(testChunks[1:], [][]float32{{0.0, 1.0, 0.001}}, "test-model")
+		if err != nil {
+			t.Fatalf("Failed to save corrupted embedding: %v", err)
+		}
+
+		// One record per page, so the mismatch is only found on the second
+		err = ValidateMigration(sourceStore, targetStore, 10, 1)
+		if err == nil || !strings.Contains(err.Error(), "file2.go:1-15") || !strings.Contains(err.Error(), "index 2") {
+			t.Errorf("Expected a value mismatch for file2.go at index 2, got %v", err)
+		}
+	})
+
 	t.Run("Validation Empty Database", func(t *testing.T) {
 		emptySourceCfg := db.DefaultConfig(":memory:")
 		emptySourceDB, err := db.Open(emptySourceCfg)
@@ -250,13 +269,408 @@ func TestValidateMigration(t *testing.T) {
 
 		emptyTarget, _ := NewEmbeddingStore(emptyTargetDB, testRepoRoot)
 
-		err = ValidateMigration(emptySource, emptyTarget, 10)
+		err = ValidateMigration(emptySource, emptyTarget, 10, 0)
 		if err != nil {
 			t.Errorf("Empty database validation should succeed: %v", err)
 		}
 	})
 }
 
+// TestMigrateDatabaseWorkers migrates several batches through the worker
+// pool, checking every record and the final progress arrive, and that a
+// cancelled context stops the migration.
+func TestMigrateDatabaseWorkers(t *testing.T) {
+	testRepoRoot := "/test/repo"
+
+	sourceDB, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open source database: %v", err)
+	}
+	defer sourceDB.Close()
+	sourceStore, err := NewEmbeddingStore(sourceDB, testRepoRoot)
+	if err != nil {
+		t.Fatalf("Failed to create source store: %v", err)
+	}
+
+	// SQLite targets fall back to one worker; batches still go through the pool
+	targetDB, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open target database: %v", err)
+	}
+	defer targetDB.Close()
+	targetStore, err := NewEmbeddingStoreWithOptions(targetDB, db.GetDialect(db.DatabaseSQLite), 3, testRepoRoot)
+	if err != nil {
+		t.Fatalf("Failed to create target store: %v", err)
+	}
+
+	const total = 50
+	chunks := make([]Chunk, total)
+	vectors := make([][]float32, total)
+	for i := range total {
+		chunks[i] = Chunk{Path: fmt.Sprintf("file%d.go", i), StartLine: 1, EndLine: 10, Content: "x"}
+		vectors[i] = []float32{float32(i), 1, 0}
+	}
+	if err := sourceStore.SaveBatch(chunks, vectors, "test-model"); err != nil {
+		t.Fatalf("Failed to save test data: %v", err)
+	}
+
+	opts := DefaultMigrationOptions()
+	opts.BatchSize = 7
+	opts.Workers = 4
+	var last MigrationProgress
+	if err := MigrateDatabase(context.Background(), sourceStore, targetStore, opts, func(p MigrationProgress) {
+		last = p
+	}); err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	if last.MigratedEmbeddings != total {
+		t.Errorf("Final progress reports %d migrated, want %d", last.MigratedEmbeddings, total)
+	}
+	count, err := targetStore.Count()
+	if err != nil {
+		t.Fatalf("Failed to count target: %v", err)
+	}
+	if count != total {
+		t.Errorf("Target has %d embeddings, want %d", count, total)
+	}
+
+	t.Run("Cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := MigrateDatabase(ctx, sourceStore, targetStore, opts, nil); err != context.Canceled {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+// TestRunMigrationWorkers runs the worker pool against a fake target that
+// only returns once every worker holds a batch, so a pool that saved one
+// batch at a time would stall. It also checks a failed save stops the run.
+func TestRunMigrationWorkers(t *testing.T) {
+	sourceDB, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open source database: %v", err)
+	}
+	defer sourceDB.Close()
+	sourceStore, err := NewEmbeddingStore(sourceDB, "/test/repo")
+	if err != nil {
+		t.Fatalf("Failed to create source store: %v", err)
+	}
+
+	const total = 50
+	chunks := make([]Chunk, total)
+	vectors := make([][]float32, total)
+	for i := range total {
+		chunks[i] = Chunk{Path: fmt.Sprintf("file%d.go", i), StartLine: 1, EndLine: 10, Content: "x"}
+		vectors[i] = []float32{float32(i), 1, 0}
+	}
+	if err := sourceStore.SaveBatch(chunks, vectors, "test-model"); err != nil {
+		t.Fatalf("Failed to save test data: %v", err)
+	}
+
+	opts := DefaultMigrationOptions()
+	opts.BatchSize = 5
+	opts.Workers = 4
+
+	var (
+		mu      sync.Mutex
+		saved   = make(map[int64]int)
+		calls   int
+		started sync.WaitGroup
+		ready   = make(chan struct{})
+	)
+	started.Add(opts.Workers)
+	go func() {
+		started.Wait()
+		close(ready)
+	}()
+	save := func(batch []EmbeddingRecord) (int, int, error) {
+		mu.Lock()
+		calls++
+		first := calls <= opts.Workers
+		for _, rec := range batch {
+			saved[rec.ID]++
+		}
+		mu.Unlock()
+
+		if first {
+			started.Done()
+			select {
+			case <-ready:
+			case <-time.After(5 * time.Second):
+				return 0, 0, fmt.Errorf("batches were not saved concurrently")
+			}
+		}
+		return len(batch), 0, nil
+	}
+
+	var last MigrationProgress
+	progress := MigrationProgress{TotalEmbeddings: total}
+	if err := runMigration(context.Background(), sourceStore, opts, save, progress, func(p MigrationProgress) {
+		last = p
+	}); err != nil {
+		t.Fatalf("runMigration() error = %v", err)
+	}
+
+	if last.MigratedEmbeddings != total {
+		t.Errorf("Final progress reports %d migrated, want %d", last.MigratedEmbeddings, total)
+	}
+	if len(saved) != total {
+		t.Errorf("Saved %d distinct records, want %d", len(saved), total)
+	}
+	for id, n := range saved {
+		if n != 1 {
+			t.Errorf("Record %d saved %d times, want once", id, n)
+		}
+	}
+
+	t.Run("SaveFails", func(t *testing.T) {
+		failing := func(batch []EmbeddingRecord) (int, int, error) {
+			return 0, 0, fmt.Errorf("target unavailable")
+		}
+		err := runMigration(context.Background(), sourceStore, opts, failing, progress, nil)
+		if err == nil || !strings.Contains(err.Error(), "target unavailable") {
+			t.Errorf("Expected the save error, got %v", err)
+		}
+	})
+}
+
+func TestEmbeddingStoreGetPage(t *testing.T) {
+	database, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	store, err := NewEmbeddingStore(database, "/test/repo")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	other, err := NewEmbeddingStore(database, "/test/other")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	const total = 25
+	for i := range total {
+		chunk := Chunk{Path: fmt.Sprintf("f%02d.go", i), StartLine: 1, EndLine: 2, Content: "x"}
+		if err := store.Save(chunk, []float32{float32(i), 1}, "test-model"); err != nil {
+			t.Fatal(err)
+		}
+		// Interleave another repo's rows, which paging must skip
+		if err := other.Save(chunk, []float32{0, 1}, "test-model"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var seen []string
+	var afterID int64
+	for {
+		page, err := store.GetPage(afterID, 10)
+		if err != nil {
+			t.Fatalf("GetPage failed: %v", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		if len(page) > 10 {
+			t.Fatalf("Page of %d records exceeds the limit", len(page))
+		}
+		for _, r := range page {
+			if r.ID <= afterID {
+				t.Fatalf("Record ID %d not above %d", r.ID, afterID)
+			}
+			afterID = r.ID
+			seen = append(seen, r.Path)
+		}
+	}
+	if len(seen) != total || seen[0] != "f00.go" || seen[total-1] != "f24.go" {
+		t.Errorf("Paged through %d records (%v), want all %d of this repo in order", len(seen), seen, total)
+	}
+}
+
+// TestMigrateDatabaseBoundedMemory migrates a source far larger than one
+// batch and checks the heap never grows by anything like the whole source,
+// as it did when the migration loaded every record up front.
+func TestMigrateDatabaseBoundedMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping large migration in short mode")
+	}
+	testRepoRoot := "/test/repo"
+
+	sourceDB, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open source database: %v", err)
+	}
+	defer sourceDB.Close()
+	sourceStore, err := NewEmbeddingStore(sourceDB, testRepoRoot)
+	if err != nil {
+		t.Fatalf("Failed to create source store: %v", err)
+	}
+
+	const total = 20000
+	chunks := make([]Chunk, total)
+	vectors := make([][]float32, total)
+	for i := range total {
+		chunks[i] = Chunk{Path: fmt.Sprintf("dir/file%05d.go", i), StartLine: i, EndLine: i + 1, Content: "x"}
+		vectors[i] = []float32{float32(i), 1, 0, 0}
+	}
+	if err := sourceStore.SaveBatch(chunks, vectors, "test-model"); err != nil {
+		t.Fatalf("Failed to save test data: %v", err)
+	}
+	chunks, vectors = nil, nil
+
+	// What holding the whole source in memory costs
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	all, err := sourceStore.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+	fullSet := after.HeapAlloc - before.HeapAlloc
+	runtime.KeepAlive(all)
+	all = nil
+
+	targetDB, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open target database: %v", err)
+	}
+	defer targetDB.Close()
+	targetStore, err := NewEmbeddingStoreWithOptions(targetDB, db.GetDialect(db.DatabaseSQLite), 4, testRepoRoot)
+	if err != nil {
+		t.Fatalf("Failed to create target store: %v", err)
+	}
+
+	opts := DefaultMigrationOptions()
+	opts.BatchSize = 100
+	opts.SkipExisting = false
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	var peak uint64
+	calls := 0
+	err = MigrateDatabase(context.Background(), sourceStore, targetStore, opts, func(p MigrationProgress) {
+		if calls++; calls%20 != 0 {
+			return
+		}
+		// Collect first so only live memory counts
+		var m runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&m)
+		peak = max(peak, m.HeapAlloc)
+	})
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	if count, _ := targetStore.Count(); count != total {
+		t.Fatalf("Target has %d embeddings, want %d", count, total)
+	}
+	if peak > before.HeapAlloc && peak-before.HeapAlloc > fullSet/4 {
+		t.Errorf("Live heap grew by %d bytes during migration; the whole source takes %d", peak-before.HeapAlloc, fullSet)
+	}
+}
+
+// TestPlanMigration checks the dry-run plan lists each repo, model and
+// dimension group with whether it migrates or why it is skipped.
+func TestPlanMigration(t *testing.T) {
+	sourceDB, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open source database: %v", err)
+	}
+	defer sourceDB.Close()
+
+	// Two repos share the source database, one embedded at two sizes
+	sourceStore, err := NewEmbeddingStore(sourceDB, "/test/repo")
+	if err != nil {
+		t.Fatalf("Failed to create source store: %v", err)
+	}
+	otherStore, err := NewEmbeddingStore(sourceDB, "/test/other")
+	if err != nil {
+		t.Fatalf("Failed to create other store: %v", err)
+	}
+	chunks := []Chunk{
+		{Path: "a.go", StartLine: 1, EndLine: 5, Content: "a"},
+		{Path: "b.go", StartLine: 1, EndLine: 5, Content: "b"},
+	}
+	if err := sourceStore.SaveBatch(chunks, [][]float32{{1, 0, 0}, {0, 1, 0}}, "small"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sourceStore.SaveBatch(chunks[:1], [][]float32{{1, 0, 0, 0}}, "large"); err != nil {
+		t.Fatal(err)
+	}
+	if err := otherStore.SaveBatch(chunks[:1], [][]float32{{0, 0, 1}}, "small"); err != nil {
+		t.Fatal(err)
+	}
+
+	targetDB, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open target database: %v", err)
+	}
+	defer targetDB.Close()
+	targetStore, err := NewEmbeddingStoreWithOptions(targetDB, db.GetDialect(db.DatabaseSQLite), 3, "/test/repo")
+	if err != nil {
+		t.Fatalf("Failed to create target store: %v", err)
+	}
+
+	plan, err := PlanMigration(sourceStore, targetStore)
+	if err != nil {
+		t.Fatalf("PlanMigration failed: %v", err)
+	}
+	if len(plan) != 3 {
+		t.Fatalf("Expected 3 groups, got %d: %+v", len(plan), plan)
+	}
+
+	want := []struct {
+		repo     string
+		model    string
+		dims     int
+		count    int
+		migrated bool
+	}{
+		{"/test/other", "small", 3, 1, false},
+		{"/test/repo", "large", 4, 1, false},
+		{"/test/repo", "small", 3, 2, true},
+	}
+	for i, w := range want {
+		e := plan[i]
+		if e.RepoRoot != w.repo || e.Model != w.model || e.Dimensions != w.dims || e.Count != w.count || e.Migrated != w.migrated {
+			t.Errorf("plan[%d] = %+v, want %+v", i, e, w)
+		}
+		if !e.Migrated && e.Skip == "" {
+			t.Errorf("plan[%d] is skipped without a reason", i)
+		}
+	}
+
+	// The migration itself agrees with the plan: the 4-dimension group is
+	// skipped instead of failing the target's 3-dimension table
+	var progress MigrationProgress
+	err = MigrateDatabase(context.Background(), sourceStore, targetStore, DefaultMigrationOptions(), func(p MigrationProgress) {
+		progress = p
+	})
+	if err != nil {
+		t.Fatalf("MigrateDatabase failed: %v", err)
+	}
+	if progress.MigratedEmbeddings != 2 || progress.SkippedEmbeddings != 1 {
+		t.Errorf("Migrated %d and skipped %d, want 2 and 1 as planned", progress.MigratedEmbeddings, progress.SkippedEmbeddings)
+	}
+	migrated, err := targetStore.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, emb := range migrated {
+		if emb.Model != "small" || len(emb.Embedding) != 3 {
+			t.Errorf("Target holds %s:%d (%s, %d dims), want only the planned group", emb.Path, emb.StartLine, emb.Model, len(emb.Embedding))
+		}
+	}
+	if err := ValidateMigration(sourceStore, targetStore, 10, 1000); err != nil {
+		t.Errorf("ValidateMigration counts the skipped group: %v", err)
+	}
+}
+
 // TestMigrateDatabaseWithVectorIndex tests migration with PostgreSQL and vector indexing.
 // This test requires a PostgreSQL database with pgvector extension.
 func TestMigrateDatabaseWithVectorIndex(t *testing.T) {
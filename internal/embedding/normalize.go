@@ -0,0 +1,68 @@
+package embedding
+
+import "math"
+
+// Score normalizations accepted by SetScoreNormalization.
+const (
+	NormalizeNone    = ""
+	NormalizeMinMax  = "minmax"
+	NormalizeSigmoid = "sigmoid"
+)
+
+// The sigmoid is centred in the band cosine similarities of related code
+// fall in, so 0.6 maps to about 0.27 and 0.85 to about 0.82.
+const (
+	sigmoidCenter    = 0.7
+	sigmoidSteepness = 10
+)
+
+// NormalizeScores rescales scores in place for display. minmax maps the
+// set's lowest score to 0 and its highest to 1 (all 1 when they're equal);
+// sigmoid squashes each score around sigmoidCenter, so it is comparable
+// across queries. Ordering is unchanged either way.
+func NormalizeScores(method string, scores []float32) {
+	switch method {
+	case NormalizeMinMax:
+		if len(scores) == 0 {
+			return
+		}
+		lo, hi := scores[0], scores[0]
+		for _, s := range scores {
+			if s < lo {
+				lo = s
+			}
+			if s > hi {
+				hi = s
+			}
+		}
+		for i, s := range scores {
+			if hi == lo {
+				scores[i] = 1
+			} else {
+				scores[i] = (s - lo) / (hi - lo)
+			}
+		}
+	case NormalizeSigmoid:
+		for i, s := range scores {
+			scores[i] = float32(1 / (1 + math.Exp(-sigmoidSteepness*(float64(s)-sigmoidCenter))))
+		}
+	}
+}
+
+// normalize rewrites the scores of results about to be returned, keeping
+// the similarity in RawScore
+func (s *SemanticSearcher) normalize(results []SemanticResult) {
+	if s.normalization == NormalizeNone || len(results) == 0 {
+		return
+	}
+	scores := make([]float32, len(results))
+	for i := range results {
+		raw := results[i].Score
+		results[i].RawScore = &raw
+		scores[i] = raw
+	}
+	NormalizeScores(s.normalization, scores)
+	for i := range results {
+		results[i].Score = scores[i]
+	}
+}
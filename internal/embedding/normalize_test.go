@@ -0,0 +1,94 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"codetect/internal/db"
+)
+
+func TestNormalizeScores(t *testing.T) {
+	scores := []float32{0.85, 0.7, 0.6}
+	NormalizeScores(NormalizeMinMax, scores)
+	if scores[0] != 1 || scores[2] != 0 || math.Abs(float64(scores[1])-0.4) > 1e-6 {
+		t.Errorf("minmax = %v, want [1 0.4 0]", scores)
+	}
+
+	same := []float32{0.7, 0.7}
+	NormalizeScores(NormalizeMinMax, same)
+	if same[0] != 1 || same[1] != 1 {
+		t.Errorf("minmax of equal scores = %v, want all 1", same)
+	}
+
+	scores = []float32{0.85, 0.7, 0.6}
+	NormalizeScores(NormalizeSigmoid, scores)
+	if math.Abs(float64(scores[1])-0.5) > 1e-6 || !(scores[0] > 0.8 && scores[0] < 1) || !(scores[2] > 0 && scores[2] < 0.3) {
+		t.Errorf("sigmoid = %v, want about [0.82 0.5 0.27]", scores)
+	}
+
+	scores = []float32{0.85, 0.7}
+	NormalizeScores(NormalizeNone, scores)
+	if scores[0] != 0.85 || scores[1] != 0.7 {
+		t.Errorf("none = %v, want scores unchanged", scores)
+	}
+}
+
+func TestSearchPageScoreNormalization(t *testing.T) {
+	database, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	store, err := NewEmbeddingStore(database, "/repo")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	embedder := newMockEmbedder(4)
+	for i := 0; i < 3; i++ {
+		chunk := Chunk{Path: fmt.Sprintf("f%d.go", i), StartLine: 1, EndLine: 2, Content: strings.Repeat("x", i+1)}
+		vectors, _ := embedder.Embed(context.Background(), []string{chunk.Content})
+		vectors[0][0] += float32(i)
+		if err := store.Save(chunk, vectors[0], embedder.ProviderID()); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	searcher := NewSemanticSearcher(store, embedder)
+	raw, err := searcher.SearchPage(context.Background(), "query", 0, 3, nil)
+	if err != nil || len(raw.Results) != 3 {
+		t.Fatalf("SearchPage() = %+v, %v, want 3 results", raw, err)
+	}
+
+	searcher.SetScoreNormalization(NormalizeMinMax)
+	got, err := searcher.SearchPage(context.Background(), "query", 0, 3, nil)
+	if err != nil || len(got.Results) != 3 {
+		t.Fatalf("SearchPage() = %+v, %v, want 3 results", got, err)
+	}
+	for i, r := range got.Results {
+		if r.Path != raw.Results[i].Path || r.RawScore == nil || *r.RawScore != raw.Results[i].Score {
+			t.Errorf("result %d = %s raw %v, want %s raw %g", i, r.Path, r.RawScore, raw.Results[i].Path, raw.Results[i].Score)
+		}
+	}
+	if got.Results[0].Score != 1 || got.Results[2].Score != 0 {
+		t.Errorf("normalized scores = %g..%g, want 1..0", got.Results[0].Score, got.Results[2].Score)
+	}
+
+	// A later page is scaled against the results before it, not its own
+	page, err := searcher.SearchPage(context.Background(), "query", 1, 1, nil)
+	if err != nil || len(page.Results) != 1 {
+		t.Fatalf("SearchPage(offset 1) = %+v, %v, want 1 result", page, err)
+	}
+	if page.Results[0].Score != got.Results[1].Score {
+		t.Errorf("page 2 score = %g, want %g as on the full page", page.Results[0].Score, got.Results[1].Score)
+	}
+
+	// Hybrid scoring reads Search, which stays raw
+	all, err := searcher.Search("query", 3)
+	if err != nil || all.Results[0].Score != raw.Results[0].Score || all.Results[0].RawScore != nil {
+		t.Errorf("Search() = %+v, %v, want raw scores", all, err)
+	}
+}
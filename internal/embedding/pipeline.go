@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"log/slog"
 	"sync"
 	"time"
 )
@@ -14,8 +15,10 @@ type EmbedResult struct {
 	Total       int           `json:"total"`        // Total chunks processed
 	CacheHits   int           `json:"cache_hits"`   // Embeddings found in cache
 	Embedded    int           `json:"embedded"`     // New embeddings generated
-	Skipped     int           `json:"skipped"`      // Chunks skipped (e.g., empty)
+	Skipped     int           `json:"skipped"`      // Chunks skipped (e.g., empty, or not reached)
 	Errors      int           `json:"errors"`       // Chunks that failed
+	Failures    []ChunkError  `json:"failures,omitempty"` // The failed chunks, where recorded
+	Replaced    []string      `json:"replaced,omitempty"` // Hashes re-embedded because their cached vector was from another model
 	Duration    time.Duration `json:"duration"`     // Total processing time
 	EmbedTime   time.Duration `json:"embed_time"`   // Time spent on embedding API
 	CacheTime   time.Duration `json:"cache_time"`   // Time spent on cache operations
@@ -33,6 +36,7 @@ type Pipeline struct {
 	// Configuration
 	batchSize int
 	maxWorkers int
+	logger     *slog.Logger
 }
 
 // PipelineOption configures a Pipeline.
@@ -56,6 +60,16 @@ func WithMaxWorkers(workers int) PipelineOption {
 	}
 }
 
+// WithLogger sets the logger for warnings that don't fail a run, such as a
+// cache counter that couldn't be updated. The default is slog.Default().
+func WithLogger(logger *slog.Logger) PipelineOption {
+	return func(p *Pipeline) {
+		if logger != nil {
+			p.logger = logger
+		}
+	}
+}
+
 // NewPipeline creates a new embedding pipeline.
 func NewPipeline(cache *EmbeddingCache, locations *LocationStore, embedder Embedder, opts ...PipelineOption) *Pipeline {
 	p := &Pipeline{
@@ -64,6 +78,7 @@ func NewPipeline(cache *EmbeddingCache, locations *LocationStore, embedder Embed
 		embedder:   embedder,
 		batchSize:  32, // Default batch size
 		maxWorkers: 1,  // Default single worker
+		logger:     slog.Default(),
 	}
 
 	for _, opt := range opts {
@@ -82,7 +97,7 @@ type PipelineChunk struct {
 
 // EmbedChunks processes chunks through the content-addressed cache pipeline.
 // 1. Computes content hashes for all chunks
-// 2. Batch looks up existing embeddings in cache
+// 2. Batch looks up existing embeddings in cache, made by the configured model
 // 3. Embeds only chunks not found in cache
 // 4. Stores new embeddings in cache
 // 5. Records all chunk locations
@@ -117,6 +132,12 @@ func (p *Pipeline) EmbedChunks(ctx context.Context, repoRoot string, chunks []Ch
 		hashSet[pc.ContentHash] = true
 	}
 
+	// Doc comments are embedded as their own content-addressed entries
+	docs := docCommentUnits(pChunks)
+	for _, dc := range docs {
+		hashSet[dc.ContentHash] = true
+	}
+
 	uniqueHashes := make([]string, 0, len(hashSet))
 	for hash := range hashSet {
 		uniqueHashes = append(uniqueHashes, hash)
@@ -128,12 +149,16 @@ func (p *Pipeline) EmbedChunks(ctx context.Context, repoRoot string, chunks []Ch
 	if err != nil {
 		return nil, fmt.Errorf("cache lookup failed: %w", err)
 	}
+	result.Replaced = p.dropOtherModels(existing)
 	result.CacheHits = len(existing)
 	result.CacheTime = time.Since(cacheStart)
+	if err := p.cache.RecordLookups(repoRoot, result.CacheHits, len(uniqueHashes)-result.CacheHits); err != nil {
+		p.logger.Warn("failed to record cache lookups", "error", err)
+	}
 
 	// 4. Identify chunks needing embedding
 	toEmbed := make([]PipelineChunk, 0)
-	for _, pc := range pChunks {
+	for _, pc := range append(pChunks, docs...) {
 		if pc.Content == "" {
 			continue
 		}
@@ -153,6 +178,9 @@ func (p *Pipeline) EmbedChunks(ctx context.Context, repoRoot string, chunks []Ch
 
 		// 6. Store in cache
 		cacheStoreStart := time.Now()
+		if err := p.clearReplaced(result.Replaced); err != nil {
+			return nil, err
+		}
 		if err := p.cache.PutBatch(newEmbeddings); err != nil {
 			return nil, fmt.Errorf("cache store failed: %w", err)
 		}
@@ -176,6 +204,8 @@ func (p *Pipeline) EmbedChunks(ctx context.Context, repoRoot string, chunks []Ch
 			NodeType:    pc.Kind,
 			NodeName:    "", // Could be extracted from chunk metadata
 			Language:    detectLanguage(pc.Path),
+			DocComment:  pc.DocComment,
+			DocHash:     docHash(pc.DocComment),
 		})
 	}
 
@@ -194,6 +224,32 @@ func (p *Pipeline) EmbedChunks(ctx context.Context, repoRoot string, chunks []Ch
 	return result, nil
 }
 
+// dropOtherModels removes cache hits made by a model other than the
+// configured one from existing, returning their hashes. Reusing them would
+// leave old-model vectors in an index being rebuilt for the new model.
+func (p *Pipeline) dropOtherModels(existing map[string]*CacheEntry) []string {
+	var replaced []string
+	for hash, entry := range existing {
+		if entry.Model != p.cache.Model() {
+			delete(existing, hash)
+			replaced = append(replaced, hash)
+		}
+	}
+	return replaced
+}
+
+// clearReplaced deletes the cached vectors of hashes about to be embedded
+// again, since PutBatch keeps existing vectors.
+func (p *Pipeline) clearReplaced(hashes []string) error {
+	// Delete in groups to stay under the database's bound parameter limit
+	for i := 0; i < len(hashes); i += 500 {
+		if err := p.cache.DeleteBatch(hashes[i:min(i+500, len(hashes))]); err != nil {
+			return fmt.Errorf("clearing replaced embeddings: %w", err)
+		}
+	}
+	return nil
+}
+
 // embedNewChunks embeds chunks that weren't found in cache.
 func (p *Pipeline) embedNewChunks(ctx context.Context, chunks []PipelineChunk) (map[string][]float32, error) {
 	if len(chunks) == 0 {
@@ -238,6 +294,35 @@ func (p *Pipeline) embedNewChunks(ctx context.Context, chunks []PipelineChunk) (
 	return result, nil
 }
 
+// Reembed embeds contents, keyed by content hash, and stores the vectors in
+// the cache in place of any existing entries, whatever model made them.
+// Locations are left alone. Returns the number of entries embedded.
+func (p *Pipeline) Reembed(ctx context.Context, contents map[string]string) (int, error) {
+	if len(contents) == 0 {
+		return 0, nil
+	}
+
+	chunks := make([]PipelineChunk, 0, len(contents))
+	hashes := make([]string, 0, len(contents))
+	for hash, content := range contents {
+		chunks = append(chunks, PipelineChunk{Chunk: Chunk{Content: content}, ContentHash: hash})
+		hashes = append(hashes, hash)
+	}
+
+	embeddings, err := p.embedNewChunks(ctx, chunks)
+	if err != nil {
+		return 0, fmt.Errorf("embedding failed: %w", err)
+	}
+	// PutBatch keeps existing vectors, so clear the old ones first
+	if err := p.cache.DeleteBatch(hashes); err != nil {
+		return 0, fmt.Errorf("cache delete failed: %w", err)
+	}
+	if err := p.cache.PutBatch(embeddings); err != nil {
+		return 0, fmt.Errorf("cache store failed: %w", err)
+	}
+	return len(embeddings), nil
+}
+
 // EmbedFile processes a single file through the pipeline.
 // Convenience method that chunks the file and processes chunks.
 func (p *Pipeline) EmbedFile(ctx context.Context, repoRoot, path string, config ChunkerConfig) (*EmbedResult, error) {
@@ -391,6 +476,12 @@ func (p *Pipeline) ParallelEmbedChunks(ctx context.Context, repoRoot string, chu
 		hashSet[pc.ContentHash] = true
 	}
 
+	// Doc comments are embedded as their own content-addressed entries
+	docs := docCommentUnits(pChunks)
+	for _, dc := range docs {
+		hashSet[dc.ContentHash] = true
+	}
+
 	uniqueHashes := make([]string, 0, len(hashSet))
 	for hash := range hashSet {
 		uniqueHashes = append(uniqueHashes, hash)
@@ -401,11 +492,12 @@ func (p *Pipeline) ParallelEmbedChunks(ctx context.Context, repoRoot string, chu
 	if err != nil {
 		return nil, fmt.Errorf("cache lookup failed: %w", err)
 	}
+	result.Replaced = p.dropOtherModels(existing)
 	result.CacheHits = len(existing)
 
 	// Identify chunks needing embedding
 	var toEmbed []PipelineChunk
-	for _, pc := range pChunks {
+	for _, pc := range append(pChunks, docs...) {
 		if pc.Content == "" {
 			continue
 		}
@@ -465,6 +557,9 @@ func (p *Pipeline) ParallelEmbedChunks(ctx context.Context, repoRoot string, chu
 		}
 
 		// Store in cache
+		if err := p.clearReplaced(result.Replaced); err != nil {
+			return nil, err
+		}
 		if err := p.cache.PutBatch(allEmbeddings); err != nil {
 			return nil, fmt.Errorf("cache store failed: %w", err)
 		}
@@ -486,6 +581,8 @@ func (p *Pipeline) ParallelEmbedChunks(ctx context.Context, repoRoot string, chu
 			ContentHash: pc.ContentHash,
 			NodeType:    pc.Kind,
 			Language:    detectLanguage(pc.Path),
+			DocComment:  pc.DocComment,
+			DocHash:     docHash(pc.DocComment),
 		})
 	}
 
@@ -504,6 +601,30 @@ func (p *Pipeline) ParallelEmbedChunks(ctx context.Context, repoRoot string, chu
 	return result, nil
 }
 
+// docCommentUnits returns one pipeline unit per chunk doc comment so the
+// comment text is embedded and cached alongside the code.
+func docCommentUnits(chunks []PipelineChunk) []PipelineChunk {
+	var units []PipelineChunk
+	for _, pc := range chunks {
+		if pc.Content == "" || pc.DocComment == "" {
+			continue
+		}
+		units = append(units, PipelineChunk{
+			Chunk:       Chunk{Path: pc.Path, Content: pc.DocComment},
+			ContentHash: HashContent(pc.DocComment),
+		})
+	}
+	return units
+}
+
+// docHash returns the cache key for a doc comment, or "" if there is none.
+func docHash(doc string) string {
+	if doc == "" {
+		return ""
+	}
+	return HashContent(doc)
+}
+
 // splitIntoBatches splits chunks into batches of the given size.
 func splitIntoBatches(chunks []PipelineChunk, batchSize int) [][]PipelineChunk {
 	var batches [][]PipelineChunk
@@ -141,6 +141,16 @@ func TestEmbedChunksWithCacheHits(t *testing.T) {
 	if embedder.embedCount != initialEmbedCount {
 		t.Errorf("embedder called unexpectedly: %d -> %d", initialEmbedCount, embedder.embedCount)
 	}
+
+	// The lifetime counters span both runs: 2 misses, then 2 hits
+	stats, err := pipeline.Cache().Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.LifetimeHits != 2 || stats.LifetimeMisses != 2 || stats.HitRatio() != 0.5 {
+		t.Errorf("lifetime hits/misses = %d/%d (ratio %v), want 2/2 (0.5)",
+			stats.LifetimeHits, stats.LifetimeMisses, stats.HitRatio())
+	}
 }
 
 func TestEmbedChunksPartialCache(t *testing.T) {
@@ -220,6 +230,39 @@ func TestEmbedChunksDuplicateContent(t *testing.T) {
 	}
 }
 
+func TestEmbedChunksDocComments(t *testing.T) {
+	pipeline, embedder := setupTestPipeline(t)
+	ctx := context.Background()
+
+	doc := "LoadConfig parses the config file."
+	chunks := []Chunk{
+		{Path: "config.go", StartLine: 3, EndLine: 10, Content: "func LoadConfig() {}", DocComment: doc},
+		{Path: "main.go", StartLine: 1, EndLine: 5, Content: "func main() {}"},
+	}
+
+	if _, err := pipeline.EmbedChunks(ctx, "/project", chunks); err != nil {
+		t.Fatalf("EmbedChunks failed: %v", err)
+	}
+
+	// Two code chunks plus one doc comment
+	if embedder.embedCount != 3 {
+		t.Errorf("embedder called %d times, want 3", embedder.embedCount)
+	}
+
+	docKey := HashContent(doc)
+	if ok, _ := pipeline.Cache().HasEntry(docKey); !ok {
+		t.Error("doc comment embedding not cached")
+	}
+
+	locs, err := pipeline.Locations().GetByPath("/project", "config.go")
+	if err != nil || len(locs) != 1 {
+		t.Fatalf("GetByPath = %v, %v", locs, err)
+	}
+	if locs[0].DocComment != doc || locs[0].DocHash != docKey {
+		t.Errorf("location doc = (%q, %q), want (%q, %q)", locs[0].DocComment, locs[0].DocHash, doc, docKey)
+	}
+}
+
 func TestEmbedChunksEmpty(t *testing.T) {
 	pipeline, _ := setupTestPipeline(t)
 	ctx := context.Background()
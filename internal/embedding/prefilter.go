@@ -0,0 +1,163 @@
+package embedding
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// DefaultPrefilterMinCandidates is the fewest keyword candidates the
+// prefilter will accept before falling back to scoring every vector.
+const DefaultPrefilterMinCandidates = 50
+
+// KeywordPrefilter is an opt-in stage of brute-force semantic search, run
+// before scoring. It keeps only chunks whose text contains at least one
+// query term, so only those are cosine-scored. When too few chunks match,
+// every chunk is scored so paraphrased queries don't lose recall.
+//
+// It narrows scoring, not loading: every vector is still read from the
+// store, and chunk text is read from disk to check it, so it helps when
+// scoring dominates, as with many high-dimension vectors.
+type KeywordPrefilter struct {
+	// MinCandidates is the minimum number of matching chunks (and at least
+	// the search limit) required to use the filtered set
+	MinCandidates int
+
+	// ChunkText returns the text of a chunk. If nil, lines are read from
+	// disk with the path resolved against repoRoot.
+	ChunkText func(repoRoot, path string, startLine, endLine int) (string, error)
+}
+
+// NewKeywordPrefilter creates a prefilter that reads chunk text from disk.
+func NewKeywordPrefilter(minCandidates int) *KeywordPrefilter {
+	if minCandidates <= 0 {
+		minCandidates = DefaultPrefilterMinCandidates
+	}
+	return &KeywordPrefilter{MinCandidates: minCandidates}
+}
+
+// Filter returns the records whose text contains any query term.
+// Returns records unchanged if the query has no usable terms or fewer
+// than max(MinCandidates, limit) records match.
+//
+// Records without a RepoRoot (single-repo queries) or keyed repoID are read
+// under dir. Other repos' records are read under their RepoRoot when it is
+// a path; those keyed by a git remote can't be located and are kept.
+func (p *KeywordPrefilter) Filter(records []EmbeddingRecord, query string, limit int, repoID, dir string) []EmbeddingRecord {
+	terms := queryTerms(query)
+	if len(terms) == 0 {
+		return records
+	}
+
+	textFn := p.ChunkText
+	if textFn == nil {
+		textFn = newFileLineReader().chunkText
+	}
+
+	candidates := make([]EmbeddingRecord, 0, len(records)/4)
+	for _, r := range records {
+		root := r.RepoRoot
+		switch {
+		case root == "" || root == repoID:
+			root = dir
+		case !filepath.IsAbs(root):
+			candidates = append(candidates, r) // Keyed by remote, location unknown
+			continue
+		}
+
+		text, err := textFn(root, r.Path, r.StartLine, r.EndLine)
+		if err != nil {
+			// Can't check it - keep it rather than silently dropping it
+			candidates = append(candidates, r)
+			continue
+		}
+		if containsAny(strings.ToLower(text), terms) {
+			candidates = append(candidates, r)
+		}
+	}
+
+	minCandidates := p.MinCandidates
+	if limit > minCandidates {
+		minCandidates = limit
+	}
+	if len(candidates) < minCandidates {
+		return records
+	}
+	return candidates
+}
+
+// queryTerms splits a query into lowercase identifier-like terms, dropping
+// short tokens and common English words that match nearly every chunk.
+func queryTerms(query string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+
+	seen := make(map[string]bool)
+	var terms []string
+	for _, f := range fields {
+		if len(f) < 3 || prefilterStopwords[f] || seen[f] {
+			continue
+		}
+		seen[f] = true
+		terms = append(terms, f)
+	}
+	return terms
+}
+
+var prefilterStopwords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "that": true,
+	"this": true, "from": true, "into": true, "how": true, "what": true,
+	"where": true, "when": true, "which": true, "does": true, "are": true,
+	"was": true, "were": true, "not": true, "its": true,
+}
+
+// containsAny reports whether text contains any of the terms.
+func containsAny(text string, terms []string) bool {
+	for _, t := range terms {
+		if strings.Contains(text, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileLineReader reads chunk lines from disk, caching each file's lines
+// for the duration of one filter pass.
+type fileLineReader struct {
+	files map[string][]string
+}
+
+func newFileLineReader() *fileLineReader {
+	return &fileLineReader{files: make(map[string][]string)}
+}
+
+// chunkText returns lines startLine..endLine (1-indexed, inclusive) of a file.
+func (f *fileLineReader) chunkText(repoRoot, path string, startLine, endLine int) (string, error) {
+	fullPath := path
+	if !filepath.IsAbs(path) && repoRoot != "" {
+		fullPath = filepath.Join(repoRoot, path)
+	}
+
+	lines, ok := f.files[fullPath]
+	if !ok {
+		content, err := os.ReadFile(fullPath)
+		if err != nil {
+			return "", err
+		}
+		lines = strings.Split(string(content), "\n")
+		f.files[fullPath] = lines
+	}
+
+	if startLine < 1 {
+		startLine = 1
+	}
+	if endLine > len(lines) {
+		endLine = len(lines)
+	}
+	if startLine > endLine {
+		return "", nil
+	}
+	return strings.Join(lines[startLine-1:endLine], "\n"), nil
+}
@@ -0,0 +1,262 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"codetect/internal/db"
+)
+
+// bagOfWordsEmbedder hashes each token into one dimension so that texts sharing
+// words are similar. Every vector has a shared bias component, so unrelated
+// chunks still score slightly above zero, as with real models.
+type bagOfWordsEmbedder struct {
+	dims int
+}
+
+func (b *bagOfWordsEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	result := make([][]float32, len(texts))
+	for i, text := range texts {
+		vec := make([]float32, b.dims)
+		vec[0] = 0.5
+		for _, tok := range queryTerms(text) {
+			h := fnv.New32a()
+			h.Write([]byte(tok))
+			vec[1+int(h.Sum32())%(b.dims-1)]++
+		}
+		result[i] = vec
+	}
+	return result, nil
+}
+
+func (b *bagOfWordsEmbedder) Available() bool    { return true }
+func (b *bagOfWordsEmbedder) ProviderID() string { return "mock:bow" }
+func (b *bagOfWordsEmbedder) Dimensions() int    { return b.dims }
+
+// setupPrefilterFixture writes a small repo of topic-themed chunks and
+// embeds them, returning a searcher over it.
+func setupPrefilterFixture(t *testing.T) *SemanticSearcher {
+	t.Helper()
+
+	topics := [][]string{
+		{"parse", "config", "yaml", "file", "defaults"},
+		{"retry", "backoff", "jitter", "attempt", "timeout"},
+		{"render", "template", "html", "escape", "layout"},
+		{"hash", "sha256", "digest", "checksum", "verify"},
+		{"socket", "listen", "accept", "connection", "close"},
+		{"sort", "compare", "swap", "pivot", "partition"},
+		{"cache", "evict", "lru", "capacity", "entry"},
+		{"token", "lexer", "scan", "rune", "keyword"},
+	}
+
+	root := t.TempDir()
+	embedder := &bagOfWordsEmbedder{dims: 768}
+
+	database, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	store, err := NewEmbeddingStore(database, root)
+	if err != nil {
+		t.Fatalf("creating store: %v", err)
+	}
+
+	var chunks []Chunk
+	for f := 0; f < 40; f++ {
+		var lines []string
+		for c := 0; c < 5; c++ {
+			words := topics[(f+c)%len(topics)]
+			start := len(lines) + 1
+			lines = append(lines,
+				fmt.Sprintf("func fn%d_%d() {", f, c),
+				fmt.Sprintf("\t// %s %s", words[c%len(words)], words[(c+1)%len(words)]),
+				fmt.Sprintf("\tuse(%s, %s)", words[(c+2)%len(words)], words[(c+3)%len(words)]),
+				"}",
+			)
+			chunks = append(chunks, Chunk{
+				Path:      fmt.Sprintf("file%02d.go", f),
+				StartLine: start,
+				EndLine:   len(lines),
+				Content:   strings.Join(lines[start-1:], "\n"),
+				Kind:      "function",
+			})
+		}
+		path := filepath.Join(root, fmt.Sprintf("file%02d.go", f))
+		if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+			t.Fatalf("writing fixture: %v", err)
+		}
+	}
+
+	contents := make([]string, len(chunks))
+	for i, c := range chunks {
+		contents[i] = c.Content
+	}
+	embeddings, err := embedder.Embed(context.Background(), contents)
+	if err != nil {
+		t.Fatalf("embedding fixture: %v", err)
+	}
+	if err := store.SaveBatch(chunks, embeddings, embedder.ProviderID()); err != nil {
+		t.Fatalf("saving fixture: %v", err)
+	}
+
+	return NewSemanticSearcher(store, embedder)
+}
+
+func TestKeywordPrefilterRecall(t *testing.T) {
+	searcher := setupPrefilterFixture(t)
+	ctx := context.Background()
+	limit := 10
+
+	queries := []string{
+		"parse the config file",
+		"retry with backoff",
+		"verify sha256 checksum",
+		"evict lru cache entry",
+		"quicksort algorithm", // no literal overlap: exercises fallback
+	}
+
+	records, err := searcher.Store().GetAll()
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+
+	prefilter := NewKeywordPrefilter(limit)
+	var totalRecall float64
+	for _, q := range queries {
+		searcher.SetKeywordPrefilter(nil)
+		full, err := searcher.SearchWithContext(ctx, q, limit)
+		if err != nil {
+			t.Fatalf("full search %q: %v", q, err)
+		}
+
+		searcher.SetKeywordPrefilter(prefilter)
+		filtered, err := searcher.SearchWithContext(ctx, q, limit)
+		if err != nil {
+			t.Fatalf("prefiltered search %q: %v", q, err)
+		}
+
+		want := make(map[string]bool)
+		for _, r := range full.Results {
+			want[fmt.Sprintf("%s:%d", r.Path, r.StartLine)] = true
+		}
+		hits := 0
+		for _, r := range filtered.Results {
+			if want[fmt.Sprintf("%s:%d", r.Path, r.StartLine)] {
+				hits++
+			}
+		}
+
+		recall := 1.0
+		if len(want) > 0 {
+			recall = float64(hits) / float64(len(want))
+		}
+		totalRecall += recall
+
+		scored := len(prefilter.Filter(records, q, limit, searcher.Store().repoID, searcher.Store().repoRoot))
+		t.Logf("query %q: recall@%d=%.2f, scored %d/%d chunks", q, limit, recall, scored, len(records))
+	}
+
+	if mean := totalRecall / float64(len(queries)); mean < 0.9 {
+		t.Errorf("mean recall@%d = %.2f, want >= 0.9", limit, mean)
+	}
+}
+
+func TestKeywordPrefilterFilter(t *testing.T) {
+	text := map[string]string{
+		"a.go": "func ParseConfig() {}",
+		"b.go": "func Render() {}",
+		"c.go": "func LoadConfigFile() {}",
+	}
+	p := &KeywordPrefilter{
+		MinCandidates: 1,
+		ChunkText: func(repoRoot, path string, start, end int) (string, error) {
+			if path == "missing.go" {
+				return "", os.ErrNotExist
+			}
+			return text[path], nil
+		},
+	}
+
+	records := []EmbeddingRecord{{Path: "a.go"}, {Path: "b.go"}, {Path: "c.go"}, {Path: "missing.go"}}
+
+	got := p.Filter(records, "config", 1, "/repo", "/repo")
+	if len(got) != 3 || got[0].Path != "a.go" || got[1].Path != "c.go" || got[2].Path != "missing.go" {
+		t.Errorf("Filter(config) = %+v, want a.go, c.go and unreadable missing.go", got)
+	}
+
+	// Too few candidates falls back to the full set
+	p.MinCandidates = 10
+	if got := p.Filter(records, "config", 1, "/repo", "/repo"); len(got) != len(records) {
+		t.Errorf("Filter below MinCandidates returned %d records, want %d", len(got), len(records))
+	}
+
+	// Queries without usable terms are not filtered
+	p.MinCandidates = 1
+	if got := p.Filter(records, "how is it", 1, "/repo", "/repo"); len(got) != len(records) {
+		t.Errorf("Filter with only stopwords returned %d records, want %d", len(got), len(records))
+	}
+}
+
+func TestKeywordPrefilterCrossRepoRoots(t *testing.T) {
+	var roots []string
+	p := &KeywordPrefilter{
+		MinCandidates: 1,
+		ChunkText: func(repoRoot, path string, start, end int) (string, error) {
+			roots = append(roots, repoRoot)
+			return "config", nil
+		},
+	}
+
+	records := []EmbeddingRecord{
+		{RepoRoot: "github.com/org/app", Path: "a.go"},   // This repo, keyed by remote
+		{RepoRoot: "/src/other", Path: "b.go"},           // Keyed by path
+		{RepoRoot: "github.com/org/third", Path: "c.go"}, // Can't be located
+	}
+	got := p.Filter(records, "config", 1, "github.com/org/app", "/src/app")
+	if len(got) != 3 {
+		t.Errorf("Filter() = %+v, want all three kept", got)
+	}
+	if len(roots) != 2 || roots[0] != "/src/app" || roots[1] != "/src/other" {
+		t.Errorf("chunk text read under %v, want [/src/app /src/other]", roots)
+	}
+}
+
+func TestKeywordPrefilterLimitsScoredRows(t *testing.T) {
+	searcher := setupPrefilterFixture(t)
+	var scored int
+	searcher.ScoreFunc = func(query, vector []float32) float32 {
+		scored++
+		return CosineSimilarity(query, vector)
+	}
+
+	records, err := searcher.Store().GetAll()
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	const query = "verify sha256 checksum"
+	searcher.SetKeywordPrefilter(NewKeywordPrefilter(10))
+	result, err := searcher.SearchWithContext(context.Background(), query, 10)
+	if err != nil || result.Error != "" {
+		t.Fatalf("search: %+v, %v", result, err)
+	}
+
+	want := len(NewKeywordPrefilter(10).Filter(records, query, 10, searcher.Store().repoID, searcher.Store().repoRoot))
+	if scored != want || scored >= len(records) {
+		t.Errorf("scored %d of %d rows, want only the %d keyword candidates", scored, len(records), want)
+	}
+}
+
+func TestQueryTerms(t *testing.T) {
+	got := queryTerms("How does the ParseConfig() handle YAML-files? yaml")
+	want := []string{"parseconfig", "handle", "yaml", "files"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("queryTerms() = %v, want %v", got, want)
+	}
+}
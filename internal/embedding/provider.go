@@ -24,6 +24,16 @@ type ProviderConfig struct {
 	LiteLLMKey string   // API key for LiteLLM
 	Model      string   // model name (provider-specific default if empty)
 	Dimensions int      // embedding dimensions (0 = auto-detect)
+
+	// TruncateDim keeps only the first TruncateDim dimensions of each
+	// embedding (Matryoshka truncation), for models that support it.
+	// 0 keeps full-size embeddings.
+	TruncateDim int
+
+	// Fallback lists providers to try, in order, when Provider fails. They
+	// share the rest of this configuration and must serve the same model;
+	// see FallbackEmbedder.
+	Fallback []Provider
 }
 
 // DefaultProviderConfig returns the default provider configuration
@@ -43,14 +53,9 @@ func LoadConfigFromEnv() ProviderConfig {
 
 	// Provider selection
 	if p := os.Getenv("CODETECT_EMBEDDING_PROVIDER"); p != "" {
-		switch strings.ToLower(p) {
-		case "ollama":
-			cfg.Provider = ProviderOllama
-		case "litellm":
-			cfg.Provider = ProviderLiteLLM
-		case "off", "disabled", "none":
-			cfg.Provider = ProviderOff
-		default:
+		if provider, ok := ParseProvider(p); ok {
+			cfg.Provider = provider
+		} else {
 			// Log warning but use default
 			fmt.Fprintf(os.Stderr, "warning: unknown embedding provider %q, using ollama\n", p)
 		}
@@ -81,11 +86,76 @@ func LoadConfigFromEnv() ProviderConfig {
 		}
 	}
 
+	// Matryoshka truncation
+	if dim := os.Getenv("CODETECT_EMBEDDING_TRUNCATE_DIM"); dim != "" {
+		if d, err := strconv.Atoi(dim); err == nil && d > 0 {
+			cfg.TruncateDim = d
+		} else {
+			fmt.Fprintf(os.Stderr, "warning: invalid CODETECT_EMBEDDING_TRUNCATE_DIM %q, using full-size embeddings\n", dim)
+		}
+	}
+
+	// Fallback chain
+	if chain := os.Getenv("CODETECT_EMBEDDING_FALLBACK"); chain != "" {
+		for _, name := range strings.Split(chain, ",") {
+			if provider, ok := ParseProvider(name); ok && provider.IsEnabled() {
+				cfg.Fallback = append(cfg.Fallback, provider)
+			} else {
+				fmt.Fprintf(os.Stderr, "warning: ignoring embedding fallback provider %q\n", name)
+			}
+		}
+	}
+
 	return cfg
 }
 
-// NewEmbedder creates an Embedder from the configuration
+// NewEmbedder creates an Embedder from the configuration. With Fallback
+// set, the providers are chained in a FallbackEmbedder, which fails unless
+// they all serve the same model. With TruncateDim set, the embedder is
+// wrapped in a TruncatingEmbedder, which fails for models that don't
+// support truncation.
 func NewEmbedder(cfg ProviderConfig) (Embedder, error) {
+	e, err := newProviderEmbedder(cfg)
+	if err != nil || !cfg.Provider.IsEnabled() {
+		return e, err
+	}
+	if len(cfg.Fallback) > 0 {
+		if e, err = newFallbackChain(e, cfg); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.TruncateDim <= 0 {
+		return e, nil
+	}
+	return NewTruncatingEmbedder(e, cfg.TruncateDim)
+}
+
+// newFallbackChain chains primary with an embedder for each of cfg's
+// fallback providers, skipping repeats of one already in the chain
+func newFallbackChain(primary Embedder, cfg ProviderConfig) (Embedder, error) {
+	links := []Embedder{primary}
+	seen := map[Provider]bool{cfg.Provider: true}
+	for _, provider := range cfg.Fallback {
+		if seen[provider] || !provider.IsEnabled() {
+			continue
+		}
+		seen[provider] = true
+		linkCfg := cfg
+		linkCfg.Provider = provider
+		link, err := newProviderEmbedder(linkCfg)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	if len(links) == 1 {
+		return primary, nil
+	}
+	return NewFallbackEmbedder(links...)
+}
+
+// newProviderEmbedder creates the provider's own Embedder
+func newProviderEmbedder(cfg ProviderConfig) (Embedder, error) {
 	switch cfg.Provider {
 	case ProviderOff:
 		return &NullEmbedder{}, nil
@@ -124,6 +194,21 @@ func NewEmbedderFromEnv() (Embedder, error) {
 	return NewEmbedder(LoadConfigFromEnv())
 }
 
+// ParseProvider parses a provider name as accepted by
+// CODETECT_EMBEDDING_PROVIDER, case-insensitively
+func ParseProvider(name string) (Provider, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "ollama":
+		return ProviderOllama, true
+	case "litellm":
+		return ProviderLiteLLM, true
+	case "off", "disabled", "none":
+		return ProviderOff, true
+	default:
+		return "", false
+	}
+}
+
 // ProviderName returns a human-readable name for the provider
 func (p Provider) String() string {
 	switch p {
@@ -116,6 +116,16 @@ func TestLoadConfigFromEnv(t *testing.T) {
 		}
 	})
 
+	t.Run("reads fallback chain", func(t *testing.T) {
+		t.Setenv("CODETECT_EMBEDDING_FALLBACK", "ollama, bogus,litellm")
+
+		cfg := LoadConfigFromEnv()
+
+		if len(cfg.Fallback) != 2 || cfg.Fallback[0] != ProviderOllama || cfg.Fallback[1] != ProviderLiteLLM {
+			t.Errorf("expected Fallback=[ollama litellm], got %v", cfg.Fallback)
+		}
+	})
+
 	t.Run("ignores invalid dimensions", func(t *testing.T) {
 		os.Setenv("CODETECT_EMBEDDING_DIMENSIONS", "invalid")
 
@@ -236,6 +246,65 @@ func TestNewEmbedder(t *testing.T) {
 			t.Errorf("expected default model %s, got %s", DefaultLiteLLMModel, client.Model())
 		}
 	})
+
+	t.Run("wraps Matryoshka models when truncating", func(t *testing.T) {
+		cfg := ProviderConfig{
+			Provider:    ProviderOllama,
+			OllamaURL:   DefaultOllamaURL,
+			Model:       "nomic-embed-text",
+			TruncateDim: 256,
+		}
+		embedder, err := NewEmbedder(cfg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := embedder.(*TruncatingEmbedder); !ok {
+			t.Errorf("expected *TruncatingEmbedder, got %T", embedder)
+		}
+		if embedder.ProviderID() != "ollama:nomic-embed-text@256" || embedder.Dimensions() != 256 {
+			t.Errorf("expected ollama:nomic-embed-text@256 with 256 dimensions, got %s with %d", embedder.ProviderID(), embedder.Dimensions())
+		}
+
+		cfg.Model = "bge-m3"
+		if _, err := NewEmbedder(cfg); err == nil {
+			t.Error("expected an error truncating a model without Matryoshka support")
+		}
+	})
+
+	t.Run("chains fallback providers serving the same model", func(t *testing.T) {
+		cfg := ProviderConfig{
+			Provider:   ProviderLiteLLM,
+			LiteLLMURL: DefaultLiteLLMURL,
+			OllamaURL:  DefaultOllamaURL,
+			Model:      "nomic-embed-text",
+			Dimensions: 768,
+			Fallback:   []Provider{ProviderLiteLLM, ProviderOllama},
+		}
+		embedder, err := NewEmbedder(cfg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		chain, ok := embedder.(*FallbackEmbedder)
+		if !ok {
+			t.Fatalf("expected *FallbackEmbedder, got %T", embedder)
+		}
+		if len(chain.links) != 2 || chain.ProviderID() != "litellm:nomic-embed-text" {
+			t.Errorf("expected litellm then ollama under litellm's ID, got %d links as %s", len(chain.links), chain.ProviderID())
+		}
+	})
+
+	t.Run("refuses a fallback serving a different model", func(t *testing.T) {
+		// The providers' default models differ
+		cfg := ProviderConfig{
+			Provider:   ProviderLiteLLM,
+			LiteLLMURL: DefaultLiteLLMURL,
+			OllamaURL:  DefaultOllamaURL,
+			Fallback:   []Provider{ProviderOllama},
+		}
+		if _, err := NewEmbedder(cfg); err == nil {
+			t.Error("expected an error falling back to a different model")
+		}
+	})
 }
 
 func TestProviderString(t *testing.T) {
@@ -259,6 +328,29 @@ func TestProviderString(t *testing.T) {
 	}
 }
 
+func TestParseProvider(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   Provider
+		wantOK bool
+	}{
+		{"ollama", ProviderOllama, true},
+		{"LiteLLM", ProviderLiteLLM, true},
+		{"none", ProviderOff, true},
+		{" off ", ProviderOff, true},
+		{"openai", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseProvider(tt.name)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ParseProvider(%q) = %q, %v, want %q, %v", tt.name, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
 func TestProviderIsEnabled(t *testing.T) {
 	if !ProviderOllama.IsEnabled() {
 		t.Error("ProviderOllama should be enabled")
@@ -0,0 +1,52 @@
+package embedding
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RecencyHalfLife is the file age at which half of the recency boost is
+// gone.
+const RecencyHalfLife = 30 * 24 * time.Hour
+
+// recencyMultiplier returns the factor a score is multiplied by for a file
+// last modified age ago: 1 when just modified, decaying toward 1-weight
+// with a half-life of RecencyHalfLife.
+func recencyMultiplier(age time.Duration, weight float64) float64 {
+	age = max(age, 0)
+	decay := math.Exp2(-age.Hours() / RecencyHalfLife.Hours())
+	return 1 - weight + weight*decay
+}
+
+// recencyBoost re-scores one search's results by file mtime, statting each
+// file once.
+type recencyBoost struct {
+	root   string // Directory result paths are relative to
+	weight float64
+	now    time.Time
+	cache  map[string]float64
+}
+
+func newRecencyBoost(root string, weight float64) *recencyBoost {
+	return &recencyBoost{root: root, weight: weight, now: time.Now(), cache: make(map[string]float64)}
+}
+
+// multiplier returns the recency factor for path. Files that can't be
+// stat'd, such as ones deleted since indexing, get no boost.
+func (b *recencyBoost) multiplier(path string) float64 {
+	if m, ok := b.cache[path]; ok {
+		return m
+	}
+	file := path
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(b.root, file)
+	}
+	m := 1 - b.weight
+	if info, err := os.Stat(file); err == nil {
+		m = recencyMultiplier(b.now.Sub(info.ModTime()), b.weight)
+	}
+	b.cache[path] = m
+	return m
+}
@@ -0,0 +1,53 @@
+package embedding
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecencyMultiplier(t *testing.T) {
+	tests := []struct {
+		age      time.Duration
+		weight   float64
+		expected float64
+	}{
+		{0, 0.5, 1},
+		{-time.Hour, 0.5, 1}, // mtime in the future
+		{RecencyHalfLife, 0.5, 0.75},
+		{2 * RecencyHalfLife, 1, 0.25},
+		{100 * RecencyHalfLife, 0.2, 0.8},
+		{RecencyHalfLife, 0, 1},
+	}
+	for _, tt := range tests {
+		if got := recencyMultiplier(tt.age, tt.weight); math.Abs(got-tt.expected) > 1e-6 {
+			t.Errorf("recencyMultiplier(%v, %g) = %g, want %g", tt.age, tt.weight, got, tt.expected)
+		}
+	}
+}
+
+func TestRecencyBoost(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"new.go", "old.go"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("package a\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-10 * RecencyHalfLife)
+	if err := os.Chtimes(filepath.Join(dir, "old.go"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	boost := newRecencyBoost(dir, 0.5)
+	if m := boost.multiplier("new.go"); m < 0.99 {
+		t.Errorf("multiplier(new.go) = %g, want ~1", m)
+	}
+	if m := boost.multiplier("old.go"); m > 0.51 {
+		t.Errorf("multiplier(old.go) = %g, want ~0.5", m)
+	}
+	if m := boost.multiplier("missing.go"); m != 0.5 {
+		t.Errorf("multiplier(missing.go) = %g, want no boost (0.5)", m)
+	}
+}
@@ -2,20 +2,27 @@ package embedding
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"log/slog"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	"codetect/internal/config"
 )
 
 // SemanticResult represents a search result from semantic search
 type SemanticResult struct {
-	Path      string  `json:"path"`
-	StartLine int     `json:"start_line"`
-	EndLine   int     `json:"end_line"`
-	Snippet   string  `json:"snippet"`
-	Score     float32 `json:"score"`
+	Path      string   `json:"path"`
+	StartLine int      `json:"start_line"`
+	EndLine   int      `json:"end_line"`
+	Snippet   string   `json:"snippet"`
+	Score     float32  `json:"score"`
+	RawScore  *float32 `json:"raw_score,omitempty"` // Similarity before score normalization; nil when scores are raw
 }
 
 // SemanticSearchResult is the full result of a semantic search
@@ -23,20 +30,113 @@ type SemanticSearchResult struct {
 	Available bool             `json:"available"`
 	Results   []SemanticResult `json:"results"`
 	Error     string           `json:"error,omitempty"`
+	Warning   string           `json:"warning,omitempty"`
+	HasMore   bool             `json:"has_more,omitempty"` // More results follow this page (SearchPage)
 }
 
 // SemanticSearcher performs semantic search over embedded code
 type SemanticSearcher struct {
-	store    *EmbeddingStore
-	embedder Embedder
+	store      *EmbeddingStore
+	embedder   Embedder
+	prefilter  *KeywordPrefilter // Optional keyword stage before brute-force scoring
+	breaker    CircuitBreakerConfig
+	flushEvery int    // Save embeddings every N successful chunks during indexing
+	model      string // Only search embeddings made by this model ("" for all)
+	logger     *slog.Logger
+
+	excludeTests  bool    // Leave test files (see IsTestPath) out of results
+	recencyWeight float64 // Boost for recently modified files (0 disables)
+	normalization string  // How returned scores are rescaled (see NormalizeScores)
+
+	// ScoreFunc ranks embeddings against the query. nil means
+	// CosineSimilarity.
+	ScoreFunc ScoreFunc
 }
 
 // NewSemanticSearcher creates a new semantic searcher from an EmbeddingStore.
+// Searches only consider embeddings made by the embedder's model.
 func NewSemanticSearcher(store *EmbeddingStore, embedder Embedder) *SemanticSearcher {
-	return &SemanticSearcher{
-		store:    store,
-		embedder: embedder,
+	s := &SemanticSearcher{
+		store:      store,
+		embedder:   embedder,
+		breaker:    DefaultCircuitBreakerConfig(),
+		flushEvery: DefaultCheckpointInterval,
+		logger:     slog.Default(),
 	}
+	s.model = s.ProviderID()
+	return s
+}
+
+// SetModelFilter restricts searches to embeddings made by model, a provider
+// ID as returned by Embedder.ProviderID. Pass "" to search every model's
+// embeddings, which is only meaningful if they share a vector space.
+func (s *SemanticSearcher) SetModelFilter(model string) {
+	s.model = model
+}
+
+// SetExcludeTests leaves embeddings of test files, as judged by IsTestPath,
+// out of search results.
+func (s *SemanticSearcher) SetExcludeTests(exclude bool) {
+	s.excludeTests = exclude
+}
+
+// SetRecencyWeight re-ranks results toward recently modified files: each
+// score is multiplied by a factor decaying from 1 for a file modified just
+// now to 1-weight for a long-dormant one. weight is in [0, 1]; 0 disables.
+func (s *SemanticSearcher) SetRecencyWeight(weight float64) {
+	s.recencyWeight = weight
+}
+
+// SetScoreNormalization rescales the scores of returned results with
+// NormalizeMinMax or NormalizeSigmoid, keeping the similarity in RawScore.
+// It applies to the results SearchPage, SearchInFile and SearchAcrossRepos
+// return, after ranking, so the order is the same either way; Search and
+// SearchWithSnippets, which feed hybrid scoring, keep raw similarities.
+// NormalizeNone, the default, returns raw similarities everywhere. With
+// minmax, SearchPage scales against every result up to the end of the page,
+// so the top result is 1 on any page but the 0 point follows the deepest
+// result fetched.
+func (s *SemanticSearcher) SetScoreNormalization(method string) {
+	s.normalization = method
+}
+
+// SetLogger sets the logger that indexing warnings, such as chunks that
+// failed to embed, are written to. The default is slog.Default().
+func (s *SemanticSearcher) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
+// topK ranks vectors against query with ScoreFunc, or cosine similarity by
+// default, which norms the query once rather than per candidate
+func (s *SemanticSearcher) topK(query []float32, vectors [][]float32, k int) []ScoredItem {
+	if s.ScoreFunc != nil {
+		return TopKByScore(query, vectors, k, s.ScoreFunc)
+	}
+	return TopKByCosineSimilarity(query, vectors, k)
+}
+
+// DefaultCheckpointInterval is how many successfully embedded chunks
+// IndexChunks and IndexChunksParallel buffer before saving them.
+const DefaultCheckpointInterval = 500
+
+// SetCheckpointInterval sets how many embedded chunks are buffered before
+// they are saved, so an interrupted run keeps most of its work and a re-run
+// skips it. n <= 0 saves only at the end of the run.
+func (s *SemanticSearcher) SetCheckpointInterval(n int) {
+	s.flushEvery = n
+}
+
+// SetCircuitBreaker configures when IndexChunks and IndexChunksParallel
+// pause for a failing provider and when they give up.
+func (s *SemanticSearcher) SetCircuitBreaker(cfg CircuitBreakerConfig) {
+	s.breaker = cfg
+}
+
+// SetKeywordPrefilter enables two-stage search: loaded chunks are narrowed
+// to those containing a query term before cosine scoring. Pass nil to
+// disable.
+func (s *SemanticSearcher) SetKeywordPrefilter(p *KeywordPrefilter) {
+	s.prefilter = p
 }
 
 // Available checks if semantic search is available
@@ -52,12 +152,40 @@ func (s *SemanticSearcher) Search(query string, limit int) (*SemanticSearchResul
 	return s.SearchWithContext(context.Background(), query, limit)
 }
 
-// SearchWithContext performs a semantic search with a custom context
+// SearchWithContext performs a semantic search with a custom context.
+// Identical searches of the same repo already in flight, e.g. from
+// concurrent agent tool calls, are coalesced into one embed and scan whose
+// result every caller gets a copy of. The shared search outlives any one
+// caller's ctx; each caller returns early when its own ctx is done.
 func (s *SemanticSearcher) SearchWithContext(ctx context.Context, query string, limit int) (*SemanticSearchResult, error) {
 	if limit <= 0 {
 		limit = 10
 	}
 
+	// Searches with a custom scorer can't be told apart, so they run alone
+	if s.ScoreFunc != nil {
+		return s.search(ctx, query, limit)
+	}
+
+	key := fmt.Sprintf("%s\x00%d\x00%s\x00%t\x00%t\x00%g\x00%d\x00%s", s.store.repoRoot, s.store.VectorDimensions(), s.model, s.prefilter != nil, s.excludeTests, s.recencyWeight, limit, query)
+	result, err, shared := semanticFlights.do(ctx, key, func(ctx context.Context) (*SemanticSearchResult, error) {
+		return s.search(ctx, query, limit)
+	})
+	if shared && result != nil {
+		result = result.clone()
+	}
+	return result, err
+}
+
+// clone copies a result so callers can fill in snippets independently
+func (r *SemanticSearchResult) clone() *SemanticSearchResult {
+	c := *r
+	c.Results = slices.Clone(r.Results)
+	return &c
+}
+
+// search performs a semantic search, uncoalesced
+func (s *SemanticSearcher) search(ctx context.Context, query string, limit int) (*SemanticSearchResult, error) {
 	// Check availability
 	if !s.Available() {
 		return &SemanticSearchResult{
@@ -67,8 +195,15 @@ func (s *SemanticSearcher) SearchWithContext(ctx context.Context, query string,
 		}, nil
 	}
 
-	// Get all embeddings
-	records, err := s.store.GetAll()
+	// Get all embeddings made by the query's model
+	records, err := s.store.GetAllForModel(s.model)
+	if errors.Is(err, ErrNoEmbeddingsForDimension) {
+		return &SemanticSearchResult{
+			Available: true,
+			Results:   []SemanticResult{},
+			Error:     fmt.Sprintf("No embeddings indexed for %d-dimension vectors. Run 'codetect-index embed' first.", s.store.VectorDimensions()),
+		}, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("getting embeddings: %w", err)
 	}
@@ -77,7 +212,7 @@ func (s *SemanticSearcher) SearchWithContext(ctx context.Context, query string,
 		return &SemanticSearchResult{
 			Available: true,
 			Results:   []SemanticResult{},
-			Error:     "No embeddings indexed. Run 'make embed' first.",
+			Error:     s.noRecordsMessage("No embeddings indexed. Run 'make embed' first."),
 		}, nil
 	}
 
@@ -91,33 +226,157 @@ func (s *SemanticSearcher) SearchWithContext(ctx context.Context, query string,
 	}
 	queryEmbedding := queryEmbeddings[0]
 
+	// Drop test files before ranking, so they don't take up result slots
+	if s.excludeTests {
+		records = slices.DeleteFunc(records, func(r EmbeddingRecord) bool {
+			return IsTestPath(s.store.relPath(r.Path))
+		})
+	}
+
+	// Optionally narrow to keyword candidates before scoring
+	if s.prefilter != nil {
+		records = s.prefilter.Filter(records, query, limit, s.store.repoID, s.store.repoRoot)
+	}
+
 	// Build vector list for search
 	vectors := make([][]float32, len(records))
 	for i, r := range records {
 		vectors[i] = r.Embedding
 	}
 
-	// Find top-k most similar
-	topK := TopKByCosineSimilarity(queryEmbedding, vectors, limit)
+	// The recency boost only lowers scores, so a chunk outside the top
+	// fetch by similarity can't beat the fetch's lowest similarity. Widen
+	// until the limit-th boosted score is at least that, so the boost ranks
+	// the whole index rather than just the first limit results.
+	var boost *recencyBoost
+	if s.recencyWeight > 0 {
+		boost = newRecencyBoost(s.store.repoRoot, s.recencyWeight)
+	}
+	var results []SemanticResult
+	for fetch := limit; ; fetch *= 2 {
+		// Find top-k most similar
+		topK := s.topK(queryEmbedding, vectors, fetch)
+
+		// Build results
+		results = make([]SemanticResult, 0, len(topK))
+		for _, item := range topK {
+			if item.Score <= 0 {
+				continue // Skip zero/negative similarity
+			}
 
-	// Build results
-	results := make([]SemanticResult, 0, len(topK))
-	for _, item := range topK {
-		if item.Score <= 0 {
-			continue // Skip zero/negative similarity
+			record := records[item.Index]
+			// Older indexes may hold absolute paths; agents need repo-relative ones
+			path := s.store.relPath(record.Path)
+			snippet := getSnippet(path, record.StartLine, record.EndLine)
+
+			score := item.Score
+			if boost != nil {
+				score *= float32(boost.multiplier(path))
+			}
+			results = append(results, SemanticResult{
+				Path:      path,
+				StartLine: record.StartLine,
+				EndLine:   record.EndLine,
+				Snippet:   snippet,
+				Score:     score,
+			})
 		}
+		sortSemanticResults(results)
 
-		record := records[item.Index]
-		snippet := getSnippet(record.Path, record.StartLine, record.EndLine)
+		if boost == nil || len(topK) < fetch || len(topK) == 0 || topK[len(topK)-1].Score <= 0 ||
+			(len(results) >= limit && results[limit-1].Score >= topK[len(topK)-1].Score) {
+			break
+		}
+	}
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return &SemanticSearchResult{
+		Available: true,
+		Results:   results,
+	}, nil
+}
+
+// SearchInFile performs semantic search over the chunks of a single file,
+// scoring only that file's embeddings against the query. snippetFn fills
+// in each result's snippet as in SearchWithSnippets; nil leaves the
+// placeholder.
+func (s *SemanticSearcher) SearchInFile(ctx context.Context, path, query string, limit int, snippetFn func(path string, start, end int) string) (*SemanticSearchResult, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if !s.Available() {
+		return &SemanticSearchResult{
+			Available: false,
+			Results:   []SemanticResult{},
+			Error:     "Embedding provider not available",
+		}, nil
+	}
 
+	records, err := s.store.GetByPath(path)
+	if err != nil && isMissingTable(err) {
+		err = s.store.noEmbeddingsErr()
+	}
+	if errors.Is(err, ErrNoEmbeddingsForDimension) {
+		return &SemanticSearchResult{
+			Available: true,
+			Results:   []SemanticResult{},
+			Error:     fmt.Sprintf("No embeddings indexed for %d-dimension vectors. Run 'codetect-index embed' first.", s.store.VectorDimensions()),
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting embeddings for %s: %w", path, err)
+	}
+
+	// Only the query's model is comparable
+	if s.model != "" {
+		records = slices.DeleteFunc(records, func(r EmbeddingRecord) bool {
+			return r.Model != s.model
+		})
+	}
+	if len(records) == 0 {
+		return &SemanticSearchResult{
+			Available: true,
+			Results:   []SemanticResult{},
+			Error:     fmt.Sprintf("No embeddings indexed for %s. Check the path or run 'codetect-index embed'.", s.store.relPath(path)),
+		}, nil
+	}
+
+	queryEmbeddings, err := s.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embedding query: %w", err)
+	}
+	if len(queryEmbeddings) == 0 {
+		return nil, fmt.Errorf("no embedding returned for query")
+	}
+
+	vectors := make([][]float32, len(records))
+	for i, r := range records {
+		vectors[i] = r.Embedding
+	}
+
+	results := make([]SemanticResult, 0, limit)
+	for _, item := range s.topK(queryEmbeddings[0], vectors, limit) {
+		if item.Score <= 0 {
+			continue
+		}
+		record := records[item.Index]
+		relPath := s.store.relPath(record.Path)
+		snippet := getSnippet(relPath, record.StartLine, record.EndLine)
+		if snippetFn != nil {
+			snippet = snippetFn(relPath, record.StartLine, record.EndLine)
+		}
 		results = append(results, SemanticResult{
-			Path:      record.Path,
+			Path:      relPath,
 			StartLine: record.StartLine,
 			EndLine:   record.EndLine,
 			Snippet:   snippet,
 			Score:     item.Score,
 		})
 	}
+	sortSemanticResults(results)
+	s.normalize(results)
 
 	return &SemanticSearchResult{
 		Available: true,
@@ -143,6 +402,36 @@ func (s *SemanticSearcher) ProviderID() string {
 	return s.embedder.ProviderID()
 }
 
+// noRecordsMessage explains an empty search, naming the model filter if
+// one is set, since other models' embeddings are skipped.
+func (s *SemanticSearcher) noRecordsMessage(msg string) string {
+	if s.model == "" {
+		return msg
+	}
+	return fmt.Sprintf("No embeddings indexed for model %s. Run 'codetect-index embed' first.", s.model)
+}
+
+// rankedBefore orders search results by score, highest first, then by path
+// and start line, so equal scores (duplicate content, quantized vectors)
+// come back in the same order on every search.
+func rankedBefore(aScore float32, aPath string, aLine int, bScore float32, bPath string, bLine int) bool {
+	if aScore != bScore {
+		return aScore > bScore
+	}
+	if aPath != bPath {
+		return aPath < bPath
+	}
+	return aLine < bLine
+}
+
+// sortSemanticResults sorts results in rankedBefore order.
+func sortSemanticResults(results []SemanticResult) {
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		return rankedBefore(a.Score, a.Path, a.StartLine, b.Score, b.Path, b.StartLine)
+	})
+}
+
 // getSnippet retrieves a code snippet placeholder (truncated for display)
 func getSnippet(path string, startLine, endLine int) string {
 	// Placeholder - in real usage with SearchWithSnippets, a custom snippetFn is provided
@@ -150,14 +439,80 @@ func getSnippet(path string, startLine, endLine int) string {
 		path, startLine, endLine, endLine-startLine+1)
 }
 
-// IndexChunks embeds and stores chunks
-func (s *SemanticSearcher) IndexChunks(ctx context.Context, chunks []Chunk, progressFn func(current, total int)) error {
+// errEmptyEmbedding is recorded for chunks the provider returned no vector for
+var errEmptyEmbedding = errors.New("empty embedding")
+
+// embeddingError returns why embs, the provider's answer for one chunk,
+// can't be stored, or nil if its vector is usable
+func embeddingError(embs [][]float32) error {
+	if len(embs) == 0 || len(embs[0]) == 0 {
+		return errEmptyEmbedding
+	}
+	return validateEmbedding(embs[0])
+}
+
+// ChunkError records a chunk that was skipped because it failed to embed
+type ChunkError struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Err       error  `json:"-"`
+}
+
+func (e ChunkError) Error() string {
+	return fmt.Sprintf("%s:%d-%d: %v", e.Path, e.StartLine, e.EndLine, e.Err)
+}
+
+// skip logs a chunk that failed to embed and records it in res
+func (s *SemanticSearcher) skip(res *EmbedResult, chunk Chunk, err error) {
+	s.logger.Warn("failed to embed chunk",
+		"path", chunk.Path,
+		"start_line", chunk.StartLine,
+		"end_line", chunk.EndLine,
+		"error", err)
+	res.Errors++
+	res.Failures = append(res.Failures, ChunkError{Path: chunk.Path, StartLine: chunk.StartLine, EndLine: chunk.EndLine, Err: err})
+}
+
+// logSkipped summarizes the chunks a run skipped
+func (s *SemanticSearcher) logSkipped(res *EmbedResult) {
+	if res.Errors > 0 {
+		s.logger.Warn("skipped chunks that failed to embed", "count", res.Errors)
+	}
+}
+
+// settle records how many of the toEmbed chunks a run saved, counting
+// the rest that didn't fail as skipped, then finishes the result
+func (r *EmbedResult) settle(saved, toEmbed int, start time.Time) {
+	r.Embedded = saved
+	r.Skipped = toEmbed - r.Embedded - r.Errors
+	r.finish(start)
+}
+
+// finish fills in the duration and rates of a run that started at start
+func (r *EmbedResult) finish(start time.Time) {
+	r.Duration = time.Since(start)
+	if r.Total > 0 {
+		r.HitRate = float64(r.CacheHits) / float64(r.Total) * 100
+		r.ChunksPerSec = float64(r.Total) / r.Duration.Seconds()
+	}
+}
+
+// IndexChunks embeds and stores chunks, reporting what this run did:
+// CacheHits counts chunks already embedded, Embedded the chunks saved, and
+// Errors the chunks that failed to embed, which are logged, skipped and
+// listed in Failures. Skipped counts chunks an interrupted or abandoned run
+// never reached. The result is returned alongside any error, covering the
+// chunks handled before it.
+func (s *SemanticSearcher) IndexChunks(ctx context.Context, chunks []Chunk, progressFn func(current, total int)) (*EmbedResult, error) {
+	start := time.Now()
+	res := &EmbedResult{Total: len(chunks)}
 	if len(chunks) == 0 {
-		return nil
+		return res, nil
 	}
 
 	if !s.Available() {
-		return fmt.Errorf("embedding provider not available")
+		return res, fmt.Errorf("embedding provider not available")
 	}
 
 	providerID := s.embedder.ProviderID()
@@ -167,73 +522,93 @@ func (s *SemanticSearcher) IndexChunks(ctx context.Context, chunks []Chunk, prog
 	for _, chunk := range chunks {
 		has, err := s.store.HasEmbedding(chunk, providerID)
 		if err != nil {
-			return fmt.Errorf("checking embedding: %w", err)
+			return res, fmt.Errorf("checking embedding: %w", err)
 		}
 		if !has {
 			toEmbed = append(toEmbed, chunk)
 		}
 	}
 
+	res.CacheHits = len(chunks) - len(toEmbed)
 	if len(toEmbed) == 0 {
-		return nil // All chunks already indexed
+		res.finish(start)
+		return res, nil // All chunks already indexed
 	}
 
 	// Embed chunks with progress tracking
 	// Process one at a time for progress reporting
-	var successfulChunks []Chunk
-	var successfulEmbeddings [][]float32
-	var skippedCount int
+	batch := s.newCheckpointBatch(providerID)
+	var attempted int
+	var offlineErr, interrupted error
+	breaker := newCircuitBreaker(s.breaker, s.embedder.Available, s.logger)
+	// The chunk in flight when ctx is cancelled is allowed to finish
+	embedCtx := context.WithoutCancel(ctx)
 
 	for i, chunk := range toEmbed {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
+		if ctx.Err() != nil {
+			interrupted = ctx.Err()
+			break
 		}
 
 		if progressFn != nil {
 			progressFn(i+1, len(toEmbed))
 		}
 
-		embs, err := s.embedder.Embed(ctx, []string{chunk.Content})
+		embs, err := s.embedder.Embed(embedCtx, []string{chunk.Content})
+		attempted++
+		if berr := breaker.Record(ctx, err); berr != nil {
+			if berr == ctx.Err() {
+				interrupted = berr
+			} else {
+				offlineErr = berr
+			}
+			break
+		}
 		if err != nil {
 			// Log and skip chunks that fail to embed
-			fmt.Fprintf(os.Stderr, "\n[codetect-index] failed to embed %s:%d-%d: %v\n", chunk.Path, chunk.StartLine, chunk.EndLine, err)
-			skippedCount++
+			s.skip(res, chunk, err)
 			continue
 		}
-		if len(embs) == 0 || len(embs[0]) == 0 {
-			// Log and skip chunks that return empty embeddings
-			fmt.Fprintf(os.Stderr, "\n[codetect-index] empty embedding for %s:%d-%d\n", chunk.Path, chunk.StartLine, chunk.EndLine)
-			skippedCount++
+		if err := embeddingError(embs); err != nil {
+			// Log and skip chunks that return empty or invalid embeddings
+			s.skip(res, chunk, err)
 			continue
 		}
-		successfulChunks = append(successfulChunks, chunk)
-		successfulEmbeddings = append(successfulEmbeddings, embs[0])
+		if err := batch.Add(chunk, embs[0]); err != nil {
+			res.settle(batch.saved, len(toEmbed), start)
+			return res, err
+		}
 	}
 
-	if skippedCount > 0 {
-		fmt.Fprintf(os.Stderr, "\n[codetect-index] skipped %d chunks that failed to embed\n", skippedCount)
-	}
+	s.logSkipped(res)
 
-	// Save all successful embeddings with provider ID
-	if len(successfulChunks) > 0 {
-		if err := s.store.SaveBatch(successfulChunks, successfulEmbeddings, providerID); err != nil {
-			return fmt.Errorf("saving embeddings: %w", err)
-		}
+	// Save the remaining embeddings
+	err := batch.Flush()
+	res.settle(batch.saved, len(toEmbed), start)
+	if err != nil {
+		return res, err
 	}
 
-	return nil
+	if interrupted != nil {
+		return res, interruptedError(interrupted, batch.saved)
+	}
+	if offlineErr != nil {
+		return res, providerOfflineError(offlineErr, attempted, batch.saved)
+	}
+	return res, nil
 }
 
-// IndexChunksParallel embeds and stores chunks with configurable parallelism.
-func (s *SemanticSearcher) IndexChunksParallel(ctx context.Context, chunks []Chunk, parallelism int, progressFn func(current, total int)) error {
+// IndexChunksParallel embeds and stores chunks with configurable
+// parallelism. Its result is as for IndexChunks.
+func (s *SemanticSearcher) IndexChunksParallel(ctx context.Context, chunks []Chunk, parallelism int, progressFn func(current, total int)) (*EmbedResult, error) {
+	start := time.Now()
+	res := &EmbedResult{Total: len(chunks)}
 	if len(chunks) == 0 {
-		return nil
+		return res, nil
 	}
 
 	if !s.Available() {
-		return fmt.Errorf("embedding provider not available")
+		return res, fmt.Errorf("embedding provider not available")
 	}
 
 	providerID := s.embedder.ProviderID()
@@ -243,15 +618,17 @@ func (s *SemanticSearcher) IndexChunksParallel(ctx context.Context, chunks []Chu
 	for _, chunk := range chunks {
 		has, err := s.store.HasEmbedding(chunk, providerID)
 		if err != nil {
-			return fmt.Errorf("checking embedding: %w", err)
+			return res, fmt.Errorf("checking embedding: %w", err)
 		}
 		if !has {
 			toEmbed = append(toEmbed, chunk)
 		}
 	}
 
+	res.CacheHits = len(chunks) - len(toEmbed)
 	if len(toEmbed) == 0 {
-		return nil // All chunks already indexed
+		res.finish(start)
+		return res, nil // All chunks already indexed
 	}
 
 	// Limit parallelism to number of chunks
@@ -286,7 +663,11 @@ func (s *SemanticSearcher) IndexChunksParallel(ctx context.Context, chunks []Chu
 	var completed atomic.Int32
 	total := int32(len(toEmbed))
 
-	// Spawn workers
+	// Spawn workers; the breaker pauses them all if the provider goes down.
+	// Chunks in flight when ctx is cancelled are allowed to finish.
+	breaker := newCircuitBreaker(s.breaker, s.embedder.Available, s.logger)
+	embedCtx := context.WithoutCancel(ctx)
+	var attempted atomic.Int32
 	var wg sync.WaitGroup
 	for i := 0; i < parallelism; i++ {
 		wg.Add(1)
@@ -300,12 +681,22 @@ func (s *SemanticSearcher) IndexChunksParallel(ctx context.Context, chunks []Chu
 				default:
 				}
 
+				if err := breaker.Allow(ctx); err != nil {
+					results <- result{err: err}
+					return
+				}
+
 				// Embed chunk
-				embs, err := s.embedder.Embed(ctx, []string{j.chunk.Content})
+				embs, err := s.embedder.Embed(embedCtx, []string{j.chunk.Content})
+				attempted.Add(1)
+				if berr := breaker.Record(ctx, err); berr != nil {
+					results <- result{err: berr}
+					return
+				}
 				if err != nil {
 					results <- result{chunk: j.chunk, err: err}
-				} else if len(embs) == 0 || len(embs[0]) == 0 {
-					results <- result{chunk: j.chunk, err: fmt.Errorf("empty embedding")}
+				} else if err := embeddingError(embs); err != nil {
+					results <- result{chunk: j.chunk, err: err}
 				} else {
 					results <- result{chunk: j.chunk, embedding: embs[0], err: nil}
 				}
@@ -332,38 +723,98 @@ func (s *SemanticSearcher) IndexChunksParallel(ctx context.Context, chunks []Chu
 	}()
 
 	// Collect results
-	var successfulChunks []Chunk
-	var successfulEmbeddings [][]float32
-	var skippedCount int
-
-	for res := range results {
-		if res.err != nil {
-			if res.err == ctx.Err() {
-				return res.err
+	batch := s.newCheckpointBatch(providerID)
+	var offlineErr, interrupted error
+
+	for r := range results {
+		if r.err != nil {
+			if r.err == ctx.Err() {
+				// Keep draining so in-flight chunks are saved
+				interrupted = r.err
+				continue
+			}
+			if errors.Is(r.err, ErrProviderOffline) {
+				// Keep draining so chunks already embedded are saved
+				offlineErr = r.err
+				continue
 			}
 			// Log the error with chunk details
-			fmt.Fprintf(os.Stderr, "\n[codetect-index] failed to embed %s:%d-%d: %v\n", res.chunk.Path, res.chunk.StartLine, res.chunk.EndLine, res.err)
-			skippedCount++
+			s.skip(res, r.chunk, r.err)
 			continue
 		}
-		successfulChunks = append(successfulChunks, res.chunk)
-		successfulEmbeddings = append(successfulEmbeddings, res.embedding)
+		if err := batch.Add(r.chunk, r.embedding); err != nil {
+			res.settle(batch.saved, len(toEmbed), start)
+			return res, err
+		}
+	}
+
+	s.logSkipped(res)
+
+	// Save the remaining embeddings
+	err := batch.Flush()
+	res.settle(batch.saved, len(toEmbed), start)
+	if err != nil {
+		return res, err
 	}
 
-	if skippedCount > 0 {
-		fmt.Fprintf(os.Stderr, "\n[codetect-index] skipped %d chunks that failed to embed\n", skippedCount)
+	if interrupted != nil {
+		return res, interruptedError(interrupted, batch.saved)
+	}
+	if offlineErr != nil {
+		return res, providerOfflineError(offlineErr, int(attempted.Load()), batch.saved)
 	}
+	return res, nil
+}
 
-	// Save all successful embeddings with provider ID
-	if len(successfulChunks) > 0 {
-		if err := s.store.SaveBatch(successfulChunks, successfulEmbeddings, providerID); err != nil {
-			return fmt.Errorf("saving embeddings: %w", err)
-		}
+// checkpointBatch buffers embedded chunks and saves them every `every` chunks.
+type checkpointBatch struct {
+	store      *EmbeddingStore
+	providerID string
+	every      int
+
+	chunks     []Chunk
+	embeddings [][]float32
+	saved      int
+}
+
+func (s *SemanticSearcher) newCheckpointBatch(providerID string) *checkpointBatch {
+	return &checkpointBatch{store: s.store, providerID: providerID, every: s.flushEvery}
+}
+
+// Add buffers an embedded chunk, saving the buffer once it is full.
+func (b *checkpointBatch) Add(chunk Chunk, embedding []float32) error {
+	b.chunks = append(b.chunks, chunk)
+	b.embeddings = append(b.embeddings, embedding)
+	if b.every > 0 && len(b.chunks) >= b.every {
+		return b.Flush()
 	}
+	return nil
+}
 
+// Flush saves any buffered embeddings with the provider ID.
+func (b *checkpointBatch) Flush() error {
+	if len(b.chunks) == 0 {
+		return nil
+	}
+	if err := b.store.SaveBatch(b.chunks, b.embeddings, b.providerID); err != nil {
+		return fmt.Errorf("saving embeddings: %w", err)
+	}
+	b.saved += len(b.chunks)
+	b.chunks, b.embeddings = nil, nil
 	return nil
 }
 
+// interruptedError reports how much was saved before the run was cancelled.
+// It wraps the context error, so callers can test for context.Canceled.
+func interruptedError(err error, saved int) error {
+	return fmt.Errorf("embedding interrupted after saving %d chunks: %w", saved, err)
+}
+
+// providerOfflineError reports how far a run got before the provider went away.
+func providerOfflineError(err error, attempted, saved int) error {
+	return fmt.Errorf("%w after %d chunks (%d embedded chunks were saved; re-run to resume)", err, attempted, saved)
+}
+
 // CrossRepoSearchResult extends SemanticResult with repo information
 type CrossRepoSearchResult struct {
 	SemanticResult
@@ -395,7 +846,14 @@ func (s *SemanticSearcher) SearchAcrossRepos(ctx context.Context, query string,
 	}
 
 	// Get all embeddings across repos (from the dimension-specific table)
-	records, err := s.store.GetAllAcrossRepos(repoRoots)
+	records, err := s.store.GetAllAcrossReposForModel(repoRoots, s.model)
+	if errors.Is(err, ErrNoEmbeddingsForDimension) {
+		return &CrossRepoSearchResponse{
+			Available: true,
+			Results:   []CrossRepoSearchResult{},
+			Error:     fmt.Sprintf("No embeddings indexed for %d-dimension vectors", s.store.VectorDimensions()),
+		}, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("getting embeddings: %w", err)
 	}
@@ -404,7 +862,7 @@ func (s *SemanticSearcher) SearchAcrossRepos(ctx context.Context, query string,
 		return &CrossRepoSearchResponse{
 			Available: true,
 			Results:   []CrossRepoSearchResult{},
-			Error:     "No embeddings indexed in this dimension group",
+			Error:     s.noRecordsMessage("No embeddings indexed in this dimension group"),
 		}, nil
 	}
 
@@ -418,6 +876,18 @@ func (s *SemanticSearcher) SearchAcrossRepos(ctx context.Context, query string,
 	}
 	queryEmbedding := queryEmbeddings[0]
 
+	// Drop test files before ranking, so they don't take up result slots
+	if s.excludeTests {
+		records = slices.DeleteFunc(records, func(r EmbeddingRecord) bool {
+			return IsTestPath(s.store.relPath(r.Path))
+		})
+	}
+
+	// Optionally narrow to keyword candidates before scoring
+	if s.prefilter != nil {
+		records = s.prefilter.Filter(records, query, limit, s.store.repoID, s.store.repoRoot)
+	}
+
 	// Build vector list for search
 	vectors := make([][]float32, len(records))
 	for i, r := range records {
@@ -425,7 +895,7 @@ func (s *SemanticSearcher) SearchAcrossRepos(ctx context.Context, query string,
 	}
 
 	// Find top-k most similar
-	topK := TopKByCosineSimilarity(queryEmbedding, vectors, limit)
+	topK := s.topK(queryEmbedding, vectors, limit)
 
 	// Build results
 	results := make([]CrossRepoSearchResult, 0, len(topK))
@@ -435,11 +905,17 @@ func (s *SemanticSearcher) SearchAcrossRepos(ctx context.Context, query string,
 		}
 
 		record := records[item.Index]
-		snippet := getSnippet(record.Path, record.StartLine, record.EndLine)
+		path := record.Path
+		if record.RepoRoot == s.store.repoID {
+			path = s.store.relPath(path)
+		} else {
+			path = config.RepoRelPath(record.RepoRoot, path)
+		}
+		snippet := repoSnippetHeader(record.RepoRoot, path, record.StartLine, record.EndLine)
 
 		results = append(results, CrossRepoSearchResult{
 			SemanticResult: SemanticResult{
-				Path:      record.Path,
+				Path:      path,
 				StartLine: record.StartLine,
 				EndLine:   record.EndLine,
 				Snippet:   snippet,
@@ -448,6 +924,23 @@ func (s *SemanticSearcher) SearchAcrossRepos(ctx context.Context, query string,
 			RepoRoot: record.RepoRoot,
 		})
 	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score == b.Score && a.Path == b.Path && a.StartLine == b.StartLine {
+			return a.RepoRoot < b.RepoRoot
+		}
+		return rankedBefore(a.Score, a.Path, a.StartLine, b.Score, b.Path, b.StartLine)
+	})
+	if s.normalization != NormalizeNone && len(results) > 0 {
+		semantic := make([]SemanticResult, len(results))
+		for i := range results {
+			semantic[i] = results[i].SemanticResult
+		}
+		s.normalize(semantic)
+		for i := range results {
+			results[i].SemanticResult = semantic[i]
+		}
+	}
 
 	return &CrossRepoSearchResponse{
 		Available: true,
@@ -455,7 +948,41 @@ func (s *SemanticSearcher) SearchAcrossRepos(ctx context.Context, query string,
 	}, nil
 }
 
-// SearchWithSnippets performs semantic search and includes actual code snippets
+// SearchAcrossReposWithSnippets performs cross-repo semantic search and
+// includes actual code snippets, each headed by the repo it came from.
+// snippetFn receives the record's repo root; an empty return keeps just the header.
+func (s *SemanticSearcher) SearchAcrossReposWithSnippets(ctx context.Context, query string, limit int, repoRoots []string, snippetFn func(repoRoot, path string, start, end int) string) (*CrossRepoSearchResponse, error) {
+	result, err := s.SearchAcrossRepos(ctx, query, limit, repoRoots)
+	if err != nil {
+		return nil, err
+	}
+
+	if snippetFn != nil && result.Available {
+		for i := range result.Results {
+			r := &result.Results[i]
+			snippet := snippetFn(r.RepoRoot, r.Path, r.StartLine, r.EndLine)
+			if snippet == "" {
+				continue
+			}
+			// Truncate long snippets
+			if len(snippet) > 500 {
+				snippet = snippet[:500] + "..."
+			}
+			r.Snippet = repoSnippetHeader(r.RepoRoot, r.Path, r.StartLine, r.EndLine) + "\n" + snippet
+		}
+	}
+
+	return result, nil
+}
+
+// repoSnippetHeader labels a cross-repo hit with its repo and location.
+func repoSnippetHeader(repoRoot, path string, startLine, endLine int) string {
+	return fmt.Sprintf("[%s] %s", repoRoot, getSnippet(path, startLine, endLine))
+}
+
+// SearchWithSnippets performs semantic search and includes actual code snippets.
+// snippetFn receives each chunk's line range and decides what to return,
+// including any surrounding context lines and truncation.
 func (s *SemanticSearcher) SearchWithSnippets(ctx context.Context, query string, limit int, snippetFn func(path string, start, end int) string) (*SemanticSearchResult, error) {
 	result, err := s.SearchWithContext(ctx, query, limit)
 	if err != nil {
@@ -466,10 +993,37 @@ func (s *SemanticSearcher) SearchWithSnippets(ctx context.Context, query string,
 		for i := range result.Results {
 			r := &result.Results[i]
 			r.Snippet = snippetFn(r.Path, r.StartLine, r.EndLine)
-			// Truncate long snippets
-			if len(r.Snippet) > 500 {
-				r.Snippet = r.Snippet[:500] + "..."
-			}
+		}
+	}
+
+	return result, nil
+}
+
+// SearchPage returns limit results starting at offset in the ranked list,
+// with HasMore set if more follow, so an agent can page through results.
+// Every candidate is scored on each search anyway, so a later page costs
+// about as much as the first; snippets are only read for the page.
+func (s *SemanticSearcher) SearchPage(ctx context.Context, query string, offset, limit int, snippetFn func(path string, start, end int) string) (*SemanticSearchResult, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	offset = max(offset, 0)
+
+	// One extra result tells whether there is a next page
+	result, err := s.SearchWithContext(ctx, query, offset+limit+1)
+	if err != nil {
+		return nil, err
+	}
+	result.HasMore = len(result.Results) > offset+limit
+	// Normalize before slicing, so page 2 is scaled against the same top
+	// result as page 1 rather than against its own best
+	s.normalize(result.Results)
+	result.Results = result.Results[min(offset, len(result.Results)):min(offset+limit, len(result.Results))]
+
+	if snippetFn != nil && result.Available {
+		for i := range result.Results {
+			r := &result.Results[i]
+			r.Snippet = snippetFn(r.Path, r.StartLine, r.EndLine)
 		}
 	}
 
@@ -0,0 +1,426 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"codetect/internal/db"
+)
+
+// cancellingEmbedder cancels the run after a fixed number of embed calls,
+// simulating Ctrl-C partway through.
+type cancellingEmbedder struct {
+	*mockEmbedder
+
+	mu     sync.Mutex
+	calls  int
+	after  int
+	cancel context.CancelFunc
+}
+
+func (e *cancellingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.calls++
+	if e.calls == e.after {
+		e.cancel()
+	}
+	return e.mockEmbedder.Embed(ctx, texts)
+}
+
+// rejectingEmbedder fails to embed any text containing "bad"
+type rejectingEmbedder struct {
+	*mockEmbedder
+}
+
+func (e *rejectingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	for _, text := range texts {
+		if strings.Contains(text, "bad") {
+			return nil, errors.New("rejected")
+		}
+	}
+	return e.mockEmbedder.Embed(ctx, texts)
+}
+
+func TestIndexChunksResult(t *testing.T) {
+	for _, parallelism := range []int{1, 4} {
+		t.Run(fmt.Sprintf("parallelism=%d", parallelism), func(t *testing.T) {
+			searcher := setupBreakerTest(t, &rejectingEmbedder{newMockEmbedder(4)})
+			var logs bytes.Buffer
+			searcher.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+			chunks := breakerTestChunks(10)
+			chunks[3].Content = "bad chunk"
+			chunks[7].Content = "another bad chunk"
+
+			res, err := searcher.IndexChunksParallel(context.Background(), chunks, parallelism, nil)
+			if err != nil {
+				t.Fatalf("IndexChunksParallel() error = %v", err)
+			}
+			if res.Errors != 2 || len(res.Failures) != 2 {
+				t.Fatalf("IndexChunksParallel() result = %+v, want 2 failures", res)
+			}
+			for _, f := range res.Failures {
+				if (f.Path != "file3.go" && f.Path != "file7.go") || f.StartLine != 1 || f.Err == nil {
+					t.Errorf("failure = %+v, want file3.go or file7.go with its error", f)
+				}
+			}
+			if !strings.Contains(logs.String(), "failed to embed chunk") || !strings.Contains(logs.String(), "path=file3.go") {
+				t.Errorf("logs = %q, want a structured warning per failed chunk", logs.String())
+			}
+			if res.Total != 10 || res.Embedded != 8 || res.CacheHits != 0 || res.Skipped != 0 {
+				t.Errorf("first run counts = %+v, want 8 of 10 embedded", res)
+			}
+
+			// A re-run only retries the failures
+			res, err = searcher.IndexChunksParallel(context.Background(), chunks, parallelism, nil)
+			if err != nil {
+				t.Fatalf("IndexChunksParallel() error = %v", err)
+			}
+			if res.Total != 10 || res.CacheHits != 8 || res.Embedded != 0 || res.Errors != 2 {
+				t.Errorf("re-run counts = %+v, want 8 cache hits and 2 failures", res)
+			}
+		})
+	}
+}
+
+func TestIndexChunksCheckpoints(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	embedder := &cancellingEmbedder{mockEmbedder: newMockEmbedder(4), after: 25, cancel: cancel}
+	searcher := setupBreakerTest(t, embedder)
+	searcher.SetCheckpointInterval(10)
+
+	res, err := searcher.IndexChunks(ctx, breakerTestChunks(100), nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("IndexChunks() error = %v, want context.Canceled", err)
+	}
+
+	// Two checkpoints plus the chunks embedded before the interruption
+	count, _, err := searcher.Store().Stats()
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if count != 25 {
+		t.Errorf("saved %d embeddings, want 25", count)
+	}
+	if res.Embedded != 25 || res.Skipped != 75 {
+		t.Errorf("IndexChunks() result = %+v, want 25 embedded and 75 skipped", res)
+	}
+}
+
+func TestIndexChunksCheckpointSaveFails(t *testing.T) {
+	embedder := &cancellingEmbedder{mockEmbedder: newMockEmbedder(4), after: 15}
+	searcher := setupBreakerTest(t, embedder)
+	searcher.SetCheckpointInterval(10)
+
+	// Dropping the table after the first checkpoint fails the second
+	store := searcher.Store()
+	embedder.cancel = func() {
+		if _, err := store.db.Exec("DROP TABLE " + store.tableName()); err != nil {
+			t.Errorf("dropping table: %v", err)
+		}
+	}
+
+	res, err := searcher.IndexChunks(context.Background(), breakerTestChunks(100), nil)
+	if err == nil {
+		t.Fatal("IndexChunks() error = nil, want the checkpoint save error")
+	}
+	if res.Embedded != 10 || res.Skipped != 90 {
+		t.Errorf("IndexChunks() result = %+v, want 10 embedded and 90 skipped", res)
+	}
+}
+
+func TestIndexChunksParallelCheckpoints(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	embedder := &cancellingEmbedder{mockEmbedder: newMockEmbedder(4), after: 25, cancel: cancel}
+	searcher := setupBreakerTest(t, embedder)
+	searcher.SetCheckpointInterval(10)
+
+	_, err := searcher.IndexChunksParallel(ctx, breakerTestChunks(100), 4, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("IndexChunksParallel() error = %v, want context.Canceled", err)
+	}
+
+	count, _, err := searcher.Store().Stats()
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	// Every chunk embedded before or during the interruption is kept
+	if count < 25 || count != embedder.calls {
+		t.Errorf("saved %d embeddings, want all %d embedded", count, embedder.calls)
+	}
+}
+
+func TestIndexChunksInterruptFlushesWithoutCheckpoints(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	embedder := &cancellingEmbedder{mockEmbedder: newMockEmbedder(4), after: 25, cancel: cancel}
+	searcher := setupBreakerTest(t, embedder)
+	searcher.SetCheckpointInterval(0)
+
+	_, err := searcher.IndexChunks(ctx, breakerTestChunks(100), nil)
+	if err == nil || !strings.Contains(err.Error(), "after saving 25 chunks") {
+		t.Errorf("IndexChunks() error = %v, want interruption summary", err)
+	}
+	if count, _, _ := searcher.Store().Stats(); count != 25 {
+		t.Errorf("saved %d embeddings with checkpoints disabled, want 25", count)
+	}
+}
+
+func TestSearchExcludeTests(t *testing.T) {
+	database, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	// The repo root itself sits under a test/ directory, which mustn't count
+	store, err := NewEmbeddingStore(database, "/test/repo")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	embedder := newMockEmbedder(4)
+	for _, path := range []string{"/test/repo/a.go", "/test/repo/a_test.go", "/test/repo/tests/b.go"} {
+		chunk := Chunk{Path: path, StartLine: 1, EndLine: 3, Content: "func a() {}"}
+		vectors, _ := embedder.Embed(context.Background(), []string{chunk.Content})
+		if err := store.Save(chunk, vectors[0], embedder.ProviderID()); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	searcher := NewSemanticSearcher(store, embedder)
+	result, err := searcher.Search("query", 10)
+	if err != nil || len(result.Results) != 3 {
+		t.Fatalf("Search() = %+v, %v, want all 3 chunks", result, err)
+	}
+
+	searcher.SetExcludeTests(true)
+	result, err = searcher.Search("query", 10)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(result.Results) != 1 || result.Results[0].Path != "a.go" {
+		t.Errorf("Search() excluding tests = %+v, want only a.go", result.Results)
+	}
+}
+
+func TestSearchTiesOrderedByPath(t *testing.T) {
+	database, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	store, err := NewEmbeddingStore(database, "/repo")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	embedder := newMockEmbedder(4)
+	// Same content everywhere, so every score ties
+	for _, loc := range []struct {
+		path  string
+		start int
+	}{{"c.go", 10}, {"a.go", 5}, {"b.go", 1}, {"a.go", 1}} {
+		chunk := Chunk{Path: loc.path, StartLine: loc.start, EndLine: loc.start + 2, Content: "func dup() {}"}
+		vectors, _ := embedder.Embed(context.Background(), []string{chunk.Content})
+		if err := store.Save(chunk, vectors[0], embedder.ProviderID()); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	searcher := NewSemanticSearcher(store, embedder)
+	result, err := searcher.Search("query", 10)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	var got []string
+	for _, r := range result.Results {
+		got = append(got, fmt.Sprintf("%s:%d", r.Path, r.StartLine))
+	}
+	if want := "a.go:1 a.go:5 b.go:1 c.go:10"; strings.Join(got, " ") != want {
+		t.Errorf("tied results = %v, want %s", got, want)
+	}
+}
+
+func TestSearchInFile(t *testing.T) {
+	database, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	store, err := NewEmbeddingStore(database, "/repo")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	embedder := newMockEmbedder(4)
+	save := func(path string, start int, model string) {
+		chunk := Chunk{Path: path, StartLine: start, EndLine: start + 2, Content: "func a() {}"}
+		vectors, _ := embedder.Embed(context.Background(), []string{chunk.Content})
+		if err := store.Save(chunk, vectors[0], model); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	save("big.go", 1, embedder.ProviderID())
+	save("big.go", 20, embedder.ProviderID())
+	save("big.go", 40, "other:model")
+	save("small.go", 1, embedder.ProviderID())
+
+	searcher := NewSemanticSearcher(store, embedder)
+	snippetFn := func(path string, start, end int) string {
+		return fmt.Sprintf("%s@%d", path, start)
+	}
+	result, err := searcher.SearchInFile(context.Background(), "/repo/big.go", "query", 10, snippetFn)
+	if err != nil {
+		t.Fatalf("SearchInFile() error = %v", err)
+	}
+	if len(result.Results) != 2 {
+		t.Fatalf("SearchInFile() = %+v, want big.go's 2 chunks from the query's model", result.Results)
+	}
+	for _, r := range result.Results {
+		if r.Path != "big.go" || r.Snippet != fmt.Sprintf("big.go@%d", r.StartLine) {
+			t.Errorf("SearchInFile() result = %+v, want a big.go chunk with its snippet", r)
+		}
+	}
+
+	// A zero or negative limit falls back to the default rather than
+	// returning nothing or panicking
+	for _, limit := range []int{0, -1} {
+		result, err = searcher.SearchInFile(context.Background(), "big.go", "query", limit, nil)
+		if err != nil {
+			t.Fatalf("SearchInFile(limit %d) error = %v", limit, err)
+		}
+		if len(result.Results) != 2 {
+			t.Errorf("SearchInFile(limit %d) = %d results, want 2", limit, len(result.Results))
+		}
+	}
+
+	result, err = searcher.SearchInFile(context.Background(), "missing.go", "query", 10, nil)
+	if err != nil {
+		t.Fatalf("SearchInFile() error = %v", err)
+	}
+	if len(result.Results) != 0 || !strings.Contains(result.Error, "missing.go") {
+		t.Errorf("SearchInFile() for an unindexed file = %+v, want an error naming it", result)
+	}
+}
+
+func TestSearchPage(t *testing.T) {
+	database, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	store, err := NewEmbeddingStore(database, "/repo")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	embedder := newMockEmbedder(4)
+	for i := 0; i < 5; i++ {
+		chunk := Chunk{Path: fmt.Sprintf("f%d.go", i), StartLine: 1, EndLine: 2, Content: strings.Repeat("x", i+1)}
+		vectors, _ := embedder.Embed(context.Background(), []string{chunk.Content})
+		vectors[0][0] += float32(i) // Spread the scores out
+		if err := store.Save(chunk, vectors[0], embedder.ProviderID()); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	searcher := NewSemanticSearcher(store, embedder)
+	all, err := searcher.Search("query", 10)
+	if err != nil || len(all.Results) != 5 {
+		t.Fatalf("Search() = %+v, %v, want 5 results", all, err)
+	}
+
+	var paged []SemanticResult
+	for offset := 0; ; offset += 2 {
+		page, err := searcher.SearchPage(context.Background(), "query", offset, 2, nil)
+		if err != nil {
+			t.Fatalf("SearchPage(offset=%d) error = %v", offset, err)
+		}
+		paged = append(paged, page.Results...)
+		if wantMore := offset+2 < 5; page.HasMore != wantMore {
+			t.Errorf("SearchPage(offset=%d).HasMore = %v, want %v", offset, page.HasMore, wantMore)
+		}
+		if !page.HasMore {
+			break
+		}
+	}
+	if len(paged) != len(all.Results) {
+		t.Fatalf("pages hold %d results, want %d", len(paged), len(all.Results))
+	}
+	for i := range paged {
+		if paged[i].Path != all.Results[i].Path {
+			t.Errorf("paged result %d = %s, want %s", i, paged[i].Path, all.Results[i].Path)
+		}
+	}
+
+	page, err := searcher.SearchPage(context.Background(), "query", 10, 2, nil)
+	if err != nil || len(page.Results) != 0 || page.HasMore {
+		t.Errorf("SearchPage() past the end = %+v, %v, want an empty last page", page, err)
+	}
+}
+
+// TestSearchRecencyBeforeLimit checks the recency boost ranks every chunk,
+// not just the first limit by similarity, so a fresh file just below the
+// cut can still make the first page.
+func TestSearchRecencyBeforeLimit(t *testing.T) {
+	dir := t.TempDir()
+	database, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	store, err := NewEmbeddingStore(database, dir)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	embedder := newMockEmbedder(4)
+	old := time.Now().Add(-10 * RecencyHalfLife)
+	// Similarity is the first element: the old files edge out new.go
+	for _, f := range []struct {
+		name  string
+		score float32
+	}{{"old1.go", 0.9}, {"old2.go", 0.85}, {"new.go", 0.8}} {
+		path := filepath.Join(dir, f.name)
+		if err := os.WriteFile(path, []byte("package a\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if f.name != "new.go" {
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+		chunk := Chunk{Path: f.name, StartLine: 1, EndLine: 1, Content: f.name}
+		if err := store.Save(chunk, []float32{f.score, 0, 0, 0}, embedder.ProviderID()); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	searcher := NewSemanticSearcher(store, embedder)
+	searcher.ScoreFunc = func(query, vector []float32) float32 { return vector[0] }
+	searcher.SetRecencyWeight(0.5)
+
+	page, err := searcher.SearchPage(context.Background(), "query", 0, 1, nil)
+	if err != nil || len(page.Results) != 1 {
+		t.Fatalf("SearchPage() = %+v, %v, want 1 result", page, err)
+	}
+	if page.Results[0].Path != "new.go" || !page.HasMore {
+		t.Errorf("first page = %+v (has_more %v), want new.go ranked first by recency", page.Results, page.HasMore)
+	}
+}
@@ -5,6 +5,8 @@ package embedding
 import (
 	"context"
 	"fmt"
+	"math"
+	"slices"
 	"sort"
 
 	"codetect/internal/config"
@@ -20,7 +22,21 @@ type V2SemanticSearcher struct {
 	locations   *LocationStore
 	vectorIndex VectorIndex
 	embedder    Embedder
-	repoRoot    string
+	repoRoot    string  // Repo key for scoped queries (see config.RepoID)
+	dir         string  // Repo checkout that result paths are relative to
+	docWeight   float32 // Weight of doc comment similarity when blending scores
+
+	candidateMultiplier int // Vector candidates fetched per requested result
+	maxCandidates       int // Cap when widening the fetch to fill a short result set
+
+	nodeTypeWeights map[string]float64 // Score multipliers by AST node type (see config.NodeTypeWeight)
+	excludeTests    bool               // Leave test files (see IsTestPath) out of results
+	recencyWeight   float64            // Boost for recently modified files (0 disables)
+
+	// ScoreFunc scores cached embeddings against the query when no vector
+	// index is set, and when blending in doc comment similarity. nil means
+	// cosine similarity.
+	ScoreFunc ScoreFunc
 }
 
 // V2SearchResult represents a single search result from v2 semantic search.
@@ -33,6 +49,7 @@ type V2SearchResult struct {
 	NodeType    string  `json:"node_type,omitempty"`
 	NodeName    string  `json:"node_name,omitempty"`
 	Language    string  `json:"language,omitempty"`
+	DocComment  string  `json:"doc_comment,omitempty"`
 	Snippet     string  `json:"snippet,omitempty"`
 }
 
@@ -54,15 +71,58 @@ func NewV2SemanticSearcher(
 	repoRoot string,
 	vectorIndex VectorIndex,
 ) *V2SemanticSearcher {
+	defaults := config.DefaultRetrieverConfig()
 	return &V2SemanticSearcher{
-		cache:       cache,
-		locations:   locations,
-		vectorIndex: vectorIndex,
-		embedder:    embedder,
-		repoRoot:    repoRoot,
+		cache:               cache,
+		locations:           locations,
+		vectorIndex:         vectorIndex,
+		embedder:            embedder,
+		repoRoot:            config.RepoID(repoRoot),
+		dir:                 config.NormalizeRepoRoot(repoRoot),
+		docWeight:           float32(defaults.DocCommentWeight),
+		candidateMultiplier: defaults.CandidateMultiplier,
+		maxCandidates:       defaults.MaxCandidates,
 	}
 }
 
+// SetDocCommentWeight sets how much doc comment similarity contributes to a
+// result's score (0 disables doc comment matching).
+func (s *V2SemanticSearcher) SetDocCommentWeight(weight float64) {
+	s.docWeight = float32(weight)
+}
+
+// SetCandidateLimits sets how many vector candidates are fetched per
+// requested result, and the most that are fetched when a search widens
+// to make up for candidates lost to deduplication or repo filtering.
+// Non-positive values keep the current setting.
+func (s *V2SemanticSearcher) SetCandidateLimits(multiplier, maxCandidates int) {
+	if multiplier > 0 {
+		s.candidateMultiplier = multiplier
+	}
+	if maxCandidates > 0 {
+		s.maxCandidates = maxCandidates
+	}
+}
+
+// SetNodeTypeWeights sets score multipliers by chunk node type, applied
+// after vector scoring (nil or empty disables weighting).
+func (s *V2SemanticSearcher) SetNodeTypeWeights(weights map[string]float64) {
+	s.nodeTypeWeights = weights
+}
+
+// SetExcludeTests leaves locations in test files, as judged by IsTestPath,
+// out of search results.
+func (s *V2SemanticSearcher) SetExcludeTests(exclude bool) {
+	s.excludeTests = exclude
+}
+
+// SetRecencyWeight re-ranks results toward recently modified files, by
+// mtime under the repoRoot the searcher was created with. weight is in
+// [0, 1]; 0 disables. See SemanticSearcher.SetRecencyWeight.
+func (s *V2SemanticSearcher) SetRecencyWeight(weight float64) {
+	s.recencyWeight = weight
+}
+
 // Available returns true if the searcher is ready for queries.
 func (s *V2SemanticSearcher) Available() bool {
 	if s.embedder == nil {
@@ -73,7 +133,46 @@ func (s *V2SemanticSearcher) Available() bool {
 
 // Search performs semantic search and returns matching locations.
 // Flow: embed query → vector search → lookup locations → return results
+//
+// Identical searches of the same repo already in flight are coalesced into
+// one, whose response every caller gets a copy of. The shared search
+// outlives any one caller's ctx; each caller returns early when its own ctx
+// is done.
 func (s *V2SemanticSearcher) Search(ctx context.Context, query string, limit int) (*V2SearchResponse, error) {
+	// Searches with a custom scorer can't be told apart, so they run alone
+	if s.ScoreFunc != nil {
+		return s.search(ctx, query, limit)
+	}
+
+	key := fmt.Sprintf("%s\x00%s\x00%s\x00%t\x00%g\x00%d\x00%d\x00%v\x00%t\x00%g\x00%d\x00%s",
+		s.repoRoot, s.dir, s.providerID(), s.vectorIndex != nil, s.docWeight,
+		s.candidateMultiplier, s.maxCandidates, s.nodeTypeWeights, s.excludeTests, s.recencyWeight, limit, query)
+	response, err, shared := v2SearchFlights.do(ctx, key, func(ctx context.Context) (*V2SearchResponse, error) {
+		return s.search(ctx, query, limit)
+	})
+	if shared && response != nil {
+		response = response.clone()
+	}
+	return response, err
+}
+
+// clone copies a response so callers can fill in snippets independently
+func (r *V2SearchResponse) clone() *V2SearchResponse {
+	c := *r
+	c.Results = slices.Clone(r.Results)
+	return &c
+}
+
+// providerID identifies the query embedder's model, or "" without one
+func (s *V2SemanticSearcher) providerID() string {
+	if s.embedder == nil {
+		return ""
+	}
+	return s.embedder.ProviderID()
+}
+
+// search performs semantic search, uncoalesced
+func (s *V2SemanticSearcher) search(ctx context.Context, query string, limit int) (*V2SearchResponse, error) {
 	response := &V2SearchResponse{
 		Query:     query,
 		Results:   []V2SearchResult{},
@@ -99,61 +198,100 @@ func (s *V2SemanticSearcher) Search(ctx context.Context, query string, limit int
 
 	queryEmbedding := queryEmbeddings[0]
 
-	// Step 2: Vector search for nearest neighbors
-	var vectorResults []VectorResult
-
-	if s.vectorIndex != nil {
-		// Use provided vector index (HNSW or configured)
-		vectorResults, err = s.vectorIndex.SearchWithFilter(ctx, queryEmbedding, limit*2, []string{s.repoRoot})
+	// Steps 2-3: Vector search for nearest neighbors, then lookup locations
+	// for each content hash. Candidates that resolve to duplicate, other
+	// repos' or excluded test locations are dropped, so widen the fetch until limit results
+	// survive, the index runs out, or maxCandidates is reached.
+	fetch := max(limit*s.candidateMultiplier, limit)
+	seenLocations := make(map[string]int) // Dedupe by path:line, value is result index
+	var docStates []docBlendState
+	for {
+		vectorResults, err := s.vectorCandidates(ctx, queryEmbedding, fetch)
 		if err != nil {
-			response.Error = fmt.Sprintf("vector search: %v", err)
+			response.Error = err.Error()
 			return response, nil
 		}
-	} else {
-		// Fall back to brute-force search against cache
-		vectorResults, err = s.bruteForceSearch(ctx, queryEmbedding, limit*2)
-		if err != nil {
-			response.Error = fmt.Sprintf("brute-force search: %v", err)
-			return response, nil
+
+		response.Results = response.Results[:0]
+		clear(seenLocations)
+		docStates = docStates[:0]
+		for _, vr := range vectorResults {
+			locs, err := s.locations.GetByHash(vr.ContentHash)
+			if err != nil {
+				continue
+			}
+
+			for _, loc := range locs {
+				// Filter to this repo, minus tests if excluded
+				if !s.wantLocation(loc) {
+					continue
+				}
+
+				key := fmt.Sprintf("%s:%d:%d", loc.Path, loc.StartLine, loc.EndLine)
+				if _, seen := seenLocations[key]; seen {
+					continue
+				}
+				seenLocations[key] = len(response.Results)
+
+				response.Results = append(response.Results, locationResult(loc, vr.Score))
+				docStates = append(docStates, docBlendState{docHash: loc.DocHash, hasCode: true})
+			}
 		}
-	}
 
-	// Step 3: Lookup locations for each content hash
-	seenLocations := make(map[string]bool) // Dedupe by path:line
-	for _, vr := range vectorResults {
-		locs, err := s.locations.GetByHash(vr.ContentHash)
-		if err != nil {
-			continue
+		if len(response.Results) >= limit || len(vectorResults) < fetch || fetch >= s.maxCandidates {
+			break
 		}
+		fetch = min(fetch*2, s.maxCandidates)
+	}
 
-		for _, loc := range locs {
-			// Filter to this repo
-			if loc.RepoRoot != s.repoRoot {
-				continue
+	// Step 4: Blend in doc comment matches. Doc hits can surface chunks whose
+	// code alone doesn't match the query (e.g. "parse the config file").
+	if s.docWeight > 0 {
+		docResults, err := s.docCommentSearch(queryEmbedding, max(limit*s.candidateMultiplier, limit))
+		if err == nil {
+			for _, dr := range docResults {
+				locs, err := s.locations.GetByDocHash(dr.ContentHash)
+				if err != nil {
+					continue
+				}
+				for _, loc := range locs {
+					if !s.wantLocation(loc) {
+						continue
+					}
+					key := fmt.Sprintf("%s:%d:%d", loc.Path, loc.StartLine, loc.EndLine)
+					if i, seen := seenLocations[key]; seen {
+						docStates[i].docScore, docStates[i].hasDoc = dr.Score, true
+						continue
+					}
+					seenLocations[key] = len(response.Results)
+					response.Results = append(response.Results, locationResult(loc, 0))
+					docStates = append(docStates, docBlendState{docHash: loc.DocHash, docScore: dr.Score, hasDoc: true})
+				}
 			}
+		}
+		s.blendDocScores(queryEmbedding, response.Results, docStates)
+	}
 
-			key := fmt.Sprintf("%s:%d:%d", loc.Path, loc.StartLine, loc.EndLine)
-			if seenLocations[key] {
-				continue
-			}
-			seenLocations[key] = true
-
-			response.Results = append(response.Results, V2SearchResult{
-				Path:        loc.Path,
-				StartLine:   loc.StartLine,
-				EndLine:     loc.EndLine,
-				Score:       vr.Score,
-				ContentHash: vr.ContentHash,
-				NodeType:    loc.NodeType,
-				NodeName:    loc.NodeName,
-				Language:    loc.Language,
-			})
+	// Step 5: Nudge definitions above boilerplate by node type
+	if len(s.nodeTypeWeights) > 0 {
+		for i := range response.Results {
+			r := &response.Results[i]
+			r.Score *= float32(config.NodeTypeWeight(s.nodeTypeWeights, r.NodeType))
+		}
+	}
+
+	// Step 6: Favor recently modified files
+	if s.recencyWeight > 0 {
+		boost := newRecencyBoost(s.dir, s.recencyWeight)
+		for i := range response.Results {
+			response.Results[i].Score *= float32(boost.multiplier(response.Results[i].Path))
 		}
 	}
 
-	// Sort by score descending
+	// Sort by score descending, ties by path and line
 	sort.Slice(response.Results, func(i, j int) bool {
-		return response.Results[i].Score > response.Results[j].Score
+		a, b := response.Results[i], response.Results[j]
+		return rankedBefore(a.Score, a.Path, a.StartLine, b.Score, b.Path, b.StartLine)
 	})
 
 	// Apply limit
@@ -165,6 +303,32 @@ func (s *V2SemanticSearcher) Search(ctx context.Context, query string, limit int
 	return response, nil
 }
 
+// wantLocation reports whether loc belongs in this searcher's results: it
+// is in the searched repo, and not a test file when tests are excluded
+func (s *V2SemanticSearcher) wantLocation(loc ChunkLocation) bool {
+	return loc.RepoRoot == s.repoRoot && !(s.excludeTests && IsTestPath(loc.Path))
+}
+
+// vectorCandidates returns the k nearest content hashes to the query, from
+// the vector index if one is configured or by brute force otherwise.
+func (s *V2SemanticSearcher) vectorCandidates(ctx context.Context, query []float32, k int) ([]VectorResult, error) {
+	if s.vectorIndex != nil {
+		// Use provided vector index (HNSW or configured)
+		results, err := s.vectorIndex.SearchWithFilter(ctx, query, k, []string{s.repoRoot})
+		if err != nil {
+			return nil, fmt.Errorf("vector search: %w", err)
+		}
+		return results, nil
+	}
+
+	// Fall back to brute-force search against cache
+	results, err := s.bruteForceSearch(ctx, query, k)
+	if err != nil {
+		return nil, fmt.Errorf("brute-force search: %w", err)
+	}
+	return results, nil
+}
+
 // SearchWithSnippets performs search and adds code snippets to results.
 func (s *V2SemanticSearcher) SearchWithSnippets(
 	ctx context.Context,
@@ -199,6 +363,21 @@ func (s *V2SemanticSearcher) bruteForceSearch(ctx context.Context, query []float
 		return nil, fmt.Errorf("getting repo hashes: %w", err)
 	}
 
+	return s.rankHashes(query, hashes, limit)
+}
+
+// docCommentSearch ranks this repo's doc comment embeddings against the query.
+func (s *V2SemanticSearcher) docCommentSearch(query []float32, limit int) ([]VectorResult, error) {
+	hashes, err := s.locations.GetDocHashesForRepo(s.repoRoot)
+	if err != nil {
+		return nil, fmt.Errorf("getting doc hashes: %w", err)
+	}
+
+	return s.rankHashes(query, hashes, limit)
+}
+
+// rankHashes scores cached embeddings for the given hashes and returns the top k.
+func (s *V2SemanticSearcher) rankHashes(query []float32, hashes []string, limit int) ([]VectorResult, error) {
 	if len(hashes) == 0 {
 		return nil, nil
 	}
@@ -210,6 +389,7 @@ func (s *V2SemanticSearcher) bruteForceSearch(ctx context.Context, query []float
 	}
 
 	// Compute similarities
+	score := s.scoreFunc()
 	type scored struct {
 		hash  string
 		score float32
@@ -220,13 +400,17 @@ func (s *V2SemanticSearcher) bruteForceSearch(ctx context.Context, query []float
 		if entry == nil || len(entry.Embedding) == 0 {
 			continue
 		}
-		sim := cosineSimilarity(query, entry.Embedding)
+		sim := score(query, entry.Embedding)
 		results = append(results, scored{hash: hash, score: sim})
 	}
 
-	// Sort by score descending
+	// Sort by score descending; entries come from a map, so break ties by
+	// hash to keep the top k the same from run to run
 	sort.Slice(results, func(i, j int) bool {
-		return results[i].score > results[j].score
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].hash < results[j].hash
 	})
 
 	// Take top k
@@ -247,36 +431,96 @@ func (s *V2SemanticSearcher) bruteForceSearch(ctx context.Context, query []float
 	return vectorResults, nil
 }
 
-// cosineSimilarity computes the cosine similarity between two vectors.
-func cosineSimilarity(a []float32, b []float32) float32 {
-	if len(a) != len(b) || len(a) == 0 {
-		return 0
+// docBlendState tracks which similarity scores are known for a result.
+type docBlendState struct {
+	docHash  string
+	docScore float32
+	hasDoc   bool
+	hasCode  bool
+}
+
+// locationResult converts a chunk location into a search result.
+func locationResult(loc ChunkLocation, score float32) V2SearchResult {
+	return V2SearchResult{
+		Path:        loc.Path,
+		StartLine:   loc.StartLine,
+		EndLine:     loc.EndLine,
+		Score:       score,
+		ContentHash: loc.ContentHash,
+		NodeType:    loc.NodeType,
+		NodeName:    loc.NodeName,
+		Language:    loc.Language,
+		DocComment:  loc.DocComment,
 	}
+}
 
-	var dot, normA, normB float32
-	for i := range a {
-		dot += a[i] * b[i]
-		normA += a[i] * a[i]
-		normB += b[i] * b[i]
+// blendDocScores fills in any missing code or doc similarities from the cache
+// and blends them. A doc comment match can raise a result's score but never
+// lowers it, so undocumented code isn't favored over documented code.
+func (s *V2SemanticSearcher) blendDocScores(query []float32, results []V2SearchResult, states []docBlendState) {
+	var missing []string
+	for i, st := range states {
+		if !st.hasCode {
+			missing = append(missing, results[i].ContentHash)
+		}
+		if st.docHash != "" && !st.hasDoc {
+			missing = append(missing, st.docHash)
+		}
 	}
 
-	if normA == 0 || normB == 0 {
-		return 0
+	var entries map[string]*CacheEntry
+	if len(missing) > 0 {
+		entries, _ = s.cache.GetBatch(missing)
+	}
+	score := s.scoreFunc()
+	similarity := func(hash string) (float32, bool) {
+		entry := entries[hash]
+		if entry == nil || len(entry.Embedding) == 0 {
+			return 0, false
+		}
+		return score(query, entry.Embedding), true
+	}
+
+	for i := range results {
+		st := &states[i]
+		if !st.hasCode {
+			results[i].Score, st.hasCode = similarity(results[i].ContentHash)
+		}
+		if st.docHash != "" && !st.hasDoc {
+			st.docScore, st.hasDoc = similarity(st.docHash)
+		}
+		if !st.hasDoc {
+			continue
+		}
+
+		blended := (1-s.docWeight)*results[i].Score + s.docWeight*st.docScore
+		if blended > results[i].Score {
+			results[i].Score = blended
+		}
 	}
+}
 
-	return dot / (sqrt32(normA) * sqrt32(normB))
+// scoreFunc returns the scorer for brute-force ranking and doc blending
+func (s *V2SemanticSearcher) scoreFunc() ScoreFunc {
+	if s.ScoreFunc != nil {
+		return s.ScoreFunc
+	}
+	return cosineSimilarity
 }
 
-// sqrt32 computes the square root of a float32.
-func sqrt32(x float32) float32 {
-	if x <= 0 {
+// cosineSimilarity computes the cosine similarity between two vectors.
+func cosineSimilarity(a []float32, b []float32) float32 {
+	if len(a) != len(b) || len(a) == 0 {
 		return 0
 	}
-	z := x / 2
-	for i := 0; i < 10; i++ {
-		z = z - (z*z-x)/(2*z)
+
+	dot, normA, normB := dot32(a, b), dot32(a, a), dot32(b, b)
+
+	if normA == 0 || normB == 0 {
+		return 0
 	}
-	return z
+
+	return finiteScore(float64(dot) / (math.Sqrt(float64(normA)) * math.Sqrt(float64(normB))))
 }
 
 // NewV2SemanticSearcherFromDB creates a V2SemanticSearcher from database components.
@@ -2,8 +2,13 @@ package embedding
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"codetect/internal/config"
 	"codetect/internal/db"
 )
 
@@ -46,8 +51,10 @@ func (m *mockEmbedderV2) Dimensions() int {
 func setupV2SearcherTest(t *testing.T) (*EmbeddingCache, *LocationStore, db.DB) {
 	t.Helper()
 
-	// Create in-memory SQLite database
-	cfg := db.DefaultConfig(":memory:")
+	// A file rather than :memory:, which gives each pooled connection its
+	// own empty database: the cache's background access-stat writes and
+	// coalesced searches can hold connections at the same time
+	cfg := db.DefaultConfig(filepath.Join(t.TempDir(), "test.db"))
 	database, err := db.Open(cfg)
 	if err != nil {
 		t.Fatalf("opening database: %v", err)
@@ -321,6 +328,347 @@ func TestV2SemanticSearcher_FilterByRepo(t *testing.T) {
 	}
 }
 
+func TestV2SemanticSearcher_WidensShortResults(t *testing.T) {
+	cache, locations, _ := setupV2SearcherTest(t)
+	ctx := context.Background()
+
+	embedder := &mockEmbedderV2{available: true, dims: 768, embeddings: make(map[string][]float32)}
+	query, _ := embedder.Embed(ctx, []string{"query"})
+
+	// A shared index where the 20 closest vectors belong to another repo
+	index := NewBruteForceVectorIndex(nil, 768)
+	for i := 0; i < 25; i++ {
+		vec := make([]float32, 768)
+		copy(vec, query[0])
+		repo := "/test/other"
+		if i < 20 {
+			vec[0] += float32(i) * 0.01
+		} else {
+			vec[1] += float32(i)
+			repo = "/test/repo"
+		}
+
+		hash := fmt.Sprintf("hash%d", i)
+		index.Insert(ctx, hash, vec)
+		if err := locations.SaveLocation(ChunkLocation{
+			RepoRoot:    repo,
+			Path:        fmt.Sprintf("file%d.go", i),
+			StartLine:   1,
+			EndLine:     5,
+			ContentHash: hash,
+		}); err != nil {
+			t.Fatalf("saving location: %v", err)
+		}
+	}
+
+	searcher := NewV2SemanticSearcher(cache, locations, embedder, "/test/repo", index)
+	searcher.SetDocCommentWeight(0)
+
+	response, err := searcher.Search(ctx, "query", 5)
+	if err != nil || response.Error != "" {
+		t.Fatalf("search error: %v %s", err, response.Error)
+	}
+	if len(response.Results) != 5 {
+		t.Errorf("expected a full 5 in-repo results, got %d", len(response.Results))
+	}
+
+	// The cap bounds how far the search widens
+	searcher.SetCandidateLimits(2, 15)
+	response, _ = searcher.Search(ctx, "query", 5)
+	if len(response.Results) != 0 {
+		t.Errorf("expected no results within 15 candidates, got %d", len(response.Results))
+	}
+}
+
+func TestV2SemanticSearcher_DocCommentBlend(t *testing.T) {
+	cache, locations, _ := setupV2SearcherTest(t)
+	repoRoot := "/test/repo"
+
+	// unitVec returns a 768-dim vector with the given leading components
+	unitVec := func(x, y float32) []float32 {
+		v := make([]float32, 768)
+		v[0], v[1] = x, y
+		return v
+	}
+
+	query := "parse the config file"
+	embedder := &mockEmbedderV2{
+		available:  true,
+		dims:       768,
+		embeddings: map[string][]float32{query: unitVec(1, 0)},
+	}
+
+	// loadConfig's body doesn't resemble the query but its doc comment does.
+	// The other chunks have slightly better code matches and no docs.
+	chunks := []struct {
+		path string
+		code []float32
+		doc  []float32
+	}{
+		{"config.go", unitVec(0, 1), unitVec(1, 0)},
+		{"b.go", unitVec(0.1, 1), nil},
+		{"c.go", unitVec(0.2, 1), nil},
+	}
+	for i, c := range chunks {
+		codeHash := hashContent(c.path)
+		if err := cache.Put(codeHash, c.code); err != nil {
+			t.Fatalf("storing embedding: %v", err)
+		}
+		loc := ChunkLocation{RepoRoot: repoRoot, Path: c.path, StartLine: 1, EndLine: 5 + i, ContentHash: codeHash}
+		if c.doc != nil {
+			loc.DocComment = "Parse the config file."
+			loc.DocHash = hashContent(loc.DocComment)
+			if err := cache.Put(loc.DocHash, c.doc); err != nil {
+				t.Fatalf("storing doc embedding: %v", err)
+			}
+		}
+		if err := locations.SaveLocation(loc); err != nil {
+			t.Fatalf("saving location: %v", err)
+		}
+	}
+
+	searcher := NewV2SemanticSearcher(cache, locations, embedder, repoRoot, nil)
+
+	// limit=1 means only the two best code matches are candidates, so
+	// config.go can only be found through its doc comment
+	response, err := searcher.Search(context.Background(), query, 1)
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if len(response.Results) != 1 || response.Results[0].Path != "config.go" {
+		t.Fatalf("expected config.go first via doc comment, got %+v", response.Results)
+	}
+	if response.Results[0].DocComment == "" {
+		t.Error("expected DocComment on result")
+	}
+
+	// Disabling doc comment matching falls back to code-only ranking
+	searcher.SetDocCommentWeight(0)
+	response, err = searcher.Search(context.Background(), query, 1)
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if len(response.Results) != 1 || response.Results[0].Path != "c.go" {
+		t.Errorf("expected c.go first without doc weighting, got %+v", response.Results)
+	}
+}
+
+func TestV2SemanticSearcher_ScoreFunc(t *testing.T) {
+	cache, locations, _ := setupV2SearcherTest(t)
+	embedder := &mockEmbedderV2{available: true, dims: 768, embeddings: make(map[string][]float32)}
+	repoRoot := "/test/repo"
+
+	for i, content := range []string{"func a() {}", "func b() {}"} {
+		hash := hashContent(content)
+		emb := make([]float32, 768)
+		emb[i] = 1
+		if err := cache.Put(hash, emb); err != nil {
+			t.Fatalf("storing embedding: %v", err)
+		}
+		loc := ChunkLocation{RepoRoot: repoRoot, Path: fmt.Sprintf("%c.go", 'a'+i), StartLine: 1, EndLine: 1, ContentHash: hash}
+		if err := locations.SaveLocation(loc); err != nil {
+			t.Fatalf("saving location: %v", err)
+		}
+	}
+
+	// The default query embedding is orthogonal to a.go, so only the custom
+	// scorer ranks it first
+	searcher := NewV2SemanticSearcher(cache, locations, embedder, repoRoot, nil)
+	searcher.ScoreFunc = func(query, candidate []float32) float32 { return candidate[0] }
+
+	response, err := searcher.Search(context.Background(), "query", 10)
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if len(response.Results) == 0 || response.Results[0].Path != "a.go" || response.Results[0].Score != 1 {
+		t.Errorf("results = %+v, want a.go first with the custom score 1", response.Results)
+	}
+}
+
+func TestV2SemanticSearcher_NodeTypeWeights(t *testing.T) {
+	cache, locations, _ := setupV2SearcherTest(t)
+	query := make([]float32, 768)
+	query[0] = 1
+	embedder := &mockEmbedderV2{available: true, dims: 768, embeddings: map[string][]float32{"query": query}}
+	repoRoot := "/test/repo"
+
+	// The import block is the closer match, the function a little further off
+	chunks := []struct {
+		path, content, nodeType string
+		x, y                    float32
+	}{
+		{"imports.go", "import \"fmt\"", "import_declaration", 1, 0},
+		{"func.go", "func a() {}", "function_declaration", 0.8, 0.6},
+	}
+	for _, c := range chunks {
+		hash := hashContent(c.content)
+		emb := make([]float32, 768)
+		emb[0], emb[1] = c.x, c.y
+		if err := cache.Put(hash, emb); err != nil {
+			t.Fatalf("storing embedding: %v", err)
+		}
+		loc := ChunkLocation{RepoRoot: repoRoot, Path: c.path, StartLine: 1, EndLine: 1, ContentHash: hash, NodeType: c.nodeType}
+		if err := locations.SaveLocation(loc); err != nil {
+			t.Fatalf("saving location: %v", err)
+		}
+	}
+
+	searcher := NewV2SemanticSearcher(cache, locations, embedder, repoRoot, nil)
+	response, err := searcher.Search(context.Background(), "query", 10)
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if len(response.Results) != 2 || response.Results[0].Path != "imports.go" {
+		t.Fatalf("unweighted results = %+v, want imports.go first", response.Results)
+	}
+
+	searcher.SetNodeTypeWeights(map[string]float64{"function": 1, "import": 0.3})
+	response, err = searcher.Search(context.Background(), "query", 10)
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if len(response.Results) != 2 || response.Results[0].Path != "func.go" {
+		t.Fatalf("weighted results = %+v, want func.go first", response.Results)
+	}
+	if got := response.Results[1].Score; got < 0.29 || got > 0.31 {
+		t.Errorf("import score = %v, want 1 * 0.3", got)
+	}
+}
+
+func TestV2SemanticSearcher_ExcludeTests(t *testing.T) {
+	cache, locations, _ := setupV2SearcherTest(t)
+	embedder := &mockEmbedderV2{available: true, dims: 768, embeddings: make(map[string][]float32)}
+	repoRoot := "/test/repo"
+
+	paths := []string{"a.go", "a_test.go", "web/__tests__/a.js"}
+	for i, path := range paths {
+		hash := hashContent(path)
+		emb := make([]float32, 768)
+		emb[i] = 1
+		if err := cache.Put(hash, emb); err != nil {
+			t.Fatalf("storing embedding: %v", err)
+		}
+		loc := ChunkLocation{RepoRoot: repoRoot, Path: path, StartLine: 1, EndLine: 1, ContentHash: hash}
+		if err := locations.SaveLocation(loc); err != nil {
+			t.Fatalf("saving location: %v", err)
+		}
+	}
+
+	searcher := NewV2SemanticSearcher(cache, locations, embedder, repoRoot, nil)
+	response, err := searcher.Search(context.Background(), "query", 10)
+	if err != nil || len(response.Results) != len(paths) {
+		t.Fatalf("Search() = %+v, %v, want all %d locations", response, err, len(paths))
+	}
+
+	searcher.SetExcludeTests(true)
+	response, err = searcher.Search(context.Background(), "query", 10)
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if len(response.Results) != 1 || response.Results[0].Path != "a.go" {
+		t.Errorf("results excluding tests = %+v, want only a.go", response.Results)
+	}
+}
+
+func TestV2SemanticSearcher_TiesOrderedByPath(t *testing.T) {
+	cache, locations, _ := setupV2SearcherTest(t)
+	embedder := &mockEmbedderV2{available: true, dims: 768, embeddings: make(map[string][]float32)}
+	repoRoot := "/test/repo"
+
+	// One chunk duplicated across files, so every location ties
+	hash := hashContent("func dup() {}")
+	emb := make([]float32, 768)
+	emb[0] = 1
+	if err := cache.Put(hash, emb); err != nil {
+		t.Fatalf("storing embedding: %v", err)
+	}
+	for _, loc := range []ChunkLocation{
+		{Path: "c.go", StartLine: 10, EndLine: 12},
+		{Path: "a.go", StartLine: 5, EndLine: 7},
+		{Path: "b.go", StartLine: 1, EndLine: 3},
+		{Path: "a.go", StartLine: 1, EndLine: 3},
+	} {
+		loc.RepoRoot, loc.ContentHash = repoRoot, hash
+		if err := locations.SaveLocation(loc); err != nil {
+			t.Fatalf("saving location: %v", err)
+		}
+	}
+
+	searcher := NewV2SemanticSearcher(cache, locations, embedder, repoRoot, nil)
+	response, err := searcher.Search(context.Background(), "query", 10)
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	var got []string
+	for _, r := range response.Results {
+		got = append(got, fmt.Sprintf("%s:%d", r.Path, r.StartLine))
+	}
+	if want := []string{"a.go:1", "a.go:5", "b.go:1", "c.go:10"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("tied results = %v, want %v", got, want)
+	}
+}
+
+func TestV2SemanticSearcher_RecencyWeight(t *testing.T) {
+	cache, locations, _ := setupV2SearcherTest(t)
+	embedder := &mockEmbedderV2{available: true, dims: 768, embeddings: make(map[string][]float32)}
+	dir := t.TempDir()
+
+	// Equally relevant chunks in a dormant and a freshly edited file
+	emb := make([]float32, 768)
+	emb[1] = 1 // The mock's default query vector is zero at index 0
+	for _, path := range []string{"old.go", "new.go"} {
+		if err := os.WriteFile(filepath.Join(dir, path), []byte("package a\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		hash := hashContent(path)
+		if err := cache.Put(hash, emb); err != nil {
+			t.Fatalf("storing embedding: %v", err)
+		}
+		loc := ChunkLocation{RepoRoot: config.RepoID(dir), Path: path, StartLine: 1, EndLine: 1, ContentHash: hash}
+		if err := locations.SaveLocation(loc); err != nil {
+			t.Fatalf("saving location: %v", err)
+		}
+	}
+	old := time.Now().Add(-365 * 24 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "old.go"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	searcher := NewV2SemanticSearcher(cache, locations, embedder, dir, nil)
+	searcher.SetRecencyWeight(0.5)
+	response, err := searcher.Search(context.Background(), "query", 10)
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if len(response.Results) != 2 || response.Results[0].Path != "new.go" {
+		t.Fatalf("results = %+v, want new.go first", response.Results)
+	}
+	if r := response.Results; r[1].Score > r[0].Score*0.55 {
+		t.Errorf("old.go score %v, want about half of new.go's %v", r[1].Score, r[0].Score)
+	}
+}
+
+// TestV2CosineMatchesV1 checks v2 scores agree with the v1 path across
+// vector magnitudes, so rankings from the two indexers stay comparable.
+// Unnormalized embeddings with large or tiny norms used to skew v2 scores.
+func TestV2CosineMatchesV1(t *testing.T) {
+	base := []float32{0.3, -0.2, 0.9, 0.1}
+	other := []float32{0.5, 0.4, 0.7, -0.3}
+	for _, scale := range []float32{1e-3, 1, 30, 1e3, 1e4} {
+		a := make([]float32, len(base))
+		b := make([]float32, len(other))
+		for i := range base {
+			a[i] = base[i] * scale
+			b[i] = other[i] * scale
+		}
+
+		v1, v2 := CosineSimilarity(a, b), cosineSimilarity(a, b)
+		if diff := v1 - v2; diff > 1e-5 || diff < -1e-5 {
+			t.Errorf("scale %g: v2 cosine = %v, v1 = %v", scale, v2, v1)
+		}
+	}
+}
+
 func TestV2CosineSimilarity(t *testing.T) {
 	tests := []struct {
 		name     string
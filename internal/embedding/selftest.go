@@ -0,0 +1,72 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+)
+
+// Self-test texts: the first two say the same thing in different words, the
+// third is unrelated. Any working embedding model scores the first pair
+// closer together.
+var selfTestTexts = []string{
+	"read the contents of a file from disk",
+	"open a file and load its data into memory",
+	"the recipe calls for two cups of flour and a pinch of salt",
+}
+
+// SelfTestResult reports what SelfTest measured
+type SelfTestResult struct {
+	ProviderID string   `json:"provider_id"`
+	Dimensions int      `json:"dimensions"` // Measured vector size
+	Similar    float32  `json:"similar"`    // Cosine similarity of the related pair
+	Dissimilar float32  `json:"dissimilar"` // Cosine similarity of an unrelated pair
+	Problems   []string `json:"problems,omitempty"`
+}
+
+// Passed reports whether the self-test found no problems
+func (r *SelfTestResult) Passed() bool {
+	return len(r.Problems) == 0
+}
+
+// SelfTest embeds a few known texts and checks the vectors are sane: of
+// wantDims dimensions (0 skips the check), finite, not all zero, and
+// scoring related texts closer than unrelated ones. Available only shows
+// the endpoint answers; this catches a wrong model or an endpoint
+// returning garbage before a long run. An error means the embed call
+// itself failed.
+func SelfTest(ctx context.Context, e Embedder, wantDims int) (*SelfTestResult, error) {
+	result := &SelfTestResult{ProviderID: e.ProviderID()}
+
+	vectors, err := e.Embed(ctx, selfTestTexts)
+	if err != nil {
+		return nil, fmt.Errorf("embedding self-test texts: %w", err)
+	}
+	if len(vectors) != len(selfTestTexts) {
+		result.Problems = append(result.Problems, fmt.Sprintf("got %d vectors for %d texts", len(vectors), len(selfTestTexts)))
+		return result, nil
+	}
+
+	result.Dimensions = len(vectors[0])
+	for i, v := range vectors {
+		if len(v) != result.Dimensions {
+			result.Problems = append(result.Problems, fmt.Sprintf("vector %d has %d dimensions, vector 0 has %d", i, len(v), result.Dimensions))
+			return result, nil
+		}
+		if problem := vectorProblem(v); problem != "" {
+			result.Problems = append(result.Problems, fmt.Sprintf("vector %d %s", i, problem))
+		}
+	}
+	if wantDims > 0 && result.Dimensions != wantDims {
+		result.Problems = append(result.Problems, fmt.Sprintf("got %d dimensions, configured for %d", result.Dimensions, wantDims))
+	}
+	if !result.Passed() {
+		return result, nil
+	}
+
+	result.Similar = CosineSimilarity(vectors[0], vectors[1])
+	result.Dissimilar = CosineSimilarity(vectors[0], vectors[2])
+	if result.Similar <= result.Dissimilar {
+		result.Problems = append(result.Problems, fmt.Sprintf("related texts score %.3f, no higher than unrelated ones at %.3f", result.Similar, result.Dissimilar))
+	}
+	return result, nil
+}
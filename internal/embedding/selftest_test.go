@@ -0,0 +1,56 @@
+package embedding
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+)
+
+// scriptedEmbedder returns the vectors it was given, in order
+type scriptedEmbedder struct {
+	*mockEmbedder
+	vectors [][]float32
+}
+
+func (e *scriptedEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return e.vectors, nil
+}
+
+func TestSelfTest(t *testing.T) {
+	nan := float32(math.NaN())
+	tests := []struct {
+		name     string
+		vectors  [][]float32
+		wantDims int
+		problem  string // Substring of the expected problem, "" to pass
+	}{
+		{"sane", [][]float32{{1, 0.1, 0}, {0.9, 0.2, 0}, {0, 0.1, 1}}, 3, ""},
+		{"unchecked size", [][]float32{{1, 0.1, 0}, {0.9, 0.2, 0}, {0, 0.1, 1}}, 0, ""},
+		{"wrong size", [][]float32{{1, 0.1, 0}, {0.9, 0.2, 0}, {0, 0.1, 1}}, 768, "configured for 768"},
+		{"ragged", [][]float32{{1, 0}, {0.9, 0.2, 0}, {0, 0.1, 1}}, 0, "dimensions"},
+		{"zero", [][]float32{{0, 0, 0}, {0.9, 0.2, 0}, {0, 0.1, 1}}, 3, "all zeros"},
+		{"nan", [][]float32{{1, nan, 0}, {0.9, 0.2, 0}, {0, 0.1, 1}}, 3, "NaN"},
+		{"unordered", [][]float32{{1, 0, 0}, {0, 0, 1}, {1, 0.1, 0}}, 3, "no higher than unrelated"},
+		{"missing", [][]float32{{1, 0, 0}}, 3, "1 vectors for 3 texts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &scriptedEmbedder{mockEmbedder: newMockEmbedder(3), vectors: tt.vectors}
+			result, err := SelfTest(context.Background(), e, tt.wantDims)
+			if err != nil {
+				t.Fatalf("SelfTest() error = %v", err)
+			}
+			if tt.problem == "" {
+				if !result.Passed() || result.Dimensions != 3 || result.Similar <= result.Dissimilar {
+					t.Errorf("SelfTest() = %+v, want a pass at 3 dimensions", result)
+				}
+				return
+			}
+			if result.Passed() || !strings.Contains(strings.Join(result.Problems, "; "), tt.problem) {
+				t.Errorf("SelfTest() problems = %v, want one mentioning %q", result.Problems, tt.problem)
+			}
+		})
+	}
+}
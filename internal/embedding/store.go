@@ -1,14 +1,17 @@
 package embedding
 
 import (
+	"context"
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
 	"time"
 
+	"codetect/internal/config"
 	"codetect/internal/db"
 )
 
@@ -25,6 +28,17 @@ type EmbeddingRecord struct {
 	CreatedAt   time.Time `json:"created_at"`
 }
 
+// ErrNoEmbeddingsForDimension is returned when the dimension-specific
+// embeddings table does not exist, e.g. a shared PostgreSQL database where
+// no repo has been embedded with this model's dimensions yet.
+// Results returned alongside it are empty rather than nil.
+var ErrNoEmbeddingsForDimension = errors.New("no embeddings for this dimension")
+
+// ErrEmbeddingConfigMismatch is returned when the configured embedding model
+// or dimensions differ from those a repository was embedded with. Searching
+// such an index compares vectors from different spaces.
+var ErrEmbeddingConfigMismatch = errors.New("embedding config mismatch")
+
 // EmbeddingStore manages embedding storage in the database.
 // Supports multiple database types via the dialect abstraction.
 // For PostgreSQL, uses dimension-grouped tables (embeddings_768, embeddings_1024, etc.)
@@ -33,15 +47,18 @@ type EmbeddingStore struct {
 	db           db.DB
 	dialect      db.Dialect
 	schema       *db.SchemaBuilder
-	vectorDim    int    // Vector dimensions (e.g., 768 for nomic-embed-text)
-	useNativeVec bool   // True if using PostgreSQL native vector type
-	repoRoot     string // Absolute path to repo root for multi-repo isolation
+	vectorDim    int          // Vector dimensions (e.g., 768 for nomic-embed-text)
+	useNativeVec bool         // True if using PostgreSQL native vector type
+	repoRoot     string       // Absolute path to repo root, used to read chunk files
+	repoID       string       // Key for multi-repo isolation (repo_root column, see config.RepoID)
+	vectorFormat VectorFormat // How SQLite vectors are written, from index metadata
 }
 
 // tableNameForDimensions returns the table name for a given vector dimension.
 // PostgreSQL uses dimension-grouped tables (embeddings_768, embeddings_1024, etc.)
 // to support multiple embedding models with different dimensions.
-// SQLite uses a single "embeddings" table since it stores vectors as JSON text.
+// SQLite uses a single "embeddings" table since it stores vectors as JSON
+// text or packed BLOBs, neither of which fixes the size.
 func tableNameForDimensions(dialect db.Dialect, dim int) string {
 	if dialect.Name() == "postgres" {
 		return fmt.Sprintf("embeddings_%d", dim)
@@ -108,7 +125,8 @@ func NewEmbeddingStoreWithOptions(database db.DB, dialect db.Dialect, vectorDim
 		schema:       db.NewSchemaBuilder(database, dialect),
 		vectorDim:    vectorDim,
 		useNativeVec: useNativeVec,
-		repoRoot:     repoRoot,
+		repoRoot:     config.NormalizeRepoRoot(repoRoot),
+		repoID:       config.RepoID(repoRoot),
 	}
 
 	// Initialize schema
@@ -116,6 +134,13 @@ func NewEmbeddingStoreWithOptions(database db.DB, dialect db.Dialect, vectorDim
 		return nil, fmt.Errorf("initializing embedding schema: %w", err)
 	}
 
+	// Pick up rows indexed under the path before the repo's key changed
+	if moved, err := db.AdoptRepoKey(context.Background(), database, dialect, store.repoRoot, store.repoID); err != nil {
+		slog.Warn("could not move embeddings to the repo's key", "from", store.repoRoot, "to", store.repoID, "error", err)
+	} else if moved {
+		slog.Info("moved embeddings to the repo's key", "from", store.repoRoot, "to", store.repoID)
+	}
+
 	return store, nil
 }
 
@@ -134,8 +159,8 @@ func NewEmbeddingStoreFromConfig(database db.DB, cfg db.Config, repoRoot string)
 }
 
 // initSchema creates the embeddings table if it doesn't exist.
-// For PostgreSQL, creates dimension-specific tables (embeddings_768, embeddings_1024, etc.)
-// and the repo_embedding_configs table for tracking model/dimensions per repository.
+// For PostgreSQL, creates dimension-specific tables (embeddings_768, embeddings_1024, etc.).
+// All dialects get the repo_embedding_configs table for tracking model/dimensions per repository.
 func (s *EmbeddingStore) initSchema() error {
 	// Run dialect-specific initialization statements (e.g., CREATE EXTENSION for PostgreSQL)
 	for _, stmt := range s.dialect.InitStatements() {
@@ -146,14 +171,45 @@ func (s *EmbeddingStore) initSchema() error {
 		}
 	}
 
-	// Use dialect-aware schema for non-SQLite databases
-	// For SQLite, we still use the raw SQL for now to maintain compatibility
-	if s.dialect.Name() != "sqlite" {
-		// Create repo_embedding_configs table first (tracks model/dimensions per repo)
-		if err := s.initRepoConfigTable(); err != nil {
-			return fmt.Errorf("creating repo config table: %w", err)
+	// Create repo_embedding_configs table first (tracks model/dimensions per repo)
+	if err := s.initRepoConfigTable(); err != nil {
+		return fmt.Errorf("creating repo config table: %w", err)
+	}
+
+	if s.dialect.Name() == "sqlite" {
+		if err := s.initMetadataTable(); err != nil {
+			return err
 		}
+		if err := s.loadVectorFormat(); err != nil {
+			return err
+		}
+	}
 
+	// Each dimension table is versioned independently since they're created on demand
+	migrator := db.NewMigrator(s.db, s.dialect, s.tableName(), s.migrations())
+	if _, err := migrator.Migrate(context.Background()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// migrations returns the ordered schema migrations for this store's embeddings table.
+// Append new migrations to the end; never edit one that has shipped.
+func (s *EmbeddingStore) migrations() []db.Migration {
+	return []db.Migration{
+		{
+			Version:     1,
+			Description: "create embeddings table",
+			Up: func(ctx context.Context, exec db.Executor) error {
+				return s.createEmbeddingsTable(exec)
+			},
+		},
+	}
+}
+
+// createEmbeddingsTable creates the embeddings table and its indexes.
+func (s *EmbeddingStore) createEmbeddingsTable(exec db.Executor) error {
+	if s.dialect.Name() != "sqlite" {
 		// Get dimension-specific table name
 		tableName := s.tableName()
 
@@ -162,7 +218,7 @@ func (s *EmbeddingStore) initSchema() error {
 
 		// Create dimension-specific table using dialect
 		sql := s.dialect.CreateTableSQL(tableName, columns)
-		if _, err := s.db.Exec(sql); err != nil {
+		if _, err := exec.Exec(sql); err != nil {
 			return fmt.Errorf("creating %s table: %w", tableName, err)
 		}
 
@@ -174,27 +230,27 @@ func (s *EmbeddingStore) initSchema() error {
 		idxUniqueName := fmt.Sprintf("idx_%s_unique", tableName)
 		idxUnique := s.dialect.CreateIndexSQL(tableName, idxUniqueName,
 			[]string{"repo_root", "path", "start_line", "end_line", "model"}, true)
-		if _, err := s.db.Exec(idxUnique); err != nil {
+		if _, err := exec.Exec(idxUnique); err != nil {
 			return fmt.Errorf("creating unique index: %w", err)
 		}
 
 		// Create indexes for common queries
 		idxPathName := fmt.Sprintf("idx_%s_path", tableName)
 		idxPath := s.dialect.CreateIndexSQL(tableName, idxPathName, []string{"path"}, false)
-		if _, err := s.db.Exec(idxPath); err != nil {
+		if _, err := exec.Exec(idxPath); err != nil {
 			return fmt.Errorf("creating path index: %w", err)
 		}
 
 		idxHashName := fmt.Sprintf("idx_%s_hash", tableName)
 		idxHash := s.dialect.CreateIndexSQL(tableName, idxHashName, []string{"content_hash"}, false)
-		if _, err := s.db.Exec(idxHash); err != nil {
+		if _, err := exec.Exec(idxHash); err != nil {
 			return fmt.Errorf("creating hash index: %w", err)
 		}
 
 		// Composite index for repo-scoped queries
 		idxRepoPathName := fmt.Sprintf("idx_%s_repo_path", tableName)
 		idxRepoPath := s.dialect.CreateIndexSQL(tableName, idxRepoPathName, []string{"repo_root", "path"}, false)
-		if _, err := s.db.Exec(idxRepoPath); err != nil {
+		if _, err := exec.Exec(idxRepoPath); err != nil {
 			return fmt.Errorf("creating repo_path index: %w", err)
 		}
 
@@ -220,16 +276,17 @@ CREATE INDEX IF NOT EXISTS idx_embeddings_path ON embeddings(path);
 CREATE INDEX IF NOT EXISTS idx_embeddings_hash ON embeddings(content_hash);
 CREATE INDEX IF NOT EXISTS idx_embeddings_repo_path ON embeddings(repo_root, path);
 `
-	if _, err := s.db.Exec(sqliteSchema); err != nil {
+	if _, err := exec.Exec(sqliteSchema); err != nil {
 		return fmt.Errorf("creating embedding schema: %w", err)
 	}
 
 	return nil
 }
 
-// initRepoConfigTable creates the repo_embedding_configs table for PostgreSQL.
+// initRepoConfigTable creates the repo_embedding_configs table.
 // This table tracks which model and dimensions each repository uses,
-// enabling cross-repo search and proper migration between dimension groups.
+// enabling cross-repo search, proper migration between dimension groups
+// (PostgreSQL) and compatibility checks before searching (all dialects).
 func (s *EmbeddingStore) initRepoConfigTable() error {
 	columns := []db.ColumnDef{
 		{Name: "repo_root", Type: db.ColTypeText, Nullable: false, PrimaryKey: true},
@@ -259,11 +316,6 @@ type RepoEmbeddingConfig struct {
 // GetRepoConfig returns the embedding configuration for a repository.
 // Returns nil if no configuration exists (repository not yet embedded).
 func (s *EmbeddingStore) GetRepoConfig(repoRoot string) (*RepoEmbeddingConfig, error) {
-	if s.dialect.Name() == "sqlite" {
-		// SQLite doesn't use repo configs (single table, no dimension constraints)
-		return nil, nil
-	}
-
 	query := s.schema.SubstitutePlaceholders(`
 		SELECT repo_root, model, dimensions, created_at, updated_at
 		FROM repo_embedding_configs
@@ -288,11 +340,6 @@ func (s *EmbeddingStore) GetRepoConfig(repoRoot string) (*RepoEmbeddingConfig, e
 
 // SetRepoConfig creates or updates the embedding configuration for a repository.
 func (s *EmbeddingStore) SetRepoConfig(repoRoot, model string, dimensions int) error {
-	if s.dialect.Name() == "sqlite" {
-		// SQLite doesn't use repo configs
-		return nil
-	}
-
 	now := time.Now().Unix()
 
 	// Upsert the config
@@ -310,10 +357,6 @@ func (s *EmbeddingStore) SetRepoConfig(repoRoot, model string, dimensions int) e
 // ListRepoConfigs returns all repository embedding configurations.
 // Useful for admin tools and cross-repo operations.
 func (s *EmbeddingStore) ListRepoConfigs() ([]RepoEmbeddingConfig, error) {
-	if s.dialect.Name() == "sqlite" {
-		return nil, nil
-	}
-
 	query := `SELECT repo_root, model, dimensions, created_at, updated_at
 		FROM repo_embedding_configs
 		ORDER BY repo_root`
@@ -364,6 +407,28 @@ func (s *EmbeddingStore) CheckDimensionMismatch(repoRoot string, newDimensions i
 	return cfg.Dimensions, false, nil
 }
 
+// VerifyRepoConfig checks that model and dimensions match what the
+// repository was embedded with. An empty model or zero dimensions skips
+// that check. Returns the stored config (nil if the repo was never
+// embedded) and an error wrapping ErrEmbeddingConfigMismatch on mismatch.
+func (s *EmbeddingStore) VerifyRepoConfig(repoRoot, model string, dimensions int) (*RepoEmbeddingConfig, error) {
+	cfg, err := s.GetRepoConfig(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return nil, nil
+	}
+
+	modelMismatch := model != "" && model != cfg.Model
+	dimMismatch := dimensions > 0 && dimensions != cfg.Dimensions
+	if modelMismatch || dimMismatch {
+		return cfg, fmt.Errorf("%w: index was built with model %q (%d dimensions), configured model %q (%d dimensions)",
+			ErrEmbeddingConfigMismatch, cfg.Model, cfg.Dimensions, model, dimensions)
+	}
+	return cfg, nil
+}
+
 // DeleteFromDimensionTable deletes all embeddings for a repository from a specific dimension table.
 // Used during dimension migration to clean up old embeddings before re-embedding.
 func (s *EmbeddingStore) DeleteFromDimensionTable(repoRoot string, dimensions int) error {
@@ -402,15 +467,36 @@ func (s *EmbeddingStore) MigrateRepoDimensions(repoRoot string, oldDimensions, n
 	return nil
 }
 
+// RepoID returns the key this store scopes its data by (see config.RepoID).
+func (s *EmbeddingStore) RepoID() string {
+	return s.repoID
+}
+
+// Close closes the database the store reads from. Only call it on a store
+// that owns its connection, not one sharing a database with other stores.
+func (s *EmbeddingStore) Close() error {
+	return s.db.Close()
+}
+
+// relPath normalizes path to the repo-relative form embeddings are stored under.
+func (s *EmbeddingStore) relPath(path string) string {
+	return config.RepoRelPath(s.repoRoot, path)
+}
+
 // VectorDimensions returns the vector dimensions configured for this store.
 func (s *EmbeddingStore) VectorDimensions() int {
 	return s.vectorDim
 }
 
-// Save stores an embedding for a chunk
+// Save stores an embedding for a chunk. An invalid vector (empty, all
+// zeros, or containing NaN or Inf) is logged and not stored.
 func (s *EmbeddingStore) Save(chunk Chunk, embedding []float32, model string) error {
+	if err := validateEmbedding(embedding); err != nil {
+		warnInvalidEmbedding(chunk.Path, chunk.StartLine, chunk.EndLine, err)
+		return nil
+	}
 	contentHash := hashContent(chunk.Content)
-	embJSON, err := json.Marshal(embedding)
+	embValue, err := s.encodeEmbedding(embedding)
 	if err != nil {
 		return fmt.Errorf("marshaling embedding: %w", err)
 	}
@@ -425,13 +511,14 @@ func (s *EmbeddingStore) Save(chunk Chunk, embedding []float32, model string) er
 	upsertSQL = s.schema.SubstitutePlaceholders(upsertSQL)
 
 	_, err = s.db.Exec(upsertSQL,
-		s.repoRoot, chunk.Path, chunk.StartLine, chunk.EndLine,
-		contentHash, string(embJSON), model, time.Now().Unix())
+		s.repoID, s.relPath(chunk.Path), chunk.StartLine, chunk.EndLine,
+		contentHash, embValue, model, time.Now().Unix())
 
 	return err
 }
 
-// SaveBatch stores multiple embeddings in a transaction
+// SaveBatch stores multiple embeddings in a transaction. Invalid vectors
+// are logged and skipped, as in Save.
 func (s *EmbeddingStore) SaveBatch(chunks []Chunk, embeddings [][]float32, model string) error {
 	if len(chunks) != len(embeddings) {
 		return fmt.Errorf("chunks and embeddings length mismatch")
@@ -460,15 +547,64 @@ func (s *EmbeddingStore) SaveBatch(chunks []Chunk, embeddings [][]float32, model
 
 	now := time.Now().Unix()
 	for i, chunk := range chunks {
+		if err := validateEmbedding(embeddings[i]); err != nil {
+			warnInvalidEmbedding(chunk.Path, chunk.StartLine, chunk.EndLine, err)
+			continue
+		}
 		contentHash := hashContent(chunk.Content)
-		embJSON, err := json.Marshal(embeddings[i])
+		embValue, err := s.encodeEmbedding(embeddings[i])
+		if err != nil {
+			return fmt.Errorf("marshaling embedding %d: %w", i, err)
+		}
+
+		_, err = stmt.Exec(
+			s.repoID, s.relPath(chunk.Path), chunk.StartLine, chunk.EndLine,
+			contentHash, embValue, model, now)
+		if err != nil {
+			return fmt.Errorf("inserting embedding %d: %w", i, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SaveRecords stores previously computed embedding records in a transaction,
+// keeping their content hash, model and creation time. Records are saved
+// under this store's repo regardless of their RepoRoot. Used to load a
+// prebuilt index. Invalid vectors are logged and skipped, as in Save.
+func (s *EmbeddingStore) SaveRecords(records []EmbeddingRecord) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	columns := []string{"repo_root", "path", "start_line", "end_line", "content_hash", "embedding", "model", "created_at"}
+	conflictColumns := []string{"repo_root", "path", "start_line", "end_line", "model"}
+	updateColumns := []string{"content_hash", "embedding", "created_at"}
+
+	upsertSQL := s.dialect.UpsertSQL(s.tableName(), columns, conflictColumns, updateColumns)
+	upsertSQL = s.schema.SubstitutePlaceholders(upsertSQL)
+
+	stmt, err := tx.Prepare(upsertSQL)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i, r := range records {
+		if err := validateEmbedding(r.Embedding); err != nil {
+			warnInvalidEmbedding(r.Path, r.StartLine, r.EndLine, err)
+			continue
+		}
+		embValue, err := s.encodeEmbedding(r.Embedding)
 		if err != nil {
 			return fmt.Errorf("marshaling embedding %d: %w", i, err)
 		}
 
 		_, err = stmt.Exec(
-			s.repoRoot, chunk.Path, chunk.StartLine, chunk.EndLine,
-			contentHash, string(embJSON), model, now)
+			s.repoID, s.relPath(r.Path), r.StartLine, r.EndLine,
+			r.ContentHash, embValue, r.Model, r.CreatedAt.Unix())
 		if err != nil {
 			return fmt.Errorf("inserting embedding %d: %w", i, err)
 		}
@@ -477,81 +613,152 @@ func (s *EmbeddingStore) SaveBatch(chunks []Chunk, embeddings [][]float32, model
 	return tx.Commit()
 }
 
+// warnInvalidEmbedding logs a chunk whose vector was not stored
+func warnInvalidEmbedding(path string, startLine, endLine int, err error) {
+	slog.Warn("skipping invalid embedding", "path", path, "start_line", startLine, "end_line", endLine, "error", err)
+}
+
 // GetByPath retrieves all embeddings for a file path within this repo
 func (s *EmbeddingStore) GetByPath(path string) ([]EmbeddingRecord, error) {
 	tableName := s.tableName()
 	query := s.schema.SubstitutePlaceholders(fmt.Sprintf(`
-		SELECT id, path, start_line, end_line, content_hash, embedding, model, created_at
+		SELECT %s
 		FROM %s
 		WHERE repo_root = ? AND path = ?
-		ORDER BY start_line`, tableName))
-	rows, err := s.db.Query(query, s.repoRoot, path)
+		ORDER BY start_line`, embeddingSelectColumns(false), tableName))
+	rows, err := s.db.Query(query, s.repoID, s.relPath(path))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	return scanEmbeddingRecords(rows)
+	return s.scanEmbeddingRecords(rows, false)
 }
 
-// GetAll retrieves all embeddings within this repo
+// GetAll retrieves all embeddings within this repo, from every model
 func (s *EmbeddingStore) GetAll() ([]EmbeddingRecord, error) {
+	return s.GetAllForModel("")
+}
+
+// GetAllForModel retrieves the embeddings within this repo that were made
+// by model (a provider ID such as "ollama:nomic-embed-text"). Vectors from
+// different models aren't comparable, so searches should never mix them.
+// An empty model returns every model's embeddings.
+func (s *EmbeddingStore) GetAllForModel(model string) ([]EmbeddingRecord, error) {
 	tableName := s.tableName()
+	where, args := "repo_root = ?", []interface{}{s.repoID}
+	if model != "" {
+		where += " AND model = ?"
+		args = append(args, model)
+	}
 	query := s.schema.SubstitutePlaceholders(fmt.Sprintf(`
-		SELECT id, path, start_line, end_line, content_hash, embedding, model, created_at
+		SELECT %s
 		FROM %s
-		WHERE repo_root = ?
-		ORDER BY path, start_line`, tableName))
-	rows, err := s.db.Query(query, s.repoRoot)
+		WHERE %s
+		ORDER BY path, start_line`, embeddingSelectColumns(false), tableName, where))
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
+		if isMissingTable(err) {
+			return []EmbeddingRecord{}, s.noEmbeddingsErr()
+		}
 		return nil, err
 	}
 	defer rows.Close()
 
-	return scanEmbeddingRecords(rows)
+	return s.scanEmbeddingRecords(rows, false)
+}
+
+// GetPage retrieves up to limit embeddings within this repo whose IDs are
+// above afterID, in ID order. Start from 0 and pass the last record's ID to
+// read the next page; an empty page is the end. Paging by ID stays cheap
+// deep into large tables, where OFFSET would rescan every earlier row.
+func (s *EmbeddingStore) GetPage(afterID int64, limit int) ([]EmbeddingRecord, error) {
+	query := s.schema.SubstitutePlaceholders(fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE repo_root = ? AND id > ?
+		ORDER BY id
+		LIMIT %d`, embeddingSelectColumns(false), s.tableName(), limit))
+	rows, err := s.db.Query(query, s.repoID, afterID)
+	if err != nil {
+		if isMissingTable(err) {
+			return []EmbeddingRecord{}, s.noEmbeddingsErr()
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	return s.scanEmbeddingRecords(rows, false)
 }
 
 // GetAllAcrossRepos retrieves all embeddings from the dimension-specific table.
-// If repoRoots is empty, returns all repos. If specified, filters to those repos.
+// If repoRoots is empty, returns all repos. If specified, filters to those repos,
+// given either as paths or as repo identities (see config.RepoIdentity).
 // This enables cross-repo semantic search within a dimension group.
 func (s *EmbeddingStore) GetAllAcrossRepos(repoRoots []string) ([]EmbeddingRecord, error) {
+	return s.GetAllAcrossReposForModel(repoRoots, "")
+}
+
+// GetAllAcrossReposForModel is GetAllAcrossRepos restricted to embeddings
+// made by model. An empty model returns every model's embeddings.
+func (s *EmbeddingStore) GetAllAcrossReposForModel(repoRoots []string, model string) ([]EmbeddingRecord, error) {
 	tableName := s.tableName()
 
 	var query string
 	var args []interface{}
 
+	modelFilter := ""
+	if model != "" {
+		modelFilter = "AND model = ?"
+	}
+
 	if len(repoRoots) == 0 {
 		// Get all repos in this dimension group
 		query = s.schema.SubstitutePlaceholders(fmt.Sprintf(`
-			SELECT id, repo_root, path, start_line, end_line, content_hash, embedding, model, created_at
+			SELECT %s
 			FROM %s
-			ORDER BY repo_root, path, start_line`, tableName))
+			WHERE 1 = 1 %s
+			ORDER BY repo_root, path, start_line`, embeddingSelectColumns(true), tableName, modelFilter))
 	} else {
-		// Filter to specific repos
-		placeholders := make([]string, len(repoRoots))
-		for i, r := range repoRoots {
-			placeholders[i] = "?"
-			args = append(args, r)
+		// Filter to specific repos, matching each by path or by identity
+		var placeholders []string
+		seen := make(map[string]bool)
+		for _, r := range repoRoots {
+			for _, key := range []string{r, config.RepoIdentity(r)} {
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				placeholders = append(placeholders, "?")
+				args = append(args, key)
+			}
 		}
 		query = s.schema.SubstitutePlaceholders(fmt.Sprintf(`
-			SELECT id, repo_root, path, start_line, end_line, content_hash, embedding, model, created_at
+			SELECT %s
 			FROM %s
-			WHERE repo_root IN (%s)
-			ORDER BY repo_root, path, start_line`, tableName, strings.Join(placeholders, ", ")))
+			WHERE repo_root IN (%s) %s
+			ORDER BY repo_root, path, start_line`, embeddingSelectColumns(true), tableName, strings.Join(placeholders, ", "), modelFilter))
+	}
+	if model != "" {
+		args = append(args, model)
 	}
 
 	rows, err := s.db.Query(query, args...)
 	if err != nil {
+		if isMissingTable(err) {
+			return []EmbeddingRecord{}, s.noEmbeddingsErr()
+		}
 		return nil, err
 	}
 	defer rows.Close()
 
-	return scanEmbeddingRecordsWithRepo(rows)
+	return s.scanEmbeddingRecords(rows, true)
 }
 
-// GetAllVectors retrieves just the embeddings for search
-func (s *EmbeddingStore) GetAllVectors() ([]EmbeddingRecord, error) {
-	return s.GetAll()
+// GetAllVectors retrieves just the embeddings for search, made by model
+// (all models if empty)
+func (s *EmbeddingStore) GetAllVectors(model string) ([]EmbeddingRecord, error) {
+	return s.GetAllForModel(model)
 }
 
 // HasEmbedding checks if a chunk already has an embedding with matching content within this repo
@@ -566,7 +773,7 @@ func (s *EmbeddingStore) HasEmbedding(chunk Chunk, model string) (bool, error) {
 
 	var count int
 	err := s.db.QueryRow(query,
-		s.repoRoot, chunk.Path, chunk.StartLine, chunk.EndLine, contentHash, model).Scan(&count)
+		s.repoID, s.relPath(chunk.Path), chunk.StartLine, chunk.EndLine, contentHash, model).Scan(&count)
 
 	if err != nil {
 		return false, err
@@ -578,7 +785,7 @@ func (s *EmbeddingStore) HasEmbedding(chunk Chunk, model string) (bool, error) {
 func (s *EmbeddingStore) DeleteByPath(path string) error {
 	tableName := s.tableName()
 	query := s.schema.SubstitutePlaceholders(fmt.Sprintf("DELETE FROM %s WHERE repo_root = ? AND path = ?", tableName))
-	_, err := s.db.Exec(query, s.repoRoot, path)
+	_, err := s.db.Exec(query, s.repoID, s.relPath(path))
 	return err
 }
 
@@ -586,7 +793,7 @@ func (s *EmbeddingStore) DeleteByPath(path string) error {
 func (s *EmbeddingStore) DeleteAll() error {
 	tableName := s.tableName()
 	query := s.schema.SubstitutePlaceholders(fmt.Sprintf("DELETE FROM %s WHERE repo_root = ?", tableName))
-	_, err := s.db.Exec(query, s.repoRoot)
+	_, err := s.db.Exec(query, s.repoID)
 	return err
 }
 
@@ -595,7 +802,10 @@ func (s *EmbeddingStore) Count() (int, error) {
 	tableName := s.tableName()
 	query := s.schema.SubstitutePlaceholders(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE repo_root = ?", tableName))
 	var count int
-	err := s.db.QueryRow(query, s.repoRoot).Scan(&count)
+	err := s.db.QueryRow(query, s.repoID).Scan(&count)
+	if err != nil && isMissingTable(err) {
+		return 0, s.noEmbeddingsErr()
+	}
 	return count, err
 }
 
@@ -603,55 +813,158 @@ func (s *EmbeddingStore) Count() (int, error) {
 func (s *EmbeddingStore) Stats() (count int, fileCount int, err error) {
 	tableName := s.tableName()
 	query := s.schema.SubstitutePlaceholders(fmt.Sprintf("SELECT COUNT(*), COUNT(DISTINCT path) FROM %s WHERE repo_root = ?", tableName))
-	err = s.db.QueryRow(query, s.repoRoot).Scan(&count, &fileCount)
+	err = s.db.QueryRow(query, s.repoID).Scan(&count, &fileCount)
 	return
 }
 
-func scanEmbeddingRecords(rows db.Rows) ([]EmbeddingRecord, error) {
-	var records []EmbeddingRecord
+// ListPaths returns the distinct paths embedded within this repo, sorted.
+// A missing dimension table yields an empty list.
+func (s *EmbeddingStore) ListPaths() ([]string, error) {
+	query := s.schema.SubstitutePlaceholders(fmt.Sprintf("SELECT DISTINCT path FROM %s WHERE repo_root = ? ORDER BY path", s.tableName()))
+	rows, err := s.db.Query(query, s.repoID)
+	if err != nil {
+		if isMissingTable(err) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("listing embedded paths: %w", err)
+	}
+	defer rows.Close()
 
+	paths := []string{}
 	for rows.Next() {
-		var r EmbeddingRecord
-		var embJSON string
-		var createdAt int64
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("scanning embedded path: %w", err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, rows.Err()
+}
 
-		err := rows.Scan(
-			&r.ID, &r.Path, &r.StartLine, &r.EndLine,
-			&r.ContentHash, &embJSON, &r.Model, &createdAt)
-		if err != nil {
-			return nil, err
+// CountsByRepo returns the number of embeddings per repo_root in this
+// store's dimension group. A missing dimension table yields an empty map.
+func (s *EmbeddingStore) CountsByRepo() (map[string]int, error) {
+	query := fmt.Sprintf("SELECT repo_root, COUNT(*) FROM %s GROUP BY repo_root", s.tableName())
+	rows, err := s.db.Query(query)
+	if err != nil {
+		if isMissingTable(err) {
+			return map[string]int{}, nil
 		}
+		return nil, fmt.Errorf("counting embeddings: %w", err)
+	}
+	defer rows.Close()
 
-		if err := json.Unmarshal([]byte(embJSON), &r.Embedding); err != nil {
-			return nil, fmt.Errorf("unmarshaling embedding: %w", err)
+	counts := make(map[string]int)
+	for rows.Next() {
+		var repoRoot string
+		var count int
+		if err := rows.Scan(&repoRoot, &count); err != nil {
+			return nil, fmt.Errorf("scanning embedding count: %w", err)
 		}
+		counts[repoRoot] = count
+	}
+	return counts, rows.Err()
+}
+
+// EmbeddingGroup counts the embeddings sharing a repo_root, model and
+// vector size.
+type EmbeddingGroup struct {
+	RepoRoot   string `json:"repo_root"`
+	Model      string `json:"model"`
+	Dimensions int    `json:"dimensions"`
+	Count      int    `json:"count"`
+}
+
+// CountsByGroup returns the number of embeddings per repo_root, model and
+// dimension across every repo in this store's table, ordered by repo and
+// model. SQLite rows each carry their own vector length, read by storage
+// class since a repacked row is a BLOB of 4 bytes per dimension; PostgreSQL
+// tables hold a single dimension. A missing table yields no groups.
+func (s *EmbeddingStore) CountsByGroup() ([]EmbeddingGroup, error) {
+	dimExpr := "CASE typeof(embedding) WHEN 'blob' THEN length(embedding) / 4 ELSE json_array_length(embedding) END"
+	if s.dialect.Name() == "postgres" {
+		dimExpr = fmt.Sprintf("%d", s.vectorDim)
+	}
+	query := fmt.Sprintf(`
+		SELECT repo_root, model, %s AS dims, COUNT(*) FROM %s
+		GROUP BY repo_root, model, dims
+		ORDER BY repo_root, model, dims`, dimExpr, s.tableName())
+	rows, err := s.db.Query(query)
+	if err != nil {
+		if isMissingTable(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("grouping embeddings: %w", err)
+	}
+	defer rows.Close()
 
-		r.CreatedAt = time.Unix(createdAt, 0)
-		records = append(records, r)
+	var groups []EmbeddingGroup
+	for rows.Next() {
+		var g EmbeddingGroup
+		if err := rows.Scan(&g.RepoRoot, &g.Model, &g.Dimensions, &g.Count); err != nil {
+			return nil, fmt.Errorf("scanning embedding group: %w", err)
+		}
+		groups = append(groups, g)
 	}
+	return groups, rows.Err()
+}
 
-	return records, rows.Err()
+// noEmbeddingsErr wraps ErrNoEmbeddingsForDimension with this store's table details.
+func (s *EmbeddingStore) noEmbeddingsErr() error {
+	return fmt.Errorf("%w (%d dimensions, table %s)", ErrNoEmbeddingsForDimension, s.vectorDim, s.tableName())
+}
+
+// isMissingTable reports whether err is a "table does not exist" error.
+// PostgreSQL reports `relation "x" does not exist` (SQLSTATE 42P01);
+// SQLite reports `no such table: x`.
+func isMissingTable(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return (strings.Contains(msg, "relation") && strings.Contains(msg, "does not exist")) ||
+		strings.Contains(msg, "42p01") ||
+		strings.Contains(msg, "no such table")
+}
+
+// embeddingColumns is the column list of every embedding record query, in
+// the order scanEmbeddingRecords reads it. Select it through
+// embeddingSelectColumns rather than spelling columns out, so queries and
+// the scan can't drift apart.
+const embeddingColumns = "id, path, start_line, end_line, content_hash, embedding, model, created_at"
+
+// embeddingSelectColumns returns the SELECT list for embedding records.
+// withRepo adds repo_root, for queries spanning repos.
+func embeddingSelectColumns(withRepo bool) string {
+	if withRepo {
+		return "repo_root, " + embeddingColumns
+	}
+	return embeddingColumns
 }
 
-// scanEmbeddingRecordsWithRepo scans rows that include repo_root column
-func scanEmbeddingRecordsWithRepo(rows db.Rows) ([]EmbeddingRecord, error) {
+// scanEmbeddingRecords scans rows selected with
+// embeddingSelectColumns(withRepo)
+func (s *EmbeddingStore) scanEmbeddingRecords(rows db.Rows, withRepo bool) ([]EmbeddingRecord, error) {
 	var records []EmbeddingRecord
 
 	for rows.Next() {
 		var r EmbeddingRecord
-		var embJSON string
+		var embRaw any
 		var createdAt int64
 
-		err := rows.Scan(
-			&r.ID, &r.RepoRoot, &r.Path, &r.StartLine, &r.EndLine,
-			&r.ContentHash, &embJSON, &r.Model, &createdAt)
-		if err != nil {
+		dest := []any{
+			&r.ID, &r.Path, &r.StartLine, &r.EndLine,
+			&r.ContentHash, &embRaw, &r.Model, &createdAt,
+		}
+		if withRepo {
+			dest = append([]any{&r.RepoRoot}, dest...)
+		}
+		if err := rows.Scan(dest...); err != nil {
 			return nil, err
 		}
 
-		if err := json.Unmarshal([]byte(embJSON), &r.Embedding); err != nil {
-			return nil, fmt.Errorf("unmarshaling embedding: %w", err)
+		embedding, err := s.decodeEmbedding(embRaw)
+		if err != nil {
+			return nil, err
 		}
+		r.Embedding = embedding
 
 		r.CreatedAt = time.Unix(createdAt, 0)
 		records = append(records, r)
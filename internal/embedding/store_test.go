@@ -0,0 +1,460 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"codetect/internal/db"
+)
+
+func TestEmbeddingStoreMissingDimensionTable(t *testing.T) {
+	database, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	store, err := NewEmbeddingStore(database, "/test/repo")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	// Simulate a dimension group that was never embedded
+	if _, err := database.Exec("DROP TABLE embeddings"); err != nil {
+		t.Fatalf("Failed to drop table: %v", err)
+	}
+
+	records, err := store.GetAll()
+	if !errors.Is(err, ErrNoEmbeddingsForDimension) {
+		t.Errorf("GetAll() error = %v, want ErrNoEmbeddingsForDimension", err)
+	}
+	if records == nil || len(records) != 0 {
+		t.Errorf("GetAll() = %v, want empty non-nil slice", records)
+	}
+
+	records, err = store.GetAllAcrossRepos(nil)
+	if !errors.Is(err, ErrNoEmbeddingsForDimension) {
+		t.Errorf("GetAllAcrossRepos() error = %v, want ErrNoEmbeddingsForDimension", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("GetAllAcrossRepos() returned %d records, want 0", len(records))
+	}
+
+	count, err := store.Count()
+	if !errors.Is(err, ErrNoEmbeddingsForDimension) {
+		t.Errorf("Count() error = %v, want ErrNoEmbeddingsForDimension", err)
+	}
+	if count != 0 {
+		t.Errorf("Count() = %d, want 0", count)
+	}
+
+	// Search reports "no embeddings" instead of a raw SQL error
+	searcher := NewSemanticSearcher(store, newMockEmbedder(3))
+	result, err := searcher.Search("query", 5)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if !result.Available || len(result.Results) != 0 || result.Error == "" {
+		t.Errorf("Search() = %+v, want available with empty results and message", result)
+	}
+}
+
+func TestIsMissingTable(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want bool
+	}{
+		{`pq: relation "embeddings_1024" does not exist`, true},
+		{`ERROR: relation "embeddings_1024" does not exist (SQLSTATE 42P01)`, true},
+		{"no such table: embeddings", true},
+		{"connection refused", false},
+		{`column "foo" does not exist`, false},
+	}
+
+	for _, tt := range tests {
+		if got := isMissingTable(errors.New(tt.msg)); got != tt.want {
+			t.Errorf("isMissingTable(%q) = %v, want %v", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestEmbeddingStoreRepoIDOverride(t *testing.T) {
+	database, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	t.Setenv("CODETECT_REPO_ID", "github.com/org/repo")
+
+	// Two checkouts of the same repo at different paths
+	first, err := NewEmbeddingStore(database, "/ci/run-1/repo")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	second, err := NewEmbeddingStore(database, "/ci/run-2/repo")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	chunk := Chunk{Path: "main.go", StartLine: 1, EndLine: 3, Content: "func main() {}"}
+	if err := first.Save(chunk, []float32{1, 0, 0}, "test"); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := second.Save(chunk, []float32{1, 0, 0}, "test"); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	records, err := second.GetAllAcrossRepos(nil)
+	if err != nil {
+		t.Fatalf("GetAllAcrossRepos() error = %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1 shared across checkouts", len(records))
+	}
+	if records[0].RepoRoot != "github.com/org/repo" {
+		t.Errorf("RepoRoot = %q, want the repo ID", records[0].RepoRoot)
+	}
+}
+
+func TestVerifyRepoConfigSQLite(t *testing.T) {
+	database, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	store, err := NewEmbeddingStore(database, "/test/repo")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	// Never embedded: nothing to compare against
+	cfg, err := store.VerifyRepoConfig(store.RepoID(), "nomic-embed-text", 768)
+	if cfg != nil || err != nil {
+		t.Errorf("VerifyRepoConfig() before embed = %v, %v; want nil, nil", cfg, err)
+	}
+
+	if err := store.SetRepoConfig(store.RepoID(), "nomic-embed-text", 768); err != nil {
+		t.Fatalf("SetRepoConfig() error = %v", err)
+	}
+
+	if _, err := store.VerifyRepoConfig(store.RepoID(), "nomic-embed-text", 768); err != nil {
+		t.Errorf("VerifyRepoConfig() matching config error = %v", err)
+	}
+	if _, err := store.VerifyRepoConfig(store.RepoID(), "", 768); err != nil {
+		t.Errorf("VerifyRepoConfig() dimensions only error = %v", err)
+	}
+
+	_, err = store.VerifyRepoConfig(store.RepoID(), "bge-m3", 1024)
+	if !errors.Is(err, ErrEmbeddingConfigMismatch) {
+		t.Fatalf("VerifyRepoConfig() mismatch error = %v, want ErrEmbeddingConfigMismatch", err)
+	}
+	for _, want := range []string{`"nomic-embed-text"`, "768", `"bge-m3"`, "1024"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("mismatch error %q missing %s", err, want)
+		}
+	}
+}
+
+func TestSemanticSearchFiltersByModel(t *testing.T) {
+	database, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	store, err := NewEmbeddingStore(database, "/test/repo")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	embedder := newMockEmbedder(3)
+	vectors, _ := embedder.Embed(context.Background(), []string{"query", "other"})
+
+	// Same chunk embedded by two models; only one is comparable to the query
+	chunk := Chunk{Path: "a.go", StartLine: 1, EndLine: 3, Content: "func a() {}"}
+	if err := store.Save(chunk, vectors[0], "other:model"); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := store.Save(chunk, vectors[1], embedder.ProviderID()); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	for model, want := range map[string]int{"": 2, "other:model": 1, embedder.ProviderID(): 1, "missing:model": 0} {
+		records, err := store.GetAllForModel(model)
+		if err != nil {
+			t.Fatalf("GetAllForModel(%q) error = %v", model, err)
+		}
+		if len(records) != want {
+			t.Errorf("GetAllForModel(%q) returned %d records, want %d", model, len(records), want)
+		}
+		if records, _ := store.GetAllAcrossReposForModel(nil, model); len(records) != want {
+			t.Errorf("GetAllAcrossReposForModel(nil, %q) returned %d records, want %d", model, len(records), want)
+		}
+	}
+
+	searcher := NewSemanticSearcher(store, embedder)
+	result, err := searcher.Search("query", 5)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(result.Results) != 1 {
+		t.Fatalf("Search() returned %d results, want only the %s embedding", len(result.Results), embedder.ProviderID())
+	}
+
+	searcher.SetModelFilter("missing:model")
+	result, _ = searcher.Search("query", 5)
+	if len(result.Results) != 0 || !strings.Contains(result.Error, "missing:model") {
+		t.Errorf("Search() with no matching model = %+v, want empty results naming the model", result)
+	}
+}
+
+func TestSearchAcrossReposWithSnippets(t *testing.T) {
+	database, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	embedder := newMockEmbedder(3)
+	vectors, _ := embedder.Embed(context.Background(), []string{"query"})
+
+	var searcher *SemanticSearcher
+	for _, repo := range []string{"/repos/a", "/repos/b"} {
+		store, err := NewEmbeddingStore(database, repo)
+		if err != nil {
+			t.Fatalf("Failed to create store: %v", err)
+		}
+		chunk := Chunk{Path: "main.go", StartLine: 1, EndLine: 3, Content: "func main() {}"}
+		if err := store.Save(chunk, vectors[0], embedder.ProviderID()); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		searcher = NewSemanticSearcher(store, embedder)
+	}
+
+	// Only repo a is "checked out"; repo b keeps the header alone
+	snippetFn := func(repoRoot, path string, start, end int) string {
+		if repoRoot == "/repos/a" {
+			return "func main() {}"
+		}
+		return ""
+	}
+
+	result, err := searcher.SearchAcrossReposWithSnippets(context.Background(), "query", 10, nil, snippetFn)
+	if err != nil {
+		t.Fatalf("SearchAcrossReposWithSnippets() error = %v", err)
+	}
+	if len(result.Results) != 2 {
+		t.Fatalf("got %d results, want one per repo", len(result.Results))
+	}
+	for _, r := range result.Results {
+		if !strings.HasPrefix(r.Snippet, "["+r.RepoRoot+"] [main.go:1-3]") {
+			t.Errorf("snippet %q is missing the %s header", r.Snippet, r.RepoRoot)
+		}
+		if hasCode := strings.Contains(r.Snippet, "func main"); hasCode != (r.RepoRoot == "/repos/a") {
+			t.Errorf("%s snippet = %q", r.RepoRoot, r.Snippet)
+		}
+	}
+
+	result, _ = searcher.SearchAcrossReposWithSnippets(context.Background(), "query", 10, []string{"/repos/b"}, snippetFn)
+	if len(result.Results) != 1 || result.Results[0].RepoRoot != "/repos/b" {
+		t.Errorf("repo filter returned %+v, want only /repos/b", result.Results)
+	}
+}
+
+func TestCountsByRepo(t *testing.T) {
+	database, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	var store *EmbeddingStore
+	for repo, n := range map[string]int{"/repos/a": 2, "/repos/b": 1} {
+		store, err = NewEmbeddingStore(database, repo)
+		if err != nil {
+			t.Fatalf("Failed to create store: %v", err)
+		}
+		for i := 0; i < n; i++ {
+			chunk := Chunk{Path: fmt.Sprintf("f%d.go", i), StartLine: 1, EndLine: 2, Content: "x"}
+			if err := store.Save(chunk, []float32{1, 0, 0}, "mock:test"); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+		}
+	}
+
+	counts, err := store.CountsByRepo()
+	if err != nil {
+		t.Fatalf("CountsByRepo() error = %v", err)
+	}
+	if len(counts) != 2 || counts["/repos/a"] != 2 || counts["/repos/b"] != 1 {
+		t.Errorf("CountsByRepo() = %v, want a:2 b:1", counts)
+	}
+}
+
+func TestEmbeddingStoreListPaths(t *testing.T) {
+	database, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	store, err := NewEmbeddingStore(database, "/repos/a")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	other, err := NewEmbeddingStore(database, "/repos/b")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	save := func(store *EmbeddingStore, path string, line int) {
+		chunk := Chunk{Path: path, StartLine: line, EndLine: line + 1, Content: fmt.Sprintf("%s:%d", path, line)}
+		if err := store.Save(chunk, []float32{1, 0, 0}, "mock:test"); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	save(store, "z.go", 1)
+	save(store, "a.go", 1)
+	save(store, "a.go", 10)
+	save(other, "b.go", 1)
+
+	paths, err := store.ListPaths()
+	if err != nil {
+		t.Fatalf("ListPaths() error = %v", err)
+	}
+	if want := []string{"a.go", "z.go"}; !slices.Equal(paths, want) {
+		t.Errorf("ListPaths() = %v, want %v", paths, want)
+	}
+}
+
+func TestSaveBatchSkipsInvalidEmbeddings(t *testing.T) {
+	database, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	store, err := NewEmbeddingStore(database, "/repos/a")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	nan := float32(math.NaN())
+	chunks := []Chunk{
+		{Path: "good.go", StartLine: 1, EndLine: 2, Content: "good"},
+		{Path: "nan.go", StartLine: 1, EndLine: 2, Content: "nan"},
+		{Path: "inf.go", StartLine: 1, EndLine: 2, Content: "inf"},
+		{Path: "zero.go", StartLine: 1, EndLine: 2, Content: "zero"},
+	}
+	embeddings := [][]float32{{1, 0, 0}, {nan, 1, 0}, {float32(math.Inf(-1)), 0, 0}, {0, 0, 0}}
+	if err := store.SaveBatch(chunks, embeddings, "mock:test"); err != nil {
+		t.Fatalf("SaveBatch() error = %v", err)
+	}
+	if err := store.Save(Chunk{Path: "nan2.go", StartLine: 1, EndLine: 2, Content: "nan2"}, []float32{nan}, "mock:test"); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	paths, err := store.ListPaths()
+	if err != nil {
+		t.Fatalf("ListPaths() error = %v", err)
+	}
+	if want := []string{"good.go"}; !slices.Equal(paths, want) {
+		t.Errorf("stored paths = %v, want only %v", paths, want)
+	}
+}
+
+// TestEmbeddingRecordColumns reads records back through every query that
+// scans embedding records, with and without repo_root, so a SELECT list
+// drifting from the scan shows up as a wrong field.
+func TestEmbeddingRecordColumns(t *testing.T) {
+	database, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	store, err := NewEmbeddingStore(database, "/repos/a")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	chunk := Chunk{Path: "pkg/f.go", StartLine: 3, EndLine: 9, Content: "func f() {}"}
+	vector := []float32{0.25, -0.5, 1}
+	if err := store.Save(chunk, vector, "mock:test"); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	check := func(name string, records []EmbeddingRecord, err error, wantRepo string) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s error = %v", name, err)
+		}
+		if len(records) != 1 {
+			t.Fatalf("%s returned %d records, want 1", name, len(records))
+		}
+		r := records[0]
+		if r.ID == 0 || r.RepoRoot != wantRepo || r.Path != "pkg/f.go" || r.StartLine != 3 || r.EndLine != 9 ||
+			r.ContentHash != hashContent(chunk.Content) || r.Model != "mock:test" || r.CreatedAt.IsZero() {
+			t.Errorf("%s = %+v, fields scanned into the wrong columns", name, r)
+		}
+		if len(r.Embedding) != len(vector) || r.Embedding[0] != vector[0] || r.Embedding[1] != vector[1] || r.Embedding[2] != vector[2] {
+			t.Errorf("%s embedding = %v, want %v", name, r.Embedding, vector)
+		}
+	}
+
+	records, err := store.GetByPath("pkg/f.go")
+	check("GetByPath", records, err, "")
+	records, err = store.GetAllForModel("mock:test")
+	check("GetAllForModel", records, err, "")
+	records, err = store.GetAllAcrossRepos(nil)
+	check("GetAllAcrossRepos(all)", records, err, "/repos/a")
+	records, err = store.GetAllAcrossReposForModel([]string{"/repos/a"}, "mock:test")
+	check("GetAllAcrossReposForModel", records, err, "/repos/a")
+}
+
+func TestEmbeddingStoreNormalizesPaths(t *testing.T) {
+	database, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	store, err := NewEmbeddingStore(database, "/test/repo")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	embedder := newMockEmbedder(3)
+	vectors, _ := embedder.Embed(context.Background(), []string{"query"})
+	chunk := Chunk{Path: "/test/repo/internal/a.go", StartLine: 1, EndLine: 3, Content: "func a() {}"}
+	if err := store.Save(chunk, vectors[0], embedder.ProviderID()); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Stored repo-relative, and found by either form
+	records, err := store.GetByPath("internal/a.go")
+	if err != nil || len(records) != 1 || records[0].Path != "internal/a.go" {
+		t.Fatalf("GetByPath(relative) = %+v, %v, want the chunk under internal/a.go", records, err)
+	}
+	if has, _ := store.HasEmbedding(chunk, embedder.ProviderID()); !has {
+		t.Error("HasEmbedding() with the absolute path = false, want true")
+	}
+
+	// Legacy rows holding absolute paths are returned relative
+	if _, err := database.Exec(`UPDATE embeddings SET path = '/test/repo/internal/a.go'`); err != nil {
+		t.Fatalf("rewriting path: %v", err)
+	}
+	result, err := NewSemanticSearcher(store, embedder).Search("query", 5)
+	if err != nil || len(result.Results) != 1 {
+		t.Fatalf("Search() = %+v, %v, want one result", result, err)
+	}
+	if got := result.Results[0].Path; got != "internal/a.go" {
+		t.Errorf("Search() path = %q, want internal/a.go", got)
+	}
+}
@@ -0,0 +1,33 @@
+package embedding
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// testDirs are directory names that conventionally hold only tests
+var testDirs = map[string]bool{
+	"test":      true,
+	"tests":     true,
+	"__tests__": true,
+}
+
+// IsTestPath reports whether p looks like a test file by convention:
+// Go's _test.go suffix, a .spec. or .test. infix (foo.spec.ts,
+// foo.test.js), or a test, tests or __tests__ directory anywhere in the
+// path.
+func IsTestPath(p string) bool {
+	p = filepath.ToSlash(p)
+	base := path.Base(p)
+	if strings.HasSuffix(base, "_test.go") || strings.Contains(base, ".spec.") || strings.Contains(base, ".test.") {
+		return true
+	}
+	dirs := strings.Split(path.Dir(p), "/")
+	for _, dir := range dirs {
+		if testDirs[dir] {
+			return true
+		}
+	}
+	return false
+}
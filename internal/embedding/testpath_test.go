@@ -0,0 +1,27 @@
+package embedding
+
+import "testing"
+
+func TestIsTestPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"internal/search/search_test.go", true},
+		{"web/src/app.spec.ts", true},
+		{"web/src/app.test.js", true},
+		{"test/fixtures/data.go", true},
+		{"pkg/tests/helpers.py", true},
+		{"web/src/__tests__/app.js", true},
+		{"internal/search/search.go", false},
+		{"internal/testutil/util.go", false},
+		{"cmd/contest/main.go", false},
+		{"latest.go", false},
+		{"test.go", false},
+	}
+	for _, tt := range tests {
+		if got := IsTestPath(tt.path); got != tt.expected {
+			t.Errorf("IsTestPath(%q) = %v, want %v", tt.path, got, tt.expected)
+		}
+	}
+}
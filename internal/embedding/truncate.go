@@ -0,0 +1,105 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// matryoshkaModels are models trained with Matryoshka representation
+// learning, whose leading dimensions form a usable smaller embedding.
+var matryoshkaModels = map[string]bool{
+	"nomic-embed-text":       true,
+	"mxbai-embed-large":      true,
+	"text-embedding-3-small": true,
+	"text-embedding-3-large": true,
+}
+
+// SupportsTruncation reports whether model is known to produce Matryoshka
+// embeddings that can be truncated. Ollama tags ("nomic-embed-text:latest")
+// and LiteLLM provider prefixes ("openai/text-embedding-3-small") are
+// ignored.
+func SupportsTruncation(model string) bool {
+	return matryoshkaModels[baseModelName(model)]
+}
+
+// baseModelName lowercases a model name and strips any LiteLLM provider
+// prefix and Ollama tag, so the same model compares equal across providers
+func baseModelName(model string) string {
+	model = strings.ToLower(model)
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	model, _, _ = strings.Cut(model, ":")
+	return model
+}
+
+// TruncatingEmbedder keeps the first Dims dimensions of another embedder's
+// vectors and re-normalizes them to unit length. Its ProviderID carries the
+// truncated size ("ollama:nomic-embed-text@256"), so truncated embeddings
+// are stored and searched apart from full-size ones.
+type TruncatingEmbedder struct {
+	Embedder
+	Dims int
+}
+
+// NewTruncatingEmbedder wraps e to truncate its vectors to dims, failing
+// if e's model isn't known to support Matryoshka truncation.
+func NewTruncatingEmbedder(e Embedder, dims int) (*TruncatingEmbedder, error) {
+	if dims <= 0 {
+		return nil, fmt.Errorf("invalid truncation size %d", dims)
+	}
+	if model := ModelName(e.ProviderID()); !SupportsTruncation(model) {
+		return nil, fmt.Errorf("model %s is not known to support Matryoshka truncation", model)
+	}
+	if full := e.Dimensions(); full > 0 && dims > full {
+		return nil, fmt.Errorf("cannot truncate %s's %d-dimension embeddings to %d", e.ProviderID(), full, dims)
+	}
+	return &TruncatingEmbedder{Embedder: e, Dims: dims}, nil
+}
+
+// Embed implements Embedder.Embed, truncating each vector
+func (t *TruncatingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors, err := t.Embedder.Embed(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range vectors {
+		if len(v) < t.Dims {
+			return nil, fmt.Errorf("%s returned %d dimensions, fewer than the truncation size %d", t.Embedder.ProviderID(), len(v), t.Dims)
+		}
+		vectors[i] = Normalize(v[:t.Dims])
+	}
+	return vectors, nil
+}
+
+// ProviderID implements Embedder.ProviderID, tagging the truncated size
+func (t *TruncatingEmbedder) ProviderID() string {
+	return fmt.Sprintf("%s@%d", t.Embedder.ProviderID(), t.Dims)
+}
+
+// Dimensions implements Embedder.Dimensions - returns the truncated size
+func (t *TruncatingEmbedder) Dimensions() int {
+	return t.Dims
+}
+
+// splitTruncation splits a provider ID or model name into the untruncated
+// model and the truncation size, 0 for full-size embeddings
+func splitTruncation(id string) (string, int) {
+	if i := strings.LastIndex(id, "@"); i >= 0 {
+		if n, err := strconv.Atoi(id[i+1:]); err == nil {
+			return id[:i], n
+		}
+	}
+	return id, 0
+}
+
+// MixesTruncation reports whether embeddings made by models a and b (both
+// model names or both provider IDs) are the same model at different
+// truncation sizes, which must not share a repo's index.
+func MixesTruncation(a, b string) bool {
+	baseA, sizeA := splitTruncation(a)
+	baseB, sizeB := splitTruncation(b)
+	return baseA == baseB && sizeA != sizeB
+}
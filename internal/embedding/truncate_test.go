@@ -0,0 +1,89 @@
+package embedding
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestSupportsTruncation(t *testing.T) {
+	tests := []struct {
+		model    string
+		expected bool
+	}{
+		{"nomic-embed-text", true},
+		{"nomic-embed-text:latest", true},
+		{"openai/text-embedding-3-small", true},
+		{"text-embedding-3-large", true},
+		{"bge-m3", false},
+		{"all-minilm", false},
+	}
+	for _, tt := range tests {
+		if got := SupportsTruncation(tt.model); got != tt.expected {
+			t.Errorf("SupportsTruncation(%q) = %v, want %v", tt.model, got, tt.expected)
+		}
+	}
+}
+
+// fixedEmbedder returns the same vector for every text
+type fixedEmbedder struct {
+	id     string
+	vector []float32
+}
+
+func (f *fixedEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i := range texts {
+		vectors[i] = append([]float32(nil), f.vector...)
+	}
+	return vectors, nil
+}
+func (f *fixedEmbedder) Available() bool    { return true }
+func (f *fixedEmbedder) ProviderID() string { return f.id }
+func (f *fixedEmbedder) Dimensions() int    { return len(f.vector) }
+
+func TestTruncatingEmbedder(t *testing.T) {
+	inner := &fixedEmbedder{id: "ollama:nomic-embed-text", vector: []float32{3, 4, 12, 0}}
+
+	if _, err := NewTruncatingEmbedder(&fixedEmbedder{id: "ollama:bge-m3", vector: inner.vector}, 2); err == nil {
+		t.Error("expected an error truncating a model without Matryoshka support")
+	}
+	if _, err := NewTruncatingEmbedder(inner, 8); err == nil {
+		t.Error("expected an error truncating past the model's dimensions")
+	}
+
+	e, err := NewTruncatingEmbedder(inner, 2)
+	if err != nil {
+		t.Fatalf("NewTruncatingEmbedder() error = %v", err)
+	}
+	if e.ProviderID() != "ollama:nomic-embed-text@2" || e.Dimensions() != 2 {
+		t.Errorf("ProviderID() = %q, Dimensions() = %d, want the truncated size in both", e.ProviderID(), e.Dimensions())
+	}
+
+	vectors, err := e.Embed(context.Background(), []string{"a"})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	want := []float32{0.6, 0.8}
+	if len(vectors[0]) != 2 || math.Abs(float64(vectors[0][0]-want[0])) > 1e-6 || math.Abs(float64(vectors[0][1]-want[1])) > 1e-6 {
+		t.Errorf("Embed() = %v, want the first 2 dimensions re-normalized to %v", vectors[0], want)
+	}
+}
+
+func TestMixesTruncation(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"nomic-embed-text", "nomic-embed-text@256", true},
+		{"nomic-embed-text@256", "nomic-embed-text@512", true},
+		{"nomic-embed-text@256", "nomic-embed-text@256", false},
+		{"nomic-embed-text", "nomic-embed-text", false},
+		{"bge-m3", "nomic-embed-text@256", false},
+	}
+	for _, tt := range tests {
+		if got := MixesTruncation(tt.a, tt.b); got != tt.expected {
+			t.Errorf("MixesTruncation(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package embedding
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 
@@ -313,6 +314,7 @@ func (s *SQLiteVectorIndex) Sync(ctx context.Context) error {
 type BruteForceVectorIndex struct {
 	store      *EmbeddingStore
 	vectors    map[string][]float32
+	norms      map[string]float64 // L2 norm of each vector, computed on insert
 	mu         sync.RWMutex
 	dimensions int
 }
@@ -323,6 +325,7 @@ func NewBruteForceVectorIndex(store *EmbeddingStore, dimensions int) *BruteForce
 	return &BruteForceVectorIndex{
 		store:      store,
 		vectors:    make(map[string][]float32),
+		norms:      make(map[string]float64),
 		dimensions: dimensions,
 	}
 }
@@ -331,7 +334,7 @@ func NewBruteForceVectorIndex(store *EmbeddingStore, dimensions int) *BruteForce
 func (b *BruteForceVectorIndex) Insert(ctx context.Context, contentHash string, embedding []float32) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	b.vectors[contentHash] = embedding
+	b.put(contentHash, embedding)
 	return nil
 }
 
@@ -340,7 +343,7 @@ func (b *BruteForceVectorIndex) InsertBatch(ctx context.Context, entries map[str
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	for hash, emb := range entries {
-		b.vectors[hash] = emb
+		b.put(hash, emb)
 	}
 	return nil
 }
@@ -360,16 +363,18 @@ func (b *BruteForceVectorIndex) Search(ctx context.Context, query []float32, k i
 		b.mu.RLock()
 	}
 
-	// Convert to slice for TopKByCosineSimilarity
+	// Convert to slices for TopKByCosineSimilarityNorms
 	hashes := make([]string, 0, len(b.vectors))
 	vectors := make([][]float32, 0, len(b.vectors))
+	norms := make([]float64, 0, len(b.vectors))
 	for hash, vec := range b.vectors {
 		hashes = append(hashes, hash)
 		vectors = append(vectors, vec)
+		norms = append(norms, b.norms[hash])
 	}
 
-	// Find top-k
-	topK := TopKByCosineSimilarity(query, vectors, k)
+	// Find top-k; only the query's norm is computed per search
+	topK := TopKByCosineSimilarityNorms(query, vectors, norms, k)
 
 	results := make([]VectorResult, len(topK))
 	for i, item := range topK {
@@ -411,6 +416,7 @@ func (b *BruteForceVectorIndex) Delete(ctx context.Context, contentHash string)
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	delete(b.vectors, contentHash)
+	delete(b.norms, contentHash)
 	return nil
 }
 
@@ -420,6 +426,7 @@ func (b *BruteForceVectorIndex) DeleteBatch(ctx context.Context, contentHashes [
 	defer b.mu.Unlock()
 	for _, hash := range contentHashes {
 		delete(b.vectors, hash)
+		delete(b.norms, hash)
 	}
 	return nil
 }
@@ -428,6 +435,7 @@ func (b *BruteForceVectorIndex) DeleteBatch(ctx context.Context, contentHashes [
 func (b *BruteForceVectorIndex) Rebuild(ctx context.Context) error {
 	b.mu.Lock()
 	b.vectors = make(map[string][]float32)
+	b.norms = make(map[string]float64)
 	b.mu.Unlock()
 	return b.loadFromStore(ctx)
 }
@@ -451,7 +459,7 @@ func (b *BruteForceVectorIndex) loadFromStore(ctx context.Context) error {
 	}
 
 	records, err := b.store.GetAll()
-	if err != nil {
+	if err != nil && !errors.Is(err, ErrNoEmbeddingsForDimension) {
 		return fmt.Errorf("loading embeddings: %w", err)
 	}
 
@@ -459,12 +467,18 @@ func (b *BruteForceVectorIndex) loadFromStore(ctx context.Context) error {
 	defer b.mu.Unlock()
 
 	for _, r := range records {
-		b.vectors[r.ContentHash] = r.Embedding
+		b.put(r.ContentHash, r.Embedding)
 	}
 
 	return nil
 }
 
+// put stores a vector and its norm. Callers hold the write lock.
+func (b *BruteForceVectorIndex) put(contentHash string, embedding []float32) {
+	b.vectors[contentHash] = embedding
+	b.norms[contentHash] = vectorNorm(embedding)
+}
+
 // NewVectorIndex creates the appropriate VectorIndex for the given database type.
 // Returns PostgresVectorIndex for PostgreSQL, SQLiteVectorIndex for SQLite,
 // or falls back to BruteForceVectorIndex if native HNSW is not available.
@@ -546,7 +560,7 @@ func (v *VectorIndexWithLocation) resolveLocations(ctx context.Context, results
 
 	// Get all embeddings and build a lookup map
 	all, err := v.store.GetAll()
-	if err != nil {
+	if err != nil && !errors.Is(err, ErrNoEmbeddingsForDimension) {
 		return nil, fmt.Errorf("getting embeddings: %w", err)
 	}
 
@@ -0,0 +1,197 @@
+package embedding
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// VectorFormat is how a SQLite index stores embedding vectors. PostgreSQL
+// always uses its native vector type.
+type VectorFormat string
+
+const (
+	// VectorFormatJSON stores vectors as JSON arrays of floats in TEXT
+	VectorFormatJSON VectorFormat = "json"
+	// VectorFormatBinary stores vectors as packed little-endian float32
+	// BLOBs, 4 bytes per dimension: about a third of the size of JSON and
+	// decoded without parsing.
+	VectorFormatBinary VectorFormat = "binary"
+)
+
+// ParseVectorFormat parses "json" or "binary" (or "float32", an alias for
+// binary).
+func ParseVectorFormat(s string) (VectorFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "json":
+		return VectorFormatJSON, nil
+	case "binary", "float32":
+		return VectorFormatBinary, nil
+	}
+	return "", fmt.Errorf("unknown vector format %q (want json or binary)", s)
+}
+
+// encodeFloat32s packs v as little-endian float32s
+func encodeFloat32s(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+	}
+	return buf
+}
+
+// decodeFloat32s unpacks a vector written by encodeFloat32s
+func decodeFloat32s(buf []byte) ([]float32, error) {
+	if len(buf)%4 != 0 {
+		return nil, fmt.Errorf("packed embedding is %d bytes, not a multiple of 4", len(buf))
+	}
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v, nil
+}
+
+// encodeEmbedding returns the value written to the embedding column: a
+// packed BLOB for a binary SQLite index, JSON text otherwise.
+func (s *EmbeddingStore) encodeEmbedding(v []float32) (any, error) {
+	if s.vectorFormat == VectorFormatBinary && s.dialect.Name() == "sqlite" {
+		return encodeFloat32s(v), nil
+	}
+	embJSON, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(embJSON), nil
+}
+
+// decodeEmbedding decodes an embedding column value. Each SQLite row is
+// read by its storage class, TEXT holding JSON and BLOB a packed vector, so
+// an index only partly repacked still reads. PostgreSQL drivers may return
+// the vector's text form as bytes, so there bytes are always JSON.
+func (s *EmbeddingStore) decodeEmbedding(raw any) ([]float32, error) {
+	var v []float32
+	switch raw := raw.(type) {
+	case string:
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			return nil, fmt.Errorf("unmarshaling embedding: %w", err)
+		}
+	case []byte:
+		if s.dialect.Name() == "sqlite" {
+			return decodeFloat32s(raw)
+		}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, fmt.Errorf("unmarshaling embedding: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected embedding column type %T", raw)
+	}
+	return v, nil
+}
+
+// VectorFormat returns the format this store writes vectors in.
+func (s *EmbeddingStore) VectorFormat() VectorFormat {
+	return s.vectorFormat
+}
+
+// loadVectorFormat reads the index's vector format from its metadata,
+// defaulting to JSON for indexes that predate the setting.
+func (s *EmbeddingStore) loadVectorFormat() error {
+	s.vectorFormat = VectorFormatJSON
+	value, err := s.GetMetadata(MetaVectorFormat)
+	if err != nil || value == "" {
+		return err
+	}
+	format, err := ParseVectorFormat(value)
+	if err != nil {
+		return fmt.Errorf("index metadata: %w", err)
+	}
+	s.vectorFormat = format
+	return nil
+}
+
+// repackBatch is how many rows Repack rewrites per query
+const repackBatch = 1000
+
+// Repack rewrites every stored vector in format and records it as the
+// index's format, so later saves use it too. SQLite only: a SQLite database
+// holds a single repo's index, so all its rows are repacked. Rows already
+// in format are rewritten harmlessly. Returns the number of rows
+// rewritten; run VACUUM afterwards to return the freed space to the
+// filesystem.
+func (s *EmbeddingStore) Repack(format VectorFormat) (int, error) {
+	if s.dialect.Name() != "sqlite" {
+		return 0, fmt.Errorf("repacking vectors is only supported on SQLite, PostgreSQL uses its native vector type")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	target := &EmbeddingStore{dialect: s.dialect, vectorFormat: format}
+	update, err := tx.Prepare("UPDATE embeddings SET embedding = ? WHERE id = ?")
+	if err != nil {
+		return 0, err
+	}
+	defer update.Close()
+
+	type row struct {
+		id  int64
+		raw any
+	}
+	repacked := 0
+	var afterID int64
+	for {
+		rows, err := tx.Query("SELECT id, embedding FROM embeddings WHERE id > ? ORDER BY id LIMIT ?", afterID, repackBatch)
+		if err != nil {
+			return repacked, fmt.Errorf("reading embeddings: %w", err)
+		}
+		var batch []row
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.id, &r.raw); err != nil {
+				rows.Close()
+				return repacked, err
+			}
+			batch = append(batch, r)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return repacked, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, r := range batch {
+			v, err := s.decodeEmbedding(r.raw)
+			if err != nil {
+				return repacked, fmt.Errorf("embedding %d: %w", r.id, err)
+			}
+			value, err := target.encodeEmbedding(v)
+			if err != nil {
+				return repacked, fmt.Errorf("embedding %d: %w", r.id, err)
+			}
+			if _, err := update.Exec(value, r.id); err != nil {
+				return repacked, fmt.Errorf("rewriting embedding %d: %w", r.id, err)
+			}
+			repacked++
+		}
+		afterID = batch[len(batch)-1].id
+	}
+
+	upsertSQL := s.dialect.UpsertSQL("index_metadata", []string{"key", "value"}, []string{"key"}, []string{"value"})
+	if _, err := tx.Exec(s.schema.SubstitutePlaceholders(upsertSQL), MetaVectorFormat, string(format)); err != nil {
+		return repacked, fmt.Errorf("setting metadata %s: %w", MetaVectorFormat, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return repacked, err
+	}
+	s.vectorFormat = format
+	return repacked, nil
+}
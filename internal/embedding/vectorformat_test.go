@@ -0,0 +1,132 @@
+package embedding
+
+import (
+	"slices"
+	"testing"
+
+	"codetect/internal/db"
+)
+
+func TestFloat32RoundTrip(t *testing.T) {
+	v := []float32{0, 1, -0.5, 3.25e-8, 1e30}
+	got, err := decodeFloat32s(encodeFloat32s(v))
+	if err != nil {
+		t.Fatalf("decodeFloat32s() error = %v", err)
+	}
+	if !slices.Equal(got, v) {
+		t.Errorf("round trip = %v, want %v", got, v)
+	}
+	if _, err := decodeFloat32s([]byte{1, 2, 3}); err == nil {
+		t.Error("decodeFloat32s() of 3 bytes succeeded, want an error")
+	}
+}
+
+func TestRepack(t *testing.T) {
+	database, err := db.Open(db.DefaultConfig(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	store, err := NewEmbeddingStore(database, "/repos/a")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	if f := store.VectorFormat(); f != VectorFormatJSON {
+		t.Fatalf("new index VectorFormat() = %q, want json", f)
+	}
+
+	save := func(path string, v []float32) {
+		t.Helper()
+		chunk := Chunk{Path: path, StartLine: 1, EndLine: 2, Content: path}
+		if err := store.Save(chunk, v, "mock:test"); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	storageClasses := func() []string {
+		t.Helper()
+		rows, err := database.Query("SELECT typeof(embedding) FROM embeddings ORDER BY path")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var classes []string
+		for rows.Next() {
+			var class string
+			if err := rows.Scan(&class); err != nil {
+				t.Fatal(err)
+			}
+			classes = append(classes, class)
+		}
+		return classes
+	}
+	vectors := func() [][]float32 {
+		t.Helper()
+		records, err := store.GetAll()
+		if err != nil {
+			t.Fatalf("GetAll() error = %v", err)
+		}
+		var vs [][]float32
+		for _, r := range records {
+			vs = append(vs, r.Embedding)
+		}
+		return vs
+	}
+
+	save("a.go", []float32{0.1, 0.2, 0.3})
+	save("b.go", []float32{-1, 0, 2.5})
+	want := vectors()
+
+	n, err := store.Repack(VectorFormatBinary)
+	if err != nil {
+		t.Fatalf("Repack() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Repack() rewrote %d rows, want 2", n)
+	}
+	if got := storageClasses(); !slices.Equal(got, []string{"blob", "blob"}) {
+		t.Errorf("storage after repack = %v, want blobs", got)
+	}
+	if got := vectors(); !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("vectors after repack = %v, want %v", got, want)
+	}
+
+	// The format sticks for later saves and reopened stores
+	save("c.go", []float32{1, 1, 1})
+	reopened, err := NewEmbeddingStore(database, "/repos/a")
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	if f := reopened.VectorFormat(); f != VectorFormatBinary {
+		t.Errorf("reopened VectorFormat() = %q, want binary", f)
+	}
+	if got := storageClasses(); !slices.Equal(got, []string{"blob", "blob", "blob"}) {
+		t.Errorf("storage after saving = %v, want blobs", got)
+	}
+
+	// A partly repacked index (JSON rows among blobs) still reads
+	if _, err := database.Exec("UPDATE embeddings SET embedding = '[0.5,0.5,0.5]' WHERE path = 'c.go'"); err != nil {
+		t.Fatal(err)
+	}
+	want = append(want, []float32{0.5, 0.5, 0.5})
+	if got := vectors(); !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("mixed-format vectors = %v, want %v", got, want)
+	}
+	groups, err := store.CountsByGroup()
+	if err != nil {
+		t.Fatalf("CountsByGroup() on a mixed-format index error = %v", err)
+	}
+	if len(groups) != 1 || groups[0].Dimensions != 3 || groups[0].Count != 3 {
+		t.Errorf("CountsByGroup() = %+v, want 3 embeddings of 3 dimensions", groups)
+	}
+
+	if _, err := store.Repack(VectorFormatJSON); err != nil {
+		t.Fatalf("Repack(json) error = %v", err)
+	}
+	if got := storageClasses(); !slices.Equal(got, []string{"text", "text", "text"}) {
+		t.Errorf("storage after repacking to JSON = %v, want text", got)
+	}
+	if got := vectors(); !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("vectors after repacking to JSON = %v, want %v", got, want)
+	}
+}
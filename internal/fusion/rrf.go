@@ -51,6 +51,37 @@ type RRFResult struct {
 
 	// Sources lists which search signals contributed to this result
 	Sources []string
+
+	// Explain is the score breakdown, populated only by Explain
+	Explain *Explanation `json:"explain,omitempty"`
+}
+
+// Explanation breaks down how a fused result was scored.
+type Explanation struct {
+	// Contributions are the per-source RRF components, in list order
+	Contributions []Contribution `json:"contributions"`
+
+	// RRFScore is the fused score before any reranking
+	RRFScore float64 `json:"rrf_score"`
+
+	// Rerank is set when a reranker rescored this result
+	Rerank *RerankExplanation `json:"rerank,omitempty"`
+}
+
+// Contribution is one source list's part in a fused score.
+type Contribution struct {
+	Source string  `json:"source"`
+	Rank   int     `json:"rank"`   // 1-indexed position in the source list
+	Score  float64 `json:"score"`  // Original score from the source
+	Weight float64 `json:"weight"` // Weight applied to the source
+	RRF    float64 `json:"rrf"`    // weight / (k + rank)
+}
+
+// RerankExplanation records how reranking changed a result.
+type RerankExplanation struct {
+	Score      float64 `json:"score"`       // Reranker score that replaced the RRF score
+	RankBefore int     `json:"rank_before"` // 1-indexed rank after fusion
+	RankAfter  int     `json:"rank_after"`  // 1-indexed rank after reranking
 }
 
 // ReciprocalRankFusion combines multiple ranked lists using the RRF algorithm.
@@ -102,7 +133,14 @@ func ReciprocalRankFusion(lists ...[]Result) []RRFResult {
 			return results[i].Score > results[j].Score
 		}
 		// Tertiary: number of sources (more sources = higher confidence)
-		return len(results[i].Sources) > len(results[j].Sources)
+		if len(results[i].Sources) != len(results[j].Sources) {
+			return len(results[i].Sources) > len(results[j].Sources)
+		}
+		// Finally path and line, so ties come out the same on every run
+		if results[i].Path != results[j].Path {
+			return results[i].Path < results[j].Path
+		}
+		return results[i].Line < results[j].Line
 	})
 
 	return results
@@ -160,12 +198,49 @@ func WeightedRRF(weights map[string]float64, lists ...[]Result) []RRFResult {
 			return results[i].Score > results[j].Score
 		}
 		// Tertiary: number of sources (more sources = higher confidence)
-		return len(results[i].Sources) > len(results[j].Sources)
+		if len(results[i].Sources) != len(results[j].Sources) {
+			return len(results[i].Sources) > len(results[j].Sources)
+		}
+		// Finally path and line, so ties come out the same on every run
+		if results[i].Path != results[j].Path {
+			return results[i].Path < results[j].Path
+		}
+		return results[i].Line < results[j].Line
 	})
 
 	return results
 }
 
+// Explain populates the Explain field of each result with its per-source
+// contributions, using the same weights and lists the results were fused
+// from. Pass nil weights for results from ReciprocalRankFusion.
+func Explain(results []RRFResult, weights map[string]float64, lists ...[]Result) []RRFResult {
+	byID := make(map[string][]Contribution)
+	for _, list := range lists {
+		for rank, result := range list {
+			weight := weights[result.Source]
+			if weight == 0 {
+				weight = 1.0
+			}
+			byID[result.ID] = append(byID[result.ID], Contribution{
+				Source: result.Source,
+				Rank:   rank + 1,
+				Score:  result.Score,
+				Weight: weight,
+				RRF:    weight / float64(RRFConstant+rank+1),
+			})
+		}
+	}
+
+	for i := range results {
+		results[i].Explain = &Explanation{
+			Contributions: byID[results[i].ID],
+			RRFScore:      results[i].RRFScore,
+		}
+	}
+	return results
+}
+
 // TopN returns the top N results from an RRF result list.
 // If n is greater than the list length, returns all results.
 func TopN(results []RRFResult, n int) []RRFResult {
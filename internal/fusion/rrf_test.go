@@ -1,6 +1,7 @@
 package fusion
 
 import (
+	"math"
 	"testing"
 )
 
@@ -95,6 +96,22 @@ func TestReciprocalRankFusionSingleList(t *testing.T) {
 	}
 }
 
+func TestReciprocalRankFusionTiesOrderedByPath(t *testing.T) {
+	// Each list's top result gets the same RRF and original score
+	keyword := []Result{{ID: "z.go:1", Path: "z.go", Line: 1, Score: 1, Source: "keyword"}}
+	semantic := []Result{
+		{ID: "a.go:9", Path: "a.go", Line: 9, Score: 1, Source: "semantic"},
+	}
+	more := []Result{{ID: "a.go:2", Path: "a.go", Line: 2, Score: 1, Source: "symbol"}}
+
+	for range 10 {
+		results := ReciprocalRankFusion(keyword, semantic, more)
+		if len(results) != 3 || results[0].ID != "a.go:2" || results[1].ID != "a.go:9" || results[2].ID != "z.go:1" {
+			t.Fatalf("tied results = %+v, want a.go:2, a.go:9, z.go:1", results)
+		}
+	}
+}
+
 func TestWeightedRRF(t *testing.T) {
 	weights := map[string]float64{
 		"keyword":  0.3,
@@ -145,6 +162,41 @@ func TestWeightedRRFDefaultWeight(t *testing.T) {
 	}
 }
 
+func TestExplain(t *testing.T) {
+	weights := map[string]float64{"keyword": 0.5, "semantic": 2.0}
+	keyword := []Result{{ID: "a", Source: "keyword", Score: 3}, {ID: "b", Source: "keyword", Score: 1}}
+	semantic := []Result{{ID: "b", Source: "semantic", Score: 0.9}}
+
+	results := WeightedRRF(weights, keyword, semantic)
+	for _, r := range results {
+		if r.Explain != nil {
+			t.Fatal("WeightedRRF should not populate Explain")
+		}
+	}
+
+	results = Explain(results, weights, keyword, semantic)
+	if results[0].ID != "b" {
+		t.Fatalf("expected 'b' first, got %q", results[0].ID)
+	}
+
+	explain := results[0].Explain
+	if explain == nil || len(explain.Contributions) != 2 {
+		t.Fatalf("expected 2 contributions for 'b', got %+v", explain)
+	}
+	kw := explain.Contributions[0]
+	if kw.Source != "keyword" || kw.Rank != 2 || kw.Score != 1 || kw.Weight != 0.5 {
+		t.Errorf("unexpected keyword contribution: %+v", kw)
+	}
+
+	var sum float64
+	for _, c := range explain.Contributions {
+		sum += c.RRF
+	}
+	if math.Abs(sum-results[0].RRFScore) > 1e-12 || explain.RRFScore != results[0].RRFScore {
+		t.Errorf("contributions sum to %f, RRF score is %f", sum, results[0].RRFScore)
+	}
+}
+
 func TestTopN(t *testing.T) {
 	results := []RRFResult{
 		{Result: Result{ID: "a"}, RRFScore: 1.0},
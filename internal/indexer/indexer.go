@@ -9,11 +9,14 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	ignore "github.com/sabhiram/go-gitignore"
 
 	"codetect/internal/chunker"
+	"codetect/internal/config"
 	"codetect/internal/db"
 	"codetect/internal/embedding"
 	"codetect/internal/merkle"
@@ -26,6 +29,7 @@ import (
 // - Optional HNSW vector indexing
 type Indexer struct {
 	repoPath string
+	repoID   string // Key for location scoping (see config.RepoID)
 	dataDir  string
 
 	// Components
@@ -35,6 +39,7 @@ type Indexer struct {
 	cache         *embedding.EmbeddingCache
 	locations     *embedding.LocationStore
 	vectorIndex   embedding.VectorIndex
+	hnsw          *embedding.HNSWIndex // Persisted graph backing vectorIndex on SQLite
 	embedder      embedding.Embedder
 	pipeline      *embedding.Pipeline
 
@@ -58,18 +63,41 @@ type Config struct {
 	EmbeddingProvider string // "ollama", "litellm", or "off"
 	EmbeddingModel    string // Model name
 	Dimensions        int    // Vector dimensions
+	TruncateDim       int    // Matryoshka truncation size (0 = full size)
 	OllamaURL         string // Ollama API URL
 	LiteLLMURL        string // LiteLLM API URL
 	LiteLLMKey        string // LiteLLM API key
 
 	// Pipeline settings
 	BatchSize  int // Batch size for embedding API calls
-	MaxWorkers int // Max concurrent embedding workers
+	MaxWorkers int // Max concurrent chunking and embedding workers
+
+	// BatchBytes caps the file content held in memory per indexing batch.
+	// A file bigger than the cap gets a batch to itself. 0 means
+	// DefaultBatchBytes.
+	BatchBytes int64
 
 	// Ignore patterns (from .gitignore)
 	IgnorePatterns []string
+
+	// DocComments extracts leading doc comments/docstrings and embeds them
+	// separately so search can blend a docstring match into the score
+	DocComments bool
+
+	// MaxDepth limits how many directory levels below the repo root are
+	// indexed (0 = unlimited)
+	MaxDepth int
+
+	// OneFileSystem keeps the walk from crossing into other mounts
+	OneFileSystem bool
 }
 
+// DefaultBatchBytes is the default file content budget per indexing batch.
+const DefaultBatchBytes = 32 << 20
+
+// maxBatchFiles caps the files per indexing batch however small they are.
+const maxBatchFiles = 100
+
 // DefaultConfig returns the default indexer configuration.
 func DefaultConfig() *Config {
 	return &Config{
@@ -84,6 +112,46 @@ func DefaultConfig() *Config {
 	}
 }
 
+// LoadConfigFromEnv builds the indexer configuration for repoPath from the
+// CODETECT_* database, embedding and indexing variables plus the repo's
+// .gitignore. The CLI and the MCP tools both use it, so the v2 index the
+// tools open is the one `codetect-index index --v2` wrote.
+func LoadConfigFromEnv(repoPath string) *Config {
+	dbConfig := config.LoadDatabaseConfigFromEnv()
+	embConfig := embedding.LoadConfigFromEnv()
+
+	indexConfig := config.LoadIndexConfigFromEnv()
+
+	cfg := &Config{
+		DBType:            string(dbConfig.Type),
+		Dimensions:        dbConfig.VectorDimensions,
+		EmbeddingProvider: string(embConfig.Provider),
+		EmbeddingModel:    embConfig.Model,
+		TruncateDim:       embConfig.TruncateDim,
+		OllamaURL:         embConfig.OllamaURL,
+		LiteLLMURL:        embConfig.LiteLLMURL,
+		LiteLLMKey:        embConfig.LiteLLMKey,
+		BatchSize:         32,
+		MaxWorkers:        4,
+		IgnorePatterns:    LoadGitignore(repoPath),
+		DocComments:       indexConfig.DocComments,
+		MaxDepth:          indexConfig.MaxDepth,
+		OneFileSystem:     indexConfig.OneFileSystem,
+	}
+	if indexConfig.Workers > 0 {
+		cfg.MaxWorkers = indexConfig.Workers
+	}
+	cfg.BatchBytes = indexConfig.BatchBytes
+
+	if dbConfig.Type == db.DatabasePostgres {
+		cfg.DSN = dbConfig.DSN
+	} else {
+		cfg.DBPath = filepath.Join(config.DataDir(repoPath), "index.db")
+	}
+
+	return cfg
+}
+
 // New creates a new v2 indexer.
 func New(repoPath string, cfg *Config) (*Indexer, error) {
 	if cfg == nil {
@@ -94,14 +162,16 @@ func New(repoPath string, cfg *Config) (*Indexer, error) {
 	if err != nil {
 		return nil, fmt.Errorf("resolving path: %w", err)
 	}
+	absPath = config.NormalizeRepoRoot(absPath)
 
-	dataDir := filepath.Join(absPath, ".codetect")
+	dataDir := config.DataDir(absPath)
 	if err := os.MkdirAll(dataDir, 0755); err != nil {
 		return nil, fmt.Errorf("creating data directory: %w", err)
 	}
 
 	idx := &Indexer{
 		repoPath: absPath,
+		repoID:   config.RepoID(absPath),
 		dataDir:  dataDir,
 		config:   cfg,
 		logger:   slog.Default(),
@@ -118,6 +188,13 @@ func New(repoPath string, cfg *Config) (*Indexer, error) {
 		return nil, fmt.Errorf("initializing components: %w", err)
 	}
 
+	// Pick up rows indexed under the path before the repo's key changed
+	if moved, err := db.AdoptRepoKey(context.Background(), idx.database, idx.dialect, absPath, idx.repoID); err != nil {
+		idx.logger.Warn("could not move index rows to the repo's key", "from", absPath, "to", idx.repoID, "error", err)
+	} else if moved {
+		idx.logger.Info("moved index rows to the repo's key", "from", absPath, "to", idx.repoID)
+	}
+
 	return idx, nil
 }
 
@@ -156,17 +233,11 @@ func (idx *Indexer) initDatabase() error {
 func (idx *Indexer) initComponents() error {
 	// Merkle tree components
 	idx.merkleStore = merkle.NewStore(idx.dataDir)
-	idx.merkleBuilder = merkle.NewBuilder()
-	// Add any additional ignore patterns
-	if len(idx.config.IgnorePatterns) > 0 {
-		idx.merkleBuilder.IgnorePatterns = append(
-			idx.merkleBuilder.IgnorePatterns,
-			idx.config.IgnorePatterns...,
-		)
-	}
+	idx.merkleBuilder = newMerkleBuilder(idx.config)
 
 	// AST chunker
 	idx.astChunker = chunker.NewASTChunker()
+	idx.astChunker.ExtractDocComments = idx.config.DocComments
 
 	// Embedding cache and locations
 	var err error
@@ -174,7 +245,7 @@ func (idx *Indexer) initComponents() error {
 		idx.database,
 		idx.dialect,
 		idx.config.Dimensions,
-		idx.config.EmbeddingModel,
+		idx.cacheModel(),
 	)
 	if err != nil {
 		return fmt.Errorf("creating embedding cache: %w", err)
@@ -185,10 +256,12 @@ func (idx *Indexer) initComponents() error {
 		return fmt.Errorf("creating location store: %w", err)
 	}
 
-	// Vector index (create brute force as fallback)
-	// The NewBruteForceVectorIndex needs an EmbeddingStore, but we can skip it
-	// for now since vector index is optional
-	idx.vectorIndex = nil // Will be initialized when needed
+	// Vector index: SQLite gets a Go-native HNSW graph persisted in the data
+	// directory, built by Index and only read here. Without one, as on
+	// PostgreSQL, search falls back to brute force.
+	if idx.dialect.Name() == "sqlite" {
+		idx.loadHNSW()
+	}
 
 	// Embedder (if enabled)
 	if idx.config.EmbeddingProvider != "off" {
@@ -207,18 +280,50 @@ func (idx *Indexer) initComponents() error {
 		idx.embedder,
 		embedding.WithBatchSize(idx.config.BatchSize),
 		embedding.WithMaxWorkers(idx.config.MaxWorkers),
+		embedding.WithLogger(idx.logger),
 	)
 
 	return nil
 }
 
+// cacheModel is the model cached embeddings are recorded under, tagged
+// with the truncation size like TruncatingEmbedder's provider ID.
+func (idx *Indexer) cacheModel() string {
+	if idx.config.TruncateDim > 0 {
+		return fmt.Sprintf("%s@%d", idx.config.EmbeddingModel, idx.config.TruncateDim)
+	}
+	return idx.config.EmbeddingModel
+}
+
+// checkTruncation refuses to add embeddings to a cache holding the same
+// model at a different truncation size, or with force evicts those.
+func (idx *Indexer) checkTruncation(force bool) error {
+	models, err := idx.cache.Models()
+	if err != nil {
+		return err
+	}
+	for _, model := range models {
+		if !embedding.MixesTruncation(model, idx.cache.Model()) {
+			continue
+		}
+		if !force {
+			return fmt.Errorf("index holds %s embeddings, which can't be mixed with %s; reindex with --force to replace them", model, idx.cache.Model())
+		}
+		if _, err := idx.cache.EvictByModel(model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // createEmbedder creates the appropriate embedder based on configuration.
 func (idx *Indexer) createEmbedder() (embedding.Embedder, error) {
 	cfg := embedding.ProviderConfig{
-		Model:      idx.config.EmbeddingModel,
-		OllamaURL:  idx.config.OllamaURL,
-		LiteLLMURL: idx.config.LiteLLMURL,
-		LiteLLMKey: idx.config.LiteLLMKey,
+		Model:       idx.config.EmbeddingModel,
+		OllamaURL:   idx.config.OllamaURL,
+		LiteLLMURL:  idx.config.LiteLLMURL,
+		LiteLLMKey:  idx.config.LiteLLMKey,
+		TruncateDim: idx.config.TruncateDim,
 	}
 
 	switch idx.config.EmbeddingProvider {
@@ -245,12 +350,25 @@ func (idx *Indexer) Close() error {
 type IndexOptions struct {
 	Force   bool // Force full reindex
 	Verbose bool // Enable verbose logging
+
+	// ReembedModel re-embeds every chunk whose cached embedding is missing
+	// or was made by another model, as after switching models. Changed
+	// files are always embedded with the configured model, but without it
+	// unchanged files keep the old vectors. Unlike Force it keeps the
+	// Merkle tree and chunk locations, and only files that changed are
+	// re-chunked.
+	ReembedModel bool
+
+	// Progress, if set, is called after each batch with the number of
+	// files processed so far and the number to process
+	Progress func(done, total int)
 }
 
 // IndexResult contains statistics from an index operation.
 type IndexResult struct {
 	FilesProcessed int           `json:"files_processed"`
 	FilesDeleted   int           `json:"files_deleted"`
+	FilesFailed    int           `json:"files_failed"` // Left for the next run to retry
 	ChunksCreated  int           `json:"chunks_created"`
 	CacheHits      int           `json:"cache_hits"`
 	ChunksEmbedded int           `json:"chunks_embedded"`
@@ -258,11 +376,56 @@ type IndexResult struct {
 	ChangeType     string        `json:"change_type"` // "full", "incremental", "none"
 }
 
+// newMerkleBuilder creates a tree builder that skips the standard ignored
+// directories plus any configured patterns.
+func newMerkleBuilder(cfg *Config) *merkle.Builder {
+	builder := merkle.NewBuilder().WithMaxDepth(cfg.MaxDepth).WithOneFileSystem(cfg.OneFileSystem)
+	for name := range config.IgnoredDirs() {
+		builder.IgnorePatterns = append(builder.IgnorePatterns, name)
+	}
+	// Add any additional ignore patterns
+	if len(cfg.IgnorePatterns) > 0 {
+		builder.IgnorePatterns = append(builder.IgnorePatterns, cfg.IgnorePatterns...)
+	}
+	return builder
+}
+
+// PendingChanges reports what an incremental index of repoPath would
+// process right now, without opening the database or indexing anything.
+// indexed is false when no tree has been stored yet, in which case every
+// file is reported as added.
+func PendingChanges(repoPath string, cfg *Config) (changes *merkle.Changes, indexed bool, err error) {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+
+	absPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return nil, false, fmt.Errorf("resolving path: %w", err)
+	}
+
+	newTree, err := newMerkleBuilder(cfg).Build(absPath)
+	if err != nil {
+		return nil, false, fmt.Errorf("building merkle tree: %w", err)
+	}
+
+	oldTree, err := merkle.NewStore(config.DataDir(absPath)).Load()
+	if err != nil {
+		return nil, false, fmt.Errorf("loading merkle tree: %w", err)
+	}
+
+	return merkle.Diff(oldTree, newTree), oldTree != nil, nil
+}
+
 // Index performs incremental or full indexing.
 func (idx *Indexer) Index(ctx context.Context, opts IndexOptions) (*IndexResult, error) {
 	start := time.Now()
 	result := &IndexResult{}
 
+	if err := idx.checkTruncation(opts.Force || opts.ReembedModel); err != nil {
+		return nil, err
+	}
+
 	// 1. Build current Merkle tree
 	if opts.Verbose {
 		idx.logger.Info("building merkle tree", "path", idx.repoPath)
@@ -287,7 +450,13 @@ func (idx *Indexer) Index(ctx context.Context, opts IndexOptions) (*IndexResult,
 		oldTree, _ := idx.merkleStore.Load()
 		changes := merkle.Diff(oldTree, newTree)
 
-		if changes.IsEmpty() {
+		if changes.IsEmpty() && !opts.ReembedModel {
+			// Build a graph that's missing or was unreadable at open
+			if idx.hnsw == nil {
+				if err := idx.updateHNSW(ctx); err != nil {
+					idx.logger.Warn("failed to update HNSW index", "error", err)
+				}
+			}
 			result.ChangeType = "none"
 			result.Duration = time.Since(start)
 			if opts.Verbose {
@@ -310,95 +479,509 @@ func (idx *Indexer) Index(ctx context.Context, opts IndexOptions) (*IndexResult,
 
 	// 3. Handle deletions
 	for _, path := range filesToDelete {
-		if err := idx.locations.DeleteByPath(idx.repoPath, path); err != nil {
+		if err := idx.locations.DeleteByPath(idx.repoID, path); err != nil {
 			idx.logger.Warn("failed to delete locations", "path", path, "error", err)
 		}
 	}
 	result.FilesDeleted = len(filesToDelete)
 
-	// 4. Process files in batches
-	batchSize := 100
-	for i := 0; i < len(filesToProcess); i += batchSize {
-		end := i + batchSize
-		if end > len(filesToProcess) {
-			end = len(filesToProcess)
+	if opts.ReembedModel {
+		rechunk, embedded, err := idx.reembedStale(ctx, filesToProcess)
+		result.ChunksEmbedded += embedded
+		if err != nil {
+			return result, fmt.Errorf("re-embedding for %s: %w", idx.cache.Model(), err)
+		}
+		filesToProcess = append(filesToProcess, rechunk...)
+	}
+
+	// 4. Process files in batches bounded by content size
+	batchBytes := idx.config.BatchBytes
+	if batchBytes <= 0 {
+		batchBytes = DefaultBatchBytes
+	}
+	next := 0 // Start of the first batch not yet run
+	var failed []string
+	for _, batch := range splitByBytes(filesToProcess, fileSizes(newTree.Root), batchBytes, maxBatchFiles) {
+		if ctx.Err() != nil {
+			break
 		}
-		batch := filesToProcess[i:end]
 
-		batchResult, err := idx.processBatch(ctx, batch, opts.Verbose)
+		batchResult, unread, err := idx.processBatch(ctx, batch, opts.Verbose)
+		if err != nil && ctx.Err() != nil {
+			break // Interrupted mid-batch, so it's still pending
+		}
+		next += len(batch)
+		if opts.Progress != nil {
+			opts.Progress(next, len(filesToProcess))
+		}
 		if err != nil {
-			idx.logger.Warn("batch processing error", "error", err)
+			idx.logger.Warn("batch processing error, will retry next run", "files", len(batch), "error", err)
+			failed = append(failed, batch...)
 			continue
 		}
 
-		result.FilesProcessed += len(batch)
+		if len(unread) > 0 {
+			idx.logger.Warn("files could not be read or chunked, will retry next run", "files", len(unread))
+			failed = append(failed, unread...)
+		}
+		result.FilesProcessed += len(batch) - len(unread)
 		result.ChunksCreated += batchResult.ChunksCreated
 		result.CacheHits += batchResult.CacheHits
 		result.ChunksEmbedded += batchResult.ChunksEmbedded
 	}
 
-	// 5. Save Merkle tree
-	if err := idx.merkleStore.Save(newTree); err != nil {
+	result.FilesFailed = len(failed)
+
+	// Save the finished batches so a re-run resumes after them
+	if err := ctx.Err(); err != nil {
+		pending := append(failed, filesToProcess[next:]...)
+		if saveErr := idx.merkleStore.Save(newTree.MarkPending(pending)); saveErr != nil {
+			idx.logger.Warn("failed to save merkle tree", "error", saveErr)
+		}
+		result.Duration = time.Since(start)
+		return result, fmt.Errorf("indexing interrupted after %d of %d files: %w", next, len(filesToProcess), err)
+	}
+
+	// 5. Save Merkle tree, leaving failed files changed so they're retried
+	if err := idx.merkleStore.Save(newTree.MarkPending(failed)); err != nil {
 		return nil, fmt.Errorf("saving merkle tree: %w", err)
 	}
 
+	// 6. Apply added and removed chunks to the HNSW graph
+	if err := idx.updateHNSW(ctx); err != nil {
+		idx.logger.Warn("failed to update HNSW index", "error", err)
+	}
+
 	result.Duration = time.Since(start)
 	return result, nil
 }
 
-// processBatch processes a batch of files.
-func (idx *Indexer) processBatch(ctx context.Context, files []string, verbose bool) (*IndexResult, error) {
+// processBatch processes a batch of files, returning those that couldn't
+// be read or chunked. Their locations are kept, since their current chunks
+// are unknown.
+func (idx *Indexer) processBatch(ctx context.Context, files []string, verbose bool) (*IndexResult, []string, error) {
 	result := &IndexResult{}
 
-	// Chunk all files using AST chunker
-	var allChunks []embedding.Chunk
-	for _, relPath := range files {
-		fullPath := filepath.Join(idx.repoPath, relPath)
-		content, err := os.ReadFile(fullPath)
+	allChunks, unread := idx.chunkFiles(ctx, files, verbose)
+
+	result.ChunksCreated = len(allChunks)
+
+	if len(allChunks) > 0 {
+		// Process through embedding pipeline
+		embedResult, err := idx.pipeline.EmbedChunks(ctx, idx.repoID, allChunks)
 		if err != nil {
-			if verbose {
-				idx.logger.Debug("skipping file", "path", relPath, "error", err)
+			return nil, nil, fmt.Errorf("embedding chunks: %w", err)
+		}
+
+		result.CacheHits = embedResult.CacheHits
+		result.ChunksEmbedded = embedResult.Embedded
+
+		// Drop replaced old-model vectors so updateHNSW inserts the new ones
+		if idx.hnsw != nil && len(embedResult.Replaced) > 0 {
+			if err := idx.hnsw.DeleteBatch(ctx, embedResult.Replaced); err != nil {
+				return nil, nil, fmt.Errorf("removing replaced vectors: %w", err)
 			}
+		}
+	}
+
+	// Drop locations of chunks the files no longer have, now their current
+	// chunks are saved. An interrupted batch may not have read every file.
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	skip := make(map[string]bool, len(unread))
+	for _, path := range unread {
+		skip[path] = true
+	}
+	current := make(map[string][]embedding.ChunkLocation, len(files))
+	for _, c := range allChunks {
+		if c.Content != "" {
+			current[c.Path] = append(current[c.Path], embedding.ChunkLocation{StartLine: c.StartLine, EndLine: c.EndLine})
+		}
+	}
+	for _, path := range files {
+		if skip[path] {
 			continue
 		}
+		if _, err := idx.locations.DeleteStaleByPath(idx.repoID, path, current[path]); err != nil {
+			return nil, nil, fmt.Errorf("removing stale locations for %s: %w", path, err)
+		}
+	}
 
-		// Use AST chunker
-		astChunks, err := idx.astChunker.ChunkFile(ctx, relPath, content)
-		if err != nil {
-			if verbose {
-				idx.logger.Debug("chunk error", "path", relPath, "error", err)
-			}
+	return result, unread, nil
+}
+
+// chunkFiles reads and AST-chunks files on up to MaxWorkers goroutines.
+// Chunks come back in file order whatever the worker count; files that
+// can't be read or chunked are skipped and returned as unread.
+func (idx *Indexer) chunkFiles(ctx context.Context, files []string, verbose bool) (chunks []embedding.Chunk, unread []string) {
+	workers := max(idx.config.MaxWorkers, 1)
+	perFile := make([][]embedding.Chunk, len(files))
+	ok := make([]bool, len(files))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, workers)
+	for i, relPath := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, relPath string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			perFile[i], ok[i] = idx.chunkFile(ctx, relPath, verbose)
+		}(i, relPath)
+	}
+	wg.Wait()
+
+	for i, fileChunks := range perFile {
+		if !ok[i] {
+			unread = append(unread, files[i])
 			continue
 		}
+		chunks = append(chunks, fileChunks...)
+	}
+	return chunks, unread
+}
 
-		// Convert chunker.Chunk to embedding.Chunk
-		for _, ac := range astChunks {
-			allChunks = append(allChunks, embedding.Chunk{
-				Path:      ac.Path,
-				StartLine: ac.StartLine,
-				EndLine:   ac.EndLine,
-				Content:   ac.Content,
-				Kind:      ac.NodeType, // Map NodeType to Kind
-			})
+// chunkFile reads and AST-chunks one file, returning false if it can't.
+func (idx *Indexer) chunkFile(ctx context.Context, relPath string, verbose bool) ([]embedding.Chunk, bool) {
+	fullPath := filepath.Join(idx.repoPath, relPath)
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		if verbose {
+			idx.logger.Debug("skipping file", "path", relPath, "error", err)
 		}
+		return nil, false
 	}
 
-	result.ChunksCreated = len(allChunks)
+	// Use AST chunker
+	astChunks, err := idx.astChunker.ChunkFile(ctx, relPath, content)
+	if err != nil {
+		if verbose {
+			idx.logger.Debug("chunk error", "path", relPath, "error", err)
+		}
+		return nil, false
+	}
 
-	if len(allChunks) == 0 {
-		return result, nil
+	// Convert chunker.Chunk to embedding.Chunk
+	chunks := make([]embedding.Chunk, 0, len(astChunks))
+	for _, ac := range astChunks {
+		chunks = append(chunks, embedding.Chunk{
+			Path:       ac.Path,
+			StartLine:  ac.StartLine,
+			EndLine:    ac.EndLine,
+			Content:    ac.Content,
+			Kind:       ac.NodeType, // Map NodeType to Kind
+			DocComment: ac.DocComment,
+		})
 	}
+	return chunks, true
+}
 
-	// Process through embedding pipeline
-	embedResult, err := idx.pipeline.EmbedChunks(ctx, idx.repoPath, allChunks)
+// loadHNSW loads the persisted HNSW graph for search. A missing or
+// unreadable graph, or one built for other dimensions, is left unset so
+// search falls back to brute force until the next Index rebuilds it.
+func (idx *Indexer) loadHNSW() {
+	path := filepath.Join(idx.dataDir, embedding.HNSWFileName)
+	graph, err := embedding.LoadHNSWIndex(path, config.LoadHNSWConfigFromEnv())
 	if err != nil {
-		return nil, fmt.Errorf("embedding chunks: %w", err)
+		idx.logger.Warn("ignoring unreadable HNSW index, searching by brute force", "path", path, "error", err)
+		return
+	}
+	if graph == nil || graph.Dimensions() != idx.config.Dimensions {
+		return
 	}
+	idx.useHNSW(graph)
+}
 
-	result.CacheHits = embedResult.CacheHits
-	result.ChunksEmbedded = embedResult.Embedded
+// useHNSW makes graph the indexer's vector index.
+func (idx *Indexer) useHNSW(graph *embedding.HNSWIndex) {
+	graph.SetRepoHashes(idx.locations.GetHashesForRepo)
+	idx.hnsw = graph
+	idx.vectorIndex = graph
+}
 
-	return result, nil
+// updateHNSW removes hashes no longer referenced by the repo from the graph,
+// inserts newly referenced ones from the embedding cache, and saves the
+// graph if that changed it. On SQLite it starts an empty graph if none was
+// loaded.
+func (idx *Indexer) updateHNSW(ctx context.Context) error {
+	if idx.hnsw == nil || idx.hnsw.Dimensions() != idx.config.Dimensions {
+		if idx.dialect.Name() != "sqlite" {
+			return nil
+		}
+		idx.useHNSW(embedding.NewHNSWIndex(idx.config.Dimensions, config.LoadHNSWConfigFromEnv()))
+	}
+
+	hashes, err := idx.locations.GetHashesForRepo(idx.repoID)
+	if err != nil {
+		return fmt.Errorf("getting repo hashes: %w", err)
+	}
+
+	live := make(map[string]bool, len(hashes))
+	var missing []string
+	for _, hash := range hashes {
+		live[hash] = true
+		if !idx.hnsw.Contains(hash) {
+			missing = append(missing, hash)
+		}
+	}
+
+	var stale []string
+	for _, hash := range idx.hnsw.Hashes() {
+		if !live[hash] {
+			stale = append(stale, hash)
+		}
+	}
+
+	if len(missing) == 0 && len(stale) == 0 {
+		return nil
+	}
+
+	if err := idx.hnsw.DeleteBatch(ctx, stale); err != nil {
+		return fmt.Errorf("removing stale vectors: %w", err)
+	}
+	changed := len(stale) > 0
+
+	// Fetch in batches to stay under the database's bound parameter limit
+	const fetchSize = 500
+	for i := 0; i < len(missing); i += fetchSize {
+		end := min(i+fetchSize, len(missing))
+		entries, err := idx.cache.PeekBatch(missing[i:end])
+		if err != nil {
+			return fmt.Errorf("fetching embeddings: %w", err)
+		}
+
+		vectors := make(map[string][]float32, len(entries))
+		for hash, entry := range entries {
+			// Skip embeddings left over from a model with other dimensions
+			if len(entry.Embedding) == idx.hnsw.Dimensions() {
+				vectors[hash] = entry.Embedding
+			}
+		}
+		if err := idx.hnsw.InsertBatch(ctx, vectors); err != nil {
+			return fmt.Errorf("inserting vectors: %w", err)
+		}
+		changed = changed || len(vectors) > 0
+	}
+
+	// Hashes with no cached embedding stay missing, so only save when
+	// something was actually added or removed
+	if !changed {
+		return nil
+	}
+	return idx.hnsw.Save(filepath.Join(idx.dataDir, embedding.HNSWFileName))
+}
+
+// reembedGroup is how many cache entries reembedStale checks and embeds at
+// a time, staying under the database's bound parameter limit
+const reembedGroup = 500
+
+// reembedStale clears cached embeddings of the repo's chunks that are
+// missing or from another model and embeds them again with the current
+// one. Chunk contents aren't stored, so each is cut from its file by line
+// range and checked against its content hash. Files where that doesn't
+// reproduce a chunk are returned for re-chunking. Files in changed are
+// skipped, since the caller re-chunks them anyway.
+func (idx *Indexer) reembedStale(ctx context.Context, changed []string) (rechunk []string, embedded int, err error) {
+	if !idx.embedder.Available() {
+		return nil, 0, fmt.Errorf("no embedding provider available")
+	}
+
+	locs, err := idx.locations.GetByRepo(idx.repoID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("getting locations: %w", err)
+	}
+	hashSet := make(map[string]bool)
+	for _, loc := range locs {
+		hashSet[loc.ContentHash] = true
+		if loc.DocHash != "" {
+			hashSet[loc.DocHash] = true
+		}
+	}
+	hashes := make([]string, 0, len(hashSet))
+	for hash := range hashSet {
+		hashes = append(hashes, hash)
+	}
+
+	stale := make(map[string]bool)
+	for i := 0; i < len(hashes); i += reembedGroup {
+		batch := hashes[i:min(i+reembedGroup, len(hashes))]
+		entries, err := idx.cache.PeekBatch(batch)
+		if err != nil {
+			return nil, 0, fmt.Errorf("checking cached embeddings: %w", err)
+		}
+		for _, hash := range batch {
+			if entry, ok := entries[hash]; !ok || entry.Model != idx.cache.Model() {
+				stale[hash] = true
+			}
+		}
+	}
+	if len(stale) == 0 {
+		return nil, 0, nil
+	}
+	staleHashes := make([]string, 0, len(stale))
+	for hash := range stale {
+		staleHashes = append(staleHashes, hash)
+	}
+
+	// Clear the old vectors up front so re-chunked files miss the cache,
+	// and drop them from the graph so updateHNSW inserts the new ones.
+	// The cache is shared, so entries other repos also reference stay until
+	// Reembed, or the pipeline for changed and re-chunked files, replaces
+	// them with the new vector.
+	shared, err := idx.locations.GetSharedHashes(idx.repoID, staleHashes)
+	if err != nil {
+		return nil, 0, fmt.Errorf("checking shared embeddings: %w", err)
+	}
+	keep := make(map[string]bool, len(shared))
+	for _, hash := range shared {
+		keep[hash] = true
+	}
+	unshared := make([]string, 0, len(staleHashes)-len(shared))
+	for _, hash := range staleHashes {
+		if !keep[hash] {
+			unshared = append(unshared, hash)
+		}
+	}
+	for i := 0; i < len(unshared); i += reembedGroup {
+		if err := idx.cache.DeleteBatch(unshared[i:min(i+reembedGroup, len(unshared))]); err != nil {
+			return nil, 0, fmt.Errorf("clearing stale embeddings: %w", err)
+		}
+	}
+	if idx.hnsw != nil {
+		if err := idx.hnsw.DeleteBatch(ctx, staleHashes); err != nil {
+			return nil, 0, fmt.Errorf("removing stale vectors: %w", err)
+		}
+	}
+
+	skip := make(map[string]bool, len(changed))
+	for _, path := range changed {
+		skip[path] = true
+	}
+	contents := make(map[string]string)
+	byPath := make(map[string][]embedding.ChunkLocation)
+	var paths []string
+	for _, loc := range locs {
+		if skip[loc.Path] {
+			continue
+		}
+		if byPath[loc.Path] == nil {
+			paths = append(paths, loc.Path)
+		}
+		byPath[loc.Path] = append(byPath[loc.Path], loc)
+	}
+	for _, path := range paths {
+		if !idx.recoverContents(path, byPath[path], stale, contents) {
+			rechunk = append(rechunk, path)
+		}
+	}
+
+	pending := make(map[string]string, reembedGroup)
+	flush := func() error {
+		n, err := idx.pipeline.Reembed(ctx, pending)
+		embedded += n
+		clear(pending)
+		return err
+	}
+	for hash, content := range contents {
+		pending[hash] = content
+		if len(pending) >= reembedGroup {
+			if err := flush(); err != nil {
+				return rechunk, embedded, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return rechunk, embedded, err
+	}
+	return rechunk, embedded, nil
+}
+
+// recoverContents adds the contents of path's stale chunks and doc
+// comments to contents, cutting chunks from the file by line range. It
+// reports false if the file can't be read or a chunk's text doesn't match
+// its hash, in which case the file needs re-chunking.
+func (idx *Indexer) recoverContents(path string, locs []embedding.ChunkLocation, stale map[string]bool, contents map[string]string) bool {
+	var lines []string
+	for _, loc := range locs {
+		if loc.DocHash != "" && stale[loc.DocHash] {
+			contents[loc.DocHash] = loc.DocComment
+		}
+		if !stale[loc.ContentHash] {
+			continue
+		}
+		if lines == nil {
+			data, err := os.ReadFile(filepath.Join(idx.repoPath, path))
+			if err != nil {
+				return false
+			}
+			lines = strings.Split(string(data), "\n")
+		}
+		if loc.StartLine < 1 || loc.EndLine > len(lines) || loc.StartLine > loc.EndLine {
+			return false
+		}
+
+		// AST chunks start at the node, not the line, so also try without
+		// the first line's indentation
+		text := strings.Join(lines[loc.StartLine-1:loc.EndLine], "\n")
+		found := false
+		for _, candidate := range []string{text, strings.TrimLeft(text, " \t")} {
+			if embedding.HashContent(candidate) == loc.ContentHash {
+				contents[loc.ContentHash] = candidate
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// fileSizes maps each file path under node to its size in bytes.
+func fileSizes(node *merkle.Node) map[string]int64 {
+	sizes := make(map[string]int64)
+	var walk func(n *merkle.Node)
+	walk = func(n *merkle.Node) {
+		if n == nil {
+			return
+		}
+		if !n.IsDir {
+			sizes[n.Path] = n.Size
+			return
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(node)
+	return sizes
+}
+
+// splitByBytes groups files into consecutive batches whose sizes add up to
+// at most maxBytes, and at most maxFiles files each. A file bigger than
+// maxBytes is a batch of its own.
+func splitByBytes(files []string, sizes map[string]int64, maxBytes int64, maxFiles int) [][]string {
+	var batches [][]string
+	var batch []string
+	var batchSize int64
+	for _, path := range files {
+		size := sizes[path]
+		if len(batch) > 0 && (batchSize+size > maxBytes || len(batch) >= maxFiles) {
+			batches = append(batches, batch)
+			batch, batchSize = nil, 0
+		}
+		batch = append(batch, path)
+		batchSize += size
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
 }
 
 // collectAllFiles recursively collects all file paths from a Merkle tree node.
@@ -423,7 +1006,7 @@ func (idx *Indexer) Stats() (*IndexStats, error) {
 	stats := &IndexStats{}
 
 	// Location stats
-	locStats, err := idx.locations.Stats(idx.repoPath)
+	locStats, err := idx.locations.Stats(idx.repoID)
 	if err != nil {
 		return nil, fmt.Errorf("getting location stats: %w", err)
 	}
@@ -439,6 +1022,32 @@ func (idx *Indexer) Stats() (*IndexStats, error) {
 		return nil, fmt.Errorf("getting cache stats: %w", err)
 	}
 	stats.CachedEmbeddings = cacheStats.TotalEntries
+	// The cache may be shared, so the counters are this repo's own
+	stats.CacheHits, stats.CacheMisses, err = idx.cache.LookupCounts(idx.repoID)
+	if err != nil {
+		return nil, fmt.Errorf("getting cache lookups: %w", err)
+	}
+	if lookups := stats.CacheHits + stats.CacheMisses; lookups > 0 {
+		stats.CacheHitRatio = float64(stats.CacheHits) / float64(lookups)
+	}
+
+	// Chunks without a cached embedding are invisible to semantic search
+	hashes, err := idx.locations.GetHashesForRepo(idx.repoID)
+	if err != nil {
+		return nil, fmt.Errorf("getting chunk hashes: %w", err)
+	}
+	for i := 0; i < len(hashes); i += 500 {
+		batch := hashes[i:min(i+500, len(hashes))]
+		cached, err := idx.cache.HasEntryBatch(batch)
+		if err != nil {
+			return nil, fmt.Errorf("checking cached embeddings: %w", err)
+		}
+		for _, hash := range batch {
+			if !cached[hash] {
+				stats.MissingEmbeddings++
+			}
+		}
+	}
 
 	// Vector index stats
 	if idx.vectorIndex != nil {
@@ -458,6 +1067,10 @@ type IndexStats struct {
 	UniqueHashes      int            `json:"unique_hashes"`
 	FileCount         int            `json:"file_count"`
 	CachedEmbeddings  int            `json:"cached_embeddings"`
+	MissingEmbeddings int            `json:"missing_embeddings"` // Unique chunk hashes with no cached embedding
+	CacheHits         int64          `json:"cache_hits"`         // Lifetime lookups found in the cache
+	CacheMisses       int64          `json:"cache_misses"`       // Lifetime lookups that had to be embedded
+	CacheHitRatio     float64        `json:"cache_hit_ratio"`
 	IndexedVectors    int            `json:"indexed_vectors"`
 	VectorIndexNative bool           `json:"vector_index_native"`
 	ByNodeType        map[string]int `json:"by_node_type"`
@@ -466,47 +1079,7 @@ type IndexStats struct {
 
 // LoadGitignore loads .gitignore patterns for the repository.
 func LoadGitignore(repoPath string) []string {
-	var patterns []string
-
-	// Load global gitignore
-	if homeDir, err := os.UserHomeDir(); err == nil {
-		globalPath := filepath.Join(homeDir, ".gitignore")
-		if content, err := os.ReadFile(globalPath); err == nil {
-			patterns = append(patterns, parseGitignore(string(content))...)
-		}
-	}
-
-	// Load local .gitignore
-	localPath := filepath.Join(repoPath, ".gitignore")
-	if content, err := os.ReadFile(localPath); err == nil {
-		patterns = append(patterns, parseGitignore(string(content))...)
-	}
-
-	return patterns
-}
-
-// parseGitignore extracts patterns from gitignore content.
-func parseGitignore(content string) []string {
-	var patterns []string
-	start := 0
-	for i := 0; i <= len(content); i++ {
-		if i == len(content) || content[i] == '\n' {
-			line := content[start:i]
-			if len(line) > 0 && line[len(line)-1] == '\r' {
-				line = line[:len(line)-1]
-			}
-			// Skip empty lines and comments
-			trimmed := line
-			for len(trimmed) > 0 && (trimmed[0] == ' ' || trimmed[0] == '\t') {
-				trimmed = trimmed[1:]
-			}
-			if len(trimmed) > 0 && trimmed[0] != '#' {
-				patterns = append(patterns, trimmed)
-			}
-			start = i + 1
-		}
-	}
-	return patterns
+	return merkle.LoadGitignore(repoPath)
 }
 
 // CompileGitignore compiles patterns into a matcher.
@@ -2,8 +2,10 @@ package indexer
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -30,6 +32,43 @@ func TestDefaultConfig(t *testing.T) {
 	}
 }
 
+func TestLoadConfigFromEnv(t *testing.T) {
+	repo := t.TempDir()
+	os.WriteFile(filepath.Join(repo, ".gitignore"), []byte("generated/\n"), 0644)
+
+	t.Setenv("CODETECT_DB_TYPE", "sqlite")
+	t.Setenv("CODETECT_EMBEDDING_PROVIDER", "off")
+	t.Setenv("CODETECT_INDEX_DOC_COMMENTS", "true")
+	t.Setenv("CODETECT_INDEX_WORKERS", "8")
+	t.Setenv("CODETECT_INDEX_BATCH_BYTES", "1048576")
+
+	cfg := LoadConfigFromEnv(repo)
+	if cfg.DBPath != filepath.Join(repo, ".codetect", "index.db") {
+		t.Errorf("DBPath = %q, want the repo's .codetect/index.db", cfg.DBPath)
+	}
+	if cfg.EmbeddingProvider != "off" {
+		t.Errorf("EmbeddingProvider = %q, want off", cfg.EmbeddingProvider)
+	}
+	if !cfg.DocComments {
+		t.Error("DocComments = false, want true")
+	}
+	if cfg.MaxWorkers != 8 {
+		t.Errorf("MaxWorkers = %d, want 8", cfg.MaxWorkers)
+	}
+	if cfg.BatchBytes != 1<<20 {
+		t.Errorf("BatchBytes = %d, want 1 MiB", cfg.BatchBytes)
+	}
+	found := false
+	for _, p := range cfg.IgnorePatterns {
+		if p == "generated" || p == "generated/" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("IgnorePatterns = %v, want the repo's .gitignore entries", cfg.IgnorePatterns)
+	}
+}
+
 func TestNewIndexer(t *testing.T) {
 	// Create temp directory for testing
 	tempDir, err := os.MkdirTemp("", "indexer_test")
@@ -137,6 +176,49 @@ func add(a, b int) int {
 	}
 }
 
+func TestIndexer_RefusesMixedTruncation(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{
+		DBType:            "sqlite",
+		EmbeddingProvider: "off",
+		EmbeddingModel:    "nomic-embed-text",
+		Dimensions:        768,
+	}
+	idx, err := New(tempDir, cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	full := make([]float32, 768)
+	full[0] = 1
+	if err := idx.Cache().Put("full", full); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	idx.Close()
+
+	// The same model truncated to 256 dimensions can't share the index
+	cfg.TruncateDim, cfg.Dimensions = 256, 256
+	idx, err = New(tempDir, cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer idx.Close()
+
+	ctx := context.Background()
+	if _, err := idx.Index(ctx, IndexOptions{}); err == nil {
+		t.Fatal("Index() mixing truncated and full-size embeddings succeeded, want an error")
+	}
+	if _, err := idx.Index(ctx, IndexOptions{Force: true}); err != nil {
+		t.Fatalf("Index(Force) error = %v", err)
+	}
+	if has, _ := idx.Cache().HasEntry("full"); has {
+		t.Error("full-size embedding still cached after a forced reindex")
+	}
+}
+
 func TestIndexer_IncrementalIndex(t *testing.T) {
 	// Create temp directory for testing
 	tempDir, err := os.MkdirTemp("", "indexer_test")
@@ -209,6 +291,280 @@ func main() {
 	}
 }
 
+func TestIndexer_IncrementalIndexMovesLocations(t *testing.T) {
+	tempDir := t.TempDir()
+	moved := "func helper() int {\n\treturn 42\n}\n"
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+	}
+	write("a.go", "package main\n\nfunc main() {\n\tprintln(helper())\n}\n\n"+moved)
+	write("c.go", "package main\n\n"+moved)
+
+	cfg := &Config{DBType: "sqlite", EmbeddingProvider: "off", Dimensions: 768}
+	idx, err := New(tempDir, cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer idx.Close()
+
+	ctx := context.Background()
+	if _, err := idx.Index(ctx, IndexOptions{Force: true}); err != nil {
+		t.Fatalf("first Index() error = %v", err)
+	}
+	// c.go holds nothing but helper, so its one location has helper's hash
+	cLocs, err := idx.locations.GetByPath(idx.repoID, "c.go")
+	if err != nil || len(cLocs) != 1 {
+		t.Fatalf("c.go locations = %+v, %v, want one", cLocs, err)
+	}
+	helperHash := cLocs[0].ContentHash
+
+	// Move helper from a.go into a new b.go, and delete c.go outright
+	write("a.go", "package main\n\nfunc main() {\n\tprintln(helper())\n}\n")
+	write("b.go", "package main\n\n// Moved here\n"+moved)
+	if err := os.Remove(filepath.Join(tempDir, "c.go")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := idx.Index(ctx, IndexOptions{}); err != nil {
+		t.Fatalf("second Index() error = %v", err)
+	}
+
+	locs, err := idx.locations.GetByHash(helperHash)
+	if err != nil {
+		t.Fatalf("GetByHash() error = %v", err)
+	}
+	if len(locs) != 1 || locs[0].Path != "b.go" || locs[0].StartLine != 4 {
+		t.Errorf("helper locations = %+v, want only b.go:4", locs)
+	}
+	if n, err := idx.locations.CountByPath(idx.repoID, "a.go"); err != nil || n != 1 {
+		t.Errorf("a.go locations = %d, %v, want just main", n, err)
+	}
+}
+
+func TestProcessBatchKeepsUnreadableFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "a.go")
+	if err := os.WriteFile(path, []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err := New(tempDir, &Config{DBType: "sqlite", EmbeddingProvider: "off", Dimensions: 768})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer idx.Close()
+
+	ctx := context.Background()
+	if _, err := idx.Index(ctx, IndexOptions{Force: true}); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	before, err := idx.locations.CountByPath(idx.repoID, "a.go")
+	if err != nil || before == 0 {
+		t.Fatalf("a.go locations = %d, %v, want some", before, err)
+	}
+
+	// A file that can't be read keeps its locations and is reported back
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	_, unread, err := idx.processBatch(ctx, []string{"a.go"}, false)
+	if err != nil {
+		t.Fatalf("processBatch() error = %v", err)
+	}
+	if len(unread) != 1 || unread[0] != "a.go" {
+		t.Errorf("processBatch() unread = %v, want [a.go]", unread)
+	}
+	if after, err := idx.locations.CountByPath(idx.repoID, "a.go"); err != nil || after != before {
+		t.Errorf("a.go locations = %d, %v after a failed read, want %d", after, err, before)
+	}
+}
+
+func TestChunkFilesKeepsFileOrder(t *testing.T) {
+	tempDir := t.TempDir()
+	var files []string
+	for i := 0; i < 20; i++ {
+		name := "file" + itoa(i) + ".go"
+		content := "package main\n\nfunc function" + itoa(i) + "() {\n\tprintln(\"hello\")\n}\n"
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("writing file: %v", err)
+		}
+		files = append(files, name)
+	}
+	files = append(files, "missing.go")
+
+	chunk := func(workers int) []string {
+		t.Helper()
+		idx, err := New(tempDir, &Config{DBType: "sqlite", EmbeddingProvider: "off", Dimensions: 768, MaxWorkers: workers})
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer idx.Close()
+
+		chunks, unread := idx.chunkFiles(context.Background(), files, false)
+		if len(unread) != 1 || unread[0] != "missing.go" {
+			t.Errorf("chunkFiles() unread = %v, want [missing.go]", unread)
+		}
+		var paths []string
+		for _, c := range chunks {
+			paths = append(paths, c.Path+":"+itoa(c.StartLine))
+		}
+		return paths
+	}
+
+	sequential := chunk(1)
+	if len(sequential) < 20 {
+		t.Fatalf("chunkFiles() = %v, want chunks for all 20 files", sequential)
+	}
+	parallel := chunk(8)
+	if strings.Join(parallel, ",") != strings.Join(sequential, ",") {
+		t.Errorf("chunkFiles() with 8 workers = %v, want the sequential order %v", parallel, sequential)
+	}
+}
+
+func TestSplitByBytes(t *testing.T) {
+	sizes := map[string]int64{"a": 40, "b": 50, "c": 20, "huge": 500, "d": 10, "e": 10}
+	files := []string{"a", "b", "c", "huge", "d", "e", "unknown"}
+
+	got := splitByBytes(files, sizes, 100, 100)
+	want := [][]string{{"a", "b"}, {"c"}, {"huge"}, {"d", "e", "unknown"}}
+	if len(got) != len(want) {
+		t.Fatalf("splitByBytes() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if strings.Join(got[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("batch %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	// Small files still stop at the file cap
+	if got := splitByBytes(files, sizes, 1000, 3); len(got) != 3 || len(got[0]) != 3 {
+		t.Errorf("splitByBytes() with a 3-file cap = %v, want batches of 3", got)
+	}
+	if got := splitByBytes(nil, sizes, 100, 100); len(got) != 0 {
+		t.Errorf("splitByBytes(nil) = %v, want no batches", got)
+	}
+}
+
+func TestIndexer_IndexResumesAfterCancel(t *testing.T) {
+	tempDir := t.TempDir()
+	content := "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n"
+	for i := 0; i < 5; i++ {
+		if err := os.WriteFile(filepath.Join(tempDir, "file"+itoa(i)+".go"), []byte(content), 0644); err != nil {
+			t.Fatalf("writing file: %v", err)
+		}
+	}
+
+	// A one-byte budget puts each file in its own batch
+	cfg := &Config{DBType: "sqlite", EmbeddingProvider: "off", Dimensions: 768, BatchBytes: 1}
+	idx, err := New(tempDir, cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer idx.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var progress []int
+	result, err := idx.Index(ctx, IndexOptions{
+		Force: true,
+		Progress: func(done, total int) {
+			if total != 5 {
+				t.Errorf("Progress total = %d, want 5", total)
+			}
+			progress = append(progress, done)
+			if done == 2 {
+				cancel()
+			}
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Index() error = %v, want context.Canceled", err)
+	}
+	if result == nil || result.FilesProcessed != 2 {
+		t.Fatalf("Index() result = %+v, want 2 files processed", result)
+	}
+	if len(progress) != 2 || progress[1] != 2 {
+		t.Errorf("Progress calls = %v, want [1 2]", progress)
+	}
+
+	// The next incremental run picks up the three files left
+	result, err = idx.Index(context.Background(), IndexOptions{})
+	if err != nil {
+		t.Fatalf("resumed Index() error = %v", err)
+	}
+	if result.ChangeType != "incremental" || result.FilesProcessed != 3 {
+		t.Errorf("resumed Index() = %+v, want 3 files incrementally", result)
+	}
+	if result, err := idx.Index(context.Background(), IndexOptions{}); err != nil || result.ChangeType != "none" {
+		t.Errorf("third Index() = %+v, %v, want no changes", result, err)
+	}
+}
+
+func TestPendingChanges(t *testing.T) {
+	tempDir := t.TempDir()
+	for name, content := range map[string]string{
+		"main.go": "package main\n\nfunc main() {}\n",
+		"util.go": "package main\n\nfunc util() {}\n",
+	} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+	}
+
+	cfg := &Config{
+		DBType:            "sqlite",
+		EmbeddingProvider: "off",
+		Dimensions:        768,
+	}
+
+	// Before the first index everything is pending
+	changes, indexed, err := PendingChanges(tempDir, cfg)
+	if err != nil {
+		t.Fatalf("PendingChanges() error = %v", err)
+	}
+	if indexed || len(changes.Added) != 2 {
+		t.Errorf("PendingChanges() = %+v, indexed=%v, want 2 added and not indexed", changes, indexed)
+	}
+
+	idx, err := New(tempDir, cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer idx.Close()
+	if _, err := idx.Index(context.Background(), IndexOptions{}); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+
+	changes, indexed, err = PendingChanges(tempDir, cfg)
+	if err != nil {
+		t.Fatalf("PendingChanges() error = %v", err)
+	}
+	if !indexed || !changes.IsEmpty() {
+		t.Errorf("PendingChanges() after index = %+v, indexed=%v, want no changes", changes, indexed)
+	}
+
+	// Modify, add and delete without reindexing
+	os.WriteFile(filepath.Join(tempDir, "main.go"), []byte("package main\n\nfunc main() { println() }\n"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "new.go"), []byte("package main\n"), 0644)
+	os.Remove(filepath.Join(tempDir, "util.go"))
+
+	changes, _, err = PendingChanges(tempDir, cfg)
+	if err != nil {
+		t.Fatalf("PendingChanges() error = %v", err)
+	}
+	if len(changes.Added) != 1 || changes.Added[0] != "new.go" {
+		t.Errorf("Added = %v, want [new.go]", changes.Added)
+	}
+	if len(changes.Modified) != 1 || changes.Modified[0] != "main.go" {
+		t.Errorf("Modified = %v, want [main.go]", changes.Modified)
+	}
+	if len(changes.Deleted) != 1 || changes.Deleted[0] != "util.go" {
+		t.Errorf("Deleted = %v, want [util.go]", changes.Deleted)
+	}
+}
+
 func TestIndexer_Stats(t *testing.T) {
 	// Create temp directory for testing
 	tempDir, err := os.MkdirTemp("", "indexer_test")
@@ -261,6 +617,10 @@ func main() {
 	if stats.FileCount == 0 {
 		t.Error("FileCount = 0, want > 0")
 	}
+	// Nothing is embedded with the provider off
+	if stats.MissingEmbeddings != stats.UniqueHashes {
+		t.Errorf("MissingEmbeddings = %d, want all %d hashes", stats.MissingEmbeddings, stats.UniqueHashes)
+	}
 }
 
 func TestLoadGitignore(t *testing.T) {
@@ -305,55 +665,6 @@ node_modules/
 	}
 }
 
-func TestParseGitignore(t *testing.T) {
-	tests := []struct {
-		name     string
-		content  string
-		expected []string
-	}{
-		{
-			name:     "simple patterns",
-			content:  "*.log\nnode_modules/",
-			expected: []string{"*.log", "node_modules/"},
-		},
-		{
-			name:     "with comments",
-			content:  "# Comment\n*.log\n# Another\nvendor/",
-			expected: []string{"*.log", "vendor/"},
-		},
-		{
-			name:     "with blank lines",
-			content:  "*.log\n\nvendor/\n\n",
-			expected: []string{"*.log", "vendor/"},
-		},
-		{
-			name:     "with whitespace",
-			content:  "  # Comment\n*.log\n  vendor/",
-			expected: []string{"*.log", "vendor/"},
-		},
-		{
-			name:     "windows line endings",
-			content:  "*.log\r\nvendor/\r\n",
-			expected: []string{"*.log", "vendor/"},
-		},
-	}
-
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			result := parseGitignore(tc.content)
-			if len(result) != len(tc.expected) {
-				t.Errorf("got %d patterns, want %d", len(result), len(tc.expected))
-				return
-			}
-			for i, exp := range tc.expected {
-				if result[i] != exp {
-					t.Errorf("pattern[%d] = %q, want %q", i, result[i], exp)
-				}
-			}
-		})
-	}
-}
-
 func TestCompileGitignore(t *testing.T) {
 	patterns := []string{"*.log", "node_modules/", ".env"}
 	gi := CompileGitignore(patterns)
@@ -447,6 +758,41 @@ func function%d() {
 	}
 }
 
+// BenchmarkChunkFiles compares chunking a batch of larger files on one
+// worker against the default pool.
+func BenchmarkChunkFiles(b *testing.B) {
+	tempDir := b.TempDir()
+	var files []string
+	for i := 0; i < 100; i++ {
+		var content strings.Builder
+		content.WriteString("package main\n")
+		for j := 0; j < 50; j++ {
+			content.WriteString("\n// function" + itoa(j) + " prints a greeting.\nfunc function" + itoa(j) + "() {\n\tfor i := 0; i < 10; i++ {\n\t\tprintln(\"hello\", i)\n\t}\n}\n")
+		}
+		name := "file" + itoa(i) + ".go"
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content.String()), 0644); err != nil {
+			b.Fatalf("writing file: %v", err)
+		}
+		files = append(files, name)
+	}
+
+	for _, workers := range []int{1, 4} {
+		b.Run("workers="+itoa(workers), func(b *testing.B) {
+			idx, err := New(tempDir, &Config{DBType: "sqlite", EmbeddingProvider: "off", Dimensions: 768, MaxWorkers: workers})
+			if err != nil {
+				b.Fatalf("New() error = %v", err)
+			}
+			defer idx.Close()
+
+			ctx := context.Background()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				idx.chunkFiles(ctx, files, false)
+			}
+		})
+	}
+}
+
 // itoa converts int to string
 func itoa(n int) string {
 	if n == 0 {
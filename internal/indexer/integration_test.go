@@ -2,8 +2,11 @@ package indexer
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -64,6 +67,236 @@ func (m *mockEmbedderIntegration) Dimensions() int {
 	return m.dims
 }
 
+// failingEmbedder fails any batch containing failOn while failing is set.
+type failingEmbedder struct {
+	*mockEmbedderIntegration
+	failOn  string
+	failing bool
+}
+
+func (f *failingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	for _, text := range texts {
+		if f.failing && strings.Contains(text, f.failOn) {
+			return nil, errors.New("embedding service unavailable")
+		}
+	}
+	return f.mockEmbedderIntegration.Embed(ctx, texts)
+}
+
+// TestV2RetriesFailedFiles checks that files whose embedding failed stay
+// changed in the Merkle tree and are picked up by the next incremental run.
+func TestV2RetriesFailedFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"good.go":   "package main\n\nfunc good() {\n\tprintln(\"fine\")\n}\n",
+		"broken.go": "package main\n\nfunc broken() {\n\tprintln(\"flaky\")\n}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+	}
+
+	// A one-byte budget puts each file in its own batch
+	cfg := &Config{DBType: "sqlite", EmbeddingProvider: "off", Dimensions: 768, BatchBytes: 1}
+	idx, err := New(tempDir, cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer idx.Close()
+
+	emb := &failingEmbedder{mockEmbedderIntegration: newMockEmbedderIntegration(768), failOn: "flaky", failing: true}
+	idx.embedder = emb
+	idx.pipeline = embedding.NewPipeline(idx.cache, idx.locations, emb,
+		embedding.WithBatchSize(10), embedding.WithMaxWorkers(1))
+
+	ctx := context.Background()
+	result, err := idx.Index(ctx, IndexOptions{Force: true})
+	if err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	if result.FilesProcessed != 1 || result.FilesFailed != 1 {
+		t.Fatalf("Index() = %+v, want 1 file processed and 1 failed", result)
+	}
+
+	emb.failing = false
+	result, err = idx.Index(ctx, IndexOptions{})
+	if err != nil {
+		t.Fatalf("retry Index() error = %v", err)
+	}
+	if result.ChangeType != "incremental" || result.FilesProcessed != 1 || result.FilesFailed != 0 {
+		t.Errorf("retry Index() = %+v, want broken.go reprocessed", result)
+	}
+
+	result, err = idx.Index(ctx, IndexOptions{})
+	if err != nil || result.ChangeType != "none" {
+		t.Errorf("third Index() = %+v, %v, want no changes", result, err)
+	}
+}
+
+// TestV2ReembedModel checks that switching models and indexing with
+// ReembedModel replaces every cached embedding without re-chunking.
+func TestV2ReembedModel(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"main.go": "package main\n\n// main starts the server.\nfunc main() {\n\tserve()\n}\n\nfunc serve() {\n\tprintln(\"listening\")\n}\n",
+		"util.go": "package main\n\ntype Config struct {\n\tPort int\n}\n\nfunc (c *Config) Addr() string {\n\treturn \"localhost\"\n}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+	}
+
+	open := func(model string) (*Indexer, *mockEmbedderIntegration) {
+		t.Helper()
+		cfg := &Config{DBType: "sqlite", EmbeddingProvider: "off", EmbeddingModel: model, Dimensions: 768, DocComments: true}
+		idx, err := New(tempDir, cfg)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		emb := newMockEmbedderIntegration(768)
+		idx.embedder = emb
+		idx.pipeline = embedding.NewPipeline(idx.cache, idx.locations, emb,
+			embedding.WithBatchSize(10), embedding.WithMaxWorkers(1))
+		return idx, emb
+	}
+
+	ctx := context.Background()
+	idx, _ := open("model-a")
+	if _, err := idx.Index(ctx, IndexOptions{Force: true}); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	idx.Close()
+
+	idx, emb := open("model-b")
+	defer idx.Close()
+
+	// Unchanged files keep model-a's vectors without ReembedModel
+	result, err := idx.Index(ctx, IndexOptions{})
+	if err != nil || result.ChangeType != "none" {
+		t.Fatalf("Index() after the switch = %+v, %v, want no changes", result, err)
+	}
+
+	result, err = idx.Index(ctx, IndexOptions{ReembedModel: true})
+	if err != nil {
+		t.Fatalf("Index(ReembedModel) error = %v", err)
+	}
+	if result.ChunksEmbedded == 0 || emb.callCount == 0 {
+		t.Errorf("Index(ReembedModel) = %+v, want chunks re-embedded", result)
+	}
+	if result.FilesProcessed != 0 {
+		t.Errorf("Index(ReembedModel) re-chunked %d files, want contents recovered from the files", result.FilesProcessed)
+	}
+	models, err := idx.cache.Models()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 1 || models[0] != "model-b" {
+		t.Errorf("cache models after re-embedding = %v, want only model-b", models)
+	}
+	if stats, err := idx.Stats(); err != nil || stats.MissingEmbeddings != 0 {
+		t.Errorf("Stats() = %+v, %v, want every chunk embedded", stats, err)
+	}
+
+	// Nothing is left to re-embed
+	result, err = idx.Index(ctx, IndexOptions{ReembedModel: true})
+	if err != nil || result.ChunksEmbedded != 0 {
+		t.Errorf("second Index(ReembedModel) = %+v, %v, want nothing embedded", result, err)
+	}
+}
+
+// shiftedEmbedder offsets the first component of every vector, so two
+// models embedding one text give different vectors.
+type shiftedEmbedder struct {
+	*mockEmbedderIntegration
+	shift float32
+}
+
+func (e *shiftedEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vecs, err := e.mockEmbedderIntegration.Embed(ctx, texts)
+	for i, v := range vecs {
+		v = append([]float32(nil), v...)
+		v[0] += e.shift
+		vecs[i] = v
+	}
+	return vecs, err
+}
+
+// TestV2ReembedModelSharedChangedFile checks a model switch replaces a
+// vector another repo also references when the file holding it changed,
+// with or without ReembedModel, so neither the cache nor the graph keeps
+// the old model's vector.
+func TestV2ReembedModelSharedChangedFile(t *testing.T) {
+	for _, reembed := range []bool{true, false} {
+		t.Run(fmt.Sprintf("ReembedModel=%v", reembed), func(t *testing.T) {
+			tempDir := t.TempDir()
+			mainPath := filepath.Join(tempDir, "main.go")
+			mainGo := "package main\n\nfunc main() {\n\tserve()\n}\n"
+			if err := os.WriteFile(mainPath, []byte(mainGo), 0644); err != nil {
+				t.Fatalf("writing main.go: %v", err)
+			}
+
+			open := func(model string, shift float32) *Indexer {
+				t.Helper()
+				cfg := &Config{DBType: "sqlite", EmbeddingProvider: "off", EmbeddingModel: model, Dimensions: 8}
+				idx, err := New(tempDir, cfg)
+				if err != nil {
+					t.Fatalf("New() error = %v", err)
+				}
+				emb := &shiftedEmbedder{mockEmbedderIntegration: newMockEmbedderIntegration(8), shift: shift}
+				idx.embedder = emb
+				idx.pipeline = embedding.NewPipeline(idx.cache, idx.locations, emb,
+					embedding.WithBatchSize(10), embedding.WithMaxWorkers(1))
+				return idx
+			}
+
+			ctx := context.Background()
+			idx := open("model-a", 0)
+			if _, err := idx.Index(ctx, IndexOptions{Force: true}); err != nil {
+				t.Fatalf("Index() error = %v", err)
+			}
+
+			// Another repo in the same database references main.go's first chunk
+			locs, err := idx.locations.GetByPath(idx.repoID, "main.go")
+			if err != nil || len(locs) == 0 {
+				t.Fatalf("GetByPath() = %v, %v, want main.go's chunks", locs, err)
+			}
+			shared := locs[0].ContentHash
+			other := locs[0]
+			other.RepoRoot = "other-repo"
+			if err := idx.locations.SaveLocationsBatch([]embedding.ChunkLocation{other}); err != nil {
+				t.Fatalf("SaveLocationsBatch() error = %v", err)
+			}
+			idx.Close()
+
+			// Change main.go while keeping the shared chunk
+			if err := os.WriteFile(mainPath, []byte(mainGo+"\nfunc serve() {\n\tprintln(\"listening\")\n}\n"), 0644); err != nil {
+				t.Fatalf("writing main.go: %v", err)
+			}
+
+			idx = open("model-b", 10)
+			defer idx.Close()
+			if _, err := idx.Index(ctx, IndexOptions{ReembedModel: reembed}); err != nil {
+				t.Fatalf("Index() error = %v", err)
+			}
+
+			entries, err := idx.cache.PeekBatch([]string{shared})
+			if err != nil {
+				t.Fatalf("PeekBatch() error = %v", err)
+			}
+			entry := entries[shared]
+			if entry == nil || entry.Model != "model-b" {
+				t.Fatalf("shared entry = %+v, want model-b's vector", entry)
+			}
+			results, err := idx.hnsw.Search(ctx, entry.Embedding, 1)
+			if err != nil || len(results) == 0 || results[0].ContentHash != shared || results[0].Distance > 1e-4 {
+				t.Errorf("graph search for the shared vector = %+v, %v, want model-b's vector in the graph", results, err)
+			}
+		})
+	}
+}
+
 // TestV2SearchIntegration tests the full v2 search pipeline:
 // 1. Create files → 2. Index with embeddings → 3. Search → 4. Verify results
 func TestV2SearchIntegration(t *testing.T) {
@@ -401,6 +634,99 @@ func newFunction() {
 	}
 }
 
+// TestV2HNSWIndex tests that the HNSW graph tracks indexed chunks and is
+// reloaded from disk when the indexer is reopened.
+func TestV2HNSWIndex(t *testing.T) {
+	tempDir := t.TempDir()
+	for name, body := range map[string]string{"a.go": "alpha", "b.go": "beta"} {
+		content := "package main\n\nfunc " + body + "() {\n\tprintln(\"" + body + "\")\n}\n"
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+	}
+
+	cfg := &Config{
+		DBType:            "sqlite",
+		EmbeddingProvider: "off",
+		Dimensions:        768,
+	}
+
+	idx, err := New(tempDir, cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	mockEmb := newMockEmbedderIntegration(768)
+	idx.embedder = mockEmb
+	idx.pipeline = embedding.NewPipeline(idx.cache, idx.locations, mockEmb)
+
+	ctx := context.Background()
+	if _, err := idx.Index(ctx, IndexOptions{Force: true}); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+
+	stats, err := idx.Stats()
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if !stats.VectorIndexNative || stats.IndexedVectors == 0 || stats.IndexedVectors != stats.UniqueHashes {
+		t.Errorf("indexed %d vectors (native=%v), want all %d hashes",
+			stats.IndexedVectors, stats.VectorIndexNative, stats.UniqueHashes)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, ".codetect", embedding.HNSWFileName)); err != nil {
+		t.Errorf("HNSW graph not saved: %v", err)
+	}
+
+	// Removing a file removes its vectors
+	if err := os.Remove(filepath.Join(tempDir, "b.go")); err != nil {
+		t.Fatalf("removing file: %v", err)
+	}
+	if _, err := idx.Index(ctx, IndexOptions{}); err != nil {
+		t.Fatalf("incremental Index() error = %v", err)
+	}
+	want, _ := idx.VectorIndex().Count(ctx)
+	if want >= stats.IndexedVectors {
+		t.Errorf("indexed %d vectors after deleting a file, want fewer than %d", want, stats.IndexedVectors)
+	}
+	idx.Close()
+
+	// Reopening loads the saved graph
+	reopened, err := New(tempDir, cfg)
+	if err != nil {
+		t.Fatalf("reopening: %v", err)
+	}
+	defer reopened.Close()
+	if got, _ := reopened.VectorIndex().Count(ctx); got != want {
+		t.Errorf("reopened index has %d vectors, want %d", got, want)
+	}
+
+	// Opening only reads the graph, and a corrupt one falls back to brute
+	// force until the next Index rebuilds it
+	graphPath := filepath.Join(tempDir, ".codetect", embedding.HNSWFileName)
+	if err := os.WriteFile(graphPath, []byte("not a graph"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	corrupt, err := New(tempDir, cfg)
+	if err != nil {
+		t.Fatalf("opening with a corrupt graph: %v", err)
+	}
+	defer corrupt.Close()
+	if corrupt.VectorIndex() != nil {
+		t.Error("corrupt graph loaded as the vector index, want brute force")
+	}
+	if data, _ := os.ReadFile(graphPath); string(data) != "not a graph" {
+		t.Error("opening the indexer rewrote the graph")
+	}
+	corrupt.embedder = mockEmb
+	corrupt.pipeline = embedding.NewPipeline(corrupt.cache, corrupt.locations, mockEmb)
+	if _, err := corrupt.Index(ctx, IndexOptions{}); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	if got, _ := corrupt.VectorIndex().Count(ctx); got != want {
+		t.Errorf("rebuilt index has %d vectors, want %d", got, want)
+	}
+}
+
 // BenchmarkV2Search benchmarks the v2 semantic search.
 func BenchmarkV2Search(b *testing.B) {
 	// Create temp directory with files
@@ -0,0 +1,146 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"codetect/internal/config"
+	"codetect/internal/embedding"
+)
+
+// MigrateResult reports what MigrateV1 carried over from a v1 index.
+type MigrateResult struct {
+	Records  int           `json:"records"`  // v1 embedding records read
+	Migrated int           `json:"migrated"` // Records written as v2 chunk locations
+	Vectors  int           `json:"vectors"`  // Unique embeddings now in the cache
+	Skipped  int           `json:"skipped"`  // Records with other dimensions, or a span already migrated
+	Duration time.Duration `json:"duration"`
+}
+
+// MigrateV1 copies v1 embedding records into the v2 embedding cache and
+// location store, so v2 search works on the existing vectors without
+// re-embedding. Both schemas key content by the same SHA-256 hash, so the
+// v1 hash is reused as-is. v1 records carry no node type, name or language;
+// those location fields are left empty.
+//
+// Vectors from different models aren't comparable, so records from more
+// than one model are refused; read them with GetAllForModel instead.
+// Afterwards every migrated hash must be in the cache and every migrated
+// location must be in the store, or an error is returned.
+func (idx *Indexer) MigrateV1(ctx context.Context, records []embedding.EmbeddingRecord) (*MigrateResult, error) {
+	start := time.Now()
+	result := &MigrateResult{Records: len(records)}
+
+	models := make(map[string]bool)
+	for _, rec := range records {
+		models[rec.Model] = true
+	}
+	if len(models) > 1 {
+		names := make([]string, 0, len(models))
+		for model := range models {
+			names = append(names, model)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("v1 index holds embeddings from %d models (%s), set CODETECT_EMBEDDING_MODEL to the one to migrate",
+			len(names), strings.Join(names, ", "))
+	}
+
+	vectors := make(map[string][]float32)
+	locations := make([]embedding.ChunkLocation, 0, len(records))
+	seen := make(map[string]bool)
+	for _, rec := range records {
+		if len(rec.Embedding) != idx.config.Dimensions {
+			result.Skipped++
+			continue
+		}
+
+		loc := embedding.ChunkLocation{
+			RepoRoot:    idx.repoID,
+			Path:        config.RepoRelPath(idx.repoPath, rec.Path),
+			StartLine:   rec.StartLine,
+			EndLine:     rec.EndLine,
+			ContentHash: rec.ContentHash,
+		}
+		key := fmt.Sprintf("%s:%d:%d", loc.Path, loc.StartLine, loc.EndLine)
+		if seen[key] {
+			result.Skipped++
+			continue
+		}
+		seen[key] = true
+
+		vectors[rec.ContentHash] = rec.Embedding
+		locations = append(locations, loc)
+	}
+
+	// Write in batches to stay under the database's bound parameter limit
+	const batchSize = 500
+	batch := make(map[string][]float32, batchSize)
+	for hash, vec := range vectors {
+		batch[hash] = vec
+		if len(batch) == batchSize {
+			if err := idx.cache.PutBatch(batch); err != nil {
+				return nil, fmt.Errorf("caching embeddings: %w", err)
+			}
+			batch = make(map[string][]float32, batchSize)
+		}
+	}
+	if err := idx.cache.PutBatch(batch); err != nil {
+		return nil, fmt.Errorf("caching embeddings: %w", err)
+	}
+
+	for i := 0; i < len(locations); i += batchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		end := min(i+batchSize, len(locations))
+		if err := idx.locations.SaveLocationsBatch(locations[i:end]); err != nil {
+			return nil, fmt.Errorf("saving locations: %w", err)
+		}
+	}
+
+	if err := idx.validateMigration(vectors, locations); err != nil {
+		return nil, err
+	}
+	result.Migrated = len(locations)
+	result.Vectors = len(vectors)
+
+	if err := idx.updateHNSW(ctx); err != nil {
+		idx.logger.Warn("failed to update HNSW index", "error", err)
+	}
+
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// validateMigration checks that every migrated embedding and location
+// landed in the v2 stores.
+func (idx *Indexer) validateMigration(vectors map[string][]float32, locations []embedding.ChunkLocation) error {
+	hashes := make([]string, 0, len(vectors))
+	for hash := range vectors {
+		hashes = append(hashes, hash)
+	}
+	for i := 0; i < len(hashes); i += 500 {
+		batch := hashes[i:min(i+500, len(hashes))]
+		cached, err := idx.cache.HasEntryBatch(batch)
+		if err != nil {
+			return fmt.Errorf("validating cache: %w", err)
+		}
+		for _, hash := range batch {
+			if !cached[hash] {
+				return fmt.Errorf("validating cache: embedding %s was not migrated", hash)
+			}
+		}
+	}
+
+	count, err := idx.locations.CountByRepo(idx.repoID)
+	if err != nil {
+		return fmt.Errorf("validating locations: %w", err)
+	}
+	if count < len(locations) {
+		return fmt.Errorf("validating locations: found %d, want at least %d", count, len(locations))
+	}
+	return nil
+}
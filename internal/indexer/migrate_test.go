@@ -0,0 +1,88 @@
+package indexer
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"codetect/internal/embedding"
+)
+
+func TestIndexer_MigrateV1(t *testing.T) {
+	tempDir := t.TempDir()
+
+	cfg := &Config{
+		DBType:            "sqlite",
+		EmbeddingProvider: "off",
+		Dimensions:        4,
+	}
+	idx, err := New(tempDir, cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer idx.Close()
+
+	records := []embedding.EmbeddingRecord{
+		{Path: "main.go", StartLine: 1, EndLine: 10, ContentHash: "hash1", Embedding: []float32{1, 0, 0, 0}},
+		// Older v1 indexes stored absolute paths
+		{Path: filepath.Join(tempDir, "util.go"), StartLine: 5, EndLine: 8, ContentHash: "hash2", Embedding: []float32{0, 1, 0, 0}},
+		// Embedded with a model of other dimensions
+		{Path: "old.go", StartLine: 1, EndLine: 2, ContentHash: "hash3", Embedding: []float32{1, 0, 0}},
+	}
+
+	result, err := idx.MigrateV1(context.Background(), records)
+	if err != nil {
+		t.Fatalf("MigrateV1() error = %v", err)
+	}
+	if result.Records != 3 || result.Migrated != 2 || result.Vectors != 2 || result.Skipped != 1 {
+		t.Errorf("MigrateV1() = %+v, want 3 read, 2 migrated, 2 vectors, 1 skipped", result)
+	}
+
+	locs, err := idx.Locations().GetByPath(idx.repoID, "util.go")
+	if err != nil {
+		t.Fatalf("GetByPath() error = %v", err)
+	}
+	if len(locs) != 1 || locs[0].ContentHash != "hash2" || locs[0].StartLine != 5 {
+		t.Errorf("util.go locations = %+v, want hash2 at line 5", locs)
+	}
+
+	// Peek rather than Get, whose access-stats update runs after Close
+	entries, err := idx.Cache().PeekBatch([]string{"hash1"})
+	if entry := entries["hash1"]; err != nil || entry == nil || entry.Embedding[0] != 1 {
+		t.Errorf("Cache().PeekBatch(hash1) = %+v, %v, want the migrated vector", entry, err)
+	}
+
+	// The vectors are searchable without re-embedding
+	if count, _ := idx.VectorIndex().Count(context.Background()); count != 2 {
+		t.Errorf("vector index holds %d vectors, want 2", count)
+	}
+
+	stats, err := idx.Stats()
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats.MissingEmbeddings != 0 {
+		t.Errorf("MissingEmbeddings = %d after migration, want 0", stats.MissingEmbeddings)
+	}
+}
+
+func TestIndexer_MigrateV1MixedModels(t *testing.T) {
+	tempDir := t.TempDir()
+
+	idx, err := New(tempDir, &Config{DBType: "sqlite", EmbeddingProvider: "off", Dimensions: 4})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer idx.Close()
+
+	records := []embedding.EmbeddingRecord{
+		{Path: "main.go", StartLine: 1, EndLine: 10, ContentHash: "hash1", Model: "ollama:nomic-embed-text", Embedding: []float32{1, 0, 0, 0}},
+		{Path: "util.go", StartLine: 1, EndLine: 10, ContentHash: "hash2", Model: "ollama:other-model", Embedding: []float32{0, 1, 0, 0}},
+	}
+	if _, err := idx.MigrateV1(context.Background(), records); err == nil {
+		t.Fatal("MigrateV1() with two models succeeded, want an error")
+	}
+	if count, _ := idx.Locations().CountByRepo(idx.repoID); count != 0 {
+		t.Errorf("%d locations saved after a refused migration, want 0", count)
+	}
+}
@@ -13,6 +13,11 @@ import (
 
 const ProtocolVersion = "2024-11-05"
 
+// StructuredProtocolVersion is the first protocol version with structured
+// tool output. The server speaks it when structured content is enabled and
+// the client asks for it or a later version.
+const StructuredProtocolVersion = "2025-06-18"
+
 // ToolHandler is the function signature for handling tool calls
 type ToolHandler func(args map[string]interface{}) (*ToolsCallResult, error)
 
@@ -23,6 +28,9 @@ type Server struct {
 	tools    []Tool
 	handlers map[string]ToolHandler
 	logger   *slog.Logger
+
+	structuredContent bool // Offer structured tool output (SetStructuredContent)
+	structured        bool // Negotiated with the client at initialize
 }
 
 // NewServer creates a new MCP server
@@ -42,6 +50,20 @@ func (s *Server) RegisterTool(tool Tool, handler ToolHandler) {
 	s.handlers[tool.Name] = handler
 }
 
+// SetStructuredContent enables structured tool output. Clients that
+// negotiate StructuredProtocolVersion then get each JSON tool result as
+// structuredContent as well as text; older clients get text only.
+func (s *Server) SetStructuredContent(enabled bool) {
+	s.structuredContent = enabled
+}
+
+// Handler returns the handler registered for a tool, so one tool can call
+// another
+func (s *Server) Handler(name string) (ToolHandler, bool) {
+	handler, ok := s.handlers[name]
+	return handler, ok
+}
+
 // Run starts the server and processes stdin/stdout
 func (s *Server) Run() error {
 	reader := bufio.NewReader(os.Stdin)
@@ -113,8 +135,20 @@ func (s *Server) handleMessage(data []byte) *Response {
 }
 
 func (s *Server) handleInitialize(req *Request) *Response {
+	version := ProtocolVersion
+	s.structured = false
+	if s.structuredContent {
+		var params InitializeParams
+		if data, err := json.Marshal(req.Params); err == nil && json.Unmarshal(data, &params) == nil &&
+			params.ProtocolVersion >= StructuredProtocolVersion {
+			// Versions are dates, so they order as strings
+			version = StructuredProtocolVersion
+			s.structured = true
+		}
+	}
+
 	result := InitializeResult{
-		ProtocolVersion: ProtocolVersion,
+		ProtocolVersion: version,
 		Capabilities: ServerCapabilities{
 			Tools: &ToolsCapability{
 				ListChanged: false,
@@ -195,6 +229,10 @@ func (s *Server) handleToolsCall(req *Request) *Response {
 		}
 	}
 
+	if s.structured {
+		addStructuredContent(result)
+	}
+
 	return &Response{
 		JSONRPC: "2.0",
 		ID:      req.ID,
@@ -202,6 +240,20 @@ func (s *Server) handleToolsCall(req *Request) *Response {
 	}
 }
 
+// addStructuredContent copies a result's JSON object text into its
+// structuredContent. Errors and plain-text results are left as they are.
+func addStructuredContent(result *ToolsCallResult) {
+	if result == nil || result.IsError || len(result.Content) != 1 || result.Content[0].Type != "text" {
+		return
+	}
+	text := []byte(result.Content[0].Text)
+	var object map[string]json.RawMessage
+	if json.Unmarshal(text, &object) != nil {
+		return
+	}
+	result.StructuredContent = text
+}
+
 func (s *Server) writeResponse(resp *Response) error {
 	data, err := json.Marshal(resp)
 	if err != nil {
@@ -1,5 +1,7 @@
 package mcp
 
+import "encoding/json"
+
 // JSON-RPC 2.0 types for MCP protocol
 
 type Request struct {
@@ -85,8 +87,9 @@ type InputSchema struct {
 }
 
 type Property struct {
-	Type        string `json:"type"`
-	Description string `json:"description"`
+	Type        string    `json:"type"`
+	Description string    `json:"description"`
+	Items       *Property `json:"items,omitempty"` // Element schema for array properties
 }
 
 type ToolsListResult struct {
@@ -101,6 +104,10 @@ type ToolsCallParams struct {
 type ToolsCallResult struct {
 	Content []Content `json:"content"`
 	IsError bool      `json:"isError,omitempty"`
+
+	// StructuredContent is the result as a JSON object, sent alongside the
+	// text content to clients that negotiated StructuredProtocolVersion
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
 }
 
 type Content struct {
@@ -6,6 +6,8 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"codetect/internal/config"
 )
 
 // DefaultIgnorePatterns contains common directories and files to skip.
@@ -60,6 +62,14 @@ type Builder struct {
 	// IncludeDotfiles is a more specific list of hidden files to include
 	// even when IncludeHidden is false (e.g., ".gitignore", ".env.example").
 	IncludeDotfiles []string
+
+	// MaxDepth skips directories more than this many levels below the
+	// root, which is depth 0. Zero means unlimited.
+	MaxDepth int
+
+	// OneFileSystem skips directories on a different device than the root.
+	// Directories reached twice (bind mount cycles) are always skipped.
+	OneFileSystem bool
 }
 
 // NewBuilder creates a Builder with default settings.
@@ -89,7 +99,8 @@ func (b *Builder) Build(repoPath string) (*Tree, error) {
 		return nil, err
 	}
 
-	root, fileCount, err := b.buildNode(absPath, "")
+	guard := config.NewWalkGuard(absPath, b.OneFileSystem)
+	root, fileCount, err := b.buildNode(absPath, "", guard)
 	if err != nil {
 		return nil, err
 	}
@@ -106,7 +117,7 @@ func (b *Builder) Build(repoPath string) (*Tree, error) {
 // basePath is the absolute path to the repository root.
 // relPath is the relative path from the root to this node.
 // Returns the node, file count, and any error.
-func (b *Builder) buildNode(basePath, relPath string) (*Node, int, error) {
+func (b *Builder) buildNode(basePath, relPath string, guard *config.WalkGuard) (*Node, int, error) {
 	fullPath := filepath.Join(basePath, relPath)
 
 	info, err := os.Lstat(fullPath)
@@ -129,6 +140,13 @@ func (b *Builder) buildNode(basePath, relPath string) (*Node, int, error) {
 	fileCount := 0
 
 	if info.IsDir() {
+		if b.MaxDepth > 0 && relPath != "" && strings.Count(relPath, string(filepath.Separator))+1 > b.MaxDepth {
+			return nil, 0, nil
+		}
+		if guard.SkipDir(info) {
+			return nil, 0, nil
+		}
+
 		entries, err := os.ReadDir(fullPath)
 		if err != nil {
 			return nil, 0, err
@@ -142,7 +160,7 @@ func (b *Builder) buildNode(basePath, relPath string) (*Node, int, error) {
 			}
 
 			childPath := filepath.Join(relPath, name)
-			child, count, err := b.buildNode(basePath, childPath)
+			child, count, err := b.buildNode(basePath, childPath, guard)
 			if err != nil {
 				// Skip unreadable files/directories
 				continue
@@ -216,6 +234,18 @@ func (b *Builder) WithIgnorePatterns(patterns ...string) *Builder {
 	return b
 }
 
+// WithMaxDepth limits how many directory levels below the root are walked.
+func (b *Builder) WithMaxDepth(depth int) *Builder {
+	b.MaxDepth = depth
+	return b
+}
+
+// WithOneFileSystem keeps the walk on the root's device.
+func (b *Builder) WithOneFileSystem(oneFileSystem bool) *Builder {
+	b.OneFileSystem = oneFileSystem
+	return b
+}
+
 // WithIncludeHidden enables including all hidden files.
 func (b *Builder) WithIncludeHidden(include bool) *Builder {
 	b.IncludeHidden = include
@@ -259,3 +289,49 @@ func (b *Builder) ParseGitignore(path string) error {
 
 	return nil
 }
+
+// LoadGitignore loads the global (~/.gitignore) and repository .gitignore
+// patterns, one per line, for IgnorePatterns.
+func LoadGitignore(repoPath string) []string {
+	var patterns []string
+
+	// Load global gitignore
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		globalPath := filepath.Join(homeDir, ".gitignore")
+		if content, err := os.ReadFile(globalPath); err == nil {
+			patterns = append(patterns, parseGitignore(string(content))...)
+		}
+	}
+
+	// Load local .gitignore
+	localPath := filepath.Join(repoPath, ".gitignore")
+	if content, err := os.ReadFile(localPath); err == nil {
+		patterns = append(patterns, parseGitignore(string(content))...)
+	}
+
+	return patterns
+}
+
+// parseGitignore extracts patterns from gitignore content.
+func parseGitignore(content string) []string {
+	var patterns []string
+	start := 0
+	for i := 0; i <= len(content); i++ {
+		if i == len(content) || content[i] == '\n' {
+			line := content[start:i]
+			if len(line) > 0 && line[len(line)-1] == '\r' {
+				line = line[:len(line)-1]
+			}
+			// Skip empty lines and comments
+			trimmed := line
+			for len(trimmed) > 0 && (trimmed[0] == ' ' || trimmed[0] == '\t') {
+				trimmed = trimmed[1:]
+			}
+			if len(trimmed) > 0 && trimmed[0] != '#' {
+				patterns = append(patterns, trimmed)
+			}
+			start = i + 1
+		}
+	}
+	return patterns
+}
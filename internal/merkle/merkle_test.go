@@ -781,6 +781,38 @@ func TestTreeClone(t *testing.T) {
 	}
 }
 
+func TestTreeMarkPending(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "sub"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "a.go"), []byte("package a"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "sub", "b.go"), []byte("package b"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "sub", "c.go"), []byte("package c"), 0644)
+
+	tree, err := NewBuilder().Build(tempDir)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	rootHash := tree.RootHash()
+
+	partial := tree.MarkPending([]string{filepath.Join("sub", "b.go")})
+	if tree.RootHash() != rootHash {
+		t.Error("MarkPending() changed the original tree")
+	}
+	if partial.RootHash() == rootHash {
+		t.Error("MarkPending() kept the root hash, so Diff would skip the pending file")
+	}
+
+	changes := Diff(partial, tree)
+	if len(changes.Modified) != 1 || changes.Modified[0] != filepath.Join("sub", "b.go") ||
+		len(changes.Added) != 0 || len(changes.Deleted) != 0 {
+		t.Errorf("Diff() after MarkPending() = %+v, want only sub/b.go modified", changes)
+	}
+
+	if got := tree.MarkPending(nil); got.RootHash() != rootHash {
+		t.Error("MarkPending(nil) changed the root hash")
+	}
+}
+
 func TestTreeCloneNil(t *testing.T) {
 	var tree *Tree
 	clone := tree.Clone()
@@ -975,6 +1007,26 @@ func TestStorePath(t *testing.T) {
 	}
 }
 
+func TestNamedStoreIsIndependent(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+	named := NewNamedStore(dir, "other-tree.json")
+
+	if named.Path() != filepath.Join(dir, "other-tree.json") {
+		t.Errorf("unexpected path %s", named.Path())
+	}
+
+	named.Save(&Tree{Root: &Node{Hash: "abc"}, FileCount: 1})
+	if store.Exists() {
+		t.Error("saving a named store should not create the default tree")
+	}
+
+	tree, err := named.Load()
+	if err != nil || tree == nil || tree.RootHash() != "abc" {
+		t.Errorf("Load() = %v, %v, want saved tree", tree, err)
+	}
+}
+
 func TestStoreGetMetadataNonExistent(t *testing.T) {
 	store := NewStore(t.TempDir())
 	meta, err := store.GetMetadata()
@@ -1050,6 +1102,27 @@ func TestBuilderEmptyDirectory(t *testing.T) {
 	}
 }
 
+func TestBuilderMaxDepth(t *testing.T) {
+	dir := createTestDir(t)
+
+	// subdir is depth 1, subdir/nested depth 2
+	tree, err := NewBuilder().WithMaxDepth(1).Build(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.FileCount != 3 {
+		t.Errorf("expected 3 files within depth 1, got %d", tree.FileCount)
+	}
+
+	tree, err = NewBuilder().WithMaxDepth(0).Build(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.FileCount != 4 {
+		t.Errorf("expected 4 files with unlimited depth, got %d", tree.FileCount)
+	}
+}
+
 // ===== Benchmarks =====
 
 func BenchmarkBuildSmallRepo(b *testing.B) {
@@ -1116,3 +1189,52 @@ func BenchmarkStoreSaveLoad(b *testing.B) {
 		store.Load()
 	}
 }
+
+func TestParseGitignore(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name:     "simple patterns",
+			content:  "*.log\nnode_modules/",
+			expected: []string{"*.log", "node_modules/"},
+		},
+		{
+			name:     "with comments",
+			content:  "# Comment\n*.log\n# Another\nvendor/",
+			expected: []string{"*.log", "vendor/"},
+		},
+		{
+			name:     "with blank lines",
+			content:  "*.log\n\nvendor/\n\n",
+			expected: []string{"*.log", "vendor/"},
+		},
+		{
+			name:     "with whitespace",
+			content:  "  # Comment\n*.log\n  vendor/",
+			expected: []string{"*.log", "vendor/"},
+		},
+		{
+			name:     "windows line endings",
+			content:  "*.log\r\nvendor/\r\n",
+			expected: []string{"*.log", "vendor/"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := parseGitignore(tc.content)
+			if len(result) != len(tc.expected) {
+				t.Errorf("got %d patterns, want %d", len(result), len(tc.expected))
+				return
+			}
+			for i, exp := range tc.expected {
+				if result[i] != exp {
+					t.Errorf("pattern[%d] = %q, want %q", i, result[i], exp)
+				}
+			}
+		})
+	}
+}
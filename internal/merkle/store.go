@@ -15,13 +15,21 @@ const TreeFileName = "merkle-tree.json"
 // Trees are stored as JSON files in the data directory,
 // typically .codetect/ within the repository.
 type Store struct {
-	dataDir string
+	dataDir  string
+	fileName string
 }
 
 // NewStore creates a store that persists data to the given directory.
 // The directory will be created if it doesn't exist.
 func NewStore(dataDir string) *Store {
-	return &Store{dataDir: dataDir}
+	return NewNamedStore(dataDir, TreeFileName)
+}
+
+// NewNamedStore creates a store that persists its tree under fileName
+// instead of TreeFileName, so independent pipelines sharing a data
+// directory each diff against their own last run.
+func NewNamedStore(dataDir, fileName string) *Store {
+	return &Store{dataDir: dataDir, fileName: fileName}
 }
 
 // Save persists the tree to disk as JSON.
@@ -42,7 +50,7 @@ func (s *Store) Save(tree *Tree) error {
 	}
 
 	// Write atomically using temp file + rename
-	targetPath := filepath.Join(s.dataDir, TreeFileName)
+	targetPath := s.Path()
 	tempPath := targetPath + ".tmp"
 
 	if err := os.WriteFile(tempPath, data, 0644); err != nil {
@@ -61,7 +69,7 @@ func (s *Store) Save(tree *Tree) error {
 // Returns nil, nil if no tree exists (first run).
 // Returns error only for actual read/parse failures.
 func (s *Store) Load() (*Tree, error) {
-	path := filepath.Join(s.dataDir, TreeFileName)
+	path := s.Path()
 
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -81,14 +89,14 @@ func (s *Store) Load() (*Tree, error) {
 
 // Exists returns true if a tree file exists.
 func (s *Store) Exists() bool {
-	path := filepath.Join(s.dataDir, TreeFileName)
+	path := s.Path()
 	_, err := os.Stat(path)
 	return err == nil
 }
 
 // Delete removes the stored tree file.
 func (s *Store) Delete() error {
-	path := filepath.Join(s.dataDir, TreeFileName)
+	path := s.Path()
 	err := os.Remove(path)
 	if os.IsNotExist(err) {
 		return nil // Already deleted is not an error
@@ -98,7 +106,7 @@ func (s *Store) Delete() error {
 
 // Path returns the path to the tree file.
 func (s *Store) Path() string {
-	return filepath.Join(s.dataDir, TreeFileName)
+	return filepath.Join(s.dataDir, s.fileName)
 }
 
 // Metadata contains information about a stored tree without loading it fully.
@@ -113,7 +121,7 @@ type Metadata struct {
 // GetMetadata returns metadata about the stored tree without fully loading it.
 // This is useful for quick checks without the overhead of parsing the entire tree.
 func (s *Store) GetMetadata() (*Metadata, error) {
-	path := filepath.Join(s.dataDir, TreeFileName)
+	path := s.Path()
 
 	info, err := os.Stat(path)
 	if err != nil {
@@ -140,8 +148,8 @@ func (s *Store) GetMetadata() (*Metadata, error) {
 
 // SaveWithBackup saves the tree and keeps a backup of the previous version.
 func (s *Store) SaveWithBackup(tree *Tree) error {
-	currentPath := filepath.Join(s.dataDir, TreeFileName)
-	backupPath := filepath.Join(s.dataDir, TreeFileName+".backup")
+	currentPath := s.Path()
+	backupPath := s.Path() + ".backup"
 
 	// If current file exists, rename it to backup
 	if _, err := os.Stat(currentPath); err == nil {
@@ -164,7 +172,7 @@ func (s *Store) SaveWithBackup(tree *Tree) error {
 
 // LoadBackup loads the backup tree if it exists.
 func (s *Store) LoadBackup() (*Tree, error) {
-	path := filepath.Join(s.dataDir, TreeFileName+".backup")
+	path := s.Path() + ".backup"
 
 	data, err := os.ReadFile(path)
 	if err != nil {
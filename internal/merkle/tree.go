@@ -41,6 +41,44 @@ func (t *Tree) Equal(other *Tree) bool {
 	return t.RootHash() == other.RootHash()
 }
 
+// MarkPending returns a copy of the tree in which the files at paths have
+// no hash, so diffing a fresh build against it reports them as modified.
+// Saving it after an interrupted or partly failed index leaves those files
+// for the next incremental run.
+func (t *Tree) MarkPending(paths []string) *Tree {
+	clone := t.Clone()
+	if clone == nil || clone.Root == nil || len(paths) == 0 {
+		return clone
+	}
+
+	pending := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		pending[p] = true
+	}
+	var mark func(n *Node) bool
+	mark = func(n *Node) bool {
+		if !n.IsDir {
+			if pending[n.Path] {
+				n.Hash = ""
+				return true
+			}
+			return false
+		}
+		changed := false
+		for _, child := range n.Children {
+			if mark(child) {
+				changed = true
+			}
+		}
+		if changed {
+			n.ComputeHash(nil)
+		}
+		return changed
+	}
+	mark(clone.Root)
+	return clone
+}
+
 // Clone creates a deep copy of the tree.
 func (t *Tree) Clone() *Tree {
 	if t == nil {
@@ -19,6 +19,9 @@ const (
 	DefaultDebounceMs = 500
 	// DefaultMaxProjects is the default maximum number of projects
 	DefaultMaxProjects = 50
+	// DefaultEmbedDebounceMs is the default quiet period before the daemon
+	// re-embeds a project with auto_embed set
+	DefaultEmbedDebounceMs = 30000
 )
 
 // IndexStats holds statistics about a project's index
@@ -28,21 +31,33 @@ type IndexStats struct {
 	DBSizeBytes int64 `json:"db_size_bytes"`
 }
 
+// ProjectSettings overrides the daemon's environment for one project, so a
+// single daemon can serve projects using different embedding providers.
+// Empty fields fall back to the environment the daemon was started with.
+type ProjectSettings struct {
+	EmbeddingProvider   string `json:"embedding_provider,omitempty"`   // ollama, litellm, off
+	EmbeddingModel      string `json:"embedding_model,omitempty"`      // Provider-specific model name
+	EmbeddingDimensions int    `json:"embedding_dimensions,omitempty"` // 0 means the model's default
+}
+
 // Project represents a registered project in the registry
 type Project struct {
-	Path         string     `json:"path"`
-	Name         string     `json:"name"`
-	AddedAt      time.Time  `json:"added_at"`
-	LastIndexed  *time.Time `json:"last_indexed,omitempty"`
-	IndexStats   IndexStats `json:"index_stats"`
-	WatchEnabled bool       `json:"watch_enabled"`
+	Path         string           `json:"path"`
+	Name         string           `json:"name"`
+	AddedAt      time.Time        `json:"added_at"`
+	LastIndexed  *time.Time       `json:"last_indexed,omitempty"`
+	IndexStats   IndexStats       `json:"index_stats"`
+	WatchEnabled bool             `json:"watch_enabled"`
+	AutoEmbed    bool             `json:"auto_embed,omitempty"` // Daemon re-embeds after reindexing
+	Settings     *ProjectSettings `json:"settings,omitempty"`
 }
 
 // Settings holds global registry settings
 type Settings struct {
-	AutoWatch   bool `json:"auto_watch"`
-	DebounceMs  int  `json:"debounce_ms"`
-	MaxProjects int  `json:"max_projects"`
+	AutoWatch       bool `json:"auto_watch"`
+	DebounceMs      int  `json:"debounce_ms"`
+	MaxProjects     int  `json:"max_projects"`
+	EmbedDebounceMs int  `json:"embed_debounce_ms,omitempty"` // 0 means DefaultEmbedDebounceMs
 }
 
 // RegistryData is the top-level structure stored in registry.json
@@ -68,8 +83,12 @@ func DefaultConfigDir() string {
 	return filepath.Join(home, ".config", "codetect")
 }
 
-// DefaultRegistryPath returns the default registry file path
+// DefaultRegistryPath returns the default registry file path, which
+// CODETECT_REGISTRY overrides
 func DefaultRegistryPath() string {
+	if path := os.Getenv("CODETECT_REGISTRY"); path != "" {
+		return path
+	}
 	return filepath.Join(DefaultConfigDir(), "registry.json")
 }
 
@@ -82,15 +101,7 @@ func NewRegistry() (*Registry, error) {
 func NewRegistryAt(path string) (*Registry, error) {
 	r := &Registry{
 		path: path,
-		data: &RegistryData{
-			Version:  RegistryVersion,
-			Projects: []Project{},
-			Settings: Settings{
-				AutoWatch:   true,
-				DebounceMs:  DefaultDebounceMs,
-				MaxProjects: DefaultMaxProjects,
-			},
-		},
+		data: defaultData(),
 	}
 
 	// Ensure directory exists
@@ -109,13 +120,45 @@ func NewRegistryAt(path string) (*Registry, error) {
 	return r, nil
 }
 
-// load reads the registry from disk
+// defaultData returns the contents of a new, empty registry
+func defaultData() *RegistryData {
+	return &RegistryData{
+		Version:  RegistryVersion,
+		Projects: []Project{},
+		Settings: Settings{
+			AutoWatch:   true,
+			DebounceMs:  DefaultDebounceMs,
+			MaxProjects: DefaultMaxProjects,
+		},
+	}
+}
+
+// load reads the registry from disk. It decodes into fresh data and swaps
+// it in, so fields and projects removed from the file don't survive a
+// Reload.
 func (r *Registry) load() error {
 	data, err := os.ReadFile(r.path)
 	if err != nil {
 		return err
 	}
-	return json.Unmarshal(data, r.data)
+	fresh := defaultData()
+	if err := json.Unmarshal(data, fresh); err != nil {
+		return err
+	}
+	r.data = fresh
+	return nil
+}
+
+// Reload re-reads the registry from disk, picking up edits made by other
+// processes such as the codetect CLI
+func (r *Registry) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := os.Stat(r.path); os.IsNotExist(err) {
+		return nil
+	}
+	return r.load()
 }
 
 // save writes the registry to disk
@@ -276,6 +319,52 @@ func (r *Registry) SetWatchEnabled(projectPath string, enabled bool) error {
 	return fmt.Errorf("project not found: %s", projectPath)
 }
 
+// SetAutoEmbed enables or disables re-embedding after the daemon reindexes
+// a project
+func (r *Registry) SetAutoEmbed(projectPath string, enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	absPath, err := filepath.Abs(projectPath)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	for i, p := range r.data.Projects {
+		if p.Path == absPath {
+			r.data.Projects[i].AutoEmbed = enabled
+			return r.save()
+		}
+	}
+
+	return fmt.Errorf("project not found: %s", projectPath)
+}
+
+// SetProjectSettings replaces a project's setting overrides. A zero
+// ProjectSettings clears them.
+func (r *Registry) SetProjectSettings(projectPath string, settings ProjectSettings) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	absPath, err := filepath.Abs(projectPath)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	for i, p := range r.data.Projects {
+		if p.Path == absPath {
+			if settings == (ProjectSettings{}) {
+				r.data.Projects[i].Settings = nil
+			} else {
+				r.data.Projects[i].Settings = &settings
+			}
+			return r.save()
+		}
+	}
+
+	return fmt.Errorf("project not found: %s", projectPath)
+}
+
 // GetWatchedProjects returns all projects with watching enabled
 func (r *Registry) GetWatchedProjects() []Project {
 	r.mu.RLock()
@@ -91,6 +91,12 @@ func (r *Reranker) Rerank(ctx context.Context, query string, candidates []fusion
 		return result, nil
 	}
 
+	// Fused ranks, for explained results
+	rankBefore := make(map[string]int, len(candidates))
+	for i, c := range candidates {
+		rankBefore[c.ID] = i + 1
+	}
+
 	// Take top K for reranking (to limit latency)
 	toRerank := candidates
 	remaining := []fusion.RRFResult{}
@@ -141,6 +147,11 @@ func (r *Reranker) Rerank(ctx context.Context, query string, candidates []fusion
 	for i, c := range docsToRerank {
 		reranked[i] = c
 		reranked[i].RRFScore = scores[i] // Replace with reranker score
+		if c.Explain != nil {
+			explain := *c.Explain
+			explain.Rerank = &fusion.RerankExplanation{Score: scores[i], RankBefore: rankBefore[c.ID]}
+			reranked[i].Explain = &explain
+		}
 	}
 
 	sort.Slice(reranked, func(i, j int) bool {
@@ -161,6 +172,12 @@ func (r *Reranker) Rerank(ctx context.Context, query string, candidates []fusion
 	// Append remaining candidates (not reranked) at the end
 	filtered = append(filtered, remaining...)
 
+	for i := range filtered {
+		if filtered[i].Explain != nil && filtered[i].Explain.Rerank != nil {
+			filtered[i].Explain.Rerank.RankAfter = i + 1
+		}
+	}
+
 	result.Results = filtered
 	result.RerankCount = len(docsToRerank)
 	result.Duration = time.Since(start)
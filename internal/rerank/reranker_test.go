@@ -75,6 +75,40 @@ func TestRerankerEnabled(t *testing.T) {
 	}
 }
 
+func TestRerankerExplain(t *testing.T) {
+	cfg := config.DefaultRerankerConfig()
+	cfg.Enabled = true
+	cfg.TopK = 10
+	cfg.Threshold = 0.0
+
+	reranker := NewRerankerWithProvider(&FixedScoreReranker{Scores: []float64{0.3, 0.8}}, cfg)
+
+	candidates := []fusion.RRFResult{
+		{Result: fusion.Result{ID: "a"}, RRFScore: 0.5, Explain: &fusion.Explanation{RRFScore: 0.5}},
+		{Result: fusion.Result{ID: "b"}, RRFScore: 0.3, Explain: &fusion.Explanation{RRFScore: 0.3}},
+	}
+	contents := map[string]string{"a": "content for a", "b": "content for b"}
+
+	result, err := reranker.Rerank(context.Background(), "query", candidates, contents)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b := result.Results[0]
+	if b.ID != "b" || b.Explain.Rerank == nil {
+		t.Fatalf("expected explained 'b' first, got %+v", b)
+	}
+	if b.Explain.Rerank.Score != 0.8 || b.Explain.Rerank.RankBefore != 2 || b.Explain.Rerank.RankAfter != 1 {
+		t.Errorf("unexpected rerank explanation: %+v", b.Explain.Rerank)
+	}
+	if b.Explain.RRFScore != 0.3 {
+		t.Errorf("expected fused score 0.3 preserved, got %f", b.Explain.RRFScore)
+	}
+	if candidates[1].Explain.Rerank != nil {
+		t.Error("Rerank should not modify the candidates' explanations")
+	}
+}
+
 func TestRerankerThreshold(t *testing.T) {
 	cfg := config.DefaultRerankerConfig()
 	cfg.Enabled = true
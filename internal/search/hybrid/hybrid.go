@@ -28,6 +28,7 @@ type SearchResult struct {
 	KeywordCount    int      `json:"keyword_count"`
 	SemanticCount   int      `json:"semantic_count"`
 	SemanticEnabled bool     `json:"semantic_enabled"`
+	HasMore         bool     `json:"has_more,omitempty"` // More results follow this page (Config.Offset)
 }
 
 // Searcher performs hybrid searches combining keyword and semantic results
@@ -49,6 +50,7 @@ type Config struct {
 	KeywordWeight   float32 // Weight for keyword results (default 0.6)
 	SemanticWeight  float32 // Weight for semantic results (default 0.4)
 	SnippetFn       func(path string, start, end int) string
+	Offset          int     // Skip this many combined results, for paging (default 0)
 }
 
 // DefaultConfig returns the default hybrid search configuration
@@ -75,11 +77,12 @@ func (s *Searcher) Search(ctx context.Context, query, dir string, config Config)
 	if config.SemanticWeight <= 0 {
 		config.SemanticWeight = 0.4
 	}
+	offset := max(config.Offset, 0)
 
 	resultMap := make(map[string]*Result) // key: "path:startLine"
 
 	// Perform keyword search (keyword.Search doesn't use context)
-	keywordResults, err := keyword.Search(query, dir, config.KeywordLimit)
+	keywordResults, err := keyword.Search(query, dir, config.KeywordLimit+offset)
 	if err != nil {
 		return nil, err
 	}
@@ -116,9 +119,9 @@ func (s *Searcher) Search(ctx context.Context, query, dir string, config Config)
 		var err error
 
 		if config.SnippetFn != nil {
-			semanticResult, err = s.semantic.SearchWithSnippets(ctx, query, config.SemanticLimit, config.SnippetFn)
+			semanticResult, err = s.semantic.SearchWithSnippets(ctx, query, config.SemanticLimit+offset, config.SnippetFn)
 		} else {
-			semanticResult, err = s.semantic.SearchWithContext(ctx, query, config.SemanticLimit)
+			semanticResult, err = s.semantic.SearchWithContext(ctx, query, config.SemanticLimit+offset)
 		}
 
 		if err != nil {
@@ -153,18 +156,25 @@ func (s *Searcher) Search(ctx context.Context, query, dir string, config Config)
 		results = append(results, *r)
 	}
 
-	// Sort by score descending
+	// Sort by score descending, ties by path and line since map order varies
 	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].Path != results[j].Path {
+			return results[i].Path < results[j].Path
+		}
+		return results[i].StartLine < results[j].StartLine
 	})
 
-	// Limit total results
+	// Limit total results, skipping earlier pages. Each channel fetched
+	// offset extra results so a page is ranked against the same pool.
 	maxResults := config.KeywordLimit + config.SemanticLimit
-	if len(results) > maxResults {
-		results = results[:maxResults]
-	}
+	hasMore := len(results) > offset+maxResults
+	results = results[min(offset, len(results)):min(offset+maxResults, len(results))]
 
 	return &SearchResult{
+		HasMore:         hasMore,
 		Results:         results,
 		KeywordCount:    keywordCount,
 		SemanticCount:   semanticCount,
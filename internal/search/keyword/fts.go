@@ -0,0 +1,223 @@
+package keyword
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"codetect/internal/config"
+	"codetect/internal/db"
+)
+
+// ftsTable holds chunk contents for keyword search. The trigram tokenizer
+// matches any substring of three or more characters, and case_sensitive
+// keeps matches as exact as ripgrep's default.
+const ftsTable = "keyword_fts"
+
+const ftsSchema = `CREATE VIRTUAL TABLE IF NOT EXISTS keyword_fts USING fts5(
+    content,
+    repo_root UNINDEXED,
+    path UNINDEXED,
+    start_line UNINDEXED,
+    tokenize = 'trigram case_sensitive 1'
+)`
+
+// minFTSQuery is the shortest query the trigram index can answer
+const minFTSQuery = 3
+
+// Chunk is a piece of a file added to the keyword index
+type Chunk struct {
+	Path      string // Absolute or repo-relative
+	StartLine int
+	Content   string
+}
+
+// FTSIndex is a SQLite FTS5 keyword index over one repo's chunk contents,
+// filled during embed and stored in the repo's symbols.db. It answers
+// literal keyword queries from the database instead of scanning files,
+// matching the content that was embedded.
+type FTSIndex struct {
+	db       db.DB
+	repoRoot string // Absolute repo root chunk paths are relative to
+	repoID   string // repo_root column value, see config.RepoID
+}
+
+// NewFTSIndex opens the keyword index in database, creating its table if
+// needed. FTS5 is SQLite-only.
+func NewFTSIndex(database db.DB, repoRoot string) (*FTSIndex, error) {
+	if _, err := database.Exec(ftsSchema); err != nil {
+		return nil, fmt.Errorf("creating keyword index: %w", err)
+	}
+	return &FTSIndex{db: database, repoRoot: config.NormalizeRepoRoot(repoRoot), repoID: config.RepoID(repoRoot)}, nil
+}
+
+// Replace swaps the indexed contents of paths for chunks in one
+// transaction. paths should include every file chunks come from, plus any
+// since deleted. A nil paths replaces the whole repo's contents.
+func (x *FTSIndex) Replace(paths []string, chunks []Chunk) error {
+	tx, err := x.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if paths == nil {
+		if _, err := tx.Exec("DELETE FROM "+ftsTable+" WHERE repo_root = ?", x.repoID); err != nil {
+			return fmt.Errorf("clearing keyword index: %w", err)
+		}
+	}
+	for _, path := range paths {
+		if _, err := tx.Exec("DELETE FROM "+ftsTable+" WHERE repo_root = ? AND path = ?", x.repoID, config.RepoRelPath(x.repoRoot, path)); err != nil {
+			return fmt.Errorf("clearing keyword index for %s: %w", path, err)
+		}
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO " + ftsTable + " (content, repo_root, path, start_line) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, c := range chunks {
+		if _, err := stmt.Exec(c.Content, x.repoID, config.RepoRelPath(x.repoRoot, c.Path), c.StartLine); err != nil {
+			return fmt.Errorf("indexing %s: %w", c.Path, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Count returns how many chunks are indexed for this repo
+func (x *FTSIndex) Count() (int, error) {
+	var n int
+	err := x.db.QueryRow("SELECT COUNT(*) FROM "+ftsTable+" WHERE repo_root = ?", x.repoID).Scan(&n)
+	return n, err
+}
+
+// Search finds lines containing query literally, case-sensitive like
+// ripgrep's default, best-ranked chunks first. Results are one line each
+// with repo-relative paths and the same descending scores as Search.
+// Queries shorter than three characters can't be answered from trigrams
+// and return an error.
+func (x *FTSIndex) Search(query string, topK int) (*SearchResult, error) {
+	if utf8.RuneCountInString(query) < minFTSQuery {
+		return nil, fmt.Errorf("keyword index needs at least %d characters, got %q", minFTSQuery, query)
+	}
+	if topK <= 0 {
+		topK = 20
+	}
+
+	// Quoting makes the query one FTS5 phrase, matched as a substring
+	phrase := `"` + strings.ReplaceAll(query, `"`, `""`) + `"`
+	rows, err := x.db.Query(
+		"SELECT path, start_line, content FROM "+ftsTable+" WHERE "+ftsTable+" MATCH ? AND repo_root = ? ORDER BY rank, path, start_line",
+		phrase, x.repoID)
+	if err != nil {
+		return nil, fmt.Errorf("querying keyword index: %w", err)
+	}
+	defer rows.Close()
+
+	// Chunks can overlap, so a line may come up more than once
+	type lineKey struct {
+		path string
+		line int
+	}
+	seen := make(map[lineKey]bool)
+	results := []Result{}
+	score := 100
+	for rows.Next() && len(results) < topK {
+		var path, content string
+		var startLine int
+		if err := rows.Scan(&path, &startLine, &content); err != nil {
+			return nil, err
+		}
+		for i, line := range strings.Split(content, "\n") {
+			if !strings.Contains(line, query) {
+				continue
+			}
+			key := lineKey{path, startLine + i}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			results = append(results, Result{
+				Path:      path,
+				LineStart: key.line,
+				LineEnd:   key.line,
+				Snippet:   strings.TrimRight(line, "\r"),
+				Score:     score,
+			})
+			score--
+			if len(results) >= topK {
+				break
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &SearchResult{Results: results}, nil
+}
+
+// Paths returns the repo-relative paths with indexed contents
+func (x *FTSIndex) Paths() (map[string]bool, error) {
+	rows, err := x.db.Query("SELECT DISTINCT path FROM "+ftsTable+" WHERE repo_root = ?", x.repoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paths := make(map[string]bool)
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths[path] = true
+	}
+	return paths, rows.Err()
+}
+
+// searchIndexed answers query from root's keyword index when it can: root
+// has a SQLite index with keyword contents, and query is a literal of at
+// least three characters (ripgrep handles regular expressions). It also
+// returns the indexed paths, whose matches ripgrep shouldn't repeat. ok is
+// false when the index can't answer, and Search falls back to ripgrep.
+func searchIndexed(query, root string, topK int) (result *SearchResult, paths map[string]bool, ok bool) {
+	if utf8.RuneCountInString(query) < minFTSQuery || regexp.QuoteMeta(query) != query {
+		return nil, nil, false
+	}
+	if root == "" {
+		root = "."
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, nil, false
+	}
+	dbPath := filepath.Join(config.DataDir(absRoot), "symbols.db")
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, nil, false
+	}
+
+	database, err := db.Open(db.DefaultConfig(dbPath))
+	if err != nil {
+		return nil, nil, false
+	}
+	defer database.Close()
+
+	var tables int
+	if err := database.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = ?", ftsTable).Scan(&tables); err != nil || tables == 0 {
+		return nil, nil, false
+	}
+	index := &FTSIndex{db: database, repoRoot: absRoot, repoID: config.RepoID(absRoot)}
+	paths, err = index.Paths()
+	if err != nil || len(paths) == 0 {
+		return nil, nil, false
+	}
+	result, err = index.Search(query, topK)
+	if err != nil {
+		return nil, nil, false
+	}
+	return result, paths, true
+}
@@ -0,0 +1,178 @@
+package keyword
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"codetect/internal/db"
+)
+
+func newTestFTSIndex(t *testing.T, dbPath, root string) *FTSIndex {
+	t.Helper()
+	database, err := db.Open(db.DefaultConfig(dbPath))
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	index, err := NewFTSIndex(database, root)
+	if err != nil {
+		t.Fatalf("NewFTSIndex() error = %v", err)
+	}
+	return index
+}
+
+func TestFTSIndexSearch(t *testing.T) {
+	root := t.TempDir()
+	index := newTestFTSIndex(t, ":memory:", root)
+
+	chunks := []Chunk{
+		{Path: filepath.Join(root, "a.go"), StartLine: 1, Content: "package a\n\nfunc keywordSearch() {}"},
+		// Overlaps a.go's chunk, so line 3 comes up twice
+		{Path: filepath.Join(root, "a.go"), StartLine: 3, Content: "func keywordSearch() {}\nvar x = 1"},
+		{Path: "b.go", StartLine: 10, Content: "// search is lower case here\nfunc Search() {}"},
+	}
+	if err := index.Replace(nil, chunks); err != nil {
+		t.Fatalf("Replace() error = %v", err)
+	}
+
+	result, err := index.Search("Search", 10)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	type hit struct {
+		path string
+		line int
+	}
+	got := make(map[hit]bool)
+	for _, r := range result.Results {
+		got[hit{r.Path, r.LineStart}] = true
+	}
+	want := map[hit]bool{{"a.go", 3}: true, {"b.go", 11}: true}
+	if len(result.Results) != len(want) {
+		t.Fatalf("Search() = %+v, want lines %v once each", result.Results, want)
+	}
+	for h := range want {
+		if !got[h] {
+			t.Errorf("Search() missing %s:%d in %+v", h.path, h.line, result.Results)
+		}
+	}
+	if result.Results[1].Score >= result.Results[0].Score {
+		t.Errorf("scores should be descending: %+v", result.Results)
+	}
+
+	if _, err := index.Search("ab", 10); err == nil {
+		t.Error("Search() of a 2-character query succeeded, want an error")
+	}
+}
+
+func TestFTSIndexReplacePaths(t *testing.T) {
+	index := newTestFTSIndex(t, ":memory:", "/repo")
+
+	if err := index.Replace(nil, []Chunk{
+		{Path: "a.go", StartLine: 1, Content: "oldName()"},
+		{Path: "b.go", StartLine: 1, Content: "oldName()"},
+	}); err != nil {
+		t.Fatalf("Replace() error = %v", err)
+	}
+	// a.go was edited and b.go deleted
+	if err := index.Replace([]string{"/repo/a.go", "b.go"}, []Chunk{
+		{Path: "/repo/a.go", StartLine: 1, Content: "newName()"},
+	}); err != nil {
+		t.Fatalf("Replace() error = %v", err)
+	}
+
+	if result, err := index.Search("oldName", 10); err != nil || len(result.Results) != 0 {
+		t.Errorf("Search(oldName) = %+v, %v, want no results", result, err)
+	}
+	result, err := index.Search("newName", 10)
+	if err != nil || len(result.Results) != 1 || result.Results[0].Path != "a.go" {
+		t.Errorf("Search(newName) = %+v, %v, want a.go", result, err)
+	}
+}
+
+func TestSearchUsesKeywordIndex(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("CODETECT_DATA_DIR", "")
+	t.Setenv("CODETECT_INDEX_KEYWORD", "true")
+	dataDir := filepath.Join(root, ".codetect")
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	index := newTestFTSIndex(t, filepath.Join(dataDir, "symbols.db"), root)
+
+	// Indexed content that isn't on disk can only come from the index
+	if err := index.Replace(nil, []Chunk{{Path: "gone.go", StartLine: 4, Content: "func onlyIndexed() {}"}}); err != nil {
+		t.Fatalf("Replace() error = %v", err)
+	}
+
+	result, err := Search("onlyIndexed", root, 10)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(result.Results) != 1 || result.Results[0].Path != "gone.go" || result.Results[0].LineStart != 4 {
+		t.Errorf("Search() = %+v, want gone.go:4 from the keyword index", result.Results)
+	}
+
+	// Regular expressions go to ripgrep, which sees no such file
+	if _, _, ok := searchIndexed(`only\w+`, root, 10); ok {
+		t.Error("searchIndexed() answered a regular expression")
+	}
+}
+
+func TestSearchMergesRipgrepForUnindexedFiles(t *testing.T) {
+	if _, err := exec.LookPath("rg"); err != nil {
+		t.Skip("ripgrep not installed")
+	}
+	root := t.TempDir()
+	t.Setenv("CODETECT_DATA_DIR", "")
+	t.Setenv("CODETECT_INDEX_KEYWORD", "true")
+	dataDir := filepath.Join(root, ".codetect")
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	index := newTestFTSIndex(t, filepath.Join(dataDir, "symbols.db"), root)
+
+	// main.go is indexed and on disk; README.md was never embedded
+	files := map[string]string{
+		"main.go":   "package main\n\nfunc setupWidget() {}\n",
+		"README.md": "# Demo\n\nCall setupWidget first.\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := index.Replace(nil, []Chunk{{Path: "main.go", StartLine: 1, Content: files["main.go"]}}); err != nil {
+		t.Fatalf("Replace() error = %v", err)
+	}
+
+	result, err := Search("setupWidget", root, 10)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	got := make(map[string]int)
+	for _, r := range result.Results {
+		got[r.Path]++
+	}
+	if len(result.Results) != 2 || got["main.go"] != 1 || got["README.md"] != 1 {
+		t.Errorf("Search() = %+v, want main.go from the index and README.md from ripgrep once each", result.Results)
+	}
+
+	// With the option off the index isn't consulted at all
+	t.Setenv("CODETECT_INDEX_KEYWORD", "false")
+	if err := index.Replace(nil, []Chunk{{Path: "gone.go", StartLine: 1, Content: "setupWidget()"}}); err != nil {
+		t.Fatalf("Replace() error = %v", err)
+	}
+	result, err = Search("setupWidget", root, 10)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	for _, r := range result.Results {
+		if r.Path == "gone.go" {
+			t.Errorf("Search() with CODETECT_INDEX_KEYWORD=false returned indexed-only %+v", r)
+		}
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+
+	"codetect/internal/config"
 )
 
 // Result represents a single search match
@@ -41,12 +43,48 @@ type RipgrepMatchData struct {
 	AbsoluteOffset int `json:"absolute_offset"`
 }
 
-// Search performs a keyword search using ripgrep
+// Search performs a keyword search using ripgrep. With
+// CODETECT_INDEX_KEYWORD set, literal queries against a repo whose embed
+// filled a keyword index are answered from that index first, and ripgrep
+// only fills the remaining results from files the index doesn't cover.
 func Search(query string, root string, topK int) (*SearchResult, error) {
 	if topK <= 0 {
 		topK = 20
 	}
 
+	if !config.LoadIndexConfigFromEnv().KeywordIndex {
+		return searchRipgrep(query, root, topK, nil)
+	}
+	indexed, paths, ok := searchIndexed(query, root, topK)
+	if !ok {
+		return searchRipgrep(query, root, topK, nil)
+	}
+	if len(indexed.Results) >= topK {
+		return indexed, nil
+	}
+
+	// Files embed skipped, such as docs, are only searchable by ripgrep
+	rest, err := searchRipgrep(query, root, topK-len(indexed.Results), func(path string) bool {
+		return paths[filepath.ToSlash(filepath.Clean(path))]
+	})
+	if err != nil {
+		if len(indexed.Results) > 0 {
+			return indexed, nil
+		}
+		return nil, err
+	}
+	score := 100 - len(indexed.Results)
+	for _, r := range rest.Results {
+		r.Score = score
+		indexed.Results = append(indexed.Results, r)
+		score--
+	}
+	return indexed, nil
+}
+
+// searchRipgrep runs ripgrep for query under root, leaving out matches in
+// files skip reports true for. A nil skip keeps every match.
+func searchRipgrep(query string, root string, topK int, skip func(path string) bool) (*SearchResult, error) {
 	// Use rg --json for structured output
 	args := []string{
 		"--json",
@@ -81,7 +119,7 @@ func Search(query string, root string, topK int) (*SearchResult, error) {
 	for scanner.Scan() && len(results) < topK {
 		line := scanner.Text()
 		result, ok := parseRipgrepJSON(line, root)
-		if ok {
+		if ok && (skip == nil || !skip(result.Path)) {
 			result.Score = score
 			results = append(results, result)
 			score--
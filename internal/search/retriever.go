@@ -259,7 +259,7 @@ func (r *Retriever) searchSymbol(ctx context.Context, query string) ([]fusion.Re
 	}
 
 	// FindSymbol does a LIKE search, which is appropriate for user queries
-	symbols, err := r.symbolIndex.FindSymbol(query, "", r.config.SymbolLimit)
+	symbols, err := r.symbolIndex.FindSymbol(query, symbols.SymbolFilter{}, r.config.SymbolLimit)
 	if err != nil {
 		return nil, err
 	}
@@ -12,10 +12,37 @@ import (
 
 // AstGrepEntry represents a single match from ast-grep JSON output
 type AstGrepEntry struct {
-	Text  string            `json:"text"`
-	Range AstGrepRange      `json:"range"`
-	File  string            `json:"file"`
-	Meta  map[string]string `json:"metaVariables,omitempty"`
+	Text  string       `json:"text"`
+	Range AstGrepRange `json:"range"`
+	File  string       `json:"file"`
+	Meta  AstGrepMeta  `json:"metaVariables,omitempty"`
+}
+
+// AstGrepMeta maps meta variable names, like NAME, to the text they matched
+type AstGrepMeta map[string]string
+
+// UnmarshalJSON accepts both a flat name-to-text object and the nested form
+// ast-grep writes, {"single": {"NAME": {"text": ...}}, "multi": ...}.
+func (m *AstGrepMeta) UnmarshalJSON(data []byte) error {
+	var flat map[string]string
+	if err := json.Unmarshal(data, &flat); err == nil {
+		*m = flat
+		return nil
+	}
+
+	var nested struct {
+		Single map[string]struct {
+			Text string `json:"text"`
+		} `json:"single"`
+	}
+	if err := json.Unmarshal(data, &nested); err != nil {
+		return err
+	}
+	*m = make(AstGrepMeta, len(nested.Single))
+	for name, v := range nested.Single {
+		(*m)[name] = v.Text
+	}
+	return nil
 }
 
 // AstGrepRange represents the location of a match
@@ -251,13 +278,19 @@ func SupportedLanguages() []string {
 	}
 }
 
-// RunAstGrep runs ast-grep on the given files and returns symbols
+// RunAstGrep runs ast-grep on the given files and returns symbols. The
+// built-in patterns for language are extended by any in the repo's
+// patterns file (see PatternsPath).
 func RunAstGrep(root string, files []string, language string) ([]Symbol, error) {
 	if !AstGrepAvailable() {
 		return nil, fmt.Errorf("ast-grep not available")
 	}
 
-	langPatterns := GetLanguagePatterns(language)
+	custom, err := LoadCustomPatterns(PatternsPath(root))
+	if err != nil {
+		return nil, fmt.Errorf("loading custom patterns: %w", err)
+	}
+	langPatterns := MergePatterns(language, custom)
 	if langPatterns == nil {
 		return nil, fmt.Errorf("no patterns defined for language: %s", language)
 	}
@@ -289,38 +322,76 @@ func RunAstGrep(root string, files []string, language string) ([]Symbol, error)
 			}
 		}
 
-		// Parse JSON output line by line
-		scanner := bufio.NewScanner(&stdout)
-		for scanner.Scan() {
-			line := scanner.Text()
-			if line == "" {
-				continue
+		for _, entry := range parseAstGrepOutput(stdout.Bytes()) {
+			// Skip matches no name could be found for rather than
+			// indexing them as "unknown"
+			if symbol, ok := astGrepEntryToSymbol(entry, pattern.Kind, root); ok {
+				allSymbols = append(allSymbols, symbol)
 			}
+		}
+	}
+
+	return deduplicateSymbols(allSymbols), nil
+}
+
+// parseAstGrepOutput decodes ast-grep's JSON output, either the array --json
+// prints or one entry per line as with --json=stream. Malformed entries are
+// skipped.
+func parseAstGrepOutput(data []byte) []AstGrepEntry {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil
+	}
 
+	if data[0] == '[' {
+		var entries []AstGrepEntry
+		if err := json.Unmarshal(data, &entries); err == nil {
+			return entries
+		}
+		// Fall back to decoding the elements one by one
+		var raw []json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil
+		}
+		for _, r := range raw {
 			var entry AstGrepEntry
-			if err := json.Unmarshal([]byte(line), &entry); err != nil {
-				continue // Skip malformed entries
+			if err := json.Unmarshal(r, &entry); err == nil {
+				entries = append(entries, entry)
 			}
-
-			// Convert to Symbol
-			symbol := astGrepEntryToSymbol(entry, pattern.Kind, root)
-			allSymbols = append(allSymbols, symbol)
 		}
+		return entries
 	}
 
-	return deduplicateSymbols(allSymbols), nil
+	var entries []AstGrepEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var entry AstGrepEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			continue // Skip malformed entries
+		}
+		entries = append(entries, entry)
+	}
+	return entries
 }
 
-// astGrepEntryToSymbol converts an ast-grep entry to a Symbol
-func astGrepEntryToSymbol(entry AstGrepEntry, kind string, root string) Symbol {
+// astGrepEntryToSymbol converts an ast-grep entry to a Symbol. It reports
+// false if the entry has no usable name.
+func astGrepEntryToSymbol(entry AstGrepEntry, kind string, root string) (Symbol, bool) {
 	// Extract name from meta variables if available
-	name := entry.Meta["NAME"]
+	name := strings.TrimSpace(entry.Meta["NAME"])
 	if name == "" {
 		// Fallback: try to extract from first line of match
-		lines := strings.Split(entry.Text, "\n")
-		if len(lines) > 0 {
-			name = extractNameFromText(lines[0])
-		}
+		firstLine, _, _ := strings.Cut(entry.Text, "\n")
+		name = extractNameFromText(firstLine)
+	}
+	if name == "" || name == "unknown" {
+		return Symbol{}, false
 	}
 
 	// Make path relative to root
@@ -333,31 +404,92 @@ func astGrepEntryToSymbol(entry AstGrepEntry, kind string, root string) Symbol {
 
 	return Symbol{
 		Name:     name,
-		Kind:     normalizeKind(kind),
+		Kind:     normalizeAstGrepKind(kind),
 		Path:     relPath,
-		Line:     entry.Range.Start.Line,
+		Line:     entry.Range.Start.Line + 1, // ast-grep lines are 0-based
 		Language: "", // Will be set by caller
 		Pattern:  strings.TrimSpace(entry.Text),
-	}
+	}, true
+}
+
+// declarationWords are the keywords and modifiers that can come before a
+// symbol's name in the languages ast-grep patterns cover.
+var declarationWords = map[string]bool{
+	"func": true, "function": true, "fn": true, "fun": true, "def": true,
+	"class": true, "interface": true, "type": true, "struct": true, "enum": true,
+	"trait": true, "module": true, "protocol": true, "typedef": true,
+	"const": true, "let": true, "var": true,
+	"export": true, "default": true, "async": true, "pub": true, "pub(crate)": true,
+	"public": true, "private": true, "protected": true, "internal": true,
+	"static": true, "final": true, "abstract": true, "override": true, "open": true,
 }
 
-// extractNameFromText tries to extract a symbol name from text
+// extractNameFromText tries to extract a symbol name from the first line of
+// a declaration. It returns "unknown" if it finds none.
 func extractNameFromText(text string) string {
-	// Remove common keywords
 	text = strings.TrimSpace(text)
-	for _, prefix := range []string{"func ", "function ", "def ", "class ", "interface ", "type ", "struct ", "enum ", "trait ", "const ", "let ", "var "} {
-		text = strings.TrimPrefix(text, prefix)
+
+	// Drop a Go method receiver: func (r *Recv) Method(...)
+	if rest, ok := strings.CutPrefix(text, "func"); ok {
+		rest = strings.TrimSpace(rest)
+		if strings.HasPrefix(rest, "(") {
+			if end := strings.IndexByte(rest, ')'); end >= 0 {
+				text = "func " + rest[end+1:]
+			}
+		}
 	}
 
-	// Extract first word (usually the name)
-	fields := strings.FieldsFunc(text, func(r rune) bool {
-		return r == '(' || r == '{' || r == '<' || r == ':' || r == '='
-	})
-	if len(fields) > 0 {
-		return strings.TrimSpace(fields[0])
+	// Remove leading keywords and modifiers
+	fields := strings.Fields(text)
+	for len(fields) > 0 && declarationWords[fields[0]] {
+		fields = fields[1:]
+	}
+	text = stripTypeArgs(strings.Join(fields, " "))
+
+	// The name is the word before the parameter list or type parameters,
+	// as in "int add(" or "Map[K any](", or else the first word, as in
+	// "Point struct {" or "handler = (req) =>"
+	head := text
+	last := false
+	if i := strings.IndexAny(text, "({<:=["); i >= 0 {
+		head = text[:i]
+		last = text[i] == '(' || text[i] == '['
+	}
+	words := strings.Fields(head)
+	if len(words) == 0 {
+		return "unknown"
+	}
+	name := words[0]
+	if last {
+		name = words[len(words)-1]
+	}
+	name = strings.TrimLeft(name, "*&")
+	if name == "" {
+		return "unknown"
 	}
+	return name
+}
 
-	return "unknown"
+// stripTypeArgs removes generic arguments written against a word, so
+// "Task<int> Run(" reads as "Task Run(". A spaced "<", as in Ruby's
+// "class Foo < Bar", is left alone.
+func stripTypeArgs(text string) string {
+	var sb strings.Builder
+	depth := 0
+	for i, r := range text {
+		switch {
+		case r == '<' && (depth > 0 || ((i == 0 || text[i-1] != ' ') && strings.IndexByte(text[i:], '>') > 0)):
+			depth++
+			continue
+		case r == '>' && depth > 0:
+			depth--
+			continue
+		}
+		if depth == 0 {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
 }
 
 // deduplicateSymbols removes duplicate symbols based on path+line+name
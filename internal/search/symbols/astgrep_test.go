@@ -1,6 +1,8 @@
 package symbols
 
 import (
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -128,6 +130,22 @@ func TestExtractNameFromText(t *testing.T) {
 		{"trait Displayable {", "Displayable"},
 		{"const myConst =", "myConst"},
 		{"  func   SpacedName  (", "SpacedName"},
+		{"func (s *Server) Start(ctx context.Context) error {", "Start"},
+		{"func (Server) Stop() {", "Stop"},
+		{"func Map[K comparable, V any](m map[K]V) {", "Map"},
+		{"type Point struct {", "Point"},
+		{"export const handler = async (req) => {", "handler"},
+		{"export default class App extends Component {", "App"},
+		{"async def fetch(url):", "fetch"},
+		{"class Child(Base):", "Child"},
+		{"pub fn parse(input: &str) -> Result<Ast> {", "parse"},
+		{"pub(crate) struct Parser {", "Parser"},
+		{"public static void main(String[] args) {", "main"},
+		{"public <T> List<T> collect(Stream<T> s) {", "collect"},
+		{"public async Task<int> CountAsync() {", "CountAsync"},
+		{"static int *alloc_buf(size_t n) {", "alloc_buf"},
+		{"class Foo < Bar", "Foo"},
+		{"(", "unknown"},
 		{"", "unknown"},
 	}
 
@@ -181,7 +199,10 @@ func TestAstGrepEntryToSymbol(t *testing.T) {
 		},
 	}
 
-	symbol := astGrepEntryToSymbol(entry, "function", "/path/to/project")
+	symbol, ok := astGrepEntryToSymbol(entry, "function", "/path/to/project")
+	if !ok {
+		t.Fatal("astGrepEntryToSymbol() skipped an entry with a NAME")
+	}
 
 	if symbol.Name != "MyFunction" {
 		t.Errorf("Symbol.Name = %q, want %q", symbol.Name, "MyFunction")
@@ -191,8 +212,9 @@ func TestAstGrepEntryToSymbol(t *testing.T) {
 		t.Errorf("Symbol.Kind = %q, want %q", symbol.Kind, "function")
 	}
 
-	if symbol.Line != 10 {
-		t.Errorf("Symbol.Line = %d, want %d", symbol.Line, 10)
+	// ast-grep lines are 0-based, symbol lines 1-based
+	if symbol.Line != 11 {
+		t.Errorf("Symbol.Line = %d, want %d", symbol.Line, 11)
 	}
 
 	if symbol.Path != "main.go" {
@@ -208,10 +230,95 @@ func TestAstGrepEntryToSymbolWithoutMetaName(t *testing.T) {
 		Meta:  map[string]string{}, // No NAME in meta
 	}
 
-	symbol := astGrepEntryToSymbol(entry, "function", ".")
+	symbol, ok := astGrepEntryToSymbol(entry, "function", ".")
+	if !ok {
+		t.Fatal("astGrepEntryToSymbol() skipped an entry whose name is in its text")
+	}
 
 	// Should extract name from text
 	if symbol.Name != "getUserData" {
 		t.Errorf("Symbol.Name = %q, want %q (extracted from text)", symbol.Name, "getUserData")
 	}
 }
+
+func TestParseAstGrepOutput(t *testing.T) {
+	// Matches as ast-grep prints them, with metaVariables nested under
+	// "single" and some without a NAME at all
+	tests := []struct {
+		name   string
+		kind   string
+		output string
+		want   []string
+		lines  []int
+	}{
+		{
+			name: "go",
+			kind: "method",
+			output: `[
+{"text":"func (s *Server) Start(ctx context.Context) error {\n\treturn nil\n}","range":{"byteOffset":{"start":10,"end":60},"start":{"line":4,"column":0},"end":{"line":6,"column":1}},"file":"/repo/server.go","language":"Go","metaVariables":{"single":{"NAME":{"text":"Start","range":{"byteOffset":{"start":27,"end":32},"start":{"line":4,"column":17},"end":{"line":4,"column":22}}}},"multi":{},"transformed":{}}},
+{"text":"func (s *Server) Stop() {\n}","range":{"byteOffset":{"start":62,"end":90},"start":{"line":8,"column":0},"end":{"line":9,"column":1}},"file":"/repo/server.go","language":"Go","metaVariables":{"single":{},"multi":{"secondary":[]},"transformed":{}}}
+]`,
+			want:  []string{"Start", "Stop"},
+			lines: []int{5, 9},
+		},
+		{
+			name: "typescript",
+			kind: "function",
+			output: `{"text":"export const handler = async (req) => {\n}","range":{"byteOffset":{"start":0,"end":40},"start":{"line":0,"column":0},"end":{"line":1,"column":1}},"file":"/repo/api.ts","language":"TypeScript","metaVariables":{"single":{},"multi":{},"transformed":{}}}
+{"text":"function render(props) {\n}","range":{"byteOffset":{"start":42,"end":68},"start":{"line":3,"column":0},"end":{"line":4,"column":1}},"file":"/repo/api.ts","language":"TypeScript","metaVariables":{"single":{"NAME":{"text":"render"}},"multi":{},"transformed":{}}}
+`,
+			want:  []string{"handler", "render"},
+			lines: []int{1, 4},
+		},
+		{
+			name:   "python",
+			kind:   "function",
+			output: `[{"text":"async def fetch(url):\n    pass","range":{"byteOffset":{"start":0,"end":30},"start":{"line":0,"column":0},"end":{"line":1,"column":8}},"file":"/repo/client.py","language":"Python","metaVariables":{"single":{},"multi":{},"transformed":{}}}]`,
+			want:   []string{"fetch"},
+			lines:  []int{1},
+		},
+		{
+			name:   "rust",
+			kind:   "function",
+			output: `[{"text":"pub fn parse(input: &str) -> Result<Ast> {\n}","range":{"byteOffset":{"start":0,"end":44},"start":{"line":2,"column":0},"end":{"line":3,"column":1}},"file":"/repo/lib.rs","language":"Rust","metaVariables":{"single":{"NAME":{"text":"parse"}},"multi":{},"transformed":{}}}]`,
+			want:   []string{"parse"},
+			lines:  []int{3},
+		},
+		{
+			name:   "java",
+			kind:   "method",
+			output: `[{"text":"public static void main(String[] args) {\n}","range":{"byteOffset":{"start":0,"end":42},"start":{"line":1,"column":4},"end":{"line":2,"column":5}},"file":"/repo/App.java","language":"Java","metaVariables":{"single":{},"multi":{},"transformed":{}}}]`,
+			want:   []string{"main"},
+			lines:  []int{2},
+		},
+		{
+			name:   "no name",
+			kind:   "function",
+			output: `[{"text":"(","range":{"byteOffset":{"start":0,"end":1},"start":{"line":0,"column":0},"end":{"line":0,"column":1}},"file":"/repo/odd.c","language":"C","metaVariables":{"single":{"NAME":{"text":""}},"multi":{},"transformed":{}}}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			var lines []int
+			for _, entry := range parseAstGrepOutput([]byte(tt.output)) {
+				symbol, ok := astGrepEntryToSymbol(entry, tt.kind, "/repo")
+				if !ok {
+					continue
+				}
+				if symbol.Name == "" || symbol.Name == "unknown" {
+					t.Errorf("astGrepEntryToSymbol() kept symbol with name %q", symbol.Name)
+				}
+				got = append(got, symbol.Name)
+				lines = append(lines, symbol.Line)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("symbol names = %v, want %v", got, tt.want)
+			}
+			if !slices.Equal(lines, tt.lines) {
+				t.Errorf("symbol lines = %v, want 1-based %v", lines, tt.lines)
+			}
+		})
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os/exec"
 	"path/filepath"
 	"strings"
+
+	"codetect/internal/config"
 )
 
 // CtagsEntry represents a single entry from ctags JSON output
@@ -36,17 +39,51 @@ func CtagsAvailable() bool {
 
 // RunCtags runs universal-ctags on the given paths and returns parsed entries
 // If paths is empty, runs on current directory recursively
-func RunCtags(root string, paths []string) ([]CtagsEntry, error) {
+func RunCtags(root string, paths []string, opts config.CtagsOptions) ([]CtagsEntry, error) {
 	if !CtagsAvailable() {
 		return nil, fmt.Errorf("universal-ctags not available")
 	}
 
+	cmd := exec.Command("ctags", ctagsArgs(root, paths, opts)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("creating stdout pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting ctags: %w", err)
+	}
+
+	entries, scanErr := parseCtagsOutput(stdout, root)
+	if scanErr != nil {
+		io.Copy(io.Discard, stdout) //nolint:errcheck // Let ctags finish writing
+	}
+
+	if err := cmd.Wait(); err != nil {
+		// ctags may exit with error even if it produced output
+		if len(entries) > 0 {
+			return entries, nil
+		}
+		return nil, fmt.Errorf("ctags error: %w", err)
+	}
+	if scanErr != nil && len(entries) == 0 {
+		return nil, fmt.Errorf("reading ctags output: %w", scanErr)
+	}
+
+	return entries, nil
+}
+
+// ctagsArgs builds the ctags command line. opts.Args come after the
+// defaults so they take precedence, and before the paths so they aren't
+// mistaken for files.
+func ctagsArgs(root string, paths []string, opts config.CtagsOptions) []string {
 	args := []string{
 		"--output-format=json",
-		"--fields=+nKS",        // Include line number, kind, scope, signature
-		"--kinds-all=*",        // Include all symbol kinds
-		"--extras=+q",          // Include qualified tags
+		"--fields=+nKS", // Include line number, kind, scope, signature
+		"--kinds-all=*", // Include all symbol kinds
+		"--extras=+q",   // Include qualified tags
 	}
+	args = append(args, opts.Args...)
 
 	if len(paths) == 0 {
 		// Recursive scan
@@ -59,19 +96,15 @@ func RunCtags(root string, paths []string) ([]CtagsEntry, error) {
 	} else {
 		args = append(args, paths...)
 	}
+	return args
+}
 
-	cmd := exec.Command("ctags", args...)
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, fmt.Errorf("creating stdout pipe: %w", err)
-	}
-
-	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("starting ctags: %w", err)
-	}
-
+// parseCtagsOutput reads ctags JSON lines, keeping tag entries with paths
+// made relative to root. Malformed lines and non-tag entries (program info
+// and the like) are skipped.
+func parseCtagsOutput(r io.Reader, root string) ([]CtagsEntry, error) {
 	var entries []CtagsEntry
-	scanner := bufio.NewScanner(stdout)
+	scanner := bufio.NewScanner(r)
 
 	// Increase buffer size for long lines
 	const maxCapacity = 1024 * 1024
@@ -104,33 +137,31 @@ func RunCtags(root string, paths []string) ([]CtagsEntry, error) {
 
 		entries = append(entries, entry)
 	}
-
-	if err := cmd.Wait(); err != nil {
-		// ctags may exit with error even if it produced output
-		if len(entries) > 0 {
-			return entries, nil
-		}
-		return nil, fmt.Errorf("ctags error: %w", err)
-	}
-
-	return entries, nil
+	return entries, scanner.Err()
 }
 
-// RunCtagsOnFile runs ctags on a single file
+// RunCtagsOnFile runs ctags on a single file with the default options
 func RunCtagsOnFile(path string) ([]CtagsEntry, error) {
-	return RunCtags("", []string{path})
+	return RunCtags("", []string{path}, config.CtagsOptions{})
 }
 
-// ToSymbol converts a CtagsEntry to a Symbol
+// ToSymbol converts a CtagsEntry to a Symbol. Functions scoped to a type,
+// such as Go methods tagged as funcs of their receiver's struct, become
+// methods.
 func (e *CtagsEntry) ToSymbol() Symbol {
 	scope := e.Scope
 	if e.ScopeKind != "" && scope != "" {
 		scope = e.ScopeKind + ":" + scope
 	}
 
+	kind := normalizeKind(e.Language, e.Kind)
+	if kind == KindFunction && typeScopeKinds[normalizeKind(e.Language, e.ScopeKind)] {
+		kind = KindMethod
+	}
+
 	return Symbol{
 		Name:      e.Name,
-		Kind:      normalizeKind(e.Kind),
+		Kind:      kind,
 		Path:      e.Path,
 		Line:      e.Line,
 		Language:  e.Language,
@@ -138,32 +169,3 @@ func (e *CtagsEntry) ToSymbol() Symbol {
 		Scope:     scope,
 	}
 }
-
-// normalizeKind normalizes ctags kind names to consistent values
-func normalizeKind(kind string) string {
-	// Map common ctags kinds to normalized names
-	switch strings.ToLower(kind) {
-	case "f", "func", "function", "method":
-		return "function"
-	case "c", "class":
-		return "class"
-	case "s", "struct":
-		return "struct"
-	case "i", "interface":
-		return "interface"
-	case "t", "type", "typedef":
-		return "type"
-	case "v", "var", "variable":
-		return "variable"
-	case "const", "constant":
-		return "constant"
-	case "p", "package":
-		return "package"
-	case "m", "member", "field":
-		return "field"
-	case "e", "enum", "enumerator":
-		return "enum"
-	default:
-		return kind
-	}
-}
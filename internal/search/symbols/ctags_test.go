@@ -1,7 +1,13 @@
 package symbols
 
 import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+
+	"codetect/internal/config"
 )
 
 func TestNormalizeKind(t *testing.T) {
@@ -12,7 +18,7 @@ func TestNormalizeKind(t *testing.T) {
 		{"f", "function"},
 		{"func", "function"},
 		{"function", "function"},
-		{"method", "function"},
+		{"method", "method"},
 		{"c", "class"},
 		{"class", "class"},
 		{"s", "struct"},
@@ -27,20 +33,22 @@ func TestNormalizeKind(t *testing.T) {
 		{"variable", "variable"},
 		{"const", "constant"},
 		{"constant", "constant"},
-		{"p", "package"},
-		{"package", "package"},
-		{"m", "field"},
-		{"member", "field"},
-		{"field", "field"},
+		{"p", "module"},
+		{"package", "module"},
+		{"namespace", "module"},
+		{"m", "variable"},
+		{"member", "variable"},
+		{"field", "variable"},
 		{"e", "enum"},
 		{"enum", "enum"},
-		{"enumerator", "enum"},
+		{"enumerator", "constant"},
+		{"trait", "interface"},
 		{"unknown_kind", "unknown_kind"}, // Passthrough
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.input, func(t *testing.T) {
-			result := normalizeKind(tt.input)
+			result := normalizeKind("", tt.input)
 			if result != tt.expected {
 				t.Errorf("normalizeKind(%q) = %q, want %q", tt.input, result, tt.expected)
 			}
@@ -48,6 +56,31 @@ func TestNormalizeKind(t *testing.T) {
 	}
 }
 
+func TestNormalizeKindByLanguage(t *testing.T) {
+	tests := []struct {
+		language string
+		kind     string
+		expected string
+	}{
+		{"Java", "m", "method"},
+		{"Java", "f", "variable"},
+		{"Go", "c", "constant"},
+		{"Go", "f", "function"},
+		{"Python", "member", "method"},
+		{"Ruby", "f", "method"},
+		{"JavaScript", "C", "constant"},
+		{"JavaScript", "c", "class"},
+		{"C", "m", "variable"},
+		{"Unknown", "m", "variable"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeKind(tt.language, tt.kind); got != tt.expected {
+			t.Errorf("normalizeKind(%q, %q) = %q, want %q", tt.language, tt.kind, got, tt.expected)
+		}
+	}
+}
+
 func TestCtagsEntryToSymbol(t *testing.T) {
 	entry := CtagsEntry{
 		Type:      "tag",
@@ -98,7 +131,106 @@ func TestCtagsEntryToSymbolWithScope(t *testing.T) {
 	if sym.Scope != "struct:Server" {
 		t.Errorf("Scope = %q, want %q", sym.Scope, "struct:Server")
 	}
-	if sym.Kind != "function" {
-		t.Errorf("Kind = %q, want %q (method normalizes to function)", sym.Kind, "function")
+	if sym.Kind != "method" {
+		t.Errorf("Kind = %q, want %q", sym.Kind, "method")
+	}
+}
+
+func TestCtagsArgs(t *testing.T) {
+	opts := config.CtagsOptions{Args: []string{"--kinds-go=-p", "--extras=-q"}}
+	got := ctagsArgs("/repo", []string{"/repo/a.go"}, opts)
+	want := []string{"--output-format=json", "--fields=+nKS", "--kinds-all=*", "--extras=+q",
+		"--kinds-go=-p", "--extras=-q", "/repo/a.go"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ctagsArgs() = %q, want %q", got, want)
+	}
+
+	if got := ctagsArgs("/repo", nil, config.CtagsOptions{}); !slices.Equal(got[len(got)-2:], []string{"-R", "/repo"}) {
+		t.Errorf("ctagsArgs() without paths = %q, want a recursive scan of /repo", got)
+	}
+}
+
+// ctagsFixtureOutput is universal-ctags JSON output for a small Go file,
+// with the program info line and a malformed line mixed in
+const ctagsFixtureOutput = `{"_type": "ptag", "name": "JSON_OUTPUT_VERSION", "path": "0.0"}
+{"_type": "tag", "name": "server", "path": "/repo/pkg/server.go", "pattern": "/^package server$/", "language": "Go", "line": 1, "kind": "package"}
+{"_type": "tag", "name": "Server", "path": "/repo/pkg/server.go", "pattern": "/^type Server struct {$/", "language": "Go", "line": 3, "kind": "struct", "scope": "server", "scopeKind": "package"}
+not json
+{"_type": "tag", "name": "Start", "path": "/repo/pkg/server.go", "pattern": "/^func (s *Server) Start() error {$/", "language": "Go", "line": 7, "kind": "func", "signature": "()", "scope": "server.Server", "scopeKind": "struct"}
+`
+
+func TestParseCtagsOutput(t *testing.T) {
+	entries, err := parseCtagsOutput(strings.NewReader(ctagsFixtureOutput), "/repo")
+	if err != nil {
+		t.Fatalf("parseCtagsOutput() error = %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("parseCtagsOutput() = %d entries, want the 3 tags: %+v", len(entries), entries)
+	}
+
+	// A func scoped to a struct is a method
+	start := entries[2].ToSymbol()
+	if start.Name != "Start" || start.Kind != "method" || start.Line != 7 ||
+		start.Path != filepath.Join("pkg", "server.go") || start.Scope != "struct:server.Server" {
+		t.Errorf("Start symbol = %+v", start)
+	}
+	if entries[1].Kind != "struct" || entries[1].Signature != "" {
+		t.Errorf("Server entry = %+v", entries[1])
+	}
+}
+
+func TestRunCtagsOnFixture(t *testing.T) {
+	if !CtagsAvailable() {
+		t.Skip("universal-ctags not available")
+	}
+
+	root := t.TempDir()
+	path := filepath.Join(root, "server.go")
+	fixture := `package server
+
+type Server struct {
+	Addr string
+}
+
+func (s *Server) Start() error {
+	return nil
+}
+
+func NewServer() *Server {
+	return &Server{}
+}
+`
+	if err := os.WriteFile(path, []byte(fixture), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := func(opts config.CtagsOptions) map[string]string {
+		t.Helper()
+		entries, err := RunCtags(root, []string{path}, opts)
+		if err != nil {
+			t.Fatalf("RunCtags() error = %v", err)
+		}
+		got := make(map[string]string)
+		for _, e := range entries {
+			sym := e.ToSymbol()
+			if sym.Path != "server.go" {
+				t.Errorf("entry path = %q, want server.go", sym.Path)
+			}
+			got[sym.Name] = sym.Kind
+		}
+		return got
+	}
+
+	got := kinds(config.CtagsOptions{})
+	for name, kind := range map[string]string{"server": "module", "Server": "struct", "Addr": "variable", "Start": "method", "NewServer": "function"} {
+		if got[name] != kind {
+			t.Errorf("symbol %s kind = %q, want %q (all: %v)", name, got[name], kind, got)
+		}
+	}
+
+	// Options come after the defaults, so they can take kinds away
+	got = kinds(config.CtagsOptions{Args: []string{"--kinds-Go=-p"}})
+	if _, ok := got["server"]; ok {
+		t.Errorf("--kinds-Go=-p still tagged the package: %v", got)
 	}
 }
@@ -0,0 +1,294 @@
+package symbols
+
+import (
+	"bufio"
+	"bytes"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ImportEdge is one import of an indexed file. A Go import of a package in
+// the repo gives an edge to each of the package's files.
+//
+// Go edges are stored against the package directory, written with a
+// trailing slash, and expanded to the package's current files when read,
+// so adding or removing a file in a package needn't reindex its importers.
+type ImportEdge struct {
+	Path   string `json:"path"`             // Importing file, repo-relative
+	Spec   string `json:"spec"`             // Import as written
+	Target string `json:"target,omitempty"` // Repo file it resolves to, empty for external packages
+}
+
+// importLanguage returns the import syntax used by path - "go", "python"
+// or "javascript" (covering TypeScript) - or "" if imports aren't extracted
+func importLanguage(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".go":
+		return "go"
+	case ".py":
+		return "python"
+	case ".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx":
+		return "javascript"
+	}
+	return ""
+}
+
+var (
+	// import x from 'm', import {a, b} from 'm' (across lines), import 'm'
+	jsImportRe = regexp.MustCompile(`(?m)^\s*import\s+(?:[^'"();]*?\s+from\s+)?['"]([^'"]+)['"]`)
+	// export {a} from 'm', export * from 'm'
+	jsExportRe = regexp.MustCompile(`(?m)^\s*export\s+[^'"();]*?\s+from\s+['"]([^'"]+)['"]`)
+	// require('m') and dynamic import('m')
+	jsCallRe = regexp.MustCompile(`\b(?:require|import)\(\s*['"]([^'"]+)['"]\s*\)`)
+
+	pyImportRe = regexp.MustCompile(`^\s*import\s+(.+)$`)
+	pyFromRe   = regexp.MustCompile(`^\s*from\s+(\S+)\s+import\s+(.+)$`)
+)
+
+// extractImports returns the import specs of a file, in order of first
+// appearance. Python relative imports keep their leading dots, and
+// "from . import x" gives ".x".
+func extractImports(path string, content []byte) []string {
+	var specs []string
+	switch importLanguage(path) {
+	case "go":
+		specs = goImports(content)
+	case "python":
+		specs = pythonImports(content)
+	case "javascript":
+		for _, re := range []*regexp.Regexp{jsImportRe, jsExportRe, jsCallRe} {
+			for _, m := range re.FindAllSubmatch(content, -1) {
+				specs = append(specs, string(m[1]))
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	unique := specs[:0]
+	for _, spec := range specs {
+		if !seen[spec] {
+			seen[spec] = true
+			unique = append(unique, spec)
+		}
+	}
+	return unique
+}
+
+func goImports(content []byte) []string {
+	f, err := parser.ParseFile(token.NewFileSet(), "", content, parser.ImportsOnly)
+	if err != nil {
+		return nil
+	}
+	var specs []string
+	for _, imp := range f.Imports {
+		if spec, err := strconv.Unquote(imp.Path.Value); err == nil {
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
+
+func pythonImports(content []byte) []string {
+	var specs []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if m := pyFromRe.FindStringSubmatch(line); m != nil {
+			module := m[1]
+			if strings.Trim(module, ".") != "" {
+				specs = append(specs, module)
+				continue
+			}
+			// from . import a, b imports sibling modules
+			for _, name := range splitPythonNames(m[2]) {
+				specs = append(specs, module+name)
+			}
+		} else if m := pyImportRe.FindStringSubmatch(line); m != nil {
+			specs = append(specs, splitPythonNames(m[1])...)
+		}
+	}
+	return specs
+}
+
+// splitPythonNames splits "a.b as c, d" into ["a.b", "d"]
+func splitPythonNames(list string) []string {
+	var names []string
+	for _, part := range strings.Split(strings.Trim(list, " ()\\"), ",") {
+		name, _, _ := strings.Cut(strings.TrimSpace(part), " ")
+		if name != "" && name != "*" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// importResolver maps import specs to files in a repo, checking the
+// filesystem as it goes
+type importResolver struct {
+	root     string // Absolute repo root
+	goModule string // Module path from the root go.mod, "" if none
+}
+
+func newImportResolver(root string) *importResolver {
+	r := &importResolver{root: root}
+	if data, err := os.ReadFile(filepath.Join(root, "go.mod")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if module, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+				r.goModule = strings.Trim(strings.TrimSpace(module), `"`)
+				break
+			}
+		}
+	}
+	return r
+}
+
+// edges returns the import edges of the repo-relative file relPath
+func (r *importResolver) edges(relPath string, content []byte) []ImportEdge {
+	var edges []ImportEdge
+	for _, spec := range extractImports(relPath, content) {
+		targets := r.resolve(relPath, spec)
+		if len(targets) == 0 {
+			edges = append(edges, ImportEdge{Path: relPath, Spec: spec})
+			continue
+		}
+		for _, target := range targets {
+			if target != relPath {
+				edges = append(edges, ImportEdge{Path: relPath, Spec: spec, Target: target})
+			}
+		}
+	}
+	return edges
+}
+
+// resolve returns the repo-relative files spec refers to from relPath,
+// none for packages outside the repo
+func (r *importResolver) resolve(relPath, spec string) []string {
+	dir := path.Dir(filepath.ToSlash(relPath))
+	switch importLanguage(relPath) {
+	case "go":
+		return r.resolveGo(spec)
+	case "python":
+		return r.resolvePython(dir, spec)
+	case "javascript":
+		if !strings.HasPrefix(spec, "./") && !strings.HasPrefix(spec, "../") {
+			return nil // Bare specifiers are packages
+		}
+		return r.firstFile(jsCandidates(path.Join(dir, spec)))
+	}
+	return nil
+}
+
+func (r *importResolver) resolveGo(spec string) []string {
+	if r.goModule == "" {
+		return nil
+	}
+	var pkgDir string
+	if spec == r.goModule {
+		pkgDir = "."
+	} else if rest, ok := strings.CutPrefix(spec, r.goModule+"/"); ok {
+		pkgDir = rest
+	} else {
+		return nil
+	}
+
+	if len(goPackageFiles(r.root, pkgDir)) == 0 {
+		return nil
+	}
+	return []string{goPackageTarget(pkgDir)}
+}
+
+// goPackageTarget is the stored target of an import of the Go package in
+// the repo-relative directory dir
+func goPackageTarget(dir string) string {
+	return dir + "/"
+}
+
+// goFileTarget is the stored target of imports of the package holding the
+// repo-relative Go file relPath
+func goFileTarget(relPath string) string {
+	return goPackageTarget(path.Dir(relPath))
+}
+
+// goPackageDir returns the directory of a stored Go package target
+func goPackageDir(target string) (string, bool) {
+	return strings.CutSuffix(target, "/")
+}
+
+// goPackageFiles lists the repo-relative non-test Go files of the package
+// in dir
+func goPackageFiles(root, dir string) []string {
+	entries, err := os.ReadDir(filepath.Join(root, filepath.FromSlash(dir)))
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() && strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") {
+			files = append(files, path.Join(dir, name))
+		}
+	}
+	return files
+}
+
+func (r *importResolver) resolvePython(dir, spec string) []string {
+	module := strings.TrimLeft(spec, ".")
+	dots := len(spec) - len(module)
+	modPath := strings.ReplaceAll(module, ".", "/")
+
+	var bases []string
+	if dots > 0 {
+		// One dot is the importing file's package, each more goes up one
+		base := dir
+		for i := 1; i < dots; i++ {
+			base = path.Dir(base)
+		}
+		bases = []string{base}
+	} else {
+		bases = []string{".", "src"}
+	}
+
+	for _, base := range bases {
+		p := path.Join(base, modPath)
+		if files := r.firstFile([]string{p + ".py", path.Join(p, "__init__.py")}); files != nil {
+			return files
+		}
+	}
+	return nil
+}
+
+// jsCandidates lists the files a relative JS/TS import of p may name
+func jsCandidates(p string) []string {
+	candidates := []string{p}
+	exts := []string{".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs"}
+	for _, ext := range exts {
+		candidates = append(candidates, p+ext)
+	}
+	// TypeScript sources are imported with the .js extension they compile to
+	if trimmed, ok := strings.CutSuffix(p, ".js"); ok {
+		candidates = append(candidates, trimmed+".ts", trimmed+".tsx")
+	}
+	for _, ext := range exts {
+		candidates = append(candidates, path.Join(p, "index"+ext))
+	}
+	return candidates
+}
+
+// firstFile returns the first candidate that is a regular file in the repo
+func (r *importResolver) firstFile(candidates []string) []string {
+	for _, c := range candidates {
+		if strings.HasPrefix(c, "../") || c == ".." {
+			continue // Outside the repo
+		}
+		if info, err := os.Stat(filepath.Join(r.root, filepath.FromSlash(c))); err == nil && info.Mode().IsRegular() {
+			return []string{c}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+package symbols
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"codetect/internal/db"
+)
+
+func TestExtractImports(t *testing.T) {
+	tests := []struct {
+		path    string
+		content string
+		want    []string
+	}{
+		{
+			path: "main.go",
+			content: `package main
+
+import (
+	"fmt"
+	log "example.com/app/internal/logging"
+)
+
+import "fmt"
+`,
+			want: []string{"fmt", "example.com/app/internal/logging"},
+		},
+		{
+			path: "pkg/app.py",
+			content: `import os, sys as system
+from pkg.util import helper  # comment
+from . import models, views
+from ..core import base
+`,
+			want: []string{"os", "sys", "pkg.util", ".models", ".views", "..core"},
+		},
+		{
+			path: "src/app.ts",
+			content: `import React from 'react';
+import {
+  a,
+  b,
+} from "./lib/ab";
+import './styles.css';
+export * from '../shared';
+const x = require('./x');
+const y = await import('./y');
+`,
+			want: []string{"react", "./lib/ab", "./styles.css", "../shared", "./x", "./y"},
+		},
+		{path: "README.md", content: "import x", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got := extractImports(tt.path, []byte(tt.content))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("extractImports() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func writeRepoFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		full := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestImportResolverEdges(t *testing.T) {
+	root := t.TempDir()
+	writeRepoFiles(t, root, map[string]string{
+		"go.mod":                 "module example.com/app\n\ngo 1.22\n",
+		"internal/db/db.go":      "package db\n",
+		"internal/db/query.go":   "package db\n",
+		"internal/db/db_test.go": "package db\n",
+		"pkg/__init__.py":        "",
+		"pkg/util.py":            "",
+		"pkg/sub/mod.py":         "",
+		"web/lib/ab.ts":          "",
+		"web/shared/index.js":    "",
+		"web/compiled.ts":        "",
+	})
+	r := newImportResolver(root)
+
+	tests := []struct {
+		path    string
+		content string
+		want    []ImportEdge
+	}{
+		{
+			path:    "cmd/main.go",
+			content: "package main\n\nimport (\n\t\"fmt\"\n\t\"example.com/app/internal/db\"\n)\n",
+			want: []ImportEdge{
+				{Path: "cmd/main.go", Spec: "fmt"},
+				// Stored against the package, expanded to its files when read
+				{Path: "cmd/main.go", Spec: "example.com/app/internal/db", Target: "internal/db/"},
+			},
+		},
+		{
+			path:    "pkg/sub/mod.py",
+			content: "import os\nimport pkg.util\nfrom .. import util\nfrom . import missing\n",
+			want: []ImportEdge{
+				{Path: "pkg/sub/mod.py", Spec: "os"},
+				{Path: "pkg/sub/mod.py", Spec: "pkg.util", Target: "pkg/util.py"},
+				{Path: "pkg/sub/mod.py", Spec: "..util", Target: "pkg/util.py"},
+				{Path: "pkg/sub/mod.py", Spec: ".missing"},
+			},
+		},
+		{
+			path:    "web/app.ts",
+			content: "import x from './lib/ab'\nimport '../outside'\nexport * from './shared'\nimport c from './compiled.js'\nimport r from 'react'\n",
+			want: []ImportEdge{
+				{Path: "web/app.ts", Spec: "./lib/ab", Target: "web/lib/ab.ts"},
+				{Path: "web/app.ts", Spec: "./compiled.js", Target: "web/compiled.ts"},
+				{Path: "web/app.ts", Spec: "../outside"},
+				{Path: "web/app.ts", Spec: "react"},
+				{Path: "web/app.ts", Spec: "./shared", Target: "web/shared/index.js"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got := r.edges(tt.path, []byte(tt.content))
+			byEdge := func(a, b ImportEdge) int {
+				if a.Spec != b.Spec {
+					if a.Spec < b.Spec {
+						return -1
+					}
+					return 1
+				}
+				if a.Target < b.Target {
+					return -1
+				} else if a.Target > b.Target {
+					return 1
+				}
+				return 0
+			}
+			slices.SortFunc(got, byEdge)
+			want := slices.Clone(tt.want)
+			slices.SortFunc(want, byEdge)
+			if !slices.Equal(got, want) {
+				t.Errorf("edges() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestImportsAndImportedBy(t *testing.T) {
+	idx, err := NewIndexWithConfig(db.DefaultConfig(filepath.Join(t.TempDir(), "symbols.db")), "/test/repo")
+	if err != nil {
+		t.Fatalf("NewIndexWithConfig() error = %v", err)
+	}
+	defer idx.Close()
+
+	replace := func(files []string, deleted []string, edges []ImportEdge) {
+		t.Helper()
+		tx, err := idx.adapter.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback() //nolint:errcheck
+		indexed := make(map[string]fileInfo)
+		for _, f := range files {
+			indexed[f] = fileInfo{}
+		}
+		if err := idx.replaceImports(tx, indexed, deleted, edges); err != nil {
+			t.Fatalf("replaceImports() error = %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	replace([]string{"a.go", "b.go"}, nil, []ImportEdge{
+		{Path: "a.go", Spec: "fmt"},
+		{Path: "a.go", Spec: "example.com/app/c", Target: "c/c.go"},
+		{Path: "b.go", Spec: "example.com/app/c", Target: "c/c.go"},
+	})
+
+	imports, err := idx.Imports("a.go")
+	if err != nil {
+		t.Fatalf("Imports() error = %v", err)
+	}
+	if len(imports) != 2 {
+		t.Errorf("Imports(a.go) = %+v, want 2 edges", imports)
+	}
+
+	importers := func() []string {
+		t.Helper()
+		edges, err := idx.ImportedBy("/test/repo/c/c.go")
+		if err != nil {
+			t.Fatalf("ImportedBy() error = %v", err)
+		}
+		var paths []string
+		for _, e := range edges {
+			paths = append(paths, e.Path)
+		}
+		return paths
+	}
+	if got := importers(); !slices.Equal(got, []string{"a.go", "b.go"}) {
+		t.Errorf("ImportedBy(c/c.go) = %v, want [a.go b.go]", got)
+	}
+
+	// a.go dropped the import and b.go was deleted
+	replace([]string{"a.go"}, []string{"b.go"}, []ImportEdge{{Path: "a.go", Spec: "fmt"}})
+	if got := importers(); len(got) != 0 {
+		t.Errorf("ImportedBy(c/c.go) after update = %v, want none", got)
+	}
+	if imports, err := idx.Imports("a.go"); err != nil || len(imports) != 1 || imports[0].Target != "" {
+		t.Errorf("Imports(a.go) after update = %+v, %v, want only fmt", imports, err)
+	}
+}
+
+func TestImportsFollowGoPackageFiles(t *testing.T) {
+	root := t.TempDir()
+	writeRepoFiles(t, root, map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.22\n",
+		"a.go":   "package main\n\nimport \"example.com/app/c\"\n",
+		"c/c.go": "package c\n",
+	})
+	idx, err := NewIndexWithConfig(db.DefaultConfig(filepath.Join(t.TempDir(), "symbols.db")), root)
+	if err != nil {
+		t.Fatalf("NewIndexWithConfig() error = %v", err)
+	}
+	defer idx.Close()
+
+	tx, err := idx.adapter.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	edges := idx.importEdges(map[string]fileInfo{"a.go": {}})
+	if err := idx.replaceImports(tx, map[string]fileInfo{"a.go": {}}, nil, edges); err != nil {
+		t.Fatalf("replaceImports() error = %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	targets := func() []string {
+		t.Helper()
+		imports, err := idx.Imports("a.go")
+		if err != nil {
+			t.Fatalf("Imports() error = %v", err)
+		}
+		var got []string
+		for _, e := range imports {
+			got = append(got, e.Target)
+		}
+		return got
+	}
+	importers := func(path string) int {
+		t.Helper()
+		edges, err := idx.ImportedBy(path)
+		if err != nil {
+			t.Fatalf("ImportedBy() error = %v", err)
+		}
+		return len(edges)
+	}
+
+	// A file added to the package is imported without reindexing a.go
+	writeRepoFiles(t, root, map[string]string{"c/d.go": "package c\n"})
+	if got := targets(); !slices.Equal(got, []string{"c/c.go", "c/d.go"}) {
+		t.Errorf("Imports(a.go) = %v, want [c/c.go c/d.go]", got)
+	}
+	if n := importers("c/d.go"); n != 1 {
+		t.Errorf("ImportedBy(c/d.go) = %d edges, want a.go's", n)
+	}
+
+	// And a deleted one no longer is
+	if err := os.Remove(filepath.Join(root, "c", "c.go")); err != nil {
+		t.Fatal(err)
+	}
+	if got := targets(); !slices.Equal(got, []string{"c/d.go"}) {
+		t.Errorf("Imports(a.go) after deleting c/c.go = %v, want [c/d.go]", got)
+	}
+	if n := importers("c/c.go"); n != 0 {
+		t.Errorf("ImportedBy(c/c.go) after deleting it = %d edges, want none", n)
+	}
+}
@@ -1,9 +1,11 @@
 package symbols
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,8 +13,15 @@ import (
 
 	"codetect/internal/config"
 	"codetect/internal/db"
+	"codetect/internal/merkle"
 )
 
+// TreeFileName is the Merkle tree stored in the data directory when change detection
+// uses content hashes. It is kept apart from the v2 indexer's tree so that
+// each pipeline diffs against its own last run and neither hides changes
+// from the other.
+const TreeFileName = "symbols-merkle-tree.json"
+
 // Index is the database-backed symbol index.
 // Uses the adapter pattern to support multiple database backends (SQLite, PostgreSQL).
 // All database operations go through the adapter interface for database portability.
@@ -21,32 +30,59 @@ type Index struct {
 	adapter    db.DB             // Adapter interface - use this for all database operations
 	dialect    db.Dialect        // SQL dialect for database-specific syntax (placeholders, etc.)
 	dbPath     string
-	root       string
+	root       string             // Repo key for scoped queries (see config.RepoID)
+	repoPath   string             // Repo directory; stored and returned paths are relative to it
 	indexCfg   config.IndexConfig // Indexing backend configuration
 }
 
 // NewIndex creates or opens a symbol index at the given path.
-// Uses SQLite by default. Sets repoRoot to current working directory.
+// Uses SQLite by default. The repo root is the directory holding dbPath's
+// .codetect directory, so rows are scoped to the indexed repo whatever the
+// working directory; a database elsewhere (e.g. under CODETECT_DATA_DIR)
+// falls back to the working directory. Use NewIndexForRepo to set it.
 //
 // Deprecated: Use NewIndexWithConfig for new code. This constructor is
 // maintained for backward compatibility with existing SQLite-based workflows.
 func NewIndex(dbPath string) (*Index, error) {
+	return NewIndexForRepo(dbPath, repoRootForDBPath(dbPath))
+}
+
+// NewIndexForRepo is NewIndex with an explicit repo root, which queries and
+// inserts are scoped to until Update or FullReindex is given another.
+//
+// Deprecated: Use NewIndexWithConfig for new code.
+func NewIndexForRepo(dbPath, repoRoot string) (*Index, error) {
 	sqlDB, err := OpenDB(dbPath)
 	if err != nil {
 		return nil, err
 	}
 
-	// Default to current working directory for repo root
-	cwd, _ := os.Getwd()
+	repoRoot = config.NormalizeRepoRoot(repoRoot)
 
-	return &Index{
+	idx := &Index{
 		sqlDB:    sqlDB,
 		adapter:  db.WrapSQL(sqlDB),
 		dialect:  db.GetDialect(db.DatabaseSQLite),
 		dbPath:   dbPath,
-		root:     cwd,
+		root:     config.RepoID(repoRoot),
+		repoPath: repoRoot,
 		indexCfg: config.LoadIndexConfigFromEnv(),
-	}, nil
+	}
+	idx.adoptRepoKey()
+	return idx, nil
+}
+
+// repoRootForDBPath returns the repo an in-tree database belongs to, the
+// parent of its .codetect directory, or the working directory when dbPath
+// isn't in one.
+func repoRootForDBPath(dbPath string) string {
+	if abs, err := filepath.Abs(dbPath); err == nil {
+		if dir := filepath.Dir(abs); filepath.Base(dir) == config.DataDirName {
+			return filepath.Dir(dir)
+		}
+	}
+	cwd, _ := os.Getwd()
+	return cwd
 }
 
 // NewIndexWithConfig creates a symbol index using the provided configuration.
@@ -63,6 +99,7 @@ func NewIndexWithConfig(cfg db.Config, repoRoot string) (*Index, error) {
 	}
 
 	dialect := cfg.Dialect()
+	repoRoot = config.NormalizeRepoRoot(repoRoot)
 
 	// Initialize schema using dialect-aware DDL
 	if err := initSchemaWithAdapter(database, dialect); err != nil {
@@ -70,13 +107,28 @@ func NewIndexWithConfig(cfg db.Config, repoRoot string) (*Index, error) {
 		return nil, fmt.Errorf("initializing schema: %w", err)
 	}
 
-	return &Index{
+	idx := &Index{
 		adapter:  database,
 		dialect:  dialect,
 		dbPath:   cfg.Path,
-		root:     repoRoot,
+		root:     config.RepoID(repoRoot),
+		repoPath: repoRoot,
 		indexCfg: config.LoadIndexConfigFromEnv(),
-	}, nil
+	}
+	idx.adoptRepoKey()
+	return idx, nil
+}
+
+// adoptRepoKey moves rows indexed under the repo's path to its key when
+// the key is no longer the path (see db.AdoptRepoKey). Failing only warns,
+// since the index still works under the new key.
+func (idx *Index) adoptRepoKey() {
+	moved, err := db.AdoptRepoKey(context.Background(), idx.adapter, idx.dialect, idx.repoPath, idx.root)
+	if err != nil {
+		slog.Warn("could not move index rows to the repo's key", "from", idx.repoPath, "to", idx.root, "error", err)
+	} else if moved {
+		slog.Info("moved index rows to the repo's key", "from", idx.repoPath, "to", idx.root)
+	}
 }
 
 // Close closes the index database
@@ -105,53 +157,58 @@ func (idx *Index) Dialect() db.Dialect {
 	return idx.dialect
 }
 
+// SymbolFilter narrows FindSymbol results. Empty fields don't filter.
+type SymbolFilter struct {
+	Kind  string // Matched as its canonical kind, so "func" finds functions
+	Scope string // Enclosing class, struct, etc., with or without its "struct:" kind prefix
+	Path  string // Repo-relative path glob, see globToLike
+}
+
 // FindSymbol searches for symbols by name (supports LIKE patterns) within this repo
-func (idx *Index) FindSymbol(name string, kind string, limit int) ([]Symbol, error) {
+func (idx *Index) FindSymbol(name string, filter SymbolFilter, limit int) ([]Symbol, error) {
 	if limit <= 0 {
 		limit = 50
 	}
-
-	var query string
-	var args []any
+	kind := filter.Kind
+	if kind != "" {
+		kind = CanonicalKind(kind)
+	}
 
 	// Use LIKE for partial matching
 	pattern := "%" + name + "%"
 
 	// Build query with dialect-aware placeholders, filtering by repo_root
+	args := []any{idx.root, pattern}
+	where := fmt.Sprintf("repo_root = %s AND name LIKE %s", idx.dialect.Placeholder(1), idx.dialect.Placeholder(2))
 	if kind != "" {
-		query = fmt.Sprintf(`SELECT name, kind, path, line, language, pattern, scope
-				 FROM symbols
-				 WHERE repo_root = %s AND name LIKE %s AND kind = %s
-				 ORDER BY
-					CASE WHEN name = %s THEN 0
-						 WHEN name LIKE %s THEN 1
-						 ELSE 2 END,
-					name
-				 LIMIT %s`,
-			idx.dialect.Placeholder(1),
-			idx.dialect.Placeholder(2),
-			idx.dialect.Placeholder(3),
-			idx.dialect.Placeholder(4),
-			idx.dialect.Placeholder(5),
-			idx.dialect.Placeholder(6))
-		args = []any{idx.root, pattern, kind, name, name + "%", limit}
-	} else {
-		query = fmt.Sprintf(`SELECT name, kind, path, line, language, pattern, scope
-				 FROM symbols
-				 WHERE repo_root = %s AND name LIKE %s
-				 ORDER BY
-					CASE WHEN name = %s THEN 0
-						 WHEN name LIKE %s THEN 1
-						 ELSE 2 END,
-					name
-				 LIMIT %s`,
-			idx.dialect.Placeholder(1),
-			idx.dialect.Placeholder(2),
-			idx.dialect.Placeholder(3),
-			idx.dialect.Placeholder(4),
-			idx.dialect.Placeholder(5))
-		args = []any{idx.root, pattern, name, name + "%", limit}
+		args = append(args, kind)
+		where += fmt.Sprintf(" AND kind = %s", idx.dialect.Placeholder(len(args)))
+	}
+	if filter.Scope != "" {
+		// ctags stores the scope's kind in front of its name, e.g. "class:UserService"
+		args = append(args, filter.Scope, "%:"+escapeLike(filter.Scope))
+		where += fmt.Sprintf(` AND (scope = %s OR scope LIKE %s ESCAPE '\')`,
+			idx.dialect.Placeholder(len(args)-1), idx.dialect.Placeholder(len(args)))
+	}
+	if filter.Path != "" {
+		args = append(args, globToLike(filter.Path))
+		where += fmt.Sprintf(` AND path LIKE %s ESCAPE '\'`, idx.dialect.Placeholder(len(args)))
 	}
+	args = append(args, name, name+"%", limit)
+	n := len(args)
+	query := fmt.Sprintf(`SELECT name, kind, path, line, language, pattern, scope
+			 FROM symbols
+			 WHERE %s
+			 ORDER BY
+				CASE WHEN name = %s THEN 0
+					 WHEN name LIKE %s THEN 1
+					 ELSE 2 END,
+				name
+			 LIMIT %s`,
+		where,
+		idx.dialect.Placeholder(n-2),
+		idx.dialect.Placeholder(n-1),
+		idx.dialect.Placeholder(n))
 
 	rows, err := idx.adapter.Query(query, args...)
 	if err != nil {
@@ -166,6 +223,7 @@ func (idx *Index) FindSymbol(name string, kind string, limit int) ([]Symbol, err
 		if err := rows.Scan(&s.Name, &s.Kind, &s.Path, &s.Line, &language, &patternStr, &scope); err != nil {
 			return nil, fmt.Errorf("scanning symbol: %w", err)
 		}
+		s.Path = config.RepoRelPath(idx.repoPath, s.Path)
 		s.Language = language.String
 		s.Pattern = patternStr.String
 		s.Scope = scope.String
@@ -175,6 +233,77 @@ func (idx *Index) FindSymbol(name string, kind string, limit int) ([]Symbol, err
 	return symbols, rows.Err()
 }
 
+// globToLike translates a path glob to a LIKE pattern escaped with '\'.
+// * and ** both match any run of characters, slashes included, and ? one
+// character. A glob ending in "/" matches everything under that directory.
+func globToLike(glob string) string {
+	glob = strings.TrimPrefix(filepath.ToSlash(glob), "./")
+	var b strings.Builder
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteByte('%')
+		case '?':
+			b.WriteByte('_')
+		default:
+			b.WriteString(escapeLike(string(r)))
+		}
+	}
+	if strings.HasSuffix(glob, "/") {
+		b.WriteByte('%')
+	}
+	return b.String()
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike escapes s to match literally in a LIKE pattern with ESCAPE '\'
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// ListByKind returns a page of this repo's symbols of one kind, ordered by
+// path then name, skipping offset. hasMore is true if more follow the page.
+func (idx *Index) ListByKind(kind string, offset, limit int) (syms []Symbol, hasMore bool, err error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	query := fmt.Sprintf(`SELECT name, kind, path, line, language, pattern, scope
+			  FROM symbols
+			  WHERE repo_root = %s AND kind = %s
+			  ORDER BY path, name, line
+			  LIMIT %s OFFSET %s`,
+		idx.dialect.Placeholder(1), idx.dialect.Placeholder(2),
+		idx.dialect.Placeholder(3), idx.dialect.Placeholder(4))
+
+	// One extra row says whether another page follows
+	rows, err := idx.adapter.Query(query, idx.root, CanonicalKind(kind), limit+1, max(offset, 0))
+	if err != nil {
+		return nil, false, fmt.Errorf("querying symbols: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s Symbol
+		var language, patternStr, scope sql.NullString
+		if err := rows.Scan(&s.Name, &s.Kind, &s.Path, &s.Line, &language, &patternStr, &scope); err != nil {
+			return nil, false, fmt.Errorf("scanning symbol: %w", err)
+		}
+		s.Path = config.RepoRelPath(idx.repoPath, s.Path)
+		s.Language = language.String
+		s.Pattern = patternStr.String
+		s.Scope = scope.String
+		syms = append(syms, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	if len(syms) > limit {
+		return syms[:limit], true, nil
+	}
+	return syms, false, nil
+}
+
 // ListDefsInFile returns all symbol definitions in a file within this repo
 func (idx *Index) ListDefsInFile(path string) ([]Symbol, error) {
 	query := fmt.Sprintf(`SELECT name, kind, path, line, language, pattern, scope
@@ -182,7 +311,7 @@ func (idx *Index) ListDefsInFile(path string) ([]Symbol, error) {
 			  WHERE repo_root = %s AND path = %s
 			  ORDER BY line`, idx.dialect.Placeholder(1), idx.dialect.Placeholder(2))
 
-	rows, err := idx.adapter.Query(query, idx.root, path)
+	rows, err := idx.adapter.Query(query, idx.root, config.RepoRelPath(idx.repoPath, path))
 	if err != nil {
 		return nil, fmt.Errorf("querying symbols: %w", err)
 	}
@@ -195,6 +324,7 @@ func (idx *Index) ListDefsInFile(path string) ([]Symbol, error) {
 		if err := rows.Scan(&s.Name, &s.Kind, &s.Path, &s.Line, &language, &patternStr, &scope); err != nil {
 			return nil, fmt.Errorf("scanning symbol: %w", err)
 		}
+		s.Path = config.RepoRelPath(idx.repoPath, s.Path)
 		s.Language = language.String
 		s.Pattern = patternStr.String
 		s.Scope = scope.String
@@ -206,15 +336,28 @@ func (idx *Index) ListDefsInFile(path string) ([]Symbol, error) {
 
 // Update re-indexes files that have changed since last index
 func (idx *Index) Update(root string) error {
-	idx.root = root
+	root = config.NormalizeRepoRoot(root)
+	idx.root = config.RepoID(root)
+	idx.repoPath = root
 
 	// Get list of files that need reindexing
-	filesToIndex, err := idx.getFilesToIndex(root)
+	var filesToIndex map[string]fileInfo
+	var deletedFiles []string
+	var tree *merkle.Tree
+	var err error
+	if idx.indexCfg.ContentHash {
+		filesToIndex, deletedFiles, tree, err = idx.getChangedFilesByHash(root)
+	} else {
+		filesToIndex, err = idx.getFilesToIndex(root)
+	}
 	if err != nil {
 		return fmt.Errorf("scanning files: %w", err)
 	}
 
-	if len(filesToIndex) == 0 {
+	if len(filesToIndex) == 0 && len(deletedFiles) == 0 {
+		if tree != nil {
+			return idx.saveTree(tree) // Only non-code files changed
+		}
 		return nil // Nothing to do
 	}
 
@@ -277,7 +420,7 @@ func (idx *Index) Update(root string) error {
 			}
 		}
 
-		entries, err := RunCtags(root, absUnsupportedFiles)
+		entries, err := RunCtags(root, absUnsupportedFiles, idx.indexCfg.Ctags)
 		if err != nil {
 			// Only error if both indexers failed and we have no symbols
 			if len(allSymbols) == 0 {
@@ -290,6 +433,14 @@ func (idx *Index) Update(root string) error {
 		}
 	}
 
+	// Backends report paths differently; store them all repo-relative
+	for i := range allSymbols {
+		allSymbols[i].Path = config.RepoRelPath(idx.repoPath, allSymbols[i].Path)
+	}
+
+	// Import edges come from the same per-file pass
+	edges := idx.importEdges(filesToIndex)
+
 	// Begin transaction for bulk insert
 	tx, err := idx.adapter.Begin()
 	if err != nil {
@@ -306,11 +457,27 @@ func (idx *Index) Update(root string) error {
 		}
 	}
 
+	// Drop files that no longer exist (only known with content hashing)
+	deleteFileQuery := fmt.Sprintf("DELETE FROM files WHERE repo_root = %s AND path = %s",
+		idx.dialect.Placeholder(1), idx.dialect.Placeholder(2))
+	for _, path := range deletedFiles {
+		if _, err := tx.Exec(deleteQuery, idx.root, path); err != nil {
+			return fmt.Errorf("clearing symbols for %s: %w", path, err)
+		}
+		if _, err := tx.Exec(deleteFileQuery, idx.root, path); err != nil {
+			return fmt.Errorf("clearing file record for %s: %w", path, err)
+		}
+	}
+
 	// Batch insert symbols (500 at a time for performance)
 	if err := idx.batchInsertSymbols(tx, allSymbols, 500); err != nil {
 		return fmt.Errorf("inserting symbols: %w", err)
 	}
 
+	if err := idx.replaceImports(tx, filesToIndex, deletedFiles, edges); err != nil {
+		return err
+	}
+
 	// Build dialect-aware upsert statement for files with repo_root
 	fileUpsertSQL := idx.dialect.UpsertSQL(
 		"files",
@@ -336,6 +503,152 @@ func (idx *Index) Update(root string) error {
 		return fmt.Errorf("committing transaction: %w", err)
 	}
 
+	return idx.saveTree(tree)
+}
+
+// importEdges extracts the import edges of the Go, Python and JS/TS files
+// among files. Files that can't be read are skipped.
+func (idx *Index) importEdges(files map[string]fileInfo) []ImportEdge {
+	resolver := newImportResolver(idx.repoPath)
+	var edges []ImportEdge
+	for path := range files {
+		if importLanguage(path) == "" {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(idx.repoPath, path))
+		if err != nil {
+			continue
+		}
+		edges = append(edges, resolver.edges(path, content)...)
+	}
+	return edges
+}
+
+// replaceImports swaps the import edges of reindexed and deleted files for
+// edges
+func (idx *Index) replaceImports(tx db.Tx, files map[string]fileInfo, deletedFiles []string, edges []ImportEdge) error {
+	deleteQuery := fmt.Sprintf("DELETE FROM imports WHERE repo_root = %s AND path = %s",
+		idx.dialect.Placeholder(1), idx.dialect.Placeholder(2))
+	for path := range files {
+		if _, err := tx.Exec(deleteQuery, idx.root, path); err != nil {
+			return fmt.Errorf("clearing imports for %s: %w", path, err)
+		}
+	}
+	for _, path := range deletedFiles {
+		if _, err := tx.Exec(deleteQuery, idx.root, path); err != nil {
+			return fmt.Errorf("clearing imports for %s: %w", path, err)
+		}
+	}
+	if len(edges) == 0 {
+		return nil
+	}
+
+	stmt, err := tx.Prepare(fmt.Sprintf("INSERT INTO imports (repo_root, path, spec, target) VALUES (%s, %s, %s, %s)",
+		idx.dialect.Placeholder(1), idx.dialect.Placeholder(2), idx.dialect.Placeholder(3), idx.dialect.Placeholder(4)))
+	if err != nil {
+		return fmt.Errorf("preparing import insert: %w", err)
+	}
+	defer stmt.Close()
+	for _, e := range edges {
+		if _, err := stmt.Exec(idx.root, e.Path, e.Spec, nullString(e.Target)); err != nil {
+			return fmt.Errorf("inserting import of %s: %w", e.Path, err)
+		}
+	}
+	return nil
+}
+
+// Imports returns the import edges of path within this repo, in the order
+// they were stored, with Go package imports expanded to the package's
+// current files. Edges with an empty Target import external packages.
+func (idx *Index) Imports(path string) ([]ImportEdge, error) {
+	query := fmt.Sprintf(`SELECT path, spec, target FROM imports
+		WHERE repo_root = %s AND path = %s`, idx.dialect.Placeholder(1), idx.dialect.Placeholder(2))
+	stored, err := idx.queryImports(query, idx.root, config.RepoRelPath(idx.repoPath, path))
+	if err != nil {
+		return nil, err
+	}
+
+	edges := []ImportEdge{}
+	for _, e := range stored {
+		dir, ok := goPackageDir(e.Target)
+		if !ok {
+			edges = append(edges, e)
+			continue
+		}
+		files := goPackageFiles(idx.repoPath, dir)
+		if len(files) == 0 {
+			// The package has since gone from the repo
+			edges = append(edges, ImportEdge{Path: e.Path, Spec: e.Spec})
+		}
+		for _, file := range files {
+			edges = append(edges, ImportEdge{Path: e.Path, Spec: e.Spec, Target: file})
+		}
+	}
+	return edges, nil
+}
+
+// ImportedBy returns the import edges whose target is path: the files that
+// import it, including importers of its Go package.
+func (idx *Index) ImportedBy(path string) ([]ImportEdge, error) {
+	relPath := config.RepoRelPath(idx.repoPath, path)
+	pkgTarget := relPath // Never a stored package target, matching nothing
+	if importLanguage(relPath) == "go" && !strings.HasSuffix(relPath, "_test.go") {
+		if info, err := os.Stat(filepath.Join(idx.repoPath, filepath.FromSlash(relPath))); err == nil && info.Mode().IsRegular() {
+			pkgTarget = goFileTarget(relPath)
+		}
+	}
+
+	query := fmt.Sprintf(`SELECT path, spec, target FROM imports
+		WHERE repo_root = %s AND target IN (%s, %s)
+		ORDER BY path`, idx.dialect.Placeholder(1), idx.dialect.Placeholder(2), idx.dialect.Placeholder(3))
+	edges, err := idx.queryImports(query, idx.root, relPath, pkgTarget)
+	if err != nil {
+		return nil, err
+	}
+	for i := range edges {
+		edges[i].Target = relPath
+	}
+	return edges, nil
+}
+
+func (idx *Index) queryImports(query string, args ...any) ([]ImportEdge, error) {
+	rows, err := idx.adapter.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying imports: %w", err)
+	}
+	defer rows.Close()
+
+	edges := []ImportEdge{}
+	for rows.Next() {
+		var e ImportEdge
+		var target sql.NullString
+		if err := rows.Scan(&e.Path, &e.Spec, &target); err != nil {
+			return nil, fmt.Errorf("scanning import: %w", err)
+		}
+		e.Target = target.String
+		edges = append(edges, e)
+	}
+	return edges, rows.Err()
+}
+
+// treeStore returns the store for the symbol index's Merkle tree.
+func (idx *Index) treeStore() *merkle.Store {
+	return merkle.NewNamedStore(config.DataDir(idx.repoPath), TreeFileName)
+}
+
+// saveTree records tree as the baseline for the next content-hash update.
+// A nil tree means the update used mtimes, which leaves any stored tree
+// stale, so it is removed to force a full diff if hashing is re-enabled.
+func (idx *Index) saveTree(tree *merkle.Tree) error {
+	if tree == nil {
+		if err := idx.treeStore().Delete(); err != nil {
+			return fmt.Errorf("removing stale merkle tree: %w", err)
+		}
+		return nil
+	}
+	if err := idx.treeStore().Save(tree); err != nil {
+		return fmt.Errorf("saving merkle tree: %w", err)
+	}
 	return nil
 }
 
@@ -367,7 +680,7 @@ func (idx *Index) batchInsertSymbols(tx db.Tx, symbols []Symbol, batchSize int)
 
 		for _, sym := range symbols[i:end] {
 			_, err := stmt.Exec(
-				idx.root, sym.Name, sym.Kind, sym.Path, sym.Line,
+				idx.root, sym.Name, CanonicalKind(sym.Kind), sym.Path, sym.Line,
 				nullString(sym.Language), nullString(sym.Pattern),
 				nullString(sym.Scope), nullString(""), // signature empty for now
 			)
@@ -384,7 +697,14 @@ func (idx *Index) batchInsertSymbols(tx db.Tx, symbols []Symbol, batchSize int)
 // FullReindex clears all data for this repo and reindexes from scratch
 func (idx *Index) FullReindex(root string) error {
 	// Set root for scoped operations
-	idx.root = root
+	root = config.NormalizeRepoRoot(root)
+	idx.root = config.RepoID(root)
+	idx.repoPath = root
+
+	// A stored tree would make the update below see no changes
+	if err := idx.treeStore().Delete(); err != nil {
+		return fmt.Errorf("clearing merkle tree: %w", err)
+	}
 
 	// Clear all existing data for this repo using the adapter
 	deleteSymbolsQuery := fmt.Sprintf("DELETE FROM symbols WHERE repo_root = %s", idx.dialect.Placeholder(1))
@@ -395,6 +715,10 @@ func (idx *Index) FullReindex(root string) error {
 	if _, err := idx.adapter.Exec(deleteFilesQuery, idx.root); err != nil {
 		return fmt.Errorf("clearing files: %w", err)
 	}
+	deleteImportsQuery := fmt.Sprintf("DELETE FROM imports WHERE repo_root = %s", idx.dialect.Placeholder(1))
+	if _, err := idx.adapter.Exec(deleteImportsQuery, idx.root); err != nil {
+		return fmt.Errorf("clearing imports: %w", err)
+	}
 
 	return idx.Update(root)
 }
@@ -426,6 +750,8 @@ func (idx *Index) getFilesToIndex(root string) (map[string]fileInfo, error) {
 
 	// Walk directory and find files needing indexing
 	needsIndex := make(map[string]fileInfo)
+	ignoredDirs := config.IgnoredDirs()
+	guard := config.NewWalkGuard(root, idx.indexCfg.OneFileSystem)
 
 	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -435,7 +761,13 @@ func (idx *Index) getFilesToIndex(root string) (map[string]fileInfo, error) {
 		// Skip hidden directories and common non-code directories
 		if d.IsDir() {
 			name := d.Name()
-			if strings.HasPrefix(name, ".") || isIgnoredDir(name) {
+			if strings.HasPrefix(name, ".") || ignoredDirs[name] {
+				return filepath.SkipDir
+			}
+			if rel, err := filepath.Rel(root, path); err == nil && idx.indexCfg.BeyondMaxDepth(rel) {
+				return filepath.SkipDir
+			}
+			if info, err := d.Info(); err == nil && guard.SkipDir(info) {
 				return filepath.SkipDir
 			}
 			return nil
@@ -473,20 +805,51 @@ func (idx *Index) getFilesToIndex(root string) (map[string]fileInfo, error) {
 	return needsIndex, err
 }
 
-// isIgnoredDir returns true for directories that should not be indexed
-func isIgnoredDir(name string) bool {
-	ignored := map[string]bool{
-		"node_modules": true,
-		"vendor":       true,
-		"dist":         true,
-		"build":        true,
-		"target":       true,
-		"__pycache__":  true,
-		".git":         true,
-		".svn":         true,
-		".hg":          true,
+// getChangedFilesByHash diffs a content-hash Merkle tree of root against the
+// tree stored by the previous update. Unlike getFilesToIndex it is not fooled
+// by uniform or reset mtimes (CI checkouts, copies across machines) and also
+// reports deleted files. The new tree is returned for saving once the update
+// commits.
+func (idx *Index) getChangedFilesByHash(root string) (map[string]fileInfo, []string, *merkle.Tree, error) {
+	builder := merkle.NewBuilder().WithMaxDepth(idx.indexCfg.MaxDepth).WithOneFileSystem(idx.indexCfg.OneFileSystem)
+	for name := range config.IgnoredDirs() {
+		builder.IgnorePatterns = append(builder.IgnorePatterns, name)
+	}
+	// The v2 indexer skips .gitignore'd paths too, so both see the same files
+	builder.IgnorePatterns = append(builder.IgnorePatterns, merkle.LoadGitignore(root)...)
+
+	tree, err := builder.Build(root)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("building merkle tree: %w", err)
 	}
-	return ignored[name]
+
+	// An unreadable tree is treated like a first run
+	oldTree, _ := idx.treeStore().Load()
+	changes := merkle.Diff(oldTree, tree)
+
+	needsIndex := make(map[string]fileInfo)
+	for _, path := range append(changes.Added, changes.Modified...) {
+		if !isCodeFile(path) {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(root, path))
+		if err != nil {
+			continue
+		}
+		needsIndex[path] = fileInfo{
+			mtime: info.ModTime().Unix(),
+			size:  info.Size(),
+		}
+	}
+
+	var deleted []string
+	for _, path := range changes.Deleted {
+		if isCodeFile(path) {
+			deleted = append(deleted, path)
+		}
+	}
+
+	return needsIndex, deleted, tree, nil
 }
 
 // isCodeFile returns true for files that should be indexed
@@ -542,3 +905,161 @@ func (idx *Index) Stats() (symbolCount int, fileCount int, err error) {
 	}
 	return symbolCount, fileCount, nil
 }
+
+// FileRecord describes when a file was last indexed
+type FileRecord struct {
+	Path      string    `json:"path"`
+	Mtime     time.Time `json:"mtime"`
+	Size      int64     `json:"size"`
+	IndexedAt time.Time `json:"indexed_at"`
+}
+
+// RecentlyIndexed returns files in this repo indexed at or after since,
+// most recent first. Useful for checking whether an edit was picked up.
+func (idx *Index) RecentlyIndexed(since time.Time) ([]FileRecord, error) {
+	query := fmt.Sprintf(`SELECT path, mtime, size, indexed_at
+			  FROM files
+			  WHERE repo_root = %s AND indexed_at >= %s
+			  ORDER BY indexed_at DESC, path`, idx.dialect.Placeholder(1), idx.dialect.Placeholder(2))
+
+	rows, err := idx.adapter.Query(query, idx.root, since.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("querying files: %w", err)
+	}
+	defer rows.Close()
+
+	return scanFileRecords(rows)
+}
+
+// Files returns every file tracked for this repo, ordered by path.
+func (idx *Index) Files() ([]FileRecord, error) {
+	query := fmt.Sprintf(`SELECT path, mtime, size, indexed_at
+			  FROM files
+			  WHERE repo_root = %s
+			  ORDER BY path`, idx.dialect.Placeholder(1))
+
+	rows, err := idx.adapter.Query(query, idx.root)
+	if err != nil {
+		return nil, fmt.Errorf("querying files: %w", err)
+	}
+	defer rows.Close()
+
+	return scanFileRecords(rows)
+}
+
+// ListFiles returns the distinct paths indexed for this repo, sorted.
+func (idx *Index) ListFiles() ([]string, error) {
+	query := fmt.Sprintf(`SELECT DISTINCT path
+			  FROM files
+			  WHERE repo_root = %s
+			  ORDER BY path`, idx.dialect.Placeholder(1))
+
+	rows, err := idx.adapter.Query(query, idx.root)
+	if err != nil {
+		return nil, fmt.Errorf("querying files: %w", err)
+	}
+	defer rows.Close()
+
+	paths := []string{}
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("scanning file path: %w", err)
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, rows.Err()
+}
+
+// scanFileRecords reads (path, mtime, size, indexed_at) rows.
+func scanFileRecords(rows db.Rows) ([]FileRecord, error) {
+	var records []FileRecord
+	for rows.Next() {
+		var r FileRecord
+		var mtime, indexedAt int64
+		if err := rows.Scan(&r.Path, &mtime, &r.Size, &indexedAt); err != nil {
+			return nil, fmt.Errorf("scanning file record: %w", err)
+		}
+		r.Mtime = time.Unix(mtime, 0)
+		r.IndexedAt = time.Unix(indexedAt, 0)
+		records = append(records, r)
+	}
+
+	return records, rows.Err()
+}
+
+// AllSymbols returns every symbol in this repo, ordered by path and line.
+func (idx *Index) AllSymbols() ([]Symbol, error) {
+	query := fmt.Sprintf(`SELECT name, kind, path, line, language, pattern, scope, signature
+			  FROM symbols
+			  WHERE repo_root = %s
+			  ORDER BY path, line, name`, idx.dialect.Placeholder(1))
+
+	rows, err := idx.adapter.Query(query, idx.root)
+	if err != nil {
+		return nil, fmt.Errorf("querying symbols: %w", err)
+	}
+	defer rows.Close()
+
+	var symbols []Symbol
+	for rows.Next() {
+		var s Symbol
+		var language, patternStr, scope, signature sql.NullString
+		if err := rows.Scan(&s.Name, &s.Kind, &s.Path, &s.Line, &language, &patternStr, &scope, &signature); err != nil {
+			return nil, fmt.Errorf("scanning symbol: %w", err)
+		}
+		s.Language = language.String
+		s.Pattern = patternStr.String
+		s.Scope = scope.String
+		s.Signature = signature.String
+		symbols = append(symbols, s)
+	}
+
+	return symbols, rows.Err()
+}
+
+// ReplaceAll replaces this repo's symbols and file records with the given
+// ones in a single transaction. Used to load a prebuilt index.
+func (idx *Index) ReplaceAll(symbols []Symbol, files []FileRecord) error {
+	tx, err := idx.adapter.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"symbols", "files"} {
+		deleteQuery := fmt.Sprintf("DELETE FROM %s WHERE repo_root = %s", table, idx.dialect.Placeholder(1))
+		if _, err := tx.Exec(deleteQuery, idx.root); err != nil {
+			return fmt.Errorf("clearing %s: %w", table, err)
+		}
+	}
+
+	if err := idx.batchInsertSymbols(tx, symbols, 500); err != nil {
+		return fmt.Errorf("inserting symbols: %w", err)
+	}
+
+	fileUpsertSQL := idx.dialect.UpsertSQL(
+		"files",
+		[]string{"repo_root", "path", "mtime", "size", "indexed_at"},
+		[]string{"repo_root", "path"},
+		[]string{"mtime", "size", "indexed_at"},
+	)
+	fileStmt, err := tx.Prepare(fileUpsertSQL)
+	if err != nil {
+		return fmt.Errorf("preparing file insert: %w", err)
+	}
+	defer fileStmt.Close()
+
+	for _, f := range files {
+		if _, err := fileStmt.Exec(idx.root, f.Path, f.Mtime.Unix(), f.Size, f.IndexedAt.Unix()); err != nil {
+			return fmt.Errorf("inserting file record for %s: %w", f.Path, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return nil
+}
@@ -106,8 +106,8 @@ class Calculator:
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// FindSymbol takes (name, kind, limit) - pass empty kind to match any
-			results, err := idx.FindSymbol(tt.expectedName, "", 10)
+			// FindSymbol takes (name, SymbolFilter{Kind, Scope, Path}, limit) - an empty filter matches any symbol
+			results, err := idx.FindSymbol(tt.expectedName, SymbolFilter{}, 10)
 			if err != nil {
 				t.Fatalf("FindSymbol(%q): %v", tt.expectedName, err)
 			}
@@ -1,9 +1,15 @@
 package symbols
 
 import (
+	"database/sql"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
+	"time"
+
+	"codetect/internal/db"
+	"codetect/internal/merkle"
 )
 
 func TestOpenDB(t *testing.T) {
@@ -27,6 +33,61 @@ func TestOpenDB(t *testing.T) {
 	}
 }
 
+func TestOpenDBUpgradesOldSchema(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "old.db")
+
+	// Simulate a database created before the signature column existed
+	old, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("opening old database: %v", err)
+	}
+	_, err = old.Exec(`
+CREATE TABLE schema_version (version INTEGER NOT NULL);
+INSERT INTO schema_version (version) VALUES (1);
+CREATE TABLE symbols (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    repo_root TEXT NOT NULL,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    path TEXT NOT NULL,
+    line INTEGER NOT NULL,
+    language TEXT,
+    pattern TEXT,
+    scope TEXT,
+    UNIQUE(repo_root, name, path, line)
+);
+INSERT INTO symbols (repo_root, name, kind, path, line) VALUES ('/repo', 'Foo', 'function', 'a.go', 1);
+INSERT INTO symbols (repo_root, name, kind, path, line) VALUES ('/repo', 'a', 'package', 'a.go', 0);`)
+	old.Close()
+	if err != nil {
+		t.Fatalf("creating old schema: %v", err)
+	}
+
+	db, err := OpenDB(dbPath)
+	if err != nil {
+		t.Fatalf("OpenDB() error = %v", err)
+	}
+	defer db.Close()
+
+	var name string
+	var signature sql.NullString
+	if err := db.QueryRow("SELECT name, signature FROM symbols WHERE name = 'Foo'").Scan(&name, &signature); err != nil {
+		t.Fatalf("querying upgraded symbols: %v", err)
+	}
+	if name != "Foo" || signature.Valid {
+		t.Errorf("got (%q, %v), want existing row with NULL signature", name, signature)
+	}
+
+	var kind string
+	if err := db.QueryRow("SELECT kind FROM symbols WHERE name = 'a'").Scan(&kind); err != nil {
+		t.Fatalf("querying upgraded kind: %v", err)
+	}
+	if kind != KindModule {
+		t.Errorf("package symbol kind = %q after upgrade, want %q", kind, KindModule)
+	}
+}
+
 func TestNewIndex(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "symbols.db")
@@ -50,6 +111,186 @@ func TestNewIndex(t *testing.T) {
 	}
 }
 
+func TestNewIndexRepoRootFromDBPath(t *testing.T) {
+	t.Setenv("CODETECT_REPO_IDENTITY", "path")
+	repo := t.TempDir()
+	dataDir := filepath.Join(repo, ".codetect")
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Run from somewhere else, as 'codetect-index index /some/repo' does
+	t.Chdir(t.TempDir())
+
+	idx, err := NewIndex(filepath.Join(dataDir, "symbols.db"))
+	if err != nil {
+		t.Fatalf("NewIndex() error = %v", err)
+	}
+	defer idx.Close()
+	if idx.repoPath != repo {
+		t.Errorf("repo root = %q, want the database's repo %q", idx.repoPath, repo)
+	}
+
+	// Rows inserted before Update are found by queries after it
+	if _, err := idx.adapter.Exec(`INSERT INTO symbols (repo_root, name, kind, path, line) VALUES (?, 'Foo', 'function', 'a.go', 1)`, idx.root); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Update(repo); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := idx.adapter.Exec(`INSERT INTO symbols (repo_root, name, kind, path, line) VALUES (?, 'Bar', 'function', 'a.go', 2)`, idx.root); err != nil {
+		t.Fatal(err)
+	}
+	syms, err := idx.FindSymbol("", SymbolFilter{Path: "a.go"}, 10)
+	if err != nil || len(syms) != 2 {
+		t.Errorf("FindSymbol() = %v, %v, want both rows under one repo_root", syms, err)
+	}
+
+	other := t.TempDir()
+	explicit, err := NewIndexForRepo(filepath.Join(t.TempDir(), "symbols.db"), other)
+	if err != nil {
+		t.Fatalf("NewIndexForRepo() error = %v", err)
+	}
+	defer explicit.Close()
+	if explicit.repoPath != other {
+		t.Errorf("NewIndexForRepo() repo root = %q, want %q", explicit.repoPath, other)
+	}
+}
+
+func TestGetChangedFilesByHash(t *testing.T) {
+	repoDir := t.TempDir()
+	idx, err := NewIndex(filepath.Join(t.TempDir(), "symbols.db"))
+	if err != nil {
+		t.Fatalf("NewIndex() error = %v", err)
+	}
+	defer idx.Close()
+	idx.repoPath = repoDir
+
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+	}
+	write("a.go", "package a\n")
+	write("b.go", "package b\n")
+	write("notes.txt", "not code\n")
+	write("gen.go", "package gen\n")
+	write(".gitignore", "gen.go\n")
+
+	changed, deleted, tree, err := idx.getChangedFilesByHash(repoDir)
+	if err != nil {
+		t.Fatalf("getChangedFilesByHash() error = %v", err)
+	}
+	if len(changed) != 2 || len(deleted) != 0 {
+		t.Errorf("first run: changed=%v deleted=%v, want a.go and b.go", changed, deleted)
+	}
+	if err := idx.saveTree(tree); err != nil {
+		t.Fatalf("saveTree() error = %v", err)
+	}
+
+	// Same size and mtime, different content: invisible to the mtime check
+	info, _ := os.Stat(filepath.Join(repoDir, "a.go"))
+	write("a.go", "package z\n")
+	os.Chtimes(filepath.Join(repoDir, "a.go"), info.ModTime(), info.ModTime())
+	os.Remove(filepath.Join(repoDir, "b.go"))
+
+	changed, deleted, _, err = idx.getChangedFilesByHash(repoDir)
+	if err != nil {
+		t.Fatalf("getChangedFilesByHash() error = %v", err)
+	}
+	if _, ok := changed["a.go"]; !ok || len(changed) != 1 {
+		t.Errorf("changed = %v, want only a.go", changed)
+	}
+	if len(deleted) != 1 || deleted[0] != "b.go" {
+		t.Errorf("deleted = %v, want [b.go]", deleted)
+	}
+
+	// The v2 indexer's tree is left alone
+	if _, err := os.Stat(filepath.Join(repoDir, ".codetect", merkle.TreeFileName)); !os.IsNotExist(err) {
+		t.Errorf("symbol index wrote the v2 merkle tree")
+	}
+}
+
+func TestRecentlyIndexed(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	idx, err := NewIndexWithConfig(db.DefaultConfig(filepath.Join(tmpDir, "symbols.db")), "/test/repo")
+	if err != nil {
+		t.Fatalf("NewIndexWithConfig() error = %v", err)
+	}
+	defer idx.Close()
+
+	now := time.Now()
+	rows := []struct {
+		root      string
+		path      string
+		indexedAt time.Time
+	}{
+		{"/test/repo", "old.go", now.Add(-2 * time.Hour)},
+		{"/test/repo", "a.go", now.Add(-10 * time.Minute)},
+		{"/test/repo", "b.go", now.Add(-time.Minute)},
+		{"/other/repo", "c.go", now},
+	}
+	for _, r := range rows {
+		_, err := idx.adapter.Exec(`INSERT INTO files (repo_root, path, mtime, size, indexed_at) VALUES (?, ?, ?, ?, ?)`,
+			r.root, r.path, now.Unix(), 42, r.indexedAt.Unix())
+		if err != nil {
+			t.Fatalf("Insert error = %v", err)
+		}
+	}
+
+	records, err := idx.RecentlyIndexed(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("RecentlyIndexed() error = %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("RecentlyIndexed() returned %d records, want 2: %+v", len(records), records)
+	}
+	if records[0].Path != "b.go" || records[1].Path != "a.go" {
+		t.Errorf("RecentlyIndexed() order = [%s %s], want [b.go a.go]", records[0].Path, records[1].Path)
+	}
+	if records[0].Size != 42 || records[0].IndexedAt.Unix() != now.Add(-time.Minute).Unix() {
+		t.Errorf("RecentlyIndexed() record = %+v, unexpected fields", records[0])
+	}
+}
+
+func TestListFiles(t *testing.T) {
+	idx, err := NewIndexWithConfig(db.DefaultConfig(filepath.Join(t.TempDir(), "symbols.db")), "/test/repo")
+	if err != nil {
+		t.Fatalf("NewIndexWithConfig() error = %v", err)
+	}
+	defer idx.Close()
+
+	files, err := idx.ListFiles()
+	if err != nil {
+		t.Fatalf("ListFiles() error = %v", err)
+	}
+	if files == nil || len(files) != 0 {
+		t.Errorf("ListFiles() on an empty index = %#v, want an empty list", files)
+	}
+
+	for _, r := range []struct{ root, path string }{
+		{"/test/repo", "b.go"},
+		{"/test/repo", "a/c.go"},
+		{"/test/repo", "a.go"},
+		{"/other/repo", "d.go"},
+	} {
+		_, err := idx.adapter.Exec(`INSERT INTO files (repo_root, path, mtime, size, indexed_at) VALUES (?, ?, ?, ?, ?)`,
+			r.root, r.path, 0, 1, 0)
+		if err != nil {
+			t.Fatalf("Insert error = %v", err)
+		}
+	}
+
+	files, err = idx.ListFiles()
+	if err != nil {
+		t.Fatalf("ListFiles() error = %v", err)
+	}
+	want := []string{"a.go", "a/c.go", "b.go"}
+	if !slices.Equal(files, want) {
+		t.Errorf("ListFiles() = %v, want %v", files, want)
+	}
+}
+
 func TestFindSymbolEmpty(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "symbols.db")
@@ -60,7 +301,7 @@ func TestFindSymbolEmpty(t *testing.T) {
 	}
 	defer idx.Close()
 
-	symbols, err := idx.FindSymbol("test", "", 10)
+	symbols, err := idx.FindSymbol("test", SymbolFilter{}, 10)
 	if err != nil {
 		t.Fatalf("FindSymbol() error = %v", err)
 	}
@@ -69,6 +310,140 @@ func TestFindSymbolEmpty(t *testing.T) {
 	}
 }
 
+func TestFindSymbolPathGlob(t *testing.T) {
+	idx, err := NewIndexWithConfig(db.DefaultConfig(filepath.Join(t.TempDir(), "symbols.db")), "/test/repo")
+	if err != nil {
+		t.Fatalf("NewIndexWithConfig() error = %v", err)
+	}
+	defer idx.Close()
+
+	for _, path := range []string{"internal/http/server.go", "internal/http/server_test.go", "internal/grpc/server.go", "internal/http_util/a.go"} {
+		if _, err := idx.adapter.Exec(`INSERT INTO symbols (repo_root, name, kind, path, line) VALUES (?, ?, ?, ?, ?)`,
+			idx.root, "handler", "function", path, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		glob string
+		want []string
+	}{
+		{"", []string{"internal/grpc/server.go", "internal/http/server.go", "internal/http/server_test.go", "internal/http_util/a.go"}},
+		{"internal/http/", []string{"internal/http/server.go", "internal/http/server_test.go"}},
+		{"./internal/http/*", []string{"internal/http/server.go", "internal/http/server_test.go"}},
+		{"**/*_test.go", []string{"internal/http/server_test.go"}},
+		{"internal/????/server.go", []string{"internal/grpc/server.go", "internal/http/server.go"}},
+		{"internal/http", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.glob, func(t *testing.T) {
+			syms, err := idx.FindSymbol("handler", SymbolFilter{Path: tt.glob}, 10)
+			if err != nil {
+				t.Fatalf("FindSymbol() error = %v", err)
+			}
+			var got []string
+			for _, s := range syms {
+				got = append(got, s.Path)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("FindSymbol(path %q) = %v, want %v", tt.glob, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindSymbolScope(t *testing.T) {
+	idx, err := NewIndexWithConfig(db.DefaultConfig(filepath.Join(t.TempDir(), "symbols.db")), "/test/repo")
+	if err != nil {
+		t.Fatalf("NewIndexWithConfig() error = %v", err)
+	}
+	defer idx.Close()
+
+	for _, sym := range []Symbol{
+		{Name: "Create", Scope: "class:UserService", Line: 1},
+		{Name: "Delete", Scope: "class:UserService", Line: 2},
+		{Name: "Create", Scope: "class:User_Service", Line: 3},
+		{Name: "Create", Scope: "OrderService", Line: 4},
+		{Name: "Create", Line: 5},
+	} {
+		if _, err := idx.adapter.Exec(`INSERT INTO symbols (repo_root, name, kind, path, line, scope) VALUES (?, ?, ?, ?, ?, ?)`,
+			idx.root, sym.Name, "method", "svc.py", sym.Line, sym.Scope); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lines := func(name, scope string) []int {
+		t.Helper()
+		syms, err := idx.FindSymbol(name, SymbolFilter{Scope: scope}, 10)
+		if err != nil {
+			t.Fatalf("FindSymbol() error = %v", err)
+		}
+		var got []int
+		for _, s := range syms {
+			got = append(got, s.Line)
+		}
+		slices.Sort(got)
+		return got
+	}
+	if got := lines("", "UserService"); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("members of UserService = lines %v, want [1 2]", got)
+	}
+	if got := lines("Create", "class:UserService"); !slices.Equal(got, []int{1}) {
+		t.Errorf("Create in class:UserService = lines %v, want [1]", got)
+	}
+	if got := lines("Create", "OrderService"); !slices.Equal(got, []int{4}) {
+		t.Errorf("Create in OrderService = lines %v, want [4]", got)
+	}
+	if got := lines("Create", ""); len(got) != 4 {
+		t.Errorf("Create in any scope = lines %v, want all 4", got)
+	}
+}
+
+func TestListByKind(t *testing.T) {
+	idx, err := NewIndexWithConfig(db.DefaultConfig(filepath.Join(t.TempDir(), "symbols.db")), "/test/repo")
+	if err != nil {
+		t.Fatalf("NewIndexWithConfig() error = %v", err)
+	}
+	defer idx.Close()
+
+	for _, sym := range []Symbol{
+		{Name: "Store", Kind: "interface", Path: "b.go", Line: 3},
+		{Name: "Reader", Kind: "interface", Path: "a.go", Line: 9},
+		{Name: "Cache", Kind: "interface", Path: "b.go", Line: 20},
+		{Name: "server", Kind: "struct", Path: "a.go", Line: 1},
+	} {
+		if _, err := idx.adapter.Exec(`INSERT INTO symbols (repo_root, name, kind, path, line) VALUES (?, ?, ?, ?, ?)`,
+			idx.root, sym.Name, sym.Kind, sym.Path, sym.Line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, hasMore, err := idx.ListByKind("interface", 0, 2)
+	if err != nil {
+		t.Fatalf("ListByKind() error = %v", err)
+	}
+	if len(page) != 2 || page[0].Name != "Reader" || page[1].Name != "Cache" || !hasMore {
+		t.Errorf("first page = %+v, hasMore %v, want Reader, Cache and more", page, hasMore)
+	}
+	page, hasMore, err = idx.ListByKind("interface", 2, 2)
+	if err != nil {
+		t.Fatalf("ListByKind() error = %v", err)
+	}
+	if len(page) != 1 || page[0].Name != "Store" || hasMore {
+		t.Errorf("second page = %+v, hasMore %v, want only Store", page, hasMore)
+	}
+
+	all, _, err := idx.ListByKind("interface", 0, 10)
+	if err != nil {
+		t.Fatalf("ListByKind() error = %v", err)
+	}
+	files := GroupByFile(all)
+	if len(files) != 2 || files[0].Path != "a.go" || len(files[0].Symbols) != 1 || files[1].Path != "b.go" || len(files[1].Symbols) != 2 {
+		t.Errorf("GroupByFile() = %+v, want a.go with 1 symbol and b.go with 2", files)
+	}
+}
+
 func TestListDefsInFileEmpty(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "symbols.db")
@@ -115,30 +490,6 @@ func TestIsCodeFile(t *testing.T) {
 	}
 }
 
-func TestIsIgnoredDir(t *testing.T) {
-	tests := []struct {
-		name     string
-		expected bool
-	}{
-		{"node_modules", true},
-		{"vendor", true},
-		{"dist", true},
-		{".git", true},
-		{"src", false},
-		{"internal", false},
-		{"pkg", false},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := isIgnoredDir(tt.name)
-			if result != tt.expected {
-				t.Errorf("isIgnoredDir(%q) = %v, want %v", tt.name, result, tt.expected)
-			}
-		})
-	}
-}
-
 func TestClearSymbols(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "symbols.db")
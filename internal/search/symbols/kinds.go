@@ -0,0 +1,139 @@
+package symbols
+
+import "strings"
+
+// Canonical symbol kinds. Both backends map what they extract onto these
+// before symbols are stored, so kind filters match whichever ran.
+const (
+	KindFunction  = "function"
+	KindMethod    = "method"
+	KindClass     = "class"
+	KindStruct    = "struct"
+	KindInterface = "interface"
+	KindEnum      = "enum"
+	KindType      = "type"
+	KindModule    = "module"
+	KindVariable  = "variable"
+	KindConstant  = "constant"
+)
+
+// CanonicalKinds lists the canonical symbol kinds
+var CanonicalKinds = []string{
+	KindFunction, KindMethod, KindClass, KindStruct, KindInterface,
+	KindEnum, KindType, KindModule, KindVariable, KindConstant,
+}
+
+// ctagsKinds maps universal-ctags kind names, long and single-letter, to
+// canonical kinds. Single letters differ between ctags languages; these are
+// the common ones.
+var ctagsKinds = map[string]string{
+	"f": KindFunction, "func": KindFunction, "function": KindFunction,
+	"prototype": KindFunction, "subroutine": KindFunction, "procedure": KindFunction,
+	"method": KindMethod, "singletonmethod": KindMethod,
+	"c": KindClass, "class": KindClass,
+	"s": KindStruct, "struct": KindStruct, "union": KindStruct,
+	"i": KindInterface, "interface": KindInterface, "trait": KindInterface, "protocol": KindInterface,
+	"e": KindEnum, "enum": KindEnum,
+	"t": KindType, "type": KindType, "typedef": KindType, "alias": KindType, "talias": KindType,
+	"p": KindModule, "package": KindModule, "module": KindModule, "namespace": KindModule,
+	"v": KindVariable, "var": KindVariable, "variable": KindVariable,
+	"m": KindVariable, "member": KindVariable, "field": KindVariable, "property": KindVariable,
+	"const": KindConstant, "constant": KindConstant, "enumerator": KindConstant,
+	"macro": KindConstant, "define": KindConstant,
+}
+
+// ctagsLanguageKinds overrides ctagsKinds for the languages whose kind
+// letters (or names) mean something else, keyed by lowercased ctags
+// language name. Letters are case-sensitive here: JavaScript's C is a
+// constant but its c a class.
+var ctagsLanguageKinds = map[string]map[string]string{
+	"go": {
+		"c": KindConstant, "n": KindMethod, "methodspec": KindMethod,
+		"a": KindType, "m": KindVariable, "M": KindVariable, "anonmember": KindVariable,
+	},
+	"java": {
+		"m": KindMethod, "f": KindVariable, "g": KindEnum, "e": KindConstant,
+		"enumconstant": KindConstant, "a": KindInterface, "annotation": KindInterface,
+	},
+	"c": {
+		"d": KindConstant, "g": KindEnum, "e": KindConstant, "p": KindFunction,
+		"u": KindStruct, "n": KindModule,
+	},
+	"c++": {
+		"d": KindConstant, "g": KindEnum, "e": KindConstant, "p": KindFunction,
+		"u": KindStruct, "n": KindModule,
+	},
+	"python": {
+		"m": KindMethod, "member": KindMethod, "i": KindModule, "I": KindModule,
+	},
+	"ruby": {
+		"f": KindMethod, "m": KindModule, "S": KindMethod,
+	},
+	"javascript": {
+		"m": KindMethod, "p": KindVariable, "C": KindConstant, "g": KindFunction, "generator": KindFunction,
+	},
+	"typescript": {
+		"m": KindMethod, "p": KindVariable, "C": KindConstant, "g": KindEnum, "e": KindConstant, "n": KindModule,
+	},
+	"rust": {
+		"n": KindModule, "g": KindEnum, "m": KindVariable, "e": KindConstant,
+		"P": KindMethod, "C": KindConstant, "M": KindFunction, "implementation": KindType, "c": KindType,
+	},
+}
+
+// typeScopeKinds are the canonical kinds of scopes whose functions are
+// methods
+var typeScopeKinds = map[string]bool{
+	KindClass: true, KindStruct: true, KindInterface: true, KindEnum: true, KindType: true,
+}
+
+// astGrepKinds maps the kinds in SymbolPattern definitions to canonical
+// kinds
+var astGrepKinds = map[string]string{
+	"function":  KindFunction,
+	"method":    KindMethod,
+	"class":     KindClass,
+	"struct":    KindStruct,
+	"interface": KindInterface,
+	"trait":     KindInterface,
+	"enum":      KindEnum,
+	"type":      KindType,
+	"module":    KindModule,
+	"variable":  KindVariable,
+	"constant":  KindConstant,
+}
+
+// normalizeKind maps a ctags kind name for language to its canonical kind.
+// Kinds with no mapping pass through lowercased.
+func normalizeKind(language, kind string) string {
+	if canonical, ok := ctagsLanguageKinds[strings.ToLower(language)][kind]; ok {
+		return canonical
+	}
+	if canonical, ok := ctagsLanguageKinds[strings.ToLower(language)][strings.ToLower(kind)]; ok {
+		return canonical
+	}
+	return mapKind(ctagsKinds, kind)
+}
+
+// normalizeAstGrepKind maps a SymbolPattern kind to its canonical kind
+func normalizeAstGrepKind(kind string) string {
+	return mapKind(astGrepKinds, kind)
+}
+
+// CanonicalKind maps a kind from either backend, or as typed in a query
+// ("func", "package"), to its canonical kind
+func CanonicalKind(kind string) string {
+	lower := strings.ToLower(strings.TrimSpace(kind))
+	if canonical, ok := astGrepKinds[lower]; ok {
+		return canonical
+	}
+	return mapKind(ctagsKinds, lower)
+}
+
+func mapKind(table map[string]string, kind string) string {
+	lower := strings.ToLower(kind)
+	if canonical, ok := table[lower]; ok {
+		return canonical
+	}
+	return lower
+}
@@ -0,0 +1,41 @@
+package symbols
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCanonicalKind(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"function", KindFunction},
+		{"Func", KindFunction},
+		{"method", KindMethod},
+		{"trait", KindInterface},
+		{"package", KindModule},
+		{"namespace", KindModule},
+		{"field", KindVariable},
+		{"enumerator", KindConstant},
+		{" struct ", KindStruct},
+		{"label", "label"}, // Passthrough
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := CanonicalKind(tt.input); got != tt.want {
+				t.Errorf("CanonicalKind(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackendKindsAreCanonical(t *testing.T) {
+	for name, table := range map[string]map[string]string{"ctags": ctagsKinds, "ast-grep": astGrepKinds} {
+		for kind, canonical := range table {
+			if !slices.Contains(CanonicalKinds, canonical) {
+				t.Errorf("%s kind %q maps to %q, not a canonical kind", name, kind, canonical)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package symbols
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"codetect/internal/config"
+)
+
+// PatternsFileName is the file in a repo's .codetect directory holding
+// extra ast-grep patterns, keyed by language:
+//
+//	python:
+//	  - kind: function
+//	    pattern: "async def $NAME($$$): $$$"
+//	rust:
+//	  - {kind: function, pattern: "pub(crate) fn $NAME($$$) $$$"}
+const PatternsFileName = "patterns.yaml"
+
+// PatternsPath returns where the custom patterns file for the repository at
+// root lives. It sits in the tree even when CODETECT_DATA_DIR moves the
+// index, since it is configuration rather than index data.
+func PatternsPath(root string) string {
+	return filepath.Join(root, config.DataDirName, PatternsFileName)
+}
+
+// customPattern is one entry of the patterns file
+type customPattern struct {
+	Kind    string `yaml:"kind"`
+	Pattern string `yaml:"pattern"`
+}
+
+// LoadCustomPatterns reads a patterns file, returning the patterns it adds
+// per language. A missing file adds none.
+func LoadCustomPatterns(path string) (map[string][]SymbolPattern, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file map[string][]customPattern
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	custom := make(map[string][]SymbolPattern, len(file))
+	for lang, entries := range file {
+		lang = strings.ToLower(lang)
+		if GetLanguagePatterns(lang) == nil {
+			return nil, fmt.Errorf("%s: unsupported language %q (supported: %s)",
+				path, lang, strings.Join(SupportedLanguages(), ", "))
+		}
+		for i, e := range entries {
+			kind, pattern := strings.TrimSpace(e.Kind), strings.TrimSpace(e.Pattern)
+			if kind == "" || pattern == "" {
+				return nil, fmt.Errorf("%s: %s pattern %d needs both kind and pattern", path, lang, i+1)
+			}
+			custom[lang] = append(custom[lang], SymbolPattern{Kind: kind, Pattern: pattern})
+		}
+	}
+	return custom, nil
+}
+
+// MergePatterns returns the built-in patterns for language followed by the
+// custom ones, leaving out custom patterns that repeat a built-in. Returns
+// nil for an unsupported language.
+func MergePatterns(language string, custom map[string][]SymbolPattern) *LanguagePattern {
+	lp := GetLanguagePatterns(language)
+	if lp == nil {
+		return nil
+	}
+
+	seen := make(map[SymbolPattern]bool, len(lp.Patterns))
+	for _, p := range lp.Patterns {
+		seen[p] = true
+	}
+	for _, p := range custom[lp.Language] {
+		if !seen[p] {
+			seen[p] = true
+			lp.Patterns = append(lp.Patterns, p)
+		}
+	}
+	return lp
+}
@@ -0,0 +1,76 @@
+package symbols
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadCustomPatterns(t *testing.T) {
+	root := t.TempDir()
+	path := PatternsPath(root)
+
+	custom, err := LoadCustomPatterns(path)
+	if err != nil || custom != nil {
+		t.Fatalf("LoadCustomPatterns(missing) = %v, %v, want no patterns", custom, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := `# Team idioms
+python:
+  - kind: function
+    pattern: "async def $NAME($$$): $$$"
+Rust:
+  - {kind: function, pattern: "pub(crate) fn $NAME($$$) $$$"}
+  - {kind: function, pattern: "fn $NAME($$$) $$$"}
+`
+	if err := os.WriteFile(path, []byte(file), 0644); err != nil {
+		t.Fatal(err)
+	}
+	custom, err = LoadCustomPatterns(path)
+	if err != nil {
+		t.Fatalf("LoadCustomPatterns() error = %v", err)
+	}
+	if len(custom["python"]) != 1 || custom["python"][0].Pattern != "async def $NAME($$$): $$$" {
+		t.Errorf("python patterns = %v, want the async def pattern", custom["python"])
+	}
+
+	// Custom patterns follow the built-ins; one repeating a built-in is dropped
+	merged := MergePatterns("rust", custom)
+	builtin := GetLanguagePatterns("rust")
+	if len(merged.Patterns) != len(builtin.Patterns)+1 {
+		t.Fatalf("merged rust patterns = %d, want %d", len(merged.Patterns), len(builtin.Patterns)+1)
+	}
+	if last := merged.Patterns[len(merged.Patterns)-1]; last.Pattern != "pub(crate) fn $NAME($$$) $$$" {
+		t.Errorf("last merged pattern = %q, want the pub(crate) pattern", last.Pattern)
+	}
+	if merged := MergePatterns("go", custom); len(merged.Patterns) != len(GetLanguagePatterns("go").Patterns) {
+		t.Errorf("go patterns changed by another language's custom patterns")
+	}
+}
+
+func TestLoadCustomPatternsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{"unsupported language", "cobol:\n  - {kind: function, pattern: \"x\"}\n", "unsupported language"},
+		{"missing pattern", "go:\n  - kind: function\n", "needs both kind and pattern"},
+		{"not yaml", "go: [\n", "parsing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), PatternsFileName)
+			if err := os.WriteFile(path, []byte(tt.file), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadCustomPatterns(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadCustomPatterns() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
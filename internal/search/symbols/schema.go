@@ -1,6 +1,7 @@
 package symbols
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
@@ -11,41 +12,12 @@ import (
 	_ "modernc.org/sqlite"
 )
 
-const schemaVersion = 2
-
-const schema = `
-CREATE TABLE IF NOT EXISTS schema_version (
-    version INTEGER NOT NULL
-);
-
-CREATE TABLE IF NOT EXISTS symbols (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    repo_root TEXT NOT NULL,
-    name TEXT NOT NULL,
-    kind TEXT NOT NULL,
-    path TEXT NOT NULL,
-    line INTEGER NOT NULL,
-    language TEXT,
-    pattern TEXT,
-    scope TEXT,
-    signature TEXT,
-    UNIQUE(repo_root, name, path, line)
-);
-
-CREATE INDEX IF NOT EXISTS idx_symbols_name ON symbols(name);
-CREATE INDEX IF NOT EXISTS idx_symbols_path ON symbols(path);
-CREATE INDEX IF NOT EXISTS idx_symbols_kind ON symbols(kind);
-CREATE INDEX IF NOT EXISTS idx_symbols_repo_path ON symbols(repo_root, path);
-
-CREATE TABLE IF NOT EXISTS files (
-    repo_root TEXT NOT NULL,
-    path TEXT NOT NULL,
-    mtime INTEGER NOT NULL,
-    size INTEGER NOT NULL,
-    indexed_at INTEGER NOT NULL,
-    PRIMARY KEY (repo_root, path)
-);
-`
+// legacySchemaVersion is written to the pre-migration schema_version table
+// so binaries that predate schema_migrations still recognize the database.
+const legacySchemaVersion = 2
+
+// migrationsComponent identifies symbol index migrations in schema_migrations
+const migrationsComponent = "symbols"
 
 // OpenDB opens or creates the symbol database at the given path
 func OpenDB(dbPath string) (*sql.DB, error) {
@@ -75,40 +47,8 @@ func OpenDB(dbPath string) (*sql.DB, error) {
 	return db, nil
 }
 
-func initSchema(db *sql.DB) error {
-	// Check current schema version
-	var version int
-	err := db.QueryRow("SELECT version FROM schema_version LIMIT 1").Scan(&version)
-	if err == sql.ErrNoRows {
-		// Fresh database, create schema
-		if _, err := db.Exec(schema); err != nil {
-			return fmt.Errorf("creating schema: %w", err)
-		}
-		if _, err := db.Exec("INSERT INTO schema_version (version) VALUES (?)", schemaVersion); err != nil {
-			return fmt.Errorf("setting schema version: %w", err)
-		}
-		return nil
-	}
-	if err != nil {
-		// Table doesn't exist, create schema
-		if _, err := db.Exec(schema); err != nil {
-			return fmt.Errorf("creating schema: %w", err)
-		}
-		if _, err := db.Exec("INSERT INTO schema_version (version) VALUES (?)", schemaVersion); err != nil {
-			return fmt.Errorf("setting schema version: %w", err)
-		}
-		return nil
-	}
-
-	// Version exists, check for migrations
-	if version < schemaVersion {
-		// Future: add migration logic here
-		if _, err := db.Exec("UPDATE schema_version SET version = ?", schemaVersion); err != nil {
-			return fmt.Errorf("updating schema version: %w", err)
-		}
-	}
-
-	return nil
+func initSchema(sqlDB *sql.DB) error {
+	return migrateSchema(db.WrapSQL(sqlDB), &db.SQLiteDialect{})
 }
 
 // ClearSymbols removes all symbols for a given file path
@@ -133,88 +73,150 @@ func initSchemaWithAdapter(adapter db.DB, dialect db.Dialect) error {
 		}
 	}
 
-	// Create schema_version table
+	return migrateSchema(adapter, dialect)
+}
+
+// migrateSchema applies any pending symbol index migrations.
+func migrateSchema(adapter db.DB, dialect db.Dialect) error {
+	migrator := db.NewMigrator(adapter, dialect, migrationsComponent, symbolMigrations(dialect))
+	if _, err := migrator.Migrate(context.Background()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// symbolMigrations returns the ordered schema migrations for the symbol index.
+// Append new migrations to the end; never edit one that has shipped.
+func symbolMigrations(dialect db.Dialect) []db.Migration {
+	return []db.Migration{
+		{
+			Version:     1,
+			Description: "create symbols and files tables",
+			Up: func(ctx context.Context, exec db.Executor) error {
+				return createInitialSchema(exec, dialect)
+			},
+		},
+		{
+			Version:     2,
+			Description: "backfill optional symbol columns on older databases",
+			Up: func(ctx context.Context, exec db.Executor) error {
+				// Databases created before these columns existed skip them in
+				// CREATE TABLE IF NOT EXISTS, so add any that are missing
+				for _, name := range []string{"language", "pattern", "scope", "signature"} {
+					col := db.ColumnDef{Name: name, Type: db.ColTypeText, Nullable: true}
+					if _, err := db.EnsureColumn(ctx, exec, dialect, "symbols", col); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Version:     3,
+			Description: "create imports table for the import graph",
+			Up: func(ctx context.Context, exec db.Executor) error {
+				importColumns := []db.ColumnDef{
+					{Name: "repo_root", Type: db.ColTypeText, Nullable: false},
+					{Name: "path", Type: db.ColTypeText, Nullable: false},
+					{Name: "spec", Type: db.ColTypeText, Nullable: false},
+					{Name: "target", Type: db.ColTypeText, Nullable: true},
+				}
+				return db.ExecAll(exec,
+					dialect.CreateTableSQL("imports", importColumns),
+					dialect.CreateIndexSQL("imports", "idx_imports_repo_path", []string{"repo_root", "path"}, false),
+					dialect.CreateIndexSQL("imports", "idx_imports_repo_target", []string{"repo_root", "target"}, false),
+				)
+			},
+		},
+		{
+			Version:     4,
+			Description: "rename stored symbol kinds to the canonical set",
+			Up: func(ctx context.Context, exec db.Executor) error {
+				// Methods were stored as functions and can't be told apart
+				// here; reindexing restores them
+				renames := [][2]string{
+					{"package", "module"},
+					{"namespace", "module"},
+					{"field", "variable"},
+					{"member", "variable"},
+					{"enumerator", "constant"},
+					{"trait", "interface"},
+					{"typedef", "type"},
+				}
+				update := fmt.Sprintf("UPDATE symbols SET kind = %s WHERE kind = %s", dialect.Placeholder(1), dialect.Placeholder(2))
+				for _, r := range renames {
+					if _, err := exec.Exec(update, r[1], r[0]); err != nil {
+						return fmt.Errorf("renaming kind %s: %w", r[0], err)
+					}
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// createInitialSchema creates the symbols and files tables.
+// Everything uses IF NOT EXISTS so databases created before migrations
+// existed adopt this migration without changes.
+func createInitialSchema(exec db.Executor, dialect db.Dialect) error {
+	// Legacy version table, kept for binaries that predate schema_migrations
 	schemaVersionColumns := []db.ColumnDef{
 		{Name: "version", Type: db.ColTypeInteger, Nullable: false},
 	}
-	if _, err := adapter.Exec(dialect.CreateTableSQL("schema_version", schemaVersionColumns)); err != nil {
+	if _, err := exec.Exec(dialect.CreateTableSQL("schema_version", schemaVersionColumns)); err != nil {
 		return fmt.Errorf("creating schema_version table: %w", err)
 	}
-
-	// Check current schema version
-	var version int
-	err := adapter.QueryRow("SELECT version FROM schema_version LIMIT 1").Scan(&version)
-	needsSchema := err != nil // Either no rows or table was just created
-
-	if needsSchema {
-		// Create symbols table with repo_root for multi-repo isolation
-		symbolColumns := []db.ColumnDef{
-			{Name: "id", Type: db.ColTypeAutoIncrement},
-			{Name: "repo_root", Type: db.ColTypeText, Nullable: false},
-			{Name: "name", Type: db.ColTypeText, Nullable: false},
-			{Name: "kind", Type: db.ColTypeText, Nullable: false},
-			{Name: "path", Type: db.ColTypeText, Nullable: false},
-			{Name: "line", Type: db.ColTypeInteger, Nullable: false},
-			{Name: "language", Type: db.ColTypeText, Nullable: true},
-			{Name: "pattern", Type: db.ColTypeText, Nullable: true},
-			{Name: "scope", Type: db.ColTypeText, Nullable: true},
-			{Name: "signature", Type: db.ColTypeText, Nullable: true},
-		}
-		if _, err := adapter.Exec(dialect.CreateTableSQL("symbols", symbolColumns)); err != nil {
-			return fmt.Errorf("creating symbols table: %w", err)
-		}
-
-		// Create unique constraint on symbols including repo_root
-		uniqueIdxSQL := dialect.CreateIndexSQL("symbols", "idx_symbols_unique", []string{"repo_root", "name", "path", "line"}, true)
-		if _, err := adapter.Exec(uniqueIdxSQL); err != nil {
-			// Ignore error if index already exists (some databases don't support IF NOT EXISTS for unique constraints)
-		}
-
-		// Create indexes on symbols table
-		if _, err := adapter.Exec(dialect.CreateIndexSQL("symbols", "idx_symbols_name", []string{"name"}, false)); err != nil {
-			return fmt.Errorf("creating name index: %w", err)
-		}
-		if _, err := adapter.Exec(dialect.CreateIndexSQL("symbols", "idx_symbols_path", []string{"path"}, false)); err != nil {
-			return fmt.Errorf("creating path index: %w", err)
-		}
-		if _, err := adapter.Exec(dialect.CreateIndexSQL("symbols", "idx_symbols_kind", []string{"kind"}, false)); err != nil {
-			return fmt.Errorf("creating kind index: %w", err)
-		}
-		// Composite index for repo-scoped queries
-		if _, err := adapter.Exec(dialect.CreateIndexSQL("symbols", "idx_symbols_repo_path", []string{"repo_root", "path"}, false)); err != nil {
-			return fmt.Errorf("creating repo_path index: %w", err)
-		}
-
-		// Create files table with repo_root for multi-repo isolation
-		// Use unique index instead of composite PK for dialect compatibility
-		fileColumns := []db.ColumnDef{
-			{Name: "repo_root", Type: db.ColTypeText, Nullable: false},
-			{Name: "path", Type: db.ColTypeText, Nullable: false},
-			{Name: "mtime", Type: db.ColTypeInteger, Nullable: false},
-			{Name: "size", Type: db.ColTypeInteger, Nullable: false},
-			{Name: "indexed_at", Type: db.ColTypeInteger, Nullable: false},
-		}
-		if _, err := adapter.Exec(dialect.CreateTableSQL("files", fileColumns)); err != nil {
-			return fmt.Errorf("creating files table: %w", err)
-		}
-		// Create unique constraint for files (repo_root, path)
-		filesUniqueIdx := dialect.CreateIndexSQL("files", "idx_files_unique", []string{"repo_root", "path"}, true)
-		if _, err := adapter.Exec(filesUniqueIdx); err != nil {
-			// Ignore error if index already exists
-		}
-
-		// Insert schema version
+	var legacyCount int
+	if err := exec.QueryRow("SELECT COUNT(*) FROM schema_version").Scan(&legacyCount); err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+	if legacyCount == 0 {
 		insertVersionSQL := fmt.Sprintf("INSERT INTO schema_version (version) VALUES (%s)", dialect.Placeholder(1))
-		if _, err := adapter.Exec(insertVersionSQL, schemaVersion); err != nil {
+		if _, err := exec.Exec(insertVersionSQL, legacySchemaVersion); err != nil {
 			return fmt.Errorf("setting schema version: %w", err)
 		}
-	} else if version < schemaVersion {
-		// Future: add migration logic here
-		updateVersionSQL := fmt.Sprintf("UPDATE schema_version SET version = %s", dialect.Placeholder(1))
-		if _, err := adapter.Exec(updateVersionSQL, schemaVersion); err != nil {
-			return fmt.Errorf("updating schema version: %w", err)
-		}
 	}
 
-	return nil
+	// Create symbols table with repo_root for multi-repo isolation
+	symbolColumns := []db.ColumnDef{
+		{Name: "id", Type: db.ColTypeAutoIncrement},
+		{Name: "repo_root", Type: db.ColTypeText, Nullable: false},
+		{Name: "name", Type: db.ColTypeText, Nullable: false},
+		{Name: "kind", Type: db.ColTypeText, Nullable: false},
+		{Name: "path", Type: db.ColTypeText, Nullable: false},
+		{Name: "line", Type: db.ColTypeInteger, Nullable: false},
+		{Name: "language", Type: db.ColTypeText, Nullable: true},
+		{Name: "pattern", Type: db.ColTypeText, Nullable: true},
+		{Name: "scope", Type: db.ColTypeText, Nullable: true},
+		{Name: "signature", Type: db.ColTypeText, Nullable: true},
+	}
+	if _, err := exec.Exec(dialect.CreateTableSQL("symbols", symbolColumns)); err != nil {
+		return fmt.Errorf("creating symbols table: %w", err)
+	}
+
+	// Create files table with repo_root for multi-repo isolation
+	// Use unique index instead of composite PK for dialect compatibility
+	fileColumns := []db.ColumnDef{
+		{Name: "repo_root", Type: db.ColTypeText, Nullable: false},
+		{Name: "path", Type: db.ColTypeText, Nullable: false},
+		{Name: "mtime", Type: db.ColTypeInteger, Nullable: false},
+		{Name: "size", Type: db.ColTypeInteger, Nullable: false},
+		{Name: "indexed_at", Type: db.ColTypeInteger, Nullable: false},
+	}
+	if _, err := exec.Exec(dialect.CreateTableSQL("files", fileColumns)); err != nil {
+		return fmt.Errorf("creating files table: %w", err)
+	}
+
+	return db.ExecAll(exec,
+		// Unique constraints including repo_root (also the upsert conflict targets)
+		dialect.CreateIndexSQL("symbols", "idx_symbols_unique", []string{"repo_root", "name", "path", "line"}, true),
+		dialect.CreateIndexSQL("files", "idx_files_unique", []string{"repo_root", "path"}, true),
+
+		// Indexes on symbols table
+		dialect.CreateIndexSQL("symbols", "idx_symbols_name", []string{"name"}, false),
+		dialect.CreateIndexSQL("symbols", "idx_symbols_path", []string{"path"}, false),
+		dialect.CreateIndexSQL("symbols", "idx_symbols_kind", []string{"kind"}, false),
+		// Composite index for repo-scoped queries
+		dialect.CreateIndexSQL("symbols", "idx_symbols_repo_path", []string{"repo_root", "path"}, false),
+	)
 }
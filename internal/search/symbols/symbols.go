@@ -22,3 +22,40 @@ type ListDefsResult struct {
 	Path    string   `json:"path"`
 	Symbols []Symbol `json:"symbols"`
 }
+
+// ListSymbolsResult is a page of a repo's symbols of one kind. Symbols is
+// flat unless grouped, when Files holds them by file instead.
+type ListSymbolsResult struct {
+	Kind    string        `json:"kind"`
+	Symbols []Symbol      `json:"symbols,omitempty"`
+	Files   []FileSymbols `json:"files,omitempty"`
+	HasMore bool          `json:"has_more"` // More symbols follow this page
+}
+
+// FileSymbols is one file's symbols in a grouped ListSymbolsResult
+type FileSymbols struct {
+	Path    string   `json:"path"`
+	Symbols []Symbol `json:"symbols"`
+}
+
+// GroupByFile groups symbols by path, keeping their order. Symbols of a
+// file are expected to be adjacent, as ListByKind returns them.
+func GroupByFile(syms []Symbol) []FileSymbols {
+	var files []FileSymbols
+	for _, s := range syms {
+		if n := len(files); n == 0 || files[n-1].Path != s.Path {
+			files = append(files, FileSymbols{Path: s.Path})
+		}
+		files[len(files)-1].Symbols = append(files[len(files)-1].Symbols, s)
+	}
+	return files
+}
+
+// RelatedFilesResult is the result of looking up a file's import graph
+// neighbours
+type RelatedFilesResult struct {
+	Path       string   `json:"path"`
+	Imports    []string `json:"imports"`            // Repo files path imports
+	ImportedBy []string `json:"imported_by"`        // Repo files that import path
+	External   []string `json:"external,omitempty"` // Imports of packages outside the repo
+}
@@ -0,0 +1,270 @@
+// Package snapshot exports a repository's index to a portable archive and
+// imports it back, so an index built once (e.g. on CI against PostgreSQL)
+// can be shipped to developers and loaded into a local SQLite index.
+//
+// The archive is a gzip-compressed tar holding a JSON manifest followed by
+// JSON Lines files for symbols, files and embeddings. Everything is read and
+// written through the store APIs, so the format is independent of the
+// database dialect on either side.
+package snapshot
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"codetect/internal/embedding"
+	"codetect/internal/search/symbols"
+)
+
+// FormatVersion is the archive format version written by Export.
+// Import rejects archives with a newer version.
+const FormatVersion = 1
+
+// Archive entry names, in the order they are written.
+const (
+	manifestEntry   = "manifest.json"
+	symbolsEntry    = "symbols.jsonl"
+	filesEntry      = "files.jsonl"
+	embeddingsEntry = "embeddings.jsonl"
+)
+
+// Manifest is the version header stored at the start of an archive.
+type Manifest struct {
+	FormatVersion    int       `json:"format_version"`
+	CreatedAt        time.Time `json:"created_at"`
+	RepoID           string    `json:"repo_id"`
+	VectorDimensions int       `json:"vector_dimensions"`
+	Models           []string  `json:"models,omitempty"`
+	Symbols          int       `json:"symbols"`
+	Files            int       `json:"files"`
+	Embeddings       int       `json:"embeddings"`
+}
+
+// Export writes the symbols, file records and embeddings of a repo to w.
+// store may be nil to export symbols only. repoID is recorded in the
+// manifest for reference; imports are keyed by the destination repo.
+func Export(w io.Writer, idx *symbols.Index, store *embedding.EmbeddingStore, repoID string) (*Manifest, error) {
+	syms, err := idx.AllSymbols()
+	if err != nil {
+		return nil, fmt.Errorf("reading symbols: %w", err)
+	}
+	files, err := idx.Files()
+	if err != nil {
+		return nil, fmt.Errorf("reading files: %w", err)
+	}
+
+	var records []embedding.EmbeddingRecord
+	manifest := &Manifest{
+		FormatVersion: FormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		RepoID:        repoID,
+		Symbols:       len(syms),
+		Files:         len(files),
+	}
+	if store != nil {
+		records, err = store.GetAll()
+		if err != nil && !errors.Is(err, embedding.ErrNoEmbeddingsForDimension) {
+			return nil, fmt.Errorf("reading embeddings: %w", err)
+		}
+		manifest.VectorDimensions = store.VectorDimensions()
+		manifest.Embeddings = len(records)
+		manifest.Models = distinctModels(records)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding manifest: %w", err)
+	}
+	if err := writeEntry(tw, manifestEntry, manifestJSON, manifest.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := writeJSONLines(tw, symbolsEntry, syms, manifest.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := writeJSONLines(tw, filesEntry, files, manifest.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := writeJSONLines(tw, embeddingsEntry, records, manifest.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("closing archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("closing archive: %w", err)
+	}
+	return manifest, nil
+}
+
+// Import loads an archive written by Export into idx and store, replacing
+// any existing data for their repo. It fails if the archive version is
+// unsupported or its embeddings don't match the store's vector dimensions.
+// store may be nil to import symbols only.
+func Import(r io.Reader, idx *symbols.Index, store *embedding.EmbeddingStore) (*Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("opening archive: %w", err)
+	}
+	defer gz.Close()
+
+	var (
+		manifest *Manifest
+		syms     []symbols.Symbol
+		files    []symbols.FileRecord
+		records  []embedding.EmbeddingRecord
+	)
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading archive: %w", err)
+		}
+
+		if hdr.Name != manifestEntry && manifest == nil {
+			return nil, fmt.Errorf("archive is missing %s header", manifestEntry)
+		}
+
+		switch hdr.Name {
+		case manifestEntry:
+			manifest = &Manifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("decoding manifest: %w", err)
+			}
+			if err := validateManifest(manifest, store); err != nil {
+				return nil, err
+			}
+		case symbolsEntry:
+			if syms, err = readJSONLines[symbols.Symbol](tr); err != nil {
+				return nil, fmt.Errorf("reading %s: %w", hdr.Name, err)
+			}
+		case filesEntry:
+			if files, err = readJSONLines[symbols.FileRecord](tr); err != nil {
+				return nil, fmt.Errorf("reading %s: %w", hdr.Name, err)
+			}
+		case embeddingsEntry:
+			if records, err = readJSONLines[embedding.EmbeddingRecord](tr); err != nil {
+				return nil, fmt.Errorf("reading %s: %w", hdr.Name, err)
+			}
+		}
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("archive is missing %s header", manifestEntry)
+	}
+
+	for i, rec := range records {
+		if len(rec.Embedding) != manifest.VectorDimensions {
+			return nil, fmt.Errorf("embedding %d (%s:%d) has %d dimensions, manifest says %d",
+				i, rec.Path, rec.StartLine, len(rec.Embedding), manifest.VectorDimensions)
+		}
+	}
+
+	if err := idx.ReplaceAll(syms, files); err != nil {
+		return nil, fmt.Errorf("importing symbols: %w", err)
+	}
+
+	if store != nil {
+		if err := store.DeleteAll(); err != nil {
+			return nil, fmt.Errorf("clearing embeddings: %w", err)
+		}
+		if err := store.SaveRecords(records); err != nil {
+			return nil, fmt.Errorf("importing embeddings: %w", err)
+		}
+		// Record what the archive was embedded with so it can be verified later
+		if len(records) > 0 && len(manifest.Models) > 0 {
+			model := manifest.Models[0]
+			if err := store.SetRepoConfig(store.RepoID(), embedding.ModelName(model), manifest.VectorDimensions); err != nil {
+				return nil, fmt.Errorf("recording embedding config: %w", err)
+			}
+			if err := store.RecordEmbedMetadata(model, manifest.VectorDimensions, ""); err != nil {
+				return nil, fmt.Errorf("recording index metadata: %w", err)
+			}
+		}
+	}
+
+	return manifest, nil
+}
+
+// validateManifest checks that an archive can be loaded into store.
+func validateManifest(m *Manifest, store *embedding.EmbeddingStore) error {
+	if m.FormatVersion < 1 || m.FormatVersion > FormatVersion {
+		return fmt.Errorf("unsupported archive format version %d (supported: 1-%d)", m.FormatVersion, FormatVersion)
+	}
+	if store != nil && m.Embeddings > 0 && m.VectorDimensions != store.VectorDimensions() {
+		return fmt.Errorf("archive embeddings have %d dimensions, local index expects %d (set CODETECT_VECTOR_DIMENSIONS=%d)",
+			m.VectorDimensions, store.VectorDimensions(), m.VectorDimensions)
+	}
+	return nil
+}
+
+// distinctModels returns the embedding models used by records, in first-seen order.
+func distinctModels(records []embedding.EmbeddingRecord) []string {
+	seen := make(map[string]bool)
+	var models []string
+	for _, r := range records {
+		if !seen[r.Model] {
+			seen[r.Model] = true
+			models = append(models, r.Model)
+		}
+	}
+	return models
+}
+
+// writeEntry writes a single file to the tar archive.
+func writeEntry(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("writing %s header: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	return nil
+}
+
+// writeJSONLines writes items as one JSON object per line.
+func writeJSONLines[T any](tw *tar.Writer, name string, items []T, modTime time.Time) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, item := range items {
+		if err := enc.Encode(item); err != nil {
+			return fmt.Errorf("encoding %s: %w", name, err)
+		}
+	}
+	return writeEntry(tw, name, buf.Bytes(), modTime)
+}
+
+// readJSONLines decodes one JSON object per line.
+func readJSONLines[T any](r io.Reader) ([]T, error) {
+	var items []T
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var item T
+		if err := dec.Decode(&item); err == io.EOF {
+			return items, nil
+		} else if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+}
@@ -0,0 +1,150 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+	"time"
+
+	"codetect/internal/db"
+	"codetect/internal/embedding"
+	"codetect/internal/search/symbols"
+)
+
+// openRepo creates an in-memory symbol index and embedding store for root.
+func openRepo(t *testing.T, root string, dims int) (*symbols.Index, *embedding.EmbeddingStore) {
+	t.Helper()
+
+	idx, err := symbols.NewIndexWithConfig(db.DefaultConfig(":memory:"), root)
+	if err != nil {
+		t.Fatalf("opening index: %v", err)
+	}
+	t.Cleanup(func() { idx.Close() })
+
+	store, err := embedding.NewEmbeddingStoreWithOptions(idx.DBAdapter(), idx.Dialect(), dims, root)
+	if err != nil {
+		t.Fatalf("creating store: %v", err)
+	}
+	return idx, store
+}
+
+func seedRepo(t *testing.T, idx *symbols.Index, store *embedding.EmbeddingStore) {
+	t.Helper()
+
+	mtime := time.Unix(1700000000, 0)
+	syms := []symbols.Symbol{
+		{Name: "main", Kind: "function", Path: "main.go", Line: 3, Language: "Go"},
+		{Name: "Config", Kind: "struct", Path: "config.go", Line: 10, Language: "Go", Scope: "config"},
+	}
+	files := []symbols.FileRecord{
+		{Path: "config.go", Mtime: mtime, Size: 200, IndexedAt: mtime},
+		{Path: "main.go", Mtime: mtime, Size: 100, IndexedAt: mtime},
+	}
+	if err := idx.ReplaceAll(syms, files); err != nil {
+		t.Fatalf("seeding symbols: %v", err)
+	}
+
+	chunks := []embedding.Chunk{
+		{Path: "main.go", StartLine: 1, EndLine: 5, Content: "func main() {}"},
+		{Path: "config.go", StartLine: 8, EndLine: 20, Content: "type Config struct{}"},
+	}
+	if err := store.SaveBatch(chunks, [][]float32{{1, 0, 0}, {0, 1, 0}}, "test-model"); err != nil {
+		t.Fatalf("seeding embeddings: %v", err)
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	srcIdx, srcStore := openRepo(t, "/ci/build-42/repo", 3)
+	seedRepo(t, srcIdx, srcStore)
+
+	var buf bytes.Buffer
+	manifest, err := Export(&buf, srcIdx, srcStore, "github.com/org/repo")
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if manifest.Symbols != 2 || manifest.Files != 2 || manifest.Embeddings != 2 {
+		t.Errorf("manifest counts = %+v, want 2 of each", manifest)
+	}
+
+	dstIdx, dstStore := openRepo(t, "/home/dev/repo", 3)
+	imported, err := Import(&buf, dstIdx, dstStore)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if imported.RepoID != "github.com/org/repo" || imported.VectorDimensions != 3 {
+		t.Errorf("imported manifest = %+v", imported)
+	}
+
+	syms, err := dstIdx.AllSymbols()
+	if err != nil {
+		t.Fatalf("AllSymbols() error = %v", err)
+	}
+	if len(syms) != 2 || syms[0].Name != "Config" || syms[0].Scope != "config" {
+		t.Errorf("imported symbols = %+v", syms)
+	}
+
+	files, err := dstIdx.Files()
+	if err != nil {
+		t.Fatalf("Files() error = %v", err)
+	}
+	if len(files) != 2 || files[1].Path != "main.go" || files[1].Size != 100 || files[1].Mtime.Unix() != 1700000000 {
+		t.Errorf("imported files = %+v", files)
+	}
+
+	srcRecords, _ := srcStore.GetAll()
+	dstRecords, err := dstStore.GetAll()
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if len(dstRecords) != len(srcRecords) {
+		t.Fatalf("imported %d embeddings, want %d", len(dstRecords), len(srcRecords))
+	}
+	if _, err := dstStore.VerifyRepoConfig(dstStore.RepoID(), "test-model", 3); err != nil {
+		t.Errorf("VerifyRepoConfig() after import error = %v", err)
+	}
+	for i := range dstRecords {
+		if dstRecords[i].ContentHash != srcRecords[i].ContentHash || dstRecords[i].Model != "test-model" {
+			t.Errorf("embedding %d = %+v, want %+v", i, dstRecords[i], srcRecords[i])
+		}
+	}
+}
+
+func TestImportRejectsDimensionMismatch(t *testing.T) {
+	srcIdx, srcStore := openRepo(t, "/ci/repo", 3)
+	seedRepo(t, srcIdx, srcStore)
+
+	var buf bytes.Buffer
+	if _, err := Export(&buf, srcIdx, srcStore, "/ci/repo"); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	dstIdx, dstStore := openRepo(t, "/home/dev/repo", 768)
+	_, err := Import(&buf, dstIdx, dstStore)
+	if err == nil || !strings.Contains(err.Error(), "dimensions") {
+		t.Fatalf("Import() error = %v, want dimension mismatch", err)
+	}
+
+	// Nothing was written
+	if syms, _ := dstIdx.AllSymbols(); len(syms) != 0 {
+		t.Errorf("symbols imported despite error: %+v", syms)
+	}
+}
+
+func TestImportRejectsUnsupportedVersion(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := writeEntry(tw, manifestEntry, []byte(`{"format_version": 99}`), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	gz.Close()
+
+	idx, store := openRepo(t, "/home/dev/repo", 3)
+	_, err := Import(&buf, idx, store)
+	if err == nil || !strings.Contains(err.Error(), "format version 99") {
+		t.Errorf("Import() error = %v, want unsupported version", err)
+	}
+}
@@ -0,0 +1,156 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"codetect/internal/mcp"
+)
+
+// maxBatchRequests caps the sub-requests in one batch_search call
+const maxBatchRequests = 16
+
+// BatchSearchRequest is one sub-request of a batch_search call: a tool
+// name and the arguments that tool takes on its own.
+type BatchSearchRequest struct {
+	Tool      string         `json:"tool"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+}
+
+// BatchSearchResponse holds one result per sub-request, in request order.
+type BatchSearchResponse struct {
+	Results []BatchSearchResult `json:"results"`
+}
+
+// BatchSearchResult is the output of one sub-request. Result holds the
+// tool's JSON output as-is; tools that answer in plain text fill Text
+// instead. Error is set if the sub-request failed, without failing the batch.
+type BatchSearchResult struct {
+	Tool   string          `json:"tool"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Text   string          `json:"text,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+func registerBatchSearch(server *mcp.Server) {
+	tool := mcp.Tool{
+		Name:        "batch_search",
+		Description: fmt.Sprintf("Run several tool calls at once, e.g. search_keyword, search_semantic and find_symbol for the same query, and get all results in one response. Sub-requests run concurrently and fail independently. Up to %d requests per batch.", maxBatchRequests),
+		InputSchema: mcp.InputSchema{
+			Type: "object",
+			Properties: map[string]mcp.Property{
+				"requests": {
+					Type:        "array",
+					Description: `Sub-requests, each {"tool": "<tool name>", "arguments": {...}} with the same arguments the tool takes when called directly`,
+					Items: &mcp.Property{
+						Type:        "object",
+						Description: "A tool name and its arguments",
+					},
+				},
+			},
+			Required: []string{"requests"},
+		},
+	}
+
+	handler := func(args map[string]any) (*mcp.ToolsCallResult, error) {
+		requests, err := parseBatchRequests(args)
+		if err != nil {
+			return nil, err
+		}
+
+		response := BatchSearchResponse{Results: make([]BatchSearchResult, len(requests))}
+		var wg sync.WaitGroup
+		for i, req := range requests {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				response.Results[i] = runBatchRequest(server, req)
+			}()
+		}
+		wg.Wait()
+
+		data, err := json.Marshal(response)
+		if err != nil {
+			return nil, err
+		}
+
+		return &mcp.ToolsCallResult{
+			Content: []mcp.Content{{
+				Type: "text",
+				Text: string(data),
+			}},
+		}, nil
+	}
+
+	server.RegisterTool(tool, handler)
+}
+
+// parseBatchRequests validates the "requests" argument of batch_search
+func parseBatchRequests(args map[string]any) ([]BatchSearchRequest, error) {
+	raw, ok := args["requests"].([]any)
+	if !ok || len(raw) == 0 {
+		return nil, fmt.Errorf("requests is required")
+	}
+	if len(raw) > maxBatchRequests {
+		return nil, fmt.Errorf("too many requests: %d (max %d)", len(raw), maxBatchRequests)
+	}
+
+	requests := make([]BatchSearchRequest, len(raw))
+	for i, r := range raw {
+		obj, ok := r.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("requests[%d] must be an object", i)
+		}
+		name, _ := obj["tool"].(string)
+		if name == "" {
+			return nil, fmt.Errorf("requests[%d].tool is required", i)
+		}
+		if name == "batch_search" {
+			return nil, fmt.Errorf("requests[%d]: batch_search cannot be nested", i)
+		}
+		arguments, _ := obj["arguments"].(map[string]any)
+		requests[i] = BatchSearchRequest{Tool: name, Arguments: arguments}
+	}
+	return requests, nil
+}
+
+// runBatchRequest calls a sub-request's tool through its registered
+// handler, so results match calling the tool directly
+func runBatchRequest(server *mcp.Server, req BatchSearchRequest) BatchSearchResult {
+	result := BatchSearchResult{Tool: req.Tool}
+
+	handler, ok := server.Handler(req.Tool)
+	if !ok {
+		result.Error = fmt.Sprintf("tool not found: %s", req.Tool)
+		return result
+	}
+	if req.Arguments == nil {
+		req.Arguments = map[string]any{}
+	}
+
+	out, err := handler(req.Arguments)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	var texts []string
+	for _, c := range out.Content {
+		if c.Type == "text" {
+			texts = append(texts, c.Text)
+		}
+	}
+	text := strings.Join(texts, "\n")
+
+	switch {
+	case out.IsError:
+		result.Error = text
+	case json.Valid([]byte(text)):
+		result.Result = json.RawMessage(text)
+	default:
+		result.Text = text
+	}
+	return result
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"codetect/internal/config"
 	"codetect/internal/db"
@@ -16,12 +17,15 @@ import (
 )
 
 // RegisterSemanticTools registers the semantic search MCP tools
-func RegisterSemanticTools(server *mcp.Server) {
-	registerSearchSemantic(server)
-	registerHybridSearch(server)
+func RegisterSemanticTools(server *mcp.Server, cfg config.ToolConfig) {
+	registerSearchSemantic(server, cfg.Limit(10))
+	registerSearchInFile(server, cfg.Limit(5))
+	registerSearchAcrossRepos(server, cfg.Limit(10))
+	registerListRepos(server)
+	registerHybridSearch(server, cfg)
 }
 
-func registerSearchSemantic(server *mcp.Server) {
+func registerSearchSemantic(server *mcp.Server, defaultLimit int) {
 	tool := mcp.Tool{
 		Name:        "search_semantic",
 		Description: "Search for code semantically similar to the query. Uses embeddings to find conceptually related code, not just keyword matches. Requires Ollama with nomic-embed-text model.",
@@ -34,8 +38,11 @@ func registerSearchSemantic(server *mcp.Server) {
 				},
 				"limit": {
 					Type:        "number",
-					Description: "Maximum number of results (default: 10)",
+					Description: fmt.Sprintf("Maximum number of results (default: %d)", defaultLimit),
 				},
+				"context_lines": contextLinesProperty,
+				"exclude_tests": excludeTestsProperty,
+				"offset":        offsetProperty,
 			},
 			Required: []string{"query"},
 		},
@@ -47,7 +54,7 @@ func registerSearchSemantic(server *mcp.Server) {
 			return nil, fmt.Errorf("query is required")
 		}
 
-		limit := 10
+		limit := defaultLimit
 		if l, ok := args["limit"].(float64); ok {
 			limit = int(l)
 		}
@@ -62,6 +69,7 @@ func registerSearchSemantic(server *mcp.Server) {
 				}},
 			}, nil
 		}
+		defer searcher.Store().Close()
 
 		// Check availability
 		if !searcher.Available() {
@@ -73,11 +81,16 @@ func registerSearchSemantic(server *mcp.Server) {
 			}, nil
 		}
 
+		if exclude, ok := args["exclude_tests"].(bool); ok {
+			searcher.SetExcludeTests(exclude)
+		}
+
 		// Perform search with snippets
-		result, err := searcher.SearchWithSnippets(context.Background(), query, limit, getSnippetFn())
+		result, err := searcher.SearchPage(context.Background(), query, offsetArg(args), limit, getSnippetFn(contextLinesArg(args)))
 		if err != nil {
 			return nil, fmt.Errorf("semantic search: %w", err)
 		}
+		result.Warning = searcher.ModelWarning()
 
 		data, err := json.Marshal(result)
 		if err != nil {
@@ -95,7 +108,266 @@ func registerSearchSemantic(server *mcp.Server) {
 	server.RegisterTool(tool, handler)
 }
 
-func registerHybridSearch(server *mcp.Server) {
+func registerSearchInFile(server *mcp.Server, defaultLimit int) {
+	tool := mcp.Tool{
+		Name:        "search_in_file",
+		Description: "Semantic search restricted to the chunks of one file. Finds the part of a large file that matches the query, e.g. where big_file.go handles retries. Returns line ranges and snippets within the file.",
+		InputSchema: mcp.InputSchema{
+			Type: "object",
+			Properties: map[string]mcp.Property{
+				"path": {
+					Type:        "string",
+					Description: "File to search within",
+				},
+				"query": {
+					Type:        "string",
+					Description: "Natural language query describing what you're looking for",
+				},
+				"limit": {
+					Type:        "number",
+					Description: fmt.Sprintf("Maximum number of results (default: %d)", defaultLimit),
+				},
+				"context_lines": contextLinesProperty,
+			},
+			Required: []string{"path", "query"},
+		},
+	}
+
+	handler := func(args map[string]any) (*mcp.ToolsCallResult, error) {
+		path, ok := args["path"].(string)
+		if !ok || path == "" {
+			return nil, fmt.Errorf("path is required")
+		}
+		query, ok := args["query"].(string)
+		if !ok || query == "" {
+			return nil, fmt.Errorf("query is required")
+		}
+
+		limit := defaultLimit
+		if l, ok := args["limit"].(float64); ok {
+			limit = int(l)
+		}
+
+		searcher, err := openSemanticSearcher()
+		if err != nil {
+			return &mcp.ToolsCallResult{
+				Content: []mcp.Content{{
+					Type: "text",
+					Text: fmt.Sprintf(`{"available": false, "error": %q}`, err.Error()),
+				}},
+			}, nil
+		}
+		defer searcher.Store().Close()
+
+		result, err := searcher.SearchInFile(context.Background(), path, query, limit, getSnippetFn(contextLinesArg(args)))
+		if err != nil {
+			return nil, fmt.Errorf("semantic search in %s: %w", path, err)
+		}
+		result.Warning = searcher.ModelWarning()
+
+		data, err := json.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+
+		return &mcp.ToolsCallResult{
+			Content: []mcp.Content{{
+				Type: "text",
+				Text: string(data),
+			}},
+		}, nil
+	}
+
+	server.RegisterTool(tool, handler)
+}
+
+func registerSearchAcrossRepos(server *mcp.Server, defaultLimit int) {
+	tool := mcp.Tool{
+		Name:        "search_across_repos",
+		Description: "Semantic search across every repository indexed in the shared database with the same embedding dimensions, not just the current one. Each result includes the repo_root it came from. Intended for an org-wide PostgreSQL index.",
+		InputSchema: mcp.InputSchema{
+			Type: "object",
+			Properties: map[string]mcp.Property{
+				"query": {
+					Type:        "string",
+					Description: "Natural language query describing what you're looking for",
+				},
+				"limit": {
+					Type:        "number",
+					Description: fmt.Sprintf("Maximum number of results (default: %d)", defaultLimit),
+				},
+				"repos": {
+					Type:        "array",
+					Description: "Repo roots to search, as reported in repo_root (default: all repos)",
+					Items:       &mcp.Property{Type: "string"},
+				},
+				"context_lines": contextLinesProperty,
+			},
+			Required: []string{"query"},
+		},
+	}
+
+	handler := func(args map[string]any) (*mcp.ToolsCallResult, error) {
+		query, ok := args["query"].(string)
+		if !ok || query == "" {
+			return nil, fmt.Errorf("query is required")
+		}
+
+		limit := defaultLimit
+		if l, ok := args["limit"].(float64); ok {
+			limit = int(l)
+		}
+
+		var repos []string
+		if list, ok := args["repos"].([]any); ok {
+			for _, r := range list {
+				if repo, ok := r.(string); ok && repo != "" {
+					repos = append(repos, repo)
+				}
+			}
+		}
+
+		searcher, err := openSemanticSearcher()
+		if err != nil {
+			return &mcp.ToolsCallResult{
+				Content: []mcp.Content{{
+					Type: "text",
+					Text: fmt.Sprintf(`{"available": false, "error": %q}`, err.Error()),
+				}},
+			}, nil
+		}
+		defer searcher.Store().Close()
+
+		if !searcher.Available() {
+			return &mcp.ToolsCallResult{
+				Content: []mcp.Content{{
+					Type: "text",
+					Text: `{"available": false, "error": "Embedding provider not available"}`,
+				}},
+			}, nil
+		}
+
+		result, err := searcher.SearchAcrossReposWithSnippets(context.Background(), query, limit, repos, getRepoSnippetFn(contextLinesArg(args)))
+		if err != nil {
+			return nil, fmt.Errorf("cross-repo search: %w", err)
+		}
+
+		data, err := json.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+
+		return &mcp.ToolsCallResult{
+			Content: []mcp.Content{{
+				Type: "text",
+				Text: string(data),
+			}},
+		}, nil
+	}
+
+	server.RegisterTool(tool, handler)
+}
+
+// repoSummary is one list_repos entry, kept small for the model's context.
+type repoSummary struct {
+	RepoRoot   string `json:"repo_root"`
+	Model      string `json:"model,omitempty"`
+	Dimensions int    `json:"dimensions,omitempty"`
+	Embeddings int    `json:"embeddings"`
+}
+
+func registerListRepos(server *mcp.Server) {
+	tool := mcp.Tool{
+		Name:        "list_repos",
+		Description: "List the repositories in the shared embedding index with their model, dimensions, and embedding count. Use the repo_root values to scope search_across_repos. On a local SQLite index this is just the current repo.",
+		InputSchema: mcp.InputSchema{
+			Type:       "object",
+			Properties: map[string]mcp.Property{},
+		},
+	}
+
+	handler := func(args map[string]any) (*mcp.ToolsCallResult, error) {
+		store, dbType, err := openConfiguredEmbeddingStore()
+		if err != nil {
+			return &mcp.ToolsCallResult{
+				Content: []mcp.Content{{
+					Type: "text",
+					Text: fmt.Sprintf(`{"repos": [], "error": %q}`, err.Error()),
+				}},
+			}, nil
+		}
+		defer store.Close()
+
+		repos, err := listRepos(store, dbType)
+		if err != nil {
+			return nil, fmt.Errorf("listing repos: %w", err)
+		}
+
+		data, err := json.Marshal(map[string]any{"repos": repos})
+		if err != nil {
+			return nil, err
+		}
+
+		return &mcp.ToolsCallResult{
+			Content: []mcp.Content{{
+				Type: "text",
+				Text: string(data),
+			}},
+		}, nil
+	}
+
+	server.RegisterTool(tool, handler)
+}
+
+// listRepos summarizes the repos in the store's dimension group. A SQLite
+// index only ever holds the current repo.
+func listRepos(store *embedding.EmbeddingStore, dbType db.DatabaseType) ([]repoSummary, error) {
+	counts, err := store.CountsByRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	if dbType != db.DatabasePostgres {
+		repo := repoSummary{RepoRoot: store.RepoID(), Embeddings: counts[store.RepoID()]}
+		if cfg, err := store.GetRepoConfig(store.RepoID()); err == nil && cfg != nil {
+			repo.Model, repo.Dimensions = cfg.Model, cfg.Dimensions
+		}
+		return []repoSummary{repo}, nil
+	}
+
+	configs, err := store.ListRepoConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	repos := make([]repoSummary, 0, len(configs))
+	for _, cfg := range configs {
+		repos = append(repos, repoSummary{
+			RepoRoot:   cfg.RepoRoot,
+			Model:      cfg.Model,
+			Dimensions: cfg.Dimensions,
+			Embeddings: counts[cfg.RepoRoot],
+		})
+		delete(counts, cfg.RepoRoot)
+	}
+
+	// Repos embedded without a recorded config still appear
+	unconfigured := make([]string, 0, len(counts))
+	for repoRoot := range counts {
+		unconfigured = append(unconfigured, repoRoot)
+	}
+	sort.Strings(unconfigured)
+	for _, repoRoot := range unconfigured {
+		repos = append(repos, repoSummary{RepoRoot: repoRoot, Embeddings: counts[repoRoot]})
+	}
+	return repos, nil
+}
+
+func registerHybridSearch(server *mcp.Server, cfg config.ToolConfig) {
+	defaults := hybrid.DefaultConfig()
+	keywordLimit := cfg.Limit(defaults.KeywordLimit)
+	semanticLimit := cfg.Limit(defaults.SemanticLimit)
+
 	tool := mcp.Tool{
 		Name:        "hybrid_search",
 		Description: "Search combining keyword (ripgrep) and semantic (embedding) search. Returns results from both approaches, ranked by combined score. Semantic search requires Ollama.",
@@ -108,12 +380,15 @@ func registerHybridSearch(server *mcp.Server) {
 				},
 				"keyword_limit": {
 					Type:        "number",
-					Description: "Max keyword results (default: 20)",
+					Description: fmt.Sprintf("Max keyword results (default: %d)", keywordLimit),
 				},
 				"semantic_limit": {
 					Type:        "number",
-					Description: "Max semantic results (default: 10)",
+					Description: fmt.Sprintf("Max semantic results (default: %d)", semanticLimit),
 				},
+				"context_lines": contextLinesProperty,
+				"exclude_tests": excludeTestsProperty,
+				"offset":        offsetProperty,
 			},
 			Required: []string{"query"},
 		},
@@ -126,18 +401,27 @@ func registerHybridSearch(server *mcp.Server) {
 		}
 
 		config := hybrid.DefaultConfig()
+		config.KeywordLimit = keywordLimit
+		config.SemanticLimit = semanticLimit
 		if kl, ok := args["keyword_limit"].(float64); ok {
 			config.KeywordLimit = int(kl)
 		}
 		if sl, ok := args["semantic_limit"].(float64); ok {
 			config.SemanticLimit = int(sl)
 		}
-		config.SnippetFn = getSnippetFn()
+		config.SnippetFn = getSnippetFn(contextLinesArg(args))
+		config.Offset = offsetArg(args)
 
 		// Try to open semantic searcher (optional)
 		var semanticSearcher *embedding.SemanticSearcher
-		if s, err := openSemanticSearcher(); err == nil && s.Available() {
-			semanticSearcher = s
+		if s, err := openSemanticSearcher(); err == nil {
+			defer s.Store().Close()
+			if s.Available() {
+				if exclude, ok := args["exclude_tests"].(bool); ok {
+					s.SetExcludeTests(exclude)
+				}
+				semanticSearcher = s
+			}
 		}
 
 		// Create hybrid searcher
@@ -173,8 +457,40 @@ func registerHybridSearch(server *mcp.Server) {
 
 // openSemanticSearcher creates a semantic searcher using the configured database.
 // It supports both SQLite and PostgreSQL based on environment configuration.
-// Falls back to SQLite if PostgreSQL is unavailable.
+// Falls back to SQLite if PostgreSQL is unavailable. The caller closes the
+// searcher's store when done.
 func openSemanticSearcher() (*embedding.SemanticSearcher, error) {
+	store, _, err := openConfiguredEmbeddingStore()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create embedder from environment configuration
+	embedder, err := embedding.NewEmbedderFromEnv()
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("creating embedder: %w", err)
+	}
+
+	// Create semantic searcher
+	searcher := embedding.NewSemanticSearcher(store, embedder)
+	if warning := searcher.ModelWarning(); warning != "" {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	retrieval := config.LoadSearchConfigFromEnv().Retrieval
+	if retrieval.KeywordPrefilter {
+		searcher.SetKeywordPrefilter(embedding.NewKeywordPrefilter(retrieval.PrefilterMinCandidates))
+	}
+	searcher.SetExcludeTests(retrieval.ExcludeTests)
+	searcher.SetRecencyWeight(retrieval.RecencyWeight)
+	searcher.SetScoreNormalization(retrieval.ScoreNormalization)
+	return searcher, nil
+}
+
+// openConfiguredEmbeddingStore opens the embedding store for the configured
+// database, falling back to SQLite if PostgreSQL is unavailable. It also
+// returns the database type actually opened.
+func openConfiguredEmbeddingStore() (*embedding.EmbeddingStore, db.DatabaseType, error) {
 	// Load database configuration from environment
 	dbConfig := config.LoadDatabaseConfigFromEnv()
 
@@ -188,25 +504,17 @@ func openSemanticSearcher() (*embedding.SemanticSearcher, error) {
 			// Fallback to SQLite
 			dbConfig.Type = db.DatabaseSQLite
 			cwd, _ := os.Getwd()
-			dbConfig.Path = filepath.Join(cwd, ".codetect", "symbols.db")
+			dbConfig.Path = filepath.Join(config.DataDir(cwd), "symbols.db")
 
 			store, err = openEmbeddingStore(dbConfig)
 			if err != nil {
-				return nil, fmt.Errorf("failed to open database (tried PostgreSQL and SQLite): %w", err)
+				return nil, "", fmt.Errorf("failed to open database (tried PostgreSQL and SQLite): %w", err)
 			}
 		} else {
-			return nil, err
+			return nil, "", err
 		}
 	}
-
-	// Create embedder from environment configuration
-	embedder, err := embedding.NewEmbedderFromEnv()
-	if err != nil {
-		return nil, fmt.Errorf("creating embedder: %w", err)
-	}
-
-	// Create semantic searcher
-	return embedding.NewSemanticSearcher(store, embedder), nil
+	return store, dbConfig.Type, nil
 }
 
 // openEmbeddingStore opens an embedding store with the given configuration.
@@ -244,7 +552,7 @@ func openEmbeddingStore(dbConfig config.DatabaseConfig) (*embedding.EmbeddingSto
 		// Determine database path
 		dbPath := dbConfig.Path
 		if dbPath == "" {
-			dbPath = filepath.Join(cwd, ".codetect", "symbols.db")
+			dbPath = filepath.Join(config.DataDir(cwd), "symbols.db")
 		}
 
 		// For SQLite, check if database exists
@@ -268,21 +576,102 @@ func openEmbeddingStore(dbConfig config.DatabaseConfig) (*embedding.EmbeddingSto
 	}
 }
 
-// getSnippetFn returns a function that reads code snippets from files
-func getSnippetFn() func(path string, start, end int) string {
+const (
+	// snippetMaxLen caps a snippet without context lines
+	snippetMaxLen = 500
+
+	// contextLineBudget is the extra snippet length allowed per context line
+	contextLineBudget = 120
+
+	// maxContextLines caps the context_lines tool argument
+	maxContextLines = 50
+)
+
+// contextLinesProperty describes the context_lines tool argument.
+var contextLinesProperty = mcp.Property{
+	Type:        "number",
+	Description: fmt.Sprintf("Lines of surrounding code to include before and after each semantic match (default: 0, max: %d)", maxContextLines),
+}
+
+// offsetProperty describes the offset tool argument.
+var offsetProperty = mcp.Property{
+	Type:        "number",
+	Description: "Skip this many ranked results, to page through them with limit; has_more in the response says whether another page follows (default: 0)",
+}
+
+// offsetArg reads the offset tool argument, clamped to be non-negative.
+func offsetArg(args map[string]any) int {
+	n, _ := args["offset"].(float64)
+	return max(int(n), 0)
+}
+
+// excludeTestsProperty describes the exclude_tests tool argument.
+var excludeTestsProperty = mcp.Property{
+	Type:        "boolean",
+	Description: "Leave test files (_test.go, *.spec.*, *.test.*, test/, tests/, __tests__/) out of semantic results (default: CODETECT_SEARCH_EXCLUDE_TESTS, normally false)",
+}
+
+// contextLinesArg reads the context_lines tool argument, clamped to [0, maxContextLines].
+func contextLinesArg(args map[string]any) int {
+	n, _ := args["context_lines"].(float64)
+	return min(max(int(n), 0), maxContextLines)
+}
+
+// getSnippetFn returns a function that reads code snippets from files,
+// widened by contextLines on each side
+func getSnippetFn(contextLines int) func(path string, start, end int) string {
 	return func(path string, start, end int) string {
-		result, err := files.GetFile(path, start, end)
-		if err != nil {
-			return fmt.Sprintf("[Error reading %s: %v]", path, err)
+		return readSnippet(path, start, end, contextLines)
+	}
+}
+
+// readSnippet reads lines start-end of path plus contextLines lines either
+// side, clamped to the file, and truncates the result.
+func readSnippet(path string, start, end, contextLines int) string {
+	from, to := start, end
+	if contextLines > 0 {
+		from = max(start-contextLines, 1)
+		if end > 0 {
+			to = end + contextLines // GetFile stops at the end of the file
 		}
+	}
 
-		snippet := result.Content
+	result, err := files.GetFile(path, from, to)
+	if err != nil {
+		return fmt.Sprintf("[Error reading %s: %v]", path, err)
+	}
 
-		// Truncate if too long
-		if len(snippet) > 500 {
-			snippet = snippet[:500] + "..."
-		}
+	snippet := result.Content
 
-		return snippet
+	// Truncate if too long, leaving room for the requested context
+	if maxLen := snippetMaxLen + 2*contextLines*contextLineBudget; len(snippet) > maxLen {
+		snippet = snippet[:maxLen] + "..."
+	}
+
+	return snippet
+}
+
+// getRepoSnippetFn returns a function that reads cross-repo snippets from
+// disk when the repo is checked out locally: the current repo, or a
+// repo_root that is an existing absolute path. Other repos get no snippet.
+func getRepoSnippetFn(contextLines int) func(repoRoot, path string, start, end int) string {
+	cwd, _ := os.Getwd()
+	currentRepo := config.RepoID(cwd)
+	snippetFn := getSnippetFn(contextLines)
+
+	return func(repoRoot, path string, start, end int) string {
+		dir := ""
+		switch {
+		case repoRoot == currentRepo:
+			dir = cwd
+		case filepath.IsAbs(repoRoot):
+			if info, err := os.Stat(repoRoot); err == nil && info.IsDir() {
+				dir = repoRoot
+			}
+		}
+		if dir == "" {
+			return ""
+		}
+		return snippetFn(filepath.Join(dir, path), start, end)
 	}
 }
@@ -16,20 +16,19 @@ import (
 	"codetect/internal/indexer"
 	"codetect/internal/mcp"
 	"codetect/internal/rerank"
-	"codetect/internal/search/files"
 	"codetect/internal/search/keyword"
 )
 
 // RegisterV2SemanticTools registers the v2 semantic search MCP tools.
 // These tools use the new retriever with RRF fusion and optional reranking.
-func RegisterV2SemanticTools(server *mcp.Server) {
-	registerHybridSearchV2(server)
+func RegisterV2SemanticTools(server *mcp.Server, cfg config.ToolConfig) {
+	registerHybridSearchV2(server, cfg.Limit(20))
 }
 
-func registerHybridSearchV2(server *mcp.Server) {
+func registerHybridSearchV2(server *mcp.Server, defaultLimit int) {
 	tool := mcp.Tool{
 		Name:        "hybrid_search_v2",
-		Description: "v2 hybrid search combining keyword, semantic, and symbol search with RRF fusion. Uses AST-based chunking and content-addressed caching. Optionally applies cross-encoder reranking for higher precision.",
+		Description: "v2 hybrid search combining keyword, semantic, and symbol search with RRF fusion. Uses AST-based chunking and content-addressed caching. Optionally applies cross-encoder reranking for higher precision. Falls back to the v1 embedding index when no v2 index exists; the response reports the backend used.",
 		InputSchema: mcp.InputSchema{
 			Type: "object",
 			Properties: map[string]mcp.Property{
@@ -39,12 +38,19 @@ func registerHybridSearchV2(server *mcp.Server) {
 				},
 				"limit": {
 					Type:        "number",
-					Description: "Max results to return (default: 20)",
+					Description: fmt.Sprintf("Max results to return (default: %d)", defaultLimit),
 				},
 				"rerank": {
 					Type:        "boolean",
 					Description: "Enable cross-encoder reranking for higher precision (default: false)",
 				},
+				"context_lines": contextLinesProperty,
+				"exclude_tests": excludeTestsProperty,
+				"offset":        offsetProperty,
+				"explain": {
+					Type:        "boolean",
+					Description: "Include a per-result score breakdown: each channel's rank, score and RRF contribution, plus the rerank change if applied (default: false)",
+				},
 			},
 			Required: []string{"query"},
 		},
@@ -56,7 +62,7 @@ func registerHybridSearchV2(server *mcp.Server) {
 			return nil, fmt.Errorf("query is required")
 		}
 
-		limit := 20
+		limit := defaultLimit
 		if l, ok := args["limit"].(float64); ok {
 			limit = int(l)
 		}
@@ -66,17 +72,29 @@ func registerHybridSearchV2(server *mcp.Server) {
 			enableRerank = r
 		}
 
+		explain := false
+		if e, ok := args["explain"].(bool); ok {
+			explain = e
+		}
+
 		// Get current working directory as repo root
 		repoRoot, err := os.Getwd()
 		if err != nil {
 			repoRoot = "."
 		}
 
-		ctx := context.Background()
-		start := time.Now()
+		opts := HybridSearchOptions{
+			Limit:        limit,
+			Offset:       offsetArg(args),
+			Rerank:       enableRerank,
+			Explain:      explain,
+			ContextLines: contextLinesArg(args),
+		}
+		if exclude, ok := args["exclude_tests"].(bool); ok {
+			opts.ExcludeTests = &exclude
+		}
 
-		// Open v2 indexer for search
-		idx, err := openV2Indexer(repoRoot)
+		response, err := HybridSearch(context.Background(), repoRoot, query, opts)
 		if err != nil {
 			return &mcp.ToolsCallResult{
 				Content: []mcp.Content{{
@@ -85,109 +103,109 @@ func registerHybridSearchV2(server *mcp.Server) {
 				}},
 			}, nil
 		}
-		defer idx.Close()
 
-		// Create native v2 semantic searcher
-		v2Searcher, err := createV2SemanticSearcher(idx, repoRoot)
-		semanticAvailable := err == nil && v2Searcher != nil && v2Searcher.Available()
+		data, err := json.Marshal(response)
+		if err != nil {
+			return nil, err
+		}
 
-		// Run keyword and semantic search in parallel
-		var keywordResults, semanticResults []fusion.Result
-		var keywordErr, semanticErr error
-		var wg sync.WaitGroup
+		return &mcp.ToolsCallResult{
+			Content: []mcp.Content{{
+				Type: "text",
+				Text: string(data),
+			}},
+		}, nil
+	}
 
-		// Keyword search
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			keywordResults, keywordErr = searchKeywordV2(ctx, query, repoRoot, limit)
-		}()
+	server.RegisterTool(tool, handler)
+}
 
-		// Semantic search using native v2 searcher
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			if v2Searcher == nil || !v2Searcher.Available() {
-				return
-			}
-			semanticResults, semanticErr = searchSemanticV2(ctx, v2Searcher, query, repoRoot, limit)
-		}()
+// HybridSearchOptions are the hybrid_search_v2 arguments besides the query.
+type HybridSearchOptions struct {
+	Limit        int
+	Offset       int   // Results to skip, for paging
+	Rerank       bool  // Apply cross-encoder reranking
+	Explain      bool  // Include per-result score breakdowns
+	ContextLines int   // Lines of context around each semantic snippet
+	ExcludeTests *bool // Overrides CODETECT_SEARCH_EXCLUDE_TESTS when set
+}
 
-		wg.Wait()
+// HybridSearch runs hybrid_search_v2 for query against the repo at
+// repoRoot: keyword and semantic search fused with RRF, optionally
+// reranked. It fails only if no index can be opened.
+func HybridSearch(ctx context.Context, repoRoot, query string, opts HybridSearchOptions) (*HybridSearchV2Result, error) {
+	start := time.Now()
 
-		// Log errors but continue (graceful degradation)
-		if keywordErr != nil {
-			// Non-fatal, just won't have keyword results
-			keywordResults = nil
-		}
-		if semanticErr != nil {
-			// Non-fatal, just won't have semantic results
-			semanticResults = nil
-		}
+	retriever, backend, err := openHybridRetriever(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	defer retriever.Close()
+	retriever.SetContextLines(opts.ContextLines)
+	if opts.ExcludeTests != nil {
+		retriever.SetExcludeTests(*opts.ExcludeTests)
+	}
 
-		// Fuse results with RRF
-		weights := config.DefaultRetrieverConfig().Weights
-		fusedResults := fusion.WeightedRRF(weights, keywordResults, semanticResults, nil)
+	// Rank enough results to cover every page up to the requested one
+	offset := max(opts.Offset, 0)
+	pool := offset + opts.Limit
 
-		// Limit fused results
-		if len(fusedResults) > limit*2 {
-			fusedResults = fusedResults[:limit*2]
-		}
+	channels := retriever.Retrieve(ctx, query, pool)
+	keywordResults, semanticResults := channels.Keyword, channels.Semantic
+	semanticAvailable := retriever.SemanticAvailable()
 
-		// Optionally apply reranking
-		if enableRerank && len(fusedResults) > 0 {
-			rerankCfg := config.DefaultRerankerConfig()
-			rerankCfg.Enabled = true
-			rerankCfg.TopK = limit
+	// Fuse results with RRF
+	weights := config.LoadRetrieverConfigFromEnv().Weights
+	fusedResults := fusion.WeightedRRF(weights, keywordResults, semanticResults, nil)
 
-			reranker := rerank.NewReranker(rerankCfg)
+	// Limit fused results
+	if len(fusedResults) > pool*2 {
+		fusedResults = fusedResults[:pool*2]
+	}
+	hasMore := len(fusedResults) > pool // Before reranking trims to pool
 
-			// Build contents map from snippets
-			contents := make(map[string]string)
-			for _, r := range fusedResults {
-				if r.Snippet != "" {
-					contents[r.ID] = r.Snippet
-				}
-			}
+	if opts.Explain {
+		fusedResults = fusion.Explain(fusedResults, weights, keywordResults, semanticResults, nil)
+	}
 
-			rerankResult, err := reranker.Rerank(ctx, query, fusedResults, contents)
-			if err == nil {
-				fusedResults = rerankResult.Results
-			}
-		}
+	// Optionally apply reranking
+	if opts.Rerank && len(fusedResults) > 0 {
+		rerankCfg := config.DefaultRerankerConfig()
+		rerankCfg.Enabled = true
+		rerankCfg.TopK = pool
 
-		// Apply final limit
-		if len(fusedResults) > limit {
-			fusedResults = fusedResults[:limit]
-		}
+		reranker := rerank.NewReranker(rerankCfg)
 
-		// Build response
-		response := HybridSearchV2Result{
-			Query:             query,
-			Results:           fusedResults,
-			KeywordCount:      len(keywordResults),
-			SemanticCount:     len(semanticResults),
-			SymbolCount:       0, // Symbol search not implemented for v2 yet
-			SemanticAvailable: semanticAvailable,
-			SymbolAvailable:   false,
-			Reranked:          enableRerank,
-			Duration:          time.Since(start).String(),
+		// Build contents map from snippets
+		contents := make(map[string]string)
+		for _, r := range fusedResults {
+			if r.Snippet != "" {
+				contents[r.ID] = r.Snippet
+			}
 		}
 
-		data, err := json.Marshal(response)
-		if err != nil {
-			return nil, err
+		rerankResult, err := reranker.Rerank(ctx, query, fusedResults, contents)
+		if err == nil {
+			fusedResults = rerankResult.Results
 		}
-
-		return &mcp.ToolsCallResult{
-			Content: []mcp.Content{{
-				Type: "text",
-				Text: string(data),
-			}},
-		}, nil
 	}
 
-	server.RegisterTool(tool, handler)
+	// Apply final limit, skipping earlier pages
+	fusedResults = fusedResults[min(offset, len(fusedResults)):min(pool, len(fusedResults))]
+
+	return &HybridSearchV2Result{
+		Query:             query,
+		Results:           fusedResults,
+		KeywordCount:      len(keywordResults),
+		SemanticCount:     len(semanticResults),
+		SymbolCount:       0, // Symbol search not implemented for v2 yet
+		SemanticAvailable: semanticAvailable,
+		SymbolAvailable:   false,
+		Reranked:          opts.Rerank,
+		HasMore:           hasMore,
+		Backend:           backend,
+		Duration:          time.Since(start).String(),
+	}, nil
 }
 
 // HybridSearchV2Result is the response format for v2 hybrid search.
@@ -200,37 +218,242 @@ type HybridSearchV2Result struct {
 	SemanticAvailable bool               `json:"semantic_available"`
 	SymbolAvailable   bool               `json:"symbol_available"`
 	Reranked          bool               `json:"reranked"`
+	HasMore           bool               `json:"has_more"` // More results follow this page (see offset)
+	Backend           string             `json:"backend"` // "v2", or "v1" when falling back to the v1 embedding store
 	Duration          string             `json:"duration"`
 }
 
+// hybridRetriever is the retrieval side of hybrid_search_v2, backed by
+// either the v2 index or, for repos not yet moved to it, the v1 store.
+type hybridRetriever interface {
+	Retrieve(ctx context.Context, query string, limit int) V2Channels
+	SetContextLines(n int)
+	SetExcludeTests(exclude bool)
+	SemanticAvailable() bool
+	Close() error
+}
+
+// openHybridRetriever opens the v2 index for repoRoot, falling back to the
+// v1 embedding store (populated by `codetect-index embed`) when there is no
+// v2 data but v1 embeddings exist. It returns the backend it chose.
+func openHybridRetriever(repoRoot string) (hybridRetriever, string, error) {
+	v2, err := OpenV2Retriever(repoRoot)
+	if err == nil && !v2.empty() {
+		return v2, "v2", nil
+	}
+
+	if v1, v1Err := openV1Retriever(repoRoot); v1Err == nil {
+		if v2 != nil {
+			v2.Close()
+		}
+		return v1, "v1", nil
+	}
+
+	if err != nil {
+		return nil, "", err
+	}
+	return v2, "v2", nil
+}
+
+// V2Channels holds the per-channel result lists that hybrid_search_v2 fuses.
+type V2Channels struct {
+	Keyword  []fusion.Result
+	Semantic []fusion.Result
+}
+
+// Lists returns the channel lists in fusion order (keyword, semantic, symbol).
+// Symbol search is not implemented for v2 yet, so the last list is nil.
+func (c V2Channels) Lists() [][]fusion.Result {
+	return [][]fusion.Result{c.Keyword, c.Semantic, nil}
+}
+
+// V2Retriever runs the hybrid_search_v2 retrieval channels against a
+// repository without fusing them. It keeps the index and embedder open,
+// so callers issuing many queries (such as evals) pay the setup cost once.
+type V2Retriever struct {
+	repoRoot     string
+	idx          *indexer.Indexer
+	searcher     *embedding.V2SemanticSearcher
+	contextLines int
+}
+
+// OpenV2Retriever opens the v2 index for repoRoot. Semantic search is
+// optional: if no embedder is available only keyword results are returned.
+func OpenV2Retriever(repoRoot string) (*V2Retriever, error) {
+	idx, err := openV2Indexer(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &V2Retriever{repoRoot: repoRoot, idx: idx}
+	if searcher, err := createV2SemanticSearcher(idx, repoRoot); err == nil && searcher.Available() {
+		r.searcher = searcher
+	}
+	return r, nil
+}
+
+// empty reports whether nothing has been indexed for the repo yet, as when
+// a shared PostgreSQL database has no v2 rows for it.
+func (r *V2Retriever) empty() bool {
+	count, err := r.idx.Locations().CountByRepo(config.RepoID(r.repoRoot))
+	return err == nil && count == 0
+}
+
+// SetContextLines widens each semantic snippet by n lines on either side.
+func (r *V2Retriever) SetContextLines(n int) {
+	r.contextLines = n
+}
+
+// SetExcludeTests overrides whether test files are left out of semantic
+// results.
+func (r *V2Retriever) SetExcludeTests(exclude bool) {
+	if r.searcher != nil {
+		r.searcher.SetExcludeTests(exclude)
+	}
+}
+
+// SemanticAvailable reports whether semantic results will be returned.
+func (r *V2Retriever) SemanticAvailable() bool {
+	return r.searcher != nil
+}
+
+// Retrieve runs keyword and semantic search in parallel, returning up to
+// limit results per channel. A failing channel is left empty.
+func (r *V2Retriever) Retrieve(ctx context.Context, query string, limit int) V2Channels {
+	var channels V2Channels
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// Non-fatal on error, just won't have keyword results
+		channels.Keyword, _ = searchKeywordV2(ctx, query, r.repoRoot, limit)
+	}()
+
+	if r.searcher != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Non-fatal on error, just won't have semantic results
+			channels.Semantic, _ = searchSemanticV2(ctx, r.searcher, query, r.repoRoot, limit, r.contextLines)
+		}()
+	}
+
+	wg.Wait()
+	return channels
+}
+
+// Close releases the underlying index.
+func (r *V2Retriever) Close() error {
+	return r.idx.Close()
+}
+
+// v1Retriever runs the hybrid_search_v2 channels against the v1 embedding
+// store, so repos embedded with `codetect-index embed` can use the tool
+// before they are reindexed with --v2.
+type v1Retriever struct {
+	repoRoot     string
+	searcher     *embedding.SemanticSearcher
+	contextLines int
+}
+
+// openV1Retriever opens the v1 embedding store, failing unless it holds
+// embeddings to search.
+func openV1Retriever(repoRoot string) (*v1Retriever, error) {
+	searcher, err := openSemanticSearcher()
+	if err != nil {
+		return nil, err
+	}
+	count, err := searcher.Store().Count()
+	if err != nil {
+		searcher.Store().Close()
+		return nil, err
+	}
+	if count == 0 {
+		searcher.Store().Close()
+		return nil, fmt.Errorf("no v1 embeddings found")
+	}
+	return &v1Retriever{repoRoot: repoRoot, searcher: searcher}, nil
+}
+
+// SetContextLines widens each semantic snippet by n lines on either side.
+func (r *v1Retriever) SetContextLines(n int) {
+	r.contextLines = n
+}
+
+// SetExcludeTests overrides whether test files are left out of semantic
+// results.
+func (r *v1Retriever) SetExcludeTests(exclude bool) {
+	r.searcher.SetExcludeTests(exclude)
+}
+
+// SemanticAvailable reports whether semantic results will be returned.
+func (r *v1Retriever) SemanticAvailable() bool {
+	return r.searcher.Available()
+}
+
+// Retrieve runs keyword and v1 semantic search in parallel, returning up to
+// limit results per channel. A failing channel is left empty.
+func (r *v1Retriever) Retrieve(ctx context.Context, query string, limit int) V2Channels {
+	var channels V2Channels
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		channels.Keyword, _ = searchKeywordV2(ctx, query, r.repoRoot, limit)
+	}()
+
+	if r.SemanticAvailable() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			channels.Semantic, _ = r.searchSemantic(ctx, query, limit)
+		}()
+	}
+
+	wg.Wait()
+	return channels
+}
+
+// searchSemantic runs the v1 searcher and converts its results for fusion.
+func (r *v1Retriever) searchSemantic(ctx context.Context, query string, limit int) ([]fusion.Result, error) {
+	response, err := r.searcher.SearchWithSnippets(ctx, query, limit, func(path string, start, end int) string {
+		return readSnippet(filepath.Join(r.repoRoot, path), start, end, r.contextLines)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !response.Available {
+		return nil, nil
+	}
+
+	fusionResults := make([]fusion.Result, 0, len(response.Results))
+	for _, res := range response.Results {
+		fusionResults = append(fusionResults, fusion.Result{
+			ID:      fmt.Sprintf("%s:%d:%d", res.Path, res.StartLine, res.EndLine),
+			Path:    res.Path,
+			Line:    res.StartLine,
+			EndLine: res.EndLine,
+			Score:   float64(res.Score),
+			Source:  "semantic",
+			Snippet: res.Snippet,
+		})
+	}
+	return fusionResults, nil
+}
+
+// Close releases the v1 embedding store, which the retriever opened itself.
+func (r *v1Retriever) Close() error {
+	return r.searcher.Store().Close()
+}
+
 // openV2Indexer opens a v2 indexer for the given repository.
 func openV2Indexer(repoRoot string) (*indexer.Indexer, error) {
-	// Load database configuration from environment
-	dbConfig := config.LoadDatabaseConfigFromEnv()
-	embConfig := embedding.LoadConfigFromEnv()
-
-	// Build indexer config
-	cfg := &indexer.Config{
-		DBType:            string(dbConfig.Type),
-		Dimensions:        dbConfig.VectorDimensions,
-		EmbeddingProvider: string(embConfig.Provider),
-		EmbeddingModel:    embConfig.Model,
-		OllamaURL:         embConfig.OllamaURL,
-		LiteLLMURL:        embConfig.LiteLLMURL,
-		LiteLLMKey:        embConfig.LiteLLMKey,
-		BatchSize:         32,
-		MaxWorkers:        4,
-	}
-
-	// Set database path/DSN
-	if dbConfig.Type == dbpkg.DatabasePostgres {
-		cfg.DSN = dbConfig.DSN
-	} else {
-		cfg.DBPath = filepath.Join(repoRoot, ".codetect", "index.db")
-	}
+	cfg := indexer.LoadConfigFromEnv(repoRoot)
 
 	// Check if v2 index exists
-	if dbConfig.Type == dbpkg.DatabaseSQLite {
+	if cfg.DBType == string(dbpkg.DatabaseSQLite) {
 		if _, err := os.Stat(cfg.DBPath); os.IsNotExist(err) {
 			return nil, fmt.Errorf("no v2 index found - run 'codetect-index index --v2' first")
 		}
@@ -268,7 +491,14 @@ func createV2SemanticSearcher(idx *indexer.Indexer, repoRoot string) (*embedding
 	vectorIndex := idx.VectorIndex()
 
 	// Create native v2 semantic searcher
-	return embedding.NewV2SemanticSearcher(cache, locations, embedder, repoRoot, vectorIndex), nil
+	searcher := embedding.NewV2SemanticSearcher(cache, locations, embedder, repoRoot, vectorIndex)
+	retrieval := config.LoadRetrieverConfigFromEnv()
+	searcher.SetDocCommentWeight(retrieval.DocCommentWeight)
+	searcher.SetCandidateLimits(retrieval.CandidateMultiplier, retrieval.MaxCandidates)
+	searcher.SetNodeTypeWeights(retrieval.NodeTypeWeights)
+	searcher.SetExcludeTests(retrieval.ExcludeTests)
+	searcher.SetRecencyWeight(retrieval.RecencyWeight)
+	return searcher, nil
 }
 
 // searchKeywordV2 performs keyword search and returns results in fusion format.
@@ -300,7 +530,7 @@ func searchKeywordV2(ctx context.Context, query, repoRoot string, limit int) ([]
 }
 
 // searchSemanticV2 performs semantic search using the native v2 searcher.
-func searchSemanticV2(ctx context.Context, searcher *embedding.V2SemanticSearcher, query, repoRoot string, limit int) ([]fusion.Result, error) {
+func searchSemanticV2(ctx context.Context, searcher *embedding.V2SemanticSearcher, query, repoRoot string, limit, contextLines int) ([]fusion.Result, error) {
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
@@ -309,15 +539,7 @@ func searchSemanticV2(ctx context.Context, searcher *embedding.V2SemanticSearche
 
 	// Use SearchWithSnippets to include code snippets
 	response, err := searcher.SearchWithSnippets(ctx, query, limit, func(path string, start, end int) string {
-		result, err := files.GetFile(filepath.Join(repoRoot, path), start, end)
-		if err != nil {
-			return fmt.Sprintf("[Error reading %s: %v]", path, err)
-		}
-		snippet := result.Content
-		if len(snippet) > 500 {
-			snippet = snippet[:500] + "..."
-		}
-		return snippet
+		return readSnippet(filepath.Join(repoRoot, path), start, end, contextLines)
 	})
 	if err != nil {
 		return nil, err
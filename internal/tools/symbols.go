@@ -13,12 +13,15 @@ import (
 )
 
 // RegisterSymbolTools registers the symbol-related MCP tools
-func RegisterSymbolTools(server *mcp.Server) {
-	registerFindSymbol(server)
+func RegisterSymbolTools(server *mcp.Server, cfg config.ToolConfig) {
+	registerFindSymbol(server, cfg.Limit(50))
 	registerListDefsInFile(server)
+	registerListSymbolsByKind(server, cfg.Limit(100))
+	registerListIndexedFiles(server)
+	registerRelatedFiles(server)
 }
 
-func registerFindSymbol(server *mcp.Server) {
+func registerFindSymbol(server *mcp.Server, defaultLimit int) {
 	tool := mcp.Tool{
 		Name:        "find_symbol",
 		Description: "Find symbol definitions (functions, types, variables, etc.) by name. Uses fuzzy matching.",
@@ -27,24 +30,32 @@ func registerFindSymbol(server *mcp.Server) {
 			Properties: map[string]mcp.Property{
 				"name": {
 					Type:        "string",
-					Description: "Symbol name to search for (supports partial matching)",
+					Description: "Symbol name to search for (supports partial matching). May be empty with scope, to list the scope's members",
 				},
 				"kind": {
 					Type:        "string",
-					Description: "Filter by symbol kind: function, type, class, struct, interface, variable, constant",
+					Description: "Filter by symbol kind: function, method, class, struct, interface, enum, type, module, variable, constant",
+				},
+				"scope": {
+					Type:        "string",
+					Description: "Only symbols inside this class, struct, etc., e.g. UserService (filled in by ctags)",
+				},
+				"path": {
+					Type:        "string",
+					Description: "Only symbols in files matching this repo-relative glob, e.g. internal/http/ or **/*_test.go",
 				},
 				"limit": {
 					Type:        "number",
-					Description: "Maximum number of results (default: 50)",
+					Description: fmt.Sprintf("Maximum number of results (default: %d)", defaultLimit),
 				},
 			},
-			Required: []string{"name"},
 		},
 	}
 
 	handler := func(args map[string]any) (*mcp.ToolsCallResult, error) {
-		name, ok := args["name"].(string)
-		if !ok || name == "" {
+		name, _ := args["name"].(string)
+		scope, _ := args["scope"].(string)
+		if name == "" && scope == "" {
 			return nil, fmt.Errorf("name is required")
 		}
 
@@ -53,7 +64,9 @@ func registerFindSymbol(server *mcp.Server) {
 			kind = k
 		}
 
-		limit := 50
+		path, _ := args["path"].(string)
+
+		limit := defaultLimit
 		if l, ok := args["limit"].(float64); ok {
 			limit = int(l)
 		}
@@ -71,7 +84,7 @@ func registerFindSymbol(server *mcp.Server) {
 		defer idx.Close()
 
 		// Search for symbols
-		syms, err := idx.FindSymbol(name, kind, limit)
+		syms, err := idx.FindSymbol(name, symbols.SymbolFilter{Kind: kind, Scope: scope, Path: path}, limit)
 		if err != nil {
 			return nil, fmt.Errorf("searching symbols: %w", err)
 		}
@@ -157,6 +170,218 @@ func registerListDefsInFile(server *mcp.Server) {
 	server.RegisterTool(tool, handler)
 }
 
+func registerListSymbolsByKind(server *mcp.Server, defaultLimit int) {
+	tool := mcp.Tool{
+		Name:        "list_symbols_by_kind",
+		Description: "List every symbol of one kind across the repo, ordered by file, for an overview such as the main interfaces or types. Use find_symbol to look up a name instead.",
+		InputSchema: mcp.InputSchema{
+			Type: "object",
+			Properties: map[string]mcp.Property{
+				"kind": {
+					Type:        "string",
+					Description: "Symbol kind: function, method, class, struct, interface, enum, type, module, variable, constant",
+				},
+				"group_by_file": {
+					Type:        "boolean",
+					Description: "Return symbols grouped under files instead of as one list (default: false)",
+				},
+				"limit": {
+					Type:        "number",
+					Description: fmt.Sprintf("Maximum number of symbols (default: %d)", defaultLimit),
+				},
+				"offset": offsetProperty,
+			},
+			Required: []string{"kind"},
+		},
+	}
+
+	handler := func(args map[string]any) (*mcp.ToolsCallResult, error) {
+		kind, ok := args["kind"].(string)
+		if !ok || kind == "" {
+			return nil, fmt.Errorf("kind is required")
+		}
+
+		limit := defaultLimit
+		if l, ok := args["limit"].(float64); ok {
+			limit = int(l)
+		}
+
+		idx, err := openIndex()
+		if err != nil {
+			return &mcp.ToolsCallResult{
+				Content: []mcp.Content{{
+					Type: "text",
+					Text: fmt.Sprintf(`{"available": false, "error": %q}`, err.Error()),
+				}},
+			}, nil
+		}
+		defer idx.Close()
+
+		syms, hasMore, err := idx.ListByKind(kind, offsetArg(args), limit)
+		if err != nil {
+			return nil, fmt.Errorf("listing symbols: %w", err)
+		}
+
+		result := symbols.ListSymbolsResult{
+			Kind:    symbols.CanonicalKind(kind),
+			Symbols: syms,
+			HasMore: hasMore,
+		}
+		if group, _ := args["group_by_file"].(bool); group {
+			result.Symbols = nil
+			result.Files = symbols.GroupByFile(syms)
+		}
+
+		data, err := json.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+
+		return &mcp.ToolsCallResult{
+			Content: []mcp.Content{{
+				Type: "text",
+				Text: string(data),
+			}},
+		}, nil
+	}
+
+	server.RegisterTool(tool, handler)
+}
+
+func registerListIndexedFiles(server *mcp.Server) {
+	tool := mcp.Tool{
+		Name:        "list_indexed_files",
+		Description: "List the files covered by the index: files holds every path in the symbol index, embedded every path with semantic embeddings. Use it to check what is covered before searching. Takes no arguments.",
+		InputSchema: mcp.InputSchema{
+			Type:       "object",
+			Properties: map[string]mcp.Property{},
+		},
+	}
+
+	handler := func(args map[string]any) (*mcp.ToolsCallResult, error) {
+		idx, err := openIndex()
+		if err != nil {
+			return &mcp.ToolsCallResult{
+				Content: []mcp.Content{{
+					Type: "text",
+					Text: fmt.Sprintf(`{"available": false, "error": %q}`, err.Error()),
+				}},
+			}, nil
+		}
+		defer idx.Close()
+
+		files, err := idx.ListFiles()
+		if err != nil {
+			return nil, fmt.Errorf("listing files: %w", err)
+		}
+
+		result := map[string]any{"files": files}
+
+		// Embeddings are optional; leave them out if there's no store
+		if store, _, err := openConfiguredEmbeddingStore(); err == nil {
+			defer store.Close()
+			if embedded, err := store.ListPaths(); err == nil {
+				result["embedded"] = embedded
+			}
+		}
+
+		data, err := json.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+
+		return &mcp.ToolsCallResult{
+			Content: []mcp.Content{{
+				Type: "text",
+				Text: string(data),
+			}},
+		}, nil
+	}
+
+	server.RegisterTool(tool, handler)
+}
+
+func registerRelatedFiles(server *mcp.Server) {
+	tool := mcp.Tool{
+		Name:        "related_files",
+		Description: "List the files related to a file through imports: imports holds the repo files it imports, imported_by the files that import it, and external its imports of packages outside the repo. Covers Go, Python and JavaScript/TypeScript.",
+		InputSchema: mcp.InputSchema{
+			Type: "object",
+			Properties: map[string]mcp.Property{
+				"path": {
+					Type:        "string",
+					Description: "File path to find related files for",
+				},
+			},
+			Required: []string{"path"},
+		},
+	}
+
+	handler := func(args map[string]any) (*mcp.ToolsCallResult, error) {
+		path, ok := args["path"].(string)
+		if !ok || path == "" {
+			return nil, fmt.Errorf("path is required")
+		}
+
+		idx, err := openIndex()
+		if err != nil {
+			return &mcp.ToolsCallResult{
+				Content: []mcp.Content{{
+					Type: "text",
+					Text: fmt.Sprintf(`{"available": false, "error": %q}`, err.Error()),
+				}},
+			}, nil
+		}
+		defer idx.Close()
+
+		imports, err := idx.Imports(path)
+		if err != nil {
+			return nil, fmt.Errorf("listing imports: %w", err)
+		}
+		importedBy, err := idx.ImportedBy(path)
+		if err != nil {
+			return nil, fmt.Errorf("listing importers: %w", err)
+		}
+
+		result := symbols.RelatedFilesResult{
+			Path:       path,
+			Imports:    []string{},
+			ImportedBy: []string{},
+		}
+		seen := make(map[string]bool)
+		for _, e := range imports {
+			switch {
+			case e.Target == "":
+				result.External = append(result.External, e.Spec)
+			case !seen[e.Target]:
+				seen[e.Target] = true
+				result.Imports = append(result.Imports, e.Target)
+			}
+		}
+		clear(seen)
+		for _, e := range importedBy {
+			if !seen[e.Path] {
+				seen[e.Path] = true
+				result.ImportedBy = append(result.ImportedBy, e.Path)
+			}
+		}
+
+		data, err := json.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+
+		return &mcp.ToolsCallResult{
+			Content: []mcp.Content{{
+				Type: "text",
+				Text: string(data),
+			}},
+		}, nil
+	}
+
+	server.RegisterTool(tool, handler)
+}
+
 // openIndex opens the symbol index for the current working directory.
 // Uses database configuration from environment variables, supporting both
 // SQLite (default) and PostgreSQL backends.
@@ -172,7 +397,7 @@ func openIndex() (*symbols.Index, error) {
 
 	// For SQLite, use path relative to current working directory
 	if dbConfig.Type == db.DatabaseSQLite {
-		dbPath := filepath.Join(cwd, ".codetect", "symbols.db")
+		dbPath := filepath.Join(config.DataDir(cwd), "symbols.db")
 		if _, err := os.Stat(dbPath); os.IsNotExist(err) {
 			return nil, fmt.Errorf("no symbol index found - run 'make index' first")
 		}
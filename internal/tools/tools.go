@@ -5,21 +5,26 @@ import (
 	"fmt"
 	"os"
 
+	"codetect/internal/config"
 	"codetect/internal/mcp"
 	"codetect/internal/search/files"
 	"codetect/internal/search/keyword"
 )
 
-// RegisterAll registers all available tools on the MCP server
+// RegisterAll registers all available tools on the MCP server.
+// Tool defaults such as CODETECT_DEFAULT_LIMIT are read once, here.
 func RegisterAll(server *mcp.Server) {
-	registerSearchKeyword(server)
+	cfg := config.LoadToolConfigFromEnv()
+	server.SetStructuredContent(cfg.StructuredContent)
+	registerSearchKeyword(server, cfg.Limit(20))
 	registerGetFile(server)
-	RegisterSymbolTools(server)
-	RegisterSemanticTools(server)
-	RegisterV2SemanticTools(server) // v2 tools with RRF fusion
+	RegisterSymbolTools(server, cfg)
+	RegisterSemanticTools(server, cfg)
+	RegisterV2SemanticTools(server, cfg) // v2 tools with RRF fusion
+	registerBatchSearch(server)          // Dispatches to the tools above
 }
 
-func registerSearchKeyword(server *mcp.Server) {
+func registerSearchKeyword(server *mcp.Server, defaultLimit int) {
 	tool := mcp.Tool{
 		Name:        "search_keyword",
 		Description: "Search for a keyword/pattern in the codebase using ripgrep. Returns matching files with line numbers and snippets.",
@@ -32,7 +37,7 @@ func registerSearchKeyword(server *mcp.Server) {
 				},
 				"top_k": {
 					Type:        "number",
-					Description: "Maximum number of results to return (default: 20)",
+					Description: fmt.Sprintf("Maximum number of results to return (default: %d)", defaultLimit),
 				},
 			},
 			Required: []string{"query"},
@@ -45,7 +50,7 @@ func registerSearchKeyword(server *mcp.Server) {
 			return nil, fmt.Errorf("query is required")
 		}
 
-		topK := 20
+		topK := defaultLimit
 		if tk, ok := args["top_k"].(float64); ok {
 			topK = int(tk)
 		}
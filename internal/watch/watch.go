@@ -0,0 +1,301 @@
+// Package watch provides recursive, gitignore-aware file watching with
+// per-root debouncing. It is shared by the background daemon and the
+// foreground `codetect-index watch` command.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	ignore "github.com/sabhiram/go-gitignore"
+
+	"codetect/internal/config"
+)
+
+// DefaultDebounce is the quiet period used when no debounce is configured
+const DefaultDebounce = 500 * time.Millisecond
+
+// MaxWatchesPerRoot limits file watchers to prevent file descriptor exhaustion
+const MaxWatchesPerRoot = 1000
+
+// Watcher watches directory trees and reports debounced changes per root
+type Watcher struct {
+	fsw         *fsnotify.Watcher
+	debounce    time.Duration
+	logger      *slog.Logger
+	ignoredDirs map[string]bool
+	indexCfg    config.IndexConfig // MaxDepth and OneFileSystem bound the watched tree
+	debounceMap map[string]*time.Timer
+	debounceMu  sync.Mutex
+}
+
+// New creates a watcher that waits for debounce of inactivity before
+// reporting a change. A non-positive debounce uses DefaultDebounce.
+func New(debounce time.Duration, logger *slog.Logger) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create watcher: %w", err)
+	}
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+	return &Watcher{
+		fsw:         fsw,
+		debounce:    debounce,
+		logger:      logger,
+		ignoredDirs: config.IgnoredDirs(),
+		indexCfg:    config.LoadIndexConfigFromEnv(),
+		debounceMap: make(map[string]*time.Timer),
+	}, nil
+}
+
+// AddTree adds watches for all non-ignored directories under root.
+// Returns the number of directories watched.
+func (w *Watcher) AddTree(root string) (int, error) {
+	count := 0
+	var limitReached bool
+
+	// Load gitignore patterns for this root
+	gi := LoadGitignore(root)
+	guard := config.NewWalkGuard(root, w.indexCfg.OneFileSystem)
+
+	err := filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip errors
+		}
+		if entry.IsDir() {
+			// Check configured ignore list first
+			if w.ignoredDirs[entry.Name()] {
+				return filepath.SkipDir
+			}
+
+			// Check gitignore patterns and depth, as the indexer does
+			if relPath, err := filepath.Rel(root, path); err == nil {
+				if gi != nil && gi.MatchesPath(relPath+"/") {
+					return filepath.SkipDir
+				}
+				if w.indexCfg.BeyondMaxDepth(relPath) {
+					return filepath.SkipDir
+				}
+			}
+			if info, err := entry.Info(); err == nil && guard.SkipDir(info) {
+				return filepath.SkipDir
+			}
+
+			if count >= MaxWatchesPerRoot {
+				if !limitReached {
+					w.logger.Warn("reached max watches limit", "limit", MaxWatchesPerRoot, "root", root)
+					limitReached = true
+				}
+				return filepath.SkipDir
+			}
+			if err := w.fsw.Add(path); err != nil {
+				return nil // Skip errors
+			}
+			count++
+		}
+		return nil
+	})
+	w.logger.Debug("added watches", "count", count, "root", root)
+	return count, err
+}
+
+// RemoveTree removes all watches under root
+func (w *Watcher) RemoveTree(root string) {
+	for _, path := range w.fsw.WatchList() {
+		if path == root || IsSubpath(path, root) {
+			w.fsw.Remove(path)
+		}
+	}
+
+	// Drop any pending change for this root
+	w.debounceMu.Lock()
+	if timer, ok := w.debounceMap[root]; ok {
+		timer.Stop()
+		delete(w.debounceMap, root)
+	}
+	w.debounceMu.Unlock()
+}
+
+// WatchList returns the directories currently being watched
+func (w *Watcher) WatchList() []string {
+	return w.fsw.WatchList()
+}
+
+// Run processes file system events until ctx is cancelled or the watcher
+// is closed. resolve maps a changed path to the root it belongs to (empty
+// to ignore the event); onChange is called with that root once changes
+// have been quiet for the debounce period.
+func (w *Watcher) Run(ctx context.Context, resolve func(path string) string, onChange func(root string)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			w.handleEvent(event, resolve, onChange)
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			w.logger.Error("watcher error", "error", err)
+		}
+	}
+}
+
+// handleEvent processes a file system event with debouncing
+func (w *Watcher) handleEvent(event fsnotify.Event, resolve func(string) string, onChange func(string)) {
+	// Skip non-code files
+	if !IsCodeFile(event.Name) && !event.Has(fsnotify.Create) {
+		return
+	}
+
+	// Find which root this event belongs to
+	root := resolve(event.Name)
+
+	// Handle new directories
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			if !w.ignoredDirs[filepath.Base(event.Name)] && !w.beyondMaxDepth(root, event.Name) && !w.offDevice(root, info) {
+				w.fsw.Add(event.Name)
+			}
+		}
+	}
+
+	if root == "" {
+		return
+	}
+
+	// Debounce: reset timer for this root
+	w.debounceMu.Lock()
+	if timer, ok := w.debounceMap[root]; ok {
+		timer.Stop()
+	}
+	w.debounceMap[root] = time.AfterFunc(w.debounce, func() {
+		w.debounceMu.Lock()
+		delete(w.debounceMap, root)
+		w.debounceMu.Unlock()
+
+		onChange(root)
+	})
+	w.debounceMu.Unlock()
+}
+
+// beyondMaxDepth reports whether dir is deeper under root than the
+// configured max depth. Directories outside any known root are not limited.
+func (w *Watcher) beyondMaxDepth(root, dir string) bool {
+	if root == "" {
+		return false
+	}
+	rel, err := filepath.Rel(root, dir)
+	return err == nil && w.indexCfg.BeyondMaxDepth(rel)
+}
+
+// offDevice reports whether a new directory is a mount that OneFileSystem
+// keeps out of the watch on root
+func (w *Watcher) offDevice(root string, info os.FileInfo) bool {
+	if root == "" || !w.indexCfg.OneFileSystem {
+		return false
+	}
+	return config.NewWalkGuard(root, true).SkipDir(info)
+}
+
+// Close stops all pending debounce timers and releases the watcher
+func (w *Watcher) Close() error {
+	w.debounceMu.Lock()
+	for root, timer := range w.debounceMap {
+		timer.Stop()
+		delete(w.debounceMap, root)
+	}
+	w.debounceMu.Unlock()
+	return w.fsw.Close()
+}
+
+// IsCodeFile returns true if a change to the file should trigger reindexing
+func IsCodeFile(path string) bool {
+	ext := filepath.Ext(path)
+	codeExts := map[string]bool{
+		".go":    true,
+		".js":    true,
+		".ts":    true,
+		".tsx":   true,
+		".jsx":   true,
+		".py":    true,
+		".java":  true,
+		".c":     true,
+		".cpp":   true,
+		".h":     true,
+		".hpp":   true,
+		".rs":    true,
+		".rb":    true,
+		".php":   true,
+		".swift": true,
+		".kt":    true,
+		".scala": true,
+		".cs":    true,
+		".md":    true,
+		".json":  true,
+		".yaml":  true,
+		".yml":   true,
+		".toml":  true,
+	}
+	return codeExts[ext]
+}
+
+// IsSubpath returns true if child is under parent
+func IsSubpath(child, parent string) bool {
+	rel, err := filepath.Rel(parent, child)
+	if err != nil {
+		return false
+	}
+	return len(rel) > 0 && rel[0] != '.'
+}
+
+// LoadGitignore loads gitignore patterns from local .gitignore and global ~/.gitignore
+func LoadGitignore(rootPath string) *ignore.GitIgnore {
+	var patterns []string
+
+	// Load global gitignore (~/.gitignore)
+	homeDir, err := os.UserHomeDir()
+	if err == nil {
+		globalGitignore := filepath.Join(homeDir, ".gitignore")
+		if content, err := os.ReadFile(globalGitignore); err == nil {
+			for _, line := range strings.Split(string(content), "\n") {
+				if line != "" && !isComment(line) {
+					patterns = append(patterns, line)
+				}
+			}
+		}
+	}
+
+	// Load local .gitignore
+	localGitignore := filepath.Join(rootPath, ".gitignore")
+	if content, err := os.ReadFile(localGitignore); err == nil {
+		for _, line := range strings.Split(string(content), "\n") {
+			if line != "" && !isComment(line) {
+				patterns = append(patterns, line)
+			}
+		}
+	}
+
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	return ignore.CompileIgnoreLines(patterns...)
+}
+
+// isComment returns true if line is a gitignore comment
+func isComment(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return len(trimmed) > 0 && trimmed[0] == '#'
+}
@@ -23,10 +23,12 @@ BIN_DIR="$INSTALL_PREFIX/bin"
 SHARE_DIR="$INSTALL_PREFIX/share/codetect"
 CONFIG_DIR="${XDG_CONFIG_HOME:-$HOME/.config}/codetect"
 CONFIG_FILE="$CONFIG_DIR/config.env"
-REGISTRY_FILE="$CONFIG_DIR/registry.json"
-PID_FILE="$CONFIG_DIR/daemon.pid"
-LOG_FILE="$CONFIG_DIR/daemon.log"
-SOCKET_PATH="/tmp/codetect-$(id -u).sock"
+REGISTRY_FILE="${CODETECT_REGISTRY:-$CONFIG_DIR/registry.json}"
+PID_FILE="${CODETECT_DAEMON_PID_FILE:-$CONFIG_DIR/daemon.pid}"
+LOG_FILE="${CODETECT_DAEMON_LOG_FILE:-$CONFIG_DIR/daemon.log}"
+SOCKET_PATH="${CODETECT_DAEMON_SOCKET:-/tmp/codetect-$(id -u).sock}"
+# IPC protocol version, must match daemon.ProtocolVersion
+DAEMON_PROTOCOL=1
 
 # Colors
 RED='\033[0;31m'
@@ -295,6 +297,15 @@ cmd_doctor() {
             error "Unknown provider: $provider"
             ;;
     esac
+    if [[ "$provider" != "off" && -x "$BIN_DIR/codetect-index" ]]; then
+        # Reachable isn't enough: check the embeddings themselves are sane
+        local selftest
+        if selftest=$("$BIN_DIR/codetect-index" embed --selftest 2>/dev/null); then
+            success "Self-test: $selftest"
+        else
+            warn "Self-test: ${selftest:-FAIL (no output)}"
+        fi
+    fi
     echo ""
 
     # Check config
@@ -397,8 +408,8 @@ daemon_start() {
         fi
     fi
 
-    # Ensure config directory exists
-    mkdir -p "$CONFIG_DIR"
+    # Ensure config and log directories exist
+    mkdir -p "$CONFIG_DIR" "$(dirname "$LOG_FILE")"
 
     # Check if daemon binary exists
     if [[ ! -x "$BIN_DIR/codetect-daemon" ]]; then
@@ -410,7 +421,9 @@ daemon_start() {
     echo -e "${CYAN}Starting daemon...${NC}"
 
     # Start daemon in background
-    nohup "$BIN_DIR/codetect-daemon" start --foreground >> "$LOG_FILE" 2>&1 &
+    nohup "$BIN_DIR/codetect-daemon" start --foreground \
+        --socket "$SOCKET_PATH" --pid-file "$PID_FILE" --log-file "$LOG_FILE" \
+        --registry "$REGISTRY_FILE" >> "$LOG_FILE" 2>&1 &
     local pid=$!
 
     # Wait for socket to be ready
@@ -439,7 +452,7 @@ daemon_stop() {
     echo -e "${CYAN}Stopping daemon...${NC}"
 
     # Send stop command via socket
-    echo '{"action":"stop"}' | nc -U "$SOCKET_PATH" 2>/dev/null || true
+    echo "{\"version\":$DAEMON_PROTOCOL,\"action\":\"stop\"}" | nc -U "$SOCKET_PATH" 2>/dev/null || true
 
     # Wait for socket to be removed
     local tries=0
@@ -467,7 +480,7 @@ daemon_status() {
         echo ""
 
         # Get status from daemon
-        local response=$(echo '{"action":"status"}' | nc -U "$SOCKET_PATH" 2>/dev/null)
+        local response=$(echo "{\"version\":$DAEMON_PROTOCOL,\"action\":\"status\"}" | nc -U "$SOCKET_PATH" 2>/dev/null)
         if [[ -n "$response" ]]; then
             echo "$response" | python3 -m json.tool 2>/dev/null || echo "$response"
         fi
@@ -491,7 +504,7 @@ daemon_logs() {
 }
 
 daemon_is_running() {
-    [[ -S "$SOCKET_PATH" ]] && echo '{"action":"status"}' | nc -U "$SOCKET_PATH" &>/dev/null
+    [[ -S "$SOCKET_PATH" ]] && echo "{\"version\":$DAEMON_PROTOCOL,\"action\":\"status\"}" | nc -U "$SOCKET_PATH" &>/dev/null
 }
 
 daemon_help() {
@@ -503,6 +516,9 @@ daemon_help() {
     echo "  status      Show daemon status"
     echo "  logs [n]    Show last n lines of logs (default: 50)"
     echo "  help        Show this help"
+    echo ""
+    echo "Set CODETECT_DAEMON_SOCKET, CODETECT_DAEMON_PID_FILE and"
+    echo "CODETECT_DAEMON_LOG_FILE to run or target another daemon instance."
 }
 
 #
@@ -525,6 +541,9 @@ cmd_registry() {
         stats)
             registry_stats
             ;;
+        auto-embed)
+            registry_auto_embed "$@"
+            ;;
         help|--help|-h)
             registry_help
             ;;
@@ -642,7 +661,7 @@ print(f'Added project: {project_name}')
 
     # If daemon is running, tell it to watch this project
     if daemon_is_running; then
-        echo "{\"action\":\"add\",\"path\":\"$path\"}" | nc -U "$SOCKET_PATH" &>/dev/null || true
+        echo "{\"version\":$DAEMON_PROTOCOL,\"action\":\"add\",\"path\":\"$path\"}" | nc -U "$SOCKET_PATH" &>/dev/null || true
         info "Daemon will watch this project"
     fi
 }
@@ -683,7 +702,7 @@ print(f'Removed project: {os.path.basename(project_path)}')
 
     # If daemon is running, tell it to stop watching
     if daemon_is_running; then
-        echo "{\"action\":\"remove\",\"path\":\"$path\"}" | nc -U "$SOCKET_PATH" &>/dev/null || true
+        echo "{\"version\":$DAEMON_PROTOCOL,\"action\":\"remove\",\"path\":\"$path\"}" | nc -U "$SOCKET_PATH" &>/dev/null || true
     fi
 }
 
@@ -716,6 +735,49 @@ print(f'Total index size: {total_size / 1024 / 1024:.2f} MB')
 "
 }
 
+registry_auto_embed() {
+    local mode="${1:-}"
+    local path="${2:-.}"
+    path=$(cd "$path" 2>/dev/null && pwd) || path="$2"
+
+    case "$mode" in
+        on) local enabled=True ;;
+        off) local enabled=False ;;
+        *)
+            error "Usage: codetect registry auto-embed <on|off> [path]"
+            return 1
+            ;;
+    esac
+
+    if [[ ! -f "$REGISTRY_FILE" ]]; then
+        error "No registry found"
+        return 1
+    fi
+
+    python3 -c "
+import json
+
+registry_file = '$REGISTRY_FILE'
+project_path = '$path'
+
+with open(registry_file) as f:
+    data = json.load(f)
+
+for p in data['projects']:
+    if p['path'] == project_path:
+        p['auto_embed'] = $enabled
+        break
+else:
+    print(f'Project not found in registry: {project_path}')
+    exit(1)
+
+with open(registry_file, 'w') as f:
+    json.dump(data, f, indent=2)
+" || return 1
+
+    success "Auto-embed $mode for $path"
+}
+
 registry_help() {
     echo "Usage: codetect registry <command>"
     echo ""
@@ -724,6 +786,7 @@ registry_help() {
     echo "  add [path]  Add project to registry (default: current directory)"
     echo "  remove <path>  Remove project from registry"
     echo "  stats       Show aggregate statistics"
+    echo "  auto-embed <on|off> [path]  Re-embed after the daemon reindexes"
     echo "  help        Show this help"
 }
 